@@ -0,0 +1,67 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// fake-gce serves a stateful fake of the compute API over HTTP, so that the machine controller can
+// be run locally against it with --compute-endpoint. It is a development tool and must not be
+// deployed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute/fakegce"
+	"github.com/openshift/machine-api-provider-gcp/pkg/version"
+	"k8s.io/klog/v2"
+)
+
+func main() {
+	var printVersion bool
+	flag.BoolVar(&printVersion, "version", false, "print version and exit")
+
+	klog.InitFlags(nil)
+
+	address := flag.String("listen-address", "127.0.0.1:8086", "address the fake compute API listens on")
+	operationLatency := flag.Duration("operation-latency", 5*time.Second, "how long operations are reported as running before they are done")
+	faultProfile := flag.String("fault-profile", "none", "errors to inject: none, flaky (10% of calls fail with 503), rate-limited (20% of calls are rate limited), stockout (half of the instance inserts run out of resources) or quota (all instance inserts exceed the CPU quota)")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the fault injection, to repeat a run")
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	if printVersion {
+		fmt.Println(version.String)
+		os.Exit(0)
+	}
+
+	faults, err := fakegce.ParseFaultProfile(*faultProfile)
+	if err != nil {
+		klog.Fatalf("Invalid --fault-profile: %v", err)
+	}
+	gce := fakegce.New(fakegce.Config{
+		OperationLatency: *operationLatency,
+		Faults:           faults,
+		Seed:             *seed,
+	})
+
+	klog.Infof("Serving the fake compute API with fault profile %s and seed %d, run the machine controller with --compute-endpoint=http://%s%s", faults.Name, *seed, *address, fakegce.BasePath)
+	server := &http.Server{Addr: *address, Handler: gce.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	if err := server.ListenAndServe(); err != nil {
+		klog.Fatalf("Failed to serve the fake compute API: %v", err)
+	}
+}
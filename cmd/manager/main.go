@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"text/template"
 	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/cache"
@@ -18,21 +22,35 @@ import (
 	"github.com/openshift/library-go/pkg/operator/events"
 	capimachine "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	"github.com/openshift/machine-api-operator/pkg/metrics"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/debugconfig"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/drexport"
 	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/machine"
 	machinesetcontroller "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/machineset"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/preemption"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/credentials"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	iamservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/iam"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/notifier"
+	pricingservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/pricing"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/tracing"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/webhook"
 	"github.com/openshift/machine-api-provider-gcp/pkg/version"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 // The default durations for the leader electrion operations.
@@ -49,6 +67,54 @@ func main() {
 		"print version and exit",
 	)
 
+	dumpSupportedFeatures := flag.Bool(
+		"dump-supported-features",
+		false,
+		"print the GCP features supported by this build as JSON and exit",
+	)
+
+	exportDRManifests := flag.String(
+		"export-dr-manifests",
+		"",
+		"Path of a region mapping file: print the MachineSets of --export-dr-namespace rewritten for the region, zones, networks and subnetworks of the mapping as YAML, for disaster recovery rehearsals in that region, and exit.",
+	)
+
+	exportDRNamespace := flag.String(
+		"export-dr-namespace",
+		"openshift-machine-api",
+		"Namespace of the MachineSets exported by --export-dr-manifests.",
+	)
+
+	exportInstanceTemplate := flag.String(
+		"export-instance-template",
+		"",
+		"Name of a MachineSet of --export-instance-template-namespace: print the GCP instance template rendered from its providerSpec as JSON, e.g. for managed instance groups, and exit.",
+	)
+
+	exportInstanceTemplateNamespace := flag.String(
+		"export-instance-template-namespace",
+		"openshift-machine-api",
+		"Namespace of the MachineSet exported by --export-instance-template.",
+	)
+
+	exportInstanceTemplateName := flag.String(
+		"export-instance-template-name",
+		"",
+		"Name of the instance template exported by --export-instance-template. Defaults to the name of the MachineSet.",
+	)
+
+	exportInstanceTemplateProject := flag.String(
+		"export-instance-template-project",
+		"",
+		"Project of the instance template exported by --export-instance-template. Defaults to the project of the MachineSet.",
+	)
+
+	exportInstanceTemplateCreate := flag.Bool(
+		"export-instance-template-create",
+		false,
+		"Also create the instance template exported by --export-instance-template in its project, with the credentials of the MachineSet, instead of only printing it.",
+	)
+
 	leaderElectResourceNamespace := flag.String(
 		"leader-elect-resource-namespace",
 		"",
@@ -85,6 +151,294 @@ func main() {
 		"Address for hosting metrics",
 	)
 
+	maxAPICallsPerReconcile := flag.Int(
+		"max-api-calls-per-reconcile",
+		100,
+		"The maximum number of compute API calls a single machine operation may make before its progress is persisted and the machine is requeued. Zero means unlimited.",
+	)
+
+	maxReconcileDuration := flag.Duration(
+		"max-reconcile-duration",
+		2*time.Minute,
+		"The maximum wall time a single machine operation may spend calling the compute API before its progress is persisted and the machine is requeued. Zero means unlimited.",
+	)
+
+	computeAPIMaxAttempts := flag.Int(
+		"compute-api-max-attempts",
+		computeservice.DefaultRetryPolicy.MaxAttempts,
+		"The number of attempts of a compute API call failing with a rate limit, a server error or a connection reset before the error is returned. Mutations are only retried on rate limits. Values below 2 disable retries.",
+	)
+
+	computeAPIRetryBackoff := flag.Duration(
+		"compute-api-retry-backoff",
+		computeservice.DefaultRetryPolicy.InitialBackoff,
+		"The wait before the first retry of a compute API call, doubled with every further retry.",
+	)
+
+	computeAPIRetryMaxBackoff := flag.Duration(
+		"compute-api-retry-max-backoff",
+		computeservice.DefaultRetryPolicy.MaxBackoff,
+		"The maximum wait between two attempts of a compute API call.",
+	)
+
+	computeAPIRetryMaxDuration := flag.Duration(
+		"compute-api-retry-max-duration",
+		computeservice.DefaultRetryPolicy.MaxRetryDuration,
+		"The maximum time a single compute API call may spend retrying. Zero means it is only bounded by the number of attempts.",
+	)
+
+	computeAPIReadQPS := flag.Float64(
+		"compute-api-read-qps",
+		computeservice.DefaultRateLimits.ReadQPS,
+		"The sustained rate of compute API read calls of the controller, protecting the project API quota shared with other controllers. Zero means unlimited.",
+	)
+
+	computeAPIReadBurst := flag.Int(
+		"compute-api-read-burst",
+		computeservice.DefaultRateLimits.ReadBurst,
+		"The number of compute API read calls allowed above --compute-api-read-qps after a quiet period.",
+	)
+
+	computeAPIMutateQPS := flag.Float64(
+		"compute-api-mutate-qps",
+		computeservice.DefaultRateLimits.MutateQPS,
+		"The sustained rate of compute API calls of the controller that change resources. Zero means unlimited.",
+	)
+
+	computeAPIMutateBurst := flag.Int(
+		"compute-api-mutate-burst",
+		computeservice.DefaultRateLimits.MutateBurst,
+		"The number of compute API calls that change resources allowed above --compute-api-mutate-qps after a quiet period.",
+	)
+
+	computeAPIMaxThrottleWait := flag.Duration(
+		"compute-api-max-throttle-wait",
+		computeservice.DefaultRateLimits.MaxWait,
+		"The maximum time a compute API call waits for the rate limits before it fails and the machine is requeued. Zero means calls always wait.",
+	)
+
+	instanceCacheTTL := flag.Duration(
+		"instance-cache-ttl",
+		computeservice.DefaultInstanceCacheTTL,
+		"How long a running instance fetched from the compute API is served from a cache to the reconciles of its machine. Zero disables the cache.",
+	)
+
+	instanceSyncInterval := flag.Duration(
+		"instance-sync-interval",
+		2*time.Minute,
+		"How often the instances of all machines are listed with one aggregated list per project, to refresh their existence checks and detect instances deleted outside the controller. Zero disables it.",
+	)
+
+	credentialsCheckInterval := flag.Duration(
+		"credentials-check-interval",
+		10*time.Minute,
+		"How often GCP is asked to accept the credentials of all machines, with one read call per credentials secret and project, to report expired or revoked keys in the CredentialsValid condition of the machines before a scale up fails. Zero disables it.",
+	)
+
+	inventoryInterval := flag.Duration(
+		"inventory-interval",
+		10*time.Minute,
+		"How often the instances, control plane instance groups and disks managed by the provider are listed, with their health, into the gcp-provider-inventory ConfigMap of every namespace with machines. Zero disables it.",
+	)
+
+	failureWebhookURL := flag.String(
+		"failure-webhook-url",
+		"",
+		"URL to POST structured machine provisioning failure events to as JSON, e.g. a Slack relay. Disabled if empty.",
+	)
+
+	failureWebhookMinInterval := flag.Duration(
+		"failure-webhook-min-interval",
+		notifier.DefaultMinInterval,
+		"The minimum interval between two failure events of the same type for the same machine.",
+	)
+
+	provisioningTimeout := flag.Duration(
+		"provisioning-timeout",
+		30*time.Minute,
+		"How long a created machine may take to become a node before the operation error, instance state and serial console output are collected into a ConfigMap referenced from the machine. Zero disables it.",
+	)
+
+	existsVerificationInterval := flag.Duration(
+		"exists-verification-interval",
+		5*time.Minute,
+		"How long the existence and state of a running machine's instance are trusted from its provider status before the compute API is asked again, by the existence check and by the update, which still reconciles the providerSpec against the instance seen last. Zero checks the compute API on every reconcile.",
+	)
+
+	remediateDrift := flag.Bool(
+		"remediate-drift",
+		false,
+		"Revert the labels, network tags and metadata of instances that were changed outside the controller to their providerSpec. Other differences are only reported in the ProviderSpecOutOfSync condition.",
+	)
+
+	sharedCorePolicy := flag.String(
+		"shared-core-policy",
+		string(machine.SharedCorePolicyWarn),
+		"What to do about control plane and infra machines using a shared-core machine type, e.g. e2-medium: warn reports them in the SharedCoreMachineType condition, block also refuses to create their instances.",
+	)
+
+	deletionProtectionPolicy := flag.String(
+		"deletion-protection-policy",
+		string(machine.DeletionProtectionPolicyRefuse),
+		"What to do when the instance of a deleted machine has deletion protection enabled: refuse keeps the instance and reports the machine in the DeletionBlocked condition, clear removes the protection and deletes the instance.",
+	)
+
+	missingNetworkPolicy := flag.String(
+		"missing-network-policy",
+		string(machine.MissingNetworkPolicyOmit),
+		"What to do about machines whose providerSpec has no network interfaces: omit creates their instances without network interfaces, project-default attaches them to the default network of the project and its subnetwork in the region of the machine, reject refuses to create them.",
+	)
+
+	checkPrivateGoogleAccess := flag.Bool(
+		"check-private-google-access",
+		false,
+		"Refuse to create instances without external IPs whose subnetwork has Private Google Access disabled and whose network has no Private Service Connect endpoint for Google APIs, as they cannot reach the GCP APIs. Leave disabled when instances reach the GCP APIs through Cloud NAT or a proxy.",
+	)
+
+	spotZonePolicy := flag.String(
+		"spot-zone-policy",
+		string(machine.SpotZonePolicyRequested),
+		"How to pick the zone of the instances of preemptible (spot) machines with fallback zones: requested creates them in the zone of the providerSpec, cheapest in the zone with the lowest spot price of the machine type in the Cloud Billing catalog, least-preempted in the zone with the fewest preemptions in the project over the last 7 days. The selection is recorded in the SpotZoneSelection condition.",
+	)
+
+	tracingEndpoint := flag.String(
+		"tracing-endpoint",
+		"",
+		"host:port of the OTLP/HTTP receiver of an OpenTelemetry collector to export traces of the machine reconciles and their compute API calls and operations to, e.g. otel-collector.observability.svc:4318. Tracing is disabled when empty.",
+	)
+
+	tracingInsecure := flag.Bool(
+		"tracing-insecure",
+		false,
+		"Export traces to --tracing-endpoint over plain HTTP instead of HTTPS.",
+	)
+
+	tracingSamplingRatio := flag.Float64(
+		"tracing-sampling-ratio",
+		1,
+		"The fraction of the machine reconciles traced, between 0 and 1.",
+	)
+
+	webhookPort := flag.Int(
+		"webhook-port",
+		0,
+		"Port of the webhook server validating and defaulting the providerSpec of Machines and MachineSets on admission. Zero disables the webhooks.",
+	)
+
+	webhookCertDir := flag.String(
+		"webhook-cert-dir",
+		"",
+		"Directory of the tls.crt and tls.key serving certificate of the webhook server. Defaults to <temp-dir>/k8s-webhook-server/serving-certs.",
+	)
+
+	webhookDryRun := flag.Bool(
+		"webhook-dry-run",
+		false,
+		"Dry run the creation of the instance of Machines and MachineSets on dry-run admission requests, e.g. of oc apply --dry-run=server, and deny them if it would fail.",
+	)
+
+	defaultsVersion := flag.Int(
+		"defaults-version",
+		machine.LatestDefaultsVersion,
+		"Version of the providerSpec defaults applied by the defaulting webhook to new Machines and MachineSets. Zero disables the defaults.",
+	)
+
+	defaultCredentialsSecret := flag.String(
+		"default-credentials-secret",
+		machine.DefaultCredentialsSecret,
+		"Credentials secret set by the defaulting webhook on the providerSpec of machines that do not name one. Empty leaves it unset.",
+	)
+
+	computeEndpoint := flag.String(
+		"compute-endpoint",
+		"",
+		"For local development only: URL of the compute API to call without authentication instead of Google's, e.g. http://127.0.0.1:8086/compute/v1/ for the fake-gce binary. Service account validation, spot prices and DNS records are disabled when it is set.",
+	)
+
+	orphanInstancePolicy := flag.String(
+		"orphan-instance-policy",
+		string(machine.OrphanInstancePolicyIgnore),
+		"What the instance sync does about instances labelled with the cluster ID that no machine refers to: ignore, dry-run only logs and counts them, delete deletes them. Requires --instance-sync-interval.",
+	)
+
+	orphanInstanceGracePeriod := flag.Duration(
+		"orphan-instance-grace-period",
+		machine.DefaultOrphanInstanceGracePeriod,
+		"How old an instance without a machine must be to be considered orphaned.",
+	)
+
+	instanceNameTemplate := flag.String(
+		"instance-name-template",
+		"",
+		"Go template of the names of new instances, from .MachineName, .Namespace, .ClusterID and .Role, e.g. {{.ClusterID}}-{{.MachineName}}. Names longer than 63 characters are truncated and end with a hash. Empty names instances after their machine.",
+	)
+
+	managedNetworkTags := flag.String(
+		"managed-network-tags",
+		"",
+		"Comma separated Go templates of network tags kept on the instances of all machines, from .MachineName, .Namespace, .ClusterID and .Role, each optionally followed by = and a Go template of the name of a firewall rule to add the tag to the target tags of, e.g. {{.ClusterID}}-{{.Role}}={{.ClusterID}}-{{.Role}}-ingress. Tags removed from the list are removed from the instances.",
+	)
+
+	dnsZone := flag.String(
+		"dns-zone",
+		"",
+		"Cloud DNS zone, as [project/]zone, in which to register A and AAAA records of the internal IPs of machines, removed when the machines are deleted. The project defaults to the one of the machine. Empty does not register records.",
+	)
+
+	dnsRecordNameTemplate := flag.String(
+		"dns-record-name-template",
+		machine.DefaultDNSRecordNameTemplate,
+		"Go template of the names of the DNS records of machines relative to --dns-zone, from .MachineName, .Namespace, .ClusterID and .Role, e.g. {{.MachineName}}.{{.Role}}.",
+	)
+
+	dnsRecordTTL := flag.Int64(
+		"dns-record-ttl",
+		machine.DefaultDNSRecordTTL,
+		"TTL, in seconds, of the DNS records of machines.",
+	)
+
+	preemptionSimulationInterval := flag.Duration(
+		"preemption-simulation-interval",
+		0,
+		"For test clusters only: how often to simulate the preemption of preemptible machines labelled "+preemption.SimulatePreemptionLabel+"=true. Zero disables the simulation.",
+	)
+
+	preemptionSimulationFraction := flag.Float64(
+		"preemption-simulation-fraction",
+		0.1,
+		"The fraction of the labelled preemptible machines preempted in each round of simulated preemptions.",
+	)
+
+	defaultServiceAccount := flag.String(
+		"default-service-account",
+		"",
+		"Email of the service account, usually the one of the cluster nodes, attached to instances of machines that do not set a service account.",
+	)
+
+	impersonateServiceAccount := flag.String(
+		"impersonate-service-account",
+		"",
+		"Email of a service account to impersonate with the credentials of the credentials secrets, unless a secret names another one in its impersonate_service_account field. The credentials then only need permission to create access tokens for it.",
+	)
+
+	impersonationDelegates := flag.String(
+		"impersonation-delegates",
+		"",
+		"Comma separated chain of service accounts to impersonate the --impersonate-service-account through.",
+	)
+
+	debugConfigMap := flag.String(
+		"debug-configmap",
+		"",
+		"Name of a ConfigMap whose "+debugconfig.LogLevelKey+" and "+debugconfig.DumpAPIRequestsKey+" keys change the log verbosity and enable dumping of compute API requests at runtime. Disabled if empty.",
+	)
+
+	debugConfigMapNamespace := flag.String(
+		"debug-configmap-namespace",
+		"openshift-machine-api",
+		"Namespace of the --debug-configmap.",
+	)
+
 	klog.InitFlags(nil)
 	flag.Set("logtostderr", "true")
 	flag.Parse()
@@ -94,12 +448,110 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *dumpSupportedFeatures {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(machine.NewCapabilityReport(nil)); err != nil {
+			klog.Fatalf("Failed to dump supported features: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	if *exportDRManifests != "" {
+		if err := exportDisasterRecoveryManifests(*exportDRManifests, *exportDRNamespace); err != nil {
+			klog.Fatalf("Failed to export disaster recovery manifests: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	if *exportInstanceTemplate != "" {
+		options := machine.InstanceTemplateOptions{
+			Name:                  *exportInstanceTemplateName,
+			ProjectID:             *exportInstanceTemplateProject,
+			DefaultServiceAccount: *defaultServiceAccount,
+		}
+		if err := exportMachineSetInstanceTemplate(*exportInstanceTemplateNamespace, *exportInstanceTemplate, options, *exportInstanceTemplateCreate); err != nil {
+			klog.Fatalf("Failed to export instance template: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	parsedSharedCorePolicy, err := machine.ParseSharedCorePolicy(*sharedCorePolicy)
+	if err != nil {
+		klog.Fatalf("Invalid --shared-core-policy: %v", err)
+	}
+	parsedDeletionProtectionPolicy, err := machine.ParseDeletionProtectionPolicy(*deletionProtectionPolicy)
+	if err != nil {
+		klog.Fatalf("Invalid --deletion-protection-policy: %v", err)
+	}
+	parsedMissingNetworkPolicy, err := machine.ParseMissingNetworkPolicy(*missingNetworkPolicy)
+	if err != nil {
+		klog.Fatalf("Invalid --missing-network-policy: %v", err)
+	}
+	parsedSpotZonePolicy, err := machine.ParseSpotZonePolicy(*spotZonePolicy)
+	if err != nil {
+		klog.Fatalf("Invalid --spot-zone-policy: %v", err)
+	}
+	parsedOrphanInstancePolicy, err := machine.ParseOrphanInstancePolicy(*orphanInstancePolicy)
+	if err != nil {
+		klog.Fatalf("Invalid --orphan-instance-policy: %v", err)
+	}
+	var parsedInstanceNameTemplate *template.Template
+	if *instanceNameTemplate != "" {
+		parsedInstanceNameTemplate, err = machine.ParseInstanceNameTemplate(*instanceNameTemplate)
+		if err != nil {
+			klog.Fatalf("Invalid --instance-name-template: %v", err)
+		}
+	}
+	parsedManagedNetworkTags, err := machine.ParseManagedTags(*managedNetworkTags)
+	if err != nil {
+		klog.Fatalf("Invalid --managed-network-tags: %v", err)
+	}
+	parsedDNSRecords, err := machine.ParseDNSRecords(*dnsZone, *dnsRecordNameTemplate, *dnsRecordTTL)
+	if err != nil {
+		klog.Fatalf("Invalid --dns-zone, --dns-record-name-template or --dns-record-ttl: %v", err)
+	}
+	if *defaultsVersion < 0 || *defaultsVersion > machine.LatestDefaultsVersion {
+		klog.Fatalf("Invalid --defaults-version: must be between 0 and %d", machine.LatestDefaultsVersion)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:      *tracingEndpoint,
+		Insecure:      *tracingInsecure,
+		SamplingRatio: *tracingSamplingRatio,
+	})
+	if err != nil {
+		klog.Fatalf("Invalid --tracing-sampling-ratio or --tracing-endpoint: %v", err)
+	}
+
 	cfg := config.GetConfigOrDie()
 
 	// Override the default 10 hour sync period so that we pick up external changes
 	// to the VMs within a reasonable time frame.
 	syncPeriod := 10 * time.Minute
 
+	stopSignalContext := ctrl.SetupSignalHandler()
+
+	featureGateAccessor, err := createFeatureGateAccessor(
+		context.Background(),
+		cfg,
+		"machine-api-provider-gcp",
+		"openshift-machine-api",
+		"machine-api-controllers",
+		getReleaseVersion(),
+		"0.0.1-snapshot",
+		syncPeriod,
+		stopSignalContext.Done(),
+	)
+	if err != nil {
+		klog.Fatalf("failed to create feature gate accessor: %v", err)
+	}
+
+	featureGates, err := awaitEnabledFeatureGates(featureGateAccessor, 1*time.Minute)
+	if err != nil {
+		klog.Fatalf("failed to get feature gates: %v", err)
+	}
+
 	opts := manager.Options{
 		LeaderElection:          *leaderElect,
 		LeaderElectionNamespace: *leaderElectResourceNamespace,
@@ -111,6 +563,9 @@ func main() {
 		},
 		Metrics: server.Options{
 			BindAddress: *metricsAddress,
+			ExtraHandlers: map[string]http.Handler{
+				machine.CapabilitiesPath: machine.NewCapabilityHandler(featureGates),
+			},
 		},
 		// Slow the default retry and renew election rate to reduce etcd writes at idle: BZ 1858400
 		RetryPeriod:   &retryPeriod,
@@ -124,41 +579,94 @@ func main() {
 		klog.Infof("Watching machine-api objects only in namespace %q for reconciliation.", *watchNamespace)
 	}
 
+	if *webhookPort > 0 {
+		opts.WebhookServer = ctrlwebhook.NewServer(ctrlwebhook.Options{
+			Port:    *webhookPort,
+			CertDir: *webhookCertDir,
+		})
+	}
+
 	// Setup a Manager
 	mgr, err := manager.New(cfg, opts)
 	if err != nil {
 		klog.Fatalf("Failed to set up overall controller manager: %v", err)
 	}
 
-	stopSignalContext := ctrl.SetupSignalHandler()
-
-	featureGateAccessor, err := createFeatureGateAccessor(
-		context.Background(),
-		cfg,
-		"machine-api-provider-gcp",
-		"openshift-machine-api",
-		"machine-api-controllers",
-		getReleaseVersion(),
-		"0.0.1-snapshot",
-		syncPeriod,
-		stopSignalContext.Done(),
-	)
-	if err != nil {
-		klog.Fatalf("failed to create feature gate accessor: %v", err)
+	// Compute services are reused for the same credentials, a rotated credentials secret gets a new one.
+	// Transient compute API errors are retried on every call, and every attempt is rate limited.
+	// Running instances are served from a cache in front of both, so cache hits cost no quota.
+	// Every attempt that reaches the compute API is counted and timed, and every call traced with its
+	// retries and waits.
+	retryPolicy := computeservice.DefaultRetryPolicy
+	retryPolicy.MaxAttempts = *computeAPIMaxAttempts
+	retryPolicy.InitialBackoff = *computeAPIRetryBackoff
+	retryPolicy.MaxBackoff = *computeAPIRetryMaxBackoff
+	retryPolicy.MaxRetryDuration = *computeAPIRetryMaxDuration
+	newComputeService := computeservice.NewComputeService
+	iamClientBuilder := iamservice.NewIAMService
+	pricingClientBuilder := pricingservice.NewPricingService
+	dnsClientBuilder := dnsservice.NewDNSService
+	if *computeEndpoint != "" {
+		klog.Warningf("Calling the compute API at %s without authentication, service accounts are not validated, spot prices are not available and DNS records are not registered", *computeEndpoint)
+		newComputeService = computeservice.NewEndpointBuilder(*computeEndpoint)
+		iamClientBuilder = nil
+		pricingClientBuilder = nil
+		dnsClientBuilder = nil
+	}
+	computeClientBuilder := computeservice.NewInstanceCachingBuilder(computeservice.NewInterceptingBuilder(
+		computeservice.NewCachingBuilder(newComputeService, computeservice.DefaultMaxCachedServices),
+		computeservice.NewTracingInterceptor(),
+		computeservice.NewRetryInterceptor(retryPolicy, nil),
+		computeservice.NewRateLimitInterceptor(computeservice.RateLimits{
+			ReadQPS:     *computeAPIReadQPS,
+			ReadBurst:   *computeAPIReadBurst,
+			MutateQPS:   *computeAPIMutateQPS,
+			MutateBurst: *computeAPIMutateBurst,
+			MaxWait:     *computeAPIMaxThrottleWait,
+		}, nil),
+		computeservice.NewMetricsInterceptor(nil),
+	), *instanceCacheTTL, nil)
+
+	credentialsBuilder := &credentials.Builder{ImpersonateServiceAccount: *impersonateServiceAccount}
+	if *impersonationDelegates != "" {
+		credentialsBuilder.Delegates = strings.Split(*impersonationDelegates, ",")
 	}
 
-	featureGates, err := awaitEnabledFeatureGates(featureGateAccessor, 1*time.Minute)
-	if err != nil {
-		klog.Fatalf("failed to get feature gates: %v", err)
+	var failureNotifier notifier.Notifier
+	if *failureWebhookURL != "" {
+		failureNotifier = notifier.NewWebhookNotifier(*failureWebhookURL, nil, nil, *failureWebhookMinInterval)
 	}
 
 	// Initialize machine actuator.
 	machineActuator := machine.NewActuator(machine.ActuatorParams{
 		CoreClient:           mgr.GetClient(),
 		EventRecorder:        mgr.GetEventRecorderFor("gcpcontroller"),
-		ComputeClientBuilder: computeservice.NewComputeService,
+		ComputeClientBuilder: computeClientBuilder,
 		TagsClientBuilder:    tagservice.NewTagService,
+		IAMClientBuilder:     iamClientBuilder,
+		Credentials:          credentialsBuilder,
 		FeatureGates:         featureGates,
+		Notifier:             failureNotifier,
+		PricingClientBuilder: pricingClientBuilder,
+		DNSClientBuilder:     dnsClientBuilder,
+		Options: machine.Options{
+			MaxAPICallsPerReconcile:    *maxAPICallsPerReconcile,
+			MaxReconcileDuration:       *maxReconcileDuration,
+			ProvisioningTimeout:        *provisioningTimeout,
+			DefaultServiceAccount:      *defaultServiceAccount,
+			ExistsVerificationInterval: *existsVerificationInterval,
+			RemediateDrift:             *remediateDrift,
+			SharedCorePolicy:           parsedSharedCorePolicy,
+			DeletionProtectionPolicy:   parsedDeletionProtectionPolicy,
+			MissingNetworkPolicy:       parsedMissingNetworkPolicy,
+			OrphanInstancePolicy:       parsedOrphanInstancePolicy,
+			OrphanInstanceGracePeriod:  *orphanInstanceGracePeriod,
+			SpotZonePolicy:             parsedSpotZonePolicy,
+			InstanceNameTemplate:       parsedInstanceNameTemplate,
+			PrivateGoogleAccessCheck:   *checkPrivateGoogleAccess,
+			ManagedTags:                parsedManagedNetworkTags,
+			DNSRecords:                 parsedDNSRecords,
+		},
 	})
 
 	if err := machinev1.AddToScheme(mgr.GetScheme()); err != nil {
@@ -173,16 +681,76 @@ func main() {
 		klog.Fatal(err)
 	}
 
+	// The machines are counted per phase and failure reason on every scrape.
+	if err := ctrlmetrics.Registry.Register(machineActuator.MachineCollector()); err != nil {
+		klog.Fatal(err)
+	}
+
+	if *instanceSyncInterval > 0 {
+		if err := mgr.Add(machineActuator.InstanceSync(*instanceSyncInterval)); err != nil {
+			klog.Fatal(err)
+		}
+	}
+
+	if *credentialsCheckInterval > 0 {
+		if err := mgr.Add(machineActuator.CredentialsCheck(*credentialsCheckInterval)); err != nil {
+			klog.Fatal(err)
+		}
+	}
+
+	if *inventoryInterval > 0 {
+		if err := mgr.Add(machineActuator.Inventory(*inventoryInterval)); err != nil {
+			klog.Fatal(err)
+		}
+	}
+
+	if *preemptionSimulationInterval > 0 {
+		simulator := &preemption.Simulator{
+			Client:               mgr.GetClient(),
+			ComputeClientBuilder: computeClientBuilder,
+			Credentials:          credentialsBuilder,
+			Interval:             *preemptionSimulationInterval,
+			Fraction:             *preemptionSimulationFraction,
+			Namespace:            *watchNamespace,
+		}
+		if err := simulator.SetupWithManager(mgr); err != nil {
+			klog.Fatal(err)
+		}
+	}
+
 	ctrl.SetLogger(klogr.New())
 	setupLog := ctrl.Log.WithName("setup")
 	if err = (&machinesetcontroller.Reconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("MachineSet"),
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("controllers").WithName("MachineSet"),
+		Credentials: credentialsBuilder,
 	}).SetupWithManager(mgr, controller.Options{}); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MachineSet")
 		os.Exit(1)
 	}
 
+	if *webhookPort > 0 {
+		defaults := machine.ProviderSpecDefaults{Version: *defaultsVersion, CredentialsSecret: *defaultCredentialsSecret}
+		var dryRunner webhook.DryRunner
+		if *webhookDryRun {
+			dryRunner = machineActuator
+		}
+		if err := webhook.SetupWithManager(mgr, defaults, dryRunner); err != nil {
+			klog.Fatal(err)
+		}
+	}
+
+	if *debugConfigMap != "" {
+		watcher := &debugconfig.Watcher{
+			Reader:    mgr.GetAPIReader(),
+			Namespace: *debugConfigMapNamespace,
+			Name:      *debugConfigMap,
+		}
+		if err := watcher.SetupWithManager(mgr); err != nil {
+			klog.Fatal(err)
+		}
+	}
+
 	if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
 		klog.Fatal(err)
 	}
@@ -194,6 +762,51 @@ func main() {
 	if err := mgr.Start(stopSignalContext); err != nil {
 		klog.Fatalf("Failed to run manager: %v", err)
 	}
+
+	// Export the spans of the last reconciles before exiting.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		klog.Errorf("Failed to flush traces: %v", err)
+	}
+}
+
+// exportDisasterRecoveryManifests prints the MachineSets of the namespace rewritten for the region of the mapping.
+func exportDisasterRecoveryManifests(mappingPath, namespace string) error {
+	mapping, err := drexport.LoadMapping(mappingPath)
+	if err != nil {
+		return err
+	}
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	c, err := client.New(config.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	return drexport.Export(context.Background(), c, namespace, mapping, os.Stdout)
+}
+
+// exportMachineSetInstanceTemplate prints the instance template rendered from the providerSpec of the MachineSet,
+// and creates it when create is set.
+func exportMachineSetInstanceTemplate(namespace, name string, options machine.InstanceTemplateOptions, create bool) error {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	c, err := client.New(config.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	var computeClientBuilder computeservice.BuilderFuncType
+	if create {
+		computeClientBuilder = computeservice.NewComputeService
+	}
+	return machine.ExportMachineSetInstanceTemplate(context.Background(), c, namespace, name, options, computeClientBuilder, os.Stdout)
 }
 
 func createFeatureGateAccessor(ctx context.Context, cfg *rest.Config, operatorName, deploymentNamespace, deploymentName, desiredVersion, missingVersion string, syncPeriod time.Duration, stop <-chan struct{}) (featuregates.FeatureGateAccess, error) {
@@ -17,13 +17,20 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/openshift/machine-api-provider-gcp/pkg/termination"
 	"github.com/openshift/machine-api-provider-gcp/pkg/version"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -40,6 +47,13 @@ func main() {
 	pollIntervalSeconds := flag.Int64("poll-interval-seconds", 5, "interval in seconds at which termination notice endpoint should be checked (Default: 5)")
 	nodeName := flag.String("node-name", "", "name of the node that the termination handler is running on")
 	namespace := flag.String("namespace", "", "namespace that the machine for the node should live in. If unspecified, look for machines across all namespaces.")
+	markRetryWindow := flag.Duration("mark-retry-window", 30*time.Second, "how long to keep retrying to mark the node once the instance is terminating, e.g. while the API server is unavailable")
+	journalPath := flag.String("journal-path", "", "file to record a pending node marking in, so that it is applied after a restart of the handler. Should be on a volume that survives container restarts. Disabled if empty.")
+	metricsAddress := flag.String("metrics-bind-address", "", "address the metrics endpoint, and the /healthz and /readyz probes, bind to. Disabled if empty or \"0\".")
+	operationPollInterval := flag.Duration("operation-poll-interval", 0, "interval at which the compute operations of the instance are checked for a preemption or maintenance event, as a second source of termination notices next to the metadata server. Requires the compute.zoneOperations.list permission for the service account of the instance. Disabled if zero.")
+	terminationAction := flag.String("termination-action", string(termination.TerminationActionMarkNode), "what to do once the instance is terminating: mark-node adds the Terminating condition to the node, delete-machine also deletes the machine of the node so that its replacement is requested immediately. delete-machine needs permission to delete machines.")
+	taintEffect := flag.String("termination-taint-effect", "", "effect, NoSchedule or NoExecute, of the cloud.google.com/impending-termination taint applied to the node once the instance is terminating, next to the Terminating condition. The handler must tolerate a NoExecute taint. Disabled if empty.")
+	configPath := flag.String("config", "", "configuration file of the termination handler. Flags that are set explicitly override its values. The file is reloaded on SIGHUP.")
 	flag.Set("logtostderr", "true")
 	flag.Parse()
 
@@ -55,19 +69,108 @@ func main() {
 		return
 	}
 
-	// Get the poll interval as a duration from the `poll-interval-seconds` flag
-	pollInterval := time.Duration(*pollIntervalSeconds) * time.Second
+	// loadConfig reads the configuration file, if any, and overrides it with the flags
+	// that were set explicitly.
+	loadConfig := func() (*termination.Config, error) {
+		handlerConfig := termination.DefaultConfig()
+		if *configPath != "" {
+			if handlerConfig, err = termination.LoadConfig(*configPath); err != nil {
+				return nil, err
+			}
+		}
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "poll-interval-seconds":
+				// Get the poll interval as a duration from the `poll-interval-seconds` flag
+				handlerConfig.PollInterval.Duration = time.Duration(*pollIntervalSeconds) * time.Second
+			case "namespace":
+				handlerConfig.Namespace = *namespace
+			case "mark-retry-window":
+				handlerConfig.Drain.MarkRetryWindow.Duration = *markRetryWindow
+			case "journal-path":
+				handlerConfig.JournalPath = *journalPath
+			case "metrics-bind-address":
+				handlerConfig.MetricsAddress = *metricsAddress
+			case "termination-action":
+				handlerConfig.Drain.Action = termination.TerminationAction(*terminationAction)
+			case "termination-taint-effect":
+				handlerConfig.Drain.TaintEffect = corev1.TaintEffect(*taintEffect)
+			case "operation-poll-interval":
+				handlerConfig.OperationPollInterval.Duration = *operationPollInterval
+			}
+		})
+		return handlerConfig, handlerConfig.Validate()
+	}
+
+	handlerConfig, err := loadConfig()
+	if err != nil {
+		logger.Error(err, "Error loading configuration")
+		return
+	}
+
+	handlerOptions := handlerConfig.Options()
+	if handlerConfig.OperationPollInterval.Duration > 0 {
+		operations, err := termination.NewComputeOperationSource(context.Background())
+		if err != nil {
+			logger.Error(err, "Error creating operation source, only the metadata server is checked")
+		} else {
+			handlerOptions = append(handlerOptions, termination.WithOperationSource(operations))
+		}
+	}
 
 	// Construct a termination handler
-	handler, err := termination.NewHandler(logger, cfg, pollInterval, *namespace, *nodeName)
+	handler, err := termination.NewHandler(logger, cfg, handlerConfig.PollInterval.Duration, handlerConfig.Namespace, *nodeName,
+		handlerOptions...)
 	if err != nil {
 		logger.Error(err, "Error constructing termination handler")
 		return
 	}
 
+	if handlerConfig.MetricsAddress != "" && handlerConfig.MetricsAddress != "0" {
+		go serveMetrics(logger, handlerConfig.MetricsAddress, handler)
+	}
+
+	if *configPath != "" {
+		go reloadOnSIGHUP(logger, handler, handlerConfig, loadConfig)
+	}
+
 	// Start the termination handler
 	if err := handler.Run(ctrl.SetupSignalHandler().Done()); err != nil {
 		logger.Error(err, "Error starting termination handler")
 		return
 	}
 }
+
+// serveMetrics serves the metrics and the health and readiness probes of the termination handler
+// on the given address.
+func serveMetrics(logger logr.Logger, address string, handler termination.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", termination.MetricsHandler())
+	mux.Handle("/healthz", termination.ProbeHandler(handler.Healthy))
+	mux.Handle("/readyz", termination.ProbeHandler(handler.Ready))
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error(err, "Error serving metrics")
+	}
+}
+
+// reloadOnSIGHUP reloads the configuration every time the process receives SIGHUP and
+// applies it to the running handler. An invalid configuration is logged and ignored.
+func reloadOnSIGHUP(logger logr.Logger, handler termination.Handler, current *termination.Config, loadConfig func() (*termination.Config, error)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		handlerConfig, err := loadConfig()
+		if err != nil {
+			logger.Error(err, "Error reloading configuration, keeping the current configuration")
+			continue
+		}
+		if handlerConfig.Namespace != current.Namespace || handlerConfig.MetricsAddress != current.MetricsAddress ||
+			(handlerConfig.OperationPollInterval.Duration > 0 && current.OperationPollInterval.Duration == 0) {
+			logger.Info("Changes to the namespace and metrics address, and enabling the operation checks, are applied on restart")
+		}
+		handler.Reconfigure(handlerConfig.Options()...)
+		current = handlerConfig
+		logger.Info("Reloaded configuration")
+	}
+}
@@ -0,0 +1,377 @@
+// Package capgconversion converts the providerSpec of GCP machines to and from the spec of the
+// GCPMachines of the Cluster API provider for GCP (CAPG), for tooling migrating machines between
+// the Machine API and the Cluster API. The conversion is best effort: fields without equivalent,
+// and fields that belong to another object of the target API, are reported as warnings.
+package capgconversion
+
+import (
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+)
+
+const (
+	// defaultDiskSizeGB and defaultDiskType are the defaults of CAPG for the boot and
+	// additional disks, used when converting to a providerSpec, which has no defaults.
+	defaultDiskSizeGB = 30
+	defaultDiskType   = "pd-standard"
+
+	kmsKeyNameFmt = "projects/%s/locations/%s/keyRings/%s/cryptoKeys/%s"
+)
+
+// WarningReason tells why a field was not converted as is.
+type WarningReason string
+
+const (
+	// Unsupported fields have no equivalent in the target API, they are dropped.
+	Unsupported WarningReason = "Unsupported"
+	// Lossy fields are converted to an approximation.
+	Lossy WarningReason = "Lossy"
+	// SetOnCluster fields belong to the GCPCluster of the Cluster API, or come from it.
+	SetOnCluster WarningReason = "SetOnCluster"
+	// SetOnMachine fields belong to the Machine of the Cluster API, or come from it.
+	SetOnMachine WarningReason = "SetOnMachine"
+)
+
+// Warning reports a field that was not converted as is.
+type Warning struct {
+	// Field is the path of the field in the source spec, or in the target spec when the field
+	// has no source in the spec, e.g. the zone of a providerSpec.
+	Field   string        `json:"field"`
+	Reason  WarningReason `json:"reason"`
+	Message string        `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s: %s", w.Field, w.Reason, w.Message)
+}
+
+// warnings accumulates the warnings of a conversion.
+type warnings []Warning
+
+func (w *warnings) add(field string, reason WarningReason, format string, args ...interface{}) {
+	*w = append(*w, Warning{Field: field, Reason: reason, Message: fmt.Sprintf(format, args...)})
+}
+
+// ToCAPG converts the providerSpec of a machine to the spec of a CAPG GCPMachine.
+func ToCAPG(providerSpec *machinev1.GCPMachineProviderSpec) (*GCPMachineSpec, []Warning) {
+	var w warnings
+	spec := &GCPMachineSpec{
+		InstanceType:          providerSpec.MachineType,
+		AdditionalNetworkTags: providerSpec.Tags,
+		Preemptible:           providerSpec.Preemptible,
+	}
+
+	if providerSpec.Zone != "" {
+		w.add("zone", SetOnMachine, "set spec.failureDomain of the Machine to %s", providerSpec.Zone)
+	}
+	if providerSpec.Region != "" {
+		w.add("region", SetOnCluster, "set spec.region of the GCPCluster to %s", providerSpec.Region)
+	}
+	if providerSpec.ProjectID != "" {
+		w.add("projectID", SetOnCluster, "set spec.project of the GCPCluster to %s", providerSpec.ProjectID)
+	}
+	if providerSpec.UserDataSecret != nil {
+		w.add("userDataSecret", SetOnMachine, "user data comes from the bootstrap provider, set spec.bootstrap of the Machine")
+	}
+	if providerSpec.CredentialsSecret != nil {
+		w.add("credentialsSecret", SetOnCluster, "set spec.credentialsRef of the GCPCluster")
+	}
+
+	convertDisksToCAPG(providerSpec, spec, &w)
+	convertNetworkInterfacesToCAPG(providerSpec.NetworkInterfaces, spec, &w)
+
+	if len(providerSpec.Labels) > 0 {
+		spec.AdditionalLabels = Labels{}
+		for key, value := range providerSpec.Labels {
+			spec.AdditionalLabels[key] = value
+		}
+	}
+	for _, item := range providerSpec.Metadata {
+		if item != nil {
+			spec.AdditionalMetadata = append(spec.AdditionalMetadata, MetadataItem{Key: item.Key, Value: item.Value})
+		}
+	}
+	for _, tag := range providerSpec.ResourceManagerTags {
+		spec.ResourceManagerTags = append(spec.ResourceManagerTags, ResourceManagerTag(tag))
+	}
+
+	if len(providerSpec.ServiceAccounts) > 0 {
+		account := providerSpec.ServiceAccounts[0]
+		spec.ServiceAccount = &ServiceAccount{Email: account.Email, Scopes: account.Scopes}
+		for i := range providerSpec.ServiceAccounts[1:] {
+			w.add(fmt.Sprintf("serviceAccounts[%d]", i+1), Unsupported, "GCPMachines have a single service account")
+		}
+	}
+
+	ipForwarding := IPForwardingDisabled
+	if providerSpec.CanIPForward {
+		ipForwarding = IPForwardingEnabled
+	}
+	spec.IPForwarding = &ipForwarding
+
+	shielded := providerSpec.ShieldedInstanceConfig
+	if shielded != (machinev1.GCPShieldedInstanceConfig{}) {
+		spec.ShieldedInstanceConfig = &GCPShieldedInstanceConfig{
+			SecureBoot:                       SecureBootPolicy(shielded.SecureBoot),
+			VirtualizedTrustedPlatformModule: VirtualizedTrustedPlatformModulePolicy(shielded.VirtualizedTrustedPlatformModule),
+			IntegrityMonitoring:              VirtualizedIntegrityMonitoringPolicy(shielded.IntegrityMonitoring),
+		}
+	}
+	if providerSpec.OnHostMaintenance != "" {
+		onHostMaintenance := HostMaintenancePolicy(providerSpec.OnHostMaintenance)
+		spec.OnHostMaintenance = &onHostMaintenance
+	}
+	if providerSpec.ConfidentialCompute != "" {
+		confidentialCompute := ConfidentialComputePolicy(providerSpec.ConfidentialCompute)
+		spec.ConfidentialCompute = &confidentialCompute
+	}
+
+	if providerSpec.DeletionProtection {
+		w.add("deletionProtection", Unsupported, "GCPMachines cannot enable deletion protection")
+	}
+	if providerSpec.RestartPolicy != "" {
+		w.add("restartPolicy", Unsupported, "GCPMachines use the default automatic restart of GCP")
+	}
+	if len(providerSpec.GPUs) > 0 {
+		w.add("gpus", Unsupported, "GCPMachines cannot attach GPUs, use an accelerator optimized instance type")
+	}
+	if len(providerSpec.TargetPools) > 0 {
+		w.add("targetPools", Unsupported, "GCPMachines are not added to target pools, the load balancers of the GCPCluster only target control plane machines")
+	}
+	return spec, w
+}
+
+// convertDisksToCAPG converts the boot disk to the root device of the GCPMachine and the other
+// disks to its additional disks, created empty.
+func convertDisksToCAPG(providerSpec *machinev1.GCPMachineProviderSpec, spec *GCPMachineSpec, w *warnings) {
+	bootDisk := false
+	for i, disk := range providerSpec.Disks {
+		if disk == nil {
+			continue
+		}
+		field := fmt.Sprintf("disks[%d]", i)
+		if len(disk.Labels) > 0 {
+			w.add(field+".labels", Unsupported, "GCPMachines have no disk labels, the disks only get the labels of the instance")
+		}
+		if !disk.AutoDelete {
+			w.add(field+".autoDelete", Unsupported, "the disks of GCPMachines are always deleted with the instance")
+		}
+
+		var diskType *DiskType
+		if disk.Type != "" {
+			t := DiskType(disk.Type)
+			diskType = &t
+		}
+		encryptionKey := encryptionKeyToCAPG(disk.EncryptionKey, providerSpec.ProjectID, field+".encryptionKey", w)
+
+		if disk.Boot && !bootDisk {
+			bootDisk = true
+			if disk.Image != "" {
+				image := disk.Image
+				if strings.Contains(image, "/family/") {
+					spec.ImageFamily = &image
+				} else {
+					spec.Image = &image
+				}
+			}
+			spec.RootDeviceSize = disk.SizeGB
+			spec.RootDeviceType = diskType
+			spec.RootDiskEncryptionKey = encryptionKey
+			continue
+		}
+
+		if disk.Image != "" {
+			w.add(field+".image", Unsupported, "the additional disks of GCPMachines are created empty")
+		}
+		additional := AttachedDiskSpec{DeviceType: diskType, EncryptionKey: encryptionKey}
+		if disk.SizeGB != 0 {
+			size := disk.SizeGB
+			additional.Size = &size
+		}
+		spec.AdditionalDisks = append(spec.AdditionalDisks, additional)
+	}
+	if !bootDisk {
+		w.add("disks", Lossy, "no boot disk, the GCPMachine gets the default image and root device of CAPG")
+	}
+}
+
+// encryptionKeyToCAPG converts a KMS key reference to a customer managed key, of the project of
+// the machine when the reference has none.
+func encryptionKeyToCAPG(key *machinev1.GCPEncryptionKeyReference, projectID, field string, w *warnings) *CustomerEncryptionKey {
+	if key == nil || key.KMSKey == nil {
+		return nil
+	}
+	project := key.KMSKey.ProjectID
+	if project == "" {
+		project = projectID
+	}
+	if project == "" {
+		w.add(field+".kmsKey.projectID", Lossy, "the key is in the project of the cluster, set it in the key name of the GCPMachine")
+	}
+	encryptionKey := &CustomerEncryptionKey{
+		KeyType:    CustomerManagedKey,
+		ManagedKey: &ManagedKey{KMSKeyName: fmt.Sprintf(kmsKeyNameFmt, project, key.KMSKey.Location, key.KMSKey.KeyRing, key.KMSKey.Name)},
+	}
+	if key.KMSKeyServiceAccount != "" {
+		serviceAccount := key.KMSKeyServiceAccount
+		encryptionKey.KeyServiceAccount = &serviceAccount
+	}
+	return encryptionKey
+}
+
+// convertNetworkInterfacesToCAPG converts the primary network interface, the network of a
+// GCPMachine is the one of its GCPCluster.
+func convertNetworkInterfacesToCAPG(nics []*machinev1.GCPNetworkInterface, spec *GCPMachineSpec, w *warnings) {
+	if len(nics) == 0 || nics[0] == nil {
+		return
+	}
+	nic := nics[0]
+	if nic.Subnetwork != "" {
+		subnet := nic.Subnetwork
+		spec.Subnet = &subnet
+	}
+	if nic.PublicIP {
+		publicIP := true
+		spec.PublicIP = &publicIP
+	}
+	if nic.Network != "" {
+		w.add("networkInterfaces[0].network", SetOnCluster, "set spec.network.name of the GCPCluster to %s", nic.Network)
+	}
+	if nic.ProjectID != "" {
+		w.add("networkInterfaces[0].projectID", SetOnCluster, "set spec.network.hostProject of the GCPCluster to %s", nic.ProjectID)
+	}
+	for i := range nics[1:] {
+		w.add(fmt.Sprintf("networkInterfaces[%d]", i+1), Unsupported, "GCPMachines have a single network interface")
+	}
+}
+
+// FromCAPG converts the spec of a CAPG GCPMachine to the providerSpec of a machine. The fields
+// the GCPMachine inherits from its GCPCluster and Machine, e.g. the zone, are left empty.
+func FromCAPG(spec *GCPMachineSpec) (*machinev1.GCPMachineProviderSpec, []Warning) {
+	var w warnings
+	providerSpec := &machinev1.GCPMachineProviderSpec{
+		MachineType: spec.InstanceType,
+		Tags:        spec.AdditionalNetworkTags,
+		Preemptible: spec.Preemptible,
+		// CAPG enables IP forwarding unless disabled.
+		CanIPForward: spec.IPForwarding == nil || *spec.IPForwarding == IPForwardingEnabled,
+	}
+	w.add("zone", SetOnMachine, "set it to spec.failureDomain of the Machine")
+	w.add("region", SetOnCluster, "set it to spec.region of the GCPCluster")
+	w.add("userDataSecret", SetOnMachine, "set it to the bootstrap data secret of the Machine")
+	w.add("credentialsSecret", SetOnCluster, "set it to a secret with the credentials of spec.credentialsRef of the GCPCluster")
+
+	convertDisksFromCAPG(spec, providerSpec, &w)
+
+	nic := &machinev1.GCPNetworkInterface{PublicIP: spec.PublicIP != nil && *spec.PublicIP}
+	if spec.Subnet != nil {
+		nic.Subnetwork = *spec.Subnet
+	}
+	providerSpec.NetworkInterfaces = []*machinev1.GCPNetworkInterface{nic}
+	w.add("networkInterfaces[0].network", SetOnCluster, "set it to spec.network.name of the GCPCluster")
+
+	if len(spec.AdditionalLabels) > 0 {
+		providerSpec.Labels = map[string]string{}
+		for key, value := range spec.AdditionalLabels {
+			providerSpec.Labels[key] = value
+		}
+	}
+	for _, item := range spec.AdditionalMetadata {
+		providerSpec.Metadata = append(providerSpec.Metadata, &machinev1.GCPMetadata{Key: item.Key, Value: item.Value})
+	}
+	for _, tag := range spec.ResourceManagerTags {
+		providerSpec.ResourceManagerTags = append(providerSpec.ResourceManagerTags, machinev1.ResourceManagerTag(tag))
+	}
+	if spec.ServiceAccount != nil {
+		providerSpec.ServiceAccounts = []machinev1.GCPServiceAccount{{Email: spec.ServiceAccount.Email, Scopes: spec.ServiceAccount.Scopes}}
+	}
+
+	if spec.ProvisioningModel != nil && *spec.ProvisioningModel == ProvisioningModelSpot {
+		providerSpec.Preemptible = true
+		w.add("provisioningModel", Lossy, "the providerSpec has no Spot VMs, the machine is a preemptible VM")
+	}
+	if spec.ShieldedInstanceConfig != nil {
+		providerSpec.ShieldedInstanceConfig = machinev1.GCPShieldedInstanceConfig{
+			SecureBoot:                       machinev1.SecureBootPolicy(spec.ShieldedInstanceConfig.SecureBoot),
+			VirtualizedTrustedPlatformModule: machinev1.VirtualizedTrustedPlatformModulePolicy(spec.ShieldedInstanceConfig.VirtualizedTrustedPlatformModule),
+			IntegrityMonitoring:              machinev1.IntegrityMonitoringPolicy(spec.ShieldedInstanceConfig.IntegrityMonitoring),
+		}
+	}
+	if spec.OnHostMaintenance != nil {
+		providerSpec.OnHostMaintenance = machinev1.GCPHostMaintenanceType(*spec.OnHostMaintenance)
+	}
+	if spec.ConfidentialCompute != nil {
+		switch *spec.ConfidentialCompute {
+		case ConfidentialComputePolicyEnabled, ConfidentialComputePolicyDisabled:
+			providerSpec.ConfidentialCompute = machinev1.ConfidentialComputePolicy(*spec.ConfidentialCompute)
+		default:
+			w.add("confidentialCompute", Unsupported, "the providerSpec only enables or disables Confidential VM, not %s", *spec.ConfidentialCompute)
+		}
+	}
+	return providerSpec, w
+}
+
+// convertDisksFromCAPG converts the root device and the additional disks of the GCPMachine to
+// disks deleted with the instance, with the defaults of CAPG.
+func convertDisksFromCAPG(spec *GCPMachineSpec, providerSpec *machinev1.GCPMachineProviderSpec, w *warnings) {
+	bootDisk := &machinev1.GCPDisk{
+		AutoDelete: true,
+		Boot:       true,
+		SizeGB:     spec.RootDeviceSize,
+		Type:       defaultDiskType,
+	}
+	if bootDisk.SizeGB == 0 {
+		bootDisk.SizeGB = defaultDiskSizeGB
+	}
+	if spec.RootDeviceType != nil {
+		bootDisk.Type = string(*spec.RootDeviceType)
+	}
+	switch {
+	case spec.Image != nil:
+		bootDisk.Image = *spec.Image
+	case spec.ImageFamily != nil:
+		bootDisk.Image = *spec.ImageFamily
+	default:
+		w.add("disks[0].image", SetOnMachine, "CAPG picks the image from the Kubernetes version of the Machine, set a RHCOS image")
+	}
+	bootDisk.EncryptionKey = encryptionKeyFromCAPG(spec.RootDiskEncryptionKey, "rootDiskEncryptionKey", w)
+	providerSpec.Disks = []*machinev1.GCPDisk{bootDisk}
+
+	for i, additional := range spec.AdditionalDisks {
+		disk := &machinev1.GCPDisk{AutoDelete: true, SizeGB: defaultDiskSizeGB, Type: defaultDiskType}
+		if additional.Size != nil {
+			disk.SizeGB = *additional.Size
+		}
+		if additional.DeviceType != nil {
+			disk.Type = string(*additional.DeviceType)
+		}
+		disk.EncryptionKey = encryptionKeyFromCAPG(additional.EncryptionKey, fmt.Sprintf("additionalDisks[%d].encryptionKey", i), w)
+		providerSpec.Disks = append(providerSpec.Disks, disk)
+	}
+}
+
+// encryptionKeyFromCAPG converts a customer managed key to a KMS key reference, the providerSpec
+// has no customer supplied keys.
+func encryptionKeyFromCAPG(key *CustomerEncryptionKey, field string, w *warnings) *machinev1.GCPEncryptionKeyReference {
+	if key == nil {
+		return nil
+	}
+	if key.KeyType != CustomerManagedKey || key.ManagedKey == nil {
+		w.add(field, Unsupported, "the providerSpec only references keys of Cloud KMS, the disk is encrypted with a Google managed key")
+		return nil
+	}
+	parts := strings.Split(key.ManagedKey.KMSKeyName, "/")
+	if len(parts) != 8 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "keyRings" || parts[6] != "cryptoKeys" {
+		w.add(field+".managedKey.kmsKeyName", Unsupported, "%q is not the name of a key of Cloud KMS, the disk is encrypted with a Google managed key", key.ManagedKey.KMSKeyName)
+		return nil
+	}
+	reference := &machinev1.GCPEncryptionKeyReference{
+		KMSKey: &machinev1.GCPKMSKeyReference{ProjectID: parts[1], Location: parts[3], KeyRing: parts[5], Name: parts[7]},
+	}
+	if key.KeyServiceAccount != nil {
+		reference.KMSKeyServiceAccount = *key.KeyServiceAccount
+	}
+	return reference
+}
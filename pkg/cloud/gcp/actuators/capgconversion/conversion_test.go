@@ -0,0 +1,232 @@
+package capgconversion
+
+import (
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestToCAPG(t *testing.T) {
+	providerSpec := &machinev1.GCPMachineProviderSpec{
+		MachineType:       "n2-standard-4",
+		Region:            "us-east1",
+		Zone:              "us-east1-b",
+		ProjectID:         "project",
+		UserDataSecret:    &corev1.LocalObjectReference{Name: "worker-user-data"},
+		CredentialsSecret: &corev1.LocalObjectReference{Name: "gcp-cloud-credentials"},
+		CanIPForward:      false,
+		Disks: []*machinev1.GCPDisk{
+			{
+				AutoDelete: true,
+				Boot:       true,
+				SizeGB:     128,
+				Type:       "pd-ssd",
+				Image:      "projects/rhcos-cloud/global/images/rhcos-415",
+				EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+					KMSKey:               &machinev1.GCPKMSKeyReference{Name: "key", KeyRing: "ring", Location: "global"},
+					KMSKeyServiceAccount: "kms@project.iam.gserviceaccount.com",
+				},
+			},
+			{AutoDelete: false, SizeGB: 500, Type: "pd-balanced", Labels: map[string]string{"data": "true"}},
+		},
+		Labels:   map[string]string{"team": "infra"},
+		Metadata: []*machinev1.GCPMetadata{{Key: "enable-oslogin", Value: stringPtr("true")}},
+		NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+			{Network: "network", ProjectID: "host-project", Subnetwork: "worker-subnet"},
+			{Network: "other", Subnetwork: "other-subnet"},
+		},
+		ServiceAccounts:        []machinev1.GCPServiceAccount{{Email: "worker@project.iam.gserviceaccount.com", Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"}}},
+		Tags:                   []string{"worker"},
+		TargetPools:            []string{"pool"},
+		GPUs:                   []machinev1.GCPGPUConfig{{Count: 1, Type: "nvidia-tesla-t4"}},
+		OnHostMaintenance:      machinev1.TerminateHostMaintenanceType,
+		RestartPolicy:          machinev1.RestartPolicyAlways,
+		ShieldedInstanceConfig: machinev1.GCPShieldedInstanceConfig{SecureBoot: machinev1.SecureBootPolicyEnabled},
+		ResourceManagerTags:    []machinev1.ResourceManagerTag{{ParentID: "1234", Key: "env", Value: "prod"}},
+	}
+
+	spec, warnings := ToCAPG(providerSpec)
+
+	ipForwardingDisabled := IPForwardingDisabled
+	terminate := HostMaintenancePolicyTerminate
+	ssd, balanced := DiskType("pd-ssd"), DiskType("pd-balanced")
+	size := int64(500)
+	expected := &GCPMachineSpec{
+		InstanceType:          "n2-standard-4",
+		Subnet:                stringPtr("worker-subnet"),
+		Image:                 stringPtr("projects/rhcos-cloud/global/images/rhcos-415"),
+		AdditionalLabels:      Labels{"team": "infra"},
+		AdditionalMetadata:    []MetadataItem{{Key: "enable-oslogin", Value: stringPtr("true")}},
+		AdditionalNetworkTags: []string{"worker"},
+		ResourceManagerTags:   ResourceManagerTags{{ParentID: "1234", Key: "env", Value: "prod"}},
+		RootDeviceSize:        128,
+		RootDeviceType:        &ssd,
+		RootDiskEncryptionKey: &CustomerEncryptionKey{
+			KeyType:           CustomerManagedKey,
+			KeyServiceAccount: stringPtr("kms@project.iam.gserviceaccount.com"),
+			ManagedKey:        &ManagedKey{KMSKeyName: "projects/project/locations/global/keyRings/ring/cryptoKeys/key"},
+		},
+		AdditionalDisks:        []AttachedDiskSpec{{DeviceType: &balanced, Size: &size}},
+		ServiceAccount:         &ServiceAccount{Email: "worker@project.iam.gserviceaccount.com", Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"}},
+		IPForwarding:           &ipForwardingDisabled,
+		ShieldedInstanceConfig: &GCPShieldedInstanceConfig{SecureBoot: SecureBootPolicyEnabled},
+		OnHostMaintenance:      &terminate,
+	}
+	if !reflect.DeepEqual(spec, expected) {
+		t.Errorf("Expected spec %+v, got %+v", expected, spec)
+	}
+
+	expectWarnings(t, warnings, map[string]WarningReason{
+		"zone":                           SetOnMachine,
+		"region":                         SetOnCluster,
+		"projectID":                      SetOnCluster,
+		"userDataSecret":                 SetOnMachine,
+		"credentialsSecret":              SetOnCluster,
+		"disks[1].labels":                Unsupported,
+		"disks[1].autoDelete":            Unsupported,
+		"networkInterfaces[0].network":   SetOnCluster,
+		"networkInterfaces[0].projectID": SetOnCluster,
+		"networkInterfaces[1]":           Unsupported,
+		"restartPolicy":                  Unsupported,
+		"gpus":                           Unsupported,
+		"targetPools":                    Unsupported,
+	})
+}
+
+func TestToCAPGImageFamily(t *testing.T) {
+	spec, warnings := ToCAPG(&machinev1.GCPMachineProviderSpec{
+		MachineType: "n2-standard-4",
+		Disks:       []*machinev1.GCPDisk{{AutoDelete: true, Boot: true, Image: "projects/rhcos-cloud/global/images/family/rhcos"}},
+	})
+	if spec.Image != nil || spec.ImageFamily == nil || *spec.ImageFamily != "projects/rhcos-cloud/global/images/family/rhcos" {
+		t.Errorf("Expected the image family to be set, got image %v and image family %v", spec.Image, spec.ImageFamily)
+	}
+	expectWarnings(t, warnings, map[string]WarningReason{})
+
+	_, warnings = ToCAPG(&machinev1.GCPMachineProviderSpec{MachineType: "n2-standard-4"})
+	expectWarnings(t, warnings, map[string]WarningReason{"disks": Lossy})
+}
+
+func TestFromCAPG(t *testing.T) {
+	spot := ProvisioningModelSpot
+	confidential := ConfidentialComputePolicyEnabled
+	ssd := DiskType("pd-ssd")
+	spec := &GCPMachineSpec{
+		InstanceType:          "n2d-standard-4",
+		Subnet:                stringPtr("worker-subnet"),
+		ImageFamily:           stringPtr("projects/rhcos-cloud/global/images/family/rhcos"),
+		AdditionalLabels:      Labels{"team": "infra"},
+		AdditionalNetworkTags: []string{"worker"},
+		RootDeviceType:        &ssd,
+		RootDiskEncryptionKey: &CustomerEncryptionKey{
+			KeyType:    CustomerManagedKey,
+			ManagedKey: &ManagedKey{KMSKeyName: "projects/kms-project/locations/us-east1/keyRings/ring/cryptoKeys/key"},
+		},
+		AdditionalDisks: []AttachedDiskSpec{
+			{},
+			{EncryptionKey: &CustomerEncryptionKey{KeyType: CustomerSuppliedKey, SuppliedKey: &SuppliedKey{RawKey: []byte("key")}}},
+		},
+		ServiceAccount:      &ServiceAccount{Email: "default", Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"}},
+		ProvisioningModel:   &spot,
+		ConfidentialCompute: &confidential,
+	}
+
+	providerSpec, warnings := FromCAPG(spec)
+
+	expected := &machinev1.GCPMachineProviderSpec{
+		MachineType:  "n2d-standard-4",
+		CanIPForward: true,
+		Preemptible:  true,
+		Disks: []*machinev1.GCPDisk{
+			{
+				AutoDelete: true,
+				Boot:       true,
+				SizeGB:     30,
+				Type:       "pd-ssd",
+				Image:      "projects/rhcos-cloud/global/images/family/rhcos",
+				EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+					KMSKey: &machinev1.GCPKMSKeyReference{ProjectID: "kms-project", Location: "us-east1", KeyRing: "ring", Name: "key"},
+				},
+			},
+			{AutoDelete: true, SizeGB: 30, Type: "pd-standard"},
+			{AutoDelete: true, SizeGB: 30, Type: "pd-standard"},
+		},
+		Labels:              map[string]string{"team": "infra"},
+		NetworkInterfaces:   []*machinev1.GCPNetworkInterface{{Subnetwork: "worker-subnet"}},
+		ServiceAccounts:     []machinev1.GCPServiceAccount{{Email: "default", Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"}}},
+		Tags:                []string{"worker"},
+		ConfidentialCompute: machinev1.ConfidentialComputePolicyEnabled,
+	}
+	if !reflect.DeepEqual(providerSpec, expected) {
+		t.Errorf("Expected providerSpec %+v, got %+v", expected, providerSpec)
+	}
+
+	expectWarnings(t, warnings, map[string]WarningReason{
+		"zone":                             SetOnMachine,
+		"region":                           SetOnCluster,
+		"userDataSecret":                   SetOnMachine,
+		"credentialsSecret":                SetOnCluster,
+		"networkInterfaces[0].network":     SetOnCluster,
+		"additionalDisks[1].encryptionKey": Unsupported,
+		"provisioningModel":                Lossy,
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	ipForwarding := IPForwardingDisabled
+	migrate := HostMaintenancePolicyMigrate
+	ssd := DiskType("pd-ssd")
+	size := int64(100)
+	publicIP := true
+	spec := &GCPMachineSpec{
+		InstanceType:           "e2-standard-4",
+		Subnet:                 stringPtr("subnet"),
+		Image:                  stringPtr("projects/rhcos-cloud/global/images/rhcos-415"),
+		AdditionalMetadata:     []MetadataItem{{Key: "key", Value: stringPtr("value")}},
+		PublicIP:               &publicIP,
+		RootDeviceSize:         50,
+		RootDeviceType:         &ssd,
+		AdditionalDisks:        []AttachedDiskSpec{{DeviceType: &ssd, Size: &size}},
+		IPForwarding:           &ipForwarding,
+		OnHostMaintenance:      &migrate,
+		ResourceManagerTags:    ResourceManagerTags{{ParentID: "1234", Key: "env", Value: "prod"}},
+		ShieldedInstanceConfig: &GCPShieldedInstanceConfig{IntegrityMonitoring: VirtualizedIntegrityMonitoringPolicyDisabled},
+	}
+
+	providerSpec, _ := FromCAPG(spec)
+	converted, warnings := ToCAPG(providerSpec)
+	if !reflect.DeepEqual(converted, spec) {
+		t.Errorf("Expected spec %+v after a round trip, got %+v", spec, converted)
+	}
+	expectWarnings(t, warnings, map[string]WarningReason{})
+}
+
+func TestEncryptionKeyFromCAPGInvalidName(t *testing.T) {
+	var w warnings
+	key := encryptionKeyFromCAPG(&CustomerEncryptionKey{KeyType: CustomerManagedKey, ManagedKey: &ManagedKey{KMSKeyName: "key"}}, "rootDiskEncryptionKey", &w)
+	if key != nil {
+		t.Errorf("Expected no key, got %v", key)
+	}
+	expectWarnings(t, w, map[string]WarningReason{"rootDiskEncryptionKey.managedKey.kmsKeyName": Unsupported})
+}
+
+func expectWarnings(t *testing.T, warnings []Warning, expected map[string]WarningReason) {
+	t.Helper()
+	if len(warnings) != len(expected) {
+		t.Errorf("Expected %d warnings, got %d: %v", len(expected), len(warnings), warnings)
+	}
+	for _, warning := range warnings {
+		if reason, ok := expected[warning.Field]; !ok || reason != warning.Reason {
+			t.Errorf("Unexpected warning %s", warning)
+		}
+		if warning.Message == "" {
+			t.Errorf("Expected a message for warning %s", warning.Field)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package capgconversion
+
+// The types below mirror the spec of the GCPMachine of the Cluster API provider for GCP (CAPG),
+// infrastructure.cluster.x-k8s.io/v1beta1, with the same JSON names. CAPG is not a dependency of
+// this provider, only the fields the conversion reads or writes are mirrored.
+
+// IPForwarding allows or disallows the instance to send and receive packets with non-matching
+// destination or source IPs.
+type IPForwarding string
+
+const (
+	IPForwardingEnabled  IPForwarding = "Enabled"
+	IPForwardingDisabled IPForwarding = "Disabled"
+)
+
+// ProvisioningModel is the provisioning model of the instance.
+type ProvisioningModel string
+
+const (
+	ProvisioningModelStandard ProvisioningModel = "Standard"
+	ProvisioningModelSpot     ProvisioningModel = "Spot"
+)
+
+// HostMaintenancePolicy is the behavior of the instance when the host is under maintenance.
+type HostMaintenancePolicy string
+
+const (
+	HostMaintenancePolicyMigrate   HostMaintenancePolicy = "Migrate"
+	HostMaintenancePolicyTerminate HostMaintenancePolicy = "Terminate"
+)
+
+// ConfidentialComputePolicy enables or disables Confidential VM.
+type ConfidentialComputePolicy string
+
+const (
+	ConfidentialComputePolicyEnabled  ConfidentialComputePolicy = "Enabled"
+	ConfidentialComputePolicyDisabled ConfidentialComputePolicy = "Disabled"
+)
+
+// SecureBootPolicy, VirtualizedTrustedPlatformModulePolicy and VirtualizedIntegrityMonitoringPolicy
+// enable or disable the features of Shielded VM.
+type (
+	SecureBootPolicy                       string
+	VirtualizedTrustedPlatformModulePolicy string
+	VirtualizedIntegrityMonitoringPolicy   string
+)
+
+const (
+	SecureBootPolicyEnabled                        SecureBootPolicy                       = "Enabled"
+	SecureBootPolicyDisabled                       SecureBootPolicy                       = "Disabled"
+	VirtualizedTrustedPlatformModulePolicyEnabled  VirtualizedTrustedPlatformModulePolicy = "Enabled"
+	VirtualizedTrustedPlatformModulePolicyDisabled VirtualizedTrustedPlatformModulePolicy = "Disabled"
+	VirtualizedIntegrityMonitoringPolicyEnabled    VirtualizedIntegrityMonitoringPolicy   = "Enabled"
+	VirtualizedIntegrityMonitoringPolicyDisabled   VirtualizedIntegrityMonitoringPolicy   = "Disabled"
+)
+
+// KeyType is the type of the key encrypting a disk.
+type KeyType string
+
+const (
+	// CustomerManagedKey is a key of Cloud KMS.
+	CustomerManagedKey KeyType = "Managed"
+	// CustomerSuppliedKey is a raw or RSA wrapped key supplied with the request.
+	CustomerSuppliedKey KeyType = "Supplied"
+)
+
+// GCPMachineSpec is the spec of a CAPG GCPMachine.
+type GCPMachineSpec struct {
+	InstanceType           string                     `json:"instanceType"`
+	Subnet                 *string                    `json:"subnet,omitempty"`
+	ProviderID             *string                    `json:"providerID,omitempty"`
+	ImageFamily            *string                    `json:"imageFamily,omitempty"`
+	Image                  *string                    `json:"image,omitempty"`
+	AdditionalLabels       Labels                     `json:"additionalLabels,omitempty"`
+	AdditionalMetadata     []MetadataItem             `json:"additionalMetadata,omitempty"`
+	PublicIP               *bool                      `json:"publicIP,omitempty"`
+	AdditionalNetworkTags  []string                   `json:"additionalNetworkTags,omitempty"`
+	ResourceManagerTags    ResourceManagerTags        `json:"resourceManagerTags,omitempty"`
+	RootDeviceSize         int64                      `json:"rootDeviceSize,omitempty"`
+	RootDeviceType         *DiskType                  `json:"rootDeviceType,omitempty"`
+	AdditionalDisks        []AttachedDiskSpec         `json:"additionalDisks,omitempty"`
+	ServiceAccount         *ServiceAccount            `json:"serviceAccounts,omitempty"`
+	Preemptible            bool                       `json:"preemptible,omitempty"`
+	ProvisioningModel      *ProvisioningModel         `json:"provisioningModel,omitempty"`
+	IPForwarding           *IPForwarding              `json:"ipForwarding,omitempty"`
+	ShieldedInstanceConfig *GCPShieldedInstanceConfig `json:"shieldedInstanceConfig,omitempty"`
+	OnHostMaintenance      *HostMaintenancePolicy     `json:"onHostMaintenance,omitempty"`
+	ConfidentialCompute    *ConfidentialComputePolicy `json:"confidentialCompute,omitempty"`
+	RootDiskEncryptionKey  *CustomerEncryptionKey     `json:"rootDiskEncryptionKey,omitempty"`
+}
+
+// Labels are the labels of the instance.
+type Labels map[string]string
+
+// DiskType is the type of a persistent disk, e.g. pd-ssd.
+type DiskType string
+
+// MetadataItem is a metadata entry of the instance.
+type MetadataItem struct {
+	Key   string  `json:"key"`
+	Value *string `json:"value,omitempty"`
+}
+
+// ResourceManagerTags are the tags of the instance, in the tag hierarchy of the Resource Manager.
+type ResourceManagerTags []ResourceManagerTag
+
+// ResourceManagerTag is a tag of the Resource Manager.
+type ResourceManagerTag struct {
+	ParentID string `json:"parentID"`
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+}
+
+// AttachedDiskSpec is an additional disk of the instance, created empty.
+type AttachedDiskSpec struct {
+	DeviceType    *DiskType              `json:"deviceType,omitempty"`
+	Size          *int64                 `json:"size,omitempty"`
+	EncryptionKey *CustomerEncryptionKey `json:"encryptionKey,omitempty"`
+}
+
+// ServiceAccount is the service account of the instance.
+type ServiceAccount struct {
+	Email  string   `json:"email,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// GCPShieldedInstanceConfig is the Shielded VM configuration of the instance.
+type GCPShieldedInstanceConfig struct {
+	SecureBoot                       SecureBootPolicy                       `json:"secureBoot,omitempty"`
+	VirtualizedTrustedPlatformModule VirtualizedTrustedPlatformModulePolicy `json:"virtualizedTrustedPlatformModule,omitempty"`
+	IntegrityMonitoring              VirtualizedIntegrityMonitoringPolicy   `json:"integrityMonitoring,omitempty"`
+}
+
+// CustomerEncryptionKey is the key encrypting a disk.
+type CustomerEncryptionKey struct {
+	KeyType           KeyType      `json:"keyType"`
+	KeyServiceAccount *string      `json:"keyServiceAccount,omitempty"`
+	ManagedKey        *ManagedKey  `json:"managedKey,omitempty"`
+	SuppliedKey       *SuppliedKey `json:"suppliedKey,omitempty"`
+}
+
+// ManagedKey is a key of Cloud KMS, by its full resource name,
+// projects/project/locations/location/keyRings/keyRing/cryptoKeys/key.
+type ManagedKey struct {
+	KMSKeyName string `json:"kmsKeyName,omitempty"`
+}
+
+// SuppliedKey is a key supplied with the request.
+type SuppliedKey struct {
+	RawKey          []byte `json:"rawKey,omitempty"`
+	RSAEncryptedKey []byte `json:"rsaEncryptedKey,omitempty"`
+}
@@ -0,0 +1,168 @@
+// Package debugconfig applies debug settings of the controller from a ConfigMap at runtime, so that
+// the log verbosity can be raised and compute API requests dumped while diagnosing an issue
+// without restarting the controller, which often makes flaky provisioning issues disappear.
+package debugconfig
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// LogLevelKey is the ConfigMap key of the klog verbosity, e.g. "4".
+	LogLevelKey = "logLevel"
+	// DumpAPIRequestsKey is the ConfigMap key that enables dumping the compute API requests and
+	// responses to the log when "true".
+	DumpAPIRequestsKey = "dumpAPIRequests"
+
+	// DefaultInterval is how often the ConfigMap is read by default.
+	DefaultInterval = 10 * time.Second
+
+	maxLogLevel = 10
+)
+
+// settings are the debug settings read from the ConfigMap.
+type settings struct {
+	logLevel        int
+	dumpAPIRequests bool
+}
+
+// Watcher periodically reads the debug ConfigMap and applies its settings. Keys that are missing,
+// including when the ConfigMap is deleted, restore the settings the controller was started with.
+// Invalid values are logged and keep the current setting.
+type Watcher struct {
+	// Reader reads the ConfigMap. An uncached reader avoids caching all ConfigMaps of the namespace.
+	Reader    client.Reader
+	Namespace string
+	Name      string
+	// Interval is how often the ConfigMap is read, DefaultInterval when zero.
+	Interval time.Duration
+
+	// setLogLevel and setRequestDumping apply the settings, they are replaced in tests.
+	setLogLevel       func(int) error
+	setRequestDumping func(bool)
+
+	once     sync.Once
+	defaults settings
+	applied  settings
+}
+
+// SetupWithManager runs the watcher with the manager.
+func (w *Watcher) SetupWithManager(mgr ctrl.Manager) error {
+	if w.Name == "" || w.Namespace == "" {
+		return fmt.Errorf("debug ConfigMap namespace and name must be set")
+	}
+	return mgr.Add(w)
+}
+
+// NeedLeaderElection returns false, the settings apply to every replica of the controller.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start reads the ConfigMap every interval until the context is done.
+func (w *Watcher) Start(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	klog.Infof("Watching ConfigMap %s/%s for debug settings", w.Namespace, w.Name)
+	wait.UntilWithContext(ctx, w.sync, interval)
+	return nil
+}
+
+func (w *Watcher) init() {
+	w.once.Do(func() {
+		if w.setLogLevel == nil {
+			w.setLogLevel = setKlogVerbosity
+		}
+		if w.setRequestDumping == nil {
+			w.setRequestDumping = computeservice.SetRequestDumping
+		}
+		w.defaults = settings{logLevel: klogVerbosity()}
+		w.applied = w.defaults
+	})
+}
+
+// sync reads the ConfigMap and applies the settings that changed since the last sync.
+func (w *Watcher) sync(ctx context.Context) {
+	w.init()
+
+	configMap := &corev1.ConfigMap{}
+	err := w.Reader.Get(ctx, client.ObjectKey{Namespace: w.Namespace, Name: w.Name}, configMap)
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("Failed to read debug ConfigMap %s/%s: %v", w.Namespace, w.Name, err)
+		return
+	}
+
+	desired := w.desired(configMap.Data)
+	if desired.logLevel != w.applied.logLevel {
+		if err := w.setLogLevel(desired.logLevel); err != nil {
+			klog.Errorf("Failed to set log level to %d: %v", desired.logLevel, err)
+		} else {
+			klog.Infof("Log level changed from %d to %d", w.applied.logLevel, desired.logLevel)
+			w.applied.logLevel = desired.logLevel
+		}
+	}
+	if desired.dumpAPIRequests != w.applied.dumpAPIRequests {
+		w.setRequestDumping(desired.dumpAPIRequests)
+		klog.Infof("Dumping of compute API requests set to %t", desired.dumpAPIRequests)
+		w.applied.dumpAPIRequests = desired.dumpAPIRequests
+	}
+}
+
+// desired returns the settings of the ConfigMap data. Missing keys fall back to the defaults and
+// invalid values to the applied settings.
+func (w *Watcher) desired(data map[string]string) settings {
+	desired := w.defaults
+	if value, ok := data[LogLevelKey]; ok {
+		level, err := strconv.Atoi(value)
+		if err != nil || level < 0 || level > maxLogLevel {
+			klog.Errorf("Ignoring invalid %s %q of debug ConfigMap %s/%s, must be between 0 and %d", LogLevelKey, value, w.Namespace, w.Name, maxLogLevel)
+			desired.logLevel = w.applied.logLevel
+		} else {
+			desired.logLevel = level
+		}
+	}
+	if value, ok := data[DumpAPIRequestsKey]; ok {
+		dump, err := strconv.ParseBool(value)
+		if err != nil {
+			klog.Errorf("Ignoring invalid %s %q of debug ConfigMap %s/%s, must be true or false", DumpAPIRequestsKey, value, w.Namespace, w.Name)
+			desired.dumpAPIRequests = w.applied.dumpAPIRequests
+		} else {
+			desired.dumpAPIRequests = dump
+		}
+	}
+	return desired
+}
+
+// klogFlags gives access to the global klog settings, whichever flag set klog was initialized with.
+var klogFlags = func() *flag.FlagSet {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	return fs
+}()
+
+func klogVerbosity() int {
+	level, err := strconv.Atoi(klogFlags.Lookup("v").Value.String())
+	if err != nil {
+		return 0
+	}
+	return level
+}
+
+func setKlogVerbosity(level int) error {
+	return klogFlags.Set("v", strconv.Itoa(level))
+}
@@ -0,0 +1,106 @@
+package debugconfig
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWatcherSync(t *testing.T) {
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-machine-api", Name: "debug"}}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+	logLevel, dumping := 2, false
+	w := &Watcher{
+		Reader:    c,
+		Namespace: configMap.Namespace,
+		Name:      configMap.Name,
+		setLogLevel: func(level int) error {
+			logLevel = level
+			return nil
+		},
+		setRequestDumping: func(enabled bool) {
+			dumping = enabled
+		},
+	}
+	w.once.Do(func() {
+		w.defaults = settings{logLevel: 2}
+		w.applied = w.defaults
+	})
+
+	steps := []struct {
+		name             string
+		data             map[string]string
+		deleted          bool
+		expectedLogLevel int
+		expectedDumping  bool
+	}{
+		{
+			name:             "Missing ConfigMap keeps the defaults",
+			deleted:          true,
+			expectedLogLevel: 2,
+		},
+		{
+			name:             "Settings are applied",
+			data:             map[string]string{LogLevelKey: "6", DumpAPIRequestsKey: "true"},
+			expectedLogLevel: 6,
+			expectedDumping:  true,
+		},
+		{
+			name:             "Invalid values keep the current settings",
+			data:             map[string]string{LogLevelKey: "verbose", DumpAPIRequestsKey: "maybe"},
+			expectedLogLevel: 6,
+			expectedDumping:  true,
+		},
+		{
+			name:             "Out of range log level is ignored",
+			data:             map[string]string{LogLevelKey: "11", DumpAPIRequestsKey: "false"},
+			expectedLogLevel: 6,
+		},
+		{
+			name:             "Removed keys restore the defaults",
+			data:             map[string]string{DumpAPIRequestsKey: "true"},
+			expectedLogLevel: 2,
+			expectedDumping:  true,
+		},
+		{
+			name:             "Deleted ConfigMap restores the defaults",
+			deleted:          true,
+			expectedLogLevel: 2,
+		},
+	}
+
+	ctx := context.Background()
+	for _, step := range steps {
+		existing := &corev1.ConfigMap{}
+		exists := c.Get(ctx, client.ObjectKeyFromObject(configMap), existing) == nil
+		switch {
+		case step.deleted && exists:
+			if err := c.Delete(ctx, existing); err != nil {
+				t.Fatal(err)
+			}
+		case !step.deleted && exists:
+			existing.Data = step.data
+			if err := c.Update(ctx, existing); err != nil {
+				t.Fatal(err)
+			}
+		case !step.deleted:
+			created := configMap.DeepCopy()
+			created.Data = step.data
+			if err := c.Create(ctx, created); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		w.sync(ctx)
+
+		if logLevel != step.expectedLogLevel || dumping != step.expectedDumping {
+			t.Errorf("%s: expected log level %d and dumping %t, got %d and %t", step.name, step.expectedLogLevel, step.expectedDumping, logLevel, dumping)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package drexport rewrites the MachineSets of a cluster for another region, to rehearse disaster
+// recovery there with the same machine configuration.
+package drexport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	machineSetLabel = "machine.openshift.io/cluster-api-machineset"
+
+	// fallbackZonesAnnotation lists the zones the machine falls back to, it is rewritten like the zone.
+	fallbackZonesAnnotation = "machine.openshift.io/gcp-fallback-zones"
+)
+
+// Mapping describes how the resources of the source region map to the target region. Networks are
+// global and only need to be mapped when the target region uses a different one.
+type Mapping struct {
+	// Region is the target region.
+	Region string `json:"region"`
+	// ProjectID, if set, replaces the project of the machines.
+	ProjectID string `json:"projectID,omitempty"`
+	// Zones maps every zone used by the MachineSets to a zone of the target region.
+	Zones map[string]string `json:"zones"`
+	// Networks, Subnetworks and TargetPools map names of the source region, names not listed are kept.
+	Networks    map[string]string `json:"networks,omitempty"`
+	Subnetworks map[string]string `json:"subnetworks,omitempty"`
+	TargetPools map[string]string `json:"targetPools,omitempty"`
+}
+
+// LoadMapping reads a mapping from a YAML or JSON file.
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping: %w", err)
+	}
+	mapping := &Mapping{}
+	if err := yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, fmt.Errorf("invalid mapping %s: %w", path, err)
+	}
+	if mapping.Region == "" {
+		return nil, fmt.Errorf("invalid mapping %s: region is required", path)
+	}
+	return mapping, nil
+}
+
+// Export writes the MachineSets of the namespace, rewritten for the target region, as a YAML stream
+// that can be applied to the cluster in the target region.
+func Export(ctx context.Context, c client.Client, namespace string, mapping *Mapping, w io.Writer) error {
+	machineSets := &machinev1.MachineSetList{}
+	if err := c.List(ctx, machineSets, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list machine sets: %w", err)
+	}
+	sort.Slice(machineSets.Items, func(i, j int) bool { return machineSets.Items[i].Name < machineSets.Items[j].Name })
+
+	for i := range machineSets.Items {
+		rewritten, err := Rewrite(&machineSets.Items[i], mapping)
+		if err != nil {
+			return fmt.Errorf("machine set %s: %w", machineSets.Items[i].Name, err)
+		}
+		data, err := yaml.Marshal(rewritten)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewrite returns a copy of the MachineSet for the target region, without the status and the
+// metadata set by the API server. MachineSets named after their zone are renamed after the target
+// zone, the others get the target zone as suffix.
+func Rewrite(machineSet *machinev1.MachineSet, mapping *Mapping) (*machinev1.MachineSet, error) {
+	providerSpec, err := util.ProviderSpecFromRawExtension(machineSet.Spec.Template.Spec.ProviderSpec.Value)
+	if err != nil {
+		return nil, err
+	}
+	zone, ok := mapping.Zones[providerSpec.Zone]
+	if !ok {
+		return nil, fmt.Errorf("zone %s is not mapped to a zone of region %s", providerSpec.Zone, mapping.Region)
+	}
+	sourceZone := providerSpec.Zone
+
+	providerSpec.Region = mapping.Region
+	providerSpec.Zone = zone
+	if mapping.ProjectID != "" {
+		providerSpec.ProjectID = mapping.ProjectID
+	}
+	for _, nic := range providerSpec.NetworkInterfaces {
+		nic.Network = mapName(mapping.Networks, nic.Network)
+		nic.Subnetwork = mapName(mapping.Subnetworks, nic.Subnetwork)
+	}
+	for i, pool := range providerSpec.TargetPools {
+		providerSpec.TargetPools[i] = mapName(mapping.TargetPools, pool)
+	}
+	providerSpecValue, err := util.RawExtensionFromProviderSpec(providerSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ReplaceAll(machineSet.Name, sourceZone, zone)
+	if name == machineSet.Name {
+		name = fmt.Sprintf("%s-%s", machineSet.Name, zone)
+	}
+	rewritten := &machinev1.MachineSet{
+		TypeMeta: metav1.TypeMeta{APIVersion: machinev1.GroupVersion.String(), Kind: "MachineSet"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   machineSet.Namespace,
+			Labels:      machineSet.Labels,
+			Annotations: machineSet.Annotations,
+		},
+		Spec: *machineSet.Spec.DeepCopy(),
+	}
+	rewritten.Spec.Template.Spec.ProviderSpec.Value = providerSpecValue
+	renameLabel(rewritten.Spec.Selector.MatchLabels, machineSet.Name, name)
+	renameLabel(rewritten.Spec.Template.Labels, machineSet.Name, name)
+
+	if value, ok := rewritten.Spec.Template.Annotations[fallbackZonesAnnotation]; ok {
+		var fallbacks []string
+		for _, fallback := range strings.Split(value, ",") {
+			fallback = strings.TrimSpace(fallback)
+			target, ok := mapping.Zones[fallback]
+			if !ok {
+				return nil, fmt.Errorf("fallback zone %s is not mapped to a zone of region %s", fallback, mapping.Region)
+			}
+			fallbacks = append(fallbacks, target)
+		}
+		rewritten.Spec.Template.Annotations[fallbackZonesAnnotation] = strings.Join(fallbacks, ",")
+	}
+	return rewritten, nil
+}
+
+func mapName(names map[string]string, name string) string {
+	if mapped, ok := names[name]; ok {
+		return mapped
+	}
+	return name
+}
+
+// renameLabel points the MachineSet label at the renamed MachineSet.
+func renameLabel(labels map[string]string, from, to string) {
+	if labels[machineSetLabel] == from {
+		labels[machineSetLabel] = to
+	}
+}
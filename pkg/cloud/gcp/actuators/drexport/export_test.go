@@ -0,0 +1,117 @@
+package drexport
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newMachineSet(t *testing.T, name, zone string) *machinev1.MachineSet {
+	providerSpec, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+		Region:      "us-east1",
+		Zone:        zone,
+		ProjectID:   "source",
+		MachineType: "n2-standard-4",
+		NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+			{Network: "cluster-network", Subnetwork: "cluster-worker-subnet"},
+		},
+		TargetPools: []string{"cluster-ingress"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]string{machineSetLabel: name}
+	return &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-machine-api", UID: types.UID(name), ResourceVersion: "42"},
+		Spec: machinev1.MachineSetSpec{
+			Selector: metav1.LabelSelector{MatchLabels: labels},
+			Template: machinev1.MachineTemplateSpec{
+				ObjectMeta: machinev1.ObjectMeta{
+					Labels:      map[string]string{machineSetLabel: name},
+					Annotations: map[string]string{fallbackZonesAnnotation: "us-east1-c, us-east1-d"},
+				},
+				Spec: machinev1.MachineSpec{ProviderSpec: machinev1.ProviderSpec{Value: providerSpec}},
+			},
+		},
+		Status: machinev1.MachineSetStatus{Replicas: 3},
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	mapping := &Mapping{
+		Region:      "us-west1",
+		ProjectID:   "target",
+		Zones:       map[string]string{"us-east1-b": "us-west1-a", "us-east1-c": "us-west1-b", "us-east1-d": "us-west1-c"},
+		Subnetworks: map[string]string{"cluster-worker-subnet": "dr-worker-subnet"},
+	}
+
+	rewritten, err := Rewrite(newMachineSet(t, "cluster-worker-us-east1-b", "us-east1-b"), mapping)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rewritten.Name != "cluster-worker-us-west1-a" || rewritten.UID != "" || rewritten.ResourceVersion != "" || rewritten.Status.Replicas != 0 {
+		t.Errorf("Expected a new MachineSet named after the target zone, got %+v", rewritten.ObjectMeta)
+	}
+	if rewritten.Spec.Selector.MatchLabels[machineSetLabel] != rewritten.Name || rewritten.Spec.Template.Labels[machineSetLabel] != rewritten.Name {
+		t.Errorf("Expected the MachineSet labels to be renamed, got %v and %v", rewritten.Spec.Selector.MatchLabels, rewritten.Spec.Template.Labels)
+	}
+	if fallbacks := rewritten.Spec.Template.Annotations[fallbackZonesAnnotation]; fallbacks != "us-west1-b,us-west1-c" {
+		t.Errorf("Expected the fallback zones to be mapped, got %q", fallbacks)
+	}
+	providerSpec, err := util.ProviderSpecFromRawExtension(rewritten.Spec.Template.Spec.ProviderSpec.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if providerSpec.Region != "us-west1" || providerSpec.Zone != "us-west1-a" || providerSpec.ProjectID != "target" {
+		t.Errorf("Expected region us-west1, zone us-west1-a and project target, got %s, %s and %s", providerSpec.Region, providerSpec.Zone, providerSpec.ProjectID)
+	}
+	if nic := providerSpec.NetworkInterfaces[0]; nic.Network != "cluster-network" || nic.Subnetwork != "dr-worker-subnet" {
+		t.Errorf("Expected the subnetwork to be mapped and the network to be kept, got %+v", nic)
+	}
+
+	if _, err := Rewrite(newMachineSet(t, "cluster-worker-us-east1-e", "us-east1-e"), mapping); err == nil {
+		t.Errorf("Expected an error for an unmapped zone")
+	}
+}
+
+func TestExport(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := controllerfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newMachineSet(t, "infra", "us-east1-c"),
+		newMachineSet(t, "cluster-worker-us-east1-b", "us-east1-b"),
+	).Build()
+
+	mappingPath := filepath.Join(t.TempDir(), "mapping.yaml")
+	if err := os.WriteFile(mappingPath, []byte("region: us-west1\nzones:\n  us-east1-b: us-west1-a\n  us-east1-c: us-west1-b\n  us-east1-d: us-west1-c\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := LoadMapping(mappingPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := Export(context.Background(), c, "openshift-machine-api", mapping, out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	documents := strings.Split(strings.TrimPrefix(out.String(), "---\n"), "---\n")
+	if len(documents) != 2 || !strings.Contains(documents[0], "name: cluster-worker-us-west1-a") || !strings.Contains(documents[1], "name: infra-us-west1-b") {
+		t.Errorf("Expected the two MachineSets sorted by name, got:\n%s", out.String())
+	}
+	if !strings.Contains(documents[0], "kind: MachineSet") {
+		t.Errorf("Expected the documents to have a kind, got:\n%s", documents[0])
+	}
+}
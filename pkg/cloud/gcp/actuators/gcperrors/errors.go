@@ -0,0 +1,229 @@
+// Package gcperrors classifies the errors returned by the GCP APIs, so that the actuator handles
+// them consistently: retryable failures are requeued, failures caused by the machine configuration
+// fail the machine, and every classified failure is surfaced with a distinct condition reason.
+package gcperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// Reason is the class of a GCP API failure. It is used as condition reason.
+type Reason string
+
+const (
+	// QuotaExceeded is returned when a project or regional quota does not allow the request.
+	QuotaExceeded Reason = "QuotaExceeded"
+	// RateLimitExceeded is returned when the API rate limits of the project are exceeded.
+	RateLimitExceeded Reason = "RateLimitExceeded"
+	// ResourceNotReady is returned when a resource the request depends on is still being changed.
+	ResourceNotReady Reason = "ResourceNotReady"
+	// ZoneResourcesExhausted is returned when the zone does not have the resources for the request.
+	ZoneResourcesExhausted Reason = "ZoneResourcesExhausted"
+	// Forbidden is returned when the credentials are not allowed to make the request.
+	Forbidden Reason = "Forbidden"
+	// NotFound is returned when the resource of the request does not exist.
+	NotFound Reason = "NotFound"
+	// InvalidRequest is returned when the request itself is invalid.
+	InvalidRequest Reason = "InvalidRequest"
+	// BackendError is returned when the API failed to serve a valid request.
+	BackendError Reason = "BackendError"
+)
+
+const (
+	googleAPIErrorPrefix  = "googleapi: "
+	rateLimitRequeueAfter = 60 * time.Second
+	retryRequeueAfter     = 20 * time.Second
+	// maxRequeueAfter caps the delays requested by the API, so that a bogus Retry-After does not
+	// park a machine for hours.
+	maxRequeueAfter = 10 * time.Minute
+	retryInfoType   = "type.googleapis.com/google.rpc.RetryInfo"
+)
+
+// markers identify the classes in error reasons, operation error codes and messages. They are
+// checked in order: API rate limits are reported as exceeded quotas of queries, and the more
+// specific classes win over Forbidden.
+var markers = []struct {
+	reason  Reason
+	markers []string
+}{
+	{ZoneResourcesExhausted, []string{"ZONE_RESOURCE_POOL_EXHAUSTED", "resourcePoolExhausted", "does not have enough resources available"}},
+	{RateLimitExceeded, []string{"rateLimitExceeded", "userRateLimitExceeded", "RATE_LIMIT_EXCEEDED", "Rate Limit Exceeded"}},
+	{QuotaExceeded, []string{"QUOTA_EXCEEDED", "quotaExceeded", "Quota exceeded"}},
+	{ResourceNotReady, []string{"resourceNotReady", "RESOURCE_NOT_READY"}},
+	{BackendError, []string{"backendError", "internalError"}},
+	{Forbidden, []string{"forbidden", "accessNotConfigured", "insufficientPermissions", "PERMISSION_DENIED"}},
+}
+
+// Error is a GCP API failure with its class.
+type Error struct {
+	Reason Reason
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the class of a GCP API failure. The googleapi error is looked up in the chain of
+// err, errors that lost it when they were wrapped are classified by their message. It returns false
+// when err is not recognized as a GCP API failure.
+func Classify(err error) (Reason, bool) {
+	if err == nil {
+		return "", false
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Reason, true
+	}
+
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		// Only the messages of googleapi errors are classified, other errors, e.g. of the
+		// Kubernetes API, may contain the same words.
+		if message := err.Error(); strings.Contains(message, googleAPIErrorPrefix) {
+			return classifyMessage(message)
+		}
+		return "", false
+	}
+
+	for _, item := range apiErr.Errors {
+		if reason, ok := classifyMessage(item.Reason); ok {
+			return reason, true
+		}
+	}
+	if reason, ok := classifyMessage(apiErr.Message); ok {
+		return reason, true
+	}
+	switch {
+	case apiErr.Code == http.StatusNotFound:
+		return NotFound, true
+	case apiErr.Code == http.StatusTooManyRequests:
+		return RateLimitExceeded, true
+	case apiErr.Code == http.StatusForbidden || apiErr.Code == http.StatusUnauthorized:
+		return Forbidden, true
+	case apiErr.Code >= 400 && apiErr.Code < 500:
+		return InvalidRequest, true
+	case apiErr.Code >= 500:
+		return BackendError, true
+	}
+	return "", false
+}
+
+// ClassifyOperation returns the class of the first error of a failed operation.
+func ClassifyOperation(operation *compute.Operation) (Reason, string, bool) {
+	if operation == nil || operation.Error == nil {
+		return "", "", false
+	}
+	for _, operationError := range operation.Error.Errors {
+		message := fmt.Sprintf("%s: %s", operationError.Code, operationError.Message)
+		if reason, ok := classifyMessage(message); ok {
+			return reason, message, true
+		}
+	}
+	return "", "", false
+}
+
+func classifyMessage(message string) (Reason, bool) {
+	for _, class := range markers {
+		for _, marker := range class.markers {
+			if strings.Contains(message, marker) {
+				return class.reason, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Is returns true if err is a GCP API failure of the given class.
+func Is(err error, reason Reason) bool {
+	classified, ok := Classify(err)
+	return ok && classified == reason
+}
+
+// IsRetryable returns true if a request that failed with the class is expected to succeed when it is retried later.
+func IsRetryable(reason Reason) bool {
+	switch reason {
+	case RateLimitExceeded, ResourceNotReady, ZoneResourcesExhausted, BackendError:
+		return true
+	}
+	return false
+}
+
+// RequeueIfRetryable classifies a GCP API failure and requeues the machine when the failure is
+// retryable, backing off longer when the API rate limits are exceeded. The delay the API asked for
+// in the Retry-After header or the RetryInfo details of the error wins over both backoffs. Errors that are not
+// classified or already requeue the machine are returned as is.
+func RequeueIfRetryable(err error) error {
+	reason, ok := Classify(err)
+	var requeueErr *machinecontroller.RequeueAfterError
+	if !ok || errors.As(err, &requeueErr) {
+		return err
+	}
+	if IsRetryable(reason) {
+		requeueAfter := retryRequeueAfter
+		if reason == RateLimitExceeded {
+			requeueAfter = rateLimitRequeueAfter
+		}
+		if retryAfter, ok := RetryAfter(err); ok {
+			requeueAfter = min(retryAfter, maxRequeueAfter)
+		}
+		err = fmt.Errorf("%w: %w", err, &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfter})
+	}
+	return &Error{Reason: reason, Err: err}
+}
+
+// RetryAfter returns the delay the API asked for before the request is retried, from the
+// Retry-After header of the response or the RetryInfo details of the error. It returns false when
+// err is not a googleapi error or the API did not ask for a delay.
+func RetryAfter(err error) (time.Duration, bool) {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+	if header := apiErr.Header.Get("Retry-After"); header != "" {
+		if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(header); err == nil {
+			if delay := time.Until(date); delay > 0 {
+				return delay, true
+			}
+		}
+	}
+	for _, detail := range apiErr.Details {
+		info, ok := detail.(map[string]interface{})
+		if !ok || info["@type"] != retryInfoType {
+			continue
+		}
+		retryDelay, _ := info["retryDelay"].(string)
+		if delay, err := time.ParseDuration(retryDelay); err == nil && delay > 0 {
+			return delay, true
+		}
+	}
+	return 0, false
+}
+
+// ToMachineError converts a GCP API failure of a creation to the error the machine controller
+// expects. Retryable failures are requeued like in RequeueIfRetryable. All other failures are
+// client errors, including quota and permission errors, and are invalid machine configurations,
+// which by convention 4xx errors signal, see https://tools.ietf.org/html/rfc2616#section-6.1.1.
+func ToMachineError(err error) error {
+	reason, ok := Classify(err)
+	if !ok || IsRetryable(reason) {
+		return RequeueIfRetryable(err)
+	}
+	return &Error{Reason: reason, Err: machinecontroller.InvalidMachineConfiguration("%v", err)}
+}
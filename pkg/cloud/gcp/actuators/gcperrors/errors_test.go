@@ -0,0 +1,272 @@
+package gcperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		name           string
+		err            error
+		expectedReason Reason
+		expectedOK     bool
+	}{
+		{
+			name: "Not a GCP API error",
+			err:  errors.New("secrets \"worker-user-data\" is forbidden"),
+		},
+		{
+			name:           "Quota exceeded",
+			err:            &googleapi.Error{Code: 403, Message: "Quota 'CPUS' exceeded. Limit: 24.0 in region us-east1.", Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}},
+			expectedReason: QuotaExceeded,
+			expectedOK:     true,
+		},
+		{
+			name:           "Rate limit exceeded is not a permission error",
+			err:            &googleapi.Error{Code: 403, Message: "Rate Limit Exceeded", Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}},
+			expectedReason: RateLimitExceeded,
+			expectedOK:     true,
+		},
+		{
+			name:           "Too many requests",
+			err:            &googleapi.Error{Code: 429},
+			expectedReason: RateLimitExceeded,
+			expectedOK:     true,
+		},
+		{
+			name:           "Resource not ready",
+			err:            &googleapi.Error{Code: 400, Message: "The resource 'projects/p/global/networks/n' is not ready", Errors: []googleapi.ErrorItem{{Reason: "resourceNotReady"}}},
+			expectedReason: ResourceNotReady,
+			expectedOK:     true,
+		},
+		{
+			name:           "Zone out of resources",
+			err:            &googleapi.Error{Code: 503, Message: "The zone 'projects/p/zones/us-east1-b' does not have enough resources available to fulfill the request."},
+			expectedReason: ZoneResourcesExhausted,
+			expectedOK:     true,
+		},
+		{
+			name:           "Forbidden",
+			err:            &googleapi.Error{Code: 403, Message: "Required 'compute.instances.create' permission", Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}},
+			expectedReason: Forbidden,
+			expectedOK:     true,
+		},
+		{
+			name:           "Not found",
+			err:            &googleapi.Error{Code: 404},
+			expectedReason: NotFound,
+			expectedOK:     true,
+		},
+		{
+			name:           "Invalid request",
+			err:            &googleapi.Error{Code: 400, Message: "Invalid value for field 'resource.machineType'"},
+			expectedReason: InvalidRequest,
+			expectedOK:     true,
+		},
+		{
+			name:           "Backend error",
+			err:            &googleapi.Error{Code: 500},
+			expectedReason: BackendError,
+			expectedOK:     true,
+		},
+		{
+			name:           "Wrapped googleapi error",
+			err:            fmt.Errorf("failed to delete instance via compute service: %w", &googleapi.Error{Code: 429}),
+			expectedReason: RateLimitExceeded,
+			expectedOK:     true,
+		},
+		{
+			name:           "Message of a googleapi error that lost its type",
+			err:            fmt.Errorf("failed to get zone: %v", &googleapi.Error{Code: 403, Message: "Quota exceeded for quota metric 'Queries'", Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}),
+			expectedReason: RateLimitExceeded,
+			expectedOK:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, ok := Classify(tc.err)
+			if reason != tc.expectedReason || ok != tc.expectedOK {
+				t.Errorf("Expected %q, %v, got %q, %v", tc.expectedReason, tc.expectedOK, reason, ok)
+			}
+		})
+	}
+}
+
+func TestClassifyOperation(t *testing.T) {
+	operation := &compute.Operation{
+		Status: "DONE",
+		Error: &compute.OperationError{Errors: []*compute.OperationErrorErrors{
+			{Code: "ZONE_RESOURCE_POOL_EXHAUSTED", Message: "The zone does not have enough resources available"},
+		}},
+	}
+	reason, message, ok := ClassifyOperation(operation)
+	if !ok || reason != ZoneResourcesExhausted {
+		t.Errorf("Expected %s, got %q, %v", ZoneResourcesExhausted, reason, ok)
+	}
+	if expected := "ZONE_RESOURCE_POOL_EXHAUSTED: The zone does not have enough resources available"; message != expected {
+		t.Errorf("Expected message %q, got %q", expected, message)
+	}
+
+	if _, _, ok := ClassifyOperation(&compute.Operation{Status: "DONE"}); ok {
+		t.Errorf("Expected a successful operation not to be classified")
+	}
+}
+
+func TestToMachineError(t *testing.T) {
+	cases := []struct {
+		name            string
+		err             error
+		expectedRequeue bool
+		expectedInvalid bool
+	}{
+		{
+			name: "Unclassified errors are returned as is",
+			err:  errors.New("boom"),
+		},
+		{
+			name:            "Rate limits are requeued",
+			err:             &googleapi.Error{Code: 429},
+			expectedRequeue: true,
+		},
+		{
+			name:            "Backend errors are requeued",
+			err:             &googleapi.Error{Code: 503},
+			expectedRequeue: true,
+		},
+		{
+			name:            "Quota errors are invalid configurations",
+			err:             &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}},
+			expectedInvalid: true,
+		},
+		{
+			name:            "Invalid requests are invalid configurations",
+			err:             &googleapi.Error{Code: 400},
+			expectedInvalid: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ToMachineError(tc.err)
+			var requeueErr *machinecontroller.RequeueAfterError
+			if requeued := errors.As(err, &requeueErr); requeued != tc.expectedRequeue {
+				t.Errorf("Expected requeue %v, got %v", tc.expectedRequeue, err)
+			}
+			var machineErr *machinecontroller.MachineError
+			if invalid := errors.As(err, &machineErr); invalid != tc.expectedInvalid {
+				t.Errorf("Expected invalid configuration %v, got %v", tc.expectedInvalid, err)
+			}
+			if _, ok := Classify(tc.err); ok {
+				var classified *Error
+				if !errors.As(err, &classified) {
+					t.Errorf("Expected a classified error, got %T", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRequeueIfRetryable(t *testing.T) {
+	err := RequeueIfRetryable(&googleapi.Error{Code: 429})
+	var requeueErr *machinecontroller.RequeueAfterError
+	if !errors.As(err, &requeueErr) || requeueErr.RequeueAfter != rateLimitRequeueAfter {
+		t.Fatalf("Expected a requeue after %s, got %v", rateLimitRequeueAfter, err)
+	}
+	if again := RequeueIfRetryable(err); again != err {
+		t.Errorf("Expected a requeued error to be returned as is, got %v", again)
+	}
+
+	var machineErr *machinecontroller.MachineError
+	if err := RequeueIfRetryable(&googleapi.Error{Code: 400}); errors.As(err, &requeueErr) || errors.As(err, &machineErr) {
+		t.Errorf("Expected invalid requests not to be requeued or failed outside of creation, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cases := []struct {
+		name          string
+		err           error
+		expectedDelay time.Duration
+		expectedOK    bool
+	}{
+		{
+			name:          "Retry-After in seconds",
+			err:           fmt.Errorf("instancesInsert request failed: %w", &googleapi.Error{Code: 429, Header: http.Header{"Retry-After": []string{"120"}}}),
+			expectedDelay: 2 * time.Minute,
+			expectedOK:    true,
+		},
+		{
+			name: "RetryInfo details",
+			err: &googleapi.Error{Code: 403, Details: []interface{}{
+				map[string]interface{}{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "RATE_LIMIT_EXCEEDED"},
+				map[string]interface{}{"@type": retryInfoType, "retryDelay": "45s"},
+			}},
+			expectedDelay: 45 * time.Second,
+			expectedOK:    true,
+		},
+		{
+			name: "Invalid Retry-After",
+			err:  &googleapi.Error{Code: 429, Header: http.Header{"Retry-After": []string{"soon"}}},
+		},
+		{
+			name: "No delay requested",
+			err:  &googleapi.Error{Code: 429},
+		},
+		{
+			name: "Not a googleapi error",
+			err:  errors.New("Retry-After: 10"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			delay, ok := RetryAfter(tc.err)
+			if ok != tc.expectedOK || delay != tc.expectedDelay {
+				t.Errorf("Expected delay %s and %v, got %s and %v", tc.expectedDelay, tc.expectedOK, delay, ok)
+			}
+		})
+	}
+}
+
+func TestRequeueIfRetryableRetryAfter(t *testing.T) {
+	cases := []struct {
+		name                 string
+		retryAfter           string
+		expectedRequeueAfter time.Duration
+	}{
+		{
+			name:                 "Shorter delay than the rate limit backoff",
+			retryAfter:           "5",
+			expectedRequeueAfter: 5 * time.Second,
+		},
+		{
+			name:                 "Longer delay than the rate limit backoff",
+			retryAfter:           "300",
+			expectedRequeueAfter: 5 * time.Minute,
+		},
+		{
+			name:                 "Delay is capped",
+			retryAfter:           "86400",
+			expectedRequeueAfter: maxRequeueAfter,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := RequeueIfRetryable(&googleapi.Error{Code: 429, Header: http.Header{"Retry-After": []string{tc.retryAfter}}})
+			var requeueErr *machinecontroller.RequeueAfterError
+			if !errors.As(err, &requeueErr) || requeueErr.RequeueAfter != tc.expectedRequeueAfter {
+				t.Errorf("Expected a requeue after %s, got %v", tc.expectedRequeueAfter, err)
+			}
+		})
+	}
+}
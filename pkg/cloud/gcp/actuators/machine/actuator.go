@@ -6,14 +6,23 @@ package machine
 import (
 	"context"
 	"fmt"
+	"net/http"
 
+	"github.com/go-logr/logr"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/credentials"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	iamservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/iam"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/notifier"
+	pricingservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/pricing"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/tracing"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
-	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 	"k8s.io/utils/pointer"
 	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -33,7 +42,18 @@ type Actuator struct {
 	eventRecorder        record.EventRecorder
 	computeClientBuilder computeservice.BuilderFuncType
 	tagsClientBuilder    tagservice.BuilderFuncType
+	iamClientBuilder     iamservice.BuilderFuncType
+	credentials          *credentials.Builder
 	featureGates         featuregates.FeatureGate
+	clock                clock.Clock
+	httpClient           *http.Client
+	notifier             notifier.Notifier
+	pricingClientBuilder pricingservice.BuilderFuncType
+	dnsClientBuilder     dnsservice.BuilderFuncType
+	options              Options
+	existence            *existenceCache
+	skuCache             *skuCache
+	preemptionStats      *preemptionStats
 }
 
 // ActuatorParams holds parameter information for Actuator.
@@ -42,7 +62,28 @@ type ActuatorParams struct {
 	EventRecorder        record.EventRecorder
 	ComputeClientBuilder computeservice.BuilderFuncType
 	TagsClientBuilder    tagservice.BuilderFuncType
-	FeatureGates         featuregates.FeatureGate
+	// IAMClientBuilder builds the client used to validate service accounts before an instance
+	// is created. The validation is skipped when it is not set.
+	IAMClientBuilder iamservice.BuilderFuncType
+	// Credentials builds the credentials of the GCP clients from the credentials secret, e.g. to
+	// impersonate a service account. Optional.
+	Credentials  *credentials.Builder
+	FeatureGates featuregates.FeatureGate
+	// Clock is used for all time based decisions of the reconciler. Defaults to the real clock.
+	Clock clock.Clock
+	// HTTPClient is used for calls to GCP that do not go through the compute or tag services.
+	// Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// Notifier publishes machine creation failures to an external sink. Optional.
+	Notifier notifier.Notifier
+	// PricingClientBuilder builds the client of the Cloud Billing catalog used by
+	// SpotZonePolicyCheapest. The requested zones are kept when it is not set.
+	PricingClientBuilder pricingservice.BuilderFuncType
+	// DNSClientBuilder builds the Cloud DNS client used to register Options.DNSRecords. Records are
+	// not registered when it is not set.
+	DNSClientBuilder dnsservice.BuilderFuncType
+	// Options are the settings of the reconciler, see Options.
+	Options Options
 }
 
 // NewActuator returns an actuator.
@@ -52,14 +93,47 @@ func NewActuator(params ActuatorParams) *Actuator {
 		eventRecorder:        params.EventRecorder,
 		computeClientBuilder: params.ComputeClientBuilder,
 		tagsClientBuilder:    params.TagsClientBuilder,
+		iamClientBuilder:     params.IAMClientBuilder,
+		credentials:          params.Credentials,
 		featureGates:         params.FeatureGates,
+		clock:                params.Clock,
+		httpClient:           params.HTTPClient,
+		notifier:             params.Notifier,
+		pricingClientBuilder: params.PricingClientBuilder,
+		dnsClientBuilder:     params.DNSClientBuilder,
+		options:              params.Options,
+		existence:            newExistenceCache(params.Clock, params.Options.ExistsVerificationInterval),
+		skuCache:             newSKUCache(params.Clock),
+		preemptionStats:      newPreemptionStats(params.Clock),
+	}
+}
+
+// scopeParams returns the parameters to create the scope of a machine actuator operation.
+func (a *Actuator) scopeParams(ctx context.Context, machine *machinev1.Machine) machineScopeParams {
+	return machineScopeParams{
+		Context:              ctx,
+		coreClient:           a.coreClient,
+		machine:              machine,
+		computeClientBuilder: a.computeClientBuilder,
+		tagsClientBuilder:    a.tagsClientBuilder,
+		iamClientBuilder:     a.iamClientBuilder,
+		credentials:          a.credentials,
+		featureGates:         a.featureGates,
+		clock:                a.clock,
+		httpClient:           a.httpClient,
+		eventRecorder:        a.eventRecorder,
+		pricingClientBuilder: a.pricingClientBuilder,
+		dnsClientBuilder:     a.dnsClientBuilder,
+		skuCache:             a.skuCache,
+		preemptionStats:      a.preemptionStats,
+		options:              a.options,
 	}
 }
 
 // Set corresponding event based on error. It also returns the original error
 // for convenience, so callers can do "return handleMachineError(...)".
-func (a *Actuator) handleMachineError(machine *machinev1.Machine, err error, eventAction string) error {
-	klog.Errorf("%v error: %v", machine.GetName(), err)
+func (a *Actuator) handleMachineError(log logr.Logger, machine *machinev1.Machine, err error, eventAction string) error {
+	log.Error(err, "Machine action failed", "action", eventAction)
 	if eventAction != noEventAction {
 		a.eventRecorder.Eventf(machine, corev1.EventTypeWarning, "Failed"+eventAction, "%v", err)
 	}
@@ -67,40 +141,40 @@ func (a *Actuator) handleMachineError(machine *machinev1.Machine, err error, eve
 }
 
 // Create creates a machine and is invoked by the machine controller.
-func (a *Actuator) Create(ctx context.Context, machine *machinev1.Machine) error {
-	klog.Infof("%s: Creating machine", machine.Name)
-	scope, err := newMachineScope(machineScopeParams{
-		Context:              ctx,
-		coreClient:           a.coreClient,
-		machine:              machine,
-		computeClientBuilder: a.computeClientBuilder,
-		tagsClientBuilder:    a.tagsClientBuilder,
-		featureGates:         a.featureGates,
-	})
+func (a *Actuator) Create(ctx context.Context, machine *machinev1.Machine) (err error) {
+	ctx, span := startReconcileSpan(ctx, createEventAction, machine)
+	defer func() { tracing.End(span, err) }()
+	log := machineLogger(ctx, machine)
+	log.Info("Creating machine")
+	scope, err := newMachineScope(a.scopeParams(ctx, machine))
 	if err != nil {
 		fmtErr := fmt.Errorf(scopeFailFmt, machine.GetName(), err)
-		return a.handleMachineError(machine, fmtErr, createEventAction)
+		return a.handleMachineError(log, machine, fmtErr, createEventAction)
 	}
 	if err := newReconciler(scope).create(); err != nil {
 		// Update machine and machine status in case it was modified
 		scope.Close()
+		err = scope.budget.requeueIfExhausted(scope.log, err)
+		a.existence.forget(machine)
+		a.notifyCreateFailure(ctx, scope, err)
 		fmtErr := fmt.Errorf(reconcilerFailFmt, machine.GetName(), createEventAction, err)
-		return a.handleMachineError(machine, fmtErr, createEventAction)
+		return a.handleMachineError(log, machine, fmtErr, createEventAction)
 	}
 	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, createEventAction, "Created Machine %v", machine.Name)
 	return scope.Close()
 }
 
-func (a *Actuator) Exists(ctx context.Context, machine *machinev1.Machine) (bool, error) {
-	klog.Infof("%s: Checking if machine exists", machine.Name)
-	scope, err := newMachineScope(machineScopeParams{
-		Context:              ctx,
-		coreClient:           a.coreClient,
-		machine:              machine,
-		computeClientBuilder: a.computeClientBuilder,
-		tagsClientBuilder:    a.tagsClientBuilder,
-		featureGates:         a.featureGates,
-	})
+func (a *Actuator) Exists(ctx context.Context, machine *machinev1.Machine) (_ bool, err error) {
+	ctx, span := startReconcileSpan(ctx, "Exists", machine)
+	defer func() { tracing.End(span, err) }()
+	log := machineLogger(ctx, machine)
+	log.V(logLevelRoutine).Info("Checking if machine exists")
+	if a.existence.fresh(machine) {
+		log.V(logLevelRoutine).Info("Instance was verified recently, trusting provider status")
+		existsChecksTotal.WithLabelValues("cached").Inc()
+		return true, nil
+	}
+	scope, err := newMachineScope(a.scopeParams(ctx, machine))
 	if err != nil {
 		return false, fmt.Errorf(scopeFailFmt, machine.Name, err)
 	}
@@ -110,6 +184,13 @@ func (a *Actuator) Exists(ctx context.Context, machine *machinev1.Machine) (bool
 	// "Operation cannot be fulfilled; the object has been modified; please apply your changes to the latest version and try again."
 	// Therefore we don't close the scope here and we only store spec/status atomically either in create()/update()"
 	exists, err := newReconciler(scope).exists()
+	err = scope.budget.requeueIfExhausted(scope.log, err)
+	existsChecksTotal.WithLabelValues("verified").Inc()
+	if exists && err == nil {
+		a.existence.record(machine)
+	} else {
+		a.existence.forget(machine)
+	}
 	if !isInvalidMachineConfigurationError(err) {
 		return exists, err
 	}
@@ -132,25 +213,24 @@ func (a *Actuator) Exists(ctx context.Context, machine *machinev1.Machine) (bool
 	return exists, err
 }
 
-func (a *Actuator) Update(ctx context.Context, machine *machinev1.Machine) error {
-	klog.Infof("%s: Updating machine", machine.Name)
-	scope, err := newMachineScope(machineScopeParams{
-		Context:              ctx,
-		coreClient:           a.coreClient,
-		machine:              machine,
-		computeClientBuilder: a.computeClientBuilder,
-		tagsClientBuilder:    a.tagsClientBuilder,
-		featureGates:         a.featureGates,
-	})
+func (a *Actuator) Update(ctx context.Context, machine *machinev1.Machine) (err error) {
+	ctx, span := startReconcileSpan(ctx, updateEventAction, machine)
+	defer func() { tracing.End(span, err) }()
+	log := machineLogger(ctx, machine)
+	log.V(logLevelDetail).Info("Updating machine")
+	scope, err := newMachineScope(a.scopeParams(ctx, machine))
 	if err != nil {
 		fmtErr := fmt.Errorf(scopeFailFmt, machine.GetName(), err)
-		return a.handleMachineError(machine, fmtErr, updateEventAction)
+		return a.handleMachineError(log, machine, fmtErr, updateEventAction)
 	}
+	scope.reconciledInstance = a.existence.reconciledInstance(machine)
 	if err := newReconciler(scope).update(); err != nil {
+		a.existence.forget(machine)
 		// Update machine and machine status in case it was modified
 		scope.Close()
+		err = scope.budget.requeueIfExhausted(scope.log, gcperrors.RequeueIfRetryable(err))
 		fmtErr := fmt.Errorf(reconcilerFailFmt, machine.GetName(), updateEventAction, err)
-		return a.handleMachineError(machine, fmtErr, updateEventAction)
+		return a.handleMachineError(log, machine, fmtErr, updateEventAction)
 	}
 
 	previousResourceVersion := scope.machine.ResourceVersion
@@ -158,6 +238,13 @@ func (a *Actuator) Update(ctx context.Context, machine *machinev1.Machine) error
 	if err := scope.Close(); err != nil {
 		return err
 	}
+	// An instance changed by the update is outdated, the next update looks it up again.
+	switch {
+	case scope.mutations.mutated:
+		a.existence.forget(scope.machine)
+	case scope.fetchedInstance != nil:
+		a.existence.recordReconciled(scope.machine, scope.fetchedInstance)
+	}
 
 	currentResourceVersion := scope.machine.ResourceVersion
 
@@ -169,23 +256,21 @@ func (a *Actuator) Update(ctx context.Context, machine *machinev1.Machine) error
 	return nil
 }
 
-func (a *Actuator) Delete(ctx context.Context, machine *machinev1.Machine) error {
-	klog.Infof("%s: Deleting machine", machine.Name)
-	scope, err := newMachineScope(machineScopeParams{
-		Context:              ctx,
-		coreClient:           a.coreClient,
-		machine:              machine,
-		computeClientBuilder: a.computeClientBuilder,
-		tagsClientBuilder:    a.tagsClientBuilder,
-		featureGates:         a.featureGates,
-	})
+func (a *Actuator) Delete(ctx context.Context, machine *machinev1.Machine) (err error) {
+	ctx, span := startReconcileSpan(ctx, deleteEventAction, machine)
+	defer func() { tracing.End(span, err) }()
+	log := machineLogger(ctx, machine)
+	log.Info("Deleting machine")
+	scope, err := newMachineScope(a.scopeParams(ctx, machine))
 	if err != nil {
 		fmtErr := fmt.Errorf(scopeFailFmt, machine.GetName(), err)
-		return a.handleMachineError(machine, fmtErr, deleteEventAction)
+		return a.handleMachineError(log, machine, fmtErr, deleteEventAction)
 	}
+	a.existence.forget(machine)
 	if err := newReconciler(scope).delete(); err != nil {
+		err = scope.budget.requeueIfExhausted(scope.log, gcperrors.RequeueIfRetryable(err))
 		fmtErr := fmt.Errorf(reconcilerFailFmt, machine.GetName(), deleteEventAction, err)
-		return a.handleMachineError(machine, fmtErr, deleteEventAction)
+		return a.handleMachineError(log, machine, fmtErr, deleteEventAction)
 	}
 	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, deleteEventAction, "Deleted machine %v", machine.Name)
 	return nil
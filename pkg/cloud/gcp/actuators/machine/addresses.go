@@ -0,0 +1,264 @@
+package machine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	internalAddressType      = "INTERNAL"
+	addressStatusReserving   = "RESERVING"
+	externalAddressType      = "EXTERNAL"
+	addressStatusReserved    = "RESERVED"
+	addressStatusInUse       = "IN_USE"
+	externalAccessConfigName = "External NAT"
+	oneToOneNATAccessConfig  = "ONE_TO_ONE_NAT"
+
+	premiumNetworkTier  = "PREMIUM"
+	standardNetworkTier = "STANDARD"
+)
+
+// networkTier returns the network tier requested for the external access configs of the
+// machine, or an empty string to use the project default. Whether the tier is offered in
+// the machine's region is left to the compute API, which rejects the instance insert.
+func (r *Reconciler) networkTier() (string, error) {
+	value, ok := r.getAnnotation(networkTierAnnotation)
+	if !ok || value == "" {
+		return "", nil
+	}
+
+	tier := strings.ToUpper(value)
+	switch tier {
+	case premiumNetworkTier, standardNetworkTier:
+	default:
+		return "", machinecontroller.InvalidMachineConfiguration("invalid value %q for annotation %s, must be one of %s or %s", value, networkTierAnnotation, premiumNetworkTier, standardNetworkTier)
+	}
+	return tier, nil
+}
+
+// parseRegionalResourceLink parses a (partial) self link of a regional resource, e.g.
+// https://www.googleapis.com/compute/v1/projects/<project>/regions/<region>/<collection>/<name>
+// or projects/<project>/regions/<region>/<collection>/<name>.
+func parseRegionalResourceLink(link, collection string) (project, region, name string, err error) {
+	parts := strings.Split(link, "/")
+	for i := 0; i+5 < len(parts); i++ {
+		if parts[i] == "projects" && parts[i+2] == "regions" && parts[i+4] == collection && i+6 == len(parts) {
+			return parts[i+1], parts[i+3], parts[i+5], nil
+		}
+	}
+	return "", "", "", fmt.Errorf("%q is not a valid %s link", link, collection)
+}
+
+// getReservedExternalAddress fetches the reserved external address referenced by the
+// externalAddressAnnotation and checks that it can be attached to this machine's instance.
+func (r *Reconciler) getReservedExternalAddress(ref string) (*compute.Address, error) {
+	project, region, name := r.projectID, r.providerSpec.Region, ref
+	if strings.Contains(ref, "/") {
+		var err error
+		if project, region, name, err = parseRegionalResourceLink(ref, "addresses"); err != nil {
+			return nil, machinecontroller.InvalidMachineConfiguration("invalid %s annotation: %v", externalAddressAnnotation, err)
+		}
+	}
+
+	if region != r.providerSpec.Region {
+		return nil, machinecontroller.InvalidMachineConfiguration("external address %q is in region %q, but the machine is in region %q", ref, region, r.providerSpec.Region)
+	}
+
+	address, err := r.computeService.AddressesGet(project, region, name)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, machinecontroller.InvalidMachineConfiguration("external address %q not found", ref)
+		}
+		return nil, fmt.Errorf("failed to get external address %q: %w", ref, err)
+	}
+
+	if address.AddressType != "" && address.AddressType != externalAddressType {
+		return nil, machinecontroller.InvalidMachineConfiguration("address %q has type %q, only %s addresses can be attached as public IP", ref, address.AddressType, externalAddressType)
+	}
+
+	switch address.Status {
+	case addressStatusReserved:
+	case addressStatusInUse:
+		// The address may already be in use by our own instance, e.g. when create is retried.
+		instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.instanceName)
+		if !containsString(address.Users, instanceSelfLink) {
+			return nil, machinecontroller.InvalidMachineConfiguration("external address %q is already in use by %v", ref, address.Users)
+		}
+	default:
+		return nil, fmt.Errorf("external address %q is not ready to be used, status: %q", ref, address.Status)
+	}
+
+	return address, nil
+}
+
+// buildAccessConfigs returns the access configs for the network interface with the given index.
+// Only the primary network interface may use a reserved external address; every other interface
+// with PublicIP set gets an ephemeral external address.
+func (r *Reconciler) buildAccessConfigs(nicIndex int, publicIP bool) ([]*compute.AccessConfig, error) {
+	addressRef, hasAddress := r.getAnnotation(externalAddressAnnotation)
+	hasAddress = hasAddress && addressRef != ""
+
+	if !publicIP {
+		if nicIndex == 0 && hasAddress {
+			return nil, machinecontroller.InvalidMachineConfiguration("%s annotation is set but the primary network interface does not have publicIP enabled", externalAddressAnnotation)
+		}
+		// No access config means the interface has no external connectivity.
+		return []*compute.AccessConfig{}, nil
+	}
+
+	tier, err := r.networkTier()
+	if err != nil {
+		return nil, err
+	}
+
+	accessConfig := &compute.AccessConfig{NetworkTier: tier}
+	if nicIndex == 0 && hasAddress {
+		address, err := r.getReservedExternalAddress(addressRef)
+		if err != nil {
+			return nil, err
+		}
+		// The access config must use the tier the address was reserved in.
+		if tier != "" && address.NetworkTier != "" && tier != address.NetworkTier {
+			return nil, machinecontroller.InvalidMachineConfiguration("external address %q is in network tier %s, but network tier %s was requested", addressRef, address.NetworkTier, tier)
+		}
+		if tier == "" {
+			tier = address.NetworkTier
+		}
+		accessConfig = &compute.AccessConfig{
+			Name:        externalAccessConfigName,
+			Type:        oneToOneNATAccessConfig,
+			NatIP:       address.Address,
+			NetworkTier: tier,
+		}
+	}
+
+	return []*compute.AccessConfig{accessConfig}, nil
+}
+
+// reserveInternalAddress returns the static internal address named after the machine,
+// reserving it in the subnetwork of the primary network interface if it does not exist yet.
+// While the address is being reserved a RequeueAfterError is returned.
+func (r *Reconciler) reserveInternalAddress(nic *machinev1.GCPNetworkInterface) (string, error) {
+	name := r.instanceName
+	address, err := r.computeService.AddressesGet(r.projectID, r.providerSpec.Region, name)
+	if isNotFoundError(err) {
+		labels, err := util.GetLabelsList(r.gcpLabelsTagsFeatureEnabled, r.coreClient,
+			r.machine.Labels[machinev1.MachineClusterIDLabel], nil)
+		if err != nil {
+			return "", fmt.Errorf("error getting labels for internal address %s: %w", name, err)
+		}
+
+		address = &compute.Address{
+			Name:        name,
+			AddressType: internalAddressType,
+			Description: fmt.Sprintf("Static internal IP of machine %s/%s", r.machine.Namespace, r.machine.Name),
+			Labels:      r.withMachineNameLabel(labels),
+		}
+		if nic.Subnetwork != "" {
+			address.Subnetwork = r.subnetworkURL(nic)
+		}
+
+		r.log.Info("Reserving static internal address", "address", address.Name)
+		if _, err := r.computeService.AddressesInsert(r.projectID, r.providerSpec.Region, address); err != nil {
+			return "", fmt.Errorf("failed to reserve internal address %s: %w", name, err)
+		}
+		return "", &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get internal address %s: %w", name, err)
+	}
+
+	if address.AddressType != internalAddressType {
+		return "", machinecontroller.InvalidMachineConfiguration("address %q has type %q, expected an %s address", name, address.AddressType, internalAddressType)
+	}
+
+	switch address.Status {
+	case addressStatusReserved:
+	case addressStatusReserving:
+		r.log.V(logLevelDetail).Info("Static internal address is being reserved, requeuing", "address", address.Name)
+		return "", &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	case addressStatusInUse:
+		instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.instanceName)
+		if !containsString(address.Users, instanceSelfLink) {
+			return "", machinecontroller.InvalidMachineConfiguration("internal address %q is already in use by %v", name, address.Users)
+		}
+	default:
+		return "", fmt.Errorf("internal address %q is not ready to be used, status: %q", name, address.Status)
+	}
+
+	return address.Address, nil
+}
+
+// releaseInternalAddress releases the static internal address of the machine once its instance is gone.
+func (r *Reconciler) releaseInternalAddress() error {
+	if enabled, err := r.getBoolAnnotation(staticInternalAddressAnnotation); err != nil || !enabled {
+		return err
+	}
+
+	name := r.instanceName
+	address, err := r.computeService.AddressesGet(r.projectID, r.providerSpec.Region, name)
+	if isNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get internal address %s: %w", name, err)
+	}
+	if err := r.checkClusterOwnership("address", name, address.Labels); err != nil {
+		return err
+	}
+	if address.Status == addressStatusInUse {
+		// The instance is still being torn down.
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+
+	r.log.Info("Releasing static internal address", "address", name)
+	if _, err := r.computeService.AddressesDelete(r.projectID, r.providerSpec.Region, name); err != nil && !isNotFoundError(err) {
+		return fmt.Errorf("failed to release internal address %s: %w", name, err)
+	}
+	return nil
+}
+
+const networkAttachmentLinkFmt = "projects/%s/regions/%s/networkAttachments/%s"
+
+// networkAttachments returns the Private Service Connect network attachment links requested
+// per network interface index.
+func (r *Reconciler) networkAttachments() (map[int]string, error) {
+	raw := map[string]string{}
+	if ok, err := r.getJSONAnnotation(networkAttachmentsAnnotation, &raw); err != nil || !ok {
+		return nil, err
+	}
+
+	attachments := make(map[int]string, len(raw))
+	for key, ref := range raw {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(r.providerSpec.NetworkInterfaces) {
+			return nil, machinecontroller.InvalidMachineConfiguration("invalid %s annotation: %q is not the index of a network interface", networkAttachmentsAnnotation, key)
+		}
+		if index == 0 {
+			return nil, machinecontroller.InvalidMachineConfiguration("invalid %s annotation: the primary network interface cannot use a network attachment", networkAttachmentsAnnotation)
+		}
+		nic := r.providerSpec.NetworkInterfaces[index]
+		if nic.Network != "" || nic.Subnetwork != "" || nic.PublicIP {
+			return nil, machinecontroller.InvalidMachineConfiguration("network interface %d uses a network attachment and must not set network, subnetwork or publicIP", index)
+		}
+
+		project, region, name := r.projectID, r.providerSpec.Region, ref
+		if strings.Contains(ref, "/") {
+			if project, region, name, err = parseRegionalResourceLink(ref, "networkAttachments"); err != nil {
+				return nil, machinecontroller.InvalidMachineConfiguration("invalid %s annotation: %v", networkAttachmentsAnnotation, err)
+			}
+		}
+		if region != r.providerSpec.Region {
+			return nil, machinecontroller.InvalidMachineConfiguration("network attachment %q is in region %q, but the machine is in region %q", ref, region, r.providerSpec.Region)
+		}
+		attachments[index] = fmt.Sprintf(networkAttachmentLinkFmt, project, region, name)
+	}
+	return attachments, nil
+}
@@ -0,0 +1,141 @@
+package machine
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseRegionalResourceLink(t *testing.T) {
+	cases := []struct {
+		link        string
+		project     string
+		region      string
+		name        string
+		expectError bool
+	}{
+		{
+			link:    "https://www.googleapis.com/compute/v1/projects/p/regions/r/addresses/a",
+			project: "p",
+			region:  "r",
+			name:    "a",
+		},
+		{
+			link:    "projects/p/regions/r/addresses/a",
+			project: "p",
+			region:  "r",
+			name:    "a",
+		},
+		{
+			link:        "projects/p/regions/r/subnetworks/a",
+			expectError: true,
+		},
+		{
+			link:        "projects/p/regions/r/addresses/a/extra",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.link, func(t *testing.T) {
+			project, region, name, err := parseRegionalResourceLink(tc.link, "addresses")
+			if tc.expectError {
+				if err == nil {
+					t.Error("Expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if project != tc.project || region != tc.region || name != tc.name {
+				t.Errorf("Expected: %s/%s/%s, got %s/%s/%s", tc.project, tc.region, tc.name, project, region, name)
+			}
+		})
+	}
+}
+
+func TestReleaseInternalAddress(t *testing.T) {
+	ownedLabels := map[string]string{"kubernetes-io-cluster-CLUSTERID": "owned"}
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		address         *compute.Address
+		expectedDeleted bool
+		expectedError   error
+	}{
+		{
+			name:    "Nothing to release without annotation",
+			address: &compute.Address{Status: addressStatusReserved},
+		},
+		{
+			name:            "Release reserved address",
+			annotations:     map[string]string{staticInternalAddressAnnotation: "true"},
+			address:         &compute.Address{Status: addressStatusReserved, Labels: ownedLabels},
+			expectedDeleted: true,
+		},
+		{
+			name:          "Refuse to release address of another cluster",
+			annotations:   map[string]string{staticInternalAddressAnnotation: "true"},
+			address:       &compute.Address{Status: addressStatusReserved, Labels: map[string]string{"kubernetes-io-cluster-other": "owned"}},
+			expectedError: errors.New("refusing to delete address test: it does not carry the label kubernetes-io-cluster-CLUSTERID=owned of cluster \"CLUSTERID\""),
+		},
+		{
+			name:          "Wait for the instance to release the address",
+			annotations:   map[string]string{staticInternalAddressAnnotation: "true"},
+			address:       &compute.Address{Status: addressStatusInUse, Labels: ownedLabels},
+			expectedError: errors.New("requeue in: 20s"),
+		},
+		{
+			name:        "Address already released",
+			annotations: map[string]string{staticInternalAddressAnnotation: "true"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockAddressesGet = func(project string, region string, name string) (*compute.Address, error) {
+				if tc.address == nil {
+					return nil, &googleapi.Error{Code: http.StatusNotFound}
+				}
+				return tc.address, nil
+			}
+			deleted := false
+			mockComputeService.MockAddressesDelete = func(project string, region string, name string) (*compute.Operation, error) {
+				deleted = true
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "test",
+						Annotations: tc.annotations,
+						Labels:      map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+					},
+				},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "test-region"},
+				computeService: mockComputeService,
+			})
+
+			err := r.releaseInternalAddress()
+			if tc.expectedError != nil {
+				if err == nil || err.Error() != tc.expectedError.Error() {
+					t.Errorf("Expected: %v, got %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if deleted != tc.expectedDeleted {
+				t.Errorf("Expected address deleted: %v, got %v", tc.expectedDeleted, deleted)
+			}
+		})
+	}
+}
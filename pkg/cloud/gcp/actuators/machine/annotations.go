@@ -0,0 +1,242 @@
+package machine
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+// The GCPMachineProviderSpec is owned by openshift/api, options that are specific
+// to this provider and not (yet) part of the API are read from Machine annotations.
+// These are usually set on the MachineSet template so that every Machine gets them.
+const (
+	// gcpAnnotationPrefix is the common prefix of all annotations understood by the GCP provider.
+	gcpAnnotationPrefix = "machine.openshift.io/gcp-"
+
+	// externalAddressAnnotation references a reserved regional external address, either by
+	// name or by self link, to attach to the primary network interface. The primary network
+	// interface must have PublicIP set for the address to be attached.
+	externalAddressAnnotation = gcpAnnotationPrefix + "external-address"
+
+	// networkTierAnnotation selects the network tier, PREMIUM or STANDARD, of the external
+	// access configs of the machine. The project's default tier is used when it is not set.
+	networkTierAnnotation = gcpAnnotationPrefix + "network-tier"
+
+	// diskResourcePoliciesAnnotation is a comma separated list of resource policies, by name or
+	// self link, that are attached to every disk of the machine, e.g. a snapshot schedule.
+	diskResourcePoliciesAnnotation = gcpAnnotationPrefix + "disk-resource-policies"
+
+	// guestOSFeaturesAnnotation is a comma separated list of guest OS features, e.g. UEFI_COMPATIBLE,GVNIC,
+	// enabled on the boot disk in addition to the features of its image, so that custom images do not
+	// have to be re-imported to flip a feature flag.
+	guestOSFeaturesAnnotation = gcpAnnotationPrefix + "guest-os-features"
+
+	// sourceImageEncryptionKeyAnnotation holds, as JSON, the KMS key the source image of the boot disk is
+	// encrypted with, in the format of the disk encryptionKey, e.g.
+	// {"kmsKey": {"name": "key", "keyRing": "ring", "location": "global"}, "kmsKeyServiceAccount": "sa@project.iam.gserviceaccount.com"}.
+	sourceImageEncryptionKeyAnnotation = gcpAnnotationPrefix + "source-image-encryption-key"
+
+	// sourceImageEncryptionKeySecretAnnotation is the name of a Secret in the namespace of the machine
+	// holding the customer-supplied key the source image of the boot disk is encrypted with, base64
+	// encoded in either its rawKey or its rsaEncryptedKey field.
+	sourceImageEncryptionKeySecretAnnotation = gcpAnnotationPrefix + "source-image-encryption-key-secret"
+
+	// nodeLabelsAnnotation is a comma separated list of key=value node labels the kubelet registers
+	// the node with, passed to the instance in the kubelet-extra-args metadata.
+	nodeLabelsAnnotation = gcpAnnotationPrefix + "node-labels"
+
+	// nodeTaintsAnnotation is a comma separated list of key=value:Effect taints the kubelet registers
+	// the node with, passed to the instance in the kubelet-extra-args metadata.
+	nodeTaintsAnnotation = gcpAnnotationPrefix + "node-taints"
+
+	// propagateMachineTaintsAnnotation, when "true", also passes the taints of the machine spec to the
+	// instance in the kubelet-extra-args metadata, so that the node registers with them instead of
+	// getting them from the machine controller once it joined.
+	propagateMachineTaintsAnnotation = gcpAnnotationPrefix + "propagate-machine-taints"
+
+	// reservationAffinityAnnotation selects which capacity reservations the instance consumes:
+	// "any" (the GCP default), "none" or "specific". The reservations to consume with "specific"
+	// are listed in the reservationsAnnotation.
+	reservationAffinityAnnotation = gcpAnnotationPrefix + "reservation-affinity"
+
+	// reservationsAnnotation is a comma separated list of reservation names, or
+	// projects/<project>/reservations/<name> for shared reservations, to consume.
+	reservationsAnnotation = gcpAnnotationPrefix + "reservations"
+
+	// advancedMachineFeaturesAnnotation holds the advanced machine features of the instance as JSON, e.g.
+	// {"enableNestedVirtualization": true, "threadsPerCore": 1, "visibleCoreCount": 2, "enableUefiNetworking": true}.
+	advancedMachineFeaturesAnnotation = gcpAnnotationPrefix + "advanced-machine-features"
+
+	// networkAttachmentsAnnotation maps network interface indexes to Private Service Connect network
+	// attachments, by name or self link, as JSON, e.g. {"1": "producer-attachment"}. Those interfaces
+	// must not set a network, subnetwork or public IP, and the primary interface cannot be attached.
+	networkAttachmentsAnnotation = gcpAnnotationPrefix + "network-attachments"
+
+	// staticInternalAddressAnnotation, when "true", makes the reconciler reserve (or reuse) a static
+	// internal address named after the machine for the primary network interface, so that the
+	// node keeps its IP when the machine is recreated. The address is released on delete.
+	staticInternalAddressAnnotation = gcpAnnotationPrefix + "static-internal-ip"
+
+	// skipClusterOwnershipCheckAnnotation, when "true", allows destructive actions on GCP resources
+	// that do not carry the cluster ownership label, e.g. for instances created before labelling.
+	skipClusterOwnershipCheckAnnotation = gcpAnnotationPrefix + "skip-cluster-ownership-check"
+
+	// skipLoadBalancerRegistrationAnnotation, when "true", stops the reconciler from adding the
+	// instance to its target pools and control plane instance group, e.g. while a control plane
+	// machine is migrated or quarantined. Existing registrations are left untouched.
+	skipLoadBalancerRegistrationAnnotation = gcpAnnotationPrefix + "skip-lb-registration"
+
+	// diskPerformanceAnnotation maps disk indexes to the IOPS and throughput, in MiB/s, provisioned for
+	// disk types with provisioned performance, as JSON, e.g. {"1": {"provisionedIops": 20000}}.
+	diskPerformanceAnnotation = gcpAnnotationPrefix + "disk-performance"
+
+	// confidentialDisksAnnotation is a comma separated list of indexes of Hyperdisk Balanced data disks
+	// to create in confidential mode, for confidential VMs with confidential storage.
+	confidentialDisksAnnotation = gcpAnnotationPrefix + "confidential-disks"
+
+	// fallbackZonesAnnotation is a comma separated, prioritized list of zones in the region of the machine
+	// the instance is created in, in turn, when the zone of the machine runs out of resources.
+	fallbackZonesAnnotation = gcpAnnotationPrefix + "fallback-zones"
+
+	// zoneAnnotation is set by the reconciler to the zone the instance is created in when it differs from
+	// the zone of the providerSpec, after a zone fallback or a spot zone selection. The providerSpec keeps
+	// the zone the machine requested.
+	zoneAnnotation = gcpAnnotationPrefix + "zone"
+
+	// requestedZoneAnnotation was set to the zone the machine originally requested by the reconcilers
+	// that wrote the zone of a zone fallback to the providerSpec. It is still read for the machines
+	// they moved.
+	requestedZoneAnnotation = gcpAnnotationPrefix + "requested-zone"
+
+	// createOperationAnnotation is set by the reconciler to the name of the zonal operation that
+	// inserted the instance, so that its outcome can be looked up later.
+	createOperationAnnotation = gcpAnnotationPrefix + "create-operation"
+
+	// pendingOperationsAnnotation is set by the reconciler to the zonal and regional operations, as
+	// JSON, that were started for the machine and did not complete yet.
+	pendingOperationsAnnotation = gcpAnnotationPrefix + "pending-operations"
+
+	// provisioningDiagnosticsAnnotation is set by the reconciler to the name of the ConfigMap holding
+	// the diagnostics bundle collected when the machine did not become a node within the provisioning
+	// timeout. Removing the annotation makes the reconciler collect a fresh bundle.
+	provisioningDiagnosticsAnnotation = gcpAnnotationPrefix + "provisioning-diagnostics"
+
+	// shieldedIntegrityAutoRelearnAnnotation, when "true", makes the reconciler update the integrity
+	// policy baseline of a shielded instance once its boot disk was recreated from a different image,
+	// so that integrity monitoring does not keep reporting the new image as a failure.
+	shieldedIntegrityAutoRelearnAnnotation = gcpAnnotationPrefix + "shielded-integrity-auto-relearn"
+
+	// shieldedIntegrityBaselineImageAnnotation is set by the reconciler to the ID of the boot image
+	// the integrity policy baseline was learned from.
+	shieldedIntegrityBaselineImageAnnotation = gcpAnnotationPrefix + "shielded-integrity-baseline-image"
+
+	// instanceMissingAnnotation is set by the instance sync to the time the instance of the machine
+	// was found missing from the cloud, which also makes the machine controller reconcile the machine.
+	instanceMissingAnnotation = gcpAnnotationPrefix + "instance-missing"
+
+	// specHashAnnotation is set by the reconciler to the hash of the instance insert request it
+	// rendered for the machine, e.g. to tell whether an upgrade of the provider changed the rendering.
+	specHashAnnotation = gcpAnnotationPrefix + "spec-hash"
+
+	// inPlaceResizeAnnotation, when "true", makes the reconciler change the machine type of the instance
+	// in place, by stopping it, setting the machine type and starting it again, when only the machine
+	// type of the providerSpec changed, instead of leaving the machine to be replaced.
+	inPlaceResizeAnnotation = gcpAnnotationPrefix + "in-place-resize"
+
+	// resizeStateAnnotation is set by the reconciler to the progress of an in-place resize, as JSON, and
+	// kept with the Failed phase once a resize was rolled back so that it is not retried.
+	resizeStateAnnotation = gcpAnnotationPrefix + "machine-type-resize"
+
+	// maintenanceWindowsAnnotation is a JSON list of the maintenance windows of the machine, e.g.
+	// [{"schedule": "0 2 * * SAT", "duration": "4h"}], with schedules as cron expressions in UTC.
+	// Disruptive actions on the instance, such as an in-place resize, wait for the next window.
+	// It is typically set on the machine template of a MachineSet.
+	maintenanceWindowsAnnotation = gcpAnnotationPrefix + "maintenance-windows"
+
+	// deleteStrategyAnnotation selects how the instance is deleted: "Immediate" (the default) deletes
+	// it right away, "StopFirst" stops it and waits for the guest to shut down cleanly first, e.g. so
+	// that databases flush their data.
+	deleteStrategyAnnotation = gcpAnnotationPrefix + "delete-strategy"
+
+	// gracefulShutdownTimeoutAnnotation is how long, e.g. "10m", the "StopFirst" delete strategy waits
+	// for the guest to shut down before the instance is deleted anyway. Defaults to 5m.
+	gracefulShutdownTimeoutAnnotation = gcpAnnotationPrefix + "graceful-shutdown-timeout"
+
+	// gracefulShutdownStartedAnnotation is set by the reconciler to the time it asked the instance to
+	// stop before deleting it.
+	gracefulShutdownStartedAnnotation = gcpAnnotationPrefix + "graceful-shutdown-started"
+
+	// networkEndpointDetachedAnnotation is set by the reconciler to the time it detached the instance
+	// of a control plane machine from its network endpoint group before deleting it.
+	networkEndpointDetachedAnnotation = gcpAnnotationPrefix + "network-endpoint-detached"
+
+	// retainDisksAnnotation, when "true", keeps the data disks of the machine that are not deleted
+	// with the instance after the machine is deleted, instead of deleting them with the machine.
+	retainDisksAnnotation = gcpAnnotationPrefix + "retain-disks"
+
+	// instanceNamePrefixAnnotation is prepended to the name of the instance of the machine, e.g. to
+	// tell apart the instances of clusters sharing a project. It applies when the instance is created.
+	instanceNamePrefixAnnotation = gcpAnnotationPrefix + "instance-name-prefix"
+
+	// managedTagsAnnotation records the managed network tags last set on the instance, as a comma
+	// separated list, so that tags no longer managed are removed from it.
+	managedTagsAnnotation = gcpAnnotationPrefix + "managed-tags"
+
+	// dnsRecordAnnotation records the fully qualified name of the Cloud DNS records registered for the
+	// internal IPs of the machine, so that they are removed when the name or the machine goes away.
+	dnsRecordAnnotation = gcpAnnotationPrefix + "dns-record"
+
+	// instanceTemplateAnnotation references an instance template, by name in the project of the machine
+	// or by partial URL or self link, global or regional, the instance is created from. The name, zone,
+	// labels and metadata of the instance come from the machine, all other properties from the template.
+	instanceTemplateAnnotation = gcpAnnotationPrefix + "instance-template"
+)
+
+// getBoolAnnotation returns the boolean value of the given annotation on the machine,
+// defaulting to false when it is not set.
+func (s *machineScope) getBoolAnnotation(key string) (bool, error) {
+	value, ok := s.getAnnotation(key)
+	if !ok || value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, machinecontroller.InvalidMachineConfiguration("invalid value %q for annotation %s: %v", value, key, err)
+	}
+	return b, nil
+}
+
+// getListAnnotation returns the non-empty elements of the comma separated list annotation.
+func (s *machineScope) getListAnnotation(key string) []string {
+	value, _ := s.getAnnotation(key)
+	var list []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
+
+// getJSONAnnotation decodes the JSON value of the given annotation into out. It returns false
+// when the annotation is not set. Unknown fields are rejected to catch typos early.
+func (s *machineScope) getJSONAnnotation(key string, out interface{}) (bool, error) {
+	value, ok := s.getAnnotation(key)
+	if !ok || value == "" {
+		return false, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(out); err != nil {
+		return false, machinecontroller.InvalidMachineConfiguration("invalid value for annotation %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// getAnnotation returns the value of the given annotation on the machine, if any.
+func (s *machineScope) getAnnotation(key string) (string, bool) {
+	value, ok := s.machine.GetAnnotations()[key]
+	return value, ok
+}
@@ -0,0 +1,71 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"k8s.io/utils/clock"
+)
+
+// reconcileBudget bounds the number of compute API calls and the wall time a single actuator
+// operation may spend, so that one pathological machine cannot starve the work queue. Once the
+// budget is exhausted every further compute API call is refused, the operation fails and the
+// actuator persists the partial progress and requeues the machine.
+type reconcileBudget struct {
+	clock    clock.Clock
+	maxCalls int
+	deadline time.Time
+
+	calls     int
+	exhausted string
+}
+
+// newReconcileBudget returns a budget allowing maxCalls compute API calls within maxDuration and
+// before the deadline of ctx, the sync context of the machine controller. A zero value disables the
+// respective limit, nil is returned when all are disabled.
+func newReconcileBudget(ctx context.Context, clk clock.Clock, maxCalls int, maxDuration time.Duration) *reconcileBudget {
+	deadline, hasDeadline := ctx.Deadline()
+	if maxCalls <= 0 && maxDuration <= 0 && !hasDeadline {
+		return nil
+	}
+	budget := &reconcileBudget{clock: clk, maxCalls: maxCalls, deadline: deadline}
+	if maxDuration > 0 && (!hasDeadline || clk.Now().Add(maxDuration).Before(deadline)) {
+		budget.deadline = clk.Now().Add(maxDuration)
+	}
+	return budget
+}
+
+// intercept is a computeservice.CallInterceptor that refuses calls once the budget is exhausted.
+// A call that failed because its context is done exhausts the budget as well, so that the
+// operation requeues the machine rather than failing it.
+func (b *reconcileBudget) intercept(ctx context.Context, method string, call func() error) error {
+	switch {
+	case b.exhausted != "":
+	case b.maxCalls > 0 && b.calls >= b.maxCalls:
+		b.exhausted = fmt.Sprintf("%d compute API calls", b.maxCalls)
+	case !b.deadline.IsZero() && !b.clock.Now().Before(b.deadline):
+		b.exhausted = fmt.Sprintf("deadline %s", b.deadline.Format(time.RFC3339))
+	}
+	if b.exhausted != "" {
+		return fmt.Errorf("refusing %s call: reconcile budget of %s exhausted", method, b.exhausted)
+	}
+	b.calls++
+	err := call()
+	if err != nil && ctx.Err() != nil {
+		b.exhausted = fmt.Sprintf("sync context (%v)", ctx.Err())
+	}
+	return err
+}
+
+// requeueIfExhausted replaces the error of an actuator operation with a RequeueAfterError when
+// the operation ran out of budget, whatever error the refused call surfaced as.
+func (b *reconcileBudget) requeueIfExhausted(log logr.Logger, err error) error {
+	if b == nil || b.exhausted == "" || err == nil {
+		return err
+	}
+	log.Error(err, "Reconcile budget exhausted, requeuing", "budget", b.exhausted, "calls", b.calls)
+	return fmt.Errorf("reconcile budget of %s exhausted: %w", b.exhausted, &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second})
+}
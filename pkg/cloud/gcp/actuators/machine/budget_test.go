@@ -0,0 +1,127 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestReconcileBudget(t *testing.T) {
+	cases := []struct {
+		name          string
+		maxCalls      int
+		maxDuration   time.Duration
+		elapsed       time.Duration
+		expectedCalls int
+	}{
+		{
+			name:          "Unlimited budget",
+			expectedCalls: 5,
+		},
+		{
+			name:          "Call budget exhausted",
+			maxCalls:      3,
+			expectedCalls: 3,
+		},
+		{
+			name:          "Deadline not reached",
+			maxDuration:   time.Minute,
+			elapsed:       30 * time.Second,
+			expectedCalls: 5,
+		},
+		{
+			name:          "Deadline exceeded",
+			maxDuration:   time.Minute,
+			elapsed:       time.Minute,
+			expectedCalls: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeClock := clocktesting.NewFakeClock(time.Now())
+			budget := newReconcileBudget(context.Background(), fakeClock, tc.maxCalls, tc.maxDuration)
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+
+			var service computeservice.GCPComputeService = mockComputeService
+			if budget != nil {
+				service = computeservice.WithInterceptors(mockComputeService, budget.intercept)
+			}
+			fakeClock.Step(tc.elapsed)
+
+			var calls int
+			var lastErr error
+			for i := 0; i < 5; i++ {
+				if _, err := service.InstancesGet("project", "zone", "instance"); err != nil {
+					lastErr = err
+					continue
+				}
+				calls++
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("Expected %d calls to succeed, got %d", tc.expectedCalls, calls)
+			}
+
+			err := budget.requeueIfExhausted(logr.Discard(), lastErr)
+			var requeueErr *machinecontroller.RequeueAfterError
+			if exhausted := tc.expectedCalls < 5; exhausted != errors.As(err, &requeueErr) {
+				t.Errorf("Expected requeue: %v, got error: %v", exhausted, err)
+			}
+		})
+	}
+}
+
+func TestReconcileBudgetSyncContext(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	ctx, cancel := context.WithDeadline(context.Background(), fakeClock.Now().Add(time.Minute))
+	defer cancel()
+	budget := newReconcileBudget(ctx, fakeClock, 0, time.Hour)
+	if budget == nil || !budget.deadline.Equal(fakeClock.Now().Add(time.Minute)) {
+		t.Fatalf("Expected the budget to end with the sync context, got %+v", budget)
+	}
+
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	service := computeservice.WithContext(ctx, computeservice.WithInterceptors(mockComputeService, budget.intercept))
+	if _, err := service.InstancesGet("project", "zone", "instance"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A call that fails because the sync context is done requeues the machine.
+	cancel()
+	mockComputeService.MockRegionGet = func(project string, region string) (*compute.Region, error) {
+		return nil, ctx.Err()
+	}
+	_, err := service.RegionGet("project", "region")
+	var requeueErr *machinecontroller.RequeueAfterError
+	if !errors.As(budget.requeueIfExhausted(logr.Discard(), err), &requeueErr) {
+		t.Errorf("Expected a requeue once the sync context is done, got %v", err)
+	}
+}
+
+func TestWithInterceptorsOrder(t *testing.T) {
+	var order []string
+	recordingInterceptor := func(name string) computeservice.CallInterceptor {
+		return func(ctx context.Context, method string, call func() error) error {
+			order = append(order, name+":"+method)
+			return call()
+		}
+	}
+
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	service := computeservice.WithInterceptors(mockComputeService, recordingInterceptor("outer"), recordingInterceptor("inner"))
+	if _, err := service.InstancesGet("project", "zone", "instance"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"outer:InstancesGet", "inner:InstancesGet"}
+	if len(order) != len(expected) || order[0] != expected[0] || order[1] != expected[1] {
+		t.Errorf("Expected interceptors to run in order %v, got %v", expected, order)
+	}
+}
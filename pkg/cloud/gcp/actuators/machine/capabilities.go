@@ -0,0 +1,103 @@
+package machine
+
+import (
+	"encoding/json"
+	"net/http"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	"github.com/openshift/machine-api-provider-gcp/pkg/version"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+)
+
+// CapabilitiesPath is the path of the capability report on the metrics server.
+const CapabilitiesPath = "/supported-features"
+
+// Capability describes a GCP feature and whether this build of the provider supports it.
+type Capability struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Supported   bool   `json:"supported"`
+	// Configuration is how the feature is requested: a providerSpec field, a Machine
+	// annotation or a controller flag.
+	Configuration string `json:"configuration,omitempty"`
+	// FeatureGate is the OpenShift feature gate the feature is behind, if any.
+	FeatureGate string `json:"featureGate,omitempty"`
+	// FeatureGateEnabled is the state of the feature gate in the cluster, unset when it is not known.
+	FeatureGateEnabled *bool `json:"featureGateEnabled,omitempty"`
+}
+
+// CapabilityReport lists the GCP features known to the provider, so that cluster admins and
+// support tooling can check what a MachineSet may use before authoring it.
+type CapabilityReport struct {
+	Version      string       `json:"version"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+// capabilities are the features reported, unsupported ones are listed so that their absence is explicit.
+var capabilities = []Capability{
+	{Name: "ConfidentialVM", Description: "Confidential VM instances", Supported: true, Configuration: "providerSpec.confidentialCompute"},
+	{Name: "ShieldedVM", Description: "Shielded VM secure boot, vTPM and integrity monitoring", Supported: true, Configuration: "providerSpec.shieldedInstanceConfig"},
+	{Name: "ShieldedIntegrityAutoRelearn", Description: "Relearn the integrity policy baseline after the boot image changed", Supported: true, Configuration: shieldedIntegrityAutoRelearnAnnotation},
+	{Name: "PreemptibleVM", Description: "Preemptible instances", Supported: true, Configuration: "providerSpec.preemptible"},
+	{Name: "SpotVM", Description: "Spot provisioning model", Supported: false},
+	{Name: "GPUs", Description: "Accelerators attached to the instance", Supported: true, Configuration: "providerSpec.gpus"},
+	{Name: "OnHostMaintenance", Description: "Host maintenance and restart policies", Supported: true, Configuration: "providerSpec.onHostMaintenance, providerSpec.restartPolicy"},
+	{Name: "DeletionProtection", Description: "Instance deletion protection", Supported: true, Configuration: "providerSpec.deletionProtection"},
+	{Name: "Labels", Description: "User defined labels on instances and disks", Supported: true, Configuration: "providerSpec.labels", FeatureGate: string(configv1.FeatureGateGCPLabelsTags)},
+	{Name: "ResourceManagerTags", Description: "Resource manager tags on instances and disks", Supported: true, Configuration: "providerSpec.resourceManagerTags", FeatureGate: string(configv1.FeatureGateGCPLabelsTags)},
+	{Name: "DiskEncryption", Description: "Customer-managed encryption keys for disks", Supported: true, Configuration: "providerSpec.disks[].encryptionKey"},
+	{Name: "SourceImageEncryption", Description: "Boot disks from encrypted source images", Supported: true, Configuration: sourceImageEncryptionKeyAnnotation + ", " + sourceImageEncryptionKeySecretAnnotation},
+	{Name: "ProvisionedDiskPerformance", Description: "Provisioned IOPS and throughput for Extreme PD and Hyperdisk", Supported: true, Configuration: diskPerformanceAnnotation},
+	{Name: "ConfidentialHyperdisk", Description: "Hyperdisk Balanced data disks in confidential mode for confidential VMs", Supported: false, Configuration: confidentialDisksAnnotation},
+	{Name: "DiskResourcePolicies", Description: "Resource policies, e.g. snapshot schedules, attached to disks", Supported: true, Configuration: diskResourcePoliciesAnnotation},
+	{Name: "GuestOSFeatures", Description: "Additional guest OS features of the boot disk", Supported: true, Configuration: guestOSFeaturesAnnotation},
+	{Name: "AdvancedMachineFeatures", Description: "Nested virtualization, threads per core and visible cores", Supported: true, Configuration: advancedMachineFeaturesAnnotation},
+	{Name: "Reservations", Description: "Consumption of capacity reservations", Supported: true, Configuration: reservationAffinityAnnotation + ", " + reservationsAnnotation},
+	{Name: "ZoneFallback", Description: "Creation in alternate zones when a zone runs out of resources", Supported: true, Configuration: fallbackZonesAnnotation},
+	{Name: "ServiceAccounts", Description: "Service account and scopes of the instance", Supported: true, Configuration: "providerSpec.serviceAccounts"},
+	{Name: "ExternalAddress", Description: "Reserved external address of the primary network interface", Supported: true, Configuration: externalAddressAnnotation},
+	{Name: "NetworkTier", Description: "Network tier of the external addresses", Supported: true, Configuration: networkTierAnnotation},
+	{Name: "StaticInternalIP", Description: "Static internal address kept across machine recreation", Supported: true, Configuration: staticInternalAddressAnnotation},
+	{Name: "PrivateServiceConnectInterfaces", Description: "Network interfaces attached to Private Service Connect network attachments", Supported: true, Configuration: networkAttachmentsAnnotation},
+	{Name: "TargetPools", Description: "Registration with target pools", Supported: true, Configuration: "providerSpec.targetPools"},
+	{Name: "InstanceGroups", Description: "Registration of control plane machines with their instance groups", Supported: true},
+	{Name: "NetworkEndpointGroups", Description: "Registration of control plane machines with the network endpoint groups of the API backend service", Supported: true, Configuration: loadBalancerConfigMapName + " ConfigMap"},
+	{Name: "NodeLabelsAndTaints", Description: "Labels and taints the node registers with", Supported: true, Configuration: nodeLabelsAnnotation + ", " + nodeTaintsAnnotation},
+	{Name: "MaintenanceWindows", Description: "Disruptive instance changes deferred until a maintenance window", Supported: true, Configuration: maintenanceWindowsAnnotation},
+	{Name: "ServiceAccountImpersonation", Description: "Impersonation of a service account by the controller", Supported: true, Configuration: "--impersonate-service-account"},
+	{Name: "WorkloadIdentityFederation", Description: "External account credentials in the credentials secret", Supported: true},
+}
+
+// NewCapabilityReport returns the capability report of this build. The state of feature gates
+// is included when the feature gates of the cluster are given.
+func NewCapabilityReport(featureGates featuregates.FeatureGate) CapabilityReport {
+	var known map[configv1.FeatureGateName]bool
+	if featureGates != nil {
+		known = map[configv1.FeatureGateName]bool{}
+		for _, name := range featureGates.KnownFeatures() {
+			known[name] = true
+		}
+	}
+
+	report := CapabilityReport{Version: version.Raw}
+	for _, capability := range capabilities {
+		gate := configv1.FeatureGateName(capability.FeatureGate)
+		if capability.FeatureGate != "" && known[gate] {
+			capability.FeatureGateEnabled = pointer.Bool(featureGates.Enabled(gate))
+		}
+		report.Capabilities = append(report.Capabilities, capability)
+	}
+	return report
+}
+
+// NewCapabilityHandler returns a handler serving the capability report as JSON.
+func NewCapabilityHandler(featureGates featuregates.FeatureGate) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(NewCapabilityReport(featureGates)); err != nil {
+			klog.Errorf("failed to write capability report: %v", err)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package machine
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	"k8s.io/utils/pointer"
+)
+
+func TestCapabilityReport(t *testing.T) {
+	cases := []struct {
+		name         string
+		featureGates featuregates.FeatureGate
+		expected     *bool
+	}{
+		{
+			name: "Feature gates unknown",
+		},
+		{
+			name:         "Feature gate enabled",
+			featureGates: featuregates.NewFeatureGate([]configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}, nil),
+			expected:     pointer.Bool(true),
+		},
+		{
+			name:         "Feature gate disabled",
+			featureGates: featuregates.NewFeatureGate(nil, []configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}),
+			expected:     pointer.Bool(false),
+		},
+		{
+			name:         "Feature gate not registered",
+			featureGates: featuregates.NewFeatureGate(nil, nil),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			NewCapabilityHandler(tc.featureGates).ServeHTTP(recorder, httptest.NewRequest("GET", CapabilitiesPath, nil))
+
+			report := CapabilityReport{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to decode report: %v", err)
+			}
+			if len(report.Capabilities) != len(capabilities) {
+				t.Fatalf("Expected %d capabilities, got %d", len(capabilities), len(report.Capabilities))
+			}
+			for _, capability := range report.Capabilities {
+				if capability.FeatureGate == "" {
+					if capability.FeatureGateEnabled != nil {
+						t.Errorf("%s: expected no feature gate state", capability.Name)
+					}
+					continue
+				}
+				if (capability.FeatureGateEnabled == nil) != (tc.expected == nil) ||
+					(tc.expected != nil && *capability.FeatureGateEnabled != *tc.expected) {
+					t.Errorf("%s: expected feature gate state %v, got %v", capability.Name, tc.expected, capability.FeatureGateEnabled)
+				}
+			}
+		})
+	}
+}
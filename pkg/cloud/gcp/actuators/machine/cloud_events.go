@@ -0,0 +1,35 @@
+package machine
+
+import (
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Reasons of the events recorded for the requests changing the cloud resources of the machine. A
+// request that fails is recorded with the reason suffixed with Failed, e.g. InstanceInsertFailed.
+const (
+	instanceInsertEvent      = "InstanceInsert"
+	instanceDeleteEvent      = "InstanceDelete"
+	targetPoolAddEvent       = "TargetPoolAdd"
+	targetPoolRemoveEvent    = "TargetPoolRemove"
+	instanceGroupAddEvent    = "InstanceGroupAdd"
+	instanceGroupRemoveEvent = "InstanceGroupRemove"
+
+	cloudMutationFailedSuffix = "Failed"
+)
+
+// recordCloudMutation records an event for a request that changes the cloud resources of the
+// machine, naming the operation it started so that the events of the machine can be matched with
+// the GCP audit logs. The outcome of the operation itself is reported once it completes, see
+// recordOperationResult.
+func (r *Reconciler) recordCloudMutation(reason, description string, operation *compute.Operation, err error) {
+	if err != nil {
+		r.eventRecorder.Eventf(r.machine, corev1.EventTypeWarning, reason+cloudMutationFailedSuffix, "Failed to %s: %v", description, err)
+		return
+	}
+	if operation == nil || operation.Name == "" {
+		r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, reason, "Requested to %s", description)
+		return
+	}
+	r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, reason, "Requested to %s, operation %s (id %d)", description, operation.Name, operation.Id)
+}
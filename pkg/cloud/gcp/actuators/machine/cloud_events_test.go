@@ -0,0 +1,79 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordCloudMutation(t *testing.T) {
+	cases := []struct {
+		name          string
+		operation     *compute.Operation
+		err           error
+		expectedEvent string
+	}{
+		{
+			name:          "Operation started",
+			operation:     &compute.Operation{Name: "operation-1234", Id: 42},
+			expectedEvent: "Normal InstanceInsert Requested to insert instance test in zone us-east1-b, operation operation-1234 (id 42)",
+		},
+		{
+			name:          "No operation returned",
+			expectedEvent: "Normal InstanceInsert Requested to insert instance test in zone us-east1-b",
+		},
+		{
+			name:          "Request failed",
+			err:           errors.New("quota exceeded"),
+			expectedEvent: "Warning InstanceInsertFailed Failed to insert instance test in zone us-east1-b: quota exceeded",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			r := newReconciler(&machineScope{
+				machine:       &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}},
+				eventRecorder: recorder,
+			})
+
+			r.recordCloudMutation(instanceInsertEvent, "insert instance test in zone us-east1-b", tc.operation, tc.err)
+
+			if event := <-recorder.Events; event != tc.expectedEvent {
+				t.Errorf("Expected event %q, got %q", tc.expectedEvent, event)
+			}
+		})
+	}
+}
+
+func TestTargetPoolEvents(t *testing.T) {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	recorder := record.NewFakeRecorder(2)
+	r := newReconciler(&machineScope{
+		machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}},
+		providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "us-east1"},
+		instanceName:   "test-instance",
+		computeService: mockComputeService,
+		eventRecorder:  recorder,
+	})
+
+	if err := r.addInstanceToTargetPool("instance-link", "pool"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.deleteInstanceFromTargetPool("instance-link", "pool"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Normal TargetPoolAdd Requested to add instance test-instance to target pool pool",
+		"Normal TargetPoolRemove Requested to remove instance test-instance from target pool pool",
+	} {
+		if event := <-recorder.Events; event != expected {
+			t.Errorf("Expected event %q, got %q", expected, event)
+		}
+	}
+}
@@ -9,6 +9,35 @@ const (
 	machineCreationSucceedReason  = "MachineCreationSucceeded"
 	machineCreationSucceedMessage = "machine successfully created"
 	machineCreationFailedReason   = "MachineCreationFailed"
+
+	// deprecatedFieldsConditionType reports whether the providerSpec uses deprecated fields.
+	deprecatedFieldsConditionType = "DeprecatedFieldsInUse"
+	deprecatedFieldsInUseReason   = "DeprecatedFieldsInUse"
+	noDeprecatedFieldsReason      = "NoDeprecatedFields"
+
+	// unsupportedFieldsConditionType reports whether the providerSpec sets fields this version of
+	// the provider does not know, e.g. fields of a newer API version, which are ignored.
+	unsupportedFieldsConditionType = "UnsupportedFieldsSet"
+	unsupportedFieldsSetReason     = "UnsupportedFieldsSet"
+	noUnsupportedFieldsReason      = "NoUnsupportedFields"
+
+	// ipForwardingConditionType reports whether the instance is allowed to send and
+	// receive packets with non-matching source or destination IPs.
+	ipForwardingConditionType  = "IPForwarding"
+	ipForwardingEnabledReason  = "IPForwardingEnabled"
+	ipForwardingEnabledMessage = "instance can send and receive packets with non-matching source or destination IPs, " +
+		"make sure firewall rules and routes restrict which traffic can be forwarded through it"
+	ipForwardingDisabledReason = "IPForwardingDisabled"
+	ipForwardingMismatchReason = "IPForwardingMismatch"
+	// loadBalancerRegistrationConditionType reports whether the instance is registered with
+	// its target pools and, for control plane machines, the control plane instance group.
+	loadBalancerRegistrationConditionType     = "LoadBalancerRegistration"
+	loadBalancerRegistrationSucceededReason   = "LoadBalancerRegistrationSucceeded"
+	loadBalancerRegistrationSkippedReason     = "LoadBalancerRegistrationSkipped"
+	loadBalancerRegistrationSucceededMessage  = "instance is registered with its load balancers"
+	loadBalancerRegistrationSkippedMessageFmt = "load balancer registration is skipped because of the %s annotation"
+
+	ipForwardingMismatchMessage = "canIPForward can only be set when the instance is created, the machine must be replaced for the providerSpec to take effect"
 )
 
 func shouldUpdateCondition(
@@ -0,0 +1,192 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"golang.org/x/oauth2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// credentialsValidConditionType reports whether GCP accepted the credentials of the machine at
+	// the last credentials check.
+	credentialsValidConditionType = "CredentialsValid"
+	credentialsAcceptedReason     = "CredentialsAccepted"
+	credentialsRejectedReason     = "CredentialsRejected"
+)
+
+// credentialsCheck periodically verifies that GCP accepts the credentials of the machines, by
+// minting an access token with them and making one read call, so that expired or revoked service
+// account keys are found before the next scale up fails. The result is recorded in the
+// CredentialsValid condition of the machines and in the mapi_gcp_credentials_valid metric.
+type credentialsCheck struct {
+	actuator *Actuator
+	interval time.Duration
+}
+
+// credentialsCheckGroup is the machines checked together: the machines in the same project using
+// the same credentials secret.
+type credentialsCheckGroup struct {
+	namespace          string
+	secretName         string
+	projectID          string
+	region             string
+	serviceAccountJSON string
+	machines           []*machinev1.Machine
+}
+
+// CredentialsCheck returns the runnable that checks the credentials of all machines every
+// interval, nil if the interval is not positive. It only runs on the leader.
+func (a *Actuator) CredentialsCheck(interval time.Duration) manager.Runnable {
+	if interval <= 0 {
+		return nil
+	}
+	return &credentialsCheck{actuator: a, interval: interval}
+}
+
+// Start checks the credentials every interval until the context is done.
+func (c *credentialsCheck) Start(ctx context.Context) error {
+	klog.Infof("Checking the credentials of all machines every %s", c.interval)
+	wait.UntilWithContext(ctx, c.check, c.interval)
+	return nil
+}
+
+// check verifies the credentials of every group of machines and records the result.
+func (c *credentialsCheck) check(ctx context.Context) {
+	machines := &machinev1.MachineList{}
+	if err := c.actuator.coreClient.List(ctx, machines); err != nil {
+		klog.Errorf("Failed to list machines to check their credentials: %v", err)
+		return
+	}
+	for _, group := range c.groupMachines(machines.Items) {
+		condition, ok := c.checkGroup(group)
+		if !ok {
+			continue
+		}
+		valid := 0.0
+		if condition.Status == metav1.ConditionTrue {
+			valid = 1
+		}
+		credentialsValid.WithLabelValues(group.namespace, group.secretName, group.projectID).Set(valid)
+		for _, machine := range group.machines {
+			if err := c.setCondition(ctx, machine, condition); err != nil {
+				klog.Errorf("%s: failed to set the %s condition: %v", machine.Name, credentialsValidConditionType, err)
+			}
+		}
+	}
+}
+
+// groupMachines groups the machines that are not being deleted by credentials secret and project.
+func (c *credentialsCheck) groupMachines(machines []machinev1.Machine) []*credentialsCheckGroup {
+	var groups []*credentialsCheckGroup
+	byKey := map[string]*credentialsCheckGroup{}
+	for i := range machines {
+		machine := &machines[i]
+		if machine.DeletionTimestamp != nil {
+			continue
+		}
+		providerSpec, err := util.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+		if err != nil || providerSpec.CredentialsSecret == nil {
+			continue
+		}
+		serviceAccountJSON, impersonateServiceAccount, err := util.GetCredentials(c.actuator.coreClient, machine.Namespace, *providerSpec)
+		if err != nil {
+			klog.V(3).Infof("%s: skipping credentials check: %v", machine.Name, err)
+			continue
+		}
+		projectID := providerSpec.ProjectID
+		if projectID == "" {
+			if projectID, err = util.GetProjectIDFromJSONKey([]byte(serviceAccountJSON)); err != nil {
+				klog.V(3).Infof("%s: skipping credentials check: error getting project from JSON key: %v", machine.Name, err)
+				continue
+			}
+		}
+		if serviceAccountJSON, err = c.actuator.credentials.Build(serviceAccountJSON, impersonateServiceAccount); err != nil {
+			klog.V(3).Infof("%s: skipping credentials check: error building credentials: %v", machine.Name, err)
+			continue
+		}
+
+		key := machine.Namespace + "\x00" + providerSpec.CredentialsSecret.Name + "\x00" + projectID + "\x00" + serviceAccountJSON
+		group, ok := byKey[key]
+		if !ok {
+			group = &credentialsCheckGroup{
+				namespace:          machine.Namespace,
+				secretName:         providerSpec.CredentialsSecret.Name,
+				projectID:          projectID,
+				region:             providerSpec.Region,
+				serviceAccountJSON: serviceAccountJSON,
+			}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.machines = append(group.machines, machine)
+	}
+	return groups
+}
+
+// checkGroup returns the CredentialsValid condition of the machines of the group. It returns false
+// when the check failed for another reason than the credentials, e.g. a GCP outage, in which case
+// the machines keep their condition.
+func (c *credentialsCheck) checkGroup(group *credentialsCheckGroup) (metav1.Condition, bool) {
+	accepted := metav1.Condition{
+		Type:    credentialsValidConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  credentialsAcceptedReason,
+		Message: fmt.Sprintf("credentials of secret %s were accepted by GCP", group.secretName),
+	}
+	rejected := func(err error) (metav1.Condition, bool) {
+		klog.Warningf("Credentials of secret %s/%s were rejected by GCP: %v", group.namespace, group.secretName, err)
+		return metav1.Condition{
+			Type:    credentialsValidConditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  credentialsRejectedReason,
+			Message: fmt.Sprintf("credentials of secret %s were rejected by GCP: %v", group.secretName, err),
+		}, true
+	}
+
+	computeService, err := c.actuator.computeClientBuilder(group.serviceAccountJSON)
+	if err != nil {
+		return rejected(err)
+	}
+	// The access token is minted by the first call.
+	if _, err := computeService.RegionGet(group.projectID, group.region); err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) || gcperrors.Is(err, gcperrors.Forbidden) {
+			return rejected(err)
+		}
+		klog.Errorf("Failed to check the credentials of secret %s/%s: %v", group.namespace, group.secretName, err)
+		return metav1.Condition{}, false
+	}
+	return accepted, true
+}
+
+// setCondition records the condition in the provider status of the machine, if it changed.
+func (c *credentialsCheck) setCondition(ctx context.Context, machine *machinev1.Machine, condition metav1.Condition) error {
+	providerStatus, err := util.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil {
+		return err
+	}
+	conditions := reconcileConditions(append([]metav1.Condition(nil), providerStatus.Conditions...), condition)
+	if equality.Semantic.DeepEqual(conditions, providerStatus.Conditions) {
+		return nil
+	}
+	providerStatus.Conditions = conditions
+	rawStatus, err := util.RawExtensionFromProviderStatus(providerStatus)
+	if err != nil {
+		return err
+	}
+	patchBase := controllerclient.MergeFromWithOptions(machine.DeepCopy(), controllerclient.MergeFromWithOptimisticLock{})
+	machine.Status.ProviderStatus = rawStatus
+	return c.actuator.coreClient.Status().Patch(ctx, machine, patchBase)
+}
@@ -0,0 +1,123 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCredentialsCheck(t *testing.T) {
+	cases := []struct {
+		name           string
+		regionErr      error
+		existing       []metav1.Condition
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "Accepted credentials",
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: credentialsAcceptedReason,
+		},
+		{
+			name:           "Revoked key",
+			regionErr:      &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusBadRequest}, Body: []byte(`{"error": "invalid_grant"}`)},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: credentialsRejectedReason,
+		},
+		{
+			name:           "Service account without permissions",
+			regionErr:      &googleapi.Error{Code: http.StatusForbidden},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: credentialsRejectedReason,
+		},
+		{
+			name:           "Outage keeps the condition",
+			regionErr:      errors.New("connection reset by peer"),
+			existing:       []metav1.Condition{{Type: credentialsValidConditionType, Status: metav1.ConditionTrue, Reason: credentialsAcceptedReason}},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: credentialsAcceptedReason,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			credentialsSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: defaultNamespaceName},
+				Data:       map[string][]byte{credentialsSecretKey: []byte(`{"project_id": "test"}`)},
+			}
+			providerSpec, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+				Region:            "us-east1",
+				CredentialsSecret: &corev1.LocalObjectReference{Name: credentialsSecretName},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			providerStatus, err := util.RawExtensionFromProviderStatus(&machinev1.GCPMachineProviderStatus{Conditions: tc.existing})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var machines []controllerclient.Object
+			for _, name := range []string{"worker-a", "worker-b"} {
+				machines = append(machines, &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultNamespaceName},
+					Spec:       machinev1.MachineSpec{ProviderSpec: machinev1.ProviderSpec{Value: providerSpec}},
+					Status:     machinev1.MachineStatus{ProviderStatus: providerStatus},
+				})
+			}
+			c := controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(append(machines, credentialsSecret)...).
+				WithStatusSubresource(&machinev1.Machine{}).Build()
+
+			var calls int
+			_, mock := computeservice.NewComputeServiceMock()
+			mock.MockRegionGet = func(project string, region string) (*compute.Region, error) {
+				calls++
+				if project != "test" || region != "us-east1" {
+					t.Errorf("Unexpected region %s of project %s", region, project)
+				}
+				return &compute.Region{}, tc.regionErr
+			}
+			actuator := NewActuator(ActuatorParams{
+				CoreClient: c,
+				ComputeClientBuilder: func(serviceAccountJSON string) (computeservice.GCPComputeService, error) {
+					return mock, nil
+				},
+			})
+
+			actuator.CredentialsCheck(time.Minute).(*credentialsCheck).check(context.Background())
+
+			if calls != 1 {
+				t.Errorf("Expected one call for the machines sharing credentials, got %d", calls)
+			}
+			for _, machine := range machines {
+				updated := &machinev1.Machine{}
+				if err := c.Get(context.Background(), controllerclient.ObjectKeyFromObject(machine), updated); err != nil {
+					t.Fatal(err)
+				}
+				status, err := util.ProviderStatusFromRawExtension(updated.Status.ProviderStatus)
+				if err != nil {
+					t.Fatal(err)
+				}
+				condition := findCondition(status.Conditions, credentialsValidConditionType)
+				if condition == nil || condition.Status != tc.expectedStatus || condition.Reason != tc.expectedReason {
+					t.Errorf("%s: expected a %s condition with reason %s, got %v", machine.GetName(), tc.expectedStatus, tc.expectedReason, condition)
+				}
+			}
+		})
+	}
+}
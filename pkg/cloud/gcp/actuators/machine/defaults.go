@@ -0,0 +1,162 @@
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// DefaultsVersionAnnotation is set by the defaulting webhook to the version of the defaults it
+	// applied to a Machine or MachineSet when it was created. Its later updates get the defaults of
+	// that version, so that a new version of the defaults does not change existing machines. Objects
+	// without it, e.g. created before the webhook, are not defaulted.
+	DefaultsVersionAnnotation = gcpAnnotationPrefix + "defaults-version"
+
+	// LatestDefaultsVersion is the version of the defaults applied to new objects by default.
+	LatestDefaultsVersion = 1
+
+	// DefaultCredentialsSecret is the credentials secret the cloud credential operator creates for
+	// the machine API.
+	DefaultCredentialsSecret = "gcp-cloud-credentials"
+
+	defaultDiskType       = "pd-ssd"
+	defaultBootDiskSizeGB = 128
+)
+
+// ProviderSpecDefaults are the defaults of the providerSpec of new machines, so that a minimal
+// MachineSet, e.g. with only a zone, a machine type and a boot image, is usable.
+type ProviderSpecDefaults struct {
+	// Version is the version of the defaults applied to new objects, zero disables the defaults.
+	Version int
+	// CredentialsSecret is the name of the credentials secret of machines that do not set one.
+	CredentialsSecret string
+}
+
+// providerSpecDefaulters are the defaults, each applied from a version of the defaults on. A change
+// of a default is a new defaulter with a new version, the old one applying until that version.
+var providerSpecDefaulters = []struct {
+	since, until int
+	apply        func(d ProviderSpecDefaults, spec map[string]interface{}, annotations map[string]string) error
+}{
+	{since: 1, apply: defaultRegion},
+	{since: 1, apply: defaultDisks},
+	{since: 1, apply: defaultCredentialsSecret},
+	{since: 1, apply: defaultNetworkTier},
+}
+
+// Default applies the defaults of the version to the raw providerSpec, and to the annotations of
+// the machine, which is returned as it may be allocated. The providerSpec is edited as JSON so that
+// the fields this version of the API does not know are kept.
+func (d ProviderSpecDefaults) Default(version int, raw []byte, annotations map[string]string) ([]byte, map[string]string, error) {
+	spec := map[string]interface{}{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode providerSpec: %w", err)
+		}
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for _, defaulter := range providerSpecDefaulters {
+		if version < defaulter.since || (defaulter.until != 0 && version >= defaulter.until) {
+			continue
+		}
+		if err := defaulter.apply(d, spec, annotations); err != nil {
+			return nil, nil, err
+		}
+	}
+	defaulted, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode providerSpec: %w", err)
+	}
+	return defaulted, annotations, nil
+}
+
+// DefaultsVersion returns the version of the defaults recorded in the annotations, zero when none
+// is recorded.
+func DefaultsVersion(annotations map[string]string) (int, error) {
+	value, ok := annotations[DefaultsVersionAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid value %q for annotation %s, must be a version", value, DefaultsVersionAnnotation)
+	}
+	return version, nil
+}
+
+// DefaultGenerateName returns the prefix of the generated names of machines, the names of their
+// instances, with the dots that Kubernetes allows in names but GCP does not in instance names
+// replaced by dashes.
+func DefaultGenerateName(generateName string) string {
+	return strings.ReplaceAll(generateName, ".", "-")
+}
+
+// defaultRegion derives the region from the zone, e.g. us-central1 from us-central1-a.
+func defaultRegion(_ ProviderSpecDefaults, spec map[string]interface{}, _ map[string]string) error {
+	if region, _, _ := unstructured.NestedString(spec, "region"); region != "" {
+		return nil
+	}
+	zone, _, _ := unstructured.NestedString(spec, "zone")
+	if !zonePattern.MatchString(zone) {
+		return nil
+	}
+	return unstructured.SetNestedField(spec, zone[:strings.LastIndex(zone, "-")], "region")
+}
+
+// defaultDisks sets the type of the disks without one to pd-ssd, and the size of a boot disk
+// without one to 128 GB instead of the size of its image, which is too small for a node.
+func defaultDisks(_ ProviderSpecDefaults, spec map[string]interface{}, _ map[string]string) error {
+	disks, _ := spec["disks"].([]interface{})
+	for _, value := range disks {
+		disk, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if diskType, _ := disk["type"].(string); diskType == "" {
+			disk["type"] = defaultDiskType
+		}
+		boot, _ := disk["boot"].(bool)
+		if size, _ := disk["sizeGb"].(float64); boot && size == 0 {
+			disk["sizeGb"] = int64(defaultBootDiskSizeGB)
+		}
+	}
+	return nil
+}
+
+// defaultCredentialsSecret names the credentials secret of machines that do not name one.
+func defaultCredentialsSecret(d ProviderSpecDefaults, spec map[string]interface{}, _ map[string]string) error {
+	if d.CredentialsSecret == "" {
+		return nil
+	}
+	if name, _, _ := unstructured.NestedString(spec, "credentialsSecret", "name"); name != "" {
+		return nil
+	}
+	return unstructured.SetNestedField(spec, d.CredentialsSecret, "credentialsSecret", "name")
+}
+
+// defaultNetworkTier requests the Premium network tier for the external addresses of machines with
+// a public IP, instead of the default tier of the project, so that the same MachineSet gets the
+// same network in every project.
+func defaultNetworkTier(_ ProviderSpecDefaults, spec map[string]interface{}, annotations map[string]string) error {
+	if _, ok := annotations[networkTierAnnotation]; ok {
+		return nil
+	}
+	networkInterfaces, _ := spec["networkInterfaces"].([]interface{})
+	for _, value := range networkInterfaces {
+		networkInterface, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if publicIP, _ := networkInterface["publicIP"].(bool); publicIP {
+			annotations[networkTierAnnotation] = premiumNetworkTier
+			return nil
+		}
+	}
+	return nil
+}
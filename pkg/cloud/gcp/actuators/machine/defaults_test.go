@@ -0,0 +1,102 @@
+package machine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProviderSpecDefaults(t *testing.T) {
+	defaults := ProviderSpecDefaults{Version: LatestDefaultsVersion, CredentialsSecret: DefaultCredentialsSecret}
+
+	cases := []struct {
+		name                string
+		version             int
+		spec                string
+		annotations         map[string]string
+		expectedSpec        string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:                "Minimal",
+			version:             1,
+			spec:                `{"zone":"us-central1-a","machineType":"n1-standard-4","disks":[{"boot":true,"image":"rhcos"},{"sizeGb":200}],"networkInterfaces":[{"network":"net","publicIP":true}]}`,
+			expectedSpec:        `{"credentialsSecret":{"name":"gcp-cloud-credentials"},"disks":[{"boot":true,"image":"rhcos","sizeGb":128,"type":"pd-ssd"},{"sizeGb":200,"type":"pd-ssd"}],"machineType":"n1-standard-4","networkInterfaces":[{"network":"net","publicIP":true}],"region":"us-central1","zone":"us-central1-a"}`,
+			expectedAnnotations: map[string]string{networkTierAnnotation: premiumNetworkTier},
+		},
+		{
+			name:                "Set fields are kept",
+			version:             1,
+			spec:                `{"region":"us-central1","zone":"us-central1-a","credentialsSecret":{"name":"mine"},"disks":[{"boot":true,"type":"pd-balanced","sizeGb":64}],"networkInterfaces":[{"publicIP":true}]}`,
+			annotations:         map[string]string{networkTierAnnotation: standardNetworkTier},
+			expectedSpec:        `{"credentialsSecret":{"name":"mine"},"disks":[{"boot":true,"sizeGb":64,"type":"pd-balanced"}],"networkInterfaces":[{"publicIP":true}],"region":"us-central1","zone":"us-central1-a"}`,
+			expectedAnnotations: map[string]string{networkTierAnnotation: standardNetworkTier},
+		},
+		{
+			name:                "Unknown fields are kept",
+			version:             1,
+			spec:                `{"zone":"not-a-zone","futureField":{"a":1}}`,
+			expectedSpec:        `{"credentialsSecret":{"name":"gcp-cloud-credentials"},"futureField":{"a":1},"zone":"not-a-zone"}`,
+			expectedAnnotations: map[string]string{},
+		},
+		{
+			name:                "Version zero",
+			version:             0,
+			spec:                `{"zone":"us-central1-a","disks":[{"boot":true}]}`,
+			expectedSpec:        `{"disks":[{"boot":true}],"zone":"us-central1-a"}`,
+			expectedAnnotations: map[string]string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, annotations, err := defaults.Default(tc.version, []byte(tc.spec), tc.annotations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(spec) != tc.expectedSpec {
+				t.Errorf("Expected providerSpec %s, got %s", tc.expectedSpec, spec)
+			}
+			if !reflect.DeepEqual(annotations, tc.expectedAnnotations) {
+				t.Errorf("Expected annotations %v, got %v", tc.expectedAnnotations, annotations)
+			}
+		})
+	}
+
+	if _, _, err := defaults.Default(1, []byte(`[]`), nil); err == nil {
+		t.Error("Expected an error for a providerSpec that is not an object")
+	}
+}
+
+func TestProviderSpecDefaultersAreVersioned(t *testing.T) {
+	for i, defaulter := range providerSpecDefaulters {
+		if defaulter.since < 1 || defaulter.since > LatestDefaultsVersion {
+			t.Errorf("Defaulter %d applies since version %d, want between 1 and %d", i, defaulter.since, LatestDefaultsVersion)
+		}
+		if defaulter.until != 0 && defaulter.until <= defaulter.since {
+			t.Errorf("Defaulter %d applies until version %d, before its version %d", i, defaulter.until, defaulter.since)
+		}
+	}
+}
+
+func TestDefaultsVersion(t *testing.T) {
+	for value, expected := range map[string]int{"1": 1, "0": 0} {
+		version, err := DefaultsVersion(map[string]string{DefaultsVersionAnnotation: value})
+		if err != nil || version != expected {
+			t.Errorf("Expected version %d for %q, got %d, %v", expected, value, version, err)
+		}
+	}
+	if version, err := DefaultsVersion(nil); err != nil || version != 0 {
+		t.Errorf("Expected version 0 without annotation, got %d, %v", version, err)
+	}
+	for _, value := range []string{"v1", "-1"} {
+		if _, err := DefaultsVersion(map[string]string{DefaultsVersionAnnotation: value}); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestDefaultGenerateName(t *testing.T) {
+	if name := DefaultGenerateName("cluster.example-worker-"); name != "cluster-example-worker-" {
+		t.Errorf("Expected cluster-example-worker-, got %s", name)
+	}
+}
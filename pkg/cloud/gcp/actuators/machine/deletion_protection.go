@@ -0,0 +1,87 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeletionProtectionPolicy is what the reconciler does when the instance of a deleted machine has
+// deletion protection enabled.
+type DeletionProtectionPolicy string
+
+const (
+	// DeletionProtectionPolicyRefuse keeps the instance and reports the machine in the
+	// DeletionBlocked condition until the protection is removed.
+	DeletionProtectionPolicyRefuse DeletionProtectionPolicy = "refuse"
+	// DeletionProtectionPolicyClear removes the protection and deletes the instance.
+	DeletionProtectionPolicyClear DeletionProtectionPolicy = "clear"
+
+	// deletionBlockedConditionType reports whether the deletion of the instance is blocked by its
+	// deletion protection.
+	deletionBlockedConditionType     = "DeletionBlocked"
+	deletionProtectionEnabledReason  = "DeletionProtectionEnabled"
+	deletionProtectionDisabledReason = "DeletionProtectionDisabled"
+	deletionProtectionClearedEvent   = "DeletionProtectionCleared"
+)
+
+// ParseDeletionProtectionPolicy returns the policy of the given name.
+func ParseDeletionProtectionPolicy(name string) (DeletionProtectionPolicy, error) {
+	switch policy := DeletionProtectionPolicy(name); policy {
+	case DeletionProtectionPolicyRefuse, DeletionProtectionPolicyClear:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown deletion protection policy %q, expected %s or %s", name, DeletionProtectionPolicyRefuse, DeletionProtectionPolicyClear)
+}
+
+// reconcileDeletionProtection handles the deletion protection of the instance of a deleted machine,
+// set either by the providerSpec or outside the controller, which makes instances.delete fail. It
+// returns nil once the instance may be deleted. With the clear policy the protection is removed,
+// otherwise the deletion is refused and reported in the DeletionBlocked condition. Instances without
+// protection only get the condition if it was reported before.
+func (r *Reconciler) reconcileDeletionProtection(instance *compute.Instance) error {
+	if instance == nil || !instance.DeletionProtection {
+		if findCondition(r.providerStatus.Conditions, deletionBlockedConditionType) != nil {
+			r.setDeletionBlockedCondition(metav1.ConditionFalse, deletionProtectionDisabledReason, "instance does not have deletion protection enabled")
+		}
+		return nil
+	}
+
+	if r.options.DeletionProtectionPolicy != DeletionProtectionPolicyClear {
+		message := fmt.Sprintf("instance %s has deletion protection enabled, disable it in the cloud console or with gcloud compute instances update --no-deletion-protection for the machine to be deleted", instance.Name)
+		r.setDeletionBlockedCondition(metav1.ConditionTrue, deletionProtectionEnabledReason, message)
+		// The machine is not persisted after a delete, persist the condition right away.
+		if err := r.Close(); err != nil {
+			r.log.Error(err, "Failed to report blocked deletion")
+		}
+		return fmt.Errorf("deletion refused: %s", message)
+	}
+
+	r.log.Info("Clearing deletion protection of instance", "instance", instance.Name)
+	operation, err := r.computeService.InstancesSetDeletionProtection(r.projectID, r.providerSpec.Zone, instance.Name, false)
+	if err != nil {
+		return fmt.Errorf("failed to clear deletion protection: %w", err)
+	}
+	if errs := operationErrors(operation); len(errs) > 0 {
+		return fmt.Errorf("clearing deletion protection failed: %s", strings.Join(errs, "; "))
+	}
+	r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, deletionProtectionClearedEvent, "Cleared deletion protection of instance %s", instance.Name)
+	if operation != nil && operation.Status != operationDoneStatus {
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+	return nil
+}
+
+func (r *Reconciler) setDeletionBlockedCondition(status metav1.ConditionStatus, reason, message string) {
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    deletionBlockedConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
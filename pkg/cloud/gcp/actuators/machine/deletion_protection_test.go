@@ -0,0 +1,191 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileDeletionProtection(t *testing.T) {
+	cases := []struct {
+		name               string
+		deletionProtection bool
+		policy             DeletionProtectionPolicy
+		existingConditions []metav1.Condition
+		expectedError      bool
+		expectedCleared    bool
+		expectedReason     string
+		expectedPersisted  bool
+	}{
+		{
+			name: "Instance without protection is deleted",
+		},
+		{
+			name:               "Protected instance is not deleted",
+			deletionProtection: true,
+			policy:             DeletionProtectionPolicyRefuse,
+			expectedError:      true,
+			expectedReason:     deletionProtectionEnabledReason,
+			expectedPersisted:  true,
+		},
+		{
+			name:               "Protected instance is not deleted by default",
+			deletionProtection: true,
+			expectedError:      true,
+			expectedReason:     deletionProtectionEnabledReason,
+			expectedPersisted:  true,
+		},
+		{
+			name:               "Protection is cleared",
+			deletionProtection: true,
+			policy:             DeletionProtectionPolicyClear,
+			expectedCleared:    true,
+		},
+		{
+			name: "Condition is cleared once the protection was removed",
+			existingConditions: []metav1.Condition{
+				{Type: deletionBlockedConditionType, Status: metav1.ConditionTrue, Reason: deletionProtectionEnabledReason},
+			},
+			expectedReason: deletionProtectionDisabledReason,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			cleared := false
+			mockComputeService.MockSetDeletionProtection = func(project string, zone string, instance string, deletionProtection bool) (*compute.Operation, error) {
+				cleared = !deletionProtection
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}}
+			coreClient := controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine.DeepCopy()).WithStatusSubresource(&machinev1.Machine{}).Build()
+			providerStatus := &machinev1.GCPMachineProviderStatus{Conditions: tc.existingConditions}
+			r := newReconciler(&machineScope{
+				Context:            context.Background(),
+				coreClient:         coreClient,
+				machine:            machine,
+				origMachine:        machine.DeepCopy(),
+				machineToBePatched: controllerclient.MergeFrom(machine.DeepCopy()),
+				providerSpec:       &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"},
+				providerStatus:     providerStatus,
+				origProviderStatus: providerStatus.DeepCopy(),
+				computeService:     mockComputeService,
+				eventRecorder:      record.NewFakeRecorder(2),
+				options:            Options{DeletionProtectionPolicy: tc.policy},
+			})
+
+			err := r.reconcileDeletionProtection(&compute.Instance{Name: "test", DeletionProtection: tc.deletionProtection})
+			if (err != nil) != tc.expectedError {
+				t.Errorf("Expected an error %v, got %v", tc.expectedError, err)
+			}
+			if cleared != tc.expectedCleared {
+				t.Errorf("Expected the deletion protection to be cleared %v, got %v", tc.expectedCleared, cleared)
+			}
+			condition := findCondition(r.providerStatus.Conditions, deletionBlockedConditionType)
+			switch {
+			case tc.expectedReason == "" && condition != nil:
+				t.Errorf("Expected no condition, got %v", condition)
+			case tc.expectedReason != "" && (condition == nil || condition.Reason != tc.expectedReason):
+				t.Errorf("Expected a condition with reason %s, got %v", tc.expectedReason, condition)
+			}
+
+			persisted := &machinev1.Machine{}
+			if err := coreClient.Get(context.Background(), controllerclient.ObjectKeyFromObject(machine), persisted); err != nil {
+				t.Fatal(err)
+			}
+			persistedStatus, err := util.ProviderStatusFromRawExtension(persisted.Status.ProviderStatus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if blocked := findCondition(persistedStatus.Conditions, deletionBlockedConditionType) != nil; blocked != tc.expectedPersisted {
+				t.Errorf("Expected the condition to be persisted %v, got %v", tc.expectedPersisted, blocked)
+			}
+		})
+	}
+}
+
+type deregistrationTrackingComputeService struct {
+	*computeservice.GCPComputeServiceMock
+	calls []string
+}
+
+func (c *deregistrationTrackingComputeService) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
+	return &compute.Instance{
+		Name:               instance,
+		Labels:             map[string]string{"kubernetes-io-cluster-" + computeservice.MockClusterID: "owned"},
+		DeletionProtection: true,
+	}, nil
+}
+
+func (c *deregistrationTrackingComputeService) TargetPoolsRemoveInstance(project string, region string, name string, instance string) (*compute.Operation, error) {
+	c.calls = append(c.calls, "TargetPoolsRemoveInstance")
+	return nil, nil
+}
+
+func (c *deregistrationTrackingComputeService) InstanceGroupsRemoveInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
+	c.calls = append(c.calls, "InstanceGroupsRemoveInstances")
+	return &compute.Operation{Status: "DONE"}, nil
+}
+
+func (c *deregistrationTrackingComputeService) NetworkEndpointGroupsDetachNetworkEndpoints(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	c.calls = append(c.calls, "NetworkEndpointGroupsDetachNetworkEndpoints")
+	return &compute.Operation{Status: "DONE"}, nil
+}
+
+func (c *deregistrationTrackingComputeService) InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error) {
+	c.calls = append(c.calls, "InstancesDelete")
+	return &compute.Operation{Status: "DONE"}, nil
+}
+
+func TestDeleteRefusedKeepsLoadBalancerMemberships(t *testing.T) {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	computeService := &deregistrationTrackingComputeService{GCPComputeServiceMock: mockComputeService}
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+		Name:      "testInstance",
+		Namespace: "test",
+		Labels: map[string]string{
+			machinev1.MachineClusterIDLabel: computeservice.MockClusterID,
+			openshiftMachineRoleLabel:       masterMachineRole,
+		},
+	}}
+	coreClient := controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine.DeepCopy()).WithStatusSubresource(&machinev1.Machine{}).Build()
+	providerStatus := &machinev1.GCPMachineProviderStatus{}
+	r := newReconciler(&machineScope{
+		Context:            context.Background(),
+		coreClient:         coreClient,
+		machine:            machine,
+		origMachine:        machine.DeepCopy(),
+		machineToBePatched: controllerclient.MergeFrom(machine.DeepCopy()),
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Zone:        "zone1",
+			Region:      computeservice.WithMachineInPool,
+			TargetPools: []string{"pool"},
+		},
+		providerStatus:     providerStatus,
+		origProviderStatus: providerStatus.DeepCopy(),
+		projectID:          "testProject",
+		instanceName:       "testInstance",
+		computeService:     computeService,
+		eventRecorder:      record.NewFakeRecorder(2),
+	})
+
+	if err := r.delete(); err == nil {
+		t.Fatal("Expected the deletion to be refused")
+	}
+	if len(computeService.calls) != 0 {
+		t.Errorf("Expected no deregistration nor deletion, got %v", computeService.calls)
+	}
+	if condition := findCondition(r.providerStatus.Conditions, deletionBlockedConditionType); condition == nil || condition.Reason != deletionProtectionEnabledReason {
+		t.Errorf("Expected a condition with reason %s, got %v", deletionProtectionEnabledReason, condition)
+	}
+}
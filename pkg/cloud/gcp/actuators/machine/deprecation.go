@@ -0,0 +1,66 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// deprecatedField describes a providerSpec field that is still honored but should be migrated away from.
+type deprecatedField struct {
+	name        string
+	replacement string
+	inUse       func(*machinev1.GCPMachineProviderSpec) bool
+}
+
+var deprecatedFields = []deprecatedField{
+	{
+		name:        "preemptible",
+		replacement: "Spot VMs",
+		inUse:       func(spec *machinev1.GCPMachineProviderSpec) bool { return spec.Preemptible },
+	},
+	{
+		name:        "targetPools",
+		replacement: "backend services",
+		inUse:       func(spec *machinev1.GCPMachineProviderSpec) bool { return len(spec.TargetPools) > 0 },
+	},
+}
+
+// reconcileDeprecatedFieldsCondition sets an informational condition and metric listing the
+// deprecated providerSpec fields the machine uses. Machines that never used deprecated
+// fields do not get the condition.
+func (r *Reconciler) reconcileDeprecatedFieldsCondition() {
+	var inUse []string
+	for _, field := range deprecatedFields {
+		labels := prometheus.Labels{"name": r.machine.Name, "namespace": r.machine.Namespace, "field": field.name}
+		if !field.inUse(r.providerSpec) {
+			deprecatedFieldInUse.Delete(labels)
+			continue
+		}
+		deprecatedFieldInUse.With(labels).Set(1)
+		inUse = append(inUse, fmt.Sprintf("%s (use %s instead)", field.name, field.replacement))
+	}
+
+	condition := metav1.Condition{
+		Type:    deprecatedFieldsConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  noDeprecatedFieldsReason,
+		Message: "providerSpec does not use deprecated fields",
+	}
+	if len(inUse) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = deprecatedFieldsInUseReason
+		condition.Message = "providerSpec uses deprecated fields: " + strings.Join(inUse, ", ")
+	} else if findCondition(r.providerStatus.Conditions, deprecatedFieldsConditionType) == nil {
+		return
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, condition)
+}
+
+// forgetDeprecatedFieldsMetrics removes the deprecated field metrics of a deleted machine.
+func (r *Reconciler) forgetDeprecatedFieldsMetrics() {
+	deprecatedFieldInUse.DeletePartialMatch(prometheus.Labels{"name": r.machine.Name, "namespace": r.machine.Namespace})
+}
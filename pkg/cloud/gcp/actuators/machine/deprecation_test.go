@@ -0,0 +1,91 @@
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func countDeprecatedFieldMetrics() int {
+	ch := make(chan prometheus.Metric, len(deprecatedFields)+1)
+	deprecatedFieldInUse.Collect(ch)
+	close(ch)
+	return len(ch)
+}
+
+func TestReconcileDeprecatedFieldsCondition(t *testing.T) {
+	cases := []struct {
+		name               string
+		providerSpec       *machinev1.GCPMachineProviderSpec
+		existingConditions []metav1.Condition
+		expectedStatus     metav1.ConditionStatus
+		expectedMessage    string
+		expectedMetrics    int
+	}{
+		{
+			name:         "No condition without deprecated fields",
+			providerSpec: &machinev1.GCPMachineProviderSpec{},
+		},
+		{
+			name: "Deprecated fields in use",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Preemptible: true,
+				TargetPools: []string{"pool"},
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "providerSpec uses deprecated fields: preemptible (use Spot VMs instead), targetPools (use backend services instead)",
+			expectedMetrics: 2,
+		},
+		{
+			name:         "Condition is cleared after migration",
+			providerSpec: &machinev1.GCPMachineProviderSpec{},
+			existingConditions: []metav1.Condition{
+				{Type: deprecatedFieldsConditionType, Status: metav1.ConditionTrue, Reason: deprecatedFieldsInUseReason},
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedMessage: "providerSpec does not use deprecated fields",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      strings.ReplaceAll(tc.name, " ", "-"),
+						Namespace: "test",
+					},
+				},
+				providerSpec:   tc.providerSpec,
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.existingConditions},
+			})
+			defer r.forgetDeprecatedFieldsMetrics()
+
+			r.reconcileDeprecatedFieldsCondition()
+
+			if metrics := countDeprecatedFieldMetrics(); metrics != tc.expectedMetrics {
+				t.Errorf("Expected %d metrics, got %d", tc.expectedMetrics, metrics)
+			}
+
+			condition := findCondition(r.providerStatus.Conditions, deprecatedFieldsConditionType)
+			if tc.expectedStatus == "" {
+				if condition != nil {
+					t.Errorf("Expected no condition, got %v", condition)
+				}
+				return
+			}
+			if condition == nil {
+				t.Fatal("Expected condition, got none")
+			}
+			if condition.Status != tc.expectedStatus {
+				t.Errorf("Expected: %s, got %s", tc.expectedStatus, condition.Status)
+			}
+			if condition.Message != tc.expectedMessage {
+				t.Errorf("Expected: %s, got %s", tc.expectedMessage, condition.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	provisioningDiagnosticsNameFmt = "%s-provisioning-diagnostics"
+	provisioningTimeoutEventReason = "ProvisioningTimeout"
+
+	// serialConsoleExcerptBytes is how much of the end of the serial console output is kept,
+	// well below the size limit of a ConfigMap.
+	serialConsoleExcerptBytes = 64 * 1024
+
+	diagnosticsOperationKey     = "operation"
+	diagnosticsInstanceKey      = "instance"
+	diagnosticsSerialConsoleKey = "serial-console"
+	diagnosticsErrorsKey        = "errors"
+)
+
+// reconcileProvisioningTimeout escalates machines that did not become a node within the provisioning
+// timeout after their instance was created: the outcome of the create operation, the state of the
+// instance and an excerpt of its serial console are bundled into a ConfigMap referenced from the
+// provisioningDiagnosticsAnnotation, and a warning event is emitted. Failures to collect or store the
+// bundle are logged and retried on the next reconcile.
+func (r *Reconciler) reconcileProvisioningTimeout() {
+	if r.options.ProvisioningTimeout <= 0 || r.machine.Status.NodeRef != nil {
+		return
+	}
+	if _, ok := r.getAnnotation(provisioningDiagnosticsAnnotation); ok {
+		return
+	}
+	created := findCondition(r.providerStatus.Conditions, string(machinev1.MachineCreated))
+	if created == nil || created.Status != metav1.ConditionTrue || r.clock.Since(created.LastTransitionTime.Time) < r.options.ProvisioningTimeout {
+		return
+	}
+
+	r.log.Info("Machine did not become a node in time, collecting diagnostics", "provisioningTimeout", r.options.ProvisioningTimeout)
+	name, err := r.storeProvisioningDiagnostics(r.collectProvisioningDiagnostics())
+	if err != nil {
+		r.log.Error(err, "Failed to store provisioning diagnostics")
+		return
+	}
+
+	if r.machine.Annotations == nil {
+		r.machine.Annotations = map[string]string{}
+	}
+	r.machine.Annotations[provisioningDiagnosticsAnnotation] = name
+	r.eventRecorder.Eventf(r.machine, corev1.EventTypeWarning, provisioningTimeoutEventReason,
+		"Machine did not become a node within %s, diagnostics are stored in ConfigMap %s", r.options.ProvisioningTimeout, name)
+}
+
+// collectProvisioningDiagnostics gathers the diagnostics bundle on a best effort basis, errors
+// are recorded in the bundle itself.
+func (r *Reconciler) collectProvisioningDiagnostics() map[string]string {
+	bundle := map[string]string{}
+	var errs []string
+
+	if name, ok := r.getAnnotation(createOperationAnnotation); ok && name != "" {
+		operation, err := r.computeService.ZoneOperationsGet(r.projectID, r.providerSpec.Zone, name)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to get create operation %s: %v", name, err))
+		} else if data, err := json.MarshalIndent(operation, "", "  "); err == nil {
+			bundle[diagnosticsOperationKey] = string(data)
+		}
+	}
+
+	instance, err := r.computeService.InstancesGet(r.projectID, r.providerSpec.Zone, r.instanceName)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("failed to get instance: %v", err))
+	} else {
+		// The metadata holds the user data, which must not be copied out of its secret.
+		redacted := *instance
+		redacted.Metadata = nil
+		if data, err := json.MarshalIndent(&redacted, "", "  "); err == nil {
+			bundle[diagnosticsInstanceKey] = string(data)
+		}
+	}
+
+	output, err := r.computeService.InstancesGetSerialPortOutput(r.projectID, r.providerSpec.Zone, r.instanceName, -serialConsoleExcerptBytes)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("failed to get serial console output: %v", err))
+	} else {
+		contents := output.Contents
+		if len(contents) > serialConsoleExcerptBytes {
+			contents = contents[len(contents)-serialConsoleExcerptBytes:]
+		}
+		bundle[diagnosticsSerialConsoleKey] = contents
+	}
+
+	if len(errs) > 0 {
+		bundle[diagnosticsErrorsKey] = strings.Join(errs, "\n")
+	}
+	return bundle
+}
+
+// storeProvisioningDiagnostics writes the bundle to a ConfigMap owned by the machine and returns its name.
+func (r *Reconciler) storeProvisioningDiagnostics(bundle map[string]string) (string, error) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf(provisioningDiagnosticsNameFmt, r.machine.Name),
+			Namespace: r.machine.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: machinev1.GroupVersion.String(),
+				Kind:       "Machine",
+				Name:       r.machine.Name,
+				UID:        r.machine.UID,
+				Controller: pointer.Bool(false),
+			}},
+		},
+		Data: bundle,
+	}
+
+	err := r.coreClient.Create(r.Context, configMap)
+	if apimachineryerrors.IsAlreadyExists(err) {
+		existing := &corev1.ConfigMap{}
+		if err := r.coreClient.Get(r.Context, client.ObjectKeyFromObject(configMap), existing); err != nil {
+			return "", err
+		}
+		existing.Data = bundle
+		err = r.coreClient.Update(r.Context, existing)
+	}
+	if err != nil {
+		return "", err
+	}
+	return configMap.Name, nil
+}
@@ -0,0 +1,130 @@
+package machine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileProvisioningTimeout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name              string
+		createdAt         time.Time
+		nodeRef           *corev1.ObjectReference
+		annotations       map[string]string
+		expectDiagnostics bool
+	}{
+		{
+			name:      "Machine within the provisioning timeout",
+			createdAt: now.Add(-10 * time.Minute),
+		},
+		{
+			name:      "Machine that became a node",
+			createdAt: now.Add(-time.Hour),
+			nodeRef:   &corev1.ObjectReference{Name: "node"},
+		},
+		{
+			name:        "Diagnostics already collected",
+			createdAt:   now.Add(-time.Hour),
+			annotations: map[string]string{provisioningDiagnosticsAnnotation: "existing"},
+		},
+		{
+			name:              "Machine past the provisioning timeout",
+			createdAt:         now.Add(-time.Hour),
+			annotations:       map[string]string{createOperationAnnotation: "operation-1"},
+			expectDiagnostics: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockZoneOperationsGet = func(_ string, _ string, operation string) (*compute.Operation, error) {
+				return &compute.Operation{Name: operation, Status: "DONE", Error: &compute.OperationError{
+					Errors: []*compute.OperationErrorErrors{{Code: "ZONE_RESOURCE_POOL_EXHAUSTED"}},
+				}}, nil
+			}
+			fakeClient := controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+			recorder := record.NewFakeRecorder(1)
+
+			r := newReconciler(&machineScope{
+				Context:    context.Background(),
+				coreClient: fakeClient,
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "worker-a",
+						Namespace:   "openshift-machine-api",
+						UID:         types.UID("uid"),
+						Annotations: tc.annotations,
+					},
+					Status: machinev1.MachineStatus{NodeRef: tc.nodeRef},
+				},
+				providerSpec: &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"},
+				providerStatus: &machinev1.GCPMachineProviderStatus{
+					Conditions: []metav1.Condition{{
+						Type:               string(machinev1.MachineCreated),
+						Status:             metav1.ConditionTrue,
+						LastTransitionTime: metav1.NewTime(tc.createdAt),
+					}},
+				},
+				computeService: mockComputeService,
+				clock:          clocktesting.NewFakeClock(now),
+				eventRecorder:  recorder,
+				options:        Options{ProvisioningTimeout: 30 * time.Minute},
+			})
+
+			r.reconcileProvisioningTimeout()
+
+			configMap := &corev1.ConfigMap{}
+			err := fakeClient.Get(context.Background(), types.NamespacedName{Namespace: "openshift-machine-api", Name: "worker-a-provisioning-diagnostics"}, configMap)
+			if !tc.expectDiagnostics {
+				if err == nil {
+					t.Errorf("Expected no diagnostics, got %v", configMap.Data)
+				}
+				if len(recorder.Events) != 0 {
+					t.Errorf("Expected no events, got %s", <-recorder.Events)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected the diagnostics ConfigMap to be created: %v", err)
+			}
+
+			if !strings.Contains(configMap.Data[diagnosticsOperationKey], "ZONE_RESOURCE_POOL_EXHAUSTED") {
+				t.Errorf("Expected the operation error in the bundle, got %q", configMap.Data[diagnosticsOperationKey])
+			}
+			if !strings.Contains(configMap.Data[diagnosticsInstanceKey], `"status": "RUNNING"`) {
+				t.Errorf("Expected the instance in the bundle, got %q", configMap.Data[diagnosticsInstanceKey])
+			}
+			if configMap.Data[diagnosticsSerialConsoleKey] != "serial console output" {
+				t.Errorf("Expected the serial console output in the bundle, got %q", configMap.Data[diagnosticsSerialConsoleKey])
+			}
+			if _, ok := configMap.Data[diagnosticsErrorsKey]; ok {
+				t.Errorf("Expected no collection errors, got %q", configMap.Data[diagnosticsErrorsKey])
+			}
+			if len(configMap.OwnerReferences) != 1 || configMap.OwnerReferences[0].UID != "uid" {
+				t.Errorf("Expected the ConfigMap to be owned by the machine, got %v", configMap.OwnerReferences)
+			}
+			if r.machine.Annotations[provisioningDiagnosticsAnnotation] != configMap.Name {
+				t.Errorf("Expected the machine to reference the ConfigMap, got annotations %v", r.machine.Annotations)
+			}
+			if event := <-recorder.Events; !strings.Contains(event, provisioningTimeoutEventReason) {
+				t.Errorf("Expected a %s event, got %q", provisioningTimeoutEventReason, event)
+			}
+		})
+	}
+}
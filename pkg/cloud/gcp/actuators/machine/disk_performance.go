@@ -0,0 +1,165 @@
+package machine
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	diskLimitExceededReason      = "DiskLimitExceeded"
+	diskPerformanceCappedReason  = "DiskPerformanceCapped"
+	defaultMaxPersistentDisks    = 128
+	sharedCoreMaxPersistentDisks = 16
+)
+
+// diskPerformance is the performance provisioned for a disk through the diskPerformanceAnnotation.
+type diskPerformance struct {
+	ProvisionedIops       int64 `json:"provisionedIops,omitempty"`
+	ProvisionedThroughput int64 `json:"provisionedThroughput,omitempty"`
+}
+
+// diskTypeLimit is the range of IOPS and throughput, in MiB/s, that can be provisioned for a single
+// disk of a type. Zero maximums mean that the value cannot be provisioned for the type.
+type diskTypeLimit struct {
+	minIops, maxIops             int64
+	minThroughput, maxThroughput int64
+}
+
+// diskTypeLimits are the disk types whose performance is provisioned, see
+// https://cloud.google.com/compute/docs/disks/extreme-persistent-disk and
+// https://cloud.google.com/compute/docs/disks/hyperdisks.
+var diskTypeLimits = map[string]diskTypeLimit{
+	"pd-extreme":           {minIops: 10000, maxIops: 120000},
+	"hyperdisk-balanced":   {minIops: 3000, maxIops: 160000, minThroughput: 140, maxThroughput: 2400},
+	"hyperdisk-extreme":    {minIops: 2, maxIops: 350000},
+	"hyperdisk-throughput": {minThroughput: 10, maxThroughput: 600},
+}
+
+// vmPerformanceLimit is the IOPS and throughput, in MiB/s, an instance with at least minCPUs vCPUs
+// can get from all of its disks with provisioned performance together. Anything provisioned above
+// it is silently capped by GCP.
+type vmPerformanceLimit struct {
+	minCPUs       int64
+	maxIops       int64
+	maxThroughput int64
+}
+
+// vmPerformanceLimits is ordered by decreasing vCPU count. The values are the lowest limits across
+// machine families documented for Hyperdisk and Extreme PD; they are only used for warnings.
+var vmPerformanceLimits = []vmPerformanceLimit{
+	{minCPUs: 64, maxIops: 350000, maxThroughput: 5000},
+	{minCPUs: 32, maxIops: 160000, maxThroughput: 2400},
+	{minCPUs: 16, maxIops: 80000, maxThroughput: 1200},
+	{minCPUs: 8, maxIops: 50000, maxThroughput: 800},
+	{minCPUs: 4, maxIops: 25000, maxThroughput: 400},
+	{minCPUs: 0, maxIops: 15000, maxThroughput: 240},
+}
+
+// sharedCoreMachineTypePrefixes identify the shared-core machine types, which can attach fewer disks.
+var sharedCoreMachineTypePrefixes = []string{"e2-micro", "e2-small", "e2-medium", "f1-micro", "g1-small"}
+
+// diskPerformances returns the performance provisioned per disk index through the diskPerformanceAnnotation.
+func (r *Reconciler) diskPerformances() (map[int]diskPerformance, error) {
+	raw := map[string]diskPerformance{}
+	if ok, err := r.getJSONAnnotation(diskPerformanceAnnotation, &raw); err != nil || !ok {
+		return nil, err
+	}
+
+	performances := make(map[int]diskPerformance, len(raw))
+	for key, performance := range raw {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(r.providerSpec.Disks) {
+			return nil, machinecontroller.InvalidMachineConfiguration("invalid %s annotation: %q is not the index of a disk", diskPerformanceAnnotation, key)
+		}
+		performances[index] = performance
+	}
+	return performances, nil
+}
+
+// checkDiskPerformance validates the disks of the instance against the limits of their disk types
+// and of the machine type. Disks the machine type cannot attach and performance a disk type cannot
+// provide fail the creation, performance the instance as a whole would be capped at is only warned about.
+func (r *Reconciler) checkDiskPerformance(state *preflightState) error {
+	maxDisks := int64(defaultMaxPersistentDisks)
+	if containsAny(r.providerSpec.MachineType, sharedCoreMachineTypePrefixes) {
+		maxDisks = sharedCoreMaxPersistentDisks
+	}
+	if state.machineType != nil && state.machineType.MaximumPersistentDisks > 0 {
+		maxDisks = state.machineType.MaximumPersistentDisks
+	}
+	if int64(len(state.instance.Disks)) > maxDisks {
+		return &preflightError{
+			reason: diskLimitExceededReason,
+			err: machinecontroller.InvalidMachineConfiguration("machine type %s can attach at most %d disks, %d requested",
+				r.providerSpec.MachineType, maxDisks, len(state.instance.Disks)),
+		}
+	}
+
+	var totalIops, totalThroughput int64
+	for i, disk := range state.instance.Disks {
+		if disk.InitializeParams == nil {
+			continue
+		}
+		params := disk.InitializeParams
+		if params.ProvisionedIops == 0 && params.ProvisionedThroughput == 0 {
+			continue
+		}
+		diskType := path.Base(params.DiskType)
+		limit := diskTypeLimits[diskType]
+		if err := checkProvisioned(i, diskType, "IOPS", params.ProvisionedIops, limit.minIops, limit.maxIops); err != nil {
+			return err
+		}
+		if err := checkProvisioned(i, diskType, "throughput", params.ProvisionedThroughput, limit.minThroughput, limit.maxThroughput); err != nil {
+			return err
+		}
+		totalIops += params.ProvisionedIops
+		totalThroughput += params.ProvisionedThroughput
+	}
+
+	if state.machineType == nil || (totalIops == 0 && totalThroughput == 0) {
+		return nil
+	}
+	for _, limit := range vmPerformanceLimits {
+		if state.machineType.GuestCpus < limit.minCPUs {
+			continue
+		}
+		var capped []string
+		if totalIops > limit.maxIops {
+			capped = append(capped, fmt.Sprintf("%d IOPS are capped at %d", totalIops, limit.maxIops))
+		}
+		if totalThroughput > limit.maxThroughput {
+			capped = append(capped, fmt.Sprintf("%d MiB/s are capped at %d MiB/s", totalThroughput, limit.maxThroughput))
+		}
+		if len(capped) > 0 {
+			message := fmt.Sprintf("disk performance provisioned for machine type %s exceeds what the instance can use: %s", r.providerSpec.MachineType, strings.Join(capped, ", "))
+			r.log.Info("Disk performance exceeds what the instance can use", "machineType", r.providerSpec.MachineType, "capped", capped)
+			r.eventRecorder.Event(r.machine, corev1.EventTypeWarning, diskPerformanceCappedReason, message)
+		}
+		break
+	}
+	return nil
+}
+
+// checkProvisioned validates the IOPS or throughput provisioned for a disk against the range of its disk type.
+func checkProvisioned(index int, diskType, what string, value, min, max int64) error {
+	switch {
+	case value == 0:
+		return nil
+	case max == 0:
+		return &preflightError{
+			reason: diskLimitExceededReason,
+			err:    machinecontroller.InvalidMachineConfiguration("disk %d: %s cannot be provisioned for disk type %s", index, what, diskType),
+		}
+	case value < min || value > max:
+		return &preflightError{
+			reason: diskLimitExceededReason,
+			err:    machinecontroller.InvalidMachineConfiguration("disk %d: provisioned %s of disk type %s must be between %d and %d, got %d", index, what, diskType, min, max, value),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestCheckDiskPerformanceWarnings(t *testing.T) {
+	cases := []struct {
+		name          string
+		guestCPUs     int64
+		iops          []int64
+		expectedEvent string
+	}{
+		{
+			name:      "Within the limits of the machine type",
+			guestCPUs: 16,
+			iops:      []int64{40000, 40000},
+		},
+		{
+			name:          "Capped by the machine type",
+			guestCPUs:     4,
+			iops:          []int64{20000, 20000},
+			expectedEvent: "Warning DiskPerformanceCapped disk performance provisioned for machine type n2-standard-4 exceeds what the instance can use: 40000 IOPS are capped at 25000",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			r := newReconciler(&machineScope{
+				machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{MachineType: "n2-standard-4"},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				eventRecorder:  recorder,
+			})
+
+			state := &preflightState{
+				instance:    &compute.Instance{},
+				machineType: &compute.MachineType{GuestCpus: tc.guestCPUs},
+			}
+			for _, iops := range tc.iops {
+				state.instance.Disks = append(state.instance.Disks, &compute.AttachedDisk{InitializeParams: &compute.AttachedDiskInitializeParams{
+					DiskType:        "zones/test-zone/diskTypes/hyperdisk-extreme",
+					ProvisionedIops: iops,
+				}})
+			}
+
+			if err := r.checkDiskPerformance(state); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			select {
+			case event := <-recorder.Events:
+				if event != tc.expectedEvent {
+					t.Errorf("Expected event %q, got %q", tc.expectedEvent, event)
+				}
+			default:
+				if tc.expectedEvent != "" {
+					t.Errorf("Expected event %q", tc.expectedEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestDiskPerformances(t *testing.T) {
+	cases := []struct {
+		name          string
+		annotation    string
+		expected      map[int]diskPerformance
+		expectedError string
+	}{
+		{
+			name: "No annotation",
+		},
+		{
+			name:       "Performance of a disk",
+			annotation: `{"1": {"provisionedIops": 20000, "provisionedThroughput": 500}}`,
+			expected:   map[int]diskPerformance{1: {ProvisionedIops: 20000, ProvisionedThroughput: 500}},
+		},
+		{
+			name:          "Unknown disk",
+			annotation:    `{"2": {"provisionedIops": 20000}}`,
+			expectedError: "invalid machine.openshift.io/gcp-disk-performance annotation: \"2\" is not the index of a disk",
+		},
+		{
+			name:          "Unknown field",
+			annotation:    `{"0": {"iops": 20000}}`,
+			expectedError: "unknown field",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if tc.annotation != "" {
+				machine.Annotations = map[string]string{diskPerformanceAnnotation: tc.annotation}
+			}
+			r := newReconciler(&machineScope{
+				machine:      machine,
+				providerSpec: &machinev1.GCPMachineProviderSpec{Disks: []*machinev1.GCPDisk{{Boot: true}, {Type: "hyperdisk-balanced"}}},
+			})
+
+			performances, err := r.diskPerformances()
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(performances) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, performances)
+			}
+			for index, performance := range tc.expected {
+				if performances[index] != performance {
+					t.Errorf("Expected disk %d to have %v, got %v", index, performance, performances[index])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package machine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	resourcePolicyLinkFmt = "projects/%s/regions/%s/resourcePolicies/%s"
+	// confidentialDiskType is the only disk type that can be created in confidential mode.
+	confidentialDiskType = "hyperdisk-balanced"
+)
+
+// supportedGuestOSFeatures are the guest OS features that can be enabled on a boot disk,
+// see https://cloud.google.com/compute/docs/images/create-custom#guest-os-features.
+var supportedGuestOSFeatures = sets.NewString(
+	"GVNIC",
+	"MULTI_IP_SUBNET",
+	"SECURE_BOOT",
+	"SEV_CAPABLE",
+	"SEV_LIVE_MIGRATABLE",
+	"SEV_SNP_CAPABLE",
+	"UEFI_COMPATIBLE",
+	"VIRTIO_SCSI_MULTIQUEUE",
+	"WINDOWS",
+)
+
+// guestOSFeatures returns the guest OS features listed in the guestOSFeaturesAnnotation
+// that are enabled on the boot disk of the machine.
+func (r *Reconciler) guestOSFeatures() ([]*compute.GuestOsFeature, error) {
+	var features []*compute.GuestOsFeature
+	seen := sets.NewString()
+	for _, value := range r.getListAnnotation(guestOSFeaturesAnnotation) {
+		feature := strings.ToUpper(value)
+		if !supportedGuestOSFeatures.Has(feature) {
+			return nil, machinecontroller.InvalidMachineConfiguration("invalid guest OS feature %q in %s annotation, must be one of %s", value, guestOSFeaturesAnnotation, strings.Join(supportedGuestOSFeatures.List(), ", "))
+		}
+		if seen.Has(feature) {
+			continue
+		}
+		seen.Insert(feature)
+		features = append(features, &compute.GuestOsFeature{Type: feature})
+	}
+
+	if len(features) > 0 && !hasBootDisk(r.providerSpec.Disks) {
+		return nil, machinecontroller.InvalidMachineConfiguration("%s annotation is set but the machine has no boot disk", guestOSFeaturesAnnotation)
+	}
+	return features, nil
+}
+
+// hasBootDisk returns whether one of the disks is the boot disk.
+func hasBootDisk(disks []*machinev1.GCPDisk) bool {
+	for _, disk := range disks {
+		if disk.Boot {
+			return true
+		}
+	}
+	return false
+}
+
+// diskResourcePolicies resolves the resource policies listed in the diskResourcePoliciesAnnotation
+// to their links and checks that they can be attached to the disks of the machine.
+func (r *Reconciler) diskResourcePolicies() ([]string, error) {
+	var links []string
+	snapshotSchedules := 0
+	for _, ref := range r.getListAnnotation(diskResourcePoliciesAnnotation) {
+		project, region, name := r.projectID, r.providerSpec.Region, ref
+		if strings.Contains(ref, "/") {
+			var err error
+			if project, region, name, err = parseRegionalResourceLink(ref, "resourcePolicies"); err != nil {
+				return nil, machinecontroller.InvalidMachineConfiguration("invalid %s annotation: %v", diskResourcePoliciesAnnotation, err)
+			}
+		}
+		if region != r.providerSpec.Region {
+			return nil, machinecontroller.InvalidMachineConfiguration("resource policy %q is in region %q, but the machine is in region %q", ref, region, r.providerSpec.Region)
+		}
+
+		policy, err := r.computeService.ResourcePoliciesGet(project, region, name)
+		if err != nil {
+			if isNotFoundError(err) {
+				return nil, machinecontroller.InvalidMachineConfiguration("resource policy %q not found", ref)
+			}
+			return nil, fmt.Errorf("failed to get resource policy %q: %w", ref, err)
+		}
+		if policy.SnapshotSchedulePolicy == nil {
+			return nil, machinecontroller.InvalidMachineConfiguration("resource policy %q is not a snapshot schedule and cannot be attached to disks", ref)
+		}
+		// A disk can only have a single snapshot schedule attached.
+		if snapshotSchedules++; snapshotSchedules > 1 {
+			return nil, machinecontroller.InvalidMachineConfiguration("only one snapshot schedule can be attached to a disk, got %d in %s annotation", snapshotSchedules, diskResourcePoliciesAnnotation)
+		}
+
+		links = append(links, fmt.Sprintf(resourcePolicyLinkFmt, project, region, name))
+	}
+	return links, nil
+}
+
+// confidentialDisks validates the disks listed in the confidentialDisksAnnotation and returns their
+// indexes. Only Hyperdisk Balanced data disks encrypted with a customer-managed key can be
+// created in confidential mode, and only for confidential VMs.
+func (r *Reconciler) confidentialDisks() (sets.Int, error) {
+	indexes := sets.NewInt()
+	for _, value := range r.getListAnnotation(confidentialDisksAnnotation) {
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(r.providerSpec.Disks) {
+			return nil, machinecontroller.InvalidMachineConfiguration("invalid %s annotation: %q is not the index of a disk", confidentialDisksAnnotation, value)
+		}
+		disk := r.providerSpec.Disks[index]
+		switch {
+		case disk.Boot:
+			return nil, machinecontroller.InvalidMachineConfiguration("disk %d: the boot disk cannot be created in confidential mode", index)
+		case disk.Type != confidentialDiskType:
+			return nil, machinecontroller.InvalidMachineConfiguration("disk %d: only %s disks can be created in confidential mode, got %s", index, confidentialDiskType, disk.Type)
+		case disk.EncryptionKey == nil || disk.EncryptionKey.KMSKey == nil:
+			return nil, machinecontroller.InvalidMachineConfiguration("disk %d: disks in confidential mode must be encrypted with a customer-managed encryption key", index)
+		}
+		indexes.Insert(index)
+	}
+
+	if indexes.Len() == 0 {
+		return indexes, nil
+	}
+	if r.providerSpec.ConfidentialCompute != machinev1.ConfidentialComputePolicyEnabled {
+		return nil, machinecontroller.InvalidMachineConfiguration("%s annotation is set but confidential compute is not enabled for the machine", confidentialDisksAnnotation)
+	}
+	// The compute API client of this build predates enableConfidentialCompute on disks. Reject the
+	// request rather than silently create disks that are not confidential.
+	return nil, machinecontroller.InvalidMachineConfiguration("disks %v cannot be created in confidential mode: the compute API client of this build does not support enableConfidentialCompute on disks", indexes.List())
+}
@@ -0,0 +1,91 @@
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfidentialDisks(t *testing.T) {
+	encryptionKey := &machinev1.GCPEncryptionKeyReference{KMSKey: &machinev1.GCPKMSKeyReference{Name: "key", KeyRing: "ring", Location: "us-east1"}}
+	disks := []*machinev1.GCPDisk{
+		{Boot: true, Type: "hyperdisk-balanced", EncryptionKey: encryptionKey},
+		{Type: "hyperdisk-balanced", EncryptionKey: encryptionKey},
+		{Type: "pd-ssd", EncryptionKey: encryptionKey},
+		{Type: "hyperdisk-balanced"},
+	}
+
+	cases := []struct {
+		name                string
+		annotation          string
+		confidentialCompute machinev1.ConfidentialComputePolicy
+		expectedError       string
+	}{
+		{
+			name: "No annotation",
+		},
+		{
+			name:          "Unknown disk",
+			annotation:    "4",
+			expectedError: "\"4\" is not the index of a disk",
+		},
+		{
+			name:                "Boot disk",
+			annotation:          "0",
+			confidentialCompute: machinev1.ConfidentialComputePolicyEnabled,
+			expectedError:       "disk 0: the boot disk cannot be created in confidential mode",
+		},
+		{
+			name:                "Disk type without confidential mode",
+			annotation:          "2",
+			confidentialCompute: machinev1.ConfidentialComputePolicyEnabled,
+			expectedError:       "disk 2: only hyperdisk-balanced disks can be created in confidential mode, got pd-ssd",
+		},
+		{
+			name:                "Disk without customer-managed encryption key",
+			annotation:          "3",
+			confidentialCompute: machinev1.ConfidentialComputePolicyEnabled,
+			expectedError:       "disk 3: disks in confidential mode must be encrypted with a customer-managed encryption key",
+		},
+		{
+			name:          "Machine is not a confidential VM",
+			annotation:    "1",
+			expectedError: "confidential compute is not enabled for the machine",
+		},
+		{
+			name:                "Valid combination is not supported by the compute API client",
+			annotation:          "1",
+			confidentialCompute: machinev1.ConfidentialComputePolicyEnabled,
+			expectedError:       "disks [1] cannot be created in confidential mode",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if tc.annotation != "" {
+				machine.Annotations = map[string]string{confidentialDisksAnnotation: tc.annotation}
+			}
+			r := newReconciler(&machineScope{
+				machine:      machine,
+				providerSpec: &machinev1.GCPMachineProviderSpec{Disks: disks, ConfidentialCompute: tc.confidentialCompute},
+			})
+
+			_, err := r.confidentialDisks()
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("Expected error containing %q, got %v", tc.expectedError, err)
+			}
+			if !isInvalidMachineConfigurationError(err) {
+				t.Errorf("Expected an invalid machine configuration error, got %v", err)
+			}
+		})
+	}
+}
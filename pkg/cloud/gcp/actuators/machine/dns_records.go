@@ -0,0 +1,213 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	dnsRecordRegisteredEventReason   = "DNSRecordRegistered"
+	dnsRecordDeregisteredEventReason = "DNSRecordDeregistered"
+
+	// DefaultDNSRecordNameTemplate names the DNS records of machines after the machines.
+	DefaultDNSRecordNameTemplate = "{{.MachineName}}"
+	// DefaultDNSRecordTTL is the TTL, in seconds, of the DNS records of machines.
+	DefaultDNSRecordTTL = 300
+)
+
+// dnsRecordTypes are the types of the records of the IPv4 and IPv6 addresses of machines.
+var dnsRecordTypes = []string{"A", "AAAA"}
+
+// DNSRecords configures the registration of the internal IPs of machines in a Cloud DNS zone, see
+// ParseDNSRecords.
+type DNSRecords struct {
+	// project of the zone, empty for the project of the machine.
+	project string
+	zone    string
+	name    *template.Template
+	ttl     int64
+}
+
+// ParseDNSRecords parses the Cloud DNS zone, as [project/]zone, the Go template of the names of the
+// records, relative to the zone, from the name, namespace, cluster ID and role of the machine, and
+// the TTL of the records. It returns nil when the zone is empty, as records are not registered then.
+func ParseDNSRecords(zone, nameTemplate string, ttl int64) (*DNSRecords, error) {
+	if zone == "" {
+		return nil, nil
+	}
+	project, name, hasProject := strings.Cut(zone, "/")
+	if !hasProject {
+		project, name = "", zone
+	}
+	if name == "" || strings.Contains(name, "/") || (hasProject && project == "") {
+		return nil, fmt.Errorf("zone %q is not a zone name or a project/zone", zone)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("TTL %d is not positive", ttl)
+	}
+	tmpl, err := parseMachineTemplate("dns-record-name", nameTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &DNSRecords{project: project, zone: name, name: tmpl, ttl: ttl}, nil
+}
+
+// dnsRecord is the DNS record configuration rendered for a machine.
+type dnsRecord struct {
+	project string
+	zone    string
+	// name of the records relative to the zone.
+	name string
+	ttl  int64
+}
+
+// renderDNSRecord renders the DNS records configuration for a machine, nil if records are not
+// registered.
+func renderDNSRecord(records *DNSRecords, machine *machinev1.Machine, projectID string) (*dnsRecord, error) {
+	if records == nil {
+		return nil, nil
+	}
+	var name strings.Builder
+	if err := records.name.Execute(&name, newInstanceNameData(machine)); err != nil {
+		return nil, fmt.Errorf("failed to render the DNS record name template: %w", err)
+	}
+	record := &dnsRecord{
+		project: records.project,
+		zone:    records.zone,
+		name:    strings.Trim(strings.ToLower(name.String()), "."),
+		ttl:     records.ttl,
+	}
+	if record.project == "" {
+		record.project = projectID
+	}
+	if record.name == "" {
+		return nil, fmt.Errorf("DNS record name template gives an empty name")
+	}
+	return record, nil
+}
+
+// reconcileDNSRecords registers the internal IPv4 and IPv6 addresses of the primary network
+// interface of the instance as A and AAAA records of the configured zone, updating records that
+// point elsewhere. The records of a previous name, e.g. after the template changed, are removed.
+func (r *Reconciler) reconcileDNSRecords(instance *compute.Instance) error {
+	if r.dnsRecord == nil || r.dnsService == nil || len(instance.NetworkInterfaces) == 0 {
+		return nil
+	}
+	zone, err := r.dnsService.ManagedZonesGet(r.Context, r.dnsRecord.project, r.dnsRecord.zone)
+	if err != nil {
+		return fmt.Errorf("failed to get DNS zone %s of project %s: %w", r.dnsRecord.zone, r.dnsRecord.project, err)
+	}
+	fqdn := r.dnsRecord.name + "." + zone.DNSName
+
+	addresses := instanceAddresses(instance)
+	if previous := r.machine.Annotations[dnsRecordAnnotation]; previous != "" && previous != fqdn {
+		if err := r.deleteDNSRecords(previous, addresses); err != nil {
+			return err
+		}
+	}
+
+	for _, recordType := range dnsRecordTypes {
+		if addresses[recordType] == "" {
+			continue
+		}
+		if err := r.ensureDNSRecord(fqdn, recordType, addresses[recordType]); err != nil {
+			return err
+		}
+	}
+
+	if r.machine.Annotations == nil {
+		r.machine.Annotations = map[string]string{}
+	}
+	r.machine.Annotations[dnsRecordAnnotation] = fqdn
+	return nil
+}
+
+// ensureDNSRecord creates the record of the given name and type pointing at the address, or updates
+// it if it points elsewhere or has another TTL.
+func (r *Reconciler) ensureDNSRecord(fqdn, recordType, address string) error {
+	desired := &dnsservice.ResourceRecordSet{Name: fqdn, Type: recordType, TTL: r.dnsRecord.ttl, Rrdatas: []string{address}}
+	current, err := r.dnsService.ResourceRecordSetsGet(r.Context, r.dnsRecord.project, r.dnsRecord.zone, fqdn, recordType)
+	switch {
+	case isNotFoundError(err):
+		if _, err := r.dnsService.ResourceRecordSetsCreate(r.Context, r.dnsRecord.project, r.dnsRecord.zone, desired); err != nil {
+			return fmt.Errorf("failed to create %s record %s: %w", recordType, fqdn, err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get %s record %s: %w", recordType, fqdn, err)
+	case current.TTL == desired.TTL && len(current.Rrdatas) == 1 && current.Rrdatas[0] == address:
+		return nil
+	default:
+		if _, err := r.dnsService.ResourceRecordSetsPatch(r.Context, r.dnsRecord.project, r.dnsRecord.zone, desired); err != nil {
+			return fmt.Errorf("failed to update %s record %s: %w", recordType, fqdn, err)
+		}
+	}
+	r.log.Info("Registered the DNS record of the machine", "record", fqdn, "type", recordType, "address", address)
+	r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, dnsRecordRegisteredEventReason,
+		"Registered %s record %s for %s", recordType, fqdn, address)
+	return nil
+}
+
+// deregisterDNSRecords removes the DNS records of the machine before its instance is deleted. The
+// instance is nil when it does not exist anymore, the addresses of the machine are used then.
+func (r *Reconciler) deregisterDNSRecords(instance *compute.Instance) error {
+	fqdn := r.machine.Annotations[dnsRecordAnnotation]
+	if fqdn == "" || r.dnsRecord == nil || r.dnsService == nil {
+		return nil
+	}
+	addresses := map[string]string{}
+	if instance != nil {
+		addresses = instanceAddresses(instance)
+	}
+	for _, address := range r.machine.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP && addresses["A"] == "" {
+			addresses["A"] = address.Address
+		}
+	}
+	if err := r.deleteDNSRecords(fqdn, addresses); err != nil {
+		return err
+	}
+	delete(r.machine.Annotations, dnsRecordAnnotation)
+	return nil
+}
+
+// deleteDNSRecords deletes the records of the given name that point at the addresses of the
+// machine. Records pointing elsewhere belong to someone else by now and are kept.
+func (r *Reconciler) deleteDNSRecords(fqdn string, addresses map[string]string) error {
+	for _, recordType := range dnsRecordTypes {
+		current, err := r.dnsService.ResourceRecordSetsGet(r.Context, r.dnsRecord.project, r.dnsRecord.zone, fqdn, recordType)
+		if isNotFoundError(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get %s record %s: %w", recordType, fqdn, err)
+		}
+		if addresses[recordType] == "" || len(current.Rrdatas) != 1 || current.Rrdatas[0] != addresses[recordType] {
+			r.log.Info("Keeping the DNS record, it does not point at the machine", "record", fqdn, "type", recordType, "data", current.Rrdatas)
+			continue
+		}
+		err = r.dnsService.ResourceRecordSetsDelete(r.Context, r.dnsRecord.project, r.dnsRecord.zone, fqdn, recordType)
+		if err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("failed to delete %s record %s: %w", recordType, fqdn, err)
+		}
+		r.log.Info("Deregistered the DNS record of the machine", "record", fqdn, "type", recordType)
+		r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, dnsRecordDeregisteredEventReason,
+			"Deregistered %s record %s", recordType, fqdn)
+	}
+	return nil
+}
+
+// instanceAddresses returns the internal addresses of the primary network interface of the
+// instance by DNS record type.
+func instanceAddresses(instance *compute.Instance) map[string]string {
+	if len(instance.NetworkInterfaces) == 0 {
+		return map[string]string{}
+	}
+	nic := instance.NetworkInterfaces[0]
+	return map[string]string{"A": nic.NetworkIP, "AAAA": nic.Ipv6Address}
+}
@@ -0,0 +1,247 @@
+package machine
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestParseDNSRecords(t *testing.T) {
+	records, err := ParseDNSRecords("dns-project/nodes", "{{.MachineName}}.{{.Role}}", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+		Name:   "Worker-A",
+		Labels: map[string]string{openshiftMachineRoleLabel: "worker"},
+	}}
+	rendered, err := renderDNSRecord(records, machine, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := dnsRecord{project: "dns-project", zone: "nodes", name: "worker-a.worker", ttl: 60}
+	if *rendered != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *rendered)
+	}
+
+	records, err = ParseDNSRecords("nodes", DefaultDNSRecordNameTemplate, DefaultDNSRecordTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered, err = renderDNSRecord(records, machine, "project"); err != nil || rendered.project != "project" {
+		t.Errorf("Expected the zone in the project of the machine, got %+v, %v", rendered, err)
+	}
+
+	if records, err := ParseDNSRecords("", DefaultDNSRecordNameTemplate, DefaultDNSRecordTTL); records != nil || err != nil {
+		t.Errorf("Expected no records without a zone, got %+v, %v", records, err)
+	}
+	for _, tc := range []struct {
+		zone, nameTemplate string
+		ttl                int64
+	}{
+		{"/nodes", DefaultDNSRecordNameTemplate, DefaultDNSRecordTTL},
+		{"project/", DefaultDNSRecordNameTemplate, DefaultDNSRecordTTL},
+		{"nodes", "{{.Zone}}", DefaultDNSRecordTTL},
+		{"nodes", DefaultDNSRecordNameTemplate, 0},
+	} {
+		if _, err := ParseDNSRecords(tc.zone, tc.nameTemplate, tc.ttl); err == nil {
+			t.Errorf("Expected an error for %+v", tc)
+		}
+	}
+}
+
+func TestReconcileDNSRecords(t *testing.T) {
+	const fqdn = "worker-a.example.com."
+
+	cases := []struct {
+		name               string
+		previous           string
+		records            map[string]*dnsservice.ResourceRecordSet
+		ipv6Address        string
+		expectedCreated    []string
+		expectedPatched    []string
+		expectedDeleted    []string
+		expectedAnnotation string
+	}{
+		{
+			name:               "Record is created",
+			expectedCreated:    []string{"A " + fqdn + " 10.0.0.2"},
+			expectedAnnotation: fqdn,
+		},
+		{
+			name:               "IPv6 address gets an AAAA record",
+			ipv6Address:        "fd20::2",
+			expectedCreated:    []string{"A " + fqdn + " 10.0.0.2", "AAAA " + fqdn + " fd20::2"},
+			expectedAnnotation: fqdn,
+		},
+		{
+			name: "Record is up to date",
+			records: map[string]*dnsservice.ResourceRecordSet{
+				"A " + fqdn: {Name: fqdn, Type: "A", TTL: 300, Rrdatas: []string{"10.0.0.2"}},
+			},
+			expectedAnnotation: fqdn,
+		},
+		{
+			name: "Record pointing elsewhere is updated",
+			records: map[string]*dnsservice.ResourceRecordSet{
+				"A " + fqdn: {Name: fqdn, Type: "A", TTL: 300, Rrdatas: []string{"10.0.0.9"}},
+			},
+			expectedPatched:    []string{"A " + fqdn + " 10.0.0.2"},
+			expectedAnnotation: fqdn,
+		},
+		{
+			name:     "Records of the previous name are removed",
+			previous: "old.example.com.",
+			records: map[string]*dnsservice.ResourceRecordSet{
+				"A old.example.com.": {Name: "old.example.com.", Type: "A", TTL: 300, Rrdatas: []string{"10.0.0.2"}},
+			},
+			expectedCreated:    []string{"A " + fqdn + " 10.0.0.2"},
+			expectedDeleted:    []string{"A old.example.com."},
+			expectedAnnotation: fqdn,
+		},
+		{
+			name:     "Records of the previous name pointing elsewhere are kept",
+			previous: "old.example.com.",
+			records: map[string]*dnsservice.ResourceRecordSet{
+				"A old.example.com.": {Name: "old.example.com.", Type: "A", TTL: 300, Rrdatas: []string{"10.0.0.9"}},
+			},
+			expectedCreated:    []string{"A " + fqdn + " 10.0.0.2"},
+			expectedAnnotation: fqdn,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDNSService, created, patched, deleted := newDNSServiceMock(t, tc.records)
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-a"}}
+			if tc.previous != "" {
+				machine.Annotations = map[string]string{dnsRecordAnnotation: tc.previous}
+			}
+			r := newReconciler(&machineScope{
+				Context:        context.Background(),
+				machine:        machine,
+				providerSpec:   &machinev1.GCPMachineProviderSpec{},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				eventRecorder:  record.NewFakeRecorder(10),
+				dnsService:     mockDNSService,
+				dnsRecord:      &dnsRecord{project: "project", zone: "nodes", name: "worker-a", ttl: 300},
+			})
+			instance := &compute.Instance{NetworkInterfaces: []*compute.NetworkInterface{{NetworkIP: "10.0.0.2", Ipv6Address: tc.ipv6Address}}}
+
+			if err := r.reconcileDNSRecords(instance); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expectStrings(t, "created", tc.expectedCreated, *created)
+			expectStrings(t, "patched", tc.expectedPatched, *patched)
+			expectStrings(t, "deleted", tc.expectedDeleted, *deleted)
+			if annotation := r.machine.Annotations[dnsRecordAnnotation]; annotation != tc.expectedAnnotation {
+				t.Errorf("Expected annotation %q, got %q", tc.expectedAnnotation, annotation)
+			}
+		})
+	}
+}
+
+func TestDeregisterDNSRecords(t *testing.T) {
+	const fqdn = "worker-a.example.com."
+	records := map[string]*dnsservice.ResourceRecordSet{
+		"A " + fqdn:    {Name: fqdn, Type: "A", TTL: 300, Rrdatas: []string{"10.0.0.2"}},
+		"AAAA " + fqdn: {Name: fqdn, Type: "AAAA", TTL: 300, Rrdatas: []string{"fd20::9"}},
+	}
+
+	cases := []struct {
+		name            string
+		instance        *compute.Instance
+		expectedDeleted []string
+	}{
+		{
+			name:            "Instance exists",
+			instance:        &compute.Instance{NetworkInterfaces: []*compute.NetworkInterface{{NetworkIP: "10.0.0.2", Ipv6Address: "fd20::9"}}},
+			expectedDeleted: []string{"A " + fqdn, "AAAA " + fqdn},
+		},
+		{
+			name:            "Instance is gone",
+			expectedDeleted: []string{"A " + fqdn},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDNSService, _, _, deleted := newDNSServiceMock(t, records)
+			machine := &machinev1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-a", Annotations: map[string]string{dnsRecordAnnotation: fqdn}},
+				Status:     machinev1.MachineStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.2"}}},
+			}
+			r := newReconciler(&machineScope{
+				Context:        context.Background(),
+				machine:        machine,
+				providerSpec:   &machinev1.GCPMachineProviderSpec{},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				eventRecorder:  record.NewFakeRecorder(10),
+				dnsService:     mockDNSService,
+				dnsRecord:      &dnsRecord{project: "project", zone: "nodes", name: "worker-a", ttl: 300},
+			})
+
+			if err := r.deregisterDNSRecords(tc.instance); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expectStrings(t, "deleted", tc.expectedDeleted, *deleted)
+			if _, ok := r.machine.Annotations[dnsRecordAnnotation]; ok {
+				t.Errorf("Expected the %s annotation to be removed", dnsRecordAnnotation)
+			}
+		})
+	}
+}
+
+// newDNSServiceMock returns a DNS service mock serving the records, keyed by type and name, of
+// the nodes zone of project, and recording the created, patched and deleted records.
+func newDNSServiceMock(t *testing.T, records map[string]*dnsservice.ResourceRecordSet) (*dnsservice.MockDNSService, *[]string, *[]string, *[]string) {
+	var created, patched, deleted []string
+	checkZone := func(project, zone string) {
+		if project != "project" || zone != "nodes" {
+			t.Errorf("Unexpected zone %s/%s", project, zone)
+		}
+	}
+	mock := dnsservice.NewMockDNSService()
+	mock.MockResourceRecordSetsGet = func(_ context.Context, project, zone, name, recordType string) (*dnsservice.ResourceRecordSet, error) {
+		checkZone(project, zone)
+		if recordSet, ok := records[recordType+" "+name]; ok {
+			return recordSet, nil
+		}
+		return nil, &googleapi.Error{Code: http.StatusNotFound}
+	}
+	mock.MockResourceRecordSetsCreate = func(_ context.Context, project, zone string, recordSet *dnsservice.ResourceRecordSet) (*dnsservice.ResourceRecordSet, error) {
+		checkZone(project, zone)
+		created = append(created, recordSet.Type+" "+recordSet.Name+" "+strings.Join(recordSet.Rrdatas, ","))
+		return recordSet, nil
+	}
+	mock.MockResourceRecordSetsPatch = func(_ context.Context, project, zone string, recordSet *dnsservice.ResourceRecordSet) (*dnsservice.ResourceRecordSet, error) {
+		checkZone(project, zone)
+		patched = append(patched, recordSet.Type+" "+recordSet.Name+" "+strings.Join(recordSet.Rrdatas, ","))
+		return recordSet, nil
+	}
+	mock.MockResourceRecordSetsDelete = func(_ context.Context, project, zone, name, recordType string) error {
+		checkZone(project, zone)
+		deleted = append(deleted, recordType+" "+name)
+		return nil
+	}
+	return mock, &created, &patched, &deleted
+}
+
+func expectStrings(t *testing.T, what string, expected, actual []string) {
+	t.Helper()
+	sort.Strings(actual)
+	if strings.Join(expected, ";") != strings.Join(actual, ";") {
+		t.Errorf("Expected %s %v, got %v", what, expected, actual)
+	}
+}
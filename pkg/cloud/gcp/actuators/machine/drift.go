@@ -0,0 +1,344 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// providerSpecOutOfSyncConditionType reports whether the instance differs from the providerSpec,
+	// e.g. because it was edited in the console.
+	providerSpecOutOfSyncConditionType = "ProviderSpecOutOfSync"
+	instanceDriftedReason              = "InstanceDrifted"
+	instanceInSyncReason               = "InstanceInSync"
+	instanceInSyncMessage              = "instance matches the providerSpec"
+
+	driftRemediatedEventReason = "DriftRemediated"
+
+	setLabelsOperationAction   = "setLabels"
+	setTagsOperationAction     = "setTags"
+	setMetadataOperationAction = "setMetadata"
+
+	// googleLabelPrefix is the prefix of the labels GCP services add to instances themselves.
+	googleLabelPrefix = "goog-"
+)
+
+// drift is a difference between the instance and the providerSpec.
+type drift struct {
+	field   string
+	details string
+}
+
+// reconcileDrift compares the instance with the providerSpec and reports the differences in the
+// ProviderSpecOutOfSync condition and an event. The instance is not changed, unless drift
+// remediation is enabled, in which case the labels, network tags and metadata, which can be changed
+// in place, are set back to the providerSpec. Other differences are only reported: the machine
+// must be replaced for them to be reverted.
+func (r *Reconciler) reconcileDrift(instance *compute.Instance) error {
+	desiredLabels, err := util.GetLabelsList(r.gcpLabelsTagsFeatureEnabled, r.coreClient,
+		r.machine.Labels[machinev1.MachineClusterIDLabel], r.providerSpec.Labels)
+	if err != nil {
+		return fmt.Errorf("error getting user-defined labels for machine %s: %w", r.machine.Name, err)
+	}
+
+	var drifts []drift
+	var errs []error
+	// remediate reverts a drift that can be reverted in place when remediation is enabled, drifts
+	// that are not reverted are reported.
+	remediate := func(d drift, action string, revert func() error) {
+		if r.options.RemediateDrift {
+			if r.hasPendingOperation(action) {
+				// The drift is reverted by the pending operation.
+				return
+			}
+			err := revert()
+			if err == nil {
+				return
+			}
+			errs = append(errs, err)
+		}
+		drifts = append(drifts, d)
+	}
+
+	if d, ok := machineTypeDrift(instance, r.providerSpec); ok {
+		drifts = append(drifts, d)
+	}
+	if d, ok := labelsDrift(instance, desiredLabels); ok {
+		remediate(d, setLabelsOperationAction, func() error { return r.remediateLabels(instance, desiredLabels, d) })
+	}
+	if d, ok := tagsDrift(instance, r.desiredTags()); ok {
+		remediate(d, setTagsOperationAction, func() error { return r.remediateTags(instance, d) })
+	}
+	if d, ok := metadataDrift(instance, r.providerSpec); ok {
+		remediate(d, setMetadataOperationAction, func() error { return r.remediateMetadata(instance, d) })
+	}
+	if d, ok := schedulingDrift(instance, r.providerSpec); ok {
+		drifts = append(drifts, d)
+	}
+	if d, ok := disksDrift(instance, r.providerSpec); ok {
+		drifts = append(drifts, d)
+	}
+
+	r.setDriftCondition(drifts)
+	return errors.Join(errs...)
+}
+
+// setDriftCondition sets the ProviderSpecOutOfSync condition from the drifts, with an event when
+// they change. Machines that never drifted do not get the condition.
+func (r *Reconciler) setDriftCondition(drifts []drift) {
+	current := findCondition(r.providerStatus.Conditions, providerSpecOutOfSyncConditionType)
+	if len(drifts) == 0 {
+		if current != nil {
+			r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+				Type:    providerSpecOutOfSyncConditionType,
+				Status:  metav1.ConditionFalse,
+				Reason:  instanceInSyncReason,
+				Message: instanceInSyncMessage,
+			})
+		}
+		return
+	}
+
+	details := make([]string, 0, len(drifts))
+	for _, d := range drifts {
+		details = append(details, fmt.Sprintf("%s (%s)", d.field, d.details))
+	}
+	message := "instance differs from the providerSpec in " + strings.Join(details, ", ")
+	if current == nil || current.Status != metav1.ConditionTrue || current.Message != message {
+		r.log.Info("Instance differs from the providerSpec", "differences", details)
+		r.eventRecorder.Event(r.machine, corev1.EventTypeWarning, providerSpecOutOfSyncConditionType, message)
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    providerSpecOutOfSyncConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  instanceDriftedReason,
+		Message: message,
+	})
+}
+
+func machineTypeDrift(instance *compute.Instance, providerSpec *machinev1.GCPMachineProviderSpec) (drift, bool) {
+	actual := path.Base(instance.MachineType)
+	if instance.MachineType == "" || actual == providerSpec.MachineType {
+		return drift{}, false
+	}
+	return drift{field: "machineType", details: fmt.Sprintf("%s instead of %s", actual, providerSpec.MachineType)}, true
+}
+
+// labelsDrift compares the labels of the instance with the desired ones. Labels GCP services add
+// themselves are ignored.
+func labelsDrift(instance *compute.Instance, desired map[string]string) (drift, bool) {
+	var changes []string
+	for key, value := range desired {
+		actual, ok := instance.Labels[key]
+		switch {
+		case !ok:
+			changes = append(changes, key+" removed")
+		case actual != value:
+			changes = append(changes, key+" changed")
+		}
+	}
+	for key := range instance.Labels {
+		if _, ok := desired[key]; !ok && !strings.HasPrefix(key, googleLabelPrefix) {
+			changes = append(changes, key+" added")
+		}
+	}
+	if len(changes) == 0 {
+		return drift{}, false
+	}
+	sort.Strings(changes)
+	return drift{field: "labels", details: strings.Join(changes, ", ")}, true
+}
+
+// tagsDrift compares the network tags of the instance with the desired ones, the tags of the
+// providerSpec and the managed tags.
+func tagsDrift(instance *compute.Instance, desired []string) (drift, bool) {
+	var actual []string
+	if instance.Tags != nil {
+		actual = instance.Tags.Items
+	}
+	added, removed := diffStrings(actual, desired)
+	var changes []string
+	for _, tag := range added {
+		changes = append(changes, tag+" added")
+	}
+	for _, tag := range removed {
+		changes = append(changes, tag+" removed")
+	}
+	if len(changes) == 0 {
+		return drift{}, false
+	}
+	return drift{field: "tags", details: strings.Join(changes, ", ")}, true
+}
+
+// metadataDrift compares the metadata items of the providerSpec with the ones of the instance.
+// Other items are ignored, the instance gets more, e.g. the user data, and GCP adds some, e.g. SSH
+// keys. The user data items are ignored, they are only used when the instance boots first.
+func metadataDrift(instance *compute.Instance, providerSpec *machinev1.GCPMachineProviderSpec) (drift, bool) {
+	actual := map[string]string{}
+	if instance.Metadata != nil {
+		for _, item := range instance.Metadata.Items {
+			actual[item.Key] = pointer.StringDeref(item.Value, "")
+		}
+	}
+	var changes []string
+	for _, item := range providerSpec.Metadata {
+		if item.Key == userDataMetadataKey || item.Key == windowsScriptMetadataKey {
+			continue
+		}
+		value, ok := actual[item.Key]
+		switch {
+		case !ok:
+			changes = append(changes, item.Key+" removed")
+		case value != pointer.StringDeref(item.Value, ""):
+			changes = append(changes, item.Key+" changed")
+		}
+	}
+	if len(changes) == 0 {
+		return drift{}, false
+	}
+	sort.Strings(changes)
+	return drift{field: "metadata", details: strings.Join(changes, ", ")}, true
+}
+
+// schedulingDrift compares the scheduling options set in the providerSpec with the instance.
+func schedulingDrift(instance *compute.Instance, providerSpec *machinev1.GCPMachineProviderSpec) (drift, bool) {
+	if instance.Scheduling == nil {
+		return drift{}, false
+	}
+	var changes []string
+	if instance.Scheduling.Preemptible != providerSpec.Preemptible {
+		changes = append(changes, fmt.Sprintf("preemptible %t", instance.Scheduling.Preemptible))
+	}
+	// The providerSpec uses e.g. Migrate, GCP returns MIGRATE.
+	if providerSpec.OnHostMaintenance != "" && !strings.EqualFold(instance.Scheduling.OnHostMaintenance, string(providerSpec.OnHostMaintenance)) {
+		changes = append(changes, "onHostMaintenance "+instance.Scheduling.OnHostMaintenance)
+	}
+	if automaticRestart, err := restartPolicyToBool(providerSpec.RestartPolicy, providerSpec.Preemptible); err == nil && automaticRestart != nil &&
+		instance.Scheduling.AutomaticRestart != nil && *instance.Scheduling.AutomaticRestart != *automaticRestart {
+		changes = append(changes, fmt.Sprintf("automaticRestart %t", *instance.Scheduling.AutomaticRestart))
+	}
+	if len(changes) == 0 {
+		return drift{}, false
+	}
+	return drift{field: "scheduling", details: strings.Join(changes, ", ")}, true
+}
+
+// disksDrift compares the number of disks and their sizes, disks can be attached, detached and
+// resized while the instance runs.
+func disksDrift(instance *compute.Instance, providerSpec *machinev1.GCPMachineProviderSpec) (drift, bool) {
+	if len(instance.Disks) != len(providerSpec.Disks) {
+		return drift{field: "disks", details: fmt.Sprintf("%d disks instead of %d", len(instance.Disks), len(providerSpec.Disks))}, true
+	}
+	var changes []string
+	for i, disk := range providerSpec.Disks {
+		actual := instance.Disks[i].DiskSizeGb
+		if disk.SizeGB > 0 && actual > 0 && actual != disk.SizeGB {
+			changes = append(changes, fmt.Sprintf("disk %d is %dGB instead of %dGB", i, actual, disk.SizeGB))
+		}
+	}
+	if len(changes) == 0 {
+		return drift{}, false
+	}
+	return drift{field: "disks", details: strings.Join(changes, ", ")}, true
+}
+
+// diffStrings returns the values only in actual and the ones only in desired, sorted.
+func diffStrings(actual, desired []string) (added, removed []string) {
+	desiredSet := map[string]bool{}
+	for _, value := range desired {
+		desiredSet[value] = true
+	}
+	actualSet := map[string]bool{}
+	for _, value := range actual {
+		actualSet[value] = true
+		if !desiredSet[value] {
+			added = append(added, value)
+		}
+	}
+	for _, value := range desired {
+		if !actualSet[value] {
+			removed = append(removed, value)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// remediateLabels sets the labels of the instance back to the desired ones, keeping the labels GCP
+// services added.
+func (r *Reconciler) remediateLabels(instance *compute.Instance, desired map[string]string, d drift) error {
+	labels := map[string]string{}
+	for key, value := range instance.Labels {
+		if strings.HasPrefix(key, googleLabelPrefix) {
+			labels[key] = value
+		}
+	}
+	for key, value := range desired {
+		labels[key] = value
+	}
+	operation, err := r.computeService.InstancesSetLabels(r.projectID, r.providerSpec.Zone, instance.Name, &compute.InstancesSetLabelsRequest{
+		Labels:           labels,
+		LabelFingerprint: instance.LabelFingerprint,
+	})
+	return r.driftRemediated(setLabelsOperationAction, operation, d, err)
+}
+
+// remediateTags sets the network tags of the instance back to the ones of the providerSpec and the
+// managed tags.
+func (r *Reconciler) remediateTags(instance *compute.Instance, d drift) error {
+	tags := &compute.Tags{Items: r.desiredTags()}
+	if instance.Tags != nil {
+		tags.Fingerprint = instance.Tags.Fingerprint
+	}
+	operation, err := r.computeService.InstancesSetTags(r.projectID, r.providerSpec.Zone, instance.Name, tags)
+	return r.driftRemediated(setTagsOperationAction, operation, d, err)
+}
+
+// remediateMetadata sets the metadata items of the providerSpec back on the instance, keeping its
+// other items.
+func (r *Reconciler) remediateMetadata(instance *compute.Instance, d drift) error {
+	metadata := &compute.Metadata{}
+	desired := map[string]*string{}
+	for _, item := range r.providerSpec.Metadata {
+		if item.Key != userDataMetadataKey && item.Key != windowsScriptMetadataKey {
+			desired[item.Key] = item.Value
+		}
+	}
+	if instance.Metadata != nil {
+		metadata.Fingerprint = instance.Metadata.Fingerprint
+		for _, item := range instance.Metadata.Items {
+			if _, ok := desired[item.Key]; !ok {
+				metadata.Items = append(metadata.Items, item)
+			}
+		}
+	}
+	for _, item := range r.providerSpec.Metadata {
+		if value, ok := desired[item.Key]; ok {
+			metadata.Items = append(metadata.Items, &compute.MetadataItems{Key: item.Key, Value: value})
+		}
+	}
+	operation, err := r.computeService.InstancesSetMetadata(r.projectID, r.providerSpec.Zone, instance.Name, metadata)
+	return r.driftRemediated(setMetadataOperationAction, operation, d, err)
+}
+
+func (r *Reconciler) driftRemediated(action string, operation *compute.Operation, d drift, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to revert the %s of instance %s: %w", d.field, r.instanceName, err)
+	}
+	r.trackOperation(action, operation)
+	r.log.Info("Reverted instance to the providerSpec", "field", d.field, "details", d.details)
+	r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, driftRemediatedEventReason,
+		"Reverted the %s of the instance to the providerSpec: %s", d.field, d.details)
+	return nil
+}
@@ -0,0 +1,175 @@
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestReconcileDrift(t *testing.T) {
+	inSyncInstance := func() *compute.Instance {
+		return &compute.Instance{
+			Name:        "worker-a",
+			MachineType: "https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b/machineTypes/n2-standard-4",
+			Labels: map[string]string{
+				"kubernetes-io-cluster-CLUSTERID": "owned",
+				"team":                            "infra",
+				"goog-ops-agent-policy":           "v2",
+			},
+			Tags:       &compute.Tags{Items: []string{"worker", "CLUSTERID-worker"}},
+			Metadata:   &compute.Metadata{Items: []*compute.MetadataItems{{Key: "user-data", Value: pointer.String("ignition")}, {Key: "foo", Value: pointer.String("bar")}}},
+			Scheduling: &compute.Scheduling{OnHostMaintenance: "MIGRATE"},
+			Disks:      []*compute.AttachedDisk{{Boot: true, DiskSizeGb: 128}},
+		}
+	}
+
+	cases := []struct {
+		name              string
+		modify            func(*compute.Instance)
+		conditions        []metav1.Condition
+		remediate         bool
+		expectedCondition *metav1.Condition
+		expectedMessage   []string
+		expectedCalls     []string
+		expectedEvents    int
+	}{
+		{
+			name: "Instance in sync has no condition",
+		},
+		{
+			name: "Drift is reported",
+			modify: func(i *compute.Instance) {
+				i.MachineType = "n2-standard-8"
+				i.Labels["owner"] = "someone"
+				delete(i.Labels, "team")
+				i.Tags.Items = []string{"worker", "ssh"}
+				i.Metadata.Items[1].Value = pointer.String("baz")
+				i.Scheduling.OnHostMaintenance = "TERMINATE"
+				i.Disks[0].DiskSizeGb = 256
+			},
+			expectedCondition: &metav1.Condition{Status: metav1.ConditionTrue, Reason: instanceDriftedReason},
+			expectedMessage: []string{
+				"machineType (n2-standard-8 instead of n2-standard-4)",
+				"labels (owner added, team removed)",
+				"tags (ssh added, CLUSTERID-worker removed)",
+				"metadata (foo changed)",
+				"scheduling (onHostMaintenance TERMINATE)",
+				"disks (disk 0 is 256GB instead of 128GB)",
+			},
+			expectedEvents: 1,
+		},
+		{
+			name: "Unchanged drift is not reported again",
+			modify: func(i *compute.Instance) {
+				i.MachineType = "n2-standard-8"
+			},
+			conditions: []metav1.Condition{{
+				Type:    providerSpecOutOfSyncConditionType,
+				Status:  metav1.ConditionTrue,
+				Reason:  instanceDriftedReason,
+				Message: "instance differs from the providerSpec in machineType (n2-standard-8 instead of n2-standard-4)",
+			}},
+			expectedCondition: &metav1.Condition{Status: metav1.ConditionTrue, Reason: instanceDriftedReason},
+		},
+		{
+			name:              "Instance back in sync",
+			conditions:        []metav1.Condition{{Type: providerSpecOutOfSyncConditionType, Status: metav1.ConditionTrue, Reason: instanceDriftedReason}},
+			expectedCondition: &metav1.Condition{Status: metav1.ConditionFalse, Reason: instanceInSyncReason},
+		},
+		{
+			name: "Remediation reverts labels, tags and metadata",
+			modify: func(i *compute.Instance) {
+				i.MachineType = "n2-standard-8"
+				i.Labels["owner"] = "someone"
+				i.Tags.Items = nil
+				i.Metadata.Items = i.Metadata.Items[:1]
+			},
+			remediate:         true,
+			expectedCondition: &metav1.Condition{Status: metav1.ConditionTrue, Reason: instanceDriftedReason},
+			expectedMessage:   []string{"machineType (n2-standard-8 instead of n2-standard-4)"},
+			expectedCalls:     []string{"labels", "tags", "metadata"},
+			expectedEvents:    4,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			var calls []string
+			mockComputeService.MockSetLabels = func(_, _, _ string, request *compute.InstancesSetLabelsRequest) (*compute.Operation, error) {
+				calls = append(calls, "labels")
+				if _, ok := request.Labels["goog-ops-agent-policy"]; !ok || request.Labels["owner"] != "" {
+					t.Errorf("Expected the labels of GCP services to be kept and others to be reverted, got %v", request.Labels)
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockSetTags = func(_, _, _ string, tags *compute.Tags) (*compute.Operation, error) {
+				calls = append(calls, "tags")
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockSetMetadata = func(_, _, _ string, metadata *compute.Metadata) (*compute.Operation, error) {
+				calls = append(calls, "metadata")
+				if len(metadata.Items) != 2 || metadata.Items[0].Key != "user-data" {
+					t.Errorf("Expected the other metadata items to be kept, got %v", metadata.Items)
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			recorder := record.NewFakeRecorder(10)
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+					Name:   "worker-a",
+					Labels: map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+				}},
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:              "us-east1-b",
+					MachineType:       "n2-standard-4",
+					OnHostMaintenance: machinev1.MigrateHostMaintenanceType,
+					Labels:            map[string]string{"team": "infra"},
+					Tags:              []string{"worker", "CLUSTERID-worker"},
+					Metadata:          []*machinev1.GCPMetadata{{Key: "foo", Value: pointer.String("bar")}},
+					Disks:             []*machinev1.GCPDisk{{Boot: true, SizeGB: 128}},
+				},
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.conditions},
+				computeService: mockComputeService,
+				eventRecorder:  recorder,
+				options:        Options{RemediateDrift: tc.remediate},
+			})
+			instance := inSyncInstance()
+			if tc.modify != nil {
+				tc.modify(instance)
+			}
+
+			if err := r.reconcileDrift(instance); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			condition := findCondition(r.providerStatus.Conditions, providerSpecOutOfSyncConditionType)
+			switch {
+			case tc.expectedCondition == nil && condition != nil:
+				t.Errorf("Expected no condition, got %v", condition)
+			case tc.expectedCondition != nil && condition == nil:
+				t.Errorf("Expected a condition")
+			case tc.expectedCondition != nil && (condition.Status != tc.expectedCondition.Status || condition.Reason != tc.expectedCondition.Reason):
+				t.Errorf("Expected status %s and reason %s, got %v", tc.expectedCondition.Status, tc.expectedCondition.Reason, condition)
+			}
+			if len(tc.expectedMessage) > 0 {
+				expected := "instance differs from the providerSpec in " + strings.Join(tc.expectedMessage, ", ")
+				if condition.Message != expected {
+					t.Errorf("Expected message %q, got %q", expected, condition.Message)
+				}
+			}
+			if strings.Join(calls, ",") != strings.Join(tc.expectedCalls, ",") {
+				t.Errorf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+			if len(recorder.Events) != tc.expectedEvents {
+				t.Errorf("Expected %d events, got %d", tc.expectedEvents, len(recorder.Events))
+			}
+		})
+	}
+}
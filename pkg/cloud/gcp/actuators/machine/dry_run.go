@@ -0,0 +1,66 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/googleapi"
+)
+
+const sourceImageNotFoundReason = "SourceImageNotFound"
+
+// DryRun runs the creation of the instance of a machine up to the insert, which the compute API
+// cannot validate without creating the instance, and returns the error the creation would fail
+// with, nil if the instance would be inserted. The instance is built from the providerSpec and
+// annotations of the machine, with the credentials of its namespace, and goes through the
+// pre-flight checks, which check e.g. the machine type, and through dry-run only checks of its
+// images. Nothing is created in GCP and the machine is neither modified nor written, it does not
+// need to exist, e.g. it can be the template of a MachineSet.
+func (a *Actuator) DryRun(ctx context.Context, machine *machinev1.Machine) error {
+	machine = machine.DeepCopy()
+	scope, err := newMachineScope(a.scopeParams(ctx, machine))
+	if err != nil {
+		return fmt.Errorf(scopeFailFmt, machine.GetName(), err)
+	}
+	scope.dryRun = true
+	return newReconciler(scope).create()
+}
+
+// checkSourceImages verifies that the images of the disks exist and can be read with the
+// credentials of the machine.
+func (r *Reconciler) checkSourceImages(state *preflightState) error {
+	for i, disk := range r.providerSpec.Disks {
+		if disk.Image == "" || i >= len(state.instance.Disks) || state.instance.Disks[i].InitializeParams == nil {
+			continue
+		}
+		sourceImage := state.instance.Disks[i].InitializeParams.SourceImage
+		project, name, family, err := parseImageLink(sourceImage)
+		if err != nil {
+			return machinecontroller.InvalidMachineConfiguration("invalid source image: %v", err)
+		}
+		if family != "" {
+			_, err = r.computeService.ImagesGetFromFamily(project, family)
+		} else {
+			_, err = r.computeService.ImagesGet(project, name)
+		}
+		if err == nil {
+			continue
+		}
+		var apiErr *googleapi.Error
+		switch {
+		case isNotFoundError(err):
+			return &preflightError{
+				reason: sourceImageNotFoundReason,
+				err:    machinecontroller.InvalidMachineConfiguration("source image %s of disk %d not found", sourceImage, i),
+			}
+		case errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden:
+			return machinecontroller.InvalidMachineConfiguration("no access to source image %s of disk %d: %v", sourceImage, i, err)
+		}
+		return fmt.Errorf("failed to get source image %s: %w", sourceImage, err)
+	}
+	return nil
+}
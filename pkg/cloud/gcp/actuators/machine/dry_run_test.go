@@ -0,0 +1,137 @@
+package machine
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCreateDryRun(t *testing.T) {
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status: configv1.InfrastructureStatus{
+			InfrastructureName: "test-748kjf",
+			PlatformStatus:     &configv1.PlatformStatus{Type: configv1.GCPPlatformType, GCP: &configv1.GCPPlatformStatus{}},
+		},
+	}
+
+	cases := []struct {
+		name              string
+		dryRun            bool
+		mockImagesGet     func(project string, image string) (*compute.Image, error)
+		expectedImages    int
+		expectedAddresses int
+		expectedReason    string
+	}{
+		{
+			name:           "Dry run succeeds",
+			dryRun:         true,
+			expectedImages: 1,
+		},
+		{
+			name:   "Dry run with a missing image",
+			dryRun: true,
+			mockImagesGet: func(_ string, _ string) (*compute.Image, error) {
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			},
+			expectedImages: 1,
+			expectedReason: sourceImageNotFoundReason,
+		},
+		{
+			// The creation stops to wait for the static internal address.
+			name:              "Images are not checked and addresses reserved outside of dry runs",
+			expectedAddresses: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			images := 0
+			mockComputeService.MockImagesGet = func(project string, image string) (*compute.Image, error) {
+				images++
+				if project != "rhcos-cloud" || image != "rhcos-415" {
+					t.Errorf("Unexpected image %s/%s", project, image)
+				}
+				if tc.mockImagesGet != nil {
+					return tc.mockImagesGet(project, image)
+				}
+				return &compute.Image{Name: image}, nil
+			}
+			mockComputeService.MockInstancesInsert = func(_ string, _ string, _ *compute.Instance) (*compute.Operation, error) {
+				t.Errorf("Expected no instance to be inserted")
+				return nil, nil
+			}
+			mockComputeService.MockAddressesGet = func(_ string, _ string, _ string) (*compute.Address, error) {
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			}
+			addresses := 0
+			mockComputeService.MockAddressesInsert = func(_ string, _ string, _ *compute.Address) (*compute.Operation, error) {
+				addresses++
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				Context: context.Background(),
+				machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker-a",
+					Namespace:   "openshift-machine-api",
+					Labels:      map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+					Annotations: map[string]string{staticInternalAddressAnnotation: "true"},
+				}},
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:              "us-east1-b",
+					Region:            "us-east1",
+					MachineType:       "n1-standard-4",
+					Disks:             []*machinev1.GCPDisk{{Boot: true, Image: "projects/rhcos-cloud/global/images/rhcos-415"}, {SizeGB: 100}},
+					NetworkInterfaces: []*machinev1.GCPNetworkInterface{{Network: "network", Subnetwork: "subnet"}},
+				},
+				coreClient:     controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(infra).Build(),
+				projectID:      "testProject",
+				instanceName:   "worker-a",
+				featureGates:   featuregates.NewFeatureGate(nil, []configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}),
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+				eventRecorder:  record.NewFakeRecorder(10),
+				dryRun:         tc.dryRun,
+			})
+
+			err := r.create()
+			if images != tc.expectedImages {
+				t.Errorf("Expected %d image lookups, got %d", tc.expectedImages, images)
+			}
+			if addresses != tc.expectedAddresses {
+				t.Errorf("Expected %d address reservations, got %d", tc.expectedAddresses, addresses)
+			}
+			if !tc.dryRun {
+				return
+			}
+			if _, ok := r.machine.Annotations[specHashAnnotation]; ok {
+				t.Errorf("Expected the instance spec hash not to be recorded in a dry run")
+			}
+			if tc.expectedReason == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if !isInvalidMachineConfigurationError(err) {
+				t.Fatalf("Expected an invalid machine configuration error, got %v", err)
+			}
+			if reason := err.(*preflightError).reason; reason != tc.expectedReason {
+				t.Errorf("Expected reason %s, got %s", tc.expectedReason, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package machine
+
+import (
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/providerid"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const duplicateMachineReason = "DuplicateMachine"
+
+// duplicateOf returns the machine that resolves to the same instance, i.e. the same project, zone
+// and name, as this machine and was created before it, if any. Instances are named after their
+// machine, so only machines of the same name in other namespaces can be duplicates. The newer of
+// two duplicates leaves the instance to the older one instead of fighting over its load balancer
+// registration and deletion.
+func (r *Reconciler) duplicateOf() (*machinev1.Machine, error) {
+	machines := &machinev1.MachineList{}
+	if err := r.coreClient.List(r.Context, machines); err != nil {
+		return nil, fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	for i := range machines.Items {
+		other := &machines.Items[i]
+		if other.Name != r.machine.Name || other.UID == r.machine.UID || !r.isOlder(other) {
+			continue
+		}
+		project, zone, ok := r.instanceLocation(other)
+		if ok && project == r.projectID && zone == r.providerSpec.Zone {
+			return other, nil
+		}
+	}
+	return nil, nil
+}
+
+// isOlder returns true if the other machine was created before this one. Machines created within
+// the same second are ordered by namespace.
+func (r *Reconciler) isOlder(other *machinev1.Machine) bool {
+	if !other.CreationTimestamp.Equal(&r.machine.CreationTimestamp) {
+		return other.CreationTimestamp.Before(&r.machine.CreationTimestamp)
+	}
+	return other.Namespace < r.machine.Namespace
+}
+
+// instanceLocation returns the project and zone of the instance of another machine, preferring
+// its provider ID. A machine without a project in its providerSpec is assumed to use the same
+// default project as this machine, unless this machine sets a project.
+func (r *Reconciler) instanceLocation(machine *machinev1.Machine) (project, zone string, ok bool) {
+	if machine.Spec.ProviderID != nil {
+		if id, err := providerid.Parse(*machine.Spec.ProviderID); err == nil {
+			return id.Project, id.Zone, true
+		}
+	}
+
+	providerSpec, err := util.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return "", "", false
+	}
+	project = providerSpec.ProjectID
+	if project == "" {
+		if r.providerSpec.ProjectID != "" {
+			return "", "", false
+		}
+		project = r.projectID
+	}
+	return project, instanceZone(machine, providerSpec), true
+}
+
+// checkDuplicateMachine fails the creation of a machine whose instance belongs to an older machine.
+func (r *Reconciler) checkDuplicateMachine() error {
+	duplicate, err := r.duplicateOf()
+	if err != nil || duplicate == nil {
+		return err
+	}
+
+	err = machinecontroller.InvalidMachineConfiguration("instance projects/%s/zones/%s/instances/%s is already managed by machine %s/%s",
+		r.projectID, r.providerSpec.Zone, r.instanceName, duplicate.Namespace, duplicate.Name)
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    string(machinev1.MachineCreated),
+		Status:  metav1.ConditionFalse,
+		Reason:  duplicateMachineReason,
+		Message: err.Error(),
+	})
+	return err
+}
+
+// isDuplicate returns true if the instance of the machine belongs to an older machine.
+func (r *Reconciler) isDuplicate() (bool, error) {
+	duplicate, err := r.duplicateOf()
+	if err != nil || duplicate == nil {
+		return false, err
+	}
+	r.log.Info("Instance is managed by another machine, leaving it alone", "managedBy", duplicate.Namespace+"/"+duplicate.Name)
+	return true, nil
+}
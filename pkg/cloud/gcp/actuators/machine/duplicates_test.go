@@ -0,0 +1,121 @@
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDuplicateMachines(t *testing.T) {
+	created := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	otherMachine := func(namespace string, createdAt metav1.Time, spec *machinev1.GCPMachineProviderSpec, providerID *string) *machinev1.Machine {
+		providerSpec, err := util.RawExtensionFromProviderSpec(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace, UID: "other", CreationTimestamp: createdAt},
+			Spec:       machinev1.MachineSpec{ProviderID: providerID, ProviderSpec: machinev1.ProviderSpec{Value: providerSpec}},
+		}
+	}
+
+	cases := []struct {
+		name            string
+		other           *machinev1.Machine
+		expectDuplicate bool
+	}{
+		{
+			name:            "Older machine in the same project and zone",
+			other:           otherMachine("other", metav1.NewTime(created.Add(-time.Hour)), &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"}, nil),
+			expectDuplicate: true,
+		},
+		{
+			name:            "Older machine with a provider ID",
+			other:           otherMachine("other", metav1.NewTime(created.Add(-time.Hour)), &machinev1.GCPMachineProviderSpec{}, pointer.String("gce://project/us-east1-b/test")),
+			expectDuplicate: true,
+		},
+		{
+			name:            "Machine created in the same second in an earlier namespace",
+			other:           otherMachine("a-namespace", created, &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"}, nil),
+			expectDuplicate: true,
+		},
+		{
+			name:  "Newer machine",
+			other: otherMachine("other", metav1.NewTime(created.Add(time.Hour)), &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"}, nil),
+		},
+		{
+			name:  "Older machine in another zone",
+			other: otherMachine("other", metav1.NewTime(created.Add(-time.Hour)), &machinev1.GCPMachineProviderSpec{Zone: "us-east1-c"}, nil),
+		},
+		{
+			name:  "Older machine in another project",
+			other: otherMachine("other", metav1.NewTime(created.Add(-time.Hour)), &machinev1.GCPMachineProviderSpec{ProjectID: "other-project", Zone: "us-east1-b"}, nil),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			r := newReconciler(&machineScope{
+				coreClient: controllerfake.NewClientBuilder().WithObjects(tc.other).Build(),
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "test",
+						Namespace:         "test",
+						UID:               "test",
+						CreationTimestamp: created,
+						Labels:            map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+					},
+				},
+				projectID:      "project",
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: computeservice.WithInterceptors(mockComputeService, func(ctx context.Context, method string, call func() error) error {
+					calls = append(calls, method)
+					return call()
+				}),
+			})
+
+			err := r.checkDuplicateMachine()
+			if tc.expectDuplicate != isInvalidMachineConfigurationError(err) {
+				t.Errorf("Expected duplicate %v, got error %v", tc.expectDuplicate, err)
+			}
+			if tc.expectDuplicate && !hasCondition(r.providerStatus.Conditions, string(machinev1.MachineCreated), duplicateMachineReason) {
+				t.Errorf("Expected the MachineCreated condition to have reason %s, got %v", duplicateMachineReason, r.providerStatus.Conditions)
+			}
+
+			exists, err := r.exists()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if exists == tc.expectDuplicate {
+				t.Errorf("Expected exists to be %v, got %v", !tc.expectDuplicate, exists)
+			}
+
+			calls = nil
+			if err := r.delete(); err != nil && tc.expectDuplicate {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.expectDuplicate && len(calls) > 0 {
+				t.Errorf("Expected the instance of the older machine to be left alone, got calls %v", calls)
+			}
+		})
+	}
+}
+
+func hasCondition(conditions []metav1.Condition, conditionType, reason string) bool {
+	for _, condition := range conditions {
+		if condition.Type == conditionType && condition.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
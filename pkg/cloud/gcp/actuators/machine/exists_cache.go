@@ -0,0 +1,157 @@
+package machine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/pointer"
+)
+
+// existenceCache remembers when the instance of a machine was last seen in the cloud, so that
+// Exists can trust the provider status of a machine for a while instead of calling the compute
+// API on every reconcile. Entries are kept in memory only: after a restart every machine is
+// verified against the cloud once.
+type existenceCache struct {
+	clock    clock.Clock
+	interval time.Duration
+
+	mu      sync.Mutex
+	entries map[types.UID]existenceEntry
+}
+
+// existenceEntry is what was observed when the instance of a machine was last verified.
+type existenceEntry struct {
+	instanceID string
+	generation int64
+	verifiedAt time.Time
+	// instance is set when the instance was verified by an update, which also refreshed the status
+	// of the machine from it, e.g. its addresses and state.
+	instance *compute.Instance
+}
+
+// newExistenceCache returns a cache trusting a verification for the given interval, or nil,
+// which disables the fast path, if the interval is not positive.
+func newExistenceCache(c clock.Clock, interval time.Duration) *existenceCache {
+	if interval <= 0 {
+		return nil
+	}
+	if c == nil {
+		c = clock.RealClock{}
+	}
+	return &existenceCache{
+		clock:    c,
+		interval: interval,
+		entries:  map[types.UID]existenceEntry{},
+	}
+}
+
+// fresh returns true if the instance of the machine was verified within the interval and nothing
+// about the machine suggests that it has changed since: the generation and the instance ID in the
+// provider status must be the ones verified, the instance must be running and the machine must
+// not be going away.
+func (c *existenceCache) fresh(machine *machinev1.Machine) bool {
+	_, ok := c.freshEntry(machine)
+	return ok
+}
+
+// reconciledInstance returns the instance of the machine seen by the last update if the
+// verification is fresh, so that the next updates can reconcile the spec of the machine against
+// it without looking up the instance until the interval has passed. A verification by Exists
+// does not count: the update following it would never refresh the status of the machine.
+func (c *existenceCache) reconciledInstance(machine *machinev1.Machine) *compute.Instance {
+	entry, ok := c.freshEntry(machine)
+	if !ok {
+		return nil
+	}
+	return entry.instance
+}
+
+func (c *existenceCache) freshEntry(machine *machinev1.Machine) (existenceEntry, bool) {
+	if c == nil || machine.DeletionTimestamp != nil || machine.Spec.ProviderID == nil {
+		return existenceEntry{}, false
+	}
+	if phase := pointer.StringDeref(machine.Status.Phase, ""); phase == "Failed" || phase == "Deleting" {
+		return existenceEntry{}, false
+	}
+	providerStatus, err := util.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil || providerStatus.InstanceID == nil || pointer.StringDeref(providerStatus.InstanceState, "") != "RUNNING" {
+		return existenceEntry{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[machine.UID]
+	if !ok {
+		return existenceEntry{}, false
+	}
+	// The spec of the machine changed since, the instance must be looked at again.
+	if entry.generation != machine.Generation {
+		delete(c.entries, machine.UID)
+		return existenceEntry{}, false
+	}
+	return entry, entry.instanceID == *providerStatus.InstanceID &&
+		c.clock.Since(entry.verifiedAt) < c.interval
+}
+
+// record remembers that the instance of the machine was just found in the cloud. Machines whose
+// provider status does not have an instance ID yet are not recorded, they are verified again.
+func (c *existenceCache) record(machine *machinev1.Machine) {
+	c.store(machine, nil)
+}
+
+// recordReconciled remembers the instance an update just found in the cloud and refreshed the
+// status of the machine from.
+func (c *existenceCache) recordReconciled(machine *machinev1.Machine, instance *compute.Instance) {
+	c.store(machine, instance)
+}
+
+func (c *existenceCache) store(machine *machinev1.Machine, instance *compute.Instance) {
+	if c == nil {
+		return
+	}
+	providerStatus, err := util.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil || providerStatus.InstanceID == nil {
+		c.forget(machine)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[machine.UID] = existenceEntry{
+		instanceID: *providerStatus.InstanceID,
+		generation: machine.Generation,
+		verifiedAt: c.clock.Now(),
+		instance:   instance,
+	}
+}
+
+// forget drops the verification of the machine, e.g. because an operation on its instance
+// failed or changed it, so that the next Exists and update ask the cloud again.
+func (c *existenceCache) forget(machine *machinev1.Machine) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, machine.UID)
+}
+
+// mutationTracker notes whether an operation made calls changing resources in the cloud, after
+// which the instance it looked up is outdated and not remembered.
+type mutationTracker struct {
+	mutated bool
+}
+
+func (t *mutationTracker) intercept(_ context.Context, method string, call func() error) error {
+	if !computeservice.IsReadMethod(method) {
+		t.mutated = true
+	}
+	return call()
+}
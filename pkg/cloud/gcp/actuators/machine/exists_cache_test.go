@@ -0,0 +1,280 @@
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func existenceCacheMachine(t *testing.T, instanceID, state string) *machinev1.Machine {
+	providerSpec, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+		CredentialsSecret: &corev1.LocalObjectReference{Name: credentialsSecretName},
+		Zone:              "us-east1-b",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providerStatus, err := util.RawExtensionFromProviderStatus(&machinev1.GCPMachineProviderStatus{
+		InstanceID:    pointer.String(instanceID),
+		InstanceState: pointer.String(state),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test",
+			Namespace:  defaultNamespaceName,
+			UID:        "uid",
+			Generation: 1,
+			Labels:     map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+		},
+		Spec: machinev1.MachineSpec{
+			ProviderID:   pointer.String("gce://test/zone/test"),
+			ProviderSpec: machinev1.ProviderSpec{Value: providerSpec},
+		},
+		Status: machinev1.MachineStatus{
+			Phase:          pointer.String("Running"),
+			ProviderStatus: providerStatus,
+		},
+	}
+}
+
+func TestExistenceCacheFresh(t *testing.T) {
+	cases := []struct {
+		name     string
+		modify   func(*machinev1.Machine)
+		elapsed  time.Duration
+		expected bool
+	}{
+		{
+			name:     "Verified recently",
+			elapsed:  time.Minute,
+			expected: true,
+		},
+		{
+			name:    "Verification expired",
+			elapsed: 5 * time.Minute,
+		},
+		{
+			name:   "Generation changed",
+			modify: func(m *machinev1.Machine) { m.Generation = 2 },
+		},
+		{
+			name: "Instance ID changed",
+			modify: func(m *machinev1.Machine) {
+				m.Status.ProviderStatus, _ = util.RawExtensionFromProviderStatus(&machinev1.GCPMachineProviderStatus{
+					InstanceID:    pointer.String("other"),
+					InstanceState: pointer.String("RUNNING"),
+				})
+			},
+		},
+		{
+			name: "Instance not running",
+			modify: func(m *machinev1.Machine) {
+				m.Status.ProviderStatus, _ = util.RawExtensionFromProviderStatus(&machinev1.GCPMachineProviderStatus{
+					InstanceID:    pointer.String("id"),
+					InstanceState: pointer.String("TERMINATED"),
+				})
+			},
+		},
+		{
+			name:   "Machine being deleted",
+			modify: func(m *machinev1.Machine) { m.DeletionTimestamp = &metav1.Time{} },
+		},
+		{
+			name:   "Machine failed",
+			modify: func(m *machinev1.Machine) { m.Status.Phase = pointer.String("Failed") },
+		},
+		{
+			name:   "Machine without provider ID",
+			modify: func(m *machinev1.Machine) { m.Spec.ProviderID = nil },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeClock := clocktesting.NewFakeClock(time.Now())
+			cache := newExistenceCache(fakeClock, 5*time.Minute)
+			machine := existenceCacheMachine(t, "id", "RUNNING")
+			cache.record(machine)
+
+			fakeClock.Step(tc.elapsed)
+			if tc.modify != nil {
+				tc.modify(machine)
+			}
+			if fresh := cache.fresh(machine); fresh != tc.expected {
+				t.Errorf("Expected fresh to be %v, got %v", tc.expected, fresh)
+			}
+		})
+	}
+}
+
+func TestExistenceCacheDisabled(t *testing.T) {
+	cache := newExistenceCache(nil, 0)
+	machine := existenceCacheMachine(t, "id", "RUNNING")
+	cache.record(machine)
+	if cache.fresh(machine) {
+		t.Error("Expected a disabled cache never to be fresh")
+	}
+}
+
+func TestActuatorExistsVerificationInterval(t *testing.T) {
+	credentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: defaultNamespaceName},
+		Data:       map[string][]byte{credentialsSecretKey: []byte("{\"project_id\": \"test\"}")},
+	}
+
+	var builds int
+	var notFound bool
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	actuator := NewActuator(ActuatorParams{
+		CoreClient: controllerfake.NewFakeClient(credentialsSecret),
+		ComputeClientBuilder: func(serviceAccountJSON string) (computeservice.GCPComputeService, error) {
+			builds++
+			if notFound {
+				return computeservice.MockBuilderFuncTypeNotFound(serviceAccountJSON)
+			}
+			return computeservice.MockBuilderFuncType(serviceAccountJSON)
+		},
+		TagsClientBuilder: tagservice.NewMockTagServiceBuilder,
+		FeatureGates:      featuregates.NewFeatureGate(nil, []configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}),
+		Clock:             fakeClock,
+		Options:           Options{ExistsVerificationInterval: 5 * time.Minute},
+	})
+	machine := existenceCacheMachine(t, "id", "RUNNING")
+
+	exists := func(expected bool, expectedBuilds int) {
+		t.Helper()
+		ok, err := actuator.Exists(context.TODO(), machine)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ok != expected {
+			t.Errorf("Expected exists to be %v, got %v", expected, ok)
+		}
+		if builds != expectedBuilds {
+			t.Errorf("Expected the cloud to be called %d times, got %d", expectedBuilds, builds)
+		}
+	}
+
+	// The first check always asks the cloud, the following ones trust the verification.
+	exists(true, 1)
+	exists(true, 1)
+
+	// Once the verification expired, the instance is looked up again and found missing.
+	notFound = true
+	fakeClock.Step(5 * time.Minute)
+	exists(false, 2)
+	// A missing instance is never trusted.
+	exists(false, 3)
+}
+
+func TestExistenceCacheReconciledInstance(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	cache := newExistenceCache(fakeClock, 5*time.Minute)
+	machine := existenceCacheMachine(t, "id", "RUNNING")
+	instance := &compute.Instance{Name: "id"}
+
+	cache.record(machine)
+	if !cache.fresh(machine) || cache.reconciledInstance(machine) != nil {
+		t.Error("Expected a verification by Exists to be fresh but not to count as a reconcile")
+	}
+	cache.recordReconciled(machine, instance)
+	if !cache.fresh(machine) || cache.reconciledInstance(machine) != instance {
+		t.Error("Expected a verification by an update to remember the instance")
+	}
+
+	machine.Generation++
+	if cache.reconciledInstance(machine) != nil {
+		t.Error("Expected a change of the generation to invalidate the instance")
+	}
+	machine.Generation--
+	if cache.fresh(machine) {
+		t.Error("Expected the verification to be dropped on a change of the generation")
+	}
+
+	cache.recordReconciled(machine, instance)
+	fakeClock.Step(5 * time.Minute)
+	if cache.reconciledInstance(machine) != nil {
+		t.Error("Expected the reconcile to expire with the interval")
+	}
+}
+
+func TestMutationTracker(t *testing.T) {
+	tracker := &mutationTracker{}
+	call := func() error { return nil }
+	if err := tracker.intercept(context.Background(), "InstancesGet", call); err != nil || tracker.mutated {
+		t.Errorf("Expected a read not to count as a mutation, got mutated %v, error %v", tracker.mutated, err)
+	}
+	if err := tracker.intercept(context.Background(), "InstancesSetTags", call); err != nil || !tracker.mutated {
+		t.Errorf("Expected a mutation to be noted, got mutated %v, error %v", tracker.mutated, err)
+	}
+}
+
+type instanceLookupCountingComputeService struct {
+	*computeservice.GCPComputeServiceMock
+	lookups int
+}
+
+func (c *instanceLookupCountingComputeService) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
+	c.lookups++
+	return c.GCPComputeServiceMock.InstancesGet(project, zone, instance)
+}
+
+func TestUpdateSkipsInstanceLookupWhenReconciledRecently(t *testing.T) {
+	for _, reconciled := range []bool{false, true} {
+		_, mockComputeService := computeservice.NewComputeServiceMock()
+		computeService := &instanceLookupCountingComputeService{GCPComputeServiceMock: mockComputeService}
+		var reconciledInstance *compute.Instance
+		if reconciled {
+			reconciledInstance = &compute.Instance{Name: "test", Status: "RUNNING", CanIpForward: true}
+		}
+		r := newReconciler(&machineScope{
+			Context: context.Background(),
+			machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+				Name:   "test",
+				Labels: map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+			}},
+			coreClient:         controllerfake.NewFakeClient(),
+			providerSpec:       &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"},
+			providerStatus:     &machinev1.GCPMachineProviderStatus{},
+			projectID:          "project",
+			instanceName:       "test",
+			computeService:     computeService,
+			featureGates:       featuregates.NewFeatureGate(nil, []configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}),
+			reconciledInstance: reconciledInstance,
+		})
+
+		if err := r.update(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := 1
+		if reconciled {
+			expected = 0
+		}
+		if computeService.lookups != expected {
+			t.Errorf("Expected %d instance lookups when reconciled recently is %v, got %d", expected, reconciled, computeService.lookups)
+		}
+		if reconciled != (r.fetchedInstance == nil) || reconciled != (len(r.machine.Status.Addresses) == 0) {
+			t.Errorf("Expected the status to be refreshed only from a looked up instance when reconciled recently is %v", reconciled)
+		}
+		// The spec is reconciled against the instance either way.
+		if reconciled && findCondition(r.providerStatus.Conditions, ipForwardingConditionType) == nil {
+			t.Error("Expected the spec to be reconciled against the instance reconciled recently")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package machine
+
+import (
+	"fmt"
+	"time"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	corev1 "k8s.io/api/core/v1"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// deleteStrategyImmediate deletes the instance right away, which powers it off without
+	// waiting for the guest.
+	deleteStrategyImmediate = "Immediate"
+	// deleteStrategyStopFirst stops the instance and waits for the guest to shut down before
+	// deleting it.
+	deleteStrategyStopFirst = "StopFirst"
+
+	defaultGracefulShutdownTimeout = 5 * time.Minute
+
+	gracefulShutdownStartedEvent   = "GracefulShutdownStarted"
+	gracefulShutdownCompletedEvent = "GracefulShutdownCompleted"
+	gracefulShutdownTimedOutEvent  = "GracefulShutdownTimedOut"
+)
+
+// shutdownBeforeDelete stops the instance and waits for the guest to shut down cleanly before the
+// instance is deleted, when the machine asks for it with the deleteStrategyAnnotation. It returns
+// nil once the instance may be deleted: when it stopped or when the timeout elapsed, and a requeue
+// error while the guest is shutting down. The deletion is never blocked by an invalid annotation.
+func (r *Reconciler) shutdownBeforeDelete() error {
+	strategy, _ := r.getAnnotation(deleteStrategyAnnotation)
+	switch strategy {
+	case "", deleteStrategyImmediate:
+		return nil
+	case deleteStrategyStopFirst:
+	default:
+		r.log.Info("Ignoring unknown delete strategy, deleting the instance immediately", "annotation", deleteStrategyAnnotation, "strategy", strategy)
+		return nil
+	}
+
+	instance, err := r.computeService.InstancesGet(r.projectID, r.providerSpec.Zone, r.instanceName)
+	if err != nil {
+		return fmt.Errorf("failed to get instance before stopping it: %w", err)
+	}
+	started, stopRequested := r.gracefulShutdownStarted()
+
+	if instance.Status == "TERMINATED" {
+		if stopRequested {
+			r.log.Info("Instance stopped, deleting it")
+			r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, gracefulShutdownCompletedEvent, "Instance %s shut down after %s", r.machine.Name, r.clock.Since(started).Round(time.Second))
+		}
+		return nil
+	}
+
+	if !stopRequested {
+		if instance.Status == "RUNNING" {
+			if _, err := r.computeService.InstancesStop(r.projectID, r.providerSpec.Zone, r.instanceName); err != nil {
+				return fmt.Errorf("failed to stop instance before deleting it: %w", err)
+			}
+		}
+		if err := r.recordGracefulShutdownStarted(); err != nil {
+			return err
+		}
+		r.log.Info("Stopping instance before deleting it")
+		r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, gracefulShutdownStartedEvent, "Stopping instance %s before deleting it", r.instanceName)
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+
+	timeout := r.gracefulShutdownTimeout()
+	if elapsed := r.clock.Since(started); elapsed >= timeout {
+		r.log.Info("Instance did not stop in time, deleting it", "timeout", timeout)
+		r.eventRecorder.Eventf(r.machine, corev1.EventTypeWarning, gracefulShutdownTimedOutEvent, "Instance %s did not shut down within %s, deleting it", r.machine.Name, timeout)
+		return nil
+	}
+	r.log.V(logLevelDetail).Info("Waiting for the instance to stop before deleting it", "instanceStatus", instance.Status)
+	return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+}
+
+// gracefulShutdownTimeout returns how long to wait for the guest to shut down.
+func (r *Reconciler) gracefulShutdownTimeout() time.Duration {
+	value, ok := r.getAnnotation(gracefulShutdownTimeoutAnnotation)
+	if !ok || value == "" {
+		return defaultGracefulShutdownTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		r.log.Info("Ignoring invalid annotation value, using the default timeout", "annotation", gracefulShutdownTimeoutAnnotation, "value", value, "timeout", defaultGracefulShutdownTimeout)
+		return defaultGracefulShutdownTimeout
+	}
+	return timeout
+}
+
+// gracefulShutdownStarted returns when the instance was asked to stop, if it was.
+func (r *Reconciler) gracefulShutdownStarted() (time.Time, bool) {
+	value, ok := r.getAnnotation(gracefulShutdownStartedAnnotation)
+	if !ok {
+		return time.Time{}, false
+	}
+	started, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		r.log.Info("Ignoring invalid annotation value", "annotation", gracefulShutdownStartedAnnotation, "value", value)
+		return time.Time{}, false
+	}
+	return started, true
+}
+
+// recordGracefulShutdownStarted patches the machine right away, the scope does not persist the
+// machine after a delete.
+func (r *Reconciler) recordGracefulShutdownStarted() error {
+	patchBase := controllerclient.MergeFrom(r.machine.DeepCopy())
+	if r.machine.Annotations == nil {
+		r.machine.Annotations = map[string]string{}
+	}
+	r.machine.Annotations[gracefulShutdownStartedAnnotation] = r.clock.Now().UTC().Format(time.RFC3339)
+	if err := r.coreClient.Patch(r.Context, r.machine, patchBase); err != nil {
+		return fmt.Errorf("failed to record the start of the graceful shutdown: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type instanceStatusComputeService struct {
+	*computeservice.GCPComputeServiceMock
+	status string
+}
+
+func (c *instanceStatusComputeService) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
+	return &compute.Instance{Name: instance, Status: c.status}, nil
+}
+
+func TestShutdownBeforeDelete(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		status          string
+		expectedRequeue bool
+		expectedStop    bool
+		expectedStarted string
+		expectedEvent   string
+	}{
+		{
+			name:   "Instance is deleted immediately by default",
+			status: "RUNNING",
+		},
+		{
+			name:            "Running instance is stopped first",
+			annotations:     map[string]string{deleteStrategyAnnotation: deleteStrategyStopFirst},
+			status:          "RUNNING",
+			expectedRequeue: true,
+			expectedStop:    true,
+			expectedStarted: "2024-01-01T12:00:00Z",
+			expectedEvent:   gracefulShutdownStartedEvent,
+		},
+		{
+			name: "Stopping instance is waited for",
+			annotations: map[string]string{
+				deleteStrategyAnnotation:          deleteStrategyStopFirst,
+				gracefulShutdownStartedAnnotation: "2024-01-01T11:58:00Z",
+			},
+			status:          "STOPPING",
+			expectedRequeue: true,
+			expectedStarted: "2024-01-01T11:58:00Z",
+		},
+		{
+			name: "Stopped instance is deleted",
+			annotations: map[string]string{
+				deleteStrategyAnnotation:          deleteStrategyStopFirst,
+				gracefulShutdownStartedAnnotation: "2024-01-01T11:58:00Z",
+			},
+			status:          "TERMINATED",
+			expectedStarted: "2024-01-01T11:58:00Z",
+			expectedEvent:   gracefulShutdownCompletedEvent,
+		},
+		{
+			name: "Instance is deleted once the timeout elapsed",
+			annotations: map[string]string{
+				deleteStrategyAnnotation:          deleteStrategyStopFirst,
+				gracefulShutdownTimeoutAnnotation: "1m",
+				gracefulShutdownStartedAnnotation: "2024-01-01T11:58:00Z",
+			},
+			status:          "STOPPING",
+			expectedStarted: "2024-01-01T11:58:00Z",
+			expectedEvent:   gracefulShutdownTimedOutEvent,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			stopped := false
+			mockComputeService.MockInstancesStop = func(project string, zone string, instance string) (*compute.Operation, error) {
+				stopped = true
+				return &compute.Operation{Status: "RUNNING"}, nil
+			}
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tc.annotations}}
+			coreClient := controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine.DeepCopy()).Build()
+			recorder := record.NewFakeRecorder(2)
+			r := newReconciler(&machineScope{
+				Context:        context.Background(),
+				coreClient:     coreClient,
+				machine:        machine,
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: &instanceStatusComputeService{GCPComputeServiceMock: mockComputeService, status: tc.status},
+				clock:          clocktesting.NewFakeClock(now),
+				eventRecorder:  recorder,
+			})
+
+			err := r.shutdownBeforeDelete()
+			var requeueErr *machinecontroller.RequeueAfterError
+			if requeue := errors.As(err, &requeueErr); requeue != tc.expectedRequeue || (!requeue && err != nil) {
+				t.Fatalf("Expected a requeue %v, got %v", tc.expectedRequeue, err)
+			}
+			if stopped != tc.expectedStop {
+				t.Errorf("Expected the instance to be stopped %v, got %v", tc.expectedStop, stopped)
+			}
+			persisted := &machinev1.Machine{}
+			if err := coreClient.Get(context.Background(), controllerclient.ObjectKeyFromObject(machine), persisted); err != nil {
+				t.Fatal(err)
+			}
+			if started := persisted.Annotations[gracefulShutdownStartedAnnotation]; started != tc.expectedStarted {
+				t.Errorf("Expected the graceful shutdown to have started at %q, got %q", tc.expectedStarted, started)
+			}
+			var event string
+			select {
+			case event = <-recorder.Events:
+			default:
+			}
+			if tc.expectedEvent == "" && event != "" || tc.expectedEvent != "" && !strings.Contains(event, " "+tc.expectedEvent+" ") {
+				t.Errorf("Expected event %q, got %q", tc.expectedEvent, event)
+			}
+		})
+	}
+}
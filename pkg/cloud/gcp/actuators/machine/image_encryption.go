@@ -0,0 +1,162 @@
+package machine
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	sourceImageKeyInvalidReason = "SourceImageKeyInvalid"
+
+	sourceImageRawKeySecretKey          = "rawKey"
+	sourceImageRSAEncryptedKeySecretKey = "rsaEncryptedKey"
+)
+
+// sourceImageEncryptionKey returns the key to decrypt the source image of the boot disk with, as
+// configured in the sourceImageEncryptionKeyAnnotation or the sourceImageEncryptionKeySecretAnnotation.
+func (r *Reconciler) sourceImageEncryptionKey() (*compute.CustomerEncryptionKey, error) {
+	keyRef := &machinev1.GCPEncryptionKeyReference{}
+	hasKMSKey, err := r.getJSONAnnotation(sourceImageEncryptionKeyAnnotation, keyRef)
+	if err != nil {
+		return nil, err
+	}
+	secretName, hasSecret := r.getAnnotation(sourceImageEncryptionKeySecretAnnotation)
+	hasSecret = hasSecret && secretName != ""
+
+	switch {
+	case hasKMSKey && hasSecret:
+		return nil, machinecontroller.InvalidMachineConfiguration("only one of the %s and %s annotations can be set", sourceImageEncryptionKeyAnnotation, sourceImageEncryptionKeySecretAnnotation)
+	case hasKMSKey:
+		if keyRef.KMSKey == nil || keyRef.KMSKey.Name == "" || keyRef.KMSKey.KeyRing == "" || keyRef.KMSKey.Location == "" {
+			return nil, machinecontroller.InvalidMachineConfiguration("%s annotation must reference a KMS key by name, keyRing and location", sourceImageEncryptionKeyAnnotation)
+		}
+		return generateDiskEncryptionKey(keyRef, r.projectID), nil
+	case hasSecret:
+		return r.sourceImageEncryptionKeyFromSecret(secretName)
+	}
+	return nil, nil
+}
+
+// sourceImageEncryptionKeyFromSecret returns the customer-supplied key stored in the given Secret.
+func (r *Reconciler) sourceImageEncryptionKeyFromSecret(name string) (*compute.CustomerEncryptionKey, error) {
+	secret := &corev1.Secret{}
+	if err := r.coreClient.Get(r.Context, client.ObjectKey{Namespace: r.machine.GetNamespace(), Name: name}, secret); err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			return nil, machinecontroller.InvalidMachineConfiguration("source image encryption key secret %q in namespace %q not found", name, r.machine.GetNamespace())
+		}
+		return nil, fmt.Errorf("error getting source image encryption key secret %q in namespace %q: %w", name, r.machine.GetNamespace(), err)
+	}
+
+	rawKey, rsaEncryptedKey := secret.Data[sourceImageRawKeySecretKey], secret.Data[sourceImageRSAEncryptedKeySecretKey]
+	switch {
+	case len(rawKey) > 0 && len(rsaEncryptedKey) > 0:
+		return nil, machinecontroller.InvalidMachineConfiguration("secret %s/%s must set only one of %q and %q", r.machine.GetNamespace(), name, sourceImageRawKeySecretKey, sourceImageRSAEncryptedKeySecretKey)
+	case len(rawKey) > 0:
+		if key, err := base64.StdEncoding.DecodeString(string(rawKey)); err != nil || len(key) != sha256.Size {
+			return nil, machinecontroller.InvalidMachineConfiguration("%q of secret %s/%s must be a base64 encoded 256-bit key", sourceImageRawKeySecretKey, r.machine.GetNamespace(), name)
+		}
+		return &compute.CustomerEncryptionKey{RawKey: string(rawKey)}, nil
+	case len(rsaEncryptedKey) > 0:
+		return &compute.CustomerEncryptionKey{RsaEncryptedKey: string(rsaEncryptedKey)}, nil
+	}
+	return nil, machinecontroller.InvalidMachineConfiguration("secret %s/%s does not have %q or %q set", r.machine.GetNamespace(), name, sourceImageRawKeySecretKey, sourceImageRSAEncryptedKeySecretKey)
+}
+
+// parseImageLink parses a (partial) link of an image or image family, e.g.
+// https://www.googleapis.com/compute/v1/projects/<project>/global/images/<name> or
+// projects/<project>/global/images/family/<family>.
+func parseImageLink(link string) (project, name, family string, err error) {
+	parts := strings.Split(link, "/")
+	for i := 0; i+4 < len(parts); i++ {
+		if parts[i] != "projects" || parts[i+2] != "global" || parts[i+3] != "images" {
+			continue
+		}
+		switch rest := parts[i+4:]; {
+		case len(rest) == 1:
+			return parts[i+1], rest[0], "", nil
+		case len(rest) == 2 && rest[0] == "family":
+			return parts[i+1], "", rest[1], nil
+		}
+	}
+	return "", "", "", fmt.Errorf("%q is not a valid image link", link)
+}
+
+// checkSourceImageEncryptionKey verifies that the source image of the boot disk is encrypted with
+// the configured key, so that a wrong key fails the creation with a meaningful error.
+func (r *Reconciler) checkSourceImageEncryptionKey(state *preflightState) error {
+	for _, disk := range state.instance.Disks {
+		if !disk.Boot || disk.InitializeParams == nil || disk.InitializeParams.SourceImageEncryptionKey == nil {
+			continue
+		}
+		sourceImage, key := disk.InitializeParams.SourceImage, disk.InitializeParams.SourceImageEncryptionKey
+
+		project, name, family, err := parseImageLink(sourceImage)
+		if err != nil {
+			return machinecontroller.InvalidMachineConfiguration("invalid source image: %v", err)
+		}
+		var image *compute.Image
+		if family != "" {
+			image, err = r.computeService.ImagesGetFromFamily(project, family)
+		} else {
+			image, err = r.computeService.ImagesGet(project, name)
+		}
+		if err != nil {
+			var apiErr *googleapi.Error
+			switch {
+			case isNotFoundError(err):
+				return machinecontroller.InvalidMachineConfiguration("source image %s not found", sourceImage)
+			case errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden:
+				return machinecontroller.InvalidMachineConfiguration("no access to source image %s: %v", sourceImage, err)
+			}
+			return fmt.Errorf("failed to get source image %s: %w", sourceImage, err)
+		}
+
+		if err := validateImageEncryptionKey(image, key); err != nil {
+			return &preflightError{
+				reason: sourceImageKeyInvalidReason,
+				err:    machinecontroller.InvalidMachineConfiguration("source image %s: %v", sourceImage, err),
+			}
+		}
+	}
+	return nil
+}
+
+// validateImageEncryptionKey checks that the key is the one the image is encrypted with.
+// Keys wrapped with the Google public key cannot be compared and are accepted.
+func validateImageEncryptionKey(image *compute.Image, key *compute.CustomerEncryptionKey) error {
+	imageKey := image.ImageEncryptionKey
+	if imageKey == nil {
+		return errors.New("the image is not encrypted with a customer managed or supplied key")
+	}
+
+	switch {
+	case key.KmsKeyName != "":
+		// The image records the key version it was encrypted with.
+		imageKMSKey := strings.SplitN(imageKey.KmsKeyName, "/cryptoKeyVersions/", 2)[0]
+		if imageKMSKey != key.KmsKeyName {
+			return fmt.Errorf("the image is encrypted with KMS key %q, not %q", imageKMSKey, key.KmsKeyName)
+		}
+	case key.RawKey != "":
+		rawKey, err := base64.StdEncoding.DecodeString(key.RawKey)
+		if err != nil {
+			return fmt.Errorf("invalid raw key: %w", err)
+		}
+		hash := sha256.Sum256(rawKey)
+		if imageKey.Sha256 != "" && imageKey.Sha256 != base64.StdEncoding.EncodeToString(hash[:]) {
+			return errors.New("the image is encrypted with a different customer-supplied key")
+		}
+	}
+	return nil
+}
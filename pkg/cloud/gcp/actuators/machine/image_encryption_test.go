@@ -0,0 +1,216 @@
+package machine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSourceImageEncryptionKey(t *testing.T) {
+	rawKey := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	secret := func(data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "image-key", Namespace: "test"}, Data: map[string][]byte{}}
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+
+	cases := []struct {
+		name          string
+		annotations   map[string]string
+		secret        *corev1.Secret
+		expected      *compute.CustomerEncryptionKey
+		expectedError string
+	}{
+		{
+			name: "No key",
+		},
+		{
+			name: "KMS key",
+			annotations: map[string]string{
+				sourceImageEncryptionKeyAnnotation: `{"kmsKey": {"name": "key", "keyRing": "ring", "location": "global"}, "kmsKeyServiceAccount": "kms@project.iam.gserviceaccount.com"}`,
+			},
+			expected: &compute.CustomerEncryptionKey{
+				KmsKeyName:           "projects/project/locations/global/keyRings/ring/cryptoKeys/key",
+				KmsKeyServiceAccount: "kms@project.iam.gserviceaccount.com",
+			},
+		},
+		{
+			name: "Incomplete KMS key",
+			annotations: map[string]string{
+				sourceImageEncryptionKeyAnnotation: `{"kmsKey": {"name": "key"}}`,
+			},
+			expectedError: "machine.openshift.io/gcp-source-image-encryption-key annotation must reference a KMS key by name, keyRing and location",
+		},
+		{
+			name:        "Customer-supplied key",
+			annotations: map[string]string{sourceImageEncryptionKeySecretAnnotation: "image-key"},
+			secret:      secret(map[string]string{sourceImageRawKeySecretKey: rawKey}),
+			expected:    &compute.CustomerEncryptionKey{RawKey: rawKey},
+		},
+		{
+			name:        "RSA wrapped customer-supplied key",
+			annotations: map[string]string{sourceImageEncryptionKeySecretAnnotation: "image-key"},
+			secret:      secret(map[string]string{sourceImageRSAEncryptedKeySecretKey: "wrapped"}),
+			expected:    &compute.CustomerEncryptionKey{RsaEncryptedKey: "wrapped"},
+		},
+		{
+			name:          "Customer-supplied key of the wrong size",
+			annotations:   map[string]string{sourceImageEncryptionKeySecretAnnotation: "image-key"},
+			secret:        secret(map[string]string{sourceImageRawKeySecretKey: base64.StdEncoding.EncodeToString([]byte("short"))}),
+			expectedError: "\"rawKey\" of secret test/image-key must be a base64 encoded 256-bit key",
+		},
+		{
+			name:          "Missing secret",
+			annotations:   map[string]string{sourceImageEncryptionKeySecretAnnotation: "image-key"},
+			expectedError: "source image encryption key secret \"image-key\" in namespace \"test\" not found",
+		},
+		{
+			name: "Both annotations",
+			annotations: map[string]string{
+				sourceImageEncryptionKeyAnnotation:       `{"kmsKey": {"name": "key", "keyRing": "ring", "location": "global"}}`,
+				sourceImageEncryptionKeySecretAnnotation: "image-key",
+			},
+			expectedError: "only one of the machine.openshift.io/gcp-source-image-encryption-key and machine.openshift.io/gcp-source-image-encryption-key-secret annotations can be set",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			builder := controllerfake.NewClientBuilder()
+			if tc.secret != nil {
+				builder = builder.WithObjects(tc.secret)
+			}
+			r := newReconciler(&machineScope{
+				Context:      context.Background(),
+				coreClient:   builder.Build(),
+				projectID:    "project",
+				machine:      &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tc.annotations}},
+				providerSpec: &machinev1.GCPMachineProviderSpec{},
+			})
+
+			key, err := r.sourceImageEncryptionKey()
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Fatalf("Expected error %q, got %v", tc.expectedError, err)
+				}
+				if !isInvalidMachineConfigurationError(err) {
+					t.Errorf("Expected an invalid machine configuration error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(key, tc.expected) {
+				t.Errorf("Expected key %+v, got %+v", tc.expected, key)
+			}
+		})
+	}
+}
+
+func TestCheckSourceImageEncryptionKey(t *testing.T) {
+	rawKey := make([]byte, 32)
+	rawKeyHash := sha256.Sum256(rawKey)
+	kmsKey := "projects/project/locations/global/keyRings/ring/cryptoKeys/key"
+
+	cases := []struct {
+		name          string
+		sourceImage   string
+		key           *compute.CustomerEncryptionKey
+		imageKey      *compute.CustomerEncryptionKey
+		expectedImage string
+		expectedError string
+	}{
+		{
+			name:          "Matching KMS key",
+			sourceImage:   "https://www.googleapis.com/compute/v1/projects/golden/global/images/rhcos",
+			key:           &compute.CustomerEncryptionKey{KmsKeyName: kmsKey},
+			imageKey:      &compute.CustomerEncryptionKey{KmsKeyName: kmsKey + "/cryptoKeyVersions/3"},
+			expectedImage: "golden/rhcos",
+		},
+		{
+			name:          "Matching customer-supplied key of an image family",
+			sourceImage:   "projects/golden/global/images/family/rhcos-4",
+			key:           &compute.CustomerEncryptionKey{RawKey: base64.StdEncoding.EncodeToString(rawKey)},
+			imageKey:      &compute.CustomerEncryptionKey{Sha256: base64.StdEncoding.EncodeToString(rawKeyHash[:])},
+			expectedImage: "golden/family/rhcos-4",
+		},
+		{
+			name:          "Different KMS key",
+			sourceImage:   "projects/golden/global/images/rhcos",
+			key:           &compute.CustomerEncryptionKey{KmsKeyName: kmsKey},
+			imageKey:      &compute.CustomerEncryptionKey{KmsKeyName: "projects/project/locations/global/keyRings/ring/cryptoKeys/other/cryptoKeyVersions/1"},
+			expectedImage: "golden/rhcos",
+			expectedError: "source image projects/golden/global/images/rhcos: the image is encrypted with KMS key \"projects/project/locations/global/keyRings/ring/cryptoKeys/other\", not \"projects/project/locations/global/keyRings/ring/cryptoKeys/key\"",
+		},
+		{
+			name:          "Different customer-supplied key",
+			sourceImage:   "projects/golden/global/images/rhcos",
+			key:           &compute.CustomerEncryptionKey{RawKey: base64.StdEncoding.EncodeToString(rawKey)},
+			imageKey:      &compute.CustomerEncryptionKey{Sha256: "other"},
+			expectedImage: "golden/rhcos",
+			expectedError: "source image projects/golden/global/images/rhcos: the image is encrypted with a different customer-supplied key",
+		},
+		{
+			name:          "Image is not encrypted",
+			sourceImage:   "projects/golden/global/images/rhcos",
+			key:           &compute.CustomerEncryptionKey{KmsKeyName: kmsKey},
+			expectedImage: "golden/rhcos",
+			expectedError: "source image projects/golden/global/images/rhcos: the image is not encrypted with a customer managed or supplied key",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotImage string
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockImagesGet = func(project string, image string) (*compute.Image, error) {
+				gotImage = project + "/" + image
+				return &compute.Image{Name: image, ImageEncryptionKey: tc.imageKey}, nil
+			}
+			mockComputeService.MockImagesGetFromFamily = func(project string, family string) (*compute.Image, error) {
+				gotImage = project + "/family/" + family
+				return &compute.Image{Name: family, ImageEncryptionKey: tc.imageKey}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+			})
+			instance := &compute.Instance{Disks: []*compute.AttachedDisk{{
+				Boot: true,
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					SourceImage:              tc.sourceImage,
+					SourceImageEncryptionKey: tc.key,
+				},
+			}}}
+
+			err := r.checkSourceImageEncryptionKey(&preflightState{instance: instance})
+			if gotImage != tc.expectedImage {
+				t.Errorf("Expected image %s to be looked up, got %s", tc.expectedImage, gotImage)
+			}
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Fatalf("Expected error %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
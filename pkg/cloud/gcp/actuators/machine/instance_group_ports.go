@@ -0,0 +1,82 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	// createdInstanceGroupDescription is the description of the control plane instance groups
+	// created by the provider, formatted with the cluster ID. It tells them apart from the groups of
+	// the installer, whose named ports are left alone unless they are configured.
+	createdInstanceGroupDescription = "Control plane instance group of cluster %s" + createdInstanceGroupSuffix
+	createdInstanceGroupSuffix      = ", created by the machine controller"
+)
+
+// defaultInstanceGroupNamedPorts are the named ports the installer sets on the control plane
+// instance groups: the API server and the machine config server.
+var defaultInstanceGroupNamedPorts = []*compute.NamedPort{
+	{Name: "https", Port: 6443},
+	{Name: "ignition", Port: 22623},
+}
+
+// instanceGroupNamedPorts returns the named ports of the control plane instance group, nil when
+// the group is being created. They are the configured ones or, for the groups created by the
+// provider, those of the installer. Groups of the installer without configured named ports have
+// none to reconcile.
+func (r *Reconciler) instanceGroupNamedPorts(instanceGroup *compute.InstanceGroup) []*compute.NamedPort {
+	if r.loadBalancerConfig != nil && len(r.loadBalancerConfig.instanceGroupNamedPorts) > 0 {
+		return r.loadBalancerConfig.instanceGroupNamedPorts
+	}
+	if instanceGroup == nil || strings.HasSuffix(instanceGroup.Description, createdInstanceGroupSuffix) {
+		return defaultInstanceGroupNamedPorts
+	}
+	return nil
+}
+
+// ensureInstanceGroupNamedPorts adds the named ports missing from the instance group, or whose port
+// drifted, keeping the other named ports of the group.
+func (r *Reconciler) ensureInstanceGroupNamedPorts(instanceGroup *compute.InstanceGroup) error {
+	if instanceGroup == nil {
+		return nil
+	}
+	namedPorts := r.instanceGroupNamedPorts(instanceGroup)
+	if len(namedPorts) == 0 {
+		return nil
+	}
+	merged := make([]*compute.NamedPort, 0, len(instanceGroup.NamedPorts)+len(namedPorts))
+	byName := map[string]*compute.NamedPort{}
+	for _, namedPort := range instanceGroup.NamedPorts {
+		namedPort := *namedPort
+		byName[namedPort.Name] = &namedPort
+		merged = append(merged, &namedPort)
+	}
+	var changed []string
+	for _, namedPort := range namedPorts {
+		existing, ok := byName[namedPort.Name]
+		switch {
+		case !ok:
+			merged = append(merged, namedPort)
+		case existing.Port != namedPort.Port:
+			existing.Port = namedPort.Port
+		default:
+			continue
+		}
+		changed = append(changed, fmt.Sprintf("%s:%d", namedPort.Name, namedPort.Port))
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	r.log.Info("Setting named ports of instance group", "instanceGroup", instanceGroup.Name, "namedPorts", changed)
+	_, err := r.computeService.InstanceGroupsSetNamedPorts(r.projectID, r.providerSpec.Zone, instanceGroup.Name, &compute.InstanceGroupsSetNamedPortsRequest{
+		NamedPorts:  merged,
+		Fingerprint: instanceGroup.Fingerprint,
+	})
+	if err != nil {
+		return fmt.Errorf("instanceGroupsSetNamedPorts request failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package machine
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureInstanceGroupNamedPorts(t *testing.T) {
+	configured := []*compute.NamedPort{{Name: "https", Port: 6443}, {Name: "ignition", Port: 22623}, {Name: "metrics", Port: 9100}}
+	cases := []struct {
+		name                string
+		configured          []*compute.NamedPort
+		existing            []*compute.NamedPort
+		description         string
+		groupNotFound       bool
+		expectedNamedPorts  []*compute.NamedPort
+		expectedDescription string
+	}{
+		{
+			name:       "Existing group with the named ports is left alone",
+			configured: configured,
+			existing:   []*compute.NamedPort{{Name: "metrics", Port: 9100}, {Name: "ignition", Port: 22623}, {Name: "https", Port: 6443}},
+		},
+		{
+			name:               "Missing and changed named ports are set on an existing group",
+			configured:         configured,
+			existing:           []*compute.NamedPort{{Name: "https", Port: 443}, {Name: "ssh", Port: 22}},
+			expectedNamedPorts: []*compute.NamedPort{{Name: "https", Port: 6443}, {Name: "ssh", Port: 22}, {Name: "ignition", Port: 22623}, {Name: "metrics", Port: 9100}},
+		},
+		{
+			name:                "Configured named ports are set on a new group",
+			configured:          configured,
+			groupNotFound:       true,
+			expectedNamedPorts:  configured,
+			expectedDescription: "Control plane instance group of cluster CLUSTERID, created by the machine controller",
+		},
+		{
+			name:                "Named ports of the installer are set on a new group",
+			groupNotFound:       true,
+			expectedNamedPorts:  defaultInstanceGroupNamedPorts,
+			expectedDescription: "Control plane instance group of cluster CLUSTERID, created by the machine controller",
+		},
+		{
+			name:               "Drifted named ports of a group created by the provider are reconciled",
+			existing:           []*compute.NamedPort{{Name: "https", Port: 443}},
+			description:        "Control plane instance group of cluster CLUSTERID, created by the machine controller",
+			expectedNamedPorts: []*compute.NamedPort{{Name: "https", Port: 6443}, {Name: "ignition", Port: 22623}},
+		},
+		{
+			name:     "Group of the installer without configured named ports is left alone",
+			existing: []*compute.NamedPort{{Name: "https", Port: 443}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var namedPorts []*compute.NamedPort
+			var description string
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockInstanceGroupGet = func(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error) {
+				if tc.groupNotFound {
+					return nil, &googleapi.Error{Code: http.StatusNotFound}
+				}
+				existing := make([]*compute.NamedPort, 0, len(tc.existing))
+				for _, namedPort := range tc.existing {
+					namedPort := *namedPort
+					existing = append(existing, &namedPort)
+				}
+				return &compute.InstanceGroup{Name: instanceGroupName, NamedPorts: existing, Description: tc.description, Fingerprint: "fingerprint"}, nil
+			}
+			mockComputeService.MockSetNamedPorts = func(project string, zone string, instanceGroupName string, request *compute.InstanceGroupsSetNamedPortsRequest) (*compute.Operation, error) {
+				if request.Fingerprint != "fingerprint" {
+					t.Errorf("Expected the fingerprint of the group, got %q", request.Fingerprint)
+				}
+				namedPorts = request.NamedPorts
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			computeService := &instanceGroupInsertTrackingComputeService{GCPComputeServiceMock: mockComputeService, namedPorts: &namedPorts, description: &description}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+					Name:   "testInstance",
+					Labels: map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+				}},
+				coreClient: controllerfake.NewFakeClient(),
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:              "zone1",
+					Region:            "region1",
+					NetworkInterfaces: []*machinev1.GCPNetworkInterface{{Network: "CLUSTERID-network", Subnetwork: "CLUSTERID-master-subnet"}},
+				},
+				projectID:      "testProject",
+				computeService: computeService,
+			})
+			r.loadBalancerConfig = &loadBalancerConfig{instanceGroupNamedPorts: tc.configured}
+			r.loadBalancerMembership = &loadBalancerMembership{}
+
+			if err := r.ensureInstanceGroup(r.controlPlaneGroupName()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(namedPorts, tc.expectedNamedPorts) {
+				t.Errorf("Expected named ports %v, got %v", tc.expectedNamedPorts, namedPorts)
+			}
+			if description != tc.expectedDescription {
+				t.Errorf("Expected the group to be created with description %q, got %q", tc.expectedDescription, description)
+			}
+		})
+	}
+}
+
+type instanceGroupInsertTrackingComputeService struct {
+	*computeservice.GCPComputeServiceMock
+	namedPorts  *[]*compute.NamedPort
+	description *string
+}
+
+func (c *instanceGroupInsertTrackingComputeService) InstanceGroupInsert(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error) {
+	*c.namedPorts = instanceGroup.NamedPorts
+	*c.description = instanceGroup.Description
+	return &compute.Operation{Status: "DONE"}, nil
+}
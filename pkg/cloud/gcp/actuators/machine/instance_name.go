@@ -0,0 +1,118 @@
+package machine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"text/template"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+)
+
+const (
+	// maxInstanceNameLength is the length limit of GCP resource names, see
+	// https://cloud.google.com/compute/docs/naming-resources#resource-name-format.
+	maxInstanceNameLength = 63
+	// instanceNameHashLength is the length of the hash ending the names that are truncated.
+	instanceNameHashLength = 8
+)
+
+// instanceNameData is what an instance name template, or a managed tag template, can refer to.
+type instanceNameData struct {
+	MachineName string
+	Namespace   string
+	ClusterID   string
+	Role        string
+}
+
+// newInstanceNameData returns the template data of a machine.
+func newInstanceNameData(machine *machinev1.Machine) instanceNameData {
+	return instanceNameData{
+		MachineName: machine.Name,
+		Namespace:   machine.Namespace,
+		ClusterID:   machine.Labels[machinev1.MachineClusterIDLabel],
+		Role:        machine.Labels[openshiftMachineRoleLabel],
+	}
+}
+
+// ParseInstanceNameTemplate parses a Go template of the names of instances, e.g.
+// "{{.ClusterID}}-{{.MachineName}}", from the name, namespace, cluster ID and role of the machine.
+// The result is made a valid instance name, see makeInstanceName.
+func ParseInstanceNameTemplate(text string) (*template.Template, error) {
+	return parseMachineTemplate("instance-name", text)
+}
+
+// parseMachineTemplate parses a Go template rendered from instanceNameData, refusing templates that
+// fail or give an empty result.
+func parseMachineTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, instanceNameData{MachineName: "machine"}); err != nil {
+		return nil, err
+	}
+	if rendered.Len() == 0 {
+		return nil, fmt.Errorf("template %q gives an empty %s", text, strings.ReplaceAll(name, "-", " "))
+	}
+	return tmpl, nil
+}
+
+// instanceName returns the name of the instance of the machine. Once the instance exists, it is the
+// name recorded in the provider status, so that a change of the template or of the prefix does not
+// lose the instance. Before, it is the prefix annotation followed by the name from the template, or
+// the name of the machine without a template, made a valid instance name.
+func instanceName(tmpl *template.Template, machine *machinev1.Machine, providerStatus *machinev1.GCPMachineProviderStatus) (string, error) {
+	if providerStatus != nil && providerStatus.InstanceID != nil && *providerStatus.InstanceID != "" {
+		return *providerStatus.InstanceID, nil
+	}
+	name := machine.Name
+	if tmpl != nil {
+		var rendered strings.Builder
+		if err := tmpl.Execute(&rendered, newInstanceNameData(machine)); err != nil {
+			return "", fmt.Errorf("failed to render the instance name template: %w", err)
+		}
+		name = rendered.String()
+	}
+	return makeInstanceName(machine.Annotations[instanceNamePrefixAnnotation] + name), nil
+}
+
+// instanceNameOf returns the name of the instance of a machine outside of its reconcile, the name
+// of the machine when it cannot be rendered.
+func (a *Actuator) instanceNameOf(machine *machinev1.Machine) string {
+	providerStatus, _ := util.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	name, err := instanceName(a.options.InstanceNameTemplate, machine, providerStatus)
+	if err != nil {
+		return machine.Name
+	}
+	return name
+}
+
+// makeInstanceName makes a name a valid GCP resource name, which must start with a lowercase letter
+// and only contain lowercase letters, digits and dashes. Names too long are truncated and end with a
+// hash of the name, so that names with the same beginning stay different. Valid names are unchanged.
+func makeInstanceName(name string) string {
+	valid := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+	if valid == "" || valid[0] < 'a' || valid[0] > 'z' {
+		valid = "m-" + valid
+	}
+	valid = strings.TrimRight(valid, "-")
+	if len(valid) <= maxInstanceNameLength {
+		return valid
+	}
+	hash := sha256.Sum256([]byte(name))
+	truncated := strings.TrimRight(valid[:maxInstanceNameLength-instanceNameHashLength-1], "-")
+	return truncated + "-" + hex.EncodeToString(hash[:])[:instanceNameHashLength]
+}
@@ -0,0 +1,96 @@
+package machine
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestMakeInstanceName(t *testing.T) {
+	long := "a-very-long-machineset-name-of-a-cluster-with-a-long-infrastructure-id-worker-us-central1-a-x7k2p"
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{name: "worker-us-central1-a-x7k2p", expected: "worker-us-central1-a-x7k2p"},
+		{name: "Worker.Example_1", expected: "worker-example-1"},
+		{name: "1st-worker", expected: "m-1st-worker"},
+		{name: long, expected: "a-very-long-machineset-name-of-a-cluster-with-a-long-i-fbb9e14c"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			name := makeInstanceName(tc.name)
+			if len(name) > maxInstanceNameLength {
+				t.Errorf("Expected at most %d characters, got %d: %s", maxInstanceNameLength, len(name), name)
+			}
+			if name != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, name)
+			}
+		})
+	}
+
+	if makeInstanceName(long+"a") == makeInstanceName(long+"b") {
+		t.Error("Expected names with the same beginning to stay different")
+	}
+}
+
+func TestInstanceName(t *testing.T) {
+	tmpl, err := ParseInstanceNameTemplate("{{.ClusterID}}-{{.Role}}-{{.MachineName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+		Name:      "worker-a-x7k2p",
+		Namespace: "openshift-machine-api",
+		Labels:    map[string]string{machinev1.MachineClusterIDLabel: "mycluster-abc12", openshiftMachineRoleLabel: "worker"},
+	}}
+	prefixed := machine.DeepCopy()
+	prefixed.Annotations = map[string]string{instanceNamePrefixAnnotation: "prod-"}
+
+	cases := []struct {
+		name           string
+		machine        *machinev1.Machine
+		tmpl           bool
+		providerStatus *machinev1.GCPMachineProviderStatus
+		expected       string
+	}{
+		{name: "Machine name", machine: machine, expected: "worker-a-x7k2p"},
+		{name: "Template", machine: machine, tmpl: true, expected: "mycluster-abc12-worker-worker-a-x7k2p"},
+		{name: "Prefix", machine: prefixed, expected: "prod-worker-a-x7k2p"},
+		{
+			name:           "Recorded name",
+			machine:        prefixed,
+			tmpl:           true,
+			providerStatus: &machinev1.GCPMachineProviderStatus{InstanceID: pointer.String("worker-a-x7k2p")},
+			expected:       "worker-a-x7k2p",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			template := tmpl
+			if !tc.tmpl {
+				template = nil
+			}
+			name, err := instanceName(template, tc.machine, tc.providerStatus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, name)
+			}
+		})
+	}
+}
+
+func TestParseInstanceNameTemplate(t *testing.T) {
+	for _, text := range []string{"{{.MachineName", "{{.Zone}}", "{{if false}}x{{end}}"} {
+		if _, err := ParseInstanceNameTemplate(text); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
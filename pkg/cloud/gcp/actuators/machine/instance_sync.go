@@ -0,0 +1,272 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const instanceMissingEventReason = "InstanceMissing"
+
+// instanceSync periodically lists the instances of the cluster with one aggregated list per
+// project instead of looking up the instance of every machine. Machines whose instance is listed
+// refresh their existence verification, so that Exists does not call the compute API for them,
+// and machines whose instance is gone, e.g. because it was deleted in the console, are annotated
+// with instanceMissingAnnotation so that the machine controller reconciles them right away instead
+// of at the next resync.
+type instanceSync struct {
+	actuator *Actuator
+	interval time.Duration
+}
+
+// instanceSyncGroup is the machines whose instances are listed together: the machines of a cluster
+// in the same project using the same credentials.
+type instanceSyncGroup struct {
+	projectID          string
+	clusterID          string
+	serviceAccountJSON string
+	machines           []*machinev1.Machine
+	// zones is the zone of the instance of each machine.
+	zones map[types.UID]string
+}
+
+// instanceKey identifies an instance, whose name is only unique in its zone and project.
+type instanceKey struct {
+	projectID string
+	zone      string
+	name      string
+}
+
+// machineInstances is the name of the machine each instance belongs to. A machine whose project or
+// zone is not known is recorded with the name of its instance only, which stands for that name in
+// every zone and project.
+type machineInstances map[instanceKey]string
+
+// machineOf returns the name of the machine the instance belongs to.
+func (m machineInstances) machineOf(key instanceKey) (string, bool) {
+	if machine, ok := m[key]; ok {
+		return machine, true
+	}
+	machine, ok := m[instanceKey{name: key.name}]
+	return machine, ok
+}
+
+// listedInstanceKey returns the key of an instance listed in the given project.
+func listedInstanceKey(projectID string, instance *compute.Instance) instanceKey {
+	return instanceKey{projectID: projectID, zone: path.Base(instance.Zone), name: instance.Name}
+}
+
+// InstanceSync returns the runnable that lists the instances of all machines every interval, nil
+// if the interval is not positive. It only runs on the leader.
+func (a *Actuator) InstanceSync(interval time.Duration) manager.Runnable {
+	if interval <= 0 {
+		return nil
+	}
+	return &instanceSync{actuator: a, interval: interval}
+}
+
+// Start lists the instances every interval until the context is done.
+func (s *instanceSync) Start(ctx context.Context) error {
+	log := klog.FromContext(ctx)
+	log.Info("Listing the instances of all machines", "interval", s.interval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.sync(ctx); err != nil {
+			log.Error(err, "Failed to sync instances")
+			instanceSyncsTotal.WithLabelValues("failed").Inc()
+			return
+		}
+		instanceSyncsTotal.WithLabelValues("succeeded").Inc()
+	}, s.interval)
+	return nil
+}
+
+// sync lists the instances of every group of machines and compares them with the machines.
+func (s *instanceSync) sync(ctx context.Context) error {
+	machines := &machinev1.MachineList{}
+	if err := s.actuator.coreClient.List(ctx, machines); err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+	groups, machineInstances := s.groupMachines(ctx, machines.Items)
+
+	var failed int
+	for _, group := range groups {
+		if err := s.syncGroup(ctx, group, machineInstances); err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to sync the instances of cluster", "clusterID", group.clusterID, "project", group.projectID)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to sync the instances of %d of %d projects", failed, len(groups))
+	}
+	return nil
+}
+
+// groupMachines groups the machines that have an instance by project, cluster and credentials.
+// Machines without an instance yet, failed or deleted ones are left to the machine controller.
+// It also returns the instances of all machines: every machine, with or without an instance,
+// keeps its instance from being collected as orphan.
+func (s *instanceSync) groupMachines(ctx context.Context, machines []machinev1.Machine) ([]*instanceSyncGroup, machineInstances) {
+	var groups []*instanceSyncGroup
+	byKey := map[string]*instanceSyncGroup{}
+	instances := make(machineInstances, len(machines))
+	for i := range machines {
+		machine := &machines[i]
+		name := s.actuator.instanceNameOf(machine)
+		projectID, zone, serviceAccountJSON, err := s.resolveMachine(machine)
+		if err != nil {
+			instances[instanceKey{name: name}] = machine.Name
+			if hasInstance(machine) {
+				machineLogger(ctx, machine).V(logLevelRoutine).Info("Skipping instance sync", "reason", err.Error())
+			}
+			continue
+		}
+		instances[instanceKey{projectID: projectID, zone: zone, name: name}] = machine.Name
+		if !hasInstance(machine) {
+			continue
+		}
+
+		clusterID := machine.Labels[machinev1.MachineClusterIDLabel]
+		key := projectID + "\x00" + clusterID + "\x00" + serviceAccountJSON
+		group, ok := byKey[key]
+		if !ok {
+			group = &instanceSyncGroup{projectID: projectID, clusterID: clusterID, serviceAccountJSON: serviceAccountJSON, zones: map[types.UID]string{}}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.machines = append(group.machines, machine)
+		group.zones[machine.UID] = zone
+	}
+	return groups, instances
+}
+
+// resolveMachine returns the project and zone of the instance of a machine and the credentials
+// to list it with.
+func (s *instanceSync) resolveMachine(machine *machinev1.Machine) (string, string, string, error) {
+	providerSpec, err := util.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return "", "", "", err
+	}
+	serviceAccountJSON, impersonateServiceAccount, err := util.GetCredentials(s.actuator.coreClient, machine.Namespace, *providerSpec)
+	if err != nil {
+		return "", "", "", err
+	}
+	projectID := providerSpec.ProjectID
+	if projectID == "" {
+		if projectID, err = util.GetProjectIDFromJSONKey([]byte(serviceAccountJSON)); err != nil {
+			return "", "", "", fmt.Errorf("error getting project from JSON key: %w", err)
+		}
+	}
+	if serviceAccountJSON, err = s.actuator.credentials.Build(serviceAccountJSON, impersonateServiceAccount); err != nil {
+		return "", "", "", fmt.Errorf("error building credentials: %w", err)
+	}
+	return projectID, instanceZone(machine, providerSpec), serviceAccountJSON, nil
+}
+
+// hasInstance returns true if the reconciler recorded an instance for the machine and the machine
+// is expected to still have it.
+func hasInstance(machine *machinev1.Machine) bool {
+	if machine.DeletionTimestamp != nil || machine.Labels[machinev1.MachineClusterIDLabel] == "" {
+		return false
+	}
+	if phase := pointer.StringDeref(machine.Status.Phase, ""); phase == "Failed" || phase == "Deleting" {
+		return false
+	}
+	providerStatus, err := util.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	return err == nil && providerStatus.InstanceID != nil
+}
+
+// syncGroup lists the instances of a group and updates its machines. Instances are only considered
+// missing when they were recorded in the provider status before the list, so that an instance
+// created concurrently is not reported. Listed instances without a machine are handed to
+// collectOrphans.
+func (s *instanceSync) syncGroup(ctx context.Context, group *instanceSyncGroup, machineInstances machineInstances) error {
+	computeService, err := s.actuator.computeClientBuilder(group.serviceAccountJSON)
+	if err != nil {
+		return fmt.Errorf("error creating compute service: %w", err)
+	}
+	filter := fmt.Sprintf("labels.%s = %q", util.ClusterOwnedLabelKey(group.clusterID), util.ClusterOwnedLabelValue)
+	instances, err := computeService.InstancesAggregatedList(group.projectID, filter)
+	if err != nil {
+		return err
+	}
+	listed := make(map[instanceKey]bool, len(instances))
+	for _, instance := range instances {
+		listed[listedInstanceKey(group.projectID, instance)] = instance.Status == "RUNNING"
+	}
+	s.collectOrphans(ctx, computeService, group, instances, machineInstances)
+
+	for _, machine := range group.machines {
+		providerStatus, err := util.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+		if err != nil {
+			continue
+		}
+		running, found := listed[instanceKey{projectID: group.projectID, zone: group.zones[machine.UID], name: *providerStatus.InstanceID}]
+		if !found {
+			s.actuator.existence.forget(machine)
+			if err := s.reportMissing(ctx, machine); err != nil {
+				machineLogger(ctx, machine).Error(err, "Failed to report missing instance", "instance", *providerStatus.InstanceID)
+			}
+			continue
+		}
+		if running {
+			s.actuator.existence.record(machine)
+		} else {
+			s.actuator.existence.forget(machine)
+		}
+		if err := s.clearMissing(ctx, machine); err != nil {
+			machineLogger(ctx, machine).Error(err, "Failed to remove annotation", "annotation", instanceMissingAnnotation)
+		}
+	}
+	return nil
+}
+
+// reportMissing annotates a machine whose instance was not listed, once, which makes the machine
+// controller reconcile it and find out that the instance is gone.
+func (s *instanceSync) reportMissing(ctx context.Context, machine *machinev1.Machine) error {
+	if _, ok := machine.Annotations[instanceMissingAnnotation]; ok {
+		return nil
+	}
+	machineLogger(ctx, machine).Info("Instance was not found in the cloud, requesting a reconcile")
+	instancesMissingTotal.Inc()
+
+	patchBase := controllerclient.MergeFrom(machine.DeepCopy())
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[instanceMissingAnnotation] = s.now().UTC().Format(time.RFC3339)
+	if err := s.actuator.coreClient.Patch(ctx, machine, patchBase); err != nil {
+		return err
+	}
+	s.actuator.eventRecorder.Eventf(machine, corev1.EventTypeWarning, instanceMissingEventReason, "Instance %s was not found in the cloud", machine.Name)
+	return nil
+}
+
+// clearMissing removes the annotation of a machine whose instance was missing from an earlier list,
+// e.g. because of an inconsistent list, and is listed again.
+func (s *instanceSync) clearMissing(ctx context.Context, machine *machinev1.Machine) error {
+	if _, ok := machine.Annotations[instanceMissingAnnotation]; !ok {
+		return nil
+	}
+	patchBase := controllerclient.MergeFrom(machine.DeepCopy())
+	delete(machine.Annotations, instanceMissingAnnotation)
+	return s.actuator.coreClient.Patch(ctx, machine, patchBase)
+}
+
+func (s *instanceSync) now() time.Time {
+	if s.actuator.clock == nil {
+		return time.Now()
+	}
+	return s.actuator.clock.Now()
+}
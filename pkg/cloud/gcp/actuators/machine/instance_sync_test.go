@@ -0,0 +1,104 @@
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInstanceSync(t *testing.T) {
+	credentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: defaultNamespaceName},
+		Data:       map[string][]byte{credentialsSecretKey: []byte("{\"project_id\": \"test\"}")},
+	}
+	newMachine := func(name string, annotations map[string]string) *machinev1.Machine {
+		machine := existenceCacheMachine(t, name, "RUNNING")
+		machine.Name = name
+		machine.UID = types.UID(name)
+		machine.Annotations = annotations
+		return machine
+	}
+	running := newMachine("running", map[string]string{instanceMissingAnnotation: "2024-01-01T00:00:00Z"})
+	stopped := newMachine("stopped", nil)
+	missing := newMachine("missing", nil)
+	// An instance of the same name in another zone is not the instance of the machine.
+	misplaced := newMachine("misplaced", nil)
+	reported := newMachine("reported", map[string]string{instanceMissingAnnotation: "2024-01-01T00:00:00Z"})
+	deleted := newMachine("deleted", nil)
+	deleted.Status.Phase = pointer.String("Failed")
+
+	var builds int
+	var projectID, filter string
+	_, mock := computeservice.NewComputeServiceMock()
+	mock.MockInstancesList = func(project string, f string) ([]*compute.Instance, error) {
+		projectID, filter = project, f
+		return []*compute.Instance{
+			{Name: "running", Status: "RUNNING", Zone: "zones/us-east1-b"},
+			{Name: "stopped", Status: "TERMINATED", Zone: "zones/us-east1-b"},
+			{Name: "misplaced", Status: "RUNNING", Zone: "zones/us-east1-c"},
+		}, nil
+	}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	c := controllerfake.NewClientBuilder().WithObjects(credentialsSecret, running, stopped, missing, misplaced, reported, deleted).Build()
+	recorder := record.NewFakeRecorder(10)
+	actuator := NewActuator(ActuatorParams{
+		CoreClient:    c,
+		EventRecorder: recorder,
+		ComputeClientBuilder: func(serviceAccountJSON string) (computeservice.GCPComputeService, error) {
+			builds++
+			return mock, nil
+		},
+		Clock:   fakeClock,
+		Options: Options{ExistsVerificationInterval: 5 * time.Minute},
+	})
+	for _, machine := range []*machinev1.Machine{stopped, missing, misplaced} {
+		actuator.existence.record(machine)
+	}
+
+	if err := actuator.InstanceSync(time.Minute).(*instanceSync).sync(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if builds != 1 || projectID != "test" || filter != `labels.kubernetes-io-cluster-CLUSTERID = "owned"` {
+		t.Errorf("Expected one list of project test filtered by the cluster label, got %d lists of project %q with filter %q", builds, projectID, filter)
+	}
+	for _, tc := range []struct {
+		machine         *machinev1.Machine
+		expectedFresh   bool
+		expectedMissing bool
+	}{
+		{machine: running, expectedFresh: true},
+		{machine: stopped},
+		{machine: missing, expectedMissing: true},
+		{machine: misplaced, expectedMissing: true},
+		{machine: reported, expectedMissing: true},
+		{machine: deleted},
+	} {
+		if fresh := actuator.existence.fresh(tc.machine); fresh != tc.expectedFresh {
+			t.Errorf("%s: expected the existence verification to be fresh %v, got %v", tc.machine.Name, tc.expectedFresh, fresh)
+		}
+		updated := &machinev1.Machine{}
+		if err := c.Get(context.Background(), controllerclient.ObjectKeyFromObject(tc.machine), updated); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := updated.Annotations[instanceMissingAnnotation]; ok != tc.expectedMissing {
+			t.Errorf("%s: expected the machine to be annotated missing %v, got annotations %v", tc.machine.Name, tc.expectedMissing, updated.Annotations)
+		}
+	}
+	// The machine reported by an earlier sync is not reported again.
+	if len(recorder.Events) != 2 {
+		t.Errorf("Expected two events, got %d", len(recorder.Events))
+	}
+}
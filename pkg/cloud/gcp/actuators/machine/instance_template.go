@@ -0,0 +1,50 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+)
+
+const instanceTemplateLinkFmt = "projects/%s/global/instanceTemplates/%s"
+
+// sourceInstanceTemplate returns the partial URL or self link of the instance template the instance
+// is created from, empty when the machine does not reference one.
+func (r *Reconciler) sourceInstanceTemplate() (string, error) {
+	ref, ok := r.getAnnotation(instanceTemplateAnnotation)
+	if !ok || ref == "" {
+		return "", nil
+	}
+	if !strings.Contains(ref, "/") {
+		return fmt.Sprintf(instanceTemplateLinkFmt, r.projectID, ref), nil
+	}
+	parts := strings.Split(resourcePath(ref), "/")
+	switch {
+	case len(parts) == 5 && parts[0] == "projects" && parts[2] == "global" && parts[3] == "instanceTemplates":
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "regions" && parts[4] == "instanceTemplates":
+	default:
+		return "", machinecontroller.InvalidMachineConfiguration("invalid value %q for annotation %s: expected an instance template name, "+
+			"projects/<project>/global/instanceTemplates/<name> or projects/<project>/regions/<region>/instanceTemplates/<name>", ref, instanceTemplateAnnotation)
+	}
+	return ref, nil
+}
+
+// fromInstanceTemplate returns whether the instance of the machine is created from an instance
+// template, whose properties the providerSpec does not describe.
+func (r *Reconciler) fromInstanceTemplate() bool {
+	ref, ok := r.getAnnotation(instanceTemplateAnnotation)
+	return ok && ref != ""
+}
+
+// instanceFromTemplate returns the request of an instance created from an instance template: only
+// the name, labels and metadata rendered from the machine override the properties of the template.
+// The labels carry the cluster ownership of the instance and the metadata its user data.
+func instanceFromTemplate(instance *compute.Instance) *compute.Instance {
+	return &compute.Instance{
+		Name:     instance.Name,
+		Labels:   instance.Labels,
+		Metadata: instance.Metadata,
+	}
+}
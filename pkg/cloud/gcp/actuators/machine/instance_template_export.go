@@ -0,0 +1,218 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/credentials"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/klog/v2"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const imageLinkFmt = "projects/%s/global/images/%s"
+
+// InstanceTemplateOptions are the properties of an exported instance template that do not come from
+// the providerSpec.
+type InstanceTemplateOptions struct {
+	// Name of the instance template.
+	Name string
+	// ProjectID is the project of the instance template. Images, networks and encryption keys the
+	// providerSpec names without a project are in it. Defaults to the project of the providerSpec.
+	ProjectID string
+	// DefaultServiceAccount is attached to the instances when the providerSpec does not set a
+	// service account, like the --default-service-account of the controller. Optional.
+	DefaultServiceAccount string
+}
+
+// ExportInstanceTemplate renders a providerSpec into an instance template with the same node shape
+// as the instances of its machines, so that e.g. managed instance groups can reuse it. The options
+// set by machine annotations, the labels and resource manager tags of the cluster, which would make
+// the instances owned by the cluster, the zone of the providerSpec and the user data, a secret, are
+// not part of templates.
+func ExportInstanceTemplate(providerSpec *machinev1.GCPMachineProviderSpec, options InstanceTemplateOptions) (*compute.InstanceTemplate, error) {
+	if options.Name == "" {
+		return nil, fmt.Errorf("instance template name must be set")
+	}
+	if options.ProjectID == "" {
+		options.ProjectID = providerSpec.ProjectID
+	}
+	if options.ProjectID == "" {
+		return nil, fmt.Errorf("instance template project must be set when the providerSpec has none")
+	}
+	if providerSpec.MachineType == "" {
+		return nil, machinecontroller.InvalidMachineConfiguration("machine type must be set")
+	}
+	r := newReconciler(&machineScope{
+		projectID:    options.ProjectID,
+		providerSpec: providerSpec,
+		options:      Options{DefaultServiceAccount: options.DefaultServiceAccount},
+	})
+
+	properties := &compute.InstanceProperties{
+		CanIpForward: providerSpec.CanIPForward,
+		Labels:       providerSpec.Labels,
+		MachineType:  providerSpec.MachineType,
+		Scheduling: &compute.Scheduling{
+			Preemptible:       providerSpec.Preemptible,
+			OnHostMaintenance: string(providerSpec.OnHostMaintenance),
+		},
+		ShieldedInstanceConfig: &compute.ShieldedInstanceConfig{
+			EnableSecureBoot:          providerSpec.ShieldedInstanceConfig.SecureBoot == machinev1.SecureBootPolicyEnabled,
+			EnableVtpm:                providerSpec.ShieldedInstanceConfig.VirtualizedTrustedPlatformModule != machinev1.VirtualizedTrustedPlatformModulePolicyDisabled,
+			EnableIntegrityMonitoring: providerSpec.ShieldedInstanceConfig.IntegrityMonitoring != machinev1.IntegrityMonitoringPolicyDisabled,
+			ForceSendFields:           []string{"EnableSecureBoot", "EnableVtpm", "EnableIntegrityMonitoring"},
+		},
+	}
+	if len(providerSpec.Tags) > 0 {
+		properties.Tags = &compute.Tags{Items: providerSpec.Tags}
+	}
+
+	automaticRestart, err := restartPolicyToBool(providerSpec.RestartPolicy, providerSpec.Preemptible)
+	if err != nil {
+		return nil, machinecontroller.InvalidMachineConfiguration("failed to determine restart policy: %v", err)
+	}
+	properties.Scheduling.AutomaticRestart = automaticRestart
+
+	if providerSpec.ConfidentialCompute == machinev1.ConfidentialComputePolicyEnabled {
+		properties.ConfidentialInstanceConfig = &compute.ConfidentialInstanceConfig{EnableConfidentialCompute: true}
+	}
+
+	switch len(providerSpec.GPUs) {
+	case 0:
+	case 1:
+		// Instance templates name accelerator types without their zone.
+		properties.GuestAccelerators = []*compute.AcceleratorConfig{{
+			AcceleratorType:  providerSpec.GPUs[0].Type,
+			AcceleratorCount: int64(providerSpec.GPUs[0].Count),
+		}}
+	default:
+		return nil, machinecontroller.InvalidMachineConfiguration("More than one type of accelerator provided. Instances support only one accelerator type at a time.")
+	}
+
+	for _, disk := range providerSpec.Disks {
+		sourceImage := disk.Image
+		if sourceImage != "" && !strings.Contains(sourceImage, "/") {
+			sourceImage = fmt.Sprintf(imageLinkFmt, options.ProjectID, sourceImage)
+		}
+		properties.Disks = append(properties.Disks, &compute.AttachedDisk{
+			AutoDelete: disk.AutoDelete,
+			Boot:       disk.Boot,
+			InitializeParams: &compute.AttachedDiskInitializeParams{
+				DiskSizeGb:  disk.SizeGB,
+				DiskType:    disk.Type,
+				SourceImage: sourceImage,
+				Labels:      disk.Labels,
+			},
+			DiskEncryptionKey: generateDiskEncryptionKey(disk.EncryptionKey, options.ProjectID),
+		})
+	}
+
+	for _, nic := range providerSpec.NetworkInterfaces {
+		computeNIC := &compute.NetworkInterface{}
+		if nic.Network != "" {
+			computeNIC.Network = r.networkURL(nic)
+		}
+		if nic.Subnetwork != "" {
+			if providerSpec.Region == "" {
+				return nil, machinecontroller.InvalidMachineConfiguration("region must be set to export subnetwork %s", nic.Subnetwork)
+			}
+			computeNIC.Subnetwork = r.subnetworkURL(nic)
+		}
+		if nic.PublicIP {
+			computeNIC.AccessConfigs = []*compute.AccessConfig{{Name: externalAccessConfigName, Type: oneToOneNATAccessConfig}}
+		}
+		properties.NetworkInterfaces = append(properties.NetworkInterfaces, computeNIC)
+	}
+
+	if properties.ServiceAccounts, err = r.serviceAccounts(); err != nil {
+		return nil, err
+	}
+
+	if len(providerSpec.Metadata) > 0 {
+		properties.Metadata = &compute.Metadata{}
+		for _, metadata := range providerSpec.Metadata {
+			properties.Metadata.Items = append(properties.Metadata.Items, &compute.MetadataItems{Key: metadata.Key, Value: metadata.Value})
+		}
+	}
+
+	return &compute.InstanceTemplate{
+		Name:       options.Name,
+		Properties: properties,
+	}, nil
+}
+
+// ExportMachineSetInstanceTemplate writes, as JSON, the instance template rendered from the
+// providerSpec of a MachineSet, named after the MachineSet unless the options name it. When
+// computeClientBuilder is set, the template is also created in its project with the credentials of
+// the MachineSet, it is only written, as a dry run, otherwise.
+func ExportMachineSetInstanceTemplate(ctx context.Context, c controllerclient.Client, namespace, name string, options InstanceTemplateOptions,
+	computeClientBuilder computeservice.BuilderFuncType, w io.Writer) error {
+	machineSet := &machinev1.MachineSet{}
+	if err := c.Get(ctx, controllerclient.ObjectKey{Namespace: namespace, Name: name}, machineSet); err != nil {
+		return fmt.Errorf("failed to get machine set %s: %w", name, err)
+	}
+	providerSpec, err := util.ProviderSpecFromRawExtension(machineSet.Spec.Template.Spec.ProviderSpec.Value)
+	if err != nil {
+		return fmt.Errorf("machine set %s: %w", name, err)
+	}
+	if options.Name == "" {
+		options.Name = machineSet.Name
+	}
+	if options.ProjectID == "" {
+		options.ProjectID = providerSpec.ProjectID
+	}
+
+	var serviceAccountJSON string
+	if computeClientBuilder != nil || options.ProjectID == "" {
+		var impersonateServiceAccount string
+		serviceAccountJSON, impersonateServiceAccount, err = util.GetCredentials(c, namespace, *providerSpec)
+		if err != nil {
+			return fmt.Errorf("machine set %s: %w", name, err)
+		}
+		if options.ProjectID == "" {
+			if options.ProjectID, err = util.GetProjectIDFromJSONKey([]byte(serviceAccountJSON)); err != nil {
+				return fmt.Errorf("error getting project from JSON key: %w", err)
+			}
+		}
+		if serviceAccountJSON, err = (&credentials.Builder{}).Build(serviceAccountJSON, impersonateServiceAccount); err != nil {
+			return fmt.Errorf("error building credentials: %w", err)
+		}
+	}
+
+	template, err := ExportInstanceTemplate(providerSpec, options)
+	if err != nil {
+		return fmt.Errorf("machine set %s: %w", name, err)
+	}
+	data, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+		return err
+	}
+	if computeClientBuilder == nil {
+		return nil
+	}
+
+	computeService, err := computeClientBuilder(serviceAccountJSON)
+	if err != nil {
+		return fmt.Errorf("error creating compute service: %w", err)
+	}
+	operation, err := computeService.InstanceTemplatesInsert(options.ProjectID, template)
+	if err != nil {
+		return fmt.Errorf("failed to create instance template %s in project %s: %w", template.Name, options.ProjectID, err)
+	}
+	if errs := operationErrors(operation); len(errs) > 0 {
+		return fmt.Errorf("failed to create instance template %s in project %s: %s", template.Name, options.ProjectID, strings.Join(errs, "; "))
+	}
+	klog.Infof("Creating instance template %s in project %s", template.Name, options.ProjectID)
+	return nil
+}
@@ -0,0 +1,329 @@
+package machine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestExportInstanceTemplate(t *testing.T) {
+	startupScript := "startup"
+	newProviderSpec := func() *machinev1.GCPMachineProviderSpec {
+		return &machinev1.GCPMachineProviderSpec{
+			ProjectID:    "cluster-project",
+			Region:       "us-east1",
+			Zone:         "us-east1-b",
+			MachineType:  "n2-standard-4",
+			CanIPForward: true,
+			Labels:       map[string]string{"team": "platform"},
+			Tags:         []string{"worker"},
+			Disks: []*machinev1.GCPDisk{{
+				AutoDelete: true,
+				Boot:       true,
+				SizeGB:     128,
+				Type:       "pd-ssd",
+				Image:      "rhcos",
+				EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+					KMSKey: &machinev1.GCPKMSKeyReference{Name: "key", KeyRing: "ring", Location: "global"},
+				},
+			}},
+			NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+				{ProjectID: "host-project", Network: "network", Subnetwork: "worker-subnet"},
+				{Network: "storage", PublicIP: true},
+			},
+			ServiceAccounts: []machinev1.GCPServiceAccount{{Email: "nodes@cluster-project.iam.gserviceaccount.com", Scopes: []string{"storage-ro"}}},
+			Metadata:        []*machinev1.GCPMetadata{{Key: "startup-script", Value: &startupScript}},
+		}
+	}
+
+	cases := []struct {
+		name          string
+		mutate        func(*machinev1.GCPMachineProviderSpec)
+		options       InstanceTemplateOptions
+		expectedError string
+		check         func(t *testing.T, properties *compute.InstanceProperties)
+	}{
+		{
+			name:    "Node shape",
+			options: InstanceTemplateOptions{Name: "workers"},
+			check: func(t *testing.T, properties *compute.InstanceProperties) {
+				if properties.MachineType != "n2-standard-4" || !properties.CanIpForward {
+					t.Errorf("Expected machine type n2-standard-4 with IP forwarding, got %s, %v", properties.MachineType, properties.CanIpForward)
+				}
+				if properties.Labels["team"] != "platform" || len(properties.Labels) != 1 {
+					t.Errorf("Expected the labels of the providerSpec only, got %v", properties.Labels)
+				}
+				if properties.Tags == nil || strings.Join(properties.Tags.Items, ",") != "worker" {
+					t.Errorf("Expected tags [worker], got %+v", properties.Tags)
+				}
+				if properties.Metadata == nil || len(properties.Metadata.Items) != 1 || properties.Metadata.Items[0].Key != "startup-script" {
+					t.Errorf("Expected the metadata of the providerSpec, got %+v", properties.Metadata)
+				}
+			},
+		},
+		{
+			name:    "Disks",
+			options: InstanceTemplateOptions{Name: "workers"},
+			check: func(t *testing.T, properties *compute.InstanceProperties) {
+				if len(properties.Disks) != 1 {
+					t.Fatalf("Expected 1 disk, got %d", len(properties.Disks))
+				}
+				disk := properties.Disks[0]
+				if !disk.Boot || !disk.AutoDelete || disk.InitializeParams.DiskSizeGb != 128 || disk.InitializeParams.DiskType != "pd-ssd" {
+					t.Errorf("Unexpected disk %+v, %+v", disk, disk.InitializeParams)
+				}
+				if disk.InitializeParams.SourceImage != "projects/cluster-project/global/images/rhcos" {
+					t.Errorf("Expected the image in the project of the template, got %s", disk.InitializeParams.SourceImage)
+				}
+				if disk.DiskEncryptionKey == nil || disk.DiskEncryptionKey.KmsKeyName != "projects/cluster-project/locations/global/keyRings/ring/cryptoKeys/key" {
+					t.Errorf("Unexpected encryption key %+v", disk.DiskEncryptionKey)
+				}
+			},
+		},
+		{
+			name: "Image of another project",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.Disks[0].Image = "projects/rhcos-cloud/global/images/rhcos-414"
+			},
+			options: InstanceTemplateOptions{Name: "workers"},
+			check: func(t *testing.T, properties *compute.InstanceProperties) {
+				if image := properties.Disks[0].InitializeParams.SourceImage; image != "projects/rhcos-cloud/global/images/rhcos-414" {
+					t.Errorf("Expected the image to be kept, got %s", image)
+				}
+			},
+		},
+		{
+			name:    "Network interfaces",
+			options: InstanceTemplateOptions{Name: "workers"},
+			check: func(t *testing.T, properties *compute.InstanceProperties) {
+				if len(properties.NetworkInterfaces) != 2 {
+					t.Fatalf("Expected 2 network interfaces, got %d", len(properties.NetworkInterfaces))
+				}
+				shared, storage := properties.NetworkInterfaces[0], properties.NetworkInterfaces[1]
+				if shared.Network != "projects/host-project/global/networks/network" ||
+					shared.Subnetwork != "projects/host-project/regions/us-east1/subnetworks/worker-subnet" || len(shared.AccessConfigs) != 0 {
+					t.Errorf("Unexpected shared VPC network interface %+v", shared)
+				}
+				if storage.Network != "projects/cluster-project/global/networks/storage" || storage.Subnetwork != "" ||
+					len(storage.AccessConfigs) != 1 || storage.AccessConfigs[0].Type != oneToOneNATAccessConfig {
+					t.Errorf("Unexpected public network interface %+v", storage)
+				}
+			},
+		},
+		{
+			name:    "Service account scope aliases",
+			options: InstanceTemplateOptions{Name: "workers", DefaultServiceAccount: "default@cluster-project.iam.gserviceaccount.com"},
+			check: func(t *testing.T, properties *compute.InstanceProperties) {
+				if len(properties.ServiceAccounts) != 1 || properties.ServiceAccounts[0].Email != "nodes@cluster-project.iam.gserviceaccount.com" ||
+					strings.Join(properties.ServiceAccounts[0].Scopes, ",") != "https://www.googleapis.com/auth/devstorage.read_only" {
+					t.Errorf("Unexpected service accounts %+v", properties.ServiceAccounts)
+				}
+			},
+		},
+		{
+			name:    "Default service account",
+			mutate:  func(spec *machinev1.GCPMachineProviderSpec) { spec.ServiceAccounts = nil },
+			options: InstanceTemplateOptions{Name: "workers", DefaultServiceAccount: "default@cluster-project.iam.gserviceaccount.com"},
+			check: func(t *testing.T, properties *compute.InstanceProperties) {
+				if len(properties.ServiceAccounts) != 1 || properties.ServiceAccounts[0].Email != "default@cluster-project.iam.gserviceaccount.com" ||
+					strings.Join(properties.ServiceAccounts[0].Scopes, ",") != cloudPlatformScope {
+					t.Errorf("Unexpected service accounts %+v", properties.ServiceAccounts)
+				}
+			},
+		},
+		{
+			name: "Scheduling, shielded and confidential instances, accelerators",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.OnHostMaintenance = machinev1.TerminateHostMaintenanceType
+				spec.RestartPolicy = machinev1.RestartPolicyNever
+				spec.ShieldedInstanceConfig = machinev1.GCPShieldedInstanceConfig{
+					SecureBoot:                       machinev1.SecureBootPolicyEnabled,
+					VirtualizedTrustedPlatformModule: machinev1.VirtualizedTrustedPlatformModulePolicyDisabled,
+				}
+				spec.ConfidentialCompute = machinev1.ConfidentialComputePolicyEnabled
+				spec.GPUs = []machinev1.GCPGPUConfig{{Type: "nvidia-tesla-t4", Count: 2}}
+			},
+			options: InstanceTemplateOptions{Name: "workers"},
+			check: func(t *testing.T, properties *compute.InstanceProperties) {
+				scheduling := properties.Scheduling
+				if scheduling.OnHostMaintenance != "Terminate" || scheduling.AutomaticRestart == nil || *scheduling.AutomaticRestart || scheduling.Preemptible {
+					t.Errorf("Unexpected scheduling %+v", scheduling)
+				}
+				shielded := properties.ShieldedInstanceConfig
+				if !shielded.EnableSecureBoot || shielded.EnableVtpm || !shielded.EnableIntegrityMonitoring {
+					t.Errorf("Unexpected shielded instance config %+v", shielded)
+				}
+				if properties.ConfidentialInstanceConfig == nil || !properties.ConfidentialInstanceConfig.EnableConfidentialCompute {
+					t.Errorf("Expected a confidential instance, got %+v", properties.ConfidentialInstanceConfig)
+				}
+				if len(properties.GuestAccelerators) != 1 || properties.GuestAccelerators[0].AcceleratorType != "nvidia-tesla-t4" || properties.GuestAccelerators[0].AcceleratorCount != 2 {
+					t.Errorf("Unexpected accelerators %+v", properties.GuestAccelerators)
+				}
+			},
+		},
+		{
+			name:    "Project of the template",
+			options: InstanceTemplateOptions{Name: "workers", ProjectID: "mig-project"},
+			check: func(t *testing.T, properties *compute.InstanceProperties) {
+				if image := properties.Disks[0].InitializeParams.SourceImage; image != "projects/mig-project/global/images/rhcos" {
+					t.Errorf("Expected the image in the project of the template, got %s", image)
+				}
+				if network := properties.NetworkInterfaces[1].Network; network != "projects/mig-project/global/networks/storage" {
+					t.Errorf("Expected the network in the project of the template, got %s", network)
+				}
+			},
+		},
+		{
+			name:          "Missing name",
+			expectedError: "instance template name must be set",
+		},
+		{
+			name:          "Missing project",
+			mutate:        func(spec *machinev1.GCPMachineProviderSpec) { spec.ProjectID = "" },
+			options:       InstanceTemplateOptions{Name: "workers"},
+			expectedError: "instance template project must be set when the providerSpec has none",
+		},
+		{
+			name: "Preemptible instances cannot restart",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.Preemptible, spec.RestartPolicy = true, machinev1.RestartPolicyAlways
+			},
+			options:       InstanceTemplateOptions{Name: "workers"},
+			expectedError: "failed to determine restart policy: preemptible instances cannot be automatically restarted",
+		},
+		{
+			name:          "Invalid scope",
+			mutate:        func(spec *machinev1.GCPMachineProviderSpec) { spec.ServiceAccounts[0].Scopes = []string{"everything"} },
+			options:       InstanceTemplateOptions{Name: "workers"},
+			expectedError: `invalid scope "everything" of service account nodes@cluster-project.iam.gserviceaccount.com, must be a URL or a gcloud scope alias`,
+		},
+		{
+			name:          "Subnetwork without region",
+			mutate:        func(spec *machinev1.GCPMachineProviderSpec) { spec.Region = "" },
+			options:       InstanceTemplateOptions{Name: "workers"},
+			expectedError: "region must be set to export subnetwork worker-subnet",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			providerSpec := newProviderSpec()
+			if tc.mutate != nil {
+				tc.mutate(providerSpec)
+			}
+			template, err := ExportInstanceTemplate(providerSpec, tc.options)
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Fatalf("Expected error %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if template.Name != tc.options.Name {
+				t.Errorf("Expected name %s, got %s", tc.options.Name, template.Name)
+			}
+			tc.check(t, template.Properties)
+		})
+	}
+}
+
+func TestExportMachineSetInstanceTemplate(t *testing.T) {
+	providerSpec, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+		Region:            "us-east1",
+		Zone:              "us-east1-b",
+		MachineType:       "n2-standard-4",
+		CredentialsSecret: &corev1.LocalObjectReference{Name: credentialsSecretName},
+		NetworkInterfaces: []*machinev1.GCPNetworkInterface{{Network: "network", Subnetwork: "worker-subnet"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	machineSet := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-worker-b", Namespace: "openshift-machine-api"},
+		Spec: machinev1.MachineSetSpec{Template: machinev1.MachineTemplateSpec{
+			Spec: machinev1.MachineSpec{ProviderSpec: machinev1.ProviderSpec{Value: providerSpec}},
+		}},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: "openshift-machine-api"},
+		Data:       map[string][]byte{credentialsSecretKey: []byte(`{"project_id": "cluster-project"}`)},
+	}
+
+	cases := []struct {
+		name            string
+		options         InstanceTemplateOptions
+		create          bool
+		expectedName    string
+		expectedProject string
+	}{
+		{
+			name:            "Dry run",
+			expectedName:    "cluster-worker-b",
+			expectedProject: "cluster-project",
+		},
+		{
+			name:            "Create",
+			options:         InstanceTemplateOptions{Name: "workers", ProjectID: "mig-project"},
+			create:          true,
+			expectedName:    "workers",
+			expectedProject: "mig-project",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machineSet.DeepCopy(), secret.DeepCopy()).Build()
+			var created *compute.InstanceTemplate
+			var computeClientBuilder computeservice.BuilderFuncType
+			if tc.create {
+				computeClientBuilder = func(serviceAccountJSON string) (computeservice.GCPComputeService, error) {
+					if serviceAccountJSON != string(secret.Data[credentialsSecretKey]) {
+						t.Errorf("Expected the credentials of the machine set, got %s", serviceAccountJSON)
+					}
+					_, mockComputeService := computeservice.NewComputeServiceMock()
+					mockComputeService.MockTemplatesInsert = func(project string, instanceTemplate *compute.InstanceTemplate) (*compute.Operation, error) {
+						if project != tc.expectedProject {
+							t.Errorf("Expected project %s, got %s", tc.expectedProject, project)
+						}
+						created = instanceTemplate
+						return &compute.Operation{Status: "PENDING"}, nil
+					}
+					return mockComputeService, nil
+				}
+			}
+
+			var out bytes.Buffer
+			if err := ExportMachineSetInstanceTemplate(context.Background(), c, "openshift-machine-api", "cluster-worker-b", tc.options, computeClientBuilder, &out); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			printed := &compute.InstanceTemplate{}
+			if err := json.Unmarshal(out.Bytes(), printed); err != nil {
+				t.Fatalf("Expected the instance template as JSON, got %s: %v", out.String(), err)
+			}
+			if printed.Name != tc.expectedName {
+				t.Errorf("Expected name %s, got %s", tc.expectedName, printed.Name)
+			}
+			expectedSubnetwork := "projects/" + tc.expectedProject + "/regions/us-east1/subnetworks/worker-subnet"
+			if subnetwork := printed.Properties.NetworkInterfaces[0].Subnetwork; subnetwork != expectedSubnetwork {
+				t.Errorf("Expected subnetwork %s, got %s", expectedSubnetwork, subnetwork)
+			}
+			if tc.create != (created != nil) {
+				t.Errorf("Expected the instance template to be created %v, got %+v", tc.create, created)
+			}
+		})
+	}
+}
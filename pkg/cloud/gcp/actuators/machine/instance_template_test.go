@@ -0,0 +1,151 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSourceInstanceTemplate(t *testing.T) {
+	cases := []struct {
+		name          string
+		annotation    string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name: "No instance template",
+		},
+		{
+			name:       "Name",
+			annotation: "workers",
+			expected:   "projects/testProject/global/instanceTemplates/workers",
+		},
+		{
+			name:       "Global instance template of another project",
+			annotation: "projects/infra/global/instanceTemplates/workers",
+			expected:   "projects/infra/global/instanceTemplates/workers",
+		},
+		{
+			name:       "Regional instance template self link",
+			annotation: "https://www.googleapis.com/compute/v1/projects/infra/regions/us-east1/instanceTemplates/workers",
+			expected:   "https://www.googleapis.com/compute/v1/projects/infra/regions/us-east1/instanceTemplates/workers",
+		},
+		{
+			name:          "Not an instance template",
+			annotation:    "projects/infra/global/images/rhcos",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-a"}}
+			if tc.annotation != "" {
+				machine.Annotations = map[string]string{instanceTemplateAnnotation: tc.annotation}
+			}
+			r := newReconciler(&machineScope{Context: context.Background(), machine: machine, projectID: "testProject"})
+
+			template, err := r.sourceInstanceTemplate()
+			if tc.expectedError {
+				if !isInvalidMachineConfigurationError(err) {
+					t.Errorf("Expected an invalid machine configuration error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if template != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, template)
+			}
+		})
+	}
+}
+
+func TestCreateFromInstanceTemplate(t *testing.T) {
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status: configv1.InfrastructureStatus{
+			InfrastructureName: "test-748kjf",
+			PlatformStatus:     &configv1.PlatformStatus{Type: configv1.GCPPlatformType, GCP: &configv1.GCPPlatformStatus{}},
+		},
+	}
+
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	var template string
+	var inserted *compute.Instance
+	mockComputeService.MockInsertFromTemplate = func(_ string, zone string, sourceInstanceTemplate string, instance *compute.Instance) (*compute.Operation, error) {
+		if zone != "us-east1-b" {
+			t.Errorf("Expected the zone of the machine, got %s", zone)
+		}
+		template, inserted = sourceInstanceTemplate, instance
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	mockComputeService.MockInstancesInsert = func(_ string, _ string, _ *compute.Instance) (*compute.Operation, error) {
+		t.Errorf("Expected the instance to be created from the instance template")
+		return nil, nil
+	}
+	mockComputeService.MockMachineTypesGet = func(_ string, _ string, machineType string) (*compute.MachineType, error) {
+		t.Errorf("Expected the machine type of the providerSpec not to be checked, got %s", machineType)
+		return nil, nil
+	}
+
+	r := newReconciler(&machineScope{
+		Context: context.Background(),
+		machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+			Name:        "worker-a",
+			Namespace:   "openshift-machine-api",
+			Labels:      map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+			Annotations: map[string]string{instanceTemplateAnnotation: "workers"},
+		}},
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Zone:     "us-east1-b",
+			Region:   "us-east1",
+			Tags:     []string{"worker"},
+			Metadata: []*machinev1.GCPMetadata{{Key: "role", Value: &[]string{"worker"}[0]}},
+		},
+		coreClient:     controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(infra).Build(),
+		projectID:      "testProject",
+		instanceName:   "worker-a",
+		featureGates:   featuregates.NewFeatureGate(nil, []configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}),
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		computeService: mockComputeService,
+		eventRecorder:  record.NewFakeRecorder(10),
+		options:        Options{MissingNetworkPolicy: MissingNetworkPolicyReject},
+	})
+
+	if err := r.create(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if template != "projects/testProject/global/instanceTemplates/workers" {
+		t.Errorf("Expected the instance template of the annotation, got %q", template)
+	}
+	if inserted == nil {
+		t.Fatal("Expected the instance to be inserted")
+	}
+	if inserted.Name != "worker-a" || inserted.Labels["kubernetes-io-cluster-CLUSTERID"] != "owned" || inserted.Metadata == nil {
+		t.Errorf("Expected the name, labels and metadata of the machine, got %+v", inserted)
+	}
+	if inserted.MachineType != "" || inserted.Tags != nil || inserted.Disks != nil || inserted.NetworkInterfaces != nil || inserted.Scheduling != nil {
+		t.Errorf("Expected the other properties to come from the instance template, got %+v", inserted)
+	}
+	var role string
+	for _, item := range inserted.Metadata.Items {
+		if item.Key == "role" {
+			role = *item.Value
+		}
+	}
+	if role != "worker" {
+		t.Errorf("Expected the metadata of the providerSpec, got %+v", inserted.Metadata.Items)
+	}
+}
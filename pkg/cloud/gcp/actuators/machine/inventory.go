@@ -0,0 +1,287 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// inventoryConfigMapName is the ConfigMap, in the namespace of the machines, the inventory of
+	// the GCP resources managed by the provider is written to.
+	inventoryConfigMapName = "gcp-provider-inventory"
+	// inventoryKey holds the inventory as JSON.
+	inventoryKey = "inventory.json"
+	// inventoryUpdatedKey holds when the inventory last changed, in RFC 3339.
+	inventoryUpdatedKey = "updated"
+
+	inventoryHealthy   = "Healthy"
+	inventoryUnhealthy = "Unhealthy"
+	// inventoryOrphaned is the health of instances labelled with the cluster ID no machine refers to.
+	inventoryOrphaned = "Orphaned"
+	// inventoryDetached is the health of disks labelled with the cluster ID not attached to an instance.
+	inventoryDetached = "Detached"
+	// inventoryMissing is the health of control plane instance groups that do not exist.
+	inventoryMissing = "Missing"
+)
+
+// inventory is the GCP footprint of the machines of a namespace.
+type inventory struct {
+	Instances      []inventoryInstance      `json:"instances"`
+	InstanceGroups []inventoryInstanceGroup `json:"instanceGroups"`
+	Disks          []inventoryDisk          `json:"disks"`
+	// Errors lists the projects whose resources could not be listed, the inventory is incomplete
+	// when it is not empty.
+	Errors []string `json:"errors,omitempty"`
+}
+
+type inventoryInstance struct {
+	Name    string `json:"name"`
+	Project string `json:"project"`
+	Zone    string `json:"zone"`
+	Machine string `json:"machine,omitempty"`
+	Status  string `json:"status"`
+	Health  string `json:"health"`
+}
+
+type inventoryInstanceGroup struct {
+	Name    string `json:"name"`
+	Project string `json:"project"`
+	Zone    string `json:"zone"`
+	Size    int64  `json:"size"`
+	// Machines is the number of control plane machines of the zone expected in the group.
+	Machines int    `json:"machines"`
+	Health   string `json:"health"`
+}
+
+type inventoryDisk struct {
+	Name     string `json:"name"`
+	Project  string `json:"project"`
+	Zone     string `json:"zone"`
+	Instance string `json:"instance,omitempty"`
+	Status   string `json:"status"`
+	Health   string `json:"health"`
+}
+
+// inventoryReport periodically writes the instances, control plane instance groups and disks
+// managed by the provider, with their health, to the inventoryConfigMapName ConfigMap of every
+// namespace with machines, so that admins can audit the cloud footprint of the provider without
+// access to the GCP console. Resources are found by the ownership label of the cluster.
+type inventoryReport struct {
+	actuator *Actuator
+	interval time.Duration
+}
+
+// Inventory returns the runnable that writes the inventory of the GCP resources of the machines
+// every interval, nil if the interval is not positive. It only runs on the leader.
+func (a *Actuator) Inventory(interval time.Duration) manager.Runnable {
+	if interval <= 0 {
+		return nil
+	}
+	return &inventoryReport{actuator: a, interval: interval}
+}
+
+// Start writes the inventory every interval until the context is done.
+func (i *inventoryReport) Start(ctx context.Context) error {
+	klog.Infof("Writing the inventory of the GCP resources of all machines every %s", i.interval)
+	wait.UntilWithContext(ctx, i.report, i.interval)
+	return nil
+}
+
+// report writes the inventory of every namespace with machines.
+func (i *inventoryReport) report(ctx context.Context) {
+	machines := &machinev1.MachineList{}
+	if err := i.actuator.coreClient.List(ctx, machines); err != nil {
+		klog.Errorf("Failed to list machines for the inventory: %v", err)
+		return
+	}
+	byNamespace := map[string][]machinev1.Machine{}
+	for _, machine := range machines.Items {
+		byNamespace[machine.Namespace] = append(byNamespace[machine.Namespace], machine)
+	}
+	sync := &instanceSync{actuator: i.actuator}
+	for namespace, machines := range byNamespace {
+		config, err := readLoadBalancerConfig(ctx, i.actuator.coreClient, namespace)
+		if err != nil {
+			klog.Errorf("Failed to read the load balancer configuration for the inventory of namespace %s: %v", namespace, err)
+			continue
+		}
+		// Every machine keeps its instance from being reported as orphaned, like in the instance sync.
+		groups, machineInstances := sync.groupMachines(ctx, machines)
+		inv := &inventory{}
+		for _, group := range groups {
+			if err := i.collect(inv, group, config, machineInstances); err != nil {
+				klog.Errorf("Failed to list the GCP resources of cluster %q in project %q: %v", group.clusterID, group.projectID, err)
+				inv.Errors = append(inv.Errors, fmt.Sprintf("project %s: %v", group.projectID, err))
+			}
+		}
+		inv.sort()
+		if err := i.write(ctx, namespace, inv); err != nil {
+			klog.Errorf("Failed to write the inventory of namespace %s: %v", namespace, err)
+		}
+	}
+}
+
+// collect adds the resources of a group of machines to the inventory.
+func (i *inventoryReport) collect(inv *inventory, group *instanceSyncGroup, config *loadBalancerConfig, machineInstances machineInstances) error {
+	computeService, err := i.actuator.computeClientBuilder(group.serviceAccountJSON)
+	if err != nil {
+		return fmt.Errorf("error creating compute service: %w", err)
+	}
+	filter := fmt.Sprintf("labels.%s = %q", util.ClusterOwnedLabelKey(group.clusterID), util.ClusterOwnedLabelValue)
+	instances, err := computeService.InstancesAggregatedList(group.projectID, filter)
+	if err != nil {
+		return err
+	}
+
+	zones := map[string]bool{}
+	controlPlaneMachines := map[string]int{}
+	for _, machine := range group.machines {
+		providerSpec, err := util.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+		if err != nil {
+			continue
+		}
+		zone := instanceZone(machine, providerSpec)
+		zones[zone] = true
+		if machine.Labels[openshiftMachineRoleLabel] == masterMachineRole {
+			controlPlaneMachines[zone]++
+		}
+	}
+
+	for _, instance := range instances {
+		zone := path.Base(instance.Zone)
+		zones[zone] = true
+		entry := inventoryInstance{Name: instance.Name, Project: group.projectID, Zone: zone, Status: instance.Status, Health: inventoryOrphaned}
+		if machine, ok := machineInstances.machineOf(listedInstanceKey(group.projectID, instance)); ok {
+			entry.Machine = machine
+			entry.Health = inventoryUnhealthy
+			if instance.Status == "RUNNING" {
+				entry.Health = inventoryHealthy
+			}
+		}
+		inv.Instances = append(inv.Instances, entry)
+	}
+
+	for zone := range zones {
+		if zone == "" {
+			continue
+		}
+		disks, err := computeService.DisksList(group.projectID, zone, filter)
+		if err != nil {
+			return fmt.Errorf("failed to list disks of zone %s: %w", zone, err)
+		}
+		for _, disk := range disks {
+			entry := inventoryDisk{Name: disk.Name, Project: group.projectID, Zone: zone, Status: disk.Status, Health: inventoryUnhealthy}
+			switch {
+			case len(disk.Users) == 0:
+				entry.Health = inventoryDetached
+			case disk.Status == "READY":
+				entry.Health = inventoryHealthy
+			}
+			if len(disk.Users) > 0 {
+				entry.Instance = path.Base(disk.Users[0])
+			}
+			inv.Disks = append(inv.Disks, entry)
+		}
+	}
+
+	for zone, machines := range controlPlaneMachines {
+		entry, err := inventoryGroup(computeService, group.projectID, config.instanceGroupName(group.clusterID, zone), zone)
+		if err != nil {
+			return err
+		}
+		entry.Machines = machines
+		inv.InstanceGroups = append(inv.InstanceGroups, entry)
+	}
+	return nil
+}
+
+// inventoryGroup returns the inventory entry of a control plane instance group.
+func inventoryGroup(computeService computeservice.GCPComputeService, projectID, name, zone string) (inventoryInstanceGroup, error) {
+	entry := inventoryInstanceGroup{Name: name, Project: projectID, Zone: zone, Health: inventoryHealthy}
+	instanceGroup, err := computeService.InstanceGroupGet(projectID, zone, name)
+	if isNotFoundError(err) {
+		entry.Health = inventoryMissing
+		return entry, nil
+	}
+	if err != nil {
+		return entry, fmt.Errorf("failed to get instance group %s: %w", name, err)
+	}
+	entry.Size = instanceGroup.Size
+	return entry, nil
+}
+
+func (inv *inventory) sort() {
+	sort.Slice(inv.Instances, func(a, b int) bool {
+		return inventoryLess(inv.Instances[a].Project, inv.Instances[a].Zone, inv.Instances[a].Name, inv.Instances[b].Project, inv.Instances[b].Zone, inv.Instances[b].Name)
+	})
+	sort.Slice(inv.InstanceGroups, func(a, b int) bool {
+		return inventoryLess(inv.InstanceGroups[a].Project, inv.InstanceGroups[a].Zone, inv.InstanceGroups[a].Name, inv.InstanceGroups[b].Project, inv.InstanceGroups[b].Zone, inv.InstanceGroups[b].Name)
+	})
+	sort.Slice(inv.Disks, func(a, b int) bool {
+		return inventoryLess(inv.Disks[a].Project, inv.Disks[a].Zone, inv.Disks[a].Name, inv.Disks[b].Project, inv.Disks[b].Zone, inv.Disks[b].Name)
+	})
+}
+
+// inventoryLess orders resources by project, zone and name.
+func inventoryLess(projectA, zoneA, nameA, projectB, zoneB, nameB string) bool {
+	if projectA != projectB {
+		return projectA < projectB
+	}
+	if zoneA != zoneB {
+		return zoneA < zoneB
+	}
+	return nameA < nameB
+}
+
+// write creates or updates the inventory ConfigMap of the namespace, when the inventory changed.
+func (i *inventoryReport) write(ctx context.Context, namespace string, inv *inventory) error {
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return err
+	}
+	updated := i.now().UTC().Format(time.RFC3339)
+
+	configMap := &corev1.ConfigMap{}
+	err = i.actuator.coreClient.Get(ctx, controllerclient.ObjectKey{Namespace: namespace, Name: inventoryConfigMapName}, configMap)
+	if apimachineryerrors.IsNotFound(err) {
+		return i.actuator.coreClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: inventoryConfigMapName},
+			Data:       map[string]string{inventoryKey: string(data), inventoryUpdatedKey: updated},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if configMap.Data[inventoryKey] == string(data) {
+		return nil
+	}
+	patchBase := controllerclient.MergeFrom(configMap.DeepCopy())
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[inventoryKey] = string(data)
+	configMap.Data[inventoryUpdatedKey] = updated
+	return i.actuator.coreClient.Patch(ctx, configMap, patchBase)
+}
+
+func (i *inventoryReport) now() time.Time {
+	if i.actuator.clock == nil {
+		return time.Now()
+	}
+	return i.actuator.clock.Now()
+}
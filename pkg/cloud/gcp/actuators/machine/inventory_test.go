@@ -0,0 +1,120 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInventory(t *testing.T) {
+	credentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: defaultNamespaceName},
+		Data:       map[string][]byte{credentialsSecretKey: []byte(`{"project_id": "test"}`)},
+	}
+	newMachine := func(name, zone, role string) *machinev1.Machine {
+		machine := existenceCacheMachine(t, name, "RUNNING")
+		machine.Name = name
+		machine.Labels[openshiftMachineRoleLabel] = role
+		providerSpec, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+			Zone:              zone,
+			CredentialsSecret: &corev1.LocalObjectReference{Name: credentialsSecretName},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		machine.Spec.ProviderSpec.Value = providerSpec
+		return machine
+	}
+	loadBalancerConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: loadBalancerConfigMapName, Namespace: defaultNamespaceName},
+		Data:       map[string]string{instanceGroupNameTemplateKey: "{clusterID}-cp-{zone}"},
+	}
+	c := controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		credentialsSecret,
+		loadBalancerConfig,
+		newMachine("master-0", "zone-a", masterMachineRole),
+		newMachine("master-1", "zone-b", masterMachineRole),
+		newMachine("worker-0", "zone-a", "worker"),
+	).Build()
+
+	_, mock := computeservice.NewComputeServiceMock()
+	mock.MockInstancesList = func(project string, filter string) ([]*compute.Instance, error) {
+		return []*compute.Instance{
+			{Name: "worker-0", Zone: "https://www.googleapis.com/compute/v1/projects/test/zones/zone-a", Status: "RUNNING"},
+			{Name: "master-0", Zone: "https://www.googleapis.com/compute/v1/projects/test/zones/zone-a", Status: "TERMINATED"},
+			{Name: "leftover", Zone: "https://www.googleapis.com/compute/v1/projects/test/zones/zone-b", Status: "RUNNING"},
+		}, nil
+	}
+	mock.MockDisksList = func(project string, zone string, filter string) ([]*compute.Disk, error) {
+		if filter != `labels.kubernetes-io-cluster-CLUSTERID = "owned"` {
+			t.Errorf("Expected the disks to be filtered by the cluster label, got %q", filter)
+		}
+		if zone != "zone-a" {
+			return nil, nil
+		}
+		return []*compute.Disk{
+			{Name: "worker-0-data", Status: "READY", Users: []string{"https://www.googleapis.com/compute/v1/projects/test/zones/zone-a/instances/worker-0"}},
+			{Name: "retained", Status: "READY"},
+		}, nil
+	}
+	mock.MockInstanceGroupGet = func(project string, zone string, name string) (*compute.InstanceGroup, error) {
+		if name != "CLUSTERID-cp-zone-a" {
+			return nil, &googleapi.Error{Code: http.StatusNotFound}
+		}
+		return &compute.InstanceGroup{Name: name, Size: 1}, nil
+	}
+	actuator := NewActuator(ActuatorParams{
+		CoreClient: c,
+		ComputeClientBuilder: func(serviceAccountJSON string) (computeservice.GCPComputeService, error) {
+			return mock, nil
+		},
+		Clock: clocktesting.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+	})
+
+	actuator.Inventory(time.Minute).(*inventoryReport).report(context.Background())
+
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(context.Background(), controllerclient.ObjectKey{Namespace: defaultNamespaceName, Name: inventoryConfigMapName}, configMap); err != nil {
+		t.Fatalf("Expected the inventory to be written: %v", err)
+	}
+	if updated := configMap.Data[inventoryUpdatedKey]; updated != "2024-01-01T00:00:00Z" {
+		t.Errorf("Expected the inventory to be updated at 2024-01-01T00:00:00Z, got %q", updated)
+	}
+	inv := &inventory{}
+	if err := json.Unmarshal([]byte(configMap.Data[inventoryKey]), inv); err != nil {
+		t.Fatal(err)
+	}
+	expected := &inventory{
+		Instances: []inventoryInstance{
+			{Name: "master-0", Project: "test", Zone: "zone-a", Machine: "master-0", Status: "TERMINATED", Health: inventoryUnhealthy},
+			{Name: "worker-0", Project: "test", Zone: "zone-a", Machine: "worker-0", Status: "RUNNING", Health: inventoryHealthy},
+			{Name: "leftover", Project: "test", Zone: "zone-b", Status: "RUNNING", Health: inventoryOrphaned},
+		},
+		InstanceGroups: []inventoryInstanceGroup{
+			{Name: "CLUSTERID-cp-zone-a", Project: "test", Zone: "zone-a", Size: 1, Machines: 1, Health: inventoryHealthy},
+			{Name: "CLUSTERID-cp-zone-b", Project: "test", Zone: "zone-b", Machines: 1, Health: inventoryMissing},
+		},
+		Disks: []inventoryDisk{
+			{Name: "retained", Project: "test", Zone: "zone-a", Status: "READY", Health: inventoryDetached},
+			{Name: "worker-0-data", Project: "test", Zone: "zone-a", Instance: "worker-0", Status: "READY", Health: inventoryHealthy},
+		},
+	}
+	if !reflect.DeepEqual(inv, expected) {
+		t.Errorf("Expected inventory %+v, got %+v", expected, inv)
+	}
+}
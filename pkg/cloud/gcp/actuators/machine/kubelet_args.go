@@ -0,0 +1,153 @@
+package machine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// kubeletExtraArgsMetadataKey is the instance metadata key read at bootstrap for additional
+	// kubelet arguments.
+	kubeletExtraArgsMetadataKey = "kubelet-extra-args"
+
+	maxNodeLabels = 16
+	maxNodeTaints = 8
+)
+
+// kubeletLabelPrefixes are the prefixes in the kubernetes.io and k8s.io namespaces that the
+// kubelet may set on its own node, see the NodeRestriction admission plugin.
+var kubeletLabelPrefixes = []string{"kubelet.kubernetes.io/", "node.kubernetes.io/"}
+
+var taintEffects = sets.NewString(
+	string(corev1.TaintEffectNoSchedule),
+	string(corev1.TaintEffectPreferNoSchedule),
+	string(corev1.TaintEffectNoExecute),
+)
+
+// kubeletExtraArgs returns the kubelet arguments registering the node with the labels and taints
+// of the nodeLabelsAnnotation and nodeTaintsAnnotation, and the taints of the machine spec when the
+// propagateMachineTaintsAnnotation is set, or an empty string when none are set.
+func (r *Reconciler) kubeletExtraArgs() (string, error) {
+	labels := r.getListAnnotation(nodeLabelsAnnotation)
+	taints := r.getListAnnotation(nodeTaintsAnnotation)
+	propagateMachineTaints, err := r.getBoolAnnotation(propagateMachineTaintsAnnotation)
+	if err != nil {
+		return "", err
+	}
+	var machineTaints []string
+	if propagateMachineTaints {
+		machineTaints = r.machineTaints()
+	}
+	if len(labels) == 0 && len(taints) == 0 && len(machineTaints) == 0 {
+		return "", nil
+	}
+
+	for _, metadata := range r.providerSpec.Metadata {
+		if metadata.Key == kubeletExtraArgsMetadataKey {
+			return "", machinecontroller.InvalidMachineConfiguration("%s metadata cannot be set together with the %s, %s or %s annotations", kubeletExtraArgsMetadataKey, nodeLabelsAnnotation, nodeTaintsAnnotation, propagateMachineTaintsAnnotation)
+		}
+	}
+
+	if len(labels) > maxNodeLabels {
+		return "", machinecontroller.InvalidMachineConfiguration("at most %d node labels can be set in %s annotation, got %d", maxNodeLabels, nodeLabelsAnnotation, len(labels))
+	}
+	for _, label := range labels {
+		if err := validateNodeLabel(label); err != nil {
+			return "", machinecontroller.InvalidMachineConfiguration("invalid node label %q in %s annotation: %v", label, nodeLabelsAnnotation, err)
+		}
+	}
+
+	if len(taints) > maxNodeTaints {
+		return "", machinecontroller.InvalidMachineConfiguration("at most %d node taints can be set in %s annotation, got %d", maxNodeTaints, nodeTaintsAnnotation, len(taints))
+	}
+	for _, taint := range taints {
+		if err := validateNodeTaint(taint); err != nil {
+			return "", machinecontroller.InvalidMachineConfiguration("invalid node taint %q in %s annotation: %v", taint, nodeTaintsAnnotation, err)
+		}
+	}
+	for _, taint := range machineTaints {
+		if err := validateNodeTaint(taint); err != nil {
+			return "", machinecontroller.InvalidMachineConfiguration("invalid taint %q in machine spec: %v", taint, err)
+		}
+	}
+	// The machine taints are not limited, the machine controller applies them all anyway.
+	taints = sets.NewString(taints...).Insert(machineTaints...).List()
+
+	// Sort the values so that the metadata does not depend on the order of the annotation.
+	sort.Strings(labels)
+	sort.Strings(taints)
+	var args []string
+	if len(labels) > 0 {
+		args = append(args, "--node-labels="+strings.Join(labels, ","))
+	}
+	if len(taints) > 0 {
+		args = append(args, "--register-with-taints="+strings.Join(taints, ","))
+	}
+	return strings.Join(args, " "), nil
+}
+
+// machineTaints returns the taints of the machine spec in the key=value:Effect format of the kubelet.
+func (r *Reconciler) machineTaints() []string {
+	var taints []string
+	for _, taint := range r.machine.Spec.Taints {
+		if taint.Value == "" {
+			taints = append(taints, fmt.Sprintf("%s:%s", taint.Key, taint.Effect))
+			continue
+		}
+		taints = append(taints, fmt.Sprintf("%s=%s:%s", taint.Key, taint.Value, taint.Effect))
+	}
+	return taints
+}
+
+// validateNodeLabel checks that the key=value label is valid and may be set by the kubelet.
+func validateNodeLabel(label string) error {
+	key, value, found := strings.Cut(label, "=")
+	if !found {
+		return fmt.Errorf("must be of the form key=value")
+	}
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("invalid key: %s", strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		return fmt.Errorf("invalid value: %s", strings.Join(errs, "; "))
+	}
+
+	prefix, _, hasPrefix := strings.Cut(key, "/")
+	if !hasPrefix {
+		return nil
+	}
+	if restricted := prefix == "kubernetes.io" || prefix == "k8s.io" || strings.HasSuffix(prefix, ".kubernetes.io") || strings.HasSuffix(prefix, ".k8s.io"); !restricted {
+		return nil
+	}
+	for _, allowed := range kubeletLabelPrefixes {
+		if strings.HasPrefix(key, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the kubelet may only set labels in the kubernetes.io namespace with the prefixes %s", strings.Join(kubeletLabelPrefixes, ", "))
+}
+
+// validateNodeTaint checks that the key=value:Effect taint is valid.
+func validateNodeTaint(taint string) error {
+	keyValue, effect, found := strings.Cut(taint, ":")
+	if !found {
+		return fmt.Errorf("must be of the form key=value:Effect")
+	}
+	if !taintEffects.Has(effect) {
+		return fmt.Errorf("effect must be one of %s", strings.Join(taintEffects.List(), ", "))
+	}
+	key, value, _ := strings.Cut(keyValue, "=")
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("invalid key: %s", strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		return fmt.Errorf("invalid value: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+)
+
+const (
+	// machineNameLabelKey is the label of the GCP resources created for a machine besides its
+	// instance, e.g. data disks that are not deleted with the instance and static internal addresses,
+	// so that they can be found and deleted with the machine.
+	machineNameLabelKey = "machine-openshift-io-name"
+
+	// maxLabelValueLength is the maximum length of the value of a GCP label.
+	maxLabelValueLength = 63
+)
+
+// machineNameLabelValue returns the value of the machineNameLabelKey label of the machine. Machine
+// names may contain dots, which label values may not, so they are replaced by underscores, which
+// machine names may not contain. Names longer than a label value can hold are not labelled, false
+// is returned for them.
+func machineNameLabelValue(name string) (string, bool) {
+	if len(name) > maxLabelValueLength {
+		return "", false
+	}
+	return strings.ReplaceAll(name, ".", "_"), true
+}
+
+// withMachineNameLabel returns the labels with the machineNameLabelKey label of the machine added.
+func (r *Reconciler) withMachineNameLabel(labels map[string]string) map[string]string {
+	value, ok := machineNameLabelValue(r.machine.Name)
+	if !ok {
+		return labels
+	}
+	labelled := make(map[string]string, len(labels)+1)
+	for key, value := range labels {
+		labelled[key] = value
+	}
+	labelled[machineNameLabelKey] = value
+	return labelled
+}
+
+// deleteLeakedResources deletes the resources labelled with the name of the machine and the
+// ownership label of its cluster that the deletion of the instance leaves behind: data disks that
+// are not deleted with the instance, unless the retainDisksAnnotation is set, and reserved
+// addresses. It runs once the instance is gone, so that the disks are detached. Resources still
+// used, e.g. a disk attached to another instance, are kept. Resource policies cannot carry labels
+// and the reconciler does not create any per machine, so they are not looked for.
+func (r *Reconciler) deleteLeakedResources() error {
+	value, ok := machineNameLabelValue(r.machine.Name)
+	clusterID := r.machine.Labels[machinev1.MachineClusterIDLabel]
+	if !ok || clusterID == "" {
+		return nil
+	}
+	filter := fmt.Sprintf("(labels.%s = %q) AND (labels.%s = %q)", util.ClusterOwnedLabelKey(clusterID), util.ClusterOwnedLabelValue, machineNameLabelKey, value)
+
+	retainDisks, err := r.getBoolAnnotation(retainDisksAnnotation)
+	if err != nil {
+		return err
+	}
+	if !retainDisks {
+		disks, err := r.computeService.DisksList(r.projectID, r.providerSpec.Zone, filter)
+		if err != nil {
+			return fmt.Errorf("failed to list disks of machine %s: %w", r.machine.Name, err)
+		}
+		for _, disk := range disks {
+			if len(disk.Users) > 0 {
+				r.log.Info("Keeping disk, it is in use", "disk", disk.Name, "users", disk.Users)
+				continue
+			}
+			if err := r.checkClusterOwnership("disk", disk.Name, disk.Labels); err != nil {
+				return err
+			}
+			r.log.Info("Deleting leaked disk", "disk", disk.Name)
+			if _, err := r.computeService.DisksDelete(r.projectID, r.providerSpec.Zone, disk.Name); err != nil && !isNotFoundError(err) {
+				return fmt.Errorf("failed to delete disk %s: %w", disk.Name, err)
+			}
+		}
+	}
+
+	addresses, err := r.computeService.AddressesList(r.projectID, r.providerSpec.Region, filter)
+	if err != nil {
+		return fmt.Errorf("failed to list addresses of machine %s: %w", r.machine.Name, err)
+	}
+	staticInternalAddress, err := r.getBoolAnnotation(staticInternalAddressAnnotation)
+	if err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		if staticInternalAddress && address.Name == r.instanceName {
+			// Released by releaseInternalAddress.
+			continue
+		}
+		if address.Status == addressStatusInUse {
+			r.log.Info("Keeping address, it is in use", "address", address.Name, "users", address.Users)
+			continue
+		}
+		r.log.Info("Deleting leaked address", "address", address.Name)
+		if _, err := r.computeService.AddressesDelete(r.projectID, r.providerSpec.Region, address.Name); err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("failed to delete address %s: %w", address.Name, err)
+		}
+	}
+	return nil
+}
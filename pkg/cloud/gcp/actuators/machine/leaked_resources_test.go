@@ -0,0 +1,140 @@
+package machine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMachineNameLabelValue(t *testing.T) {
+	for name, expected := range map[string]string{
+		"cluster-worker-a-x7k2p": "cluster-worker-a-x7k2p",
+		"worker.example":         "worker_example",
+		strings.Repeat("a", 64):  "",
+	} {
+		if value, _ := machineNameLabelValue(name); value != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, value)
+		}
+	}
+}
+
+func TestDeleteLeakedResources(t *testing.T) {
+	owned := map[string]string{"kubernetes-io-cluster-CLUSTERID": "owned"}
+	disks := []*compute.Disk{
+		{Name: "test-data", Labels: owned},
+		{Name: "test-shared", Labels: owned, Users: []string{"projects/test/zones/us-east1-b/instances/other"}},
+	}
+	addresses := []*compute.Address{
+		{Name: "test", Status: addressStatusReserved},
+		{Name: "test-external", Status: addressStatusReserved},
+		{Name: "test-used", Status: addressStatusInUse},
+	}
+	cases := []struct {
+		name              string
+		annotations       map[string]string
+		expectedDisks     []string
+		expectedAddresses []string
+	}{
+		{
+			name:              "Unused disks and addresses are deleted",
+			expectedDisks:     []string{"test-data"},
+			expectedAddresses: []string{"test", "test-external"},
+		},
+		{
+			name:              "Disks are retained",
+			annotations:       map[string]string{retainDisksAnnotation: "true"},
+			expectedAddresses: []string{"test", "test-external"},
+		},
+		{
+			name:              "Static internal address is left to its release",
+			annotations:       map[string]string{staticInternalAddressAnnotation: "true"},
+			expectedDisks:     []string{"test-data"},
+			expectedAddresses: []string{"test-external"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			var filters, deletedDisks, deletedAddresses []string
+			mockComputeService.MockDisksList = func(project string, zone string, filter string) ([]*compute.Disk, error) {
+				filters = append(filters, filter)
+				return disks, nil
+			}
+			mockComputeService.MockDisksDelete = func(project string, zone string, disk string) (*compute.Operation, error) {
+				deletedDisks = append(deletedDisks, disk)
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockAddressesList = func(project string, region string, filter string) ([]*compute.Address, error) {
+				filters = append(filters, filter)
+				return addresses, nil
+			}
+			mockComputeService.MockAddressesDelete = func(project string, region string, name string) (*compute.Operation, error) {
+				deletedAddresses = append(deletedAddresses, name)
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "test",
+						Annotations: tc.annotations,
+						Labels:      map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+					},
+				},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "us-east1", Zone: "us-east1-b"},
+				computeService: mockComputeService,
+			})
+
+			if err := r.deleteLeakedResources(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(deletedDisks, tc.expectedDisks) {
+				t.Errorf("Expected deleted disks %v, got %v", tc.expectedDisks, deletedDisks)
+			}
+			if !reflect.DeepEqual(deletedAddresses, tc.expectedAddresses) {
+				t.Errorf("Expected deleted addresses %v, got %v", tc.expectedAddresses, deletedAddresses)
+			}
+			for _, filter := range filters {
+				if filter != `(labels.kubernetes-io-cluster-CLUSTERID = "owned") AND (labels.machine-openshift-io-name = "test")` {
+					t.Errorf("Expected the resources of the machine to be filtered by their labels, got %q", filter)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteLeakedResourcesRefusesDiskOfAnotherCluster(t *testing.T) {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	var deletedDisks []string
+	mockComputeService.MockDisksList = func(project string, zone string, filter string) ([]*compute.Disk, error) {
+		return []*compute.Disk{{Name: "test-data", Labels: map[string]string{"kubernetes-io-cluster-OTHER": "owned"}}}, nil
+	}
+	mockComputeService.MockDisksDelete = func(project string, zone string, disk string) (*compute.Operation, error) {
+		deletedDisks = append(deletedDisks, disk)
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	r := newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "test",
+				Labels: map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+			},
+		},
+		providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "us-east1", Zone: "us-east1-b"},
+		computeService: mockComputeService,
+	})
+
+	expectedError := "refusing to delete disk test-data: it does not carry the label kubernetes-io-cluster-CLUSTERID=owned of cluster \"CLUSTERID\""
+	if err := r.deleteLeakedResources(); err == nil || err.Error() != expectedError {
+		t.Errorf("Expected: %v, got %v", expectedError, err)
+	}
+	if len(deletedDisks) > 0 {
+		t.Errorf("Expected no disk to be deleted, got %v", deletedDisks)
+	}
+}
@@ -0,0 +1,134 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// loadBalancerConfigMapName is the ConfigMap, in the namespace of the machines, holding the
+	// authoritative names of the load balancer resources control plane machines are registered with.
+	// The Infrastructure status does not carry these names, so clusters whose installer used a
+	// custom naming scheme record them here. Names that are not set fall back to the installer's
+	// naming convention.
+	loadBalancerConfigMapName = "gcp-load-balancer-config"
+	// backendServiceNameKey holds the name of the internal API backend service.
+	backendServiceNameKey = "backendServiceName"
+	// instanceGroupNameKeyPrefix, followed by a zone, holds the name of the control plane instance group of that zone.
+	instanceGroupNameKeyPrefix = "instanceGroupName."
+	// networkEndpointGroupNameKeyPrefix, followed by a zone, holds the name of the GCE_VM_IP network
+	// endpoint group of that zone backing the backend service. Control plane machines of zones with
+	// one are attached to it instead of the control plane instance group.
+	networkEndpointGroupNameKeyPrefix = "networkEndpointGroupName."
+	// instanceGroupNameTemplateKey holds the naming template of the control plane instance groups of
+	// zones without an instanceGroupNameKeyPrefix key, e.g. "{clusterID}-cp-{zone}". It defaults to
+	// the installer's "{clusterID}-master-{zone}".
+	instanceGroupNameTemplateKey = "instanceGroupNameTemplate"
+	// instanceGroupNamedPortsKey holds the named ports of the control plane instance groups, as a
+	// comma separated list of name:port, e.g. "https:6443,ignition:22623". They are set on the groups
+	// the provider creates and added to existing groups missing them.
+	instanceGroupNamedPortsKey = "instanceGroupNamedPorts"
+
+	defaultInstanceGroupNameTemplate = "{clusterID}-master-{zone}"
+)
+
+// loadBalancerConfig holds the load balancer resource names read from the loadBalancerConfigMapName ConfigMap.
+type loadBalancerConfig struct {
+	backendServiceName string
+	// instanceGroupNames maps zones to control plane instance group names.
+	instanceGroupNames map[string]string
+	// networkEndpointGroupNames maps zones to the network endpoint groups control plane machines are attached to.
+	networkEndpointGroupNames map[string]string
+	// instanceGroupNameTemplate names the control plane instance groups of the other zones.
+	instanceGroupNameTemplate string
+	// instanceGroupNamedPorts are the named ports of the control plane instance groups.
+	instanceGroupNamedPorts []*compute.NamedPort
+}
+
+// loadLoadBalancerConfig reads the load balancer configuration of the cluster, if any.
+func (r *Reconciler) loadLoadBalancerConfig() error {
+	config, err := readLoadBalancerConfig(r.Context, r.coreClient, r.machine.Namespace)
+	if err != nil {
+		return err
+	}
+	r.log.V(logLevelRoutine).Info("Using load balancer configuration", "config", fmt.Sprintf("%+v", *config))
+	r.loadBalancerConfig = config
+	return nil
+}
+
+// readLoadBalancerConfig reads the load balancer configuration of the namespace. It is empty when
+// the ConfigMap does not exist.
+func readLoadBalancerConfig(ctx context.Context, c client.Client, namespace string) (*loadBalancerConfig, error) {
+	configMap := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: namespace, Name: loadBalancerConfigMapName}
+	if err := c.Get(ctx, key, configMap); err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			return &loadBalancerConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to get load balancer configuration %s: %w", key, err)
+	}
+
+	config := &loadBalancerConfig{
+		backendServiceName:        configMap.Data[backendServiceNameKey],
+		instanceGroupNames:        map[string]string{},
+		networkEndpointGroupNames: map[string]string{},
+		instanceGroupNameTemplate: configMap.Data[instanceGroupNameTemplateKey],
+	}
+	if template := config.instanceGroupNameTemplate; template != "" && !strings.Contains(template, "{zone}") {
+		return nil, fmt.Errorf("invalid %s %q in load balancer configuration %s: it must contain {zone}", instanceGroupNameTemplateKey, template, key)
+	}
+	namedPorts, err := parseNamedPorts(configMap.Data[instanceGroupNamedPortsKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in load balancer configuration %s: %v", instanceGroupNamedPortsKey, key, err)
+	}
+	config.instanceGroupNamedPorts = namedPorts
+	for key, value := range configMap.Data {
+		if zone := strings.TrimPrefix(key, instanceGroupNameKeyPrefix); zone != key && zone != "" {
+			config.instanceGroupNames[zone] = value
+		}
+		if zone := strings.TrimPrefix(key, networkEndpointGroupNameKeyPrefix); zone != key && zone != "" {
+			config.networkEndpointGroupNames[zone] = value
+		}
+	}
+	return config, nil
+}
+
+// instanceGroupName returns the name of the control plane instance group of the zone of the
+// cluster: the configured name, else the configured template or the installer's naming convention.
+func (c *loadBalancerConfig) instanceGroupName(clusterID, zone string) string {
+	template := defaultInstanceGroupNameTemplate
+	if c != nil {
+		if name := c.instanceGroupNames[zone]; name != "" {
+			return name
+		}
+		if c.instanceGroupNameTemplate != "" {
+			template = c.instanceGroupNameTemplate
+		}
+	}
+	return strings.NewReplacer("{clusterID}", clusterID, "{zone}", zone).Replace(template)
+}
+
+// parseNamedPorts parses a comma separated list of name:port.
+func parseNamedPorts(value string) ([]*compute.NamedPort, error) {
+	var namedPorts []*compute.NamedPort
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, portValue, ok := strings.Cut(item, ":")
+		port, err := strconv.ParseInt(portValue, 10, 64)
+		if !ok || name == "" || err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid named port %q, expected name:port", item)
+		}
+		namedPorts = append(namedPorts, &compute.NamedPort{Name: name, Port: port})
+	}
+	return namedPorts, nil
+}
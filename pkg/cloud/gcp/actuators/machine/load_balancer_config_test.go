@@ -0,0 +1,117 @@
+package machine
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoadBalancerNames(t *testing.T) {
+	cases := []struct {
+		name                       string
+		configMap                  *corev1.ConfigMap
+		expectedBackendServiceName string
+		expectedInstanceGroupName  string
+		expectedEndpointGroupName  string
+	}{
+		{
+			name:                       "Naming convention without configuration",
+			expectedBackendServiceName: "CLUSTERID-api-internal",
+			expectedInstanceGroupName:  "CLUSTERID-master-zone-a",
+		},
+		{
+			name: "Names from configuration",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: loadBalancerConfigMapName, Namespace: "openshift-machine-api"},
+				Data: map[string]string{
+					backendServiceNameKey:                        "custom-api-internal",
+					instanceGroupNameKeyPrefix + "zone-a":        "custom-master-a",
+					networkEndpointGroupNameKeyPrefix + "zone-a": "custom-api-a",
+				},
+			},
+			expectedBackendServiceName: "custom-api-internal",
+			expectedInstanceGroupName:  "custom-master-a",
+			expectedEndpointGroupName:  "custom-api-a",
+		},
+		{
+			name: "Naming convention for zones missing from configuration",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: loadBalancerConfigMapName, Namespace: "openshift-machine-api"},
+				Data: map[string]string{
+					instanceGroupNameKeyPrefix + "zone-b": "custom-master-b",
+				},
+			},
+			expectedBackendServiceName: "CLUSTERID-api-internal",
+			expectedInstanceGroupName:  "CLUSTERID-master-zone-a",
+		},
+		{
+			name: "Naming template for zones missing from configuration",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: loadBalancerConfigMapName, Namespace: "openshift-machine-api"},
+				Data: map[string]string{
+					instanceGroupNameKeyPrefix + "zone-b": "custom-master-b",
+					instanceGroupNameTemplateKey:          "{clusterID}-cp-{zone}",
+				},
+			},
+			expectedBackendServiceName: "CLUSTERID-api-internal",
+			expectedInstanceGroupName:  "CLUSTERID-cp-zone-a",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := []client.Object{}
+			if tc.configMap != nil {
+				objects = append(objects, tc.configMap)
+			}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "openshift-machine-api",
+						Labels:    map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+					},
+				},
+				coreClient:   controllerfake.NewClientBuilder().WithObjects(objects...).Build(),
+				providerSpec: &machinev1.GCPMachineProviderSpec{Zone: "zone-a"},
+			})
+
+			if err := r.loadLoadBalancerConfig(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if name := r.backendServiceName(); name != tc.expectedBackendServiceName {
+				t.Errorf("Expected backend service %q, got %q", tc.expectedBackendServiceName, name)
+			}
+			if name := r.controlPlaneGroupName(); name != tc.expectedInstanceGroupName {
+				t.Errorf("Expected instance group %q, got %q", tc.expectedInstanceGroupName, name)
+			}
+			if name := r.controlPlaneEndpointGroupName(); name != tc.expectedEndpointGroupName {
+				t.Errorf("Expected network endpoint group %q, got %q", tc.expectedEndpointGroupName, name)
+			}
+		})
+	}
+}
+
+func TestInvalidLoadBalancerConfig(t *testing.T) {
+	for _, data := range []map[string]string{
+		{instanceGroupNameTemplateKey: "{clusterID}-master"},
+		{instanceGroupNamedPortsKey: "https"},
+		{instanceGroupNamedPortsKey: "https:6443,ignition:70000"},
+	} {
+		r := newReconciler(&machineScope{
+			machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "openshift-machine-api"}},
+			coreClient: controllerfake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: loadBalancerConfigMapName, Namespace: "openshift-machine-api"},
+				Data:       data,
+			}).Build(),
+			providerSpec: &machinev1.GCPMachineProviderSpec{Zone: "zone-a"},
+		})
+		if err := r.loadLoadBalancerConfig(); err == nil {
+			t.Errorf("Expected an error for %v", data)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package machine
+
+import (
+	"fmt"
+	"path"
+
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// loadBalancerHealthyConditionType reports the health of the instance as seen by the health
+	// check of the backend service it is registered in, so that remediation can act on the health
+	// GCP routes traffic by and not only on node heartbeats.
+	loadBalancerHealthyConditionType = "LoadBalancerHealthy"
+	loadBalancerHealthyReason        = "Healthy"
+	loadBalancerUnhealthyReason      = "Unhealthy"
+	loadBalancerHealthUnknownReason  = "HealthUnknown"
+	notInBackendServiceReason        = "NotInBackendService"
+
+	healthStateHealthy = "HEALTHY"
+)
+
+// reportLoadBalancerHealth records the health state of the instance in the backend service the
+// load balancer registration found it registered in. The condition is only added to machines
+// registered in a backend service, and set to Unknown when a machine no longer is. Failures to get
+// the health are logged and keep the condition, they must not fail the reconcile.
+func (r *Reconciler) reportLoadBalancerHealth() {
+	membership := r.loadBalancerMembership
+	if membership == nil {
+		return
+	}
+	if len(membership.backendServices) == 0 {
+		if findCondition(r.providerStatus.Conditions, loadBalancerHealthyConditionType) != nil {
+			r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+				Type:    loadBalancerHealthyConditionType,
+				Status:  metav1.ConditionUnknown,
+				Reason:  notInBackendServiceReason,
+				Message: "instance is not registered in a backend service",
+			})
+		}
+		return
+	}
+
+	backendServiceName := membership.backendServices[0]
+	health, err := r.computeService.BackendServiceGetHealth(r.projectID, r.providerSpec.Region, backendServiceName, r.loadBalancerHealthGroup())
+	if err != nil {
+		r.log.Error(err, "Failed to get the health of backend service", "backendService", backendServiceName)
+		return
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, r.loadBalancerHealthCondition(backendServiceName, health))
+}
+
+// loadBalancerHealthGroup returns the link of the group the instance is a backend through: the
+// network endpoint group of its zone, or else the control plane instance group.
+func (r *Reconciler) loadBalancerHealthGroup() string {
+	if group := r.controlPlaneEndpointGroupName(); group != "" {
+		return r.networkEndpointGroupLink(group)
+	}
+	return r.FQDNInstanceGroup()
+}
+
+// loadBalancerHealthCondition returns the LoadBalancerHealthy condition for the health statuses of
+// the group. The health of an instance that was just added is not reported yet, it is Unknown.
+func (r *Reconciler) loadBalancerHealthCondition(backendServiceName string, health []*compute.HealthStatus) metav1.Condition {
+	for _, status := range health {
+		if status == nil || path.Base(status.Instance) != r.instanceName {
+			continue
+		}
+		if status.HealthState == healthStateHealthy {
+			return metav1.Condition{
+				Type:    loadBalancerHealthyConditionType,
+				Status:  metav1.ConditionTrue,
+				Reason:  loadBalancerHealthyReason,
+				Message: fmt.Sprintf("instance is healthy in backend service %s", backendServiceName),
+			}
+		}
+		return metav1.Condition{
+			Type:    loadBalancerHealthyConditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  loadBalancerUnhealthyReason,
+			Message: fmt.Sprintf("instance is %s in backend service %s", status.HealthState, backendServiceName),
+		}
+	}
+	return metav1.Condition{
+		Type:    loadBalancerHealthyConditionType,
+		Status:  metav1.ConditionUnknown,
+		Reason:  loadBalancerHealthUnknownReason,
+		Message: fmt.Sprintf("backend service %s does not report the health of the instance yet", backendServiceName),
+	}
+}
@@ -0,0 +1,119 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReportLoadBalancerHealth(t *testing.T) {
+	instance := "https://www.googleapis.com/compute/v1/projects/testProject/zones/zone1/instances/testInstance"
+	cases := []struct {
+		name           string
+		role           string
+		health         []*compute.HealthStatus
+		healthErr      error
+		existing       []metav1.Condition
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "Healthy instance",
+			role:           masterMachineRole,
+			health:         []*compute.HealthStatus{{Instance: instance, HealthState: "HEALTHY"}},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: loadBalancerHealthyReason,
+		},
+		{
+			name: "Unhealthy instance",
+			role: masterMachineRole,
+			health: []*compute.HealthStatus{
+				{Instance: "https://www.googleapis.com/compute/v1/projects/testProject/zones/zone1/instances/other", HealthState: "HEALTHY"},
+				{Instance: instance, HealthState: "UNHEALTHY"},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: loadBalancerUnhealthyReason,
+		},
+		{
+			name:           "Health not reported yet",
+			role:           masterMachineRole,
+			expectedStatus: metav1.ConditionUnknown,
+			expectedReason: loadBalancerHealthUnknownReason,
+		},
+		{
+			name:           "Failure to get the health keeps the condition",
+			role:           masterMachineRole,
+			healthErr:      errors.New("backend error"),
+			existing:       []metav1.Condition{{Type: loadBalancerHealthyConditionType, Status: metav1.ConditionTrue, Reason: loadBalancerHealthyReason}},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: loadBalancerHealthyReason,
+		},
+		{
+			name: "Worker instance has no condition",
+			role: "worker",
+		},
+		{
+			name:           "Instance removed from the backend service",
+			role:           "worker",
+			existing:       []metav1.Condition{{Type: loadBalancerHealthyConditionType, Status: metav1.ConditionTrue, Reason: loadBalancerHealthyReason}},
+			expectedStatus: metav1.ConditionUnknown,
+			expectedReason: notInBackendServiceReason,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockBackendServiceHealth = func(project string, region string, backendServiceName string, group string) ([]*compute.HealthStatus, error) {
+				if expected := "https://www.googleapis.com/compute/v1/projects/testProject/zones/zone1/instanceGroups/CLUSTERID-master-zone1"; group != expected {
+					t.Errorf("Expected the health of group %s, got %s", expected, group)
+				}
+				return tc.health, tc.healthErr
+			}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "testInstance",
+						Labels: map[string]string{
+							openshiftMachineRoleLabel:       tc.role,
+							machinev1.MachineClusterIDLabel: "CLUSTERID",
+						},
+					},
+				},
+				coreClient: controllerfake.NewFakeClient(),
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:        "zone1",
+					Region:      computeservice.WithMachineInPool,
+					TargetPools: []string{"pool1"},
+				},
+				projectID: "testProject",
+				providerStatus: &machinev1.GCPMachineProviderStatus{
+					InstanceState: pointer.String("RUNNING"),
+					Conditions:    tc.existing,
+				},
+				computeService: mockComputeService,
+			})
+
+			if err := r.reconcileLoadBalancerRegistration(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			condition := findCondition(r.providerStatus.Conditions, loadBalancerHealthyConditionType)
+			if tc.expectedStatus == "" {
+				if condition != nil {
+					t.Errorf("Expected no %s condition, got %v", loadBalancerHealthyConditionType, condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tc.expectedStatus || condition.Reason != tc.expectedReason {
+				t.Errorf("Expected a %s condition with reason %s, got %v", tc.expectedStatus, tc.expectedReason, condition)
+			}
+		})
+	}
+}
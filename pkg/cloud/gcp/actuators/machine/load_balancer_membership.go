@@ -0,0 +1,97 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// loadBalancerMembershipConditionType reports the target pools, instance groups, network
+	// endpoint groups and backend services the load balancer registration found the instance
+	// registered in. Instances added by the registration are reported once the next reconcile finds
+	// them registered.
+	loadBalancerMembershipConditionType = "LoadBalancerMembership"
+	loadBalancerMemberReason            = "Registered"
+	notLoadBalancerMemberReason         = "NotRegistered"
+)
+
+// loadBalancerMembership collects the load balancer resources the instance was found registered in.
+type loadBalancerMembership struct {
+	targetPools     []string
+	instanceGroups  []string
+	endpointGroups  []string
+	backendServices []string
+	// groupBackendService is the backend service the control plane instance group or network
+	// endpoint group is a backend of.
+	groupBackendService string
+}
+
+// recordTargetPool records that the instance is registered in the target pool.
+func (m *loadBalancerMembership) recordTargetPool(pool string) {
+	if m != nil {
+		m.targetPools = append(m.targetPools, pool)
+	}
+}
+
+// recordInstanceGroup records that the instance is registered in the instance group, and through
+// it in the backend service of the group, if any.
+func (m *loadBalancerMembership) recordInstanceGroup(group string) {
+	if m == nil {
+		return
+	}
+	m.instanceGroups = append(m.instanceGroups, group)
+	if m.groupBackendService != "" {
+		m.backendServices = append(m.backendServices, m.groupBackendService)
+	}
+}
+
+// recordNetworkEndpointGroup records that the instance is an endpoint of the network endpoint group,
+// and through it registered in the backend service of the group, if any.
+func (m *loadBalancerMembership) recordNetworkEndpointGroup(group string) {
+	if m == nil {
+		return
+	}
+	m.endpointGroups = append(m.endpointGroups, group)
+	if m.groupBackendService != "" {
+		m.backendServices = append(m.backendServices, m.groupBackendService)
+	}
+}
+
+func (m *loadBalancerMembership) recordGroupBackendService(backendService string) {
+	if m != nil {
+		m.groupBackendService = backendService
+	}
+}
+
+func (m *loadBalancerMembership) String() string {
+	list := func(names []string) string {
+		if len(names) == 0 {
+			return "none"
+		}
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("target pools: %s; instance groups: %s; network endpoint groups: %s; backend services: %s",
+		list(m.targetPools), list(m.instanceGroups), list(m.endpointGroups), list(m.backendServices))
+}
+
+// reportLoadBalancerMembership records the membership observed by the load balancer registration
+// in the provider status, so that load balancer flaps can be debugged from the machine.
+func (r *Reconciler) reportLoadBalancerMembership() {
+	membership := r.loadBalancerMembership
+	if membership == nil {
+		return
+	}
+	condition := metav1.Condition{
+		Type:    loadBalancerMembershipConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  notLoadBalancerMemberReason,
+		Message: membership.String(),
+	}
+	if len(membership.targetPools) > 0 || len(membership.instanceGroups) > 0 || len(membership.endpointGroups) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = loadBalancerMemberReason
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, condition)
+}
@@ -0,0 +1,87 @@
+package machine
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReportLoadBalancerMembership(t *testing.T) {
+	cases := []struct {
+		name            string
+		projectID       string
+		region          string
+		role            string
+		expectedStatus  metav1.ConditionStatus
+		expectedMessage string
+	}{
+		{
+			name:            "Control plane instance registered everywhere",
+			projectID:       "testProject",
+			region:          computeservice.WithMachineInPool,
+			role:            masterMachineRole,
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "target pools: pool1; instance groups: CLUSTERID-master-zone1; network endpoint groups: none; backend services: CLUSTERID-api-internal",
+		},
+		{
+			name:            "Control plane instance not yet in its instance group",
+			projectID:       computeservice.EmptyInstanceList,
+			region:          computeservice.NoMachinesInPool,
+			role:            masterMachineRole,
+			expectedStatus:  metav1.ConditionFalse,
+			expectedMessage: "target pools: none; instance groups: none; network endpoint groups: none; backend services: none",
+		},
+		{
+			name:            "Worker instance registered in its target pool",
+			projectID:       "testProject",
+			region:          computeservice.WithMachineInPool,
+			role:            "worker",
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "target pools: pool1; instance groups: none; network endpoint groups: none; backend services: none",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "testInstance",
+						Labels: map[string]string{
+							openshiftMachineRoleLabel:       tc.role,
+							machinev1.MachineClusterIDLabel: "CLUSTERID",
+						},
+					},
+				},
+				coreClient: controllerfake.NewFakeClient(),
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:        "zone1",
+					Region:      tc.region,
+					TargetPools: []string{"pool1"},
+				},
+				projectID: tc.projectID,
+				providerStatus: &machinev1.GCPMachineProviderStatus{
+					InstanceState: pointer.String("RUNNING"),
+				},
+				computeService: mockComputeService,
+			})
+
+			if err := r.reconcileLoadBalancerRegistration(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			condition := findCondition(r.providerStatus.Conditions, loadBalancerMembershipConditionType)
+			if condition == nil {
+				t.Fatalf("Expected a %s condition", loadBalancerMembershipConditionType)
+			}
+			if condition.Status != tc.expectedStatus || condition.Message != tc.expectedMessage {
+				t.Errorf("Expected %s %q, got %s %q", tc.expectedStatus, tc.expectedMessage, condition.Status, condition.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package machine
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/klog/v2"
+)
+
+// The verbosity levels of the messages of the actuator, consistent with the termination handler:
+// changes of the cloud resources of a machine and failures are logged by default, the details of
+// the actions taken at level 1 and the routine checks of every reconcile at level 2.
+const (
+	logLevelDetail  = 1
+	logLevelRoutine = 2
+)
+
+// machineLogger returns the logger of the actions on the machine: the logger of the context, e.g.
+// the one of the machine controller, with the namespaced name of the machine.
+func machineLogger(ctx context.Context, machine *machinev1.Machine) logr.Logger {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return klog.FromContext(ctx).WithValues("machine", machine.Namespace+"/"+machine.Name)
+}
+
+// newScopeLogger returns the logger of the reconciler of the machine, adding the project and the
+// zone of its instance to every message so that the messages of thousands of machines can be
+// filtered by any of them.
+func newScopeLogger(ctx context.Context, machine *machinev1.Machine, projectID, zone string) logr.Logger {
+	return machineLogger(ctx, machine).WithValues("project", projectID, "zone", zone)
+}
@@ -0,0 +1,51 @@
+package machine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestScopeLogger(t *testing.T) {
+	var lines []string
+	ctx := logr.NewContext(context.Background(), funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{}))
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-a", Namespace: "openshift-machine-api"}}
+	scope := &machineScope{
+		Context:      ctx,
+		machine:      machine,
+		projectID:    "project",
+		providerSpec: &machinev1.GCPMachineProviderSpec{Zone: "us-central1-a"},
+	}
+	r := newReconciler(scope)
+
+	r.log.Info("Before fallback")
+	r.setZone("us-central1-b")
+	r.operationLogger(trackedOperation{Name: "operation-1", Action: insertOperationAction, Zone: "us-central1-b"}).Info("After fallback")
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 messages, got %d: %v", len(lines), lines)
+	}
+	for _, expected := range []string{`"machine"="openshift-machine-api/worker-a"`, `"project"="project"`, `"zone"="us-central1-a"`} {
+		if !strings.Contains(lines[0], expected) {
+			t.Errorf("Expected %s in the first message, got %s", expected, lines[0])
+		}
+	}
+	for _, expected := range []string{`"zone"="us-central1-b"`, `"operation"="operation-1"`, `"operationAction"="insert"`} {
+		if !strings.Contains(lines[1], expected) {
+			t.Errorf("Expected %s in the second message, got %s", expected, lines[1])
+		}
+	}
+	if strings.Contains(lines[1], "us-central1-a") {
+		t.Errorf("Expected the previous zone to be dropped, got %s", lines[1])
+	}
+	if r.providerSpec.Zone != "us-central1-b" || r.machine.Annotations[zoneAnnotation] != "us-central1-b" {
+		t.Errorf("Expected the zone of the instance to be us-central1-b, got %s and annotation %q", r.providerSpec.Zone, r.machine.Annotations[zoneAnnotation])
+	}
+}
@@ -0,0 +1,39 @@
+package machine
+
+import (
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+)
+
+// advancedMachineFeatures mirrors compute.AdvancedMachineFeatures for the
+// advancedMachineFeaturesAnnotation.
+type advancedMachineFeatures struct {
+	EnableNestedVirtualization bool  `json:"enableNestedVirtualization,omitempty"`
+	EnableUefiNetworking       bool  `json:"enableUefiNetworking,omitempty"`
+	ThreadsPerCore             int64 `json:"threadsPerCore,omitempty"`
+	VisibleCoreCount           int64 `json:"visibleCoreCount,omitempty"`
+}
+
+// advancedMachineFeatures returns the advanced machine features requested for the instance,
+// or nil when the GCP defaults should be used.
+func (r *Reconciler) advancedMachineFeatures() (*compute.AdvancedMachineFeatures, error) {
+	features := advancedMachineFeatures{}
+	if ok, err := r.getJSONAnnotation(advancedMachineFeaturesAnnotation, &features); err != nil || !ok {
+		return nil, err
+	}
+
+	// 0 leaves the choice to GCP.
+	if features.ThreadsPerCore != 0 && features.ThreadsPerCore != 1 && features.ThreadsPerCore != 2 {
+		return nil, machinecontroller.InvalidMachineConfiguration("threadsPerCore must be 1 or 2, got %d", features.ThreadsPerCore)
+	}
+	if features.VisibleCoreCount < 0 {
+		return nil, machinecontroller.InvalidMachineConfiguration("visibleCoreCount must be positive, got %d", features.VisibleCoreCount)
+	}
+
+	return &compute.AdvancedMachineFeatures{
+		EnableNestedVirtualization: features.EnableNestedVirtualization,
+		EnableUefiNetworking:       features.EnableUefiNetworking,
+		ThreadsPerCore:             features.ThreadsPerCore,
+		VisibleCoreCount:           features.VisibleCoreCount,
+	}, nil
+}
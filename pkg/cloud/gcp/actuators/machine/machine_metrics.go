@@ -0,0 +1,99 @@
+package machine
+
+import (
+	"context"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// machineCollectorTimeout bounds listing the machines on a scrape.
+const machineCollectorTimeout = 10 * time.Second
+
+var (
+	machinesDesc = prometheus.NewDesc(
+		"mapi_gcp_machines",
+		"Number of GCP machines, by namespace and phase",
+		[]string{"namespace", "phase"}, nil,
+	)
+	machineFailuresDesc = prometheus.NewDesc(
+		"mapi_gcp_machine_failures",
+		"Number of GCP machines that failed, by namespace and reason: the error reason of the machine, or the reason of its MachineCreated condition when it is false",
+		[]string{"namespace", "reason"}, nil,
+	)
+)
+
+// machineCollector reports the machines by phase and by failure reason. The machines are listed
+// from the cache of the manager on every scrape, so that the gauges never report deleted machines.
+type machineCollector struct {
+	coreClient controllerclient.Client
+}
+
+// MachineCollector returns the collector of the gauges of the machines per phase and per failure
+// reason, to be registered with the metrics registry of the manager.
+func (a *Actuator) MachineCollector() prometheus.Collector {
+	return &machineCollector{coreClient: a.coreClient}
+}
+
+// Describe implements prometheus.Collector.
+func (c *machineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- machinesDesc
+	ch <- machineFailuresDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *machineCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), machineCollectorTimeout)
+	defer cancel()
+	machines := &machinev1.MachineList{}
+	if err := c.coreClient.List(ctx, machines); err != nil {
+		klog.Errorf("Failed to list machines for the metrics: %v", err)
+		ch <- prometheus.NewInvalidMetric(machinesDesc, err)
+		return
+	}
+
+	type key struct{ namespace, value string }
+	phases, failures := map[key]int{}, map[key]int{}
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+		phase := "Unknown"
+		if machine.Status.Phase != nil && *machine.Status.Phase != "" {
+			phase = *machine.Status.Phase
+		}
+		phases[key{machine.Namespace, phase}]++
+		if reason := machineFailureReason(machine); reason != "" {
+			failures[key{machine.Namespace, reason}]++
+		}
+	}
+	for k, count := range phases {
+		ch <- prometheus.MustNewConstMetric(machinesDesc, prometheus.GaugeValue, float64(count), k.namespace, k.value)
+	}
+	for k, count := range failures {
+		ch <- prometheus.MustNewConstMetric(machineFailuresDesc, prometheus.GaugeValue, float64(count), k.namespace, k.value)
+	}
+}
+
+// machineFailureReason returns the error reason of the machine or, while it has none, the reason of
+// its MachineCreated condition when the condition is false, e.g. QuotaExceeded. It is empty for
+// machines that did not fail.
+func machineFailureReason(machine *machinev1.Machine) string {
+	if machine.Status.ErrorReason != nil {
+		return string(*machine.Status.ErrorReason)
+	}
+	if machine.Status.ProviderStatus == nil {
+		return ""
+	}
+	providerStatus, err := util.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil {
+		return ""
+	}
+	if condition := findCondition(providerStatus.Conditions, string(machinev1.MachineCreated)); condition != nil && condition.Status == metav1.ConditionFalse {
+		return condition.Reason
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package machine
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMachineCollector(t *testing.T) {
+	createFailed, err := util.RawExtensionFromProviderStatus(&machinev1.GCPMachineProviderStatus{
+		Conditions: []metav1.Condition{{Type: string(machinev1.MachineCreated), Status: metav1.ConditionFalse, Reason: "QuotaExceeded"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := util.RawExtensionFromProviderStatus(&machinev1.GCPMachineProviderStatus{
+		Conditions: []metav1.Condition{{Type: string(machinev1.MachineCreated), Status: metav1.ConditionTrue, Reason: "MachineCreationSucceeded"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalidConfiguration := machinev1.InvalidConfigurationMachineError
+	machines := []controllerclient.Object{
+		&machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "openshift-machine-api"},
+			Status:     machinev1.MachineStatus{Phase: pointer.String("Running"), ProviderStatus: created},
+		},
+		&machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "openshift-machine-api"},
+			Status:     machinev1.MachineStatus{Phase: pointer.String("Provisioning"), ProviderStatus: createFailed},
+		},
+		&machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "openshift-machine-api"},
+			Status:     machinev1.MachineStatus{Phase: pointer.String("Failed"), ErrorReason: &invalidConfiguration},
+		},
+		&machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "openshift-machine-api"}},
+	}
+	coreClient := controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machines...).Build()
+	collector := (&Actuator{coreClient: coreClient}).MachineCollector()
+
+	ch := make(chan prometheus.Metric, 10)
+	collector.Collect(ch)
+	close(ch)
+	got := map[string]float64{}
+	for metric := range ch {
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		name := ""
+		for _, label := range m.GetLabel() {
+			if label.GetName() != "namespace" {
+				name = label.GetValue()
+			}
+		}
+		if metric.Desc() == machineFailuresDesc {
+			name = "failure:" + name
+		}
+		got[name] = m.GetGauge().GetValue()
+	}
+
+	expected := map[string]float64{
+		"Running":               1,
+		"Provisioning":          1,
+		"Failed":                1,
+		"Unknown":               1,
+		"failure:QuotaExceeded": 1,
+		"failure:" + string(invalidConfiguration): 1,
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected metrics %v, got %v", expected, got)
+	}
+	for name, value := range expected {
+		if got[name] != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, got[name])
+		}
+	}
+}
@@ -3,18 +3,27 @@ package machine
 import (
 	"context"
 	"fmt"
+	"net/http"
 
+	"github.com/go-logr/logr"
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
 	machineapierros "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/providerid"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/credentials"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	iamservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/iam"
+	pricingservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/pricing"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
 	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
 
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/klog/v2"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -26,20 +35,37 @@ type machineScopeParams struct {
 	machine              *machinev1.Machine
 	computeClientBuilder computeservice.BuilderFuncType
 	tagsClientBuilder    tagservice.BuilderFuncType
+	iamClientBuilder     iamservice.BuilderFuncType
+	// credentials builds the credentials of the GCP clients from the credentials secret.
+	credentials          *credentials.Builder
 	featureGates         featuregates.FeatureGate
+	clock                clock.Clock
+	httpClient           *http.Client
+	eventRecorder        record.EventRecorder
+	pricingClientBuilder pricingservice.BuilderFuncType
+	// dnsClientBuilder builds the Cloud DNS client of options.DNSRecords.
+	dnsClientBuilder dnsservice.BuilderFuncType
+	skuCache         *skuCache
+	preemptionStats  *preemptionStats
+	options          Options
 }
 
 // machineScope defines a scope defined around a machine and its cluster.
 type machineScope struct {
 	context.Context
 
-	coreClient     controllerclient.Client
-	projectID      string
-	providerID     string
+	coreClient controllerclient.Client
+	projectID  string
+	providerID string
+	// instanceName is the name of the instance of the machine, see instanceName.
+	instanceName   string
 	computeService computeservice.GCPComputeService
 	machine        *machinev1.Machine
 	providerSpec   *machinev1.GCPMachineProviderSpec
 	providerStatus *machinev1.GCPMachineProviderStatus
+	// specZone is the zone of the providerSpec as persisted, the zone of providerSpec is the zone of
+	// the instance, see setZone.
+	specZone string
 
 	// origMachine captures original value of machine before it is updated (to
 	// skip object updated if nothing is changed)
@@ -56,7 +82,52 @@ type machineScope struct {
 	// tagService is for handling resource manager tags related operations.
 	tagService tagservice.TagService
 
+	// iamService is used to validate service accounts, nil skips the validation.
+	iamService iamservice.IAMService
+
 	featureGates featuregates.FeatureGate
+
+	// clock, httpClient and eventRecorder are the external dependencies of the reconciler
+	// besides the GCP services. They are defaulted by newReconciler when not set, so tests
+	// can build a scope with only the dependencies they care about.
+	clock         clock.Clock
+	httpClient    *http.Client
+	eventRecorder record.EventRecorder
+
+	// budget limits the compute API calls of the operation, nil when unlimited.
+	budget *reconcileBudget
+
+	// log carries the machine, project and zone of the instance on every message, it is
+	// defaulted by newReconciler when not set.
+	log logr.Logger
+
+	// options are the settings of the reconciler shared by all machines.
+	options Options
+	// pricingService and skuCache provide the spot prices of SpotZonePolicyCheapest, a nil
+	// pricingService keeps the requested zones.
+	pricingService pricingservice.PricingService
+	skuCache       *skuCache
+	// preemptionStats provides the recent preemptions of SpotZonePolicyLeastPreempted.
+	preemptionStats *preemptionStats
+	// managedTags are the network tags kept on the instance, and in the target tags of their firewall
+	// rules, rendered for the machine.
+	managedTags []managedTag
+	// dnsService and dnsRecord register the internal IPs of the machine in Cloud DNS, nil when
+	// records are not registered.
+	dnsService dnsservice.DNSService
+	dnsRecord  *dnsRecord
+	// dryRun stops the creation before the instance is inserted, and skips what has side effects
+	// before, see Actuator.DryRun.
+	dryRun bool
+	// reconciledInstance is the instance the machine was reconciled with recently, see
+	// existenceCache.reconciledInstance. When set, update reconciles the spec against it instead of
+	// looking up the instance and refreshing the status of the machine.
+	reconciledInstance *compute.Instance
+	// fetchedInstance is the instance looked up to refresh the status of the machine, nil when the
+	// instance was not looked up.
+	fetchedInstance *compute.Instance
+	// mutations notes whether the operation changed anything in the cloud.
+	mutations *mutationTracker
 }
 
 // newMachineScope creates a new MachineScope from the supplied parameters.
@@ -70,13 +141,15 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 	if err != nil {
 		return nil, machineapierros.InvalidMachineConfiguration("failed to get machine config: %v", err)
 	}
+	specZone := providerSpec.Zone
+	providerSpec.Zone = instanceZone(params.machine, providerSpec)
 
 	providerStatus, err := util.ProviderStatusFromRawExtension(params.machine.Status.ProviderStatus)
 	if err != nil {
 		return nil, machineapierros.InvalidMachineConfiguration("failed to get machine provider status: %v", err.Error())
 	}
 
-	serviceAccountJSON, err := util.GetCredentialsSecret(params.coreClient, params.machine.GetNamespace(), *providerSpec)
+	serviceAccountJSON, impersonateServiceAccount, err := util.GetCredentials(params.coreClient, params.machine.GetNamespace(), *providerSpec)
 	if err != nil {
 		return nil, err
 	}
@@ -89,11 +162,30 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		}
 	}
 
+	serviceAccountJSON, err = params.credentials.Build(serviceAccountJSON, impersonateServiceAccount)
+	if err != nil {
+		return nil, machineapierros.InvalidMachineConfiguration("error building credentials: %v", err)
+	}
+
 	computeService, err := params.computeClientBuilder(serviceAccountJSON)
 	if err != nil {
 		return nil, machineapierros.InvalidMachineConfiguration("error creating compute service: %v", err)
 	}
 
+	budgetClock := params.clock
+	if budgetClock == nil {
+		budgetClock = clock.RealClock{}
+	}
+	// The API calls are cancelled and their retries and rate limiting do not wait past the deadline
+	// of the sync context, the budget turns the failures into a requeue.
+	computeService = computeservice.WithContext(params.Context, computeService)
+	budget := newReconcileBudget(params.Context, budgetClock, params.options.MaxAPICallsPerReconcile, params.options.MaxReconcileDuration)
+	if budget != nil {
+		computeService = computeservice.WithInterceptors(computeService, budget.intercept)
+	}
+	mutations := &mutationTracker{}
+	computeService = computeservice.WithInterceptors(computeService, mutations.intercept)
+
 	var tagService tagservice.TagService
 	if params.featureGates.Enabled(configv1.FeatureGateGCPLabelsTags) {
 		tagService, err = params.tagsClientBuilder(params.Context, serviceAccountJSON)
@@ -102,12 +194,51 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		}
 	}
 
+	var iamService iamservice.IAMService
+	if params.iamClientBuilder != nil {
+		iamService, err = params.iamClientBuilder(params.Context, serviceAccountJSON)
+		if err != nil {
+			return nil, machineapierros.InvalidMachineConfiguration("error creating iam service: %v", err)
+		}
+	}
+
+	name, err := instanceName(params.options.InstanceNameTemplate, params.machine, providerStatus)
+	if err != nil {
+		return nil, machineapierros.InvalidMachineConfiguration("error naming the instance: %v", err)
+	}
+
+	managedTags, err := renderManagedTags(params.options.ManagedTags, params.machine)
+	if err != nil {
+		return nil, machineapierros.InvalidMachineConfiguration("error rendering the managed tags: %v", err)
+	}
+
+	var dnsService dnsservice.DNSService
+	var record *dnsRecord
+	if params.options.DNSRecords != nil && params.dnsClientBuilder != nil {
+		record, err = renderDNSRecord(params.options.DNSRecords, params.machine, projectID)
+		if err != nil {
+			return nil, machineapierros.InvalidMachineConfiguration("error rendering the DNS record: %v", err)
+		}
+		dnsService, err = params.dnsClientBuilder(params.Context, serviceAccountJSON)
+		if err != nil {
+			return nil, machineapierros.InvalidMachineConfiguration("error creating dns service: %v", err)
+		}
+	}
+
+	var pricingService pricingservice.PricingService
+	if params.options.SpotZonePolicy == SpotZonePolicyCheapest && params.pricingClientBuilder != nil && providerSpec.Preemptible {
+		pricingService, err = params.pricingClientBuilder(params.Context, serviceAccountJSON)
+		if err != nil {
+			return nil, machineapierros.InvalidMachineConfiguration("error creating pricing service: %v", err)
+		}
+	}
+
 	return &machineScope{
-		Context:    params.Context,
-		coreClient: params.coreClient,
-		projectID:  projectID,
-		// https://github.com/kubernetes/kubernetes/blob/8765fa2e48974e005ad16e65cb5c3acf5acff17b/staging/src/k8s.io/legacy-cloud-providers/gce/gce_util.go#L204
-		providerID:     fmt.Sprintf("gce://%s/%s/%s", projectID, providerSpec.Zone, params.machine.Name),
+		Context:        params.Context,
+		coreClient:     params.coreClient,
+		projectID:      projectID,
+		providerID:     providerid.New(projectID, providerSpec.Zone, name).String(),
+		instanceName:   name,
 		computeService: computeService,
 		// Deep copy the machine since it is changed outside
 		// of the machine scope by consumers of the machine
@@ -115,6 +246,7 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		machine:        params.machine.DeepCopy(),
 		providerSpec:   providerSpec,
 		providerStatus: providerStatus,
+		specZone:       specZone,
 		// Once set, they can not be changed. Otherwise, status change computation
 		// might be invalid and result in skipping the status update.
 		origMachine:        params.machine.DeepCopy(),
@@ -122,6 +254,20 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		machineToBePatched: controllerclient.MergeFrom(params.machine.DeepCopy()),
 		featureGates:       params.featureGates,
 		tagService:         tagService,
+		iamService:         iamService,
+		clock:              params.clock,
+		httpClient:         params.httpClient,
+		eventRecorder:      params.eventRecorder,
+		budget:             budget,
+		mutations:          mutations,
+		log:                newScopeLogger(params.Context, params.machine, projectID, providerSpec.Zone),
+		options:            params.options,
+		pricingService:     pricingService,
+		skuCache:           params.skuCache,
+		preemptionStats:    params.preemptionStats,
+		managedTags:        managedTags,
+		dnsService:         dnsService,
+		dnsRecord:          record,
 	}, nil
 }
 
@@ -155,12 +301,17 @@ func (s *machineScope) Close() error {
 }
 
 func (s *machineScope) setMachineSpec() error {
-	ext, err := util.RawExtensionFromProviderSpec(s.providerSpec)
+	providerSpec := s.providerSpec
+	if s.specZone != "" && s.specZone != providerSpec.Zone {
+		providerSpec = providerSpec.DeepCopy()
+		providerSpec.Zone = s.specZone
+	}
+	ext, err := util.RawExtensionFromProviderSpec(providerSpec)
 	if err != nil {
 		return err
 	}
 
-	klog.V(4).Infof("Storing machine spec for %q, resourceVersion: %v, generation: %v", s.machine.Name, s.machine.ResourceVersion, s.machine.Generation)
+	s.log.V(logLevelRoutine).Info("Storing machine spec", "resourceVersion", s.machine.ResourceVersion, "generation", s.machine.Generation)
 	s.machine.Spec.ProviderSpec.Value = ext
 
 	return nil
@@ -168,11 +319,11 @@ func (s *machineScope) setMachineSpec() error {
 
 func (s *machineScope) setMachineStatus() error {
 	if equality.Semantic.DeepEqual(s.providerStatus, s.origProviderStatus) && equality.Semantic.DeepEqual(s.machine.Status.Addresses, s.origMachine.Status.Addresses) {
-		klog.Infof("%s: status unchanged", s.machine.Name)
+		s.log.V(logLevelRoutine).Info("Machine status unchanged")
 		return nil
 	}
 
-	klog.V(4).Infof("Storing machine status for %q, resourceVersion: %v, generation: %v", s.machine.Name, s.machine.ResourceVersion, s.machine.Generation)
+	s.log.V(logLevelRoutine).Info("Storing machine status", "resourceVersion", s.machine.ResourceVersion, "generation", s.machine.Generation)
 	ext, err := util.RawExtensionFromProviderStatus(s.providerStatus)
 	if err != nil {
 		return err
@@ -186,13 +337,13 @@ func (s *machineScope) setMachineStatus() error {
 }
 
 func (s *machineScope) PatchMachine() error {
-	klog.V(3).Infof("%q: patching machine", s.machine.GetName())
+	s.log.V(logLevelDetail).Info("Patching machine")
 
 	statusCopy := *s.machine.Status.DeepCopy()
 
 	// patch machine
 	if err := s.coreClient.Patch(s.Context, s.machine, s.machineToBePatched); err != nil {
-		klog.Errorf("Failed to patch machine %q: %v", s.machine.GetName(), err)
+		s.log.Error(err, "Failed to patch machine")
 		return err
 	}
 
@@ -200,7 +351,7 @@ func (s *machineScope) PatchMachine() error {
 
 	// patch status
 	if err := s.coreClient.Status().Patch(s.Context, s.machine, s.machineToBePatched); err != nil {
-		klog.Errorf("Failed to patch machine status %q: %v", s.machine.GetName(), err)
+		s.log.Error(err, "Failed to patch machine status")
 		return err
 	}
 
@@ -0,0 +1,118 @@
+package machine
+
+import (
+	"fmt"
+	"time"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/robfig/cron"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// deferredConditionType reports that a disruptive action on the instance waits for a
+	// maintenance window of the machine.
+	deferredConditionType    = "DeferredUntilMaintenanceWindow"
+	deferredReason           = "OutsideMaintenanceWindow"
+	notDeferredReason        = "InsideMaintenanceWindow"
+	notDeferredMessage       = "no disruptive action is deferred"
+	maintenanceWindowsFormat = `a list of {"schedule": "<cron expression>", "duration": "<duration>"}`
+)
+
+// maintenanceWindow is a recurring period during which disruptive actions on the instance, which
+// stop it, are allowed.
+type maintenanceWindow struct {
+	// Schedule is a standard cron expression, e.g. "0 2 * * SAT", of the starts of the window in UTC.
+	Schedule string `json:"schedule"`
+	// Duration is how long the window lasts after each start, e.g. "4h".
+	Duration string `json:"duration"`
+}
+
+// parsedMaintenanceWindow is a maintenanceWindow ready to be evaluated.
+type parsedMaintenanceWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// maintenanceWindows returns the maintenance windows of the machine, set through the
+// maintenanceWindowsAnnotation, typically by its MachineSet. No windows means disruptive actions
+// are always allowed.
+func (r *Reconciler) maintenanceWindows() ([]parsedMaintenanceWindow, error) {
+	var raw []maintenanceWindow
+	if ok, err := r.getJSONAnnotation(maintenanceWindowsAnnotation, &raw); err != nil || !ok {
+		return nil, err
+	}
+	windows := make([]parsedMaintenanceWindow, 0, len(raw))
+	for _, window := range raw {
+		schedule, err := cron.ParseStandard(window.Schedule)
+		if err != nil {
+			return nil, machinecontroller.InvalidMachineConfiguration("invalid schedule %q in annotation %s, expected %s: %v", window.Schedule, maintenanceWindowsAnnotation, maintenanceWindowsFormat, err)
+		}
+		duration, err := time.ParseDuration(window.Duration)
+		if err != nil || duration <= 0 {
+			return nil, machinecontroller.InvalidMachineConfiguration("invalid duration %q in annotation %s, expected %s", window.Duration, maintenanceWindowsAnnotation, maintenanceWindowsFormat)
+		}
+		windows = append(windows, parsedMaintenanceWindow{schedule: schedule, duration: duration})
+	}
+	return windows, nil
+}
+
+// nextMaintenanceWindow returns whether now is inside one of the windows and, if it is not, when
+// the next window starts.
+func nextMaintenanceWindow(windows []parsedMaintenanceWindow, now time.Time) (bool, time.Time) {
+	now = now.UTC()
+	var next time.Time
+	for _, window := range windows {
+		// The window is open if it started within its duration.
+		if start := window.schedule.Next(now.Add(-window.duration)); !start.After(now) {
+			return true, time.Time{}
+		}
+		if start := window.schedule.Next(now); !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return len(windows) == 0, next
+}
+
+// deferUntilMaintenanceWindow returns true when the disruptive action, e.g. "resizing the instance
+// to n2-standard-8", must wait for the next maintenance window of the machine, and reports the
+// deferral with the DeferredUntilMaintenanceWindow condition. The condition is cleared once an
+// action is no longer deferred.
+func (r *Reconciler) deferUntilMaintenanceWindow(action string) (bool, error) {
+	windows, err := r.maintenanceWindows()
+	if err != nil {
+		return false, err
+	}
+	inside, next := nextMaintenanceWindow(windows, r.clock.Now())
+	if inside {
+		r.clearMaintenanceWindowDeferral()
+		return false, nil
+	}
+
+	message := fmt.Sprintf("%s is deferred until the next maintenance window", action)
+	if !next.IsZero() {
+		message = fmt.Sprintf("%s is deferred until the maintenance window starting at %s", action, next.Format(time.RFC3339))
+	}
+	r.log.Info("Deferring disruptive action until the next maintenance window", "action", action, "nextWindow", next)
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    deferredConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  deferredReason,
+		Message: message,
+	})
+	return true, nil
+}
+
+// clearMaintenanceWindowDeferral sets the DeferredUntilMaintenanceWindow condition to False when
+// an action was deferred, once it is performed or no longer needed.
+func (r *Reconciler) clearMaintenanceWindowDeferral() {
+	if condition := findCondition(r.providerStatus.Conditions, deferredConditionType); condition == nil || condition.Status != metav1.ConditionTrue {
+		return
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    deferredConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  notDeferredReason,
+		Message: notDeferredMessage,
+	})
+}
@@ -0,0 +1,144 @@
+package machine
+
+import (
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestNextMaintenanceWindow(t *testing.T) {
+	// Saturday.
+	now := time.Date(2024, 1, 6, 3, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name           string
+		windows        string
+		expectedInside bool
+		expectedNext   time.Time
+	}{
+		{
+			name:           "No windows",
+			windows:        `[]`,
+			expectedInside: true,
+		},
+		{
+			name:           "Inside a window",
+			windows:        `[{"schedule": "0 2 * * SAT", "duration": "4h"}]`,
+			expectedInside: true,
+		},
+		{
+			name:         "After a window",
+			windows:      `[{"schedule": "0 2 * * SAT", "duration": "30m"}]`,
+			expectedNext: time.Date(2024, 1, 13, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "Earliest of several windows",
+			windows:      `[{"schedule": "0 2 * * SAT", "duration": "30m"}, {"schedule": "0 22 * * *", "duration": "1h"}]`,
+			expectedNext: time.Date(2024, 1, 6, 22, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker-a",
+					Annotations: map[string]string{maintenanceWindowsAnnotation: tc.windows},
+				}},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+			})
+			windows, err := r.maintenanceWindows()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			inside, next := nextMaintenanceWindow(windows, now)
+			if inside != tc.expectedInside || !next.Equal(tc.expectedNext) {
+				t.Errorf("Expected inside %v and next window %v, got %v and %v", tc.expectedInside, tc.expectedNext, inside, next)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowsInvalid(t *testing.T) {
+	for _, windows := range []string{
+		`{"schedule": "0 2 * * SAT", "duration": "4h"}`,
+		`[{"schedule": "every saturday", "duration": "4h"}]`,
+		`[{"schedule": "0 2 * * SAT", "duration": "0s"}]`,
+		`[{"schedule": "0 2 * * SAT", "duration": "4h", "timezone": "CET"}]`,
+	} {
+		r := newReconciler(&machineScope{
+			machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+				Name:        "worker-a",
+				Annotations: map[string]string{maintenanceWindowsAnnotation: windows},
+			}},
+			providerSpec:   &machinev1.GCPMachineProviderSpec{},
+			providerStatus: &machinev1.GCPMachineProviderStatus{},
+		})
+		if _, err := r.maintenanceWindows(); err == nil {
+			t.Errorf("Expected an error for %s", windows)
+		}
+	}
+}
+
+func TestResizeDeferredUntilMaintenanceWindow(t *testing.T) {
+	instance := &compute.Instance{
+		Name:        "worker-a",
+		Status:      "RUNNING",
+		MachineType: "https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b/machineTypes/n2-standard-4",
+	}
+	stopped := false
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	mockComputeService.MockInstancesStop = func(_, _, _ string) (*compute.Operation, error) {
+		stopped = true
+		return &compute.Operation{Name: "stop", Status: "PENDING"}, nil
+	}
+	clock := clocktesting.NewFakeClock(time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC))
+	r := newReconciler(&machineScope{
+		machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+			Name: "worker-a",
+			Annotations: map[string]string{
+				inPlaceResizeAnnotation:      "true",
+				maintenanceWindowsAnnotation: `[{"schedule": "0 2 * * SAT", "duration": "4h"}]`,
+			},
+		}},
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Zone:        "us-east1-b",
+			MachineType: "n2-standard-8",
+		},
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		computeService: mockComputeService,
+		clock:          clock,
+		eventRecorder:  record.NewFakeRecorder(10),
+	})
+
+	resizing, err := r.reconcileMachineTypeResize(instance)
+	if resizing || err != nil {
+		t.Fatalf("Expected the resize to be deferred, got %v, %v", resizing, err)
+	}
+	if stopped {
+		t.Errorf("Expected the instance not to be stopped outside of a maintenance window")
+	}
+	condition := findCondition(r.providerStatus.Conditions, deferredConditionType)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Message != "resizing the instance from n2-standard-4 to n2-standard-8 is deferred until the maintenance window starting at 2024-01-06T02:00:00Z" {
+		t.Fatalf("Expected a True %s condition, got %v", deferredConditionType, condition)
+	}
+
+	clock.SetTime(time.Date(2024, 1, 6, 3, 0, 0, 0, time.UTC))
+	resizing, err = r.reconcileMachineTypeResize(instance)
+	if !resizing || err == nil {
+		t.Fatalf("Expected the resize to start inside the maintenance window, got %v, %v", resizing, err)
+	}
+	if !stopped {
+		t.Errorf("Expected the instance to be stopped inside the maintenance window")
+	}
+	condition = findCondition(r.providerStatus.Conditions, deferredConditionType)
+	if condition == nil || condition.Status != metav1.ConditionFalse {
+		t.Errorf("Expected a False %s condition, got %v", deferredConditionType, condition)
+	}
+}
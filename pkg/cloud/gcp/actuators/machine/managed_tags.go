@@ -0,0 +1,209 @@
+package machine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	managedTagsUpdatedEventReason   = "ManagedTagsUpdated"
+	firewallRuleTaggedEventReason   = "FirewallRuleTagged"
+	firewallRuleNotFoundEventReason = "FirewallRuleNotFound"
+)
+
+// ManagedTag is a network tag the provider keeps on the instances of all machines and, optionally,
+// in the target tags of a firewall rule, both rendered from the machine, see ParseManagedTags.
+type ManagedTag struct {
+	tag          *template.Template
+	firewallRule *template.Template
+}
+
+// ParseManagedTags parses a comma separated list of managed network tags. Each is a Go template of
+// the tag from the name, namespace, cluster ID and role of the machine, optionally followed by =
+// and a Go template of the name of a firewall rule that must target the tag, e.g.
+// "{{.ClusterID}}-{{.Role}}={{.ClusterID}}-{{.Role}}-ingress".
+func ParseManagedTags(text string) ([]ManagedTag, error) {
+	var managedTags []ManagedTag
+	for _, entry := range strings.Split(text, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tagText, firewallRuleText, hasFirewallRule := strings.Cut(entry, "=")
+		tag, err := parseMachineTemplate("network-tag", tagText)
+		if err != nil {
+			return nil, err
+		}
+		managedTag := ManagedTag{tag: tag}
+		if hasFirewallRule {
+			if managedTag.firewallRule, err = parseMachineTemplate("firewall-rule", firewallRuleText); err != nil {
+				return nil, err
+			}
+		}
+		managedTags = append(managedTags, managedTag)
+	}
+	return managedTags, nil
+}
+
+// managedTag is a managed network tag rendered for a machine.
+type managedTag struct {
+	tag string
+	// firewallRule is the name of the firewall rule that must target the tag, empty if none.
+	firewallRule string
+}
+
+// renderManagedTags renders the managed network tags of a machine, made valid network tags, whose
+// format is the one of resource names.
+func renderManagedTags(managedTags []ManagedTag, machine *machinev1.Machine) ([]managedTag, error) {
+	data := newInstanceNameData(machine)
+	rendered := make([]managedTag, 0, len(managedTags))
+	for _, managed := range managedTags {
+		var tag strings.Builder
+		if err := managed.tag.Execute(&tag, data); err != nil {
+			return nil, fmt.Errorf("failed to render the managed tag template: %w", err)
+		}
+		entry := managedTag{tag: makeInstanceName(tag.String())}
+		if managed.firewallRule != nil {
+			var firewallRule strings.Builder
+			if err := managed.firewallRule.Execute(&firewallRule, data); err != nil {
+				return nil, fmt.Errorf("failed to render the firewall rule template: %w", err)
+			}
+			entry.firewallRule = makeInstanceName(firewallRule.String())
+		}
+		rendered = append(rendered, entry)
+	}
+	return rendered, nil
+}
+
+// desiredTags returns the network tags of the instance: the ones of the providerSpec and the
+// managed tags.
+func (r *Reconciler) desiredTags() []string {
+	if len(r.managedTags) == 0 {
+		return r.providerSpec.Tags
+	}
+	tags := append([]string{}, r.providerSpec.Tags...)
+	for _, managed := range r.managedTags {
+		if !containsString(tags, managed.tag) {
+			tags = append(tags, managed.tag)
+		}
+	}
+	return tags
+}
+
+// reconcileManagedTags adds the managed network tags missing from the instance and removes the ones
+// that were managed before, e.g. because the tags were rotated, and are neither managed nor in the
+// providerSpec anymore, regardless of drift remediation. Other tags are kept. The firewall rules of
+// the managed tags are then made to target them.
+func (r *Reconciler) reconcileManagedTags(instance *compute.Instance) error {
+	previous := splitManagedTags(r.machine.Annotations[managedTagsAnnotation])
+	if len(r.managedTags) == 0 && len(previous) == 0 {
+		return nil
+	}
+
+	var current []string
+	if instance.Tags != nil {
+		current = instance.Tags.Items
+	}
+	desired := r.desiredTags()
+	var tags, changes []string
+	for _, tag := range current {
+		if containsString(previous, tag) && !containsString(desired, tag) {
+			changes = append(changes, tag+" removed")
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	for _, managed := range r.managedTags {
+		if !containsString(tags, managed.tag) {
+			tags = append(tags, managed.tag)
+			changes = append(changes, managed.tag+" added")
+		}
+	}
+
+	if len(changes) > 0 && !r.hasPendingOperation(setTagsOperationAction) {
+		request := &compute.Tags{Items: tags}
+		if instance.Tags != nil {
+			request.Fingerprint = instance.Tags.Fingerprint
+		}
+		operation, err := r.computeService.InstancesSetTags(r.projectID, r.providerSpec.Zone, instance.Name, request)
+		if err != nil {
+			return fmt.Errorf("failed to set the managed tags of instance %s: %w", instance.Name, err)
+		}
+		r.trackOperation(setTagsOperationAction, operation)
+		r.log.Info("Updated the managed network tags of the instance", "changes", changes)
+		r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, managedTagsUpdatedEventReason,
+			"Updated the managed network tags of the instance: %s", strings.Join(changes, ", "))
+		// The drift detection that follows compares the tags being set.
+		instance.Tags = &compute.Tags{Items: tags}
+	}
+
+	managed := make([]string, 0, len(r.managedTags))
+	for _, tag := range r.managedTags {
+		managed = append(managed, tag.tag)
+	}
+	if len(managed) == 0 {
+		delete(r.machine.Annotations, managedTagsAnnotation)
+	} else {
+		if r.machine.Annotations == nil {
+			r.machine.Annotations = map[string]string{}
+		}
+		sort.Strings(managed)
+		r.machine.Annotations[managedTagsAnnotation] = strings.Join(managed, ",")
+	}
+
+	for _, tag := range r.managedTags {
+		if tag.firewallRule == "" {
+			continue
+		}
+		if err := r.ensureFirewallRuleTargetsTag(tag.firewallRule, tag.tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureFirewallRuleTargetsTag adds the tag to the target tags of the firewall rule, in the project
+// of the network of the primary network interface, the host project of a shared VPC. Tags are never
+// removed from firewall rules, other machines may still have them.
+func (r *Reconciler) ensureFirewallRuleTargetsTag(name, tag string) error {
+	project := r.projectID
+	if len(r.providerSpec.NetworkInterfaces) > 0 {
+		project = r.networkProject(r.providerSpec.NetworkInterfaces[0])
+	}
+
+	firewall, err := r.computeService.FirewallsGet(project, name)
+	if err != nil {
+		if isNotFoundError(err) {
+			r.eventRecorder.Eventf(r.machine, corev1.EventTypeWarning, firewallRuleNotFoundEventReason,
+				"Firewall rule %s of managed tag %s does not exist in project %s", name, tag, project)
+			return nil
+		}
+		return fmt.Errorf("failed to get firewall rule %s of project %s: %w", name, project, err)
+	}
+	if containsString(firewall.TargetTags, tag) {
+		return nil
+	}
+
+	targetTags := append(append([]string{}, firewall.TargetTags...), tag)
+	if _, err := r.computeService.FirewallsPatch(project, name, &compute.Firewall{TargetTags: targetTags}); err != nil {
+		return fmt.Errorf("failed to add tag %s to firewall rule %s of project %s: %w", tag, name, project, err)
+	}
+	r.log.Info("Added the managed tag to the target tags of the firewall rule", "tag", tag, "firewallRule", name)
+	r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, firewallRuleTaggedEventReason,
+		"Added managed tag %s to the target tags of firewall rule %s", tag, name)
+	return nil
+}
+
+// splitManagedTags returns the tags recorded in the managedTagsAnnotation.
+func splitManagedTags(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,196 @@
+package machine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestParseManagedTags(t *testing.T) {
+	managedTags, err := ParseManagedTags("{{.ClusterID}}-{{.Role}}={{.ClusterID}}-{{.Role}}-ingress, Monitored_{{.Role}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+		Name:   "worker-a",
+		Labels: map[string]string{machinev1.MachineClusterIDLabel: "mycluster-abc12", openshiftMachineRoleLabel: "worker"},
+	}}
+	rendered, err := renderManagedTags(managedTags, machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []managedTag{
+		{tag: "mycluster-abc12-worker", firewallRule: "mycluster-abc12-worker-ingress"},
+		{tag: "monitored-worker"},
+	}
+	if len(rendered) != len(expected) || rendered[0] != expected[0] || rendered[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, rendered)
+	}
+
+	for _, text := range []string{"{{.Role", "{{.Zone}}", "worker={{if false}}x{{end}}"} {
+		if _, err := ParseManagedTags(text); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
+
+func TestReconcileManagedTags(t *testing.T) {
+	cases := []struct {
+		name                string
+		instanceTags        []string
+		previous            string
+		managedTags         []managedTag
+		firewall            *compute.Firewall
+		expectedTags        []string
+		expectedAnnotation  string
+		expectedTargetTags  []string
+		expectedEventReason string
+	}{
+		{
+			name:         "Nothing managed",
+			instanceTags: []string{"worker", "ssh"},
+		},
+		{
+			name:                "Missing managed tag is added",
+			instanceTags:        []string{"worker", "ssh"},
+			managedTags:         []managedTag{{tag: "mycluster-worker"}},
+			expectedTags:        []string{"worker", "ssh", "mycluster-worker"},
+			expectedAnnotation:  "mycluster-worker",
+			expectedEventReason: managedTagsUpdatedEventReason,
+		},
+		{
+			name:               "Managed tag present",
+			instanceTags:       []string{"worker", "mycluster-worker"},
+			managedTags:        []managedTag{{tag: "mycluster-worker"}},
+			expectedAnnotation: "mycluster-worker",
+		},
+		{
+			name:                "Rotated tag is replaced",
+			instanceTags:        []string{"worker", "ssh", "mycluster-worker"},
+			previous:            "mycluster-worker",
+			managedTags:         []managedTag{{tag: "mycluster-worker-v2"}},
+			expectedTags:        []string{"worker", "ssh", "mycluster-worker-v2"},
+			expectedAnnotation:  "mycluster-worker-v2",
+			expectedEventReason: managedTagsUpdatedEventReason,
+		},
+		{
+			name:                "Tags no longer managed are removed",
+			instanceTags:        []string{"worker", "mycluster-worker"},
+			previous:            "mycluster-worker",
+			expectedTags:        []string{"worker"},
+			expectedEventReason: managedTagsUpdatedEventReason,
+		},
+		{
+			name:               "Tags no longer managed are kept if in the providerSpec",
+			instanceTags:       []string{"worker", "mycluster-worker"},
+			previous:           "worker,mycluster-worker",
+			managedTags:        []managedTag{{tag: "mycluster-worker"}},
+			expectedAnnotation: "mycluster-worker",
+		},
+		{
+			name:                "Firewall rule is made to target the tag",
+			instanceTags:        []string{"worker", "mycluster-worker"},
+			managedTags:         []managedTag{{tag: "mycluster-worker", firewallRule: "mycluster-worker-ingress"}},
+			firewall:            &compute.Firewall{Name: "mycluster-worker-ingress", TargetTags: []string{"mycluster-master"}},
+			expectedAnnotation:  "mycluster-worker",
+			expectedTargetTags:  []string{"mycluster-master", "mycluster-worker"},
+			expectedEventReason: firewallRuleTaggedEventReason,
+		},
+		{
+			name:               "Firewall rule targeting the tag",
+			instanceTags:       []string{"worker", "mycluster-worker"},
+			managedTags:        []managedTag{{tag: "mycluster-worker", firewallRule: "mycluster-worker-ingress"}},
+			firewall:           &compute.Firewall{Name: "mycluster-worker-ingress", TargetTags: []string{"mycluster-worker"}},
+			expectedAnnotation: "mycluster-worker",
+		},
+		{
+			name:                "Firewall rule does not exist",
+			instanceTags:        []string{"worker", "mycluster-worker"},
+			managedTags:         []managedTag{{tag: "mycluster-worker", firewallRule: "mycluster-worker-ingress"}},
+			expectedAnnotation:  "mycluster-worker",
+			expectedEventReason: firewallRuleNotFoundEventReason,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			var setTags []string
+			mockComputeService.MockSetTags = func(_, _, _ string, tags *compute.Tags) (*compute.Operation, error) {
+				if tags.Fingerprint != "fingerprint" {
+					t.Errorf("Expected the fingerprint of the instance tags, got %q", tags.Fingerprint)
+				}
+				setTags = tags.Items
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			if tc.firewall != nil {
+				mockComputeService.MockFirewallsGet = func(project string, _ string) (*compute.Firewall, error) {
+					if project != "host-project" {
+						t.Errorf("Expected the firewall rule of the network project, got %s", project)
+					}
+					return tc.firewall, nil
+				}
+			}
+			var targetTags []string
+			mockComputeService.MockFirewallsPatch = func(_ string, _ string, firewall *compute.Firewall) (*compute.Operation, error) {
+				targetTags = firewall.TargetTags
+				return nil, nil
+			}
+
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-a"}}
+			if tc.previous != "" {
+				machine.Annotations = map[string]string{managedTagsAnnotation: tc.previous}
+			}
+			recorder := record.NewFakeRecorder(10)
+			r := newReconciler(&machineScope{
+				Context: context.Background(),
+				machine: machine,
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Tags:              []string{"worker"},
+					NetworkInterfaces: []*machinev1.GCPNetworkInterface{{ProjectID: "host-project", Network: "network"}},
+				},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+				eventRecorder:  recorder,
+				managedTags:    tc.managedTags,
+			})
+			instance := &compute.Instance{Name: "worker-a", Tags: &compute.Tags{Items: tc.instanceTags, Fingerprint: "fingerprint"}}
+
+			if err := r.reconcileManagedTags(instance); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if strings.Join(setTags, ",") != strings.Join(tc.expectedTags, ",") {
+				t.Errorf("Expected tags %v to be set, got %v", tc.expectedTags, setTags)
+			}
+			if annotation := r.machine.Annotations[managedTagsAnnotation]; annotation != tc.expectedAnnotation {
+				t.Errorf("Expected annotation %q, got %q", tc.expectedAnnotation, annotation)
+			}
+			if strings.Join(targetTags, ",") != strings.Join(tc.expectedTargetTags, ",") {
+				t.Errorf("Expected target tags %v, got %v", tc.expectedTargetTags, targetTags)
+			}
+			var reason string
+			select {
+			case event := <-recorder.Events:
+				reason = strings.Fields(event)[1]
+			default:
+			}
+			if reason != tc.expectedEventReason {
+				t.Errorf("Expected event %q, got %q", tc.expectedEventReason, reason)
+			}
+			if d, ok := tagsDrift(instance, r.desiredTags()); ok {
+				for _, managed := range tc.managedTags {
+					if strings.Contains(d.details, managed.tag) {
+						t.Errorf("Expected managed tag %s not to be reported as drift, got %v", managed.tag, d)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package machine
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// deprecatedFieldInUse reports, per machine, which deprecated providerSpec fields are set
+	// so that fleets can track their migration away from them.
+	deprecatedFieldInUse = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mapi_gcp_machine_deprecated_field_in_use",
+			Help: "Set to 1 for every deprecated providerSpec field a GCP machine uses",
+		}, []string{"name", "namespace", "field"},
+	)
+
+	// existsChecksTotal counts the existence checks of machines by whether the instance was
+	// looked up in the cloud or a recent verification was trusted.
+	existsChecksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_gcp_machine_exists_checks_total",
+			Help: "Number of existence checks of GCP machines, by whether the cloud was called (verified) or not (cached)",
+		}, []string{"result"},
+	)
+
+	// instanceSyncsTotal counts the rounds of the instance sync by result.
+	instanceSyncsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_gcp_instance_syncs_total",
+			Help: "Number of aggregated instance lists of all GCP machines, by result (succeeded or failed)",
+		}, []string{"result"},
+	)
+
+	// instanceCreationsTotal counts the attempts to create an instance by zone, machine type and
+	// outcome, so that the provisioning success rate, e.g. the stock-out rate of a machine type in a
+	// zone, can be computed with PromQL.
+	instanceCreationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_gcp_instance_creations_total",
+			Help: "Number of attempts to create GCP instances, by zone, machine type, result (succeeded or failed) and reason of failures",
+		}, []string{"zone", "machine_type", "result", "reason"},
+	)
+
+	// orphanInstancesTotal counts the instances of the cluster without a machine found by the
+	// instance sync, by what was done about them.
+	orphanInstancesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_gcp_orphan_instances_total",
+			Help: "Number of GCP instances labelled with the cluster ID without a machine found by the instance sync, by action (reported, deleted or failed)",
+		}, []string{"action"},
+	)
+
+	// credentialsValid reports, per credentials secret and project, whether GCP accepted the
+	// credentials at the last credentials check.
+	credentialsValid = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mapi_gcp_credentials_valid",
+			Help: "Set to 1 when GCP accepted the credentials of a credentials secret at the last credentials check, 0 when it rejected them",
+		}, []string{"namespace", "secret", "project"},
+	)
+
+	// instancesMissingTotal counts the machines whose instance the instance sync found missing.
+	instancesMissingTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mapi_gcp_instance_sync_missing_instances_total",
+			Help: "Number of GCP machines whose instance was found missing from the cloud by the instance sync",
+		},
+	)
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(deprecatedFieldInUse, existsChecksTotal, instanceSyncsTotal, instancesMissingTotal, instanceCreationsTotal, orphanInstancesTotal, credentialsValid)
+}
+
+// recordInstanceCreation counts an attempt to create an instance, reason is empty if it succeeded.
+func recordInstanceCreation(zone, machineType, reason string) {
+	result := "succeeded"
+	if reason != "" {
+		result = "failed"
+	}
+	instanceCreationsTotal.WithLabelValues(zone, machineType, result, reason).Inc()
+}
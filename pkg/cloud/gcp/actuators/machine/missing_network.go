@@ -0,0 +1,75 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// MissingNetworkPolicy is what the reconciler does about machines whose providerSpec has no network interfaces.
+type MissingNetworkPolicy string
+
+const (
+	// MissingNetworkPolicyOmit creates the instances without network interfaces, leaving it to
+	// instances.insert to accept or reject them.
+	MissingNetworkPolicyOmit MissingNetworkPolicy = "omit"
+	// MissingNetworkPolicyProjectDefault attaches the instances to the default network of the project
+	// and to its subnetwork in the region of the machine.
+	MissingNetworkPolicyProjectDefault MissingNetworkPolicy = "project-default"
+	// MissingNetworkPolicyReject refuses to create the instances.
+	MissingNetworkPolicyReject MissingNetworkPolicy = "reject"
+
+	networkInterfacesMissingReason = "NetworkInterfacesMissing"
+	defaultNetworkUsedEvent        = "DefaultNetworkUsed"
+
+	// projectDefaultNetwork is the name of the network GCP creates in new projects.
+	projectDefaultNetwork = "default"
+)
+
+// ParseMissingNetworkPolicy returns the policy of the given name.
+func ParseMissingNetworkPolicy(name string) (MissingNetworkPolicy, error) {
+	switch policy := MissingNetworkPolicy(name); policy {
+	case MissingNetworkPolicyOmit, MissingNetworkPolicyProjectDefault, MissingNetworkPolicyReject:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown missing network policy %q, expected %s, %s or %s", name, MissingNetworkPolicyOmit, MissingNetworkPolicyProjectDefault, MissingNetworkPolicyReject)
+}
+
+// checkNetworkInterfaces refuses to create the instance of a machine without network interfaces
+// when the policy rejects them.
+func (r *Reconciler) checkNetworkInterfaces(_ *preflightState) error {
+	if r.options.MissingNetworkPolicy != MissingNetworkPolicyReject || len(r.providerSpec.NetworkInterfaces) > 0 {
+		return nil
+	}
+	return &preflightError{
+		reason: networkInterfacesMissingReason,
+		err:    machinecontroller.InvalidMachineConfiguration("providerSpec has no network interfaces"),
+	}
+}
+
+// projectDefaultNetworkInterface returns the network interface of an instance attached to the
+// default network of the project, for machines without network interfaces. The subnetwork is the
+// one of the network in the region of the machine.
+func (r *Reconciler) projectDefaultNetworkInterface() (*compute.NetworkInterface, error) {
+	network, err := r.computeService.NetworksGet(r.projectID, projectDefaultNetwork)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, machinecontroller.InvalidMachineConfiguration("providerSpec has no network interfaces and project %s has no %s network", r.projectID, projectDefaultNetwork)
+		}
+		return nil, fmt.Errorf("failed to get network %s of project %s: %w", projectDefaultNetwork, r.projectID, err)
+	}
+
+	regionPath := fmt.Sprintf("/regions/%s/subnetworks/", r.providerSpec.Region)
+	for _, subnetwork := range network.Subnetworks {
+		if !strings.Contains(subnetwork, regionPath) {
+			continue
+		}
+		r.log.Info("ProviderSpec has no network interfaces, using the network of the project", "network", network.Name, "subnetwork", subnetwork)
+		r.eventRecorder.Eventf(r.machine, corev1.EventTypeWarning, defaultNetworkUsedEvent, "providerSpec has no network interfaces, the instance is attached to network %s and subnetwork %s", network.SelfLink, subnetwork)
+		return &compute.NetworkInterface{Network: network.SelfLink, Subnetwork: subnetwork}, nil
+	}
+	return nil, machinecontroller.InvalidMachineConfiguration("providerSpec has no network interfaces and network %s of project %s has no subnetwork in region %s", projectDefaultNetwork, r.projectID, r.providerSpec.Region)
+}
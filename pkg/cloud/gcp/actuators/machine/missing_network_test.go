@@ -0,0 +1,143 @@
+package machine
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMissingNetworkPolicy(t *testing.T) {
+	defaultNetwork := &compute.Network{
+		Name:     "default",
+		SelfLink: "https://www.googleapis.com/compute/v1/projects/testProject/global/networks/default",
+		Subnetworks: []string{
+			"https://www.googleapis.com/compute/v1/projects/testProject/regions/us-west1/subnetworks/default",
+			"https://www.googleapis.com/compute/v1/projects/testProject/regions/us-east1/subnetworks/default",
+		},
+	}
+	cases := []struct {
+		name              string
+		policy            MissingNetworkPolicy
+		networkInterfaces []*machinev1.GCPNetworkInterface
+		network           *compute.Network
+		expectedNetwork   string
+		expectedSubnet    string
+		expectedInvalid   bool
+	}{
+		{
+			name:   "Instance without network interfaces is inserted as is",
+			policy: MissingNetworkPolicyOmit,
+		},
+		{
+			name:              "Network interfaces of the providerSpec are used",
+			policy:            MissingNetworkPolicyProjectDefault,
+			networkInterfaces: []*machinev1.GCPNetworkInterface{{Network: "custom", Subnetwork: "custom-subnet"}},
+			expectedNetwork:   "projects/testProject/global/networks/custom",
+			expectedSubnet:    "projects/testProject/regions/us-east1/subnetworks/custom-subnet",
+		},
+		{
+			name:            "Default network of the project is used",
+			policy:          MissingNetworkPolicyProjectDefault,
+			network:         defaultNetwork,
+			expectedNetwork: defaultNetwork.SelfLink,
+			expectedSubnet:  defaultNetwork.Subnetworks[1],
+		},
+		{
+			name:            "Project without a default network",
+			policy:          MissingNetworkPolicyProjectDefault,
+			expectedInvalid: true,
+		},
+		{
+			name:            "Default network without a subnetwork in the region",
+			policy:          MissingNetworkPolicyProjectDefault,
+			network:         &compute.Network{Name: "default", Subnetworks: defaultNetwork.Subnetworks[:1]},
+			expectedInvalid: true,
+		},
+		{
+			name:            "Instance without network interfaces is rejected",
+			policy:          MissingNetworkPolicyReject,
+			expectedInvalid: true,
+		},
+	}
+
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status: configv1.InfrastructureStatus{
+			InfrastructureName: "test-748kjf",
+			PlatformStatus:     &configv1.PlatformStatus{Type: configv1.GCPPlatformType, GCP: &configv1.GCPPlatformStatus{}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var inserted *compute.Instance
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockNetworksGet = func(project string, network string) (*compute.Network, error) {
+				if tc.network == nil || network != "default" {
+					return nil, &googleapi.Error{Code: http.StatusNotFound}
+				}
+				return tc.network, nil
+			}
+			mockComputeService.MockInstancesInsert = func(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
+				inserted = instance
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+					Name:      "worker-a",
+					Namespace: "openshift-machine-api",
+					Labels:    map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+				}},
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:              "us-east1-b",
+					Region:            "us-east1",
+					MachineType:       "n1-standard-4",
+					NetworkInterfaces: tc.networkInterfaces,
+				},
+				coreClient:     controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(infra.DeepCopy()).Build(),
+				projectID:      "testProject",
+				featureGates:   featuregates.NewFeatureGate(nil, []configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}),
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+				eventRecorder:  record.NewFakeRecorder(10),
+				options:        Options{MissingNetworkPolicy: tc.policy},
+			})
+
+			err := r.create()
+			var machineErr *machinecontroller.MachineError
+			if invalid := errors.As(err, &machineErr); invalid != tc.expectedInvalid {
+				t.Fatalf("Expected an invalid configuration %v, got %v", tc.expectedInvalid, err)
+			}
+			if tc.expectedInvalid {
+				if inserted != nil {
+					t.Errorf("Expected no instance to be inserted")
+				}
+				return
+			}
+			if inserted == nil {
+				t.Fatalf("Expected the instance to be inserted, got %v", err)
+			}
+			if tc.expectedNetwork == "" {
+				if len(inserted.NetworkInterfaces) != 0 {
+					t.Errorf("Expected no network interfaces, got %+v", inserted.NetworkInterfaces[0])
+				}
+				return
+			}
+			if len(inserted.NetworkInterfaces) != 1 || inserted.NetworkInterfaces[0].Network != tc.expectedNetwork || inserted.NetworkInterfaces[0].Subnetwork != tc.expectedSubnet {
+				t.Errorf("Expected network %s and subnetwork %s, got %+v", tc.expectedNetwork, tc.expectedSubnet, inserted.NetworkInterfaces)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package machine
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	networkEndpointGroupLinkFmt = "https://www.googleapis.com/compute/v1/projects/%s/zones/%s/networkEndpointGroups/%s"
+	// gceVMIPEndpointType is the type of the network endpoint groups backing internal passthrough
+	// load balancers, whose endpoints are instances.
+	gceVMIPEndpointType = "GCE_VM_IP"
+
+	// maxDeregistrationWait bounds how long the deletion of a control plane machine waits, after the
+	// connection draining timeout of the backend service, for the health check to stop reporting the
+	// detached instance.
+	maxDeregistrationWait = 5 * time.Minute
+
+	networkEndpointDetachedEvent = "NetworkEndpointDetached"
+)
+
+// controlPlaneEndpointGroupName returns the network endpoint group control plane machines of the
+// zone are attached to, or an empty string when they are registered with the instance group.
+func (r *Reconciler) controlPlaneEndpointGroupName() string {
+	if r.loadBalancerConfig == nil {
+		return ""
+	}
+	return r.loadBalancerConfig.networkEndpointGroupNames[r.providerSpec.Zone]
+}
+
+func (r *Reconciler) networkEndpointGroupLink(name string) string {
+	return fmt.Sprintf(networkEndpointGroupLinkFmt, r.projectID, r.providerSpec.Zone, name)
+}
+
+// ensureNetworkEndpointGroup ensures that the network endpoint group exists and is a backend of the
+// backend service, and returns whether it is.
+func (r *Reconciler) ensureNetworkEndpointGroup(name string) (bool, error) {
+	_, err := r.computeService.NetworkEndpointGroupGet(r.projectID, r.providerSpec.Zone, name)
+	if isNotFoundError(err) {
+		nic := r.controlPlaneNetworkInterface()
+		_, err = r.computeService.NetworkEndpointGroupInsert(r.projectID, r.providerSpec.Zone, &compute.NetworkEndpointGroup{
+			Name:                name,
+			NetworkEndpointType: gceVMIPEndpointType,
+			Network:             r.networkURL(nic),
+			Subnetwork:          r.subnetworkURL(nic),
+		})
+		if err != nil {
+			return false, fmt.Errorf("networkEndpointGroupInsert request failed: %w", err)
+		}
+	} else if err != nil {
+		return false, fmt.Errorf("networkEndpointGroupGet request failed: %w", err)
+	}
+
+	backendServiceName := r.backendServiceName()
+	backendService, err := r.computeService.BackendServiceGet(r.projectID, r.providerSpec.Region, backendServiceName)
+	if err != nil {
+		return false, fmt.Errorf("backendServiceGet request failed: %v", err)
+	}
+	link := r.networkEndpointGroupLink(name)
+	for _, backend := range backendService.Backends {
+		if backend.Group == link {
+			return true, nil
+		}
+	}
+	// Internal passthrough load balancers only support the CONNECTION balancing mode.
+	backendService.Backends = append(backendService.Backends, &compute.Backend{
+		BalancingMode: "CONNECTION",
+		Group:         link,
+	})
+	if _, err := r.computeService.AddInstanceGroupToBackendService(r.projectID, r.providerSpec.Region, backendServiceName, backendService); err != nil {
+		return false, fmt.Errorf("failed to add network endpoint group %s to backend service %s: %v", name, backendServiceName, err)
+	}
+	return false, nil
+}
+
+// isAttachedToNetworkEndpointGroup returns true if the instance is an endpoint of the network endpoint group.
+func (r *Reconciler) isAttachedToNetworkEndpointGroup(name string) (bool, error) {
+	endpoints, err := r.computeService.NetworkEndpointGroupsListNetworkEndpoints(r.projectID, r.providerSpec.Zone, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to list the endpoints of network endpoint group %s: %w", name, err)
+	}
+	for _, endpoint := range endpoints {
+		if endpoint != nil && path.Base(endpoint.Instance) == r.instanceName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// registerInstanceToNetworkEndpointGroup attaches the instance of a control plane machine to the
+// network endpoint group of its zone, which backs the backend service of the internal API load
+// balancer directly.
+func (r *Reconciler) registerInstanceToNetworkEndpointGroup(name string) error {
+	inBackendService, err := r.ensureNetworkEndpointGroup(name)
+	if err != nil {
+		return fmt.Errorf("failed to ensure that network endpoint group %s backs the backend service: %v", name, err)
+	}
+	if inBackendService {
+		r.loadBalancerMembership.recordGroupBackendService(r.backendServiceName())
+	}
+
+	attached, err := r.isAttachedToNetworkEndpointGroup(name)
+	if err != nil {
+		return err
+	}
+	if attached {
+		r.loadBalancerMembership.recordNetworkEndpointGroup(name)
+		return nil
+	}
+	if pointer.StringDeref(r.providerStatus.InstanceState, "") != "RUNNING" {
+		return nil
+	}
+
+	r.log.V(logLevelDetail).Info("Attaching instance to network endpoint group", "networkEndpointGroup", name)
+	operation, err := r.computeService.NetworkEndpointGroupsAttachNetworkEndpoints(r.projectID, r.providerSpec.Zone, name, []*compute.NetworkEndpoint{{Instance: r.instanceName}})
+	if err != nil {
+		return fmt.Errorf("networkEndpointGroupsAttachNetworkEndpoints request failed: %v", err)
+	}
+	r.trackOperation(attachEndpointOperationAction, operation)
+	return nil
+}
+
+// deregisterInstanceFromNetworkEndpointGroup detaches the instance of a control plane machine from
+// the network endpoint group of its zone and waits until the load balancer stopped sending it new
+// connections: until the connection draining timeout of the backend service elapsed and its health
+// check no longer reports the instance. It returns nil once the instance may be deleted and a
+// requeue error while the instance is draining.
+func (r *Reconciler) deregisterInstanceFromNetworkEndpointGroup(name string) error {
+	attached, err := r.isAttachedToNetworkEndpointGroup(name)
+	if err != nil {
+		return err
+	}
+	detached, detachRecorded := r.networkEndpointDetached()
+
+	if attached {
+		r.log.Info("Detaching instance from network endpoint group", "networkEndpointGroup", name)
+		if _, err := r.computeService.NetworkEndpointGroupsDetachNetworkEndpoints(r.projectID, r.providerSpec.Zone, name, []*compute.NetworkEndpoint{{Instance: r.instanceName}}); err != nil {
+			return fmt.Errorf("networkEndpointGroupsDetachNetworkEndpoints request failed: %v", err)
+		}
+		if !detachRecorded {
+			if err := r.recordNetworkEndpointDetached(); err != nil {
+				return err
+			}
+			r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, networkEndpointDetachedEvent, "Detached instance %s from network endpoint group %s, draining it before deleting it", r.instanceName, name)
+		}
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+	if !detachRecorded {
+		return nil
+	}
+
+	backendServiceName := r.backendServiceName()
+	backendService, err := r.computeService.BackendServiceGet(r.projectID, r.providerSpec.Region, backendServiceName)
+	if err != nil {
+		return fmt.Errorf("backendServiceGet request failed: %v", err)
+	}
+	var draining time.Duration
+	if backendService.ConnectionDraining != nil {
+		draining = time.Duration(backendService.ConnectionDraining.DrainingTimeoutSec) * time.Second
+	}
+	elapsed := r.clock.Since(detached)
+	if elapsed < draining {
+		r.log.V(logLevelDetail).Info("Draining connections of the instance before deleting it", "remaining", (draining - elapsed).Round(time.Second))
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+
+	health, err := r.computeService.BackendServiceGetHealth(r.projectID, r.providerSpec.Region, backendServiceName, r.networkEndpointGroupLink(name))
+	if err != nil {
+		return fmt.Errorf("failed to get the health of backend service %s: %w", backendServiceName, err)
+	}
+	for _, status := range health {
+		if status == nil || path.Base(status.Instance) != r.instanceName {
+			continue
+		}
+		if elapsed >= draining+maxDeregistrationWait {
+			r.log.Info("Health check still reports the instance, deleting it", "backendService", backendServiceName, "healthState", status.HealthState, "elapsed", elapsed.Round(time.Second))
+			return nil
+		}
+		r.log.V(logLevelDetail).Info("Waiting for the health check to stop reporting the instance", "backendService", backendServiceName, "healthState", status.HealthState)
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+	return nil
+}
+
+// networkEndpointDetached returns when the instance was detached from its network endpoint group, if it was.
+func (r *Reconciler) networkEndpointDetached() (time.Time, bool) {
+	value, ok := r.getAnnotation(networkEndpointDetachedAnnotation)
+	if !ok {
+		return time.Time{}, false
+	}
+	detached, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		r.log.Info("Ignoring invalid annotation value", "annotation", networkEndpointDetachedAnnotation, "value", value)
+		return time.Time{}, false
+	}
+	return detached, true
+}
+
+// recordNetworkEndpointDetached patches the machine right away, the scope does not persist the
+// machine after a delete.
+func (r *Reconciler) recordNetworkEndpointDetached() error {
+	patchBase := controllerclient.MergeFrom(r.machine.DeepCopy())
+	if r.machine.Annotations == nil {
+		r.machine.Annotations = map[string]string{}
+	}
+	r.machine.Annotations[networkEndpointDetachedAnnotation] = r.clock.Now().UTC().Format(time.RFC3339)
+	if err := r.coreClient.Patch(r.Context, r.machine, patchBase); err != nil {
+		return fmt.Errorf("failed to record the detachment from the network endpoint group: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testEndpointGroupLink = "https://www.googleapis.com/compute/v1/projects/testProject/zones/zone1/networkEndpointGroups/CLUSTERID-api-zone1"
+
+func newNetworkEndpointsReconciler(mockComputeService *computeservice.GCPComputeServiceMock, annotations map[string]string, now time.Time) (*Reconciler, controllerclient.Client) {
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "testInstance",
+			Namespace:   "openshift-machine-api",
+			Annotations: annotations,
+			Labels: map[string]string{
+				openshiftMachineRoleLabel:       masterMachineRole,
+				machinev1.MachineClusterIDLabel: "CLUSTERID",
+			},
+		},
+	}
+	coreClient := controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine.DeepCopy()).Build()
+	r := newReconciler(&machineScope{
+		Context:    context.Background(),
+		coreClient: coreClient,
+		machine:    machine,
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Zone:   "zone1",
+			Region: "region1",
+			NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+				{Network: "CLUSTERID-network", Subnetwork: "CLUSTERID-master-subnet"},
+			},
+		},
+		projectID: "testProject",
+		providerStatus: &machinev1.GCPMachineProviderStatus{
+			InstanceState: pointer.String("RUNNING"),
+		},
+		computeService: mockComputeService,
+		clock:          clocktesting.NewFakeClock(now),
+		eventRecorder:  record.NewFakeRecorder(2),
+	})
+	r.loadBalancerConfig = &loadBalancerConfig{networkEndpointGroupNames: map[string]string{"zone1": "CLUSTERID-api-zone1"}}
+	return r, coreClient
+}
+
+func TestRegisterInstanceToNetworkEndpointGroup(t *testing.T) {
+	cases := []struct {
+		name                   string
+		endpoints              []*compute.NetworkEndpoint
+		backends               []*compute.Backend
+		negNotFound            bool
+		expectedInsert         bool
+		expectedBackendsUpdate bool
+		expectedAttach         bool
+		expectedMessage        string
+	}{
+		{
+			name:            "Attached instance is left alone",
+			endpoints:       []*compute.NetworkEndpoint{{Instance: "testInstance"}},
+			backends:        []*compute.Backend{{Group: testEndpointGroupLink}},
+			expectedMessage: "target pools: none; instance groups: none; network endpoint groups: CLUSTERID-api-zone1; backend services: CLUSTERID-api-internal",
+		},
+		{
+			name:            "Instance is attached",
+			endpoints:       []*compute.NetworkEndpoint{{Instance: "otherInstance"}},
+			backends:        []*compute.Backend{{Group: testEndpointGroupLink}},
+			expectedAttach:  true,
+			expectedMessage: "target pools: none; instance groups: none; network endpoint groups: none; backend services: none",
+		},
+		{
+			name:                   "Missing group is created and added to the backend service",
+			negNotFound:            true,
+			expectedInsert:         true,
+			expectedBackendsUpdate: true,
+			expectedAttach:         true,
+			expectedMessage:        "target pools: none; instance groups: none; network endpoint groups: none; backend services: none",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			var inserted *compute.NetworkEndpointGroup
+			var updatedBackends []*compute.Backend
+			var attached []*compute.NetworkEndpoint
+			if tc.negNotFound {
+				mockComputeService.MockNEGGet = func(project string, zone string, name string) (*compute.NetworkEndpointGroup, error) {
+					return nil, &googleapi.Error{Code: http.StatusNotFound}
+				}
+			}
+			mockComputeService.MockNEGInsert = func(project string, zone string, networkEndpointGroup *compute.NetworkEndpointGroup) (*compute.Operation, error) {
+				inserted = networkEndpointGroup
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockNEGListEndpoints = func(project string, zone string, name string) ([]*compute.NetworkEndpoint, error) {
+				return tc.endpoints, nil
+			}
+			mockComputeService.MockNEGAttachEndpoints = func(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+				attached = endpoints
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockBackendServiceGet = func(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+				return &compute.BackendService{Name: backendServiceName, Backends: tc.backends}, nil
+			}
+			computeService := &backendServiceUpdateTrackingComputeService{GCPComputeServiceMock: mockComputeService, updated: &updatedBackends}
+			r, _ := newNetworkEndpointsReconciler(mockComputeService, nil, time.Now())
+			r.computeService = computeService
+			r.loadBalancerMembership = &loadBalancerMembership{}
+
+			if err := r.registerInstanceToNetworkEndpointGroup("CLUSTERID-api-zone1"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if (inserted != nil) != tc.expectedInsert {
+				t.Errorf("Expected the group to be created %v, got %+v", tc.expectedInsert, inserted)
+			}
+			if inserted != nil && (inserted.NetworkEndpointType != gceVMIPEndpointType || inserted.Network != "projects/testProject/global/networks/CLUSTERID-network") {
+				t.Errorf("Unexpected group %+v", inserted)
+			}
+			if backendsUpdated := updatedBackends != nil; backendsUpdated != tc.expectedBackendsUpdate {
+				t.Errorf("Expected the backends to be updated %v, got %v", tc.expectedBackendsUpdate, updatedBackends)
+			}
+			if len(updatedBackends) > 0 && (updatedBackends[0].Group != testEndpointGroupLink || updatedBackends[0].BalancingMode != "CONNECTION") {
+				t.Errorf("Unexpected backend %+v", updatedBackends[0])
+			}
+			if (attached != nil) != tc.expectedAttach {
+				t.Errorf("Expected the instance to be attached %v, got %v", tc.expectedAttach, attached)
+			}
+			if len(attached) > 0 && attached[0].Instance != "testInstance" {
+				t.Errorf("Expected the instance to be attached, got %+v", attached[0])
+			}
+			if message := r.loadBalancerMembership.String(); message != tc.expectedMessage {
+				t.Errorf("Expected membership %q, got %q", tc.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func TestDeregisterInstanceFromNetworkEndpointGroup(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name             string
+		detached         string
+		attached         bool
+		health           []*compute.HealthStatus
+		expectedDetach   bool
+		expectedRequeue  bool
+		expectedDetached string
+	}{
+		{
+			name: "Instance that is not attached is deleted",
+		},
+		{
+			name:             "Attached instance is detached",
+			attached:         true,
+			expectedDetach:   true,
+			expectedRequeue:  true,
+			expectedDetached: "2024-01-01T12:00:00Z",
+		},
+		{
+			name:             "Connections are drained",
+			detached:         "2024-01-01T11:59:30Z",
+			expectedRequeue:  true,
+			expectedDetached: "2024-01-01T11:59:30Z",
+		},
+		{
+			name:             "Instance still reported by the health check is waited for",
+			detached:         "2024-01-01T11:58:00Z",
+			health:           []*compute.HealthStatus{{Instance: "https://www.googleapis.com/compute/v1/projects/testProject/zones/zone1/instances/testInstance", HealthState: "HEALTHY"}},
+			expectedRequeue:  true,
+			expectedDetached: "2024-01-01T11:58:00Z",
+		},
+		{
+			name:             "Drained instance is deleted",
+			detached:         "2024-01-01T11:58:00Z",
+			health:           []*compute.HealthStatus{{Instance: "https://www.googleapis.com/compute/v1/projects/testProject/zones/zone1/instances/otherInstance", HealthState: "HEALTHY"}},
+			expectedDetached: "2024-01-01T11:58:00Z",
+		},
+		{
+			name:             "Instance is deleted once the health check was waited for long enough",
+			detached:         "2024-01-01T11:50:00Z",
+			health:           []*compute.HealthStatus{{Instance: "https://www.googleapis.com/compute/v1/projects/testProject/zones/zone1/instances/testInstance", HealthState: "HEALTHY"}},
+			expectedDetached: "2024-01-01T11:50:00Z",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			detached := false
+			mockComputeService.MockNEGListEndpoints = func(project string, zone string, name string) ([]*compute.NetworkEndpoint, error) {
+				if tc.attached {
+					return []*compute.NetworkEndpoint{{Instance: "testInstance"}}, nil
+				}
+				return nil, nil
+			}
+			mockComputeService.MockNEGDetachEndpoints = func(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+				detached = true
+				return &compute.Operation{Status: "RUNNING"}, nil
+			}
+			mockComputeService.MockBackendServiceGet = func(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+				return &compute.BackendService{ConnectionDraining: &compute.ConnectionDraining{DrainingTimeoutSec: 60}}, nil
+			}
+			mockComputeService.MockBackendServiceHealth = func(project string, region string, backendServiceName string, group string) ([]*compute.HealthStatus, error) {
+				if group != testEndpointGroupLink {
+					t.Errorf("Unexpected group %s", group)
+				}
+				return tc.health, nil
+			}
+			annotations := map[string]string{}
+			if tc.detached != "" {
+				annotations[networkEndpointDetachedAnnotation] = tc.detached
+			}
+			r, coreClient := newNetworkEndpointsReconciler(mockComputeService, annotations, now)
+
+			err := r.deregisterInstanceFromNetworkEndpointGroup("CLUSTERID-api-zone1")
+			var requeueErr *machinecontroller.RequeueAfterError
+			if requeue := errors.As(err, &requeueErr); requeue != tc.expectedRequeue || (!requeue && err != nil) {
+				t.Fatalf("Expected a requeue %v, got %v", tc.expectedRequeue, err)
+			}
+			if detached != tc.expectedDetach {
+				t.Errorf("Expected the instance to be detached %v, got %v", tc.expectedDetach, detached)
+			}
+			persisted := &machinev1.Machine{}
+			if err := coreClient.Get(context.Background(), controllerclient.ObjectKeyFromObject(r.machine), persisted); err != nil {
+				t.Fatal(err)
+			}
+			if value := persisted.Annotations[networkEndpointDetachedAnnotation]; value != tc.expectedDetached {
+				t.Errorf("Expected the instance to have been detached at %q, got %q", tc.expectedDetached, value)
+			}
+		})
+	}
+}
+
+type backendServiceUpdateTrackingComputeService struct {
+	*computeservice.GCPComputeServiceMock
+	updated *[]*compute.Backend
+}
+
+func (c *backendServiceUpdateTrackingComputeService) AddInstanceGroupToBackendService(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+	*c.updated = backendService.Backends
+	return &compute.Operation{Status: "DONE"}, nil
+}
@@ -0,0 +1,68 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/notifier"
+)
+
+// notifyCreateFailure publishes a failed creation to the failure notifier, if one is configured.
+// Requeues are part of the normal creation flow and are not published, unless they retry a
+// creation that failed because of quota or capacity.
+func (a *Actuator) notifyCreateFailure(ctx context.Context, scope *machineScope, err error) {
+	if a.notifier == nil {
+		return
+	}
+	eventType := createFailureEventType(scope.providerStatus, err)
+	var requeueErr *machinecontroller.RequeueAfterError
+	if errors.As(err, &requeueErr) && eventType == notifier.CreateFailed {
+		return
+	}
+
+	event := notifier.Event{
+		Type:      eventType,
+		Cluster:   scope.machine.Labels[machinev1.MachineClusterIDLabel],
+		Namespace: scope.machine.Namespace,
+		Machine:   scope.machine.Name,
+		Zone:      scope.providerSpec.Zone,
+		Message:   err.Error(),
+	}
+	if err := a.notifier.Notify(ctx, event); err != nil {
+		scope.log.Error(err, "Failed to publish event", "eventType", event.Type)
+	}
+}
+
+// createFailureEventType classifies a failed creation from the reason of the MachineCreated
+// condition set by the pre-flight checks or the error returned by the compute API.
+func createFailureEventType(providerStatus *machinev1.GCPMachineProviderStatus, err error) notifier.EventType {
+	if condition := findCondition(providerStatus.Conditions, string(machinev1.MachineCreated)); condition != nil {
+		switch condition.Reason {
+		case insufficientCPUQuotaReason, insufficientGPUQuotaReason, insufficientSSDQuotaReason:
+			return notifier.QuotaExceeded
+		}
+	}
+
+	reason, _ := gcperrors.Classify(err)
+	switch reason {
+	case gcperrors.QuotaExceeded:
+		return notifier.QuotaExceeded
+	case gcperrors.ZoneResourcesExhausted:
+		return notifier.StockOut
+	default:
+		return notifier.CreateFailed
+	}
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/notifier"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNotifyCreateFailure(t *testing.T) {
+	cases := []struct {
+		name          string
+		conditions    []metav1.Condition
+		err           error
+		expectedEvent notifier.EventType
+	}{
+		{
+			name:          "Generic failure",
+			err:           errors.New("failed to create instance via compute service: boom"),
+			expectedEvent: notifier.CreateFailed,
+		},
+		{
+			name: "Insufficient quota found by the pre-flight checks",
+			conditions: []metav1.Condition{
+				{Type: string(machinev1.MachineCreated), Status: metav1.ConditionFalse, Reason: insufficientCPUQuotaReason},
+			},
+			err:           machinecontroller.InvalidMachineConfiguration("insufficient CPUS quota in region test-region"),
+			expectedEvent: notifier.QuotaExceeded,
+		},
+		{
+			name:          "Quota exceeded reported by the compute API",
+			err:           errors.New("error launching instance: googleapi: Error 403: Quota 'CPUS' exceeded. Limit: 24.0 in region us-east1., quotaExceeded"),
+			expectedEvent: notifier.QuotaExceeded,
+		},
+		{
+			name:          "Zone out of resources",
+			err:           errors.New("error launching instance: googleapi: Error 503: The zone 'us-east1-b' does not have enough resources available to fulfill the request."),
+			expectedEvent: notifier.StockOut,
+		},
+		{
+			name: "Requeues are not published",
+			err:  &machinecontroller.RequeueAfterError{RequeueAfter: time.Second},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockNotifier := &notifier.MockNotifier{}
+			actuator := NewActuator(ActuatorParams{Notifier: mockNotifier})
+			scope := &machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "worker-a",
+						Namespace: "openshift-machine-api",
+						Labels:    map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+					},
+				},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"},
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.conditions},
+			}
+
+			actuator.notifyCreateFailure(context.Background(), scope, tc.err)
+
+			if tc.expectedEvent == "" {
+				if len(mockNotifier.Events) != 0 {
+					t.Errorf("Expected no events, got %+v", mockNotifier.Events)
+				}
+				return
+			}
+			if len(mockNotifier.Events) != 1 {
+				t.Fatalf("Expected one event, got %+v", mockNotifier.Events)
+			}
+			event := mockNotifier.Events[0]
+			if event.Type != tc.expectedEvent {
+				t.Errorf("Expected event type %s, got %s", tc.expectedEvent, event.Type)
+			}
+			if event.Cluster != "CLUSTERID" || event.Machine != "worker-a" || event.Zone != "us-east1-b" || event.Message != tc.err.Error() {
+				t.Errorf("Unexpected event %+v", event)
+			}
+		})
+	}
+}
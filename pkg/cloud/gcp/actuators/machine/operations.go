@@ -0,0 +1,272 @@
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// operationsConditionType reports the outcome of the last zonal or regional operation of the machine
+	// that failed or completed with warnings.
+	operationsConditionType   = "OperationsSucceeded"
+	operationsSucceededReason = "OperationsSucceeded"
+	operationWarningsReason   = "OperationWarnings"
+	operationFailedReason     = "OperationFailed"
+
+	operationDoneStatus      = "DONE"
+	operationPollInterval    = 5 * time.Second
+	maxOperationPollInterval = 2 * time.Minute
+
+	insertOperationAction             = "insert"
+	addToTargetPoolOperationAction    = "addToTargetPool"
+	addToInstanceGroupOperationAction = "addToInstanceGroup"
+	attachEndpointOperationAction     = "attachToNetworkEndpointGroup"
+)
+
+// trackedOperation is a zonal or regional operation started for the machine that did not complete yet.
+type trackedOperation struct {
+	Name string `json:"name"`
+	// Action is what the operation does, e.g. insert, and is used in messages.
+	Action string `json:"action"`
+	Zone   string `json:"zone,omitempty"`
+	Region string `json:"region,omitempty"`
+	// Polls is the number of times the operation was found running, used to back off.
+	Polls int `json:"polls,omitempty"`
+}
+
+// operationFailure is an operation that completed with errors.
+type operationFailure struct {
+	action  string
+	reason  gcperrors.Reason
+	message string
+}
+
+// pendingOperations returns the operations tracked in the pendingOperationsAnnotation.
+func (r *Reconciler) pendingOperations() []trackedOperation {
+	value, ok := r.getAnnotation(pendingOperationsAnnotation)
+	if !ok || value == "" {
+		return nil
+	}
+	var operations []trackedOperation
+	if err := json.Unmarshal([]byte(value), &operations); err != nil {
+		r.log.Error(err, "Ignoring invalid annotation", "annotation", pendingOperationsAnnotation)
+		return nil
+	}
+	return operations
+}
+
+func (r *Reconciler) setPendingOperations(operations []trackedOperation) {
+	if len(operations) == 0 {
+		delete(r.machine.Annotations, pendingOperationsAnnotation)
+		return
+	}
+	data, err := json.Marshal(operations)
+	if err != nil {
+		r.log.Error(err, "Failed to encode pending operations")
+		return
+	}
+	if r.machine.Annotations == nil {
+		r.machine.Annotations = map[string]string{}
+	}
+	r.machine.Annotations[pendingOperationsAnnotation] = string(data)
+}
+
+// trackOperation records an operation returned by the compute API, so that its outcome is checked
+// by the following reconciles instead of being assumed. Global operations are not tracked.
+func (r *Reconciler) trackOperation(action string, operation *compute.Operation) {
+	if operation == nil || operation.Name == "" {
+		return
+	}
+	tracked := trackedOperation{Name: operation.Name, Action: action}
+	switch {
+	case operation.Zone != "":
+		tracked.Zone = path.Base(operation.Zone)
+	case operation.Region != "":
+		tracked.Region = path.Base(operation.Region)
+	default:
+		return
+	}
+	if operation.Status == operationDoneStatus {
+		r.recordOperationResult(tracked, operation)
+		return
+	}
+	r.setPendingOperations(append(r.pendingOperations(), tracked))
+}
+
+// hasPendingOperation returns true if an operation with the given action is still running.
+func (r *Reconciler) hasPendingOperation(action string) bool {
+	for _, tracked := range r.pendingOperations() {
+		if tracked.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileOperations polls the pending operations of the machine. Completed operations are dropped
+// and their errors and warnings recorded in the OperationsSucceeded condition. It returns the
+// operations that failed and how long to wait before polling the operations that are still
+// running, which is zero when none are.
+func (r *Reconciler) reconcileOperations() (time.Duration, []operationFailure, error) {
+	pending := r.pendingOperations()
+	if len(pending) == 0 {
+		return 0, nil, nil
+	}
+
+	var running []trackedOperation
+	var failures []operationFailure
+	var requeueAfter time.Duration
+	for i, tracked := range pending {
+		operation, err := r.getOperation(tracked)
+		if err != nil {
+			if isNotFoundError(err) {
+				r.operationLogger(tracked).Info("Operation no longer exists, no longer tracking it")
+				continue
+			}
+			r.setPendingOperations(append(running, pending[i:]...))
+			return 0, failures, fmt.Errorf("failed to get %s operation %s: %w", tracked.Action, tracked.Name, err)
+		}
+		if operation.Status != operationDoneStatus {
+			tracked.Polls++
+			running = append(running, tracked)
+			if backoff := operationBackoff(tracked.Polls); requeueAfter == 0 || backoff < requeueAfter {
+				requeueAfter = backoff
+			}
+			continue
+		}
+		if failure := r.recordOperationResult(tracked, operation); failure != nil {
+			failures = append(failures, *failure)
+		}
+	}
+	r.setPendingOperations(running)
+	return requeueAfter, failures, nil
+}
+
+func (r *Reconciler) getOperation(tracked trackedOperation) (*compute.Operation, error) {
+	if tracked.Region != "" {
+		return r.computeService.RegionOperationsGet(r.projectID, tracked.Region, tracked.Name)
+	}
+	return r.computeService.ZoneOperationsGet(r.projectID, tracked.Zone, tracked.Name)
+}
+
+// operationBackoff doubles the poll interval with every poll of a running operation.
+func operationBackoff(polls int) time.Duration {
+	backoff := operationPollInterval
+	for i := 1; i < polls && backoff < maxOperationPollInterval; i++ {
+		backoff *= 2
+	}
+	if backoff > maxOperationPollInterval {
+		return maxOperationPollInterval
+	}
+	return backoff
+}
+
+// recordOperationResult reports the errors and warnings of a completed operation in the
+// OperationsSucceeded condition and in events. Operations without either only mark a previously
+// reported condition as succeeded, to not add the condition to every machine.
+func (r *Reconciler) recordOperationResult(tracked trackedOperation, operation *compute.Operation) *operationFailure {
+	errs := operationErrors(operation)
+	r.traceOperation(tracked, operation, errs)
+	var warnings []string
+	for _, warning := range operation.Warnings {
+		warnings = append(warnings, fmt.Sprintf("%s: %s", warning.Code, warning.Message))
+	}
+
+	var failure *operationFailure
+	if len(errs) > 0 {
+		failure = &operationFailure{action: tracked.Action, reason: operationFailedReason}
+		if reason, _, ok := gcperrors.ClassifyOperation(operation); ok {
+			failure.reason = reason
+		}
+	}
+	if tracked.Action == insertOperationAction {
+		var reason string
+		if failure != nil {
+			reason = string(failure.reason)
+		}
+		recordInstanceCreation(tracked.Zone, r.providerSpec.MachineType, reason)
+	}
+
+	switch {
+	case failure != nil:
+		failure.message = fmt.Sprintf("%s operation %s failed: %s", tracked.Action, tracked.Name, strings.Join(errs, "; "))
+		if len(warnings) > 0 {
+			failure.message += fmt.Sprintf(" (warnings: %s)", strings.Join(warnings, "; "))
+		}
+		r.operationLogger(tracked).Info("Operation failed", "reason", failure.reason, "errors", errs, "warnings", warnings)
+		r.eventRecorder.Event(r.machine, corev1.EventTypeWarning, string(failure.reason), failure.message)
+		r.setOperationsCondition(metav1.ConditionFalse, string(failure.reason), failure.message)
+		return failure
+	case len(warnings) > 0:
+		message := fmt.Sprintf("%s operation %s completed with warnings: %s", tracked.Action, tracked.Name, strings.Join(warnings, "; "))
+		r.operationLogger(tracked).Info("Operation completed with warnings", "warnings", warnings)
+		r.eventRecorder.Event(r.machine, corev1.EventTypeWarning, operationWarningsReason, message)
+		r.setOperationsCondition(metav1.ConditionTrue, operationWarningsReason, message)
+	case findCondition(r.providerStatus.Conditions, operationsConditionType) != nil:
+		r.setOperationsCondition(metav1.ConditionTrue, operationsSucceededReason, fmt.Sprintf("%s operation %s succeeded", tracked.Action, tracked.Name))
+	}
+	return nil
+}
+
+// operationLogger returns the logger of the messages about a tracked operation.
+func (r *Reconciler) operationLogger(tracked trackedOperation) logr.Logger {
+	return r.log.WithValues("operation", tracked.Name, "operationAction", tracked.Action, "operationZone", tracked.Zone)
+}
+
+func (r *Reconciler) setOperationsCondition(status metav1.ConditionStatus, reason, message string) {
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    operationsConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// operationErrors returns the errors of a completed operation.
+func operationErrors(operation *compute.Operation) []string {
+	if operation == nil || operation.Error == nil {
+		return nil
+	}
+	var errs []string
+	for _, operationError := range operation.Error.Errors {
+		errs = append(errs, fmt.Sprintf("%s: %s", operationError.Code, operationError.Message))
+	}
+	return errs
+}
+
+// reconcileCreateOperations polls the pending operations before an instance is created. The creation
+// waits for an insert operation that is still running, instead of inserting the instance again,
+// and an insert operation that failed is reported on the MachineCreated condition.
+func (r *Reconciler) reconcileCreateOperations() error {
+	requeueAfter, failures, err := r.reconcileOperations()
+	if err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		if failure.action != insertOperationAction {
+			continue
+		}
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+			Type:    string(machinev1.MachineCreated),
+			Status:  metav1.ConditionFalse,
+			Reason:  string(failure.reason),
+			Message: failure.message,
+		})
+	}
+	if requeueAfter > 0 && r.hasPendingOperation(insertOperationAction) {
+		r.log.V(logLevelDetail).Info("Instance is still being inserted, requeuing", "requeueAfter", requeueAfter)
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfter}
+	}
+	return nil
+}
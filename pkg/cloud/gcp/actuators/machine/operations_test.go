@@ -0,0 +1,223 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileOperations(t *testing.T) {
+	cases := []struct {
+		name                 string
+		pending              string
+		conditions           []metav1.Condition
+		zoneOperation        *compute.Operation
+		zoneOperationError   error
+		regionOperation      *compute.Operation
+		expectedRequeueAfter time.Duration
+		expectedPending      string
+		expectedFailures     int
+		expectedCondition    *metav1.Condition
+	}{
+		{
+			name: "No pending operations",
+		},
+		{
+			name:                 "Running operation is polled with backoff",
+			pending:              `[{"name":"operation-1","action":"insert","zone":"us-east1-b","polls":2}]`,
+			zoneOperation:        &compute.Operation{Status: "RUNNING"},
+			expectedRequeueAfter: 20 * time.Second,
+			expectedPending:      `[{"name":"operation-1","action":"insert","zone":"us-east1-b","polls":3}]`,
+		},
+		{
+			name:          "Successful operation is dropped",
+			pending:       `[{"name":"operation-1","action":"insert","zone":"us-east1-b"}]`,
+			zoneOperation: &compute.Operation{Status: "DONE"},
+		},
+		{
+			name:    "Failed operation is reported",
+			pending: `[{"name":"operation-1","action":"insert","zone":"us-east1-b"}]`,
+			zoneOperation: &compute.Operation{Status: "DONE", Error: &compute.OperationError{Errors: []*compute.OperationErrorErrors{
+				{Code: "QUOTA_EXCEEDED", Message: "Quota 'CPUS' exceeded."},
+			}}},
+			expectedFailures: 1,
+			expectedCondition: &metav1.Condition{
+				Type:    operationsConditionType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "QuotaExceeded",
+				Message: "insert operation operation-1 failed: QUOTA_EXCEEDED: Quota 'CPUS' exceeded.",
+			},
+		},
+		{
+			name:    "Warnings of a regional operation are reported",
+			pending: `[{"name":"operation-2","action":"addToTargetPool","region":"us-east1"}]`,
+			regionOperation: &compute.Operation{Status: "DONE", Warnings: []*compute.OperationWarnings{
+				{Code: "NOT_CRITICAL_ERROR", Message: "Some instances were not added."},
+			}},
+			expectedCondition: &metav1.Condition{
+				Type:    operationsConditionType,
+				Status:  metav1.ConditionTrue,
+				Reason:  operationWarningsReason,
+				Message: "addToTargetPool operation operation-2 completed with warnings: NOT_CRITICAL_ERROR: Some instances were not added.",
+			},
+		},
+		{
+			name:    "Success clears a previous failure",
+			pending: `[{"name":"operation-1","action":"insert","zone":"us-east1-b"}]`,
+			conditions: []metav1.Condition{
+				{Type: operationsConditionType, Status: metav1.ConditionFalse, Reason: operationFailedReason},
+			},
+			zoneOperation: &compute.Operation{Status: "DONE"},
+			expectedCondition: &metav1.Condition{
+				Type:    operationsConditionType,
+				Status:  metav1.ConditionTrue,
+				Reason:  operationsSucceededReason,
+				Message: "insert operation operation-1 succeeded",
+			},
+		},
+		{
+			name:               "Operation that no longer exists is dropped",
+			pending:            `[{"name":"operation-1","action":"insert","zone":"us-east1-b"}]`,
+			zoneOperationError: &googleapi.Error{Code: 404},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockZoneOperationsGet = func(project string, zone string, operation string) (*compute.Operation, error) {
+				return tc.zoneOperation, tc.zoneOperationError
+			}
+			mockComputeService.MockRegionOperationsGet = func(project string, region string, operation string) (*compute.Operation, error) {
+				return tc.regionOperation, nil
+			}
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if tc.pending != "" {
+				machine.Annotations = map[string]string{pendingOperationsAnnotation: tc.pending}
+			}
+			r := newReconciler(&machineScope{
+				machine:        machine,
+				providerSpec:   &machinev1.GCPMachineProviderSpec{},
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.conditions},
+				computeService: mockComputeService,
+				eventRecorder:  record.NewFakeRecorder(2),
+			})
+
+			requeueAfter, failures, err := r.reconcileOperations()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if requeueAfter != tc.expectedRequeueAfter {
+				t.Errorf("Expected requeue after %s, got %s", tc.expectedRequeueAfter, requeueAfter)
+			}
+			if len(failures) != tc.expectedFailures {
+				t.Errorf("Expected %d failures, got %v", tc.expectedFailures, failures)
+			}
+			if pending := machine.Annotations[pendingOperationsAnnotation]; pending != tc.expectedPending {
+				t.Errorf("Expected pending operations %q, got %q", tc.expectedPending, pending)
+			}
+			condition := findCondition(r.providerStatus.Conditions, operationsConditionType)
+			if tc.expectedCondition == nil {
+				if condition != nil {
+					t.Errorf("Unexpected condition: %v", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tc.expectedCondition.Status || condition.Reason != tc.expectedCondition.Reason || condition.Message != tc.expectedCondition.Message {
+				t.Errorf("Expected condition %v, got %v", tc.expectedCondition, condition)
+			}
+		})
+	}
+}
+
+func TestCreateWaitsForPendingInsert(t *testing.T) {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	mockComputeService.MockZoneOperationsGet = func(project string, zone string, operation string) (*compute.Operation, error) {
+		return &compute.Operation{Status: "RUNNING"}, nil
+	}
+	inserted := false
+	mockComputeService.MockInstancesInsert = func(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
+		inserted = true
+		return nil, nil
+	}
+	r := newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Labels:      map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+				Annotations: map[string]string{pendingOperationsAnnotation: `[{"name":"operation-1","action":"insert","zone":"us-east1-b"}]`},
+			},
+		},
+		coreClient:     controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "us-east1", Zone: "us-east1-b"},
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		computeService: mockComputeService,
+	})
+
+	err := r.create()
+	var requeueErr *machinecontroller.RequeueAfterError
+	if !errors.As(err, &requeueErr) || requeueErr.RequeueAfter != operationPollInterval {
+		t.Fatalf("Expected a requeue after %s, got %v", operationPollInterval, err)
+	}
+	if inserted {
+		t.Errorf("Expected the instance not to be inserted again")
+	}
+}
+
+func TestInstanceCreationMetrics(t *testing.T) {
+	creations := func(result, reason string) float64 {
+		metric := &dto.Metric{}
+		if err := instanceCreationsTotal.WithLabelValues("us-east1-b", "n2d-standard-4", result, reason).Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		return metric.GetCounter().GetValue()
+	}
+	succeeded, exhausted := creations("succeeded", ""), creations("failed", "ZoneResourcesExhausted")
+
+	r := newReconciler(&machineScope{
+		machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+		providerSpec:   &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b", MachineType: "n2d-standard-4"},
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		eventRecorder:  record.NewFakeRecorder(2),
+	})
+	tracked := trackedOperation{Name: "operation-1", Action: insertOperationAction, Zone: "us-east1-b"}
+	r.recordOperationResult(tracked, &compute.Operation{Status: "DONE"})
+	r.recordOperationResult(tracked, &compute.Operation{Status: "DONE", Error: &compute.OperationError{Errors: []*compute.OperationErrorErrors{
+		{Code: "ZONE_RESOURCE_POOL_EXHAUSTED", Message: "The zone does not have enough resources available."},
+	}}})
+	r.recordOperationResult(trackedOperation{Name: "operation-2", Action: addToTargetPoolOperationAction, Region: "us-east1"}, &compute.Operation{Status: "DONE"})
+
+	if delta := creations("succeeded", "") - succeeded; delta != 1 {
+		t.Errorf("Expected 1 successful creation, got %v", delta)
+	}
+	if delta := creations("failed", "ZoneResourcesExhausted") - exhausted; delta != 1 {
+		t.Errorf("Expected 1 creation failed with ZoneResourcesExhausted, got %v", delta)
+	}
+}
+
+func TestTrackOperation(t *testing.T) {
+	r := newReconciler(&machineScope{
+		machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+	})
+
+	r.trackOperation(insertOperationAction, &compute.Operation{Name: "operation-1", Status: "PENDING", Zone: "https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b"})
+	r.trackOperation(addToTargetPoolOperationAction, &compute.Operation{Name: "operation-2", Status: "RUNNING", Region: "https://www.googleapis.com/compute/v1/projects/p/regions/us-east1"})
+	r.trackOperation(insertOperationAction, &compute.Operation{Status: "DONE"})
+
+	expected := `[{"name":"operation-1","action":"insert","zone":"us-east1-b"},{"name":"operation-2","action":"addToTargetPool","region":"us-east1"}]`
+	if pending := r.machine.Annotations[pendingOperationsAnnotation]; pending != expected {
+		t.Errorf("Expected pending operations %s, got %s", expected, pending)
+	}
+}
@@ -0,0 +1,63 @@
+package machine
+
+import (
+	"text/template"
+	"time"
+)
+
+// Options are the settings of the reconciler that apply to all machines, usually set from the
+// flags of the controller. They are passed by value from ActuatorParams down to the scope of
+// every machine actuator operation. The zero value keeps the defaults of every setting.
+type Options struct {
+	// MaxAPICallsPerReconcile and MaxReconcileDuration bound the compute API calls and the wall
+	// time of a single machine operation. Once exceeded, the partial progress is persisted and the
+	// machine is requeued. Zero means unlimited.
+	MaxAPICallsPerReconcile int
+	MaxReconcileDuration    time.Duration
+	// ProvisioningTimeout is how long a created machine may take to become a node before a
+	// diagnostics bundle is collected for it. Zero disables it.
+	ProvisioningTimeout time.Duration
+	// DefaultServiceAccount is the email of the service account attached, with the cloud-platform
+	// scope, to instances of machines that do not set one, usually the service account of the
+	// cluster nodes. Optional.
+	DefaultServiceAccount string
+	// ExistsVerificationInterval is how long Exists trusts the provider status of a machine whose
+	// instance was found in the cloud before asking the cloud again. Machines that changed, are not
+	// running or whose last operation failed are always verified. Zero always asks the cloud.
+	ExistsVerificationInterval time.Duration
+	// RemediateDrift reverts the labels, network tags and metadata of instances that differ from
+	// their providerSpec. Other differences, and all of them when false, are only reported in the
+	// ProviderSpecOutOfSync condition.
+	RemediateDrift bool
+	// SharedCorePolicy is what the reconciler does about control plane and infra machines using a
+	// shared-core machine type, e.g. e2-medium. Defaults to SharedCorePolicyWarn.
+	SharedCorePolicy SharedCorePolicy
+	// DeletionProtectionPolicy is what the reconciler does when the instance of a deleted machine has
+	// deletion protection enabled. Defaults to DeletionProtectionPolicyRefuse.
+	DeletionProtectionPolicy DeletionProtectionPolicy
+	// MissingNetworkPolicy is what the reconciler does about machines whose providerSpec has no
+	// network interfaces. Defaults to MissingNetworkPolicyOmit.
+	MissingNetworkPolicy MissingNetworkPolicy
+	// OrphanInstancePolicy is what the instance sync does about instances labelled with the cluster
+	// ID that no machine refers to. Defaults to OrphanInstancePolicyIgnore.
+	OrphanInstancePolicy OrphanInstancePolicy
+	// OrphanInstanceGracePeriod is how old an instance without a machine must be to be considered
+	// orphaned. Defaults to DefaultOrphanInstanceGracePeriod.
+	OrphanInstanceGracePeriod time.Duration
+	// SpotZonePolicy is how the reconciler picks the zone of the instances of preemptible machines
+	// with fallback zones, e.g. by spot price or recent preemptions. Defaults to SpotZonePolicyRequested.
+	SpotZonePolicy SpotZonePolicy
+	// InstanceNameTemplate renders the names of new instances, see ParseInstanceNameTemplate.
+	// Instances are named after their machine when it is not set.
+	InstanceNameTemplate *template.Template
+	// PrivateGoogleAccessCheck refuses to create instances without external IPs whose subnetwork
+	// has Private Google Access disabled and whose network has no Private Service Connect endpoint
+	// for Google APIs, as they could not reach the GCP APIs.
+	PrivateGoogleAccessCheck bool
+	// ManagedTags are network tags kept on the instances of all machines, and optionally in the
+	// target tags of firewall rules, see ParseManagedTags.
+	ManagedTags []ManagedTag
+	// DNSRecords registers the internal IPs of machines in a Cloud DNS zone, see ParseDNSRecords.
+	// Records are only registered when ActuatorParams.DNSClientBuilder is set. Optional.
+	DNSRecords *DNSRecords
+}
@@ -0,0 +1,86 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/klog/v2"
+)
+
+// OrphanInstancePolicy is what the instance sync does about instances labelled with the cluster ID
+// that no machine refers to, e.g. because a create timed out between the insert and the persistence
+// of the provider status and the machine was deleted since.
+type OrphanInstancePolicy string
+
+const (
+	// OrphanInstancePolicyIgnore leaves orphaned instances alone.
+	OrphanInstancePolicyIgnore OrphanInstancePolicy = "ignore"
+	// OrphanInstancePolicyDryRun only logs and counts orphaned instances.
+	OrphanInstancePolicyDryRun OrphanInstancePolicy = "dry-run"
+	// OrphanInstancePolicyDelete deletes orphaned instances.
+	OrphanInstancePolicyDelete OrphanInstancePolicy = "delete"
+
+	// DefaultOrphanInstanceGracePeriod is how old an instance without a machine must be to be
+	// considered orphaned.
+	DefaultOrphanInstanceGracePeriod = time.Hour
+)
+
+// ParseOrphanInstancePolicy returns the policy of the given name.
+func ParseOrphanInstancePolicy(name string) (OrphanInstancePolicy, error) {
+	switch policy := OrphanInstancePolicy(name); policy {
+	case OrphanInstancePolicyIgnore, OrphanInstancePolicyDryRun, OrphanInstancePolicyDelete:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown orphan instance policy %q, expected %s, %s or %s", name, OrphanInstancePolicyIgnore, OrphanInstancePolicyDryRun, OrphanInstancePolicyDelete)
+}
+
+// collectOrphans reports or deletes the listed instances of a group that no machine refers to.
+// Instances younger than the grace period are skipped, so that the instance of a machine that is
+// not in the cache yet is never taken for an orphan. The request ID of the deletion is derived from
+// the instance, so that deleting it again while the deletion is in progress is a no-op.
+func (s *instanceSync) collectOrphans(ctx context.Context, computeService computeservice.GCPComputeService, group *instanceSyncGroup, instances []*compute.Instance, machineInstances machineInstances) {
+	policy := s.actuator.options.OrphanInstancePolicy
+	if policy != OrphanInstancePolicyDryRun && policy != OrphanInstancePolicyDelete {
+		return
+	}
+	gracePeriod := s.actuator.options.OrphanInstanceGracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultOrphanInstanceGracePeriod
+	}
+
+	for _, instance := range instances {
+		if _, ok := machineInstances.machineOf(listedInstanceKey(group.projectID, instance)); ok {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, instance.CreationTimestamp)
+		if err != nil || s.now().Sub(created) < gracePeriod {
+			continue
+		}
+		zone := path.Base(instance.Zone)
+		log := klog.FromContext(ctx).WithValues("instance", instance.Name, "zone", zone, "project", group.projectID)
+
+		if policy == OrphanInstancePolicyDryRun {
+			log.Info("Instance has no machine and would be deleted")
+			orphanInstancesTotal.WithLabelValues("reported").Inc()
+			continue
+		}
+		if instance.DeletionProtection {
+			log.Info("Instance has no machine but has deletion protection enabled, not deleting it")
+			orphanInstancesTotal.WithLabelValues("reported").Inc()
+			continue
+		}
+		log.Info("Instance has no machine, deleting it")
+		requestID := uuid.NewSHA1(uuid.NameSpaceURL, []byte(instance.SelfLink)).String()
+		if _, err := computeService.InstancesDelete(requestID, group.projectID, zone, instance.Name); err != nil {
+			log.Error(err, "Failed to delete orphaned instance")
+			orphanInstancesTotal.WithLabelValues("failed").Inc()
+			continue
+		}
+		orphanInstancesTotal.WithLabelValues("deleted").Inc()
+	}
+}
@@ -0,0 +1,109 @@
+package machine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type deleteTrackingComputeService struct {
+	*computeservice.GCPComputeServiceMock
+	deleted   []string
+	requestID string
+}
+
+func (c *deleteTrackingComputeService) InstancesDelete(requestID string, project string, zone string, instance string) (*compute.Operation, error) {
+	c.deleted = append(c.deleted, zone+"/"+instance)
+	c.requestID = requestID
+	return c.GCPComputeServiceMock.InstancesDelete(requestID, project, zone, instance)
+}
+
+func TestCollectOrphans(t *testing.T) {
+	credentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: defaultNamespaceName},
+		Data:       map[string][]byte{credentialsSecretKey: []byte("{\"project_id\": \"test\"}")},
+	}
+	newMachine := func(name string) *machinev1.Machine {
+		machine := existenceCacheMachine(t, name, "RUNNING")
+		machine.Name = name
+		machine.UID = types.UID(name)
+		return machine
+	}
+	running := newMachine("running")
+	// A machine that was not created yet keeps its instance from being collected.
+	provisioning := newMachine("provisioning")
+	provisioning.Status = machinev1.MachineStatus{Phase: pointer.String("Provisioning")}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-2 * time.Hour).Format(time.RFC3339)
+	instances := []*compute.Instance{
+		{Name: "running", Status: "RUNNING", Zone: "zones/us-east1-b", CreationTimestamp: old},
+		// Instance names are only unique per zone, this one has no machine.
+		{Name: "running", Status: "RUNNING", Zone: "zones/us-east1-c", CreationTimestamp: old, SelfLink: "projects/test/zones/us-east1-c/instances/running"},
+		{Name: "provisioning", Status: "RUNNING", Zone: "zones/us-east1-b", CreationTimestamp: old},
+		{Name: "orphan", Status: "RUNNING", Zone: "zones/us-east1-c", CreationTimestamp: old, SelfLink: "projects/test/zones/us-east1-c/instances/orphan"},
+		{Name: "protected", Status: "RUNNING", Zone: "zones/us-east1-c", CreationTimestamp: old, DeletionProtection: true},
+		{Name: "recent", Status: "RUNNING", Zone: "zones/us-east1-c", CreationTimestamp: now.Add(-10 * time.Minute).Format(time.RFC3339)},
+	}
+
+	cases := []struct {
+		name            string
+		policy          OrphanInstancePolicy
+		expectedDeleted []string
+	}{
+		{
+			name: "Orphans are ignored by default",
+		},
+		{
+			name:   "Orphans are only reported in dry-run",
+			policy: OrphanInstancePolicyDryRun,
+		},
+		{
+			name:            "Orphans older than the grace period are deleted",
+			policy:          OrphanInstancePolicyDelete,
+			expectedDeleted: []string{"us-east1-c/running", "us-east1-c/orphan"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mock := computeservice.NewComputeServiceMock()
+			mock.MockInstancesList = func(project string, filter string) ([]*compute.Instance, error) {
+				return instances, nil
+			}
+			computeService := &deleteTrackingComputeService{GCPComputeServiceMock: mock}
+			c := controllerfake.NewClientBuilder().WithObjects(credentialsSecret, running, provisioning).Build()
+			actuator := NewActuator(ActuatorParams{
+				CoreClient:    c,
+				EventRecorder: record.NewFakeRecorder(10),
+				ComputeClientBuilder: func(serviceAccountJSON string) (computeservice.GCPComputeService, error) {
+					return computeService, nil
+				},
+				Clock:   clocktesting.NewFakeClock(now),
+				Options: Options{OrphanInstancePolicy: tc.policy},
+			})
+
+			if err := actuator.InstanceSync(time.Minute).(*instanceSync).sync(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(computeService.deleted, tc.expectedDeleted) {
+				t.Errorf("Expected deleted instances %v, got %v", tc.expectedDeleted, computeService.deleted)
+			}
+			if len(tc.expectedDeleted) > 0 && computeService.requestID == "" {
+				t.Errorf("Expected the deletion to have a request ID")
+			}
+		})
+	}
+}
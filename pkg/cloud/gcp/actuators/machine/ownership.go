@@ -0,0 +1,65 @@
+package machine
+
+import (
+	"fmt"
+	"path"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+)
+
+// checkClusterOwnership verifies that a GCP resource carries the ownership label of the machine's
+// cluster before it, or its membership in a load balancer, is destroyed. Names of GCP resources
+// are only unique per project, so in shared projects a resource with the expected name may belong
+// to another cluster.
+func (r *Reconciler) checkClusterOwnership(kind, name string, labels map[string]string) error {
+	clusterID := r.machine.Labels[machinev1.MachineClusterIDLabel]
+	if util.IsClusterOwned(labels, clusterID) {
+		return nil
+	}
+
+	skip, err := r.getBoolAnnotation(skipClusterOwnershipCheckAnnotation)
+	if err != nil {
+		return err
+	}
+	if skip {
+		r.log.Info("Resource is not labelled as owned by the cluster, continuing because of the annotation",
+			"kind", kind, "name", name, "clusterID", clusterID, "annotation", skipClusterOwnershipCheckAnnotation)
+		return nil
+	}
+
+	return fmt.Errorf("refusing to delete %s %s: it does not carry the label %s=%s of cluster %q",
+		kind, name, util.ClusterOwnedLabelKey(clusterID), util.ClusterOwnedLabelValue, clusterID)
+}
+
+// checkInstanceOwnership verifies that the instance and the disks deleted along with it belong to
+// the machine's cluster before the instance leaves its target pools, instance group or network
+// endpoint group and is deleted. Target pools and groups cannot carry labels, the membership is
+// checked through the instance. Disks that are only detached by the deletion, e.g. persistent
+// volumes, are not destroyed and not checked. A nil instance, which is gone, is not checked.
+func (r *Reconciler) checkInstanceOwnership(instance *compute.Instance) error {
+	if instance == nil {
+		return nil
+	}
+	if err := r.checkClusterOwnership("instance", instance.Name, instance.Labels); err != nil {
+		return err
+	}
+	for _, attached := range instance.Disks {
+		if !attached.AutoDelete || attached.Source == "" {
+			continue
+		}
+		name := path.Base(attached.Source)
+		disk, err := r.computeService.DisksGet(r.projectID, r.providerSpec.Zone, name)
+		if err != nil {
+			if isNotFoundError(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get disk %s: %w", name, err)
+		}
+		if err := r.checkClusterOwnership("disk", disk.Name, disk.Labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
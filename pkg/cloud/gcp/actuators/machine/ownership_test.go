@@ -0,0 +1,285 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckClusterOwnership(t *testing.T) {
+	cases := []struct {
+		name          string
+		annotations   map[string]string
+		labels        map[string]string
+		expectedError error
+	}{
+		{
+			name:   "Resource owned by the cluster",
+			labels: map[string]string{"kubernetes-io-cluster-CLUSTERID": "owned"},
+		},
+		{
+			name:          "Resource owned by another cluster",
+			labels:        map[string]string{"kubernetes-io-cluster-OTHER": "owned"},
+			expectedError: errors.New("refusing to delete instance test: it does not carry the label kubernetes-io-cluster-CLUSTERID=owned of cluster \"CLUSTERID\""),
+		},
+		{
+			name:          "Resource without labels",
+			expectedError: errors.New("refusing to delete instance test: it does not carry the label kubernetes-io-cluster-CLUSTERID=owned of cluster \"CLUSTERID\""),
+		},
+		{
+			name:        "Resource without labels with ownership check skipped",
+			annotations: map[string]string{skipClusterOwnershipCheckAnnotation: "true"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "test",
+						Labels:      map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+						Annotations: tc.annotations,
+					},
+				},
+			})
+
+			err := r.checkClusterOwnership("instance", "test", tc.labels)
+			if tc.expectedError != nil {
+				if err == nil || err.Error() != tc.expectedError.Error() {
+					t.Errorf("Expected: %v, got %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeleteRefusesInstanceOfAnotherCluster(t *testing.T) {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	r := newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "test",
+				Labels: map[string]string{machinev1.MachineClusterIDLabel: "OTHER"},
+			},
+		},
+		coreClient:     controllerfake.NewFakeClient(),
+		providerSpec:   &machinev1.GCPMachineProviderSpec{},
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		computeService: mockComputeService,
+	})
+
+	expectedError := "refusing to delete instance test: it does not carry the label kubernetes-io-cluster-OTHER=owned of cluster \"OTHER\""
+	if err := r.delete(); err == nil || err.Error() != expectedError {
+		t.Errorf("Expected: %v, got %v", expectedError, err)
+	}
+}
+
+type instanceLookupFailingComputeService struct {
+	*computeservice.GCPComputeServiceMock
+	err     error
+	deletes int
+}
+
+func (c *instanceLookupFailingComputeService) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
+	return nil, c.err
+}
+
+func (c *instanceLookupFailingComputeService) TargetPoolsRemoveInstance(project string, region string, name string, instance string) (*compute.Operation, error) {
+	c.deletes++
+	return c.GCPComputeServiceMock.TargetPoolsRemoveInstance(project, region, name, instance)
+}
+
+func (c *instanceLookupFailingComputeService) InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error) {
+	c.deletes++
+	return c.GCPComputeServiceMock.InstancesDelete(requestId, project, zone, instance)
+}
+
+func TestDeleteStopsWhenInstanceLookupFails(t *testing.T) {
+	cases := []struct {
+		name            string
+		code            int
+		expectedRequeue bool
+	}{
+		{
+			name: "Permission denied",
+			code: 403,
+		},
+		{
+			name:            "Service unavailable",
+			code:            503,
+			expectedRequeue: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			computeService := &instanceLookupFailingComputeService{
+				GCPComputeServiceMock: mockComputeService,
+				err:                   &googleapi.Error{Code: tc.code},
+			}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "test",
+						Labels: map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+					},
+				},
+				coreClient:     controllerfake.NewFakeClient(),
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "WithMachineInPool", TargetPools: []string{"pool"}},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: computeService,
+			})
+
+			err := r.delete()
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			var requeueErr *machinecontroller.RequeueAfterError
+			if requeued := errors.As(err, &requeueErr); requeued != tc.expectedRequeue {
+				t.Errorf("Expected requeue %v, got %v", tc.expectedRequeue, err)
+			}
+			if computeService.deletes != 0 {
+				t.Errorf("Expected the instance and its memberships to be left alone, got %d removals", computeService.deletes)
+			}
+		})
+	}
+}
+
+type deletionRecordingComputeService struct {
+	*computeservice.GCPComputeServiceMock
+	instanceLabels map[string]string
+	diskLabels     map[string]string
+	removals       []string
+}
+
+func (c *deletionRecordingComputeService) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
+	found, err := c.GCPComputeServiceMock.InstancesGet(project, zone, instance)
+	if err != nil {
+		return nil, err
+	}
+	found.Labels = c.instanceLabels
+	found.Disks = []*compute.AttachedDisk{
+		{Source: "projects/project/zones/zone1/disks/test", AutoDelete: true, Boot: true},
+		{Source: "projects/project/zones/zone1/disks/pvc", AutoDelete: false},
+	}
+	return found, nil
+}
+
+func (c *deletionRecordingComputeService) DisksGet(project string, zone string, disk string) (*compute.Disk, error) {
+	if disk != "test" {
+		return nil, fmt.Errorf("unexpected lookup of disk %s", disk)
+	}
+	return &compute.Disk{Name: disk, Labels: c.diskLabels}, nil
+}
+
+func (c *deletionRecordingComputeService) TargetPoolsRemoveInstance(project string, region string, name string, instance string) (*compute.Operation, error) {
+	c.removals = append(c.removals, "targetPool/"+name)
+	return c.GCPComputeServiceMock.TargetPoolsRemoveInstance(project, region, name, instance)
+}
+
+func (c *deletionRecordingComputeService) InstanceGroupsRemoveInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
+	c.removals = append(c.removals, "instanceGroup/"+instanceGroup)
+	return c.GCPComputeServiceMock.InstanceGroupsRemoveInstances(project, zone, instance, instanceGroup)
+}
+
+func (c *deletionRecordingComputeService) InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error) {
+	c.removals = append(c.removals, "instance/"+instance)
+	return c.GCPComputeServiceMock.InstancesDelete(requestId, project, zone, instance)
+}
+
+func TestDeleteChecksOwnershipBeforeLeavingLoadBalancers(t *testing.T) {
+	owned := map[string]string{"kubernetes-io-cluster-CLUSTERID": "owned"}
+	otherCluster := map[string]string{"kubernetes-io-cluster-OTHER": "owned"}
+	cases := []struct {
+		name             string
+		role             string
+		instanceLabels   map[string]string
+		diskLabels       map[string]string
+		expectedError    string
+		expectedRemovals []string
+	}{
+		{
+			name:             "Instance and disks owned by the cluster",
+			instanceLabels:   owned,
+			diskLabels:       owned,
+			expectedRemovals: []string{"targetPool/pool", "instance/testInstance"},
+		},
+		{
+			name:           "Instance of another cluster",
+			instanceLabels: otherCluster,
+			diskLabels:     owned,
+			expectedError:  "refusing to delete instance testInstance: it does not carry the label kubernetes-io-cluster-CLUSTERID=owned of cluster \"CLUSTERID\"",
+		},
+		{
+			name:           "Control plane instance of another cluster",
+			role:           masterMachineRole,
+			instanceLabels: otherCluster,
+			diskLabels:     owned,
+			expectedError:  "refusing to delete instance testInstance: it does not carry the label kubernetes-io-cluster-CLUSTERID=owned of cluster \"CLUSTERID\"",
+		},
+		{
+			name:           "Disk deleted with the instance of another cluster",
+			instanceLabels: owned,
+			diskLabels:     otherCluster,
+			expectedError:  "refusing to delete disk test: it does not carry the label kubernetes-io-cluster-CLUSTERID=owned of cluster \"CLUSTERID\"",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			computeService := &deletionRecordingComputeService{
+				GCPComputeServiceMock: mockComputeService,
+				instanceLabels:        tc.instanceLabels,
+				diskLabels:            tc.diskLabels,
+			}
+			labels := map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"}
+			if tc.role != "" {
+				labels[openshiftMachineRoleLabel] = tc.role
+			}
+			r := newReconciler(&machineScope{
+				Context: context.Background(),
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: labels},
+				},
+				coreClient:     controllerfake.NewFakeClient(),
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "WithMachineInPool", Zone: "zone1", TargetPools: []string{"pool"}},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				projectID:      "testProject",
+				instanceName:   "testInstance",
+				computeService: computeService,
+				eventRecorder:  record.NewFakeRecorder(10),
+			})
+
+			err := r.delete()
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected: %v, got %v", tc.expectedError, err)
+				}
+			} else {
+				var requeueErr *machinecontroller.RequeueAfterError
+				if err != nil && !errors.As(err, &requeueErr) {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(computeService.removals, tc.expectedRemovals) {
+				t.Errorf("Expected removals %v, got %v", tc.expectedRemovals, computeService.removals)
+			}
+		})
+	}
+}
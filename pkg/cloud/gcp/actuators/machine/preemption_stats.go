@@ -0,0 +1,87 @@
+package machine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// preemptedOperationFilter selects the operations GCP records when it preempts an instance.
+	preemptedOperationFilter = `operationType="compute.instances.preempted"`
+	// preemptionStatsWindow is how far back preemptions are counted. GCP keeps the operations of a
+	// zone for a limited time, so older preemptions may already be gone.
+	preemptionStatsWindow = 7 * 24 * time.Hour
+	// preemptionStatsTTL is how long the preemptions counted in a zone are reused before the
+	// operations of the zone are listed again.
+	preemptionStatsTTL = time.Hour
+)
+
+// preemptionStats counts the recent preemptions of instances per project and zone for all machines of
+// the actuator, from the preempted operations of the zones.
+type preemptionStats struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]preemptionStatsEntry
+}
+
+// preemptionStatsEntry is the number of preemptions in a zone when it was last counted.
+type preemptionStatsEntry struct {
+	preemptions int
+	countedAt   time.Time
+}
+
+func newPreemptionStats(c clock.Clock) *preemptionStats {
+	if c == nil {
+		c = clock.RealClock{}
+	}
+	return &preemptionStats{clock: c, entries: map[string]preemptionStatsEntry{}}
+}
+
+// count returns the number of instances of the project preempted in the zone within the last
+// preemptionStatsWindow, listing the operations of the zone when they were counted more than
+// preemptionStatsTTL ago. Failures are not cached.
+func (s *preemptionStats) count(service computeservice.GCPComputeService, project, zone string) (int, error) {
+	key := project + "/" + zone
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[key]; ok && s.clock.Since(entry.countedAt) < preemptionStatsTTL {
+		return entry.preemptions, nil
+	}
+
+	operations, err := service.ZoneOperationsList(project, zone, preemptedOperationFilter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list the preemptions of zone %s: %w", zone, err)
+	}
+	now := s.clock.Now()
+	preemptions := 0
+	for _, operation := range operations {
+		inserted, err := time.Parse(time.RFC3339, operation.InsertTime)
+		if err != nil || now.Sub(inserted) > preemptionStatsWindow {
+			continue
+		}
+		preemptions++
+	}
+	s.entries[key] = preemptionStatsEntry{preemptions: preemptions, countedAt: now}
+	return preemptions, nil
+}
+
+// zonePreemptions returns the number of recent preemptions in each of the zones.
+func (r *Reconciler) zonePreemptions(zones []string) (map[string]float64, error) {
+	if r.preemptionStats == nil {
+		return nil, fmt.Errorf("preemption statistics are not available")
+	}
+	preemptions := map[string]float64{}
+	for _, zone := range zones {
+		count, err := r.preemptionStats.count(r.computeService, r.projectID, zone)
+		if err != nil {
+			return nil, err
+		}
+		preemptions[zone] = float64(count)
+	}
+	return preemptions, nil
+}
@@ -0,0 +1,161 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func preemptedOperations(now time.Time, ages ...time.Duration) []*compute.Operation {
+	operations := make([]*compute.Operation, 0, len(ages))
+	for _, age := range ages {
+		operations = append(operations, &compute.Operation{
+			OperationType: "compute.instances.preempted",
+			InsertTime:    now.Add(-age).Format(time.RFC3339),
+		})
+	}
+	return operations
+}
+
+func TestPreemptionStats(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	lookups := 0
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	mockComputeService.MockZoneOperationsList = func(project string, zone string, filter string) ([]*compute.Operation, error) {
+		lookups++
+		if filter != preemptedOperationFilter {
+			t.Errorf("Expected filter %s, got %s", preemptedOperationFilter, filter)
+		}
+		if lookups == 1 {
+			return nil, errors.New("backend error")
+		}
+		return preemptedOperations(fakeClock.Now(), time.Hour, 2*24*time.Hour, 8*24*time.Hour), nil
+	}
+	stats := newPreemptionStats(fakeClock)
+
+	if _, err := stats.count(mockComputeService, "testProject", "us-east1-b"); err == nil {
+		t.Fatal("Expected the error of the operations list")
+	}
+	for i := 0; i < 3; i++ {
+		count, err := stats.count(mockComputeService, "testProject", "us-east1-b")
+		if err != nil || count != 2 {
+			t.Fatalf("Expected the 2 preemptions of the last %s, got %d, %v", preemptionStatsWindow, count, err)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("Expected the failure not to be cached and the count to be, got %d lookups", lookups)
+	}
+
+	fakeClock.Step(preemptionStatsTTL)
+	if _, err := stats.count(mockComputeService, "testProject", "us-east1-b"); err != nil {
+		t.Fatal(err)
+	}
+	if lookups != 3 {
+		t.Errorf("Expected the preemptions to be counted again after %s, got %d lookups", preemptionStatsTTL, lookups)
+	}
+}
+
+func TestSelectLeastPreemptedZone(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name               string
+		zone               string
+		annotations        map[string]string
+		preemptions        map[string][]*compute.Operation
+		listErr            error
+		expectedError      error
+		expectedZone       string
+		expectedAnnotation string
+		expectedCondition  metav1.ConditionStatus
+		expectedMessage    string
+	}{
+		{
+			name:        "Moves to the zone with the fewest preemptions",
+			zone:        "us-east1-b",
+			annotations: map[string]string{fallbackZonesAnnotation: "us-east1-c,us-east1-d"},
+			preemptions: map[string][]*compute.Operation{
+				"us-east1-b": preemptedOperations(now, time.Hour, 2*time.Hour, 3*time.Hour),
+				"us-east1-c": preemptedOperations(now, time.Hour),
+				"us-east1-d": preemptedOperations(now, time.Hour),
+			},
+			expectedError:      &machinecontroller.RequeueAfterError{},
+			expectedZone:       "us-east1-c",
+			expectedAnnotation: "us-east1-c",
+			expectedCondition:  metav1.ConditionTrue,
+			expectedMessage:    "selected zone us-east1-c by the spot preemptions of the last 7 days: us-east1-b 3, us-east1-c 1, us-east1-d 1",
+		},
+		{
+			name:        "Keeps the requested zone on ties",
+			zone:        "us-east1-b",
+			annotations: map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+			preemptions: map[string][]*compute.Operation{
+				"us-east1-b": preemptedOperations(now, time.Hour),
+				"us-east1-c": preemptedOperations(now, time.Hour),
+			},
+			expectedZone:      "us-east1-b",
+			expectedCondition: metav1.ConditionTrue,
+			expectedMessage:   "selected zone us-east1-b by the spot preemptions of the last 7 days: us-east1-b 1, us-east1-c 1",
+		},
+		{
+			name:              "Keeps the requested zone when the preemptions can not be counted",
+			zone:              "us-east1-b",
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+			listErr:           errors.New("permission denied"),
+			expectedZone:      "us-east1-b",
+			expectedCondition: metav1.ConditionFalse,
+			expectedMessage:   "kept zone us-east1-b: failed to list the preemptions of zone us-east1-b: permission denied",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockZoneOperationsList = func(project string, zone string, filter string) ([]*compute.Operation, error) {
+				return tc.preemptions[zone], tc.listErr
+			}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{Name: "testInstance", Annotations: tc.annotations},
+				},
+				projectID: "testProject",
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:        tc.zone,
+					Region:      "us-east1",
+					MachineType: "n2-standard-2",
+					Preemptible: true,
+				},
+				providerStatus:  &machinev1.GCPMachineProviderStatus{},
+				computeService:  mockComputeService,
+				options:         Options{SpotZonePolicy: SpotZonePolicyLeastPreempted},
+				preemptionStats: newPreemptionStats(clocktesting.NewFakeClock(now)),
+			})
+
+			err := r.selectSpotZone()
+			if tc.expectedError != nil {
+				var requeue *machinecontroller.RequeueAfterError
+				if !errors.As(err, &requeue) {
+					t.Fatalf("Expected a requeue, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if r.providerSpec.Zone != tc.expectedZone {
+				t.Errorf("Expected zone %s, got %s", tc.expectedZone, r.providerSpec.Zone)
+			}
+			if zone := r.machine.Annotations[zoneAnnotation]; zone != tc.expectedAnnotation {
+				t.Errorf("Expected the zone of the instance to be recorded as %q, got %q", tc.expectedAnnotation, zone)
+			}
+			condition := findCondition(r.providerStatus.Conditions, spotZoneSelectionConditionType)
+			if condition == nil || condition.Status != tc.expectedCondition || condition.Message != tc.expectedMessage {
+				t.Errorf("Expected a %s condition with message %q, got %v", tc.expectedCondition, tc.expectedMessage, condition)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	machineTypeUnavailableReason = "MachineTypeUnavailable"
+	acceleratorUnavailableReason = "AcceleratorUnavailable"
+	insufficientCPUQuotaReason   = "InsufficientCPUQuota"
+	insufficientGPUQuotaReason   = "InsufficientGPUQuota"
+	insufficientSSDQuotaReason   = "InsufficientSSDQuota"
+	metadataTooLargeReason       = "MetadataTooLarge"
+
+	pdSSDDiskType       = "pd-ssd"
+	localSSDDiskType    = "local-ssd"
+	localSSDPartitionGB = 375
+)
+
+// cpuQuotaMetrics maps the machine families that have a dedicated regional CPU quota to its
+// metric, all other families count against the CPUS quota.
+var cpuQuotaMetrics = map[string]string{
+	"a2":  "A2_CPUS",
+	"c2":  "C2_CPUS",
+	"c2d": "C2D_CPUS",
+	"c3":  "C3_CPUS",
+	"m1":  "M1_CPUS",
+	"m2":  "M2_CPUS",
+	"m3":  "M3_CPUS",
+	"n2":  "N2_CPUS",
+	"n2d": "N2D_CPUS",
+	"t2a": "T2A_CPUS",
+	"t2d": "T2D_CPUS",
+}
+
+// preflightError is returned by pre-flight checks to give the MachineCreated condition a reason
+// more specific than MachineCreationFailed.
+type preflightError struct {
+	reason string
+	err    error
+}
+
+func (e *preflightError) Error() string { return e.err.Error() }
+
+func (e *preflightError) Unwrap() error { return e.err }
+
+// preflightState carries the instance about to be inserted and what earlier checks looked up.
+type preflightState struct {
+	instance    *compute.Instance
+	machineType *compute.MachineType
+}
+
+// preflightCheck validates the instance about to be inserted against what the target project
+// and zone offer, so that misconfigurations fail fast with a meaningful InvalidMachineConfiguration
+// instead of an opaque error from instances.insert.
+type preflightCheck struct {
+	name  string
+	check func(r *Reconciler, state *preflightState) error
+	// fromTemplate runs the check for instances created from an instance template too, only checks
+	// of the properties of the request do, the others check the providerSpec.
+	fromTemplate bool
+	// dryRunOnly runs the check only in dry runs, for checks whose failure instances.insert reports
+	// clearly enough not to spend an API call on every creation.
+	dryRunOnly bool
+}
+
+// preflightChecks run in order, the first failing check aborts the creation.
+var preflightChecks = []preflightCheck{
+	{name: "MetadataSize", check: (*Reconciler).checkMetadataSize, fromTemplate: true},
+	{name: "SharedCoreMachineType", check: (*Reconciler).checkSharedCoreMachineType},
+	{name: "NetworkInterfaces", check: (*Reconciler).checkNetworkInterfaces},
+	{name: "SharedVPCPermissions", check: (*Reconciler).checkSharedVPCPermissions},
+	{name: "PrivateGoogleAccess", check: (*Reconciler).checkPrivateGoogleAccess},
+	{name: "MachineTypeAvailability", check: (*Reconciler).checkMachineTypeAvailability},
+	{name: "AcceleratorAvailability", check: (*Reconciler).checkAcceleratorAvailability},
+	{name: "DiskPerformance", check: (*Reconciler).checkDiskPerformance},
+	{name: "RegionalQuota", check: (*Reconciler).checkRegionalQuota},
+	{name: "ServiceAccounts", check: (*Reconciler).checkServiceAccounts},
+	{name: "SourceImageEncryptionKey", check: (*Reconciler).checkSourceImageEncryptionKey},
+	{name: "SourceImages", check: (*Reconciler).checkSourceImages, dryRunOnly: true},
+}
+
+// runPreflightChecks runs the pre-flight validation stage before an instance is inserted.
+// A failing check sets the MachineCreated condition to False.
+func (r *Reconciler) runPreflightChecks(instance *compute.Instance) error {
+	state := &preflightState{instance: instance}
+	fromTemplate := r.fromInstanceTemplate()
+	for _, preflight := range preflightChecks {
+		if (fromTemplate && !preflight.fromTemplate) || (preflight.dryRunOnly && !r.dryRun) {
+			continue
+		}
+		r.log.V(logLevelRoutine).Info("Running pre-flight check", "check", preflight.name)
+		if err := preflight.check(r, state); err != nil {
+			reason := machineCreationFailedReason
+			var preflightErr *preflightError
+			if errors.As(err, &preflightErr) {
+				reason = preflightErr.reason
+			}
+			r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+				Type:    string(machinev1.MachineCreated),
+				Status:  metav1.ConditionFalse,
+				Reason:  reason,
+				Message: err.Error(),
+			})
+			return err
+		}
+	}
+	return nil
+}
+
+// checkMetadataSize verifies the metadata of the instance, the user data included, against the
+// limits of GCP, naming the key over the limit.
+func (r *Reconciler) checkMetadataSize(state *preflightState) error {
+	if state.instance.Metadata == nil {
+		return nil
+	}
+	total := 0
+	for _, item := range state.instance.Metadata.Items {
+		size := 0
+		if item.Value != nil {
+			size = len(*item.Value)
+		}
+		if size > maxMetadataValueLength {
+			return &preflightError{
+				reason: metadataTooLargeReason,
+				err:    machinecontroller.InvalidMachineConfiguration("metadata value of key %q is %d bytes, more than the limit of %d bytes", item.Key, size, maxMetadataValueLength),
+			}
+		}
+		total += len(item.Key) + size
+	}
+	if total > maxMetadataTotalLength {
+		return &preflightError{
+			reason: metadataTooLargeReason,
+			err:    machinecontroller.InvalidMachineConfiguration("metadata of the instance, the user data included, is %d bytes, more than the limit of %d bytes", total, maxMetadataTotalLength),
+		}
+	}
+	return nil
+}
+
+// checkMachineTypeAvailability verifies that the machine type is offered in the zone.
+// Custom machine types are built from their name and not looked up.
+func (r *Reconciler) checkMachineTypeAvailability(state *preflightState) error {
+	if custom, err := util.ParseCustomMachineType(r.providerSpec.MachineType); err != nil || custom != nil {
+		if custom != nil {
+			state.machineType = &compute.MachineType{Name: r.providerSpec.MachineType, GuestCpus: custom.CPUs, MemoryMb: custom.MemoryMb}
+		}
+		return err
+	}
+
+	machineType, err := r.computeService.MachineTypesGet(r.projectID, r.providerSpec.Zone, r.providerSpec.MachineType)
+	if err != nil {
+		if isNotFoundError(err) {
+			return &preflightError{
+				reason: machineTypeUnavailableReason,
+				err:    machinecontroller.InvalidMachineConfiguration("machine type %s is not available in zone %s", r.providerSpec.MachineType, r.providerSpec.Zone),
+			}
+		}
+		return fmt.Errorf("failed to get machine type %s in zone %s: %w", r.providerSpec.MachineType, r.providerSpec.Zone, err)
+	}
+	if machineType.Deprecated != nil && machineType.Deprecated.State == "OBSOLETE" {
+		return &preflightError{
+			reason: machineTypeUnavailableReason,
+			err:    machinecontroller.InvalidMachineConfiguration("machine type %s is obsolete in zone %s", r.providerSpec.MachineType, r.providerSpec.Zone),
+		}
+	}
+	state.machineType = machineType
+	return nil
+}
+
+// checkAcceleratorAvailability verifies that the requested GPU type is offered in the zone
+// and that the zone supports the requested number of cards per instance.
+func (r *Reconciler) checkAcceleratorAvailability(_ *preflightState) error {
+	if len(r.providerSpec.GPUs) == 0 {
+		return nil
+	}
+
+	acceleratorTypes, err := r.computeService.AcceleratorTypesList(r.projectID, r.providerSpec.Zone, r.Context)
+	if err != nil {
+		return fmt.Errorf("failed to list accelerator types in zone %s: %w", r.providerSpec.Zone, err)
+	}
+
+	gpu := r.providerSpec.GPUs[0]
+	for _, acceleratorType := range acceleratorTypes {
+		if acceleratorType.Name != gpu.Type {
+			continue
+		}
+		if acceleratorType.Deprecated != nil && acceleratorType.Deprecated.State == "OBSOLETE" {
+			return &preflightError{
+				reason: acceleratorUnavailableReason,
+				err:    machinecontroller.InvalidMachineConfiguration("accelerator type %s is obsolete in zone %s", gpu.Type, r.providerSpec.Zone),
+			}
+		}
+		if int64(gpu.Count) > acceleratorType.MaximumCardsPerInstance {
+			return &preflightError{
+				reason: acceleratorUnavailableReason,
+				err: machinecontroller.InvalidMachineConfiguration("accelerator type %s supports at most %d cards per instance in zone %s, got %d",
+					gpu.Type, acceleratorType.MaximumCardsPerInstance, r.providerSpec.Zone, gpu.Count),
+			}
+		}
+		return nil
+	}
+
+	return &preflightError{
+		reason: acceleratorUnavailableReason,
+		err:    machinecontroller.InvalidMachineConfiguration("accelerator type %s is not available in zone %s", gpu.Type, r.providerSpec.Zone),
+	}
+}
+
+// quotaRequest is the amount of a regional quota metric the instance consumes.
+type quotaRequest struct {
+	metric string
+	amount float64
+	reason string
+}
+
+// checkRegionalQuota verifies that the regional CPU, GPU and SSD quotas leave room for the instance.
+// Metrics the region does not report a quota for are not limited.
+func (r *Reconciler) checkRegionalQuota(state *preflightState) error {
+	requests := r.quotaRequests(state)
+	if len(requests) == 0 {
+		return nil
+	}
+
+	region, err := r.computeService.RegionGet(r.projectID, r.providerSpec.Region)
+	if err != nil {
+		return fmt.Errorf("failed to get region %s via compute service: %w", r.providerSpec.Region, err)
+	}
+
+	quotas := make(map[string]*compute.Quota, len(region.Quotas))
+	for _, quota := range region.Quotas {
+		quotas[quota.Metric] = quota
+	}
+
+	for _, request := range requests {
+		quota, ok := quotas[request.metric]
+		if !ok {
+			continue
+		}
+		if quota.Usage+request.amount > quota.Limit {
+			return &preflightError{
+				reason: request.reason,
+				err: machinecontroller.InvalidMachineConfiguration("insufficient %s quota in region %s: %v requested, %v of %v in use",
+					request.metric, r.providerSpec.Region, request.amount, quota.Usage, quota.Limit),
+			}
+		}
+	}
+	return nil
+}
+
+// quotaRequests returns the regional quota the instance consumes. Preemptible instances
+// consume the separate PREEMPTIBLE_ quotas.
+func (r *Reconciler) quotaRequests(state *preflightState) []quotaRequest {
+	prefix := ""
+	if r.providerSpec.Preemptible {
+		prefix = "PREEMPTIBLE_"
+	}
+
+	var requests []quotaRequest
+	if state.machineType != nil && state.machineType.GuestCpus > 0 {
+		metric := "CPUS"
+		if family := strings.SplitN(r.providerSpec.MachineType, "-", 2)[0]; cpuQuotaMetrics[family] != "" && prefix == "" {
+			metric = cpuQuotaMetrics[family]
+		}
+		requests = append(requests, quotaRequest{metric: prefix + metric, amount: float64(state.machineType.GuestCpus), reason: insufficientCPUQuotaReason})
+	}
+
+	// A2 machine types come with pre-attached GPUs, others use the GPUs of the providerSpec.
+	gpuType, gpuCount := "", int64(0)
+	if len(r.providerSpec.GPUs) > 0 {
+		gpuType, gpuCount = r.providerSpec.GPUs[0].Type, int64(r.providerSpec.GPUs[0].Count)
+	} else if state.machineType != nil && len(state.machineType.Accelerators) > 0 {
+		gpuType, gpuCount = state.machineType.Accelerators[0].GuestAcceleratorType, state.machineType.Accelerators[0].GuestAcceleratorCount
+	}
+	if metric := supportedGpuTypes[gpuType]; metric != "" && gpuCount > 0 {
+		requests = append(requests, quotaRequest{metric: prefix + metric, amount: float64(gpuCount), reason: insufficientGPUQuotaReason})
+	}
+
+	var ssdGB, localSSDGB int64
+	for _, disk := range r.providerSpec.Disks {
+		switch disk.Type {
+		case pdSSDDiskType:
+			ssdGB += disk.SizeGB
+		case localSSDDiskType:
+			localSSDGB += localSSDPartitionGB
+		}
+	}
+	if ssdGB > 0 {
+		requests = append(requests, quotaRequest{metric: "SSD_TOTAL_GB", amount: float64(ssdGB), reason: insufficientSSDQuotaReason})
+	}
+	if localSSDGB > 0 {
+		metric := "LOCAL_SSD_TOTAL_GB"
+		if r.providerSpec.Preemptible {
+			metric = "PREEMPTIBLE_LOCAL_SSD_GB"
+		}
+		requests = append(requests, quotaRequest{metric: metric, amount: float64(localSSDGB), reason: insufficientSSDQuotaReason})
+	}
+	return requests
+}
@@ -0,0 +1,247 @@
+package machine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	iamservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/iam"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestRunPreflightChecks(t *testing.T) {
+	quotas := func(quotas ...*compute.Quota) func(string, string) (*compute.Region, error) {
+		return func(_ string, _ string) (*compute.Region, error) {
+			return &compute.Region{Quotas: quotas}, nil
+		}
+	}
+
+	cases := []struct {
+		name                string
+		providerSpec        *machinev1.GCPMachineProviderSpec
+		mockMachineTypesGet func(project string, zone string, machineType string) (*compute.MachineType, error)
+		mockRegionGet       func(project string, region string) (*compute.Region, error)
+		disks               []*compute.AttachedDisk
+		metadata            []*compute.MetadataItems
+		serviceAccount      string
+		mockServiceAccount  func(ctx context.Context, email string) (*iamservice.ServiceAccount, error)
+		expectedReason      string
+		expectedError       string
+	}{
+		{
+			name:          "All checks pass",
+			providerSpec:  &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			mockRegionGet: quotas(&compute.Quota{Metric: "CPUS", Limit: 24, Usage: 20}),
+		},
+		{
+			name:         "User data larger than a metadata value",
+			providerSpec: &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			metadata: []*compute.MetadataItems{
+				{Key: userDataMetadataKey, Value: pointer.String(strings.Repeat("a", maxMetadataValueLength+1))},
+			},
+			expectedReason: metadataTooLargeReason,
+			expectedError:  `metadata value of key "user-data" is 262145 bytes, more than the limit of 262144 bytes`,
+		},
+		{
+			name:         "Metadata larger than the metadata of an instance",
+			providerSpec: &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			metadata: []*compute.MetadataItems{
+				{Key: userDataMetadataKey, Value: pointer.String(strings.Repeat("a", maxMetadataValueLength))},
+				{Key: "startup-script", Value: pointer.String(strings.Repeat("a", maxMetadataValueLength))},
+			},
+			expectedReason: metadataTooLargeReason,
+			expectedError:  "metadata of the instance, the user data included, is 524311 bytes, more than the limit of 524288 bytes",
+		},
+		{
+			name:         "Machine type not available in the zone",
+			providerSpec: &machinev1.GCPMachineProviderSpec{MachineType: "n9-standard-4", Zone: "test-zone"},
+			mockMachineTypesGet: func(_ string, _ string, _ string) (*compute.MachineType, error) {
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			},
+			expectedReason: machineTypeUnavailableReason,
+			expectedError:  "machine type n9-standard-4 is not available in zone test-zone",
+		},
+		{
+			name:         "Disk IOPS out of the range of the disk type",
+			providerSpec: &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			disks: []*compute.AttachedDisk{{InitializeParams: &compute.AttachedDiskInitializeParams{
+				DiskType: "zones/test-zone/diskTypes/pd-extreme", ProvisionedIops: 5000,
+			}}},
+			expectedReason: diskLimitExceededReason,
+			expectedError:  "disk 0: provisioned IOPS of disk type pd-extreme must be between 10000 and 120000, got 5000",
+		},
+		{
+			name:         "Disk throughput for a disk type without provisioned throughput",
+			providerSpec: &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			disks: []*compute.AttachedDisk{{InitializeParams: &compute.AttachedDiskInitializeParams{
+				DiskType: "zones/test-zone/diskTypes/pd-ssd", ProvisionedThroughput: 200,
+			}}},
+			expectedReason: diskLimitExceededReason,
+			expectedError:  "disk 0: throughput cannot be provisioned for disk type pd-ssd",
+		},
+		{
+			name:         "More disks than the machine type can attach",
+			providerSpec: &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			mockMachineTypesGet: func(_ string, _ string, _ string) (*compute.MachineType, error) {
+				return &compute.MachineType{Name: "n1-standard-4", GuestCpus: 4, MaximumPersistentDisks: 2}, nil
+			},
+			disks:          []*compute.AttachedDisk{{}, {}, {}},
+			expectedReason: diskLimitExceededReason,
+			expectedError:  "machine type n1-standard-4 can attach at most 2 disks, 3 requested",
+		},
+		{
+			name:           "Insufficient CPU quota",
+			providerSpec:   &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4", Region: "test-region"},
+			mockRegionGet:  quotas(&compute.Quota{Metric: "CPUS", Limit: 24, Usage: 22}),
+			expectedReason: insufficientCPUQuotaReason,
+			expectedError:  "insufficient CPUS quota in region test-region: 4 requested, 22 of 24 in use",
+		},
+		{
+			name:           "Machine family with a dedicated CPU quota",
+			providerSpec:   &machinev1.GCPMachineProviderSpec{MachineType: "n2-standard-4", Region: "test-region"},
+			mockRegionGet:  quotas(&compute.Quota{Metric: "CPUS", Limit: 24, Usage: 24}, &compute.Quota{Metric: "N2_CPUS", Limit: 8, Usage: 6}),
+			expectedReason: insufficientCPUQuotaReason,
+			expectedError:  "insufficient N2_CPUS quota in region test-region: 4 requested, 6 of 8 in use",
+		},
+		{
+			name:           "Insufficient preemptible CPU quota",
+			providerSpec:   &machinev1.GCPMachineProviderSpec{MachineType: "n2-standard-4", Region: "test-region", Preemptible: true},
+			mockRegionGet:  quotas(&compute.Quota{Metric: "N2_CPUS", Limit: 8}, &compute.Quota{Metric: "PREEMPTIBLE_CPUS", Limit: 2}),
+			expectedReason: insufficientCPUQuotaReason,
+			expectedError:  "insufficient PREEMPTIBLE_CPUS quota in region test-region: 4 requested, 0 of 2 in use",
+		},
+		{
+			name: "Insufficient GPU quota",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				MachineType: "n1-standard-4",
+				Region:      "test-region",
+				GPUs:        []machinev1.GCPGPUConfig{{Type: "nvidia-tesla-t4", Count: 2}},
+			},
+			mockRegionGet:  quotas(&compute.Quota{Metric: "NVIDIA_T4_GPUS", Limit: 4, Usage: 3}),
+			expectedReason: insufficientGPUQuotaReason,
+			expectedError:  "insufficient NVIDIA_T4_GPUS quota in region test-region: 2 requested, 3 of 4 in use",
+		},
+		{
+			name:         "Insufficient GPU quota for pre-attached accelerators",
+			providerSpec: &machinev1.GCPMachineProviderSpec{MachineType: "a2-highgpu-2g", Region: "test-region"},
+			mockMachineTypesGet: func(_ string, _ string, machineType string) (*compute.MachineType, error) {
+				return &compute.MachineType{
+					Name:         machineType,
+					GuestCpus:    24,
+					Accelerators: []*compute.MachineTypeAccelerators{{GuestAcceleratorType: "nvidia-tesla-a100", GuestAcceleratorCount: 2}},
+				}, nil
+			},
+			mockRegionGet:  quotas(&compute.Quota{Metric: "A2_CPUS", Limit: 96}, &compute.Quota{Metric: "NVIDIA_A100_GPUS", Limit: 1}),
+			expectedReason: insufficientGPUQuotaReason,
+			expectedError:  "insufficient NVIDIA_A100_GPUS quota in region test-region: 2 requested, 0 of 1 in use",
+		},
+		{
+			name:         "Custom machine type is not looked up",
+			providerSpec: &machinev1.GCPMachineProviderSpec{MachineType: "n2-custom-6-12288", Region: "test-region"},
+			mockMachineTypesGet: func(_ string, _ string, _ string) (*compute.MachineType, error) {
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			},
+			mockRegionGet:  quotas(&compute.Quota{Metric: "N2_CPUS", Limit: 8, Usage: 4}),
+			expectedReason: insufficientCPUQuotaReason,
+			expectedError:  "insufficient N2_CPUS quota in region test-region: 6 requested, 4 of 8 in use",
+		},
+		{
+			name: "Insufficient SSD quota",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				MachineType: "n1-standard-4",
+				Region:      "test-region",
+				Disks:       []*machinev1.GCPDisk{{Type: "pd-ssd", SizeGB: 128}, {Type: "pd-standard", SizeGB: 500}},
+			},
+			mockRegionGet:  quotas(&compute.Quota{Metric: "SSD_TOTAL_GB", Limit: 500, Usage: 400}),
+			expectedReason: insufficientSSDQuotaReason,
+			expectedError:  "insufficient SSD_TOTAL_GB quota in region test-region: 128 requested, 400 of 500 in use",
+		},
+		{
+			name:           "Service account exists",
+			providerSpec:   &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			serviceAccount: "worker@project.iam.gserviceaccount.com",
+		},
+		{
+			name:           "Service account does not exist",
+			providerSpec:   &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			serviceAccount: "wroker@project.iam.gserviceaccount.com",
+			mockServiceAccount: func(_ context.Context, _ string) (*iamservice.ServiceAccount, error) {
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			},
+			expectedReason: serviceAccountNotFoundReason,
+			expectedError:  "service account wroker@project.iam.gserviceaccount.com does not exist",
+		},
+		{
+			name:           "Service account is disabled",
+			providerSpec:   &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			serviceAccount: "worker@project.iam.gserviceaccount.com",
+			mockServiceAccount: func(_ context.Context, email string) (*iamservice.ServiceAccount, error) {
+				return &iamservice.ServiceAccount{Email: email, Disabled: true}, nil
+			},
+			expectedReason: serviceAccountDisabledReason,
+			expectedError:  "service account worker@project.iam.gserviceaccount.com is disabled",
+		},
+		{
+			name:           "Service account check is skipped without permission",
+			providerSpec:   &machinev1.GCPMachineProviderSpec{MachineType: "n1-standard-4"},
+			serviceAccount: "worker@project.iam.gserviceaccount.com",
+			mockServiceAccount: func(_ context.Context, _ string) (*iamservice.ServiceAccount, error) {
+				return nil, &googleapi.Error{Code: http.StatusForbidden}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockMachineTypesGet = tc.mockMachineTypesGet
+			mockComputeService.MockRegionGet = tc.mockRegionGet
+			mockIAMService := iamservice.NewMockIAMService()
+			mockIAMService.MockServiceAccountsGet = tc.mockServiceAccount
+
+			r := newReconciler(&machineScope{
+				Context:        context.Background(),
+				machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+				providerSpec:   tc.providerSpec,
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+				iamService:     mockIAMService,
+			})
+
+			instance := &compute.Instance{Disks: tc.disks, Metadata: &compute.Metadata{Items: tc.metadata}}
+			if tc.serviceAccount != "" {
+				instance.ServiceAccounts = []*compute.ServiceAccount{{Email: tc.serviceAccount}}
+			}
+			err := r.runPreflightChecks(instance)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(r.providerStatus.Conditions) != 0 {
+					t.Errorf("Expected no conditions, got %v", r.providerStatus.Conditions)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Fatalf("Expected error %q, got %v", tc.expectedError, err)
+			}
+			if !isInvalidMachineConfigurationError(err) {
+				t.Errorf("Expected an invalid machine configuration error, got %v", err)
+			}
+
+			condition := findCondition(r.providerStatus.Conditions, string(machinev1.MachineCreated))
+			if condition == nil {
+				t.Fatal("Expected the MachineCreated condition to be set")
+			}
+			if condition.Status != metav1.ConditionFalse || condition.Reason != tc.expectedReason {
+				t.Errorf("Expected condition False/%s, got %s/%s", tc.expectedReason, condition.Status, condition.Reason)
+			}
+		})
+	}
+}
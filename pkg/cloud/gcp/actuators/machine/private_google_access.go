@@ -0,0 +1,104 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+const privateGoogleAccessDisabledReason = "PrivateGoogleAccessDisabled"
+
+// googleAPIsPSCTargets are the targets of the Private Service Connect endpoints that give access to
+// the Google APIs, see https://cloud.google.com/vpc/docs/about-accessing-google-apis-endpoints.
+var googleAPIsPSCTargets = map[string]bool{
+	"all-apis": true,
+	"vpc-sc":   true,
+}
+
+// checkPrivateGoogleAccess verifies that an instance without external IPs can reach the GCP APIs,
+// which nodes need e.g. to pull images and to register with the cluster: the subnetwork of its
+// primary network interface must have Private Google Access enabled, or its network a Private
+// Service Connect endpoint for Google APIs. The check only runs when enabled, as clusters
+// reaching the APIs through Cloud NAT or a proxy do not need either. It is skipped when the
+// credentials are not allowed to read the subnetwork or the endpoints.
+func (r *Reconciler) checkPrivateGoogleAccess(state *preflightState) error {
+	if !r.options.PrivateGoogleAccessCheck || len(state.instance.NetworkInterfaces) == 0 {
+		return nil
+	}
+	for _, nic := range state.instance.NetworkInterfaces {
+		if len(nic.AccessConfigs) > 0 {
+			return nil
+		}
+	}
+	project, region, name, ok := parseSubnetworkURL(state.instance.NetworkInterfaces[0].Subnetwork)
+	if !ok {
+		return nil
+	}
+
+	var apiErr *googleapi.Error
+	subnetwork, err := r.computeService.SubnetworksGet(project, region, name)
+	if err != nil {
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+			r.log.Error(err, "Not allowed to get the subnetwork, skipping the Private Google Access check", "subnetwork", name, "project", project)
+			return nil
+		}
+		return fmt.Errorf("failed to get subnetwork %s of project %s: %w", name, project, err)
+	}
+	if subnetwork.PrivateIpGoogleAccess {
+		return nil
+	}
+
+	forwardingRules, err := r.computeService.GlobalForwardingRulesList(project, "")
+	if err != nil {
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+			r.log.Error(err, "Not allowed to list the forwarding rules, skipping the Private Google Access check", "project", project)
+			return nil
+		}
+		return fmt.Errorf("failed to list the global forwarding rules of project %s: %w", project, err)
+	}
+	if hasGoogleAPIsEndpoint(forwardingRules, subnetwork.Network) {
+		return nil
+	}
+
+	return &preflightError{
+		reason: privateGoogleAccessDisabledReason,
+		err: machinecontroller.InvalidMachineConfiguration("instance has no external IP and cannot reach the GCP APIs: subnetwork %s of project %s has Private Google Access disabled "+
+			"and its network has no Private Service Connect endpoint for Google APIs, enable it with "+
+			"`gcloud compute networks subnets update %s --project %s --region %s --enable-private-ip-google-access`",
+			name, project, name, project, region),
+	}
+}
+
+// hasGoogleAPIsEndpoint returns true if one of the forwarding rules is a Private Service Connect
+// endpoint for Google APIs in the network.
+func hasGoogleAPIsEndpoint(forwardingRules []*compute.ForwardingRule, network string) bool {
+	for _, rule := range forwardingRules {
+		if googleAPIsPSCTargets[rule.Target] && resourcePath(rule.Network) == resourcePath(network) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSubnetworkURL returns the project, region and name of a subnetwork from its partial URL,
+// projects/project/regions/region/subnetworks/name, or its self link.
+func parseSubnetworkURL(url string) (project, region, name string, ok bool) {
+	parts := strings.Split(resourcePath(url), "/")
+	if len(parts) != 6 || parts[0] != "projects" || parts[2] != "regions" || parts[4] != "subnetworks" {
+		return "", "", "", false
+	}
+	return parts[1], parts[3], parts[5], true
+}
+
+// resourcePath returns the path of a resource from the projects segment on, e.g. of a self link.
+func resourcePath(url string) string {
+	if i := strings.Index(url, "projects/"); i >= 0 {
+		return url[i:]
+	}
+	return url
+}
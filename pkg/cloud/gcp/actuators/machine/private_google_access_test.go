@@ -0,0 +1,140 @@
+package machine
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckPrivateGoogleAccess(t *testing.T) {
+	const network = "https://www.googleapis.com/compute/v1/projects/host-project/global/networks/network"
+	privateNIC := &compute.NetworkInterface{Subnetwork: "projects/host-project/regions/us-east1/subnetworks/subnet"}
+	subnetwork := func(privateGoogleAccess bool) func(string, string, string) (*compute.Subnetwork, error) {
+		return func(project string, region string, name string) (*compute.Subnetwork, error) {
+			if project != "host-project" || region != "us-east1" || name != "subnet" {
+				t.Errorf("Unexpected subnetwork %s/%s/%s", project, region, name)
+			}
+			return &compute.Subnetwork{Name: name, Network: network, PrivateIpGoogleAccess: privateGoogleAccess}, nil
+		}
+	}
+
+	cases := []struct {
+		name                string
+		disabled            bool
+		networkInterfaces   []*compute.NetworkInterface
+		mockSubnetworksGet  func(project string, region string, subnetwork string) (*compute.Subnetwork, error)
+		forwardingRules     []*compute.ForwardingRule
+		expectedError       bool
+		expectedSubnetworks int
+	}{
+		{
+			name:              "Check disabled",
+			disabled:          true,
+			networkInterfaces: []*compute.NetworkInterface{privateNIC},
+		},
+		{
+			name: "Instance with an external IP",
+			networkInterfaces: []*compute.NetworkInterface{{
+				Subnetwork:    privateNIC.Subnetwork,
+				AccessConfigs: []*compute.AccessConfig{{Type: oneToOneNATAccessConfig}},
+			}},
+		},
+		{
+			name:                "Private Google Access enabled",
+			networkInterfaces:   []*compute.NetworkInterface{privateNIC},
+			mockSubnetworksGet:  subnetwork(true),
+			expectedSubnetworks: 1,
+		},
+		{
+			name: "Subnetwork self link",
+			networkInterfaces: []*compute.NetworkInterface{{
+				Subnetwork: "https://www.googleapis.com/compute/v1/projects/host-project/regions/us-east1/subnetworks/subnet",
+			}},
+			mockSubnetworksGet:  subnetwork(true),
+			expectedSubnetworks: 1,
+		},
+		{
+			name:                "Private Service Connect endpoint for Google APIs",
+			networkInterfaces:   []*compute.NetworkInterface{privateNIC},
+			mockSubnetworksGet:  subnetwork(false),
+			forwardingRules:     []*compute.ForwardingRule{{Name: "googleapis", Target: "all-apis", Network: network}},
+			expectedSubnetworks: 1,
+		},
+		{
+			name:               "Private Service Connect endpoint in another network",
+			networkInterfaces:  []*compute.NetworkInterface{privateNIC},
+			mockSubnetworksGet: subnetwork(false),
+			forwardingRules: []*compute.ForwardingRule{
+				{Name: "googleapis", Target: "all-apis", Network: "https://www.googleapis.com/compute/v1/projects/host-project/global/networks/other"},
+				{Name: "service", Target: "projects/producer/regions/us-east1/serviceAttachments/service", Network: network},
+			},
+			expectedError:       true,
+			expectedSubnetworks: 1,
+		},
+		{
+			name:                "Private Google Access disabled",
+			networkInterfaces:   []*compute.NetworkInterface{privateNIC},
+			mockSubnetworksGet:  subnetwork(false),
+			expectedError:       true,
+			expectedSubnetworks: 1,
+		},
+		{
+			name:              "Check is skipped without permission",
+			networkInterfaces: []*compute.NetworkInterface{privateNIC},
+			mockSubnetworksGet: func(_ string, _ string, _ string) (*compute.Subnetwork, error) {
+				return nil, &googleapi.Error{Code: http.StatusForbidden}
+			},
+			expectedSubnetworks: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			subnetworks := 0
+			mockComputeService.MockSubnetworksGet = func(project string, region string, name string) (*compute.Subnetwork, error) {
+				subnetworks++
+				return tc.mockSubnetworksGet(project, region, name)
+			}
+			mockComputeService.MockForwardingRulesList = func(project string, _ string) ([]*compute.ForwardingRule, error) {
+				if project != "host-project" {
+					t.Errorf("Expected the forwarding rules of host-project, got %s", project)
+				}
+				return tc.forwardingRules, nil
+			}
+
+			r := newReconciler(&machineScope{
+				Context:        context.Background(),
+				machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "us-east1"},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+				projectID:      "service-project",
+				options:        Options{PrivateGoogleAccessCheck: !tc.disabled},
+			})
+
+			err := r.checkPrivateGoogleAccess(&preflightState{instance: &compute.Instance{NetworkInterfaces: tc.networkInterfaces}})
+			if subnetworks != tc.expectedSubnetworks {
+				t.Errorf("Expected %d subnetwork lookups, got %d", tc.expectedSubnetworks, subnetworks)
+			}
+			if !tc.expectedError {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if !isInvalidMachineConfigurationError(err) {
+				t.Fatalf("Expected an invalid machine configuration error, got %v", err)
+			}
+			if reason := err.(*preflightError).reason; reason != privateGoogleAccessDisabledReason {
+				t.Errorf("Expected reason %s, got %s", privateGoogleAccessDisabledReason, reason)
+			}
+		})
+	}
+}
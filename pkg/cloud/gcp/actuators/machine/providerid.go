@@ -0,0 +1,32 @@
+package machine
+
+import (
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/providerid"
+)
+
+// instanceID returns the instance of the machine: the one named by its provider ID when it has one
+// that parses, which may have been set in another format by a migration, otherwise the instance of
+// its providerSpec.
+func (r *Reconciler) instanceID() providerid.ProviderID {
+	if r.machine.Spec.ProviderID != nil {
+		if id, err := providerid.Parse(*r.machine.Spec.ProviderID); err == nil {
+			return id
+		}
+	}
+	return providerid.New(r.projectID, r.providerSpec.Zone, r.instanceName)
+}
+
+// reconciledProviderID returns the provider ID to set on the machine. A provider ID that names the
+// instance in a format other than the canonical one is kept, so that the machine stays linked to a
+// node registered with that format by another version of the cloud provider.
+func (r *Reconciler) reconciledProviderID() *string {
+	canonical := r.providerID
+	if r.machine.Spec.ProviderID == nil {
+		return &canonical
+	}
+	current, err := providerid.Parse(*r.machine.Spec.ProviderID)
+	if err != nil || current != providerid.New(r.projectID, r.providerSpec.Zone, r.instanceName) {
+		return &canonical
+	}
+	return r.machine.Spec.ProviderID
+}
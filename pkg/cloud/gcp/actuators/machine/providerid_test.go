@@ -0,0 +1,58 @@
+package machine
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestReconciledProviderID(t *testing.T) {
+	canonical := "gce://project/us-central1-a/worker"
+
+	cases := []struct {
+		name       string
+		providerID *string
+		expected   string
+	}{
+		{name: "No provider ID", expected: canonical},
+		{name: "Canonical provider ID", providerID: pointer.String(canonical), expected: canonical},
+		{name: "Legacy format of the instance", providerID: pointer.String("GCE:///project/us-central1-a/worker"), expected: "GCE:///project/us-central1-a/worker"},
+		{name: "Other instance", providerID: pointer.String("gce://project/us-central1-b/worker"), expected: canonical},
+		{name: "Unparsable", providerID: pointer.String("worker"), expected: canonical},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newReconciler(&machineScope{
+				machine:      &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker"}, Spec: machinev1.MachineSpec{ProviderID: tc.providerID}},
+				providerSpec: &machinev1.GCPMachineProviderSpec{Zone: "us-central1-a"},
+				projectID:    "project",
+				providerID:   canonical,
+			})
+			if providerID := r.reconciledProviderID(); *providerID != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, *providerID)
+			}
+		})
+	}
+}
+
+func TestInstanceID(t *testing.T) {
+	r := newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+			Spec:       machinev1.MachineSpec{ProviderID: pointer.String("projects/legacy-project/zones/us-central1-b/instances/legacy-worker")},
+		},
+		providerSpec: &machinev1.GCPMachineProviderSpec{Zone: "us-central1-a"},
+		projectID:    "project",
+	})
+	if id := r.instanceID(); id.Project != "legacy-project" || id.Zone != "us-central1-b" || id.Instance != "legacy-worker" {
+		t.Errorf("Expected the instance of the provider ID, got %v", id)
+	}
+
+	r.machine.Spec.ProviderID = nil
+	if id := r.instanceID(); id.Project != "project" || id.Zone != "us-central1-a" || id.Instance != "worker" {
+		t.Errorf("Expected the instance of the providerSpec, got %v", id)
+	}
+}
@@ -0,0 +1,306 @@
+package machine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// The limits of the metadata of an instance, see
+// https://cloud.google.com/compute/docs/metadata/setting-custom-metadata#limitations.
+const (
+	maxMetadataKeyLength   = 128
+	maxMetadataValueLength = 256 * 1024
+	maxMetadataTotalLength = 512 * 1024
+)
+
+// maxLabels is the number of labels a GCP resource may carry.
+const maxLabels = 64
+
+// reservedMetadataKeys are the metadata keys set on instances by the controller or by GCP, which a
+// providerSpec must not set: the kubelet arguments built from the node labels and taints, and the
+// keys that make GCP and its tools treat the instance as a member of a managed instance group.
+var reservedMetadataKeys = map[string]string{
+	kubeletExtraArgsMetadataKey: "it is set by the controller from the node labels and taints of the machine",
+	"instance-template":         "it is set by GCP on the instances of managed instance groups",
+	"created-by":                "it is set by GCP on the instances of managed instance groups",
+}
+
+var (
+	// zonePattern matches the zones of a region, e.g. us-central1-a.
+	zonePattern = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+-[a-z]$`)
+	// labelKeyPattern and labelValuePattern are the syntax of GCP labels, see
+	// https://cloud.google.com/compute/docs/labeling-resources#requirements.
+	labelKeyPattern    = regexp.MustCompile(`^[\p{Ll}\p{Lo}][\p{Ll}\p{Lo}\p{N}_-]{0,62}$`)
+	labelValuePattern  = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}_-]{0,63}$`)
+	metadataKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	// serviceAccountEmailPattern matches the emails of the service accounts of a project, e.g.
+	// worker@project.iam.gserviceaccount.com or 123-compute@developer.gserviceaccount.com.
+	serviceAccountEmailPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*@[a-z0-9][a-z0-9.-]*\.gserviceaccount\.com$`)
+)
+
+// minDiskSizeGB is the smallest size of the disk types, see
+// https://cloud.google.com/compute/docs/disks#disk-types. A size of zero uses the size of the image.
+var minDiskSizeGB = map[string]int64{
+	"pd-standard":          10,
+	"pd-balanced":          10,
+	"pd-ssd":               10,
+	"pd-extreme":           500,
+	"hyperdisk-balanced":   4,
+	"hyperdisk-extreme":    64,
+	"hyperdisk-ml":         4,
+	"hyperdisk-throughput": 2048,
+}
+
+// ValidateProviderSpec checks the parts of the providerSpec that can be checked without calling
+// GCP, so that a Machine or MachineSet that would fail at instance creation is rejected on
+// admission instead. The user data is not checked, it is read from a secret at creation.
+func ValidateProviderSpec(providerSpec *machinev1.GCPMachineProviderSpec, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateLocation(providerSpec, fldPath)...)
+
+	if providerSpec.MachineType == "" {
+		errs = append(errs, field.Required(fldPath.Child("machineType"), "machine type must be set"))
+	} else if _, err := util.ParseCustomMachineType(providerSpec.MachineType); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("machineType"), providerSpec.MachineType, err.Error()))
+	}
+
+	for i, disk := range providerSpec.Disks {
+		if disk == nil {
+			continue
+		}
+		diskPath := fldPath.Child("disks").Index(i)
+		if disk.SizeGB < 0 {
+			errs = append(errs, field.Invalid(diskPath.Child("sizeGb"), disk.SizeGB, "must not be negative"))
+		} else if minimum, ok := minDiskSizeGB[disk.Type]; ok && disk.SizeGB != 0 && disk.SizeGB < minimum {
+			errs = append(errs, field.Invalid(diskPath.Child("sizeGb"), disk.SizeGB, fmt.Sprintf("disks of type %s must be at least %d GB", disk.Type, minimum)))
+		}
+		errs = append(errs, validateLabels(disk.Labels, diskPath.Child("labels"))...)
+	}
+
+	errs = append(errs, validateGPUs(providerSpec, fldPath)...)
+	errs = append(errs, validateLabels(providerSpec.Labels, fldPath.Child("labels"))...)
+	errs = append(errs, validateMetadata(providerSpec.Metadata, fldPath.Child("gcpMetadata"))...)
+	errs = append(errs, validateServiceAccounts(providerSpec.ServiceAccounts, fldPath.Child("serviceAccounts"))...)
+
+	for i, pool := range providerSpec.TargetPools {
+		if pool == "" {
+			errs = append(errs, field.Required(fldPath.Child("targetPools").Index(i), "target pools must have a name"))
+		}
+	}
+	if _, err := restartPolicyToBool(providerSpec.RestartPolicy, providerSpec.Preemptible); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("restartPolicy"), providerSpec.RestartPolicy, err.Error()))
+	}
+	return errs
+}
+
+// validateLocation checks that the zone is in the region.
+func validateLocation(providerSpec *machinev1.GCPMachineProviderSpec, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if providerSpec.Region == "" {
+		errs = append(errs, field.Required(fldPath.Child("region"), "region must be set"))
+	}
+	switch {
+	case providerSpec.Zone == "":
+		errs = append(errs, field.Required(fldPath.Child("zone"), "zone must be set"))
+	case !zonePattern.MatchString(providerSpec.Zone):
+		errs = append(errs, field.Invalid(fldPath.Child("zone"), providerSpec.Zone, "must be a zone, e.g. us-central1-a"))
+	case providerSpec.Region != "" && !strings.HasPrefix(providerSpec.Zone, providerSpec.Region+"-"):
+		errs = append(errs, field.Invalid(fldPath.Child("zone"), providerSpec.Zone, fmt.Sprintf("must be a zone of region %s", providerSpec.Region)))
+	}
+	return errs
+}
+
+// validateGPUs checks the guest accelerators against the machine type, like the reconciler does
+// before creating the instance, without checking their availability in the zone.
+func validateGPUs(providerSpec *machinev1.GCPMachineProviderSpec, fldPath *field.Path) field.ErrorList {
+	if len(providerSpec.GPUs) == 0 {
+		return nil
+	}
+	gpusPath := fldPath.Child("gpus")
+	var errs field.ErrorList
+	switch {
+	case strings.HasPrefix(providerSpec.MachineType, "a2-"):
+		return append(errs, field.Forbidden(gpusPath, "A2 machine types have pre-attached guest accelerators, additional guest accelerators are not supported"))
+	case !strings.HasPrefix(providerSpec.MachineType, "n1-"):
+		return append(errs, field.Forbidden(gpusPath, fmt.Sprintf("machine type %s does not support accelerators, only the A2 and N1 machine families do", providerSpec.MachineType)))
+	}
+	if len(providerSpec.GPUs) > 1 {
+		errs = append(errs, field.TooMany(gpusPath, len(providerSpec.GPUs), 1))
+	}
+	for i, gpu := range providerSpec.GPUs {
+		if supportedGpuTypes[gpu.Type] == "" {
+			errs = append(errs, field.NotSupported(gpusPath.Index(i).Child("type"), gpu.Type, sets.StringKeySet(supportedGpuTypes).List()))
+		}
+		if gpu.Count < 1 {
+			errs = append(errs, field.Invalid(gpusPath.Index(i).Child("count"), gpu.Count, "must be at least 1"))
+		}
+	}
+	if providerSpec.OnHostMaintenance == machinev1.MigrateHostMaintenanceType {
+		errs = append(errs, field.Invalid(fldPath.Child("onHostMaintenance"), providerSpec.OnHostMaintenance, "instances with guest accelerators must terminate on host maintenance"))
+	}
+	return errs
+}
+
+// validateLabels checks the syntax of the GCP labels of a resource.
+func validateLabels(labels map[string]string, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(labels) > maxLabels {
+		errs = append(errs, field.TooMany(fldPath, len(labels), maxLabels))
+	}
+	for _, key := range sets.StringKeySet(labels).List() {
+		if !labelKeyPattern.MatchString(key) {
+			errs = append(errs, field.Invalid(fldPath.Key(key), key, "keys must start with a lowercase letter and contain at most 63 lowercase letters, digits, underscores and dashes"))
+		}
+		if value := labels[key]; !labelValuePattern.MatchString(value) {
+			errs = append(errs, field.Invalid(fldPath.Key(key), value, "values must contain at most 63 lowercase letters, digits, underscores and dashes"))
+		}
+	}
+	return errs
+}
+
+// validateMetadata checks the keys of the metadata and the size of their values.
+func validateMetadata(metadata []*machinev1.GCPMetadata, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]bool{}
+	for i, item := range metadata {
+		if item == nil {
+			continue
+		}
+		keyPath := fldPath.Index(i).Child("key")
+		switch {
+		case !metadataKeyPattern.MatchString(item.Key):
+			errs = append(errs, field.Invalid(keyPath, item.Key, "must contain only letters, digits, underscores and dashes"))
+		case len(item.Key) > maxMetadataKeyLength:
+			errs = append(errs, field.TooLong(keyPath, item.Key, maxMetadataKeyLength))
+		case reservedMetadataKeys[item.Key] != "":
+			errs = append(errs, field.Forbidden(keyPath, fmt.Sprintf("metadata key %q is reserved, %s", item.Key, reservedMetadataKeys[item.Key])))
+		case seen[item.Key]:
+			errs = append(errs, field.Duplicate(keyPath, item.Key))
+		}
+		seen[item.Key] = true
+		if item.Value != nil && len(*item.Value) > maxMetadataValueLength {
+			errs = append(errs, field.TooLong(fldPath.Index(i).Child("value"), fmt.Sprintf("the value of metadata key %q, %d bytes", item.Key, len(*item.Value)), maxMetadataValueLength))
+		}
+	}
+	if total := metadataLength(metadata); total > maxMetadataTotalLength {
+		errs = append(errs, field.Invalid(fldPath, fmt.Sprintf("%d bytes", total), fmt.Sprintf("the metadata of an instance must not exceed %d bytes", maxMetadataTotalLength)))
+	}
+	return errs
+}
+
+// ValidateUserData checks the size of the user data of the userDataSecret, set on the instance as
+// the user-data metadata value unless the metadata of the providerSpec replaces it, against the
+// limits of GCP on a metadata value and on the metadata of an instance. Oversized ignition
+// configurations are rejected with the size they have instead of failing the instance insert.
+func ValidateUserData(userData []byte, metadata []*machinev1.GCPMetadata, fldPath *field.Path) field.ErrorList {
+	for _, item := range metadata {
+		if item != nil && (item.Key == userDataMetadataKey || item.Key == windowsScriptMetadataKey) {
+			return nil
+		}
+	}
+	var errs field.ErrorList
+	if len(userData) > maxMetadataValueLength {
+		errs = append(errs, field.TooLong(fldPath, fmt.Sprintf("the user data, %d bytes", len(userData)), maxMetadataValueLength))
+	}
+	if total := len(userDataMetadataKey) + len(userData) + metadataLength(metadata); total > maxMetadataTotalLength {
+		errs = append(errs, field.Invalid(fldPath, fmt.Sprintf("%d bytes", total), fmt.Sprintf("the user data and the metadata of an instance must not exceed %d bytes together", maxMetadataTotalLength)))
+	}
+	return errs
+}
+
+// metadataLength is the size of the keys and values of the metadata, as counted by GCP.
+func metadataLength(metadata []*machinev1.GCPMetadata) int {
+	total := 0
+	for _, item := range metadata {
+		if item == nil {
+			continue
+		}
+		total += len(item.Key)
+		if item.Value != nil {
+			total += len(*item.Value)
+		}
+	}
+	return total
+}
+
+// validateServiceAccounts checks that the service account is the default one or the email of a
+// service account, and that its scopes are URLs or gcloud aliases.
+func validateServiceAccounts(serviceAccounts []machinev1.GCPServiceAccount, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(serviceAccounts) > 1 {
+		errs = append(errs, field.TooMany(fldPath, len(serviceAccounts), 1))
+	}
+	for i, serviceAccount := range serviceAccounts {
+		emailPath := fldPath.Index(i).Child("email")
+		switch {
+		case serviceAccount.Email == "":
+			errs = append(errs, field.Required(emailPath, "service account email must be set"))
+		case serviceAccount.Email != gceDefaultServiceAccount && !serviceAccountEmailPattern.MatchString(serviceAccount.Email):
+			errs = append(errs, field.Invalid(emailPath, serviceAccount.Email, "must be the email of a service account, e.g. name@project.iam.gserviceaccount.com, or default"))
+		}
+		for j, scope := range serviceAccount.Scopes {
+			if _, ok := serviceAccountScopeAliases[scope]; !ok && !strings.HasPrefix(scope, "https://") {
+				errs = append(errs, field.Invalid(fldPath.Index(i).Child("scopes").Index(j), scope, "must be a URL or a gcloud scope alias"))
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateProviderSpecUpdate checks that an update of the providerSpec of a Machine whose instance
+// exists changes only what can be reconciled on the instance. Moving the instance to another
+// project, region or zone, recreating its boot disk from another image, or attaching it to another
+// network cannot be done in place; the fields that can, e.g. the labels, tags and metadata, are
+// allowed.
+func ValidateProviderSpecUpdate(newSpec, oldSpec *machinev1.GCPMachineProviderSpec, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	immutable := func(path *field.Path, newValue, oldValue string) {
+		if newValue != oldValue {
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("cannot be changed from %q to %q on a machine with an instance: %s", oldValue, newValue, replaceMachineHint)))
+		}
+	}
+	immutable(fldPath.Child("projectID"), newSpec.ProjectID, oldSpec.ProjectID)
+	immutable(fldPath.Child("region"), newSpec.Region, oldSpec.Region)
+	immutable(fldPath.Child("zone"), newSpec.Zone, oldSpec.Zone)
+
+	newBoot, newIndex := bootDisk(newSpec.Disks)
+	oldBoot, _ := bootDisk(oldSpec.Disks)
+	if newBoot != nil && oldBoot != nil {
+		immutable(fldPath.Child("disks").Index(newIndex).Child("image"), newBoot.Image, oldBoot.Image)
+	}
+
+	if len(newSpec.NetworkInterfaces) != len(oldSpec.NetworkInterfaces) {
+		errs = append(errs, field.Forbidden(fldPath.Child("networkInterfaces"), fmt.Sprintf("network interfaces cannot be added or removed on a machine with an instance: %s", replaceMachineHint)))
+		return errs
+	}
+	for i, newInterface := range newSpec.NetworkInterfaces {
+		oldInterface := oldSpec.NetworkInterfaces[i]
+		if newInterface == nil || oldInterface == nil {
+			continue
+		}
+		interfacePath := fldPath.Child("networkInterfaces").Index(i)
+		immutable(interfacePath.Child("projectID"), newInterface.ProjectID, oldInterface.ProjectID)
+		immutable(interfacePath.Child("network"), newInterface.Network, oldInterface.Network)
+		immutable(interfacePath.Child("subnetwork"), newInterface.Subnetwork, oldInterface.Subnetwork)
+	}
+	return errs
+}
+
+// replaceMachineHint tells how to apply a change that cannot be made in place.
+const replaceMachineHint = "update the MachineSet and delete the machine instead, so that the MachineSet replaces it with a new instance"
+
+// bootDisk returns the boot disk of the disks and its index, nil when there is none.
+func bootDisk(disks []*machinev1.GCPDisk) (*machinev1.GCPDisk, int) {
+	for i, disk := range disks {
+		if disk != nil && disk.Boot {
+			return disk, i
+		}
+	}
+	return nil, -1
+}
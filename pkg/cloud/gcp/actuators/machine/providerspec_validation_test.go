@@ -0,0 +1,269 @@
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+)
+
+func TestValidateProviderSpec(t *testing.T) {
+	validSpec := func() *machinev1.GCPMachineProviderSpec {
+		return &machinev1.GCPMachineProviderSpec{
+			Region:      "us-central1",
+			Zone:        "us-central1-a",
+			MachineType: "n1-standard-4",
+			Disks:       []*machinev1.GCPDisk{{Boot: true, Type: "pd-ssd", SizeGB: 128, Labels: map[string]string{"team": "infra"}}},
+			Labels:      map[string]string{"kubernetes-io-cluster-abc": "owned", "cost_center": ""},
+			Metadata:    []*machinev1.GCPMetadata{{Key: "startup-script", Value: pointer.String("echo hello")}},
+			ServiceAccounts: []machinev1.GCPServiceAccount{{
+				Email:  "worker@project.iam.gserviceaccount.com",
+				Scopes: []string{"cloud-platform", "https://www.googleapis.com/auth/devstorage.read_only"},
+			}},
+			GPUs: []machinev1.GCPGPUConfig{{Type: "nvidia-tesla-t4", Count: 1}},
+		}
+	}
+
+	cases := []struct {
+		name           string
+		mutate         func(*machinev1.GCPMachineProviderSpec)
+		expectedFields []string
+	}{
+		{
+			name:   "Valid",
+			mutate: func(*machinev1.GCPMachineProviderSpec) {},
+		},
+		{
+			name:   "Default service account",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) { spec.ServiceAccounts[0].Email = "default" },
+		},
+		{
+			name:   "Disk size from the image",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) { spec.Disks[0].SizeGB = 0 },
+		},
+		{
+			name:           "Zone of another region",
+			mutate:         func(spec *machinev1.GCPMachineProviderSpec) { spec.Zone = "europe-west1-b" },
+			expectedFields: []string{"spec.zone"},
+		},
+		{
+			name:           "Region as zone",
+			mutate:         func(spec *machinev1.GCPMachineProviderSpec) { spec.Zone = "us-central1" },
+			expectedFields: []string{"spec.zone"},
+		},
+		{
+			name: "Missing location and machine type",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.Region, spec.Zone, spec.MachineType, spec.GPUs = "", "", "", nil
+			},
+			expectedFields: []string{"spec.region", "spec.zone", "spec.machineType"},
+		},
+		{
+			name: "Disk smaller than its type allows",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.Disks[0].Type, spec.Disks[0].SizeGB = "pd-extreme", 100
+			},
+			expectedFields: []string{"spec.disks[0].sizeGb"},
+		},
+		{
+			name:           "Invalid custom machine type",
+			mutate:         func(spec *machinev1.GCPMachineProviderSpec) { spec.MachineType, spec.GPUs = "custom-3-1024", nil },
+			expectedFields: []string{"spec.machineType"},
+		},
+		{
+			name:           "GPUs on an A2 machine type",
+			mutate:         func(spec *machinev1.GCPMachineProviderSpec) { spec.MachineType = "a2-highgpu-1g" },
+			expectedFields: []string{"spec.gpus"},
+		},
+		{
+			name:           "GPUs on a machine type without accelerators",
+			mutate:         func(spec *machinev1.GCPMachineProviderSpec) { spec.MachineType = "e2-standard-4" },
+			expectedFields: []string{"spec.gpus"},
+		},
+		{
+			name: "Unsupported GPU that migrates on maintenance",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.GPUs[0] = machinev1.GCPGPUConfig{Type: "nvidia-h100-80gb", Count: 0}
+				spec.OnHostMaintenance = machinev1.MigrateHostMaintenanceType
+			},
+			expectedFields: []string{"spec.gpus[0].type", "spec.gpus[0].count", "spec.onHostMaintenance"},
+		},
+		{
+			name: "Invalid labels",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.Labels = map[string]string{"Team": "infra", "env": "Prod"}
+				spec.Disks[0].Labels = map[string]string{"1st": "disk"}
+			},
+			expectedFields: []string{"spec.disks[0].labels[1st]", "spec.labels[Team]", "spec.labels[env]"},
+		},
+		{
+			name: "Invalid metadata",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.Metadata = []*machinev1.GCPMetadata{
+					{Key: "startup script", Value: pointer.String("")},
+					{Key: "large", Value: pointer.String(strings.Repeat("a", maxMetadataValueLength+1))},
+					{Key: "large", Value: pointer.String(strings.Repeat("a", maxMetadataValueLength))},
+				}
+			},
+			expectedFields: []string{"spec.gcpMetadata[0].key", "spec.gcpMetadata[1].value", "spec.gcpMetadata[2].key", "spec.gcpMetadata"},
+		},
+		{
+			name: "Reserved metadata keys",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.Metadata = []*machinev1.GCPMetadata{
+					{Key: "user-data", Value: pointer.String("{}")},
+					{Key: "kubelet-extra-args", Value: pointer.String("--max-pods=50")},
+					{Key: "instance-template", Value: pointer.String("workers")},
+				}
+			},
+			expectedFields: []string{"spec.gcpMetadata[1].key", "spec.gcpMetadata[2].key"},
+		},
+		{
+			name: "Invalid service account",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.ServiceAccounts[0].Email = "worker@example.com"
+				spec.ServiceAccounts[0].Scopes = []string{"storage"}
+			},
+			expectedFields: []string{"spec.serviceAccounts[0].email", "spec.serviceAccounts[0].scopes[0]"},
+		},
+		{
+			name: "Preemptible instance restarted automatically",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.Preemptible, spec.RestartPolicy = true, machinev1.RestartPolicyAlways
+			},
+			expectedFields: []string{"spec.restartPolicy"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := validSpec()
+			tc.mutate(spec)
+			errs := ValidateProviderSpec(spec, field.NewPath("spec"))
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tc.expectedFields, ",") {
+				t.Errorf("Expected errors on %v, got %v", tc.expectedFields, errs)
+			}
+		})
+	}
+}
+
+func TestValidateProviderSpecUpdate(t *testing.T) {
+	oldSpec := func() *machinev1.GCPMachineProviderSpec {
+		return &machinev1.GCPMachineProviderSpec{
+			ProjectID:         "project",
+			Region:            "us-central1",
+			Zone:              "us-central1-a",
+			MachineType:       "n1-standard-4",
+			Disks:             []*machinev1.GCPDisk{{Boot: false, Image: "data"}, {Boot: true, Image: "rhcos-415"}},
+			NetworkInterfaces: []*machinev1.GCPNetworkInterface{{Network: "net", Subnetwork: "workers"}},
+			Labels:            map[string]string{"team": "infra"},
+			Tags:              []string{"worker"},
+		}
+	}
+
+	cases := []struct {
+		name           string
+		mutate         func(*machinev1.GCPMachineProviderSpec)
+		expectedFields []string
+	}{
+		{
+			name: "Mutable fields",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.Labels = map[string]string{"team": "platform"}
+				spec.Tags = append(spec.Tags, "ingress")
+				spec.Metadata = []*machinev1.GCPMetadata{{Key: "env", Value: pointer.String("prod")}}
+				spec.MachineType = "n1-standard-8"
+				spec.Disks[0].Image = "other-data"
+			},
+		},
+		{
+			name: "Location",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.ProjectID, spec.Region, spec.Zone = "other", "europe-west1", "europe-west1-b"
+			},
+			expectedFields: []string{"spec.projectID", "spec.region", "spec.zone"},
+		},
+		{
+			name:           "Boot image",
+			mutate:         func(spec *machinev1.GCPMachineProviderSpec) { spec.Disks[1].Image = "rhcos-416" },
+			expectedFields: []string{"spec.disks[1].image"},
+		},
+		{
+			name:           "Subnetwork",
+			mutate:         func(spec *machinev1.GCPMachineProviderSpec) { spec.NetworkInterfaces[0].Subnetwork = "infra" },
+			expectedFields: []string{"spec.networkInterfaces[0].subnetwork"},
+		},
+		{
+			name: "Added network interface",
+			mutate: func(spec *machinev1.GCPMachineProviderSpec) {
+				spec.NetworkInterfaces = append(spec.NetworkInterfaces, &machinev1.GCPNetworkInterface{Network: "other"})
+			},
+			expectedFields: []string{"spec.networkInterfaces"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := oldSpec()
+			tc.mutate(spec)
+			errs := ValidateProviderSpecUpdate(spec, oldSpec(), field.NewPath("spec"))
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tc.expectedFields, ",") {
+				t.Errorf("Expected errors on %v, got %v", tc.expectedFields, errs)
+			}
+			for _, err := range errs {
+				if !strings.Contains(err.Error(), "MachineSet") {
+					t.Errorf("Expected the error to point at replacing the machine, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateUserData(t *testing.T) {
+	large := pointer.String(strings.Repeat("a", maxMetadataValueLength/2))
+
+	cases := []struct {
+		name           string
+		userData       int
+		metadata       []*machinev1.GCPMetadata
+		expectedErrors int
+	}{
+		{name: "Small", userData: 1024, metadata: []*machinev1.GCPMetadata{{Key: "startup-script", Value: large}}},
+		{name: "Larger than a metadata value", userData: maxMetadataValueLength + 1, expectedErrors: 1},
+		{
+			name:           "Larger than the metadata of an instance with the other items",
+			userData:       maxMetadataValueLength,
+			metadata:       []*machinev1.GCPMetadata{{Key: "a", Value: large}, {Key: "b", Value: large}},
+			expectedErrors: 1,
+		},
+		{
+			name:     "Replaced by the metadata",
+			userData: maxMetadataValueLength + 1,
+			metadata: []*machinev1.GCPMetadata{{Key: "user-data", Value: pointer.String("{}")}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := ValidateUserData(make([]byte, tc.userData), tc.metadata, field.NewPath("spec", "userDataSecret"))
+			if len(errs) != tc.expectedErrors {
+				t.Errorf("Expected %d errors, got %v", tc.expectedErrors, errs)
+			}
+			for _, err := range errs {
+				if err.Field != "spec.userDataSecret" {
+					t.Errorf("Expected an error on spec.userDataSecret, got %v", err)
+				}
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"net/http"
 	"strings"
 	"time"
 
@@ -13,6 +13,7 @@ import (
 	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	"github.com/openshift/machine-api-operator/pkg/metrics"
 	"github.com/openshift/machine-api-operator/pkg/util/windows"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
 	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
@@ -20,7 +21,9 @@ import (
 	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -33,6 +36,7 @@ const (
 	machineTypeFmt            = "zones/%s/machineTypes/%s"
 	acceleratorTypeFmt        = "zones/%s/acceleratorTypes/%s"
 	windowsScriptMetadataKey  = "sysprep-specialize-script-ps1"
+	userDataMetadataKey       = "user-data"
 	openshiftMachineRoleLabel = "machine.openshift.io/cluster-api-machine-role"
 	masterMachineRole         = "master"
 )
@@ -40,12 +44,39 @@ const (
 // Reconciler are list of services required by machine actuator, easy to create a fake
 type Reconciler struct {
 	*machineScope
+
+	// loadBalancerConfig holds the names of the load balancer resources, it is loaded
+	// before control plane machines are (un)registered.
+	loadBalancerConfig *loadBalancerConfig
+	// loadBalancerMembership collects the load balancer resources the instance is registered in
+	// while the load balancer registration is reconciled, nil otherwise.
+	loadBalancerMembership *loadBalancerMembership
 }
 
 // NewReconciler populates all the services based on input scope
 func newReconciler(scope *machineScope) *Reconciler {
+	if scope.clock == nil {
+		scope.clock = clock.RealClock{}
+	}
+	if scope.httpClient == nil {
+		scope.httpClient = http.DefaultClient
+	}
+	if scope.eventRecorder == nil {
+		// A FakeRecorder without an events channel drops all events.
+		scope.eventRecorder = &record.FakeRecorder{}
+	}
+	if scope.log.GetSink() == nil && scope.machine != nil {
+		zone := ""
+		if scope.providerSpec != nil {
+			zone = scope.providerSpec.Zone
+		}
+		scope.log = newScopeLogger(scope.Context, scope.machine, scope.projectID, zone)
+	}
+	if scope.instanceName == "" && scope.machine != nil {
+		scope.instanceName = scope.machine.Name
+	}
 	return &Reconciler{
-		scope,
+		machineScope: scope,
 	}
 }
 
@@ -86,13 +117,10 @@ func restartPolicyToBool(policy machinev1.GCPRestartPolicyType, preemptible bool
 	return nil, fmt.Errorf("unrecognized restart policy: %s", policy)
 }
 
-// machineTypeAcceleratorCount represents nvidia-tesla-A100 GPUs which are only compatible with A2 machine family
-func (r *Reconciler) checkQuota(machineTypeAcceleratorCount int64) error {
-	region, err := r.computeService.RegionGet(r.projectID, r.providerSpec.Region)
-	if err != nil {
-		return machinecontroller.InvalidMachineConfiguration(fmt.Sprintf("Failed to get region %s via compute service: %v", r.providerSpec.Region, err))
-	}
-	quotas := region.Quotas
+// validateAcceleratorType checks that the accelerator type is available in the zone and supported.
+// machineTypeAcceleratorCount represents nvidia-tesla-A100 GPUs which are only compatible with A2 machine family.
+// The quota of the accelerators is checked by the pre-flight checks.
+func (r *Reconciler) validateAcceleratorType(machineTypeAcceleratorCount int64) error {
 	var guestAccelerators = []machinev1.GCPGPUConfig{}
 	// When the machine type has associated accelerator instances (A2 machine family), accelerators will be nvidia-tesla-A100s.
 	// Additional guest accelerators are not allowed so ignore the providerSpec GuestAccelerators.
@@ -101,34 +129,16 @@ func (r *Reconciler) checkQuota(machineTypeAcceleratorCount int64) error {
 	} else {
 		guestAccelerators = r.providerSpec.GPUs
 	}
-	// validate zone and then quota
 	// guestAccelerators slice can not store more than 1 element.
 	// More than one accelerator included in request results in error -> googleapi: Error 413: Value for field 'resource.guestAccelerators' is too large: maximum size 1 element(s); actual size 2., fieldSizeTooLarge
 	accelerator := guestAccelerators[0]
-	_, err = r.computeService.AcceleratorTypeGet(r.projectID, r.providerSpec.Zone, accelerator.Type)
+	_, err := r.computeService.AcceleratorTypeGet(r.projectID, r.providerSpec.Zone, accelerator.Type)
 	if err != nil {
 		return machinecontroller.InvalidMachineConfiguration(fmt.Sprintf("AcceleratorType %s not available in the zone %s : %v", accelerator.Type, r.providerSpec.Zone, err))
 	}
-	metric := supportedGpuTypes[accelerator.Type]
-	if metric == "" {
+	if supportedGpuTypes[accelerator.Type] == "" {
 		return machinecontroller.InvalidMachineConfiguration(fmt.Sprintf("Unsupported accelerator type %s", accelerator.Type))
 	}
-	// preemptible instances have separate quota
-	if r.providerSpec.Preemptible {
-		metric = "PREEMPTIBLE_" + metric
-	}
-	// check quota for GA
-	for i, q := range quotas {
-		if q.Metric == metric {
-			if int32(q.Usage)+accelerator.Count > int32(q.Limit) {
-				return machinecontroller.InvalidMachineConfiguration(fmt.Sprintf("Quota exceeded. Metric: %s. Usage: %v. Limit: %v.", metric, q.Usage, q.Limit))
-			}
-			break
-		}
-		if i == len(quotas)-1 {
-			return machinecontroller.InvalidMachineConfiguration(fmt.Sprintf("No quota found. Metric: %s.", metric))
-		}
-	}
 	return nil
 }
 
@@ -148,10 +158,10 @@ func (r *Reconciler) validateGuestAccelerators() error {
 	switch {
 	case a2MachineFamily[machineType] != 0:
 		// a2 family machine - has fixed type and count of GPUs
-		return r.checkQuota(a2MachineFamily[machineType])
+		return r.validateAcceleratorType(a2MachineFamily[machineType])
 	case containsString(n1MachineFamily, machineType):
 		// n1 family machine
-		return r.checkQuota(0)
+		return r.validateAcceleratorType(0)
 	default:
 		// any other machine type
 		return machinecontroller.InvalidMachineConfiguration(fmt.Sprintf("MachineType %s is not available in the zone %s.", r.providerSpec.MachineType, r.providerSpec.Zone))
@@ -164,21 +174,41 @@ func (r *Reconciler) create() error {
 		return machinecontroller.InvalidMachineConfiguration("failed validating machine provider spec: %v", err)
 	}
 
+	if !r.dryRun {
+		if err := r.checkDuplicateMachine(); err != nil {
+			return err
+		}
+
+		if err := r.reconcileCreateOperations(); err != nil {
+			return err
+		}
+		if err := r.reconcilePreviousCreateOperation(); err != nil {
+			return err
+		}
+		if err := r.selectSpotZone(); err != nil {
+			return err
+		}
+	}
+
 	labels, err := util.GetLabelsList(r.gcpLabelsTagsFeatureEnabled, r.coreClient,
 		r.machine.Labels[machinev1.MachineClusterIDLabel], r.providerSpec.Labels)
 	if err != nil {
 		return fmt.Errorf("error getting user-defined labels for machine %s: %w", r.machine.Name, err)
 	}
 
+	if r.providerSpec.CanIPForward {
+		r.log.Info("Creating instance with IP forwarding enabled, it will be able to route traffic for other IPs")
+	}
+
 	zone := r.providerSpec.Zone
 	instance := &compute.Instance{
 		CanIpForward:       r.providerSpec.CanIPForward,
 		DeletionProtection: r.providerSpec.DeletionProtection,
 		Labels:             labels,
 		MachineType:        fmt.Sprintf(machineTypeFmt, zone, r.providerSpec.MachineType),
-		Name:               r.machine.Name,
+		Name:               r.instanceName,
 		Tags: &compute.Tags{
-			Items: r.providerSpec.Tags,
+			Items: r.desiredTags(),
 		},
 		Scheduling: &compute.Scheduling{
 			Preemptible:       r.providerSpec.Preemptible,
@@ -191,6 +221,14 @@ func (r *Reconciler) create() error {
 		},
 	}
 
+	if instance.ReservationAffinity, err = r.reservationAffinity(); err != nil {
+		return err
+	}
+
+	if instance.AdvancedMachineFeatures, err = r.advancedMachineFeatures(); err != nil {
+		return err
+	}
+
 	var userTags map[string]string
 	if r.featureGates.Enabled(configv1.FeatureGateGCPLabelsTags) {
 		userTags, err = util.GetResourceManagerTags(r.Context, r.coreClient, r.tagService, r.providerSpec.ResourceManagerTags)
@@ -241,8 +279,30 @@ func (r *Reconciler) create() error {
 	}
 
 	// disks
+	diskResourcePolicies, err := r.diskResourcePolicies()
+	if err != nil {
+		return err
+	}
+	guestOSFeatures, err := r.guestOSFeatures()
+	if err != nil {
+		return err
+	}
+	sourceImageEncryptionKey, err := r.sourceImageEncryptionKey()
+	if err != nil {
+		return err
+	}
+	if sourceImageEncryptionKey != nil && !hasBootDisk(r.providerSpec.Disks) {
+		return machinecontroller.InvalidMachineConfiguration("a source image encryption key is set but the machine has no boot disk")
+	}
+	diskPerformances, err := r.diskPerformances()
+	if err != nil {
+		return err
+	}
+	if _, err := r.confidentialDisks(); err != nil {
+		return err
+	}
 	var disks = []*compute.AttachedDisk{}
-	for _, disk := range r.providerSpec.Disks {
+	for i, disk := range r.providerSpec.Disks {
 		srcImage := disk.Image
 		if !strings.Contains(disk.Image, "/") {
 			// only image name provided therefore defaulting to the current project
@@ -255,53 +315,96 @@ func (r *Reconciler) create() error {
 			return fmt.Errorf("error getting user-defined labels for machine disk %s: %w", r.machine.Name, err)
 		}
 
-		disks = append(disks, &compute.AttachedDisk{
+		attachedDisk := &compute.AttachedDisk{
 			AutoDelete: disk.AutoDelete,
 			Boot:       disk.Boot,
 			InitializeParams: &compute.AttachedDiskInitializeParams{
 				DiskSizeGb:          disk.SizeGB,
 				DiskType:            fmt.Sprintf("zones/%s/diskTypes/%s", zone, disk.Type),
 				SourceImage:         srcImage,
-				Labels:              labels,
+				Labels:              r.withMachineNameLabel(labels),
 				ResourceManagerTags: userTags,
+				ResourcePolicies:    diskResourcePolicies,
 			},
 			DiskEncryptionKey: generateDiskEncryptionKey(disk.EncryptionKey, r.projectID),
-		})
+		}
+		if performance, ok := diskPerformances[i]; ok {
+			attachedDisk.InitializeParams.ProvisionedIops = performance.ProvisionedIops
+			attachedDisk.InitializeParams.ProvisionedThroughput = performance.ProvisionedThroughput
+		}
+		if disk.Boot {
+			// Guest OS features are only applicable to bootable images.
+			attachedDisk.GuestOsFeatures = guestOSFeatures
+			attachedDisk.InitializeParams.SourceImageEncryptionKey = sourceImageEncryptionKey
+		}
+		disks = append(disks, attachedDisk)
 	}
 	instance.Disks = disks
 
 	// networking
 	var networkInterfaces = []*compute.NetworkInterface{}
 
-	for _, nic := range r.providerSpec.NetworkInterfaces {
-		accessConfigs := []*compute.AccessConfig{}
-		if nic.PublicIP {
-			accessConfigs = append(accessConfigs, &compute.AccessConfig{})
+	if _, ok := r.getAnnotation(externalAddressAnnotation); ok && len(r.providerSpec.NetworkInterfaces) == 0 {
+		return machinecontroller.InvalidMachineConfiguration("%s annotation is set but the machine has no network interfaces", externalAddressAnnotation)
+	}
+	networkAttachments, err := r.networkAttachments()
+	if err != nil {
+		return err
+	}
+	for i, nic := range r.providerSpec.NetworkInterfaces {
+		if attachment, ok := networkAttachments[i]; ok {
+			networkInterfaces = append(networkInterfaces, &compute.NetworkInterface{NetworkAttachment: attachment})
+			continue
+		}
+
+		accessConfigs, err := r.buildAccessConfigs(i, nic.PublicIP)
+		if err != nil {
+			return err
 		}
 		computeNIC := &compute.NetworkInterface{
 			AccessConfigs: accessConfigs,
 		}
-		projectID := nic.ProjectID
-		if projectID == "" {
-			projectID = r.projectID
-		}
 		if len(nic.Network) != 0 {
-			computeNIC.Network = fmt.Sprintf("projects/%s/global/networks/%s", projectID, nic.Network)
+			computeNIC.Network = r.networkURL(nic)
 		}
 		if len(nic.Subnetwork) != 0 {
-			computeNIC.Subnetwork = fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", projectID, r.providerSpec.Region, nic.Subnetwork)
+			computeNIC.Subnetwork = r.subnetworkURL(nic)
 		}
 		networkInterfaces = append(networkInterfaces, computeNIC)
 	}
+	if len(r.providerSpec.NetworkInterfaces) == 0 && r.options.MissingNetworkPolicy == MissingNetworkPolicyProjectDefault {
+		defaultNIC, err := r.projectDefaultNetworkInterface()
+		if err != nil {
+			return err
+		}
+		networkInterfaces = append(networkInterfaces, defaultNIC)
+	}
 	instance.NetworkInterfaces = networkInterfaces
 
+	staticInternalAddress, err := r.getBoolAnnotation(staticInternalAddressAnnotation)
+	if err != nil {
+		return err
+	}
+	if staticInternalAddress {
+		if r.fromInstanceTemplate() {
+			return machinecontroller.InvalidMachineConfiguration("%s annotation cannot be set on machines created from an instance template", staticInternalAddressAnnotation)
+		}
+		if len(r.providerSpec.NetworkInterfaces) == 0 {
+			return machinecontroller.InvalidMachineConfiguration("%s annotation is set but the machine has no network interfaces", staticInternalAddressAnnotation)
+		}
+		if !r.dryRun {
+			networkIP, err := r.reserveInternalAddress(r.providerSpec.NetworkInterfaces[0])
+			if err != nil {
+				return err
+			}
+			networkInterfaces[0].NetworkIP = networkIP
+		}
+	}
+
 	// serviceAccounts
-	var serviceAccounts = []*compute.ServiceAccount{}
-	for _, sa := range r.providerSpec.ServiceAccounts {
-		serviceAccounts = append(serviceAccounts, &compute.ServiceAccount{
-			Email:  sa.Email,
-			Scopes: sa.Scopes,
-		})
+	serviceAccounts, err := r.serviceAccounts()
+	if err != nil {
+		return err
 	}
 	instance.ServiceAccounts = serviceAccounts
 
@@ -313,7 +416,7 @@ func (r *Reconciler) create() error {
 	// check to see if this is a windows machine, if so then the user data secret
 	// should be set in the metadata using a key to designate that it is a windows
 	// boot script.
-	userdataKey := "user-data"
+	userdataKey := userDataMetadataKey
 	if windows.IsMachineOSWindows(*r.machine) {
 		userdataKey = windowsScriptMetadataKey
 		// ensure that the powershell script is not enclosed by <powershell> tags
@@ -337,35 +440,83 @@ func (r *Reconciler) create() error {
 			})
 		}
 	}
+	kubeletExtraArgs, err := r.kubeletExtraArgs()
+	if err != nil {
+		return err
+	}
+	if kubeletExtraArgs != "" {
+		metadataItems = append(metadataItems, &compute.MetadataItems{
+			Key:   kubeletExtraArgsMetadataKey,
+			Value: &kubeletExtraArgs,
+		})
+	}
 	instance.Metadata = &compute.Metadata{
 		Items: metadataItems,
 	}
 
-	_, err = r.computeService.InstancesInsert(r.projectID, zone, instance)
+	sourceInstanceTemplate, err := r.sourceInstanceTemplate()
+	if err != nil {
+		return err
+	}
+	if sourceInstanceTemplate != "" {
+		instance = instanceFromTemplate(instance)
+	}
+
+	if err := r.runPreflightChecks(instance); err != nil {
+		return err
+	}
+	if r.dryRun {
+		r.log.Info("Dry run of the instance creation succeeded", "instance", instance.Name, "zone", zone)
+		return nil
+	}
+	r.recordInstanceSpecHash(instance)
+
+	var operation *compute.Operation
+	if sourceInstanceTemplate != "" {
+		r.log.Info("Creating instance from instance template", "instanceTemplate", sourceInstanceTemplate)
+		operation, err = r.computeService.InstancesInsertFromTemplate(r.projectID, zone, sourceInstanceTemplate, instance)
+	} else {
+		operation, err = r.computeService.InstancesInsert(r.projectID, zone, instance)
+	}
+	r.recordCloudMutation(instanceInsertEvent, fmt.Sprintf("insert instance %s in zone %s", instance.Name, zone), operation, err)
 	if err != nil {
 		metrics.RegisterFailedInstanceCreate(&metrics.MachineLabels{
 			Name:      r.machine.Name,
 			Namespace: r.machine.Namespace,
 			Reason:    "failed to create instance via compute service",
 		})
+		reason, classified := gcperrors.Classify(err)
+		if reason == gcperrors.ZoneResourcesExhausted {
+			if fallbackErr := r.fallBackToNextZone(err.Error()); fallbackErr != nil {
+				return fallbackErr
+			}
+		}
+		conditionReason := machineCreationFailedReason
+		if classified {
+			conditionReason = string(reason)
+		}
+		recordInstanceCreation(zone, r.providerSpec.MachineType, conditionReason)
 		if reconcileWithCloudError := r.reconcileMachineWithCloudState(&metav1.Condition{
 			Type:    string(machinev1.MachineCreated),
-			Reason:  machineCreationFailedReason,
+			Reason:  conditionReason,
 			Message: err.Error(),
 			Status:  metav1.ConditionFalse,
 		}); reconcileWithCloudError != nil {
-			klog.Errorf("Failed to reconcile machine with cloud state: %v", reconcileWithCloudError)
-		}
-		if googleError, ok := err.(*googleapi.Error); ok {
-			// we return InvalidMachineConfiguration for 4xx errors which by convention signal client misconfiguration
-			// https://tools.ietf.org/html/rfc2616#section-6.1.1
-			if strings.HasPrefix(strconv.Itoa(googleError.Code), "4") {
-				klog.Infof("Error launching instance: %v", googleError)
-				return machinecontroller.InvalidMachineConfiguration("error launching instance: %v", googleError.Error())
-			}
+			r.log.Error(reconcileWithCloudError, "Failed to reconcile machine with cloud state")
+		}
+		if classified {
+			r.log.Error(err, "Failed to launch instance", "reason", conditionReason)
+			return gcperrors.ToMachineError(fmt.Errorf("error launching instance: %w", err))
 		}
 		return fmt.Errorf("failed to create instance via compute service: %v", err)
 	}
+	if operation != nil && operation.Name != "" {
+		if r.machine.Annotations == nil {
+			r.machine.Annotations = map[string]string{}
+		}
+		r.machine.Annotations[createOperationAnnotation] = operation.Name
+	}
+	r.trackOperation(insertOperationAction, operation)
 	return r.reconcileMachineWithCloudState(nil)
 }
 
@@ -374,87 +525,218 @@ func (r *Reconciler) update() error {
 		return machinecontroller.InvalidMachineConfiguration("failed validating machine provider spec: %v", err)
 	}
 
+	if err := r.reconcileLoadBalancerRegistration(); err != nil {
+		return err
+	}
+	if err := r.reconcileMachineWithCloudState(nil); err != nil {
+		return err
+	}
+	// Operations still running are polled again by the following updates, which the machine
+	// controller requeues until the machine has a node.
+	if _, _, err := r.reconcileOperations(); err != nil {
+		r.log.Error(err, "Failed to poll pending operations")
+	}
+	r.reconcileProvisioningTimeout()
+	return nil
+}
+
+// reconcileLoadBalancerRegistration adds the instance to its target pools and, for control plane
+// machines, to the control plane instance group or network endpoint group, unless the machine
+// opted out of it.
+func (r *Reconciler) reconcileLoadBalancerRegistration() error {
+	isControlPlane := r.machineScope.machine.ObjectMeta.Labels[openshiftMachineRoleLabel] == masterMachineRole
+	if len(r.providerSpec.TargetPools) == 0 && !isControlPlane {
+		return nil
+	}
+
+	skip, err := r.getBoolAnnotation(skipLoadBalancerRegistrationAnnotation)
+	if err != nil {
+		return err
+	}
+	if skip {
+		r.log.V(logLevelDetail).Info("Skipping load balancer registration")
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+			Type:    loadBalancerRegistrationConditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  loadBalancerRegistrationSkippedReason,
+			Message: fmt.Sprintf(loadBalancerRegistrationSkippedMessageFmt, skipLoadBalancerRegistrationAnnotation),
+		})
+		return nil
+	}
+
+	r.loadBalancerMembership = &loadBalancerMembership{}
+	defer func() { r.loadBalancerMembership = nil }()
+
 	// Add target pools, if necessary
 	if err := r.processTargetPools(true, r.addInstanceToTargetPool); err != nil {
 		return err
 	}
 
 	// Add control plane machines to instance group, if necessary
-	if r.machineScope.machine.ObjectMeta.Labels[openshiftMachineRoleLabel] == masterMachineRole {
-		if err := r.registerInstanceToControlPlaneInstanceGroup(); err != nil {
+	if isControlPlane {
+		if err := r.loadLoadBalancerConfig(); err != nil {
+			return err
+		}
+		if group := r.controlPlaneEndpointGroupName(); group != "" {
+			if err := r.registerInstanceToNetworkEndpointGroup(group); err != nil {
+				return fmt.Errorf("failed to register instance to network endpoint group: %v", err)
+			}
+		} else if err := r.registerInstanceToControlPlaneInstanceGroup(); err != nil {
 			return fmt.Errorf("failed to register instance to instance group: %v", err)
 		}
 	}
-	return r.reconcileMachineWithCloudState(nil)
+
+	// Only report the registration once it was skipped before, to not add a condition to every machine.
+	if findCondition(r.providerStatus.Conditions, loadBalancerRegistrationConditionType) != nil {
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+			Type:    loadBalancerRegistrationConditionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  loadBalancerRegistrationSucceededReason,
+			Message: loadBalancerRegistrationSucceededMessage,
+		})
+	}
+	r.reportLoadBalancerMembership()
+	r.reportLoadBalancerHealth()
+	return nil
 }
 
 // reconcileMachineWithCloudState reconcile machineSpec and status with the latest cloud state
 // if a failedCondition is passed it updates the providerStatus.Conditions and return
-// otherwise it fetches the relevant cloud instance and reconcile the rest of the fields
+// otherwise it fetches the relevant cloud instance and reconcile the rest of the fields.
+// An instance the machine was reconciled with recently is not fetched again: the status is left
+// as is and only the spec is reconciled against it.
 func (r *Reconciler) reconcileMachineWithCloudState(failedCondition *metav1.Condition) error {
-	klog.Infof("%s: Reconciling machine object with cloud state", r.machine.Name)
+	r.log.V(logLevelRoutine).Info("Reconciling machine object with cloud state")
 	if failedCondition != nil {
 		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, *failedCondition)
 		return nil
 	} else {
-		freshInstance, err := r.computeService.InstancesGet(r.projectID, r.providerSpec.Zone, r.machine.Name)
-		if err != nil {
-			return fmt.Errorf("failed to get instance via compute service: %v", err)
+		freshInstance := r.reconciledInstance
+		if freshInstance != nil {
+			r.log.V(logLevelRoutine).Info("Machine was reconciled with its instance recently, skipping the instance lookup")
+		} else {
+			var err error
+			freshInstance, err = r.computeService.InstancesGet(r.projectID, r.providerSpec.Zone, r.instanceName)
+			if err != nil {
+				return fmt.Errorf("failed to get instance via compute service: %v", err)
+			}
+			if err := r.reconcileStatusWithInstance(freshInstance); err != nil {
+				return err
+			}
+			r.fetchedInstance = freshInstance
 		}
+		r.reconcileIPForwardingCondition(freshInstance)
+		r.reconcileDeprecatedFieldsCondition()
+		r.reconcileUnsupportedFieldsCondition()
+		r.reconcileSharedCoreCondition()
 
-		if len(freshInstance.NetworkInterfaces) < 1 {
-			return fmt.Errorf("could not find network interfaces for instance %q", freshInstance.Name)
+		r.setMachineCloudProviderSpecifics(freshInstance)
+		if err := r.reconcileManagedTags(freshInstance); err != nil {
+			r.log.Error(err, "Failed to reconcile the managed network tags")
 		}
-		networkInterface := freshInstance.NetworkInterfaces[0]
-
-		nodeAddresses := []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: networkInterface.NetworkIP}}
-		for _, config := range networkInterface.AccessConfigs {
-			nodeAddresses = append(nodeAddresses, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: config.NatIP})
+		if err := r.reconcileDNSRecords(freshInstance); err != nil {
+			r.log.Error(err, "Failed to reconcile the DNS records")
 		}
-		// Since we don't know when the project was created, we must account for
-		// both types of internal-dns:
-		// https://cloud.google.com/compute/docs/internal-dns#instance-fully-qualified-domain-names
-		// [INSTANCE_NAME].[ZONE].c.[PROJECT_ID].internal (newer)
-		nodeAddresses = append(nodeAddresses, corev1.NodeAddress{
-			Type:    corev1.NodeInternalDNS,
-			Address: fmt.Sprintf("%s.%s.c.%s.internal", r.machine.Name, r.providerSpec.Zone, r.projectID),
-		})
-		// [INSTANCE_NAME].c.[PROJECT_ID].internal
-		nodeAddresses = append(nodeAddresses, corev1.NodeAddress{
-			Type:    corev1.NodeInternalDNS,
-			Address: fmt.Sprintf("%s.c.%s.internal", r.machine.Name, r.projectID),
-		})
-		// Add the machine's name as a known NodeInternalDNS because GCP platform
-		// provides search paths to resolve those.
-		// https://cloud.google.com/compute/docs/internal-dns#resolv.conf
-		nodeAddresses = append(nodeAddresses, corev1.NodeAddress{
-			Type:    corev1.NodeInternalDNS,
-			Address: r.machine.GetName(),
-		})
-
-		r.machine.Spec.ProviderID = &r.providerID
-		r.machine.Status.Addresses = nodeAddresses
-		r.providerStatus.InstanceState = &freshInstance.Status
-		r.providerStatus.InstanceID = &freshInstance.Name
-		succeedCondition := metav1.Condition{
-			Type:    string(machinev1.MachineCreated),
-			Reason:  machineCreationSucceedReason,
-			Message: machineCreationSucceedMessage,
-			Status:  metav1.ConditionTrue,
+		// The providerSpec does not describe the instances created from an instance template.
+		if !r.fromInstanceTemplate() {
+			if err := r.reconcileDrift(freshInstance); err != nil {
+				r.log.Error(err, "Failed to reconcile drift from the providerSpec")
+			}
+			if resizing, err := r.reconcileMachineTypeResize(freshInstance); resizing || err != nil {
+				return err
+			}
 		}
-		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, succeedCondition)
-
-		r.setMachineCloudProviderSpecifics(freshInstance)
 
 		if freshInstance.Status != "RUNNING" {
-			klog.Infof("%s: machine status is %q, requeuing...", r.machine.Name, freshInstance.Status)
+			r.log.V(logLevelDetail).Info("Instance is not running yet, requeuing", "instanceStatus", freshInstance.Status)
 			return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
 		}
+
+		if err := r.reconcileShieldedIntegrityPolicy(freshInstance); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// reconcileStatusWithInstance refreshes the addresses, provider ID, instance state and creation
+// condition of the machine from its instance.
+func (r *Reconciler) reconcileStatusWithInstance(freshInstance *compute.Instance) error {
+	if len(freshInstance.NetworkInterfaces) < 1 {
+		return fmt.Errorf("could not find network interfaces for instance %q", freshInstance.Name)
+	}
+	networkInterface := freshInstance.NetworkInterfaces[0]
+
+	nodeAddresses := []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: networkInterface.NetworkIP}}
+	for _, config := range networkInterface.AccessConfigs {
+		if config.NatIP == "" {
+			continue
+		}
+		nodeAddresses = append(nodeAddresses, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: config.NatIP})
+	}
+	// Since we don't know when the project was created, we must account for
+	// both types of internal-dns:
+	// https://cloud.google.com/compute/docs/internal-dns#instance-fully-qualified-domain-names
+	// [INSTANCE_NAME].[ZONE].c.[PROJECT_ID].internal (newer)
+	nodeAddresses = append(nodeAddresses, corev1.NodeAddress{
+		Type:    corev1.NodeInternalDNS,
+		Address: fmt.Sprintf("%s.%s.c.%s.internal", r.instanceName, r.providerSpec.Zone, r.projectID),
+	})
+	// [INSTANCE_NAME].c.[PROJECT_ID].internal
+	nodeAddresses = append(nodeAddresses, corev1.NodeAddress{
+		Type:    corev1.NodeInternalDNS,
+		Address: fmt.Sprintf("%s.c.%s.internal", r.instanceName, r.projectID),
+	})
+	// Add the machine's name as a known NodeInternalDNS because GCP platform
+	// provides search paths to resolve those.
+	// https://cloud.google.com/compute/docs/internal-dns#resolv.conf
+	nodeAddresses = append(nodeAddresses, corev1.NodeAddress{
+		Type:    corev1.NodeInternalDNS,
+		Address: r.instanceName,
+	})
+
+	r.machine.Spec.ProviderID = r.reconciledProviderID()
+	r.machine.Status.Addresses = nodeAddresses
+	r.providerStatus.InstanceState = &freshInstance.Status
+	r.providerStatus.InstanceID = &freshInstance.Name
+	succeedCondition := metav1.Condition{
+		Type:    string(machinev1.MachineCreated),
+		Reason:  machineCreationSucceedReason,
+		Message: machineCreationSucceedMessage,
+		Status:  metav1.ConditionTrue,
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, succeedCondition)
+	return nil
+}
+
+// reconcileIPForwardingCondition surfaces IP forwarding on the instance, and whether it matches
+// the providerSpec, as a condition so that it is visible to cluster administrators.
+// Instances without IP forwarding only get the condition if it was reported before.
+func (r *Reconciler) reconcileIPForwardingCondition(instance *compute.Instance) {
+	condition := metav1.Condition{
+		Type:    ipForwardingConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  ipForwardingDisabledReason,
+		Message: "instance does not forward packets",
+	}
+	if instance.CanIpForward {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ipForwardingEnabledReason
+		condition.Message = ipForwardingEnabledMessage
+	}
+	if instance.CanIpForward != r.providerSpec.CanIPForward {
+		condition.Reason = ipForwardingMismatchReason
+		condition.Message = ipForwardingMismatchMessage
+	}
+
+	if condition.Status == metav1.ConditionFalse && condition.Reason == ipForwardingDisabledReason &&
+		findCondition(r.providerStatus.Conditions, ipForwardingConditionType) == nil {
+		return
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, condition)
+}
+
 func (r *Reconciler) setMachineCloudProviderSpecifics(instance *compute.Instance) {
 	if r.machine.Labels == nil {
 		r.machine.Labels = make(map[string]string)
@@ -517,6 +799,10 @@ func validateMachine(machine machinev1.Machine, providerSpec machinev1.GCPMachin
 		return machinecontroller.InvalidMachineConfiguration("machine is missing %q label", machinev1.MachineClusterIDLabel)
 	}
 
+	if _, err := util.ParseCustomMachineType(providerSpec.MachineType); err != nil {
+		return machinecontroller.InvalidMachineConfiguration("%v", err)
+	}
+
 	return nil
 }
 
@@ -524,7 +810,7 @@ func isInvalidMachineConfigurationError(err error) bool {
 	var machineError *machinecontroller.MachineError
 	if errors.As(err, &machineError) {
 		if machineError.Reason == machinev1.InvalidConfigurationMachineError {
-			klog.Infof("Actuator returned invalid configuration error: %v", machineError)
+			klog.V(logLevelDetail).InfoS("Actuator returned invalid configuration error", "error", machineError.Error())
 			return true
 		}
 	}
@@ -550,12 +836,18 @@ func (r *Reconciler) exists() (bool, error) {
 		return false, fmt.Errorf("unable to verify project/zone exists: %v/%v; err: %v", r.projectID, zone, err)
 	}
 
-	instance, err := r.computeService.InstancesGet(r.projectID, zone, r.machine.Name)
+	// The instance of a duplicate machine belongs to the older machine.
+	if duplicate, err := r.isDuplicate(); err != nil || duplicate {
+		return false, err
+	}
+
+	id := r.instanceID()
+	instance, err := r.computeService.InstancesGet(id.Project, id.Zone, id.Instance)
 	if instance != nil && err == nil {
 		return true, nil
 	}
 	if isNotFoundError(err) {
-		klog.Infof("%s: Machine does not exist", r.machine.Name)
+		r.log.V(logLevelDetail).Info("Instance does not exist")
 		return false, nil
 	}
 	return false, fmt.Errorf("error getting running instances: %v", err)
@@ -563,6 +855,24 @@ func (r *Reconciler) exists() (bool, error) {
 
 // Returns true if machine exists.
 func (r *Reconciler) delete() error {
+	// Never delete the instance of the older machine a duplicate machine resolves to.
+	if duplicate, err := r.isDuplicate(); err != nil || duplicate {
+		return err
+	}
+
+	// Make sure the instance belongs to this cluster and may be deleted before touching it or its
+	// load balancer memberships. Only an instance that is gone skips the check.
+	instance, err := r.computeService.InstancesGet(r.projectID, r.providerSpec.Zone, r.instanceName)
+	if err != nil && !isNotFoundError(err) {
+		return gcperrors.RequeueIfRetryable(fmt.Errorf("failed to get instance via compute service: %w", err))
+	}
+	if err := r.checkInstanceOwnership(instance); err != nil {
+		return err
+	}
+	if err := r.reconcileDeletionProtection(instance); err != nil {
+		return err
+	}
+
 	// Remove instance from target pools, if necessary
 	if err := r.processTargetPools(false, r.deleteInstanceFromTargetPool); err != nil {
 		return err
@@ -578,26 +888,57 @@ func (r *Reconciler) delete() error {
 		return err
 	}
 	if !exists {
-		klog.Infof("%s: Machine not found during delete, skipping", r.machine.Name)
+		r.log.Info("Instance not found during delete, skipping")
+		if err := r.deregisterDNSRecords(nil); err != nil {
+			return err
+		}
+		if err := r.releaseInternalAddress(); err != nil {
+			return err
+		}
+		if err := r.deleteLeakedResources(); err != nil {
+			return err
+		}
+		r.forgetDeprecatedFieldsMetrics()
 		return nil
 	}
 
 	// Remove instance from instance group, if necessary
 	if r.machineScope.machine.Labels[openshiftMachineRoleLabel] == masterMachineRole {
-		if err := r.unregisterInstanceFromControlPlaneInstanceGroup(); err != nil {
+		if err := r.loadLoadBalancerConfig(); err != nil {
+			return err
+		}
+		if group := r.controlPlaneEndpointGroupName(); group != "" {
+			if err := r.deregisterInstanceFromNetworkEndpointGroup(group); err != nil {
+				return fmt.Errorf("%s: failed to deregister instance from network endpoint group: %w", r.machine.Name, err)
+			}
+		} else if err := r.unregisterInstanceFromControlPlaneInstanceGroup(); err != nil {
 			return fmt.Errorf("%s: failed to unregister instance from instance group: %v", r.machine.Name, err)
 		}
 	}
 
-	if _, err = r.computeService.InstancesDelete(string(r.machine.UID), r.projectID, r.providerSpec.Zone, r.machine.Name); err != nil {
+	if err := r.shutdownBeforeDelete(); err != nil {
+		return err
+	}
+	if err := r.deregisterDNSRecords(instance); err != nil {
+		return err
+	}
+
+	operation, err := r.computeService.InstancesDelete(string(r.machine.UID), r.projectID, r.providerSpec.Zone, r.instanceName)
+	r.recordCloudMutation(instanceDeleteEvent, fmt.Sprintf("delete instance %s in zone %s", r.instanceName, r.providerSpec.Zone), operation, err)
+	if err != nil {
 		metrics.RegisterFailedInstanceDelete(&metrics.MachineLabels{
 			Name:      r.machine.Name,
 			Namespace: r.machine.Namespace,
 			Reason:    "failed to delete instance via compute service",
 		})
-		return fmt.Errorf("failed to delete instance via compute service: %v", err)
+		return fmt.Errorf("failed to delete instance via compute service: %w", err)
+	}
+	// The request ID makes the compute API return the operation of the first request while the
+	// instance is being deleted, so a deletion that failed is reported instead of retried blindly.
+	if errs := operationErrors(operation); len(errs) > 0 && operation.Status == operationDoneStatus {
+		return fmt.Errorf("delete operation %s failed: %s", operation.Name, strings.Join(errs, "; "))
 	}
-	klog.Infof("%s: machine status is exists, requeuing...", r.machine.Name)
+	r.log.V(logLevelDetail).Info("Instance is being deleted, requeuing")
 	return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
 }
 
@@ -636,15 +977,18 @@ func (r *Reconciler) instanceExistsInPool(instanceLink string, pool string) (boo
 type poolProcessor func(instanceLink, pool string) error
 
 func (r *Reconciler) processTargetPools(desired bool, poolFunc poolProcessor) error {
-	instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.machine.Name)
+	instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.instanceName)
 	// TargetPools may be empty/nil, and that's okay.
 	for _, pool := range r.providerSpec.TargetPools {
 		present, err := r.instanceExistsInPool(instanceSelfLink, pool)
 		if err != nil {
 			return err
 		}
+		if present {
+			r.loadBalancerMembership.recordTargetPool(pool)
+		}
 		if present != desired {
-			klog.Infof("%v: reconciling instance for targetpool with cloud provider; desired state: %v", r.machine.Name, desired)
+			r.log.Info("Reconciling instance membership of target pool", "targetPool", pool, "desired", desired)
 			err := poolFunc(instanceSelfLink, pool)
 			if err != nil {
 				return err
@@ -659,7 +1003,7 @@ func (r *Reconciler) processTargetPools(desired bool, poolFunc poolProcessor) er
 // it to a backend service correctly.
 func (r *Reconciler) ensureInstanceGroup(instanceGroupName string) error {
 	// Get an instance group so we can check that it does in fact exist
-	_, err := r.computeService.InstanceGroupGet(r.projectID, r.providerSpec.Zone, instanceGroupName)
+	instanceGroup, err := r.computeService.InstanceGroupGet(r.projectID, r.providerSpec.Zone, instanceGroupName)
 	if isNotFoundError(err) {
 		// Handle the creation of a new instance group
 		if err := r.registerNewInstanceGroup(); err != nil {
@@ -667,6 +1011,8 @@ func (r *Reconciler) ensureInstanceGroup(instanceGroupName string) error {
 		}
 	} else if err != nil {
 		return fmt.Errorf("instanceGroupGet request failed: %v", err)
+	} else if err := r.ensureInstanceGroupNamedPorts(instanceGroup); err != nil {
+		return fmt.Errorf("failed to set the named ports of instance group %s: %v", instanceGroupName, err)
 	}
 
 	registered, err := r.checkRegistrationOfBackend()
@@ -674,7 +1020,9 @@ func (r *Reconciler) ensureInstanceGroup(instanceGroupName string) error {
 		return fmt.Errorf("failed to retrieve the backend service: %v", err)
 	}
 
-	if !registered {
+	if registered {
+		r.loadBalancerMembership.recordGroupBackendService(r.backendServiceName())
+	} else {
 		// Handle the registration of backend to backend service
 		if err := r.updateBackendServiceWithInstanceGroup(); err != nil {
 			return fmt.Errorf("failed to update the backend service with new instance group %s: %v", instanceGroupName, err)
@@ -684,35 +1032,35 @@ func (r *Reconciler) ensureInstanceGroup(instanceGroupName string) error {
 	return nil
 }
 
-// ensureCorrectNetworkAndSubnetName makes sure that we are working with the correct network and subnet.
+// controlPlaneNetworkInterface makes sure that we are working with the correct network and subnet.
 // In a case of VPC, we have to look whether the expect name of the network and subnet resource
-// matches the one, that is actually up in the cluster.
-func (r *Reconciler) ensureCorrectNetworkAndSubnetName() (string, string) {
+// matches the one, that is actually up in the cluster. The interface is returned so that the
+// network and subnetwork are looked up in its project, the host project of a shared VPC.
+func (r *Reconciler) controlPlaneNetworkInterface() *machinev1.GCPNetworkInterface {
 	actualNetworkName := fmt.Sprintf("%s-network", r.machine.Labels[machinev1.MachineClusterIDLabel])
 	actualSubnetworkName := fmt.Sprintf("%s-%s-subnet", r.machine.Labels[machinev1.MachineClusterIDLabel], r.machineScope.machine.ObjectMeta.Labels[openshiftMachineRoleLabel])
 
 	for _, network := range r.providerSpec.NetworkInterfaces {
 		if network.Network == actualNetworkName && network.Subnetwork == actualSubnetworkName {
-			return actualNetworkName, actualSubnetworkName
+			return network
 		}
 	}
-	actualNetworkName = r.providerSpec.NetworkInterfaces[0].Network
-	actualSubnetworkName = r.providerSpec.NetworkInterfaces[0].Subnetwork
-
-	return actualNetworkName, actualSubnetworkName
+	return r.providerSpec.NetworkInterfaces[0]
 }
 
 // registerNewInstanceGroup registers an instance group when there is an instance
 // that is using that unkown instance group.
 func (r *Reconciler) registerNewInstanceGroup() error {
-	actualNetworkName, actualSubnetworkName := r.ensureCorrectNetworkAndSubnetName()
+	nic := r.controlPlaneNetworkInterface()
 
 	_, err := r.computeService.InstanceGroupInsert(r.projectID, r.providerSpec.Zone, &compute.InstanceGroup{
-		Name:       r.controlPlaneGroupName(),
-		Region:     r.providerSpec.Region,
-		Zone:       r.providerSpec.Zone,
-		Network:    r.instanceGroupNetworkName(actualNetworkName),
-		Subnetwork: r.instanceGroupSubNetworkName(actualSubnetworkName),
+		Name:        r.controlPlaneGroupName(),
+		Region:      r.providerSpec.Region,
+		Zone:        r.providerSpec.Zone,
+		Network:     r.networkURL(nic),
+		Subnetwork:  r.subnetworkURL(nic),
+		NamedPorts:  r.instanceGroupNamedPorts(nil),
+		Description: fmt.Sprintf(createdInstanceGroupDescription, r.machine.Labels[machinev1.MachineClusterIDLabel]),
 	})
 	if err != nil {
 		return fmt.Errorf("instanceGroupInsert request failed: %w", err)
@@ -765,7 +1113,7 @@ func (r *Reconciler) updateBackendServiceWithInstanceGroup() error {
 
 // registerInstanceToControlPlaneInstanceGroup ensures that the instance is assigned to the control plane instance group of its zone.
 func (r *Reconciler) registerInstanceToControlPlaneInstanceGroup() error {
-	instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.machine.Name)
+	instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.instanceName)
 	instanceGroupName := r.controlPlaneGroupName()
 
 	if err := r.ensureInstanceGroup(instanceGroupName); err != nil {
@@ -777,16 +1125,21 @@ func (r *Reconciler) registerInstanceToControlPlaneInstanceGroup() error {
 		return fmt.Errorf("failed to fetch running instances in instance group %s: %v", instanceGroupName, err)
 	}
 
+	if instanceSets.Has(instanceSelfLink) {
+		r.loadBalancerMembership.recordInstanceGroup(instanceGroupName)
+	}
 	if !instanceSets.Has(instanceSelfLink) && pointer.StringDeref(r.providerStatus.InstanceState, "") == "RUNNING" {
-		klog.V(4).Info("Registering instance in the instancegroup", "name", r.machine.Name, "instancegroup", instanceGroupName)
-		_, err := r.computeService.InstanceGroupsAddInstances(
+		r.log.V(logLevelDetail).Info("Registering instance in the instance group", "instanceGroup", instanceGroupName)
+		operation, err := r.computeService.InstanceGroupsAddInstances(
 			r.projectID,
 			r.providerSpec.Zone,
 			instanceSelfLink,
 			instanceGroupName)
+		r.recordCloudMutation(instanceGroupAddEvent, fmt.Sprintf("add instance %s to instance group %s", r.instanceName, instanceGroupName), operation, err)
 		if err != nil {
 			return fmt.Errorf("InstanceGroupsAddInstances request failed: %v", err)
 		}
+		r.trackOperation(addToInstanceGroupOperationAction, operation)
 	}
 
 	return nil
@@ -794,7 +1147,7 @@ func (r *Reconciler) registerInstanceToControlPlaneInstanceGroup() error {
 
 // unregisterInstanceFromControlPlaneInstanceGroup ensures that the instance is removed from the control plane instance group.
 func (r *Reconciler) unregisterInstanceFromControlPlaneInstanceGroup() error {
-	instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.machine.Name)
+	instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.instanceName)
 	instanceGroupName := r.controlPlaneGroupName()
 
 	instanceSets, err := r.fetchRunningInstancesInInstanceGroup(r.projectID, r.providerSpec.Zone, instanceGroupName)
@@ -803,12 +1156,13 @@ func (r *Reconciler) unregisterInstanceFromControlPlaneInstanceGroup() error {
 	}
 
 	if len(instanceSets) > 0 && instanceSets.Has(instanceSelfLink) {
-		klog.V(4).Info("Unregistering instance from the instancegroup", "name", r.machine.Name, "instancegroup", instanceGroupName)
-		_, err := r.computeService.InstanceGroupsRemoveInstances(
+		r.log.V(logLevelDetail).Info("Unregistering instance from the instance group", "instanceGroup", instanceGroupName)
+		operation, err := r.computeService.InstanceGroupsRemoveInstances(
 			r.projectID,
 			r.providerSpec.Zone,
 			instanceSelfLink,
 			instanceGroupName)
+		r.recordCloudMutation(instanceGroupRemoveEvent, fmt.Sprintf("remove instance %s from instance group %s", r.instanceName, instanceGroupName), operation, err)
 		if err != nil {
 			return fmt.Errorf("InstanceGroupsRemoveInstances request failed: %v", err)
 		}
@@ -844,29 +1198,22 @@ func (r *Reconciler) FQDNInstanceGroup() string {
 
 // backendServiceName generates the name of a cluster's backend service
 func (r *Reconciler) backendServiceName() string {
+	if r.loadBalancerConfig != nil && r.loadBalancerConfig.backendServiceName != "" {
+		return r.loadBalancerConfig.backendServiceName
+	}
 	return fmt.Sprintf("%s-api-internal", r.machine.Labels[machinev1.MachineClusterIDLabel])
 }
 
-// instanceGroupNetworkName generates the name of a instance groups' network
-func (r *Reconciler) instanceGroupNetworkName(networkName string) string {
-	return fmt.Sprintf("projects/%s/global/networks/%s", r.projectID, networkName)
-}
-
-// instanceGroupSubNetworkName generates the name of a instance groups' subnetwork
-func (r *Reconciler) instanceGroupSubNetworkName(subnetworkName string) string {
-	return fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", r.projectID, r.providerSpec.Region, subnetworkName)
-}
-
 // ControlPlaneGroupName generates the name of the instance group that this instace should belong to.
 func (r *Reconciler) controlPlaneGroupName() string {
-	return fmt.Sprintf("%s-%s-%s", r.machine.Labels[machinev1.MachineClusterIDLabel], masterMachineRole, r.providerSpec.Zone)
+	return r.loadBalancerConfig.instanceGroupName(r.machine.Labels[machinev1.MachineClusterIDLabel], r.providerSpec.Zone)
 }
 
 func (r *Reconciler) addInstanceToTargetPool(instanceLink string, pool string) error {
-	_, err := r.computeService.TargetPoolsAddInstance(r.projectID, r.providerSpec.Region, pool, instanceLink)
-	// Probably safe to disregard the returned operation; it either worked or it didn't.
+	operation, err := r.computeService.TargetPoolsAddInstance(r.projectID, r.providerSpec.Region, pool, instanceLink)
+	r.recordCloudMutation(targetPoolAddEvent, fmt.Sprintf("add instance %s to target pool %s", r.instanceName, pool), operation, err)
 	// Even if the instance doesn't exist, it will return without error and the non-existent
-	// instance will be associated.
+	// instance will be associated. The operation is tracked to report it if it fails later.
 	if err != nil {
 		metrics.RegisterFailedInstanceUpdate(&metrics.MachineLabels{
 			Name:      r.machine.Name,
@@ -875,11 +1222,13 @@ func (r *Reconciler) addInstanceToTargetPool(instanceLink string, pool string) e
 		})
 		return fmt.Errorf("failed to add instance %v to target pool %v: %v", r.machine.Name, pool, err)
 	}
+	r.trackOperation(addToTargetPoolOperationAction, operation)
 	return nil
 }
 
 func (r *Reconciler) deleteInstanceFromTargetPool(instanceLink string, pool string) error {
-	_, err := r.computeService.TargetPoolsRemoveInstance(r.projectID, r.providerSpec.Region, pool, instanceLink)
+	operation, err := r.computeService.TargetPoolsRemoveInstance(r.projectID, r.providerSpec.Region, pool, instanceLink)
+	r.recordCloudMutation(targetPoolRemoveEvent, fmt.Sprintf("remove instance %s from target pool %s", r.instanceName, pool), operation, err)
 	if err != nil {
 		metrics.RegisterFailedInstanceDelete(&metrics.MachineLabels{
 			Name:      r.machine.Name,
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/googleapis/gax-go/v2/apierror"
 	configv1 "github.com/openshift/api/config/v1"
@@ -14,6 +16,7 @@ import (
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
 	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
 	tags "google.golang.org/api/cloudresourcemanager/v3"
@@ -22,6 +25,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
 	"k8s.io/utils/pointer"
 	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -30,10 +35,14 @@ func TestCreate(t *testing.T) {
 	cases := []struct {
 		name                string
 		labels              map[string]string
+		annotations         map[string]string
+		taints              []corev1.Taint
 		providerSpec        *machinev1.GCPMachineProviderSpec
 		expectedCondition   *metav1.Condition
 		secret              *corev1.Secret
 		mockInstancesInsert func(project string, zone string, instance *compute.Instance) (*compute.Operation, error)
+		mockAddressesGet    func(project string, region string, name string) (*compute.Address, error)
+		mockResourcePolicy  func(project string, region string, name string) (*compute.ResourcePolicy, error)
 		validateInstance    func(t *testing.T, instance *compute.Instance)
 		expectedError       error
 	}{
@@ -97,7 +106,7 @@ func TestCreate(t *testing.T) {
 			expectedCondition: &metav1.Condition{
 				Type:    string(machinev1.MachineCreated),
 				Status:  metav1.ConditionFalse,
-				Reason:  machineCreationFailedReason,
+				Reason:  string(gcperrors.InvalidRequest),
 				Message: "googleapi: Error 400: error",
 			},
 			mockInstancesInsert: func(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
@@ -158,6 +167,34 @@ func TestCreate(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Fail pre-flight when the accelerator type is not offered in the zone",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Zone:        "test-zone",
+				MachineType: "n1-test-machineType",
+				GPUs: []machinev1.GCPGPUConfig{
+					{
+						Type:  "nvidia-tesla-p4",
+						Count: 1,
+					},
+				},
+			},
+			expectedError: errors.New("accelerator type nvidia-tesla-p4 is not available in zone test-zone"),
+		},
+		{
+			name: "Fail pre-flight when the zone does not support the requested accelerator count",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Zone:        "test-zone",
+				MachineType: "n1-test-machineType",
+				GPUs: []machinev1.GCPGPUConfig{
+					{
+						Type:  "nvidia-tesla-t4",
+						Count: 8,
+					},
+				},
+			},
+			expectedError: errors.New("accelerator type nvidia-tesla-t4 supports at most 4 cards per instance in zone test-zone, got 8"),
+		},
 		{
 			name: "Use projectID from ProviderSpec if not set in the NetworkInterface",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
@@ -478,6 +515,492 @@ func TestCreate(t *testing.T) {
 			},
 			expectedError: errors.New("failed to fetch user-defined tags for : failed to fetch openshift/key2/value2 tag details: googleapi: Error 500: Internal error while fetching 'openshift/key2/value2'"),
 		},
+		{
+			name: "IP forwarding is passed to the api",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				CanIPForward: true,
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if !instance.CanIpForward {
+					t.Error("expected instance to have CanIpForward set")
+				}
+			},
+		},
+		{
+			name: "Resource policies are attached to disks",
+			annotations: map[string]string{
+				diskResourcePoliciesAnnotation: "daily-snapshots",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Disks: []*machinev1.GCPDisk{
+					{Boot: true, Image: "image"},
+					{Image: "image"},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := []string{"projects/project/regions/test-region/resourcePolicies/daily-snapshots"}
+				for i, disk := range instance.Disks {
+					if !reflect.DeepEqual(disk.InitializeParams.ResourcePolicies, expected) {
+						t.Errorf("Expected disk %d resource policies %v, got %v", i, expected, disk.InitializeParams.ResourcePolicies)
+					}
+				}
+			},
+		},
+		{
+			name: "Fail on resource policy that is not a snapshot schedule",
+			annotations: map[string]string{
+				diskResourcePoliciesAnnotation: "placement",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+			},
+			mockResourcePolicy: func(project string, region string, name string) (*compute.ResourcePolicy, error) {
+				return &compute.ResourcePolicy{Name: name, GroupPlacementPolicy: &compute.ResourcePolicyGroupPlacementPolicy{}}, nil
+			},
+			expectedError: errors.New("resource policy \"placement\" is not a snapshot schedule and cannot be attached to disks"),
+		},
+		{
+			name: "Fail on more than one snapshot schedule",
+			annotations: map[string]string{
+				diskResourcePoliciesAnnotation: "daily, projects/project/regions/test-region/resourcePolicies/weekly",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+			},
+			expectedError: errors.New("only one snapshot schedule can be attached to a disk, got 2 in machine.openshift.io/gcp-disk-resource-policies annotation"),
+		},
+		{
+			name: "Guest OS features are enabled on the boot disk",
+			annotations: map[string]string{
+				guestOSFeaturesAnnotation: "uefi_compatible, GVNIC, GVNIC",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Disks: []*machinev1.GCPDisk{
+					{Boot: true, Image: "image"},
+					{Image: "image"},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := []*compute.GuestOsFeature{{Type: "UEFI_COMPATIBLE"}, {Type: "GVNIC"}}
+				if !reflect.DeepEqual(instance.Disks[0].GuestOsFeatures, expected) {
+					t.Errorf("Expected boot disk guest OS features %v, got %v", expected, instance.Disks[0].GuestOsFeatures)
+				}
+				if instance.Disks[1].GuestOsFeatures != nil {
+					t.Errorf("Expected no guest OS features on data disk, got %v", instance.Disks[1].GuestOsFeatures)
+				}
+			},
+		},
+		{
+			name: "Fail on unknown guest OS feature",
+			annotations: map[string]string{
+				guestOSFeaturesAnnotation: "UEFI",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Disks: []*machinev1.GCPDisk{{Boot: true, Image: "image"}},
+			},
+			expectedError: errors.New("invalid guest OS feature \"UEFI\" in machine.openshift.io/gcp-guest-os-features annotation, must be one of GVNIC, MULTI_IP_SUBNET, SECURE_BOOT, SEV_CAPABLE, SEV_LIVE_MIGRATABLE, SEV_SNP_CAPABLE, UEFI_COMPATIBLE, VIRTIO_SCSI_MULTIQUEUE, WINDOWS"),
+		},
+		{
+			name: "Node labels and taints are passed in the kubelet-extra-args metadata",
+			annotations: map[string]string{
+				nodeLabelsAnnotation: "team=ml, node.kubernetes.io/pool=gpu",
+				nodeTaintsAnnotation: "dedicated=ml:NoSchedule",
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := "--node-labels=node.kubernetes.io/pool=gpu,team=ml --register-with-taints=dedicated=ml:NoSchedule"
+				for _, item := range instance.Metadata.Items {
+					if item.Key == kubeletExtraArgsMetadataKey {
+						if *item.Value != expected {
+							t.Errorf("Expected kubelet-extra-args %q, got %q", expected, *item.Value)
+						}
+						return
+					}
+				}
+				t.Errorf("Expected kubelet-extra-args metadata, got %v", instance.Metadata.Items)
+			},
+		},
+		{
+			name: "Machine taints are passed in the kubelet-extra-args metadata",
+			annotations: map[string]string{
+				nodeTaintsAnnotation:             "dedicated=ml:NoSchedule",
+				propagateMachineTaintsAnnotation: "true",
+			},
+			taints: []corev1.Taint{
+				{Key: "dedicated", Value: "ml", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "node-role.kubernetes.io/infra", Effect: corev1.TaintEffectNoSchedule},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := "--register-with-taints=dedicated=ml:NoSchedule,node-role.kubernetes.io/infra:NoSchedule"
+				for _, item := range instance.Metadata.Items {
+					if item.Key == kubeletExtraArgsMetadataKey {
+						if *item.Value != expected {
+							t.Errorf("Expected kubelet-extra-args %q, got %q", expected, *item.Value)
+						}
+						return
+					}
+				}
+				t.Errorf("Expected kubelet-extra-args metadata, got %v", instance.Metadata.Items)
+			},
+		},
+		{
+			name: "Fail on node label the kubelet may not set",
+			annotations: map[string]string{
+				nodeLabelsAnnotation: "node-role.kubernetes.io/infra=",
+			},
+			expectedError: errors.New("invalid node label \"node-role.kubernetes.io/infra=\" in machine.openshift.io/gcp-node-labels annotation: the kubelet may only set labels in the kubernetes.io namespace with the prefixes kubelet.kubernetes.io/, node.kubernetes.io/"),
+		},
+		{
+			name: "Fail on node taint with an invalid effect",
+			annotations: map[string]string{
+				nodeTaintsAnnotation: "dedicated=ml:NoRun",
+			},
+			expectedError: errors.New("invalid node taint \"dedicated=ml:NoRun\" in machine.openshift.io/gcp-node-taints annotation: effect must be one of NoExecute, NoSchedule, PreferNoSchedule"),
+		},
+		{
+			name: "Specific reservations are passed to the api",
+			annotations: map[string]string{
+				reservationAffinityAnnotation: "specific",
+				reservationsAnnotation:        "gpu-reservation, projects/shared/reservations/other",
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := &compute.ReservationAffinity{
+					ConsumeReservationType: "SPECIFIC_RESERVATION",
+					Key:                    "compute.googleapis.com/reservation-name",
+					Values:                 []string{"gpu-reservation", "projects/shared/reservations/other"},
+				}
+				if !reflect.DeepEqual(instance.ReservationAffinity, expected) {
+					t.Errorf("Expected reservation affinity %+v, got %+v", expected, instance.ReservationAffinity)
+				}
+			},
+		},
+		{
+			name: "Reservations can be avoided",
+			annotations: map[string]string{
+				reservationAffinityAnnotation: "none",
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.ReservationAffinity == nil || instance.ReservationAffinity.ConsumeReservationType != "NO_RESERVATION" {
+					t.Errorf("Expected NO_RESERVATION affinity, got %+v", instance.ReservationAffinity)
+				}
+			},
+		},
+		{
+			name: "Fail on specific reservation affinity without reservations",
+			annotations: map[string]string{
+				reservationAffinityAnnotation: "specific",
+			},
+			expectedError: errors.New("machine.openshift.io/gcp-reservation-affinity \"specific\" requires the reservations to consume in the machine.openshift.io/gcp-reservations annotation"),
+		},
+		{
+			name: "Fail on reservations without specific reservation affinity",
+			annotations: map[string]string{
+				reservationAffinityAnnotation: "any",
+				reservationsAnnotation:        "gpu-reservation",
+			},
+			expectedError: errors.New("machine.openshift.io/gcp-reservations annotation can only be used with machine.openshift.io/gcp-reservation-affinity \"specific\", got \"any\""),
+		},
+		{
+			name: "Advanced machine features are passed to the api",
+			annotations: map[string]string{
+				advancedMachineFeaturesAnnotation: `{"enableNestedVirtualization": true, "threadsPerCore": 1, "visibleCoreCount": 2, "enableUefiNetworking": true}`,
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := &compute.AdvancedMachineFeatures{
+					EnableNestedVirtualization: true,
+					EnableUefiNetworking:       true,
+					ThreadsPerCore:             1,
+					VisibleCoreCount:           2,
+				}
+				if !reflect.DeepEqual(instance.AdvancedMachineFeatures, expected) {
+					t.Errorf("Expected advanced machine features %+v, got %+v", expected, instance.AdvancedMachineFeatures)
+				}
+			},
+		},
+		{
+			name: "Fail on invalid threads per core",
+			annotations: map[string]string{
+				advancedMachineFeaturesAnnotation: `{"threadsPerCore": 4}`,
+			},
+			expectedError: errors.New("threadsPerCore must be 1 or 2, got 4"),
+		},
+		{
+			name: "Fail on unknown advanced machine feature",
+			annotations: map[string]string{
+				advancedMachineFeaturesAnnotation: `{"enableNestedVirtualisation": true}`,
+			},
+			expectedError: errors.New("invalid value for annotation machine.openshift.io/gcp-advanced-machine-features: json: unknown field \"enableNestedVirtualisation\""),
+		},
+		{
+			name: "No access config without public IP",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork"},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if len(instance.NetworkInterfaces[0].AccessConfigs) != 0 {
+					t.Errorf("expected no access configs, got %d", len(instance.NetworkInterfaces[0].AccessConfigs))
+				}
+			},
+		},
+		{
+			name: "Ephemeral access config with public IP",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork", PublicIP: true},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if len(instance.NetworkInterfaces[0].AccessConfigs) != 1 {
+					t.Fatalf("expected one access config, got %d", len(instance.NetworkInterfaces[0].AccessConfigs))
+				}
+				if natIP := instance.NetworkInterfaces[0].AccessConfigs[0].NatIP; natIP != "" {
+					t.Errorf("expected ephemeral external IP, got %q", natIP)
+				}
+			},
+		},
+		{
+			name: "Reserved external address is attached to the primary interface",
+			annotations: map[string]string{
+				externalAddressAnnotation: "projects/project/regions/test-region/addresses/test-address",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork", PublicIP: true},
+				},
+			},
+			mockAddressesGet: func(project string, region string, name string) (*compute.Address, error) {
+				if project != "project" || region != "test-region" || name != "test-address" {
+					return nil, &googleapi.Error{Code: http.StatusNotFound}
+				}
+				return &compute.Address{Name: name, Address: "34.1.2.3", AddressType: "EXTERNAL", Status: "RESERVED"}, nil
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if len(instance.NetworkInterfaces[0].AccessConfigs) != 1 {
+					t.Fatalf("expected one access config, got %d", len(instance.NetworkInterfaces[0].AccessConfigs))
+				}
+				accessConfig := instance.NetworkInterfaces[0].AccessConfigs[0]
+				if accessConfig.NatIP != "34.1.2.3" || accessConfig.Type != "ONE_TO_ONE_NAT" {
+					t.Errorf("expected ONE_TO_ONE_NAT access config with NatIP 34.1.2.3, got %+v", accessConfig)
+				}
+			},
+		},
+		{
+			name: "Network tier is set on access configs",
+			annotations: map[string]string{
+				networkTierAnnotation: "standard",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork", PublicIP: true},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if tier := instance.NetworkInterfaces[0].AccessConfigs[0].NetworkTier; tier != "STANDARD" {
+					t.Errorf("Expected network tier STANDARD, got %q", tier)
+				}
+			},
+		},
+		{
+			name: "Fail on invalid network tier",
+			annotations: map[string]string{
+				networkTierAnnotation: "gold",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork", PublicIP: true},
+				},
+			},
+			expectedError: errors.New("invalid value \"gold\" for annotation machine.openshift.io/gcp-network-tier, must be one of PREMIUM or STANDARD"),
+		},
+		{
+			name: "Fail on standard network tier rejected by the compute API",
+			annotations: map[string]string{
+				networkTierAnnotation: "STANDARD",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "me-central2",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork", PublicIP: true},
+				},
+			},
+			mockInstancesInsert: func(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
+				return nil, &googleapi.Error{Code: 400, Message: "STANDARD network tier is not supported in region me-central2"}
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("error launching instance: %v", "googleapi: Error 400: STANDARD network tier is not supported in region me-central2"),
+		},
+		{
+			name: "Fail on reserved external address in another network tier",
+			annotations: map[string]string{
+				externalAddressAnnotation: "test-address",
+				networkTierAnnotation:     "PREMIUM",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork", PublicIP: true},
+				},
+			},
+			mockAddressesGet: func(project string, region string, name string) (*compute.Address, error) {
+				return &compute.Address{Name: name, Address: "34.1.2.3", AddressType: "EXTERNAL", Status: "RESERVED", NetworkTier: "STANDARD"}, nil
+			},
+			expectedError: errors.New("external address \"test-address\" is in network tier STANDARD, but network tier PREMIUM was requested"),
+		},
+		{
+			name: "Network attachment is set on secondary interface",
+			annotations: map[string]string{
+				networkAttachmentsAnnotation: `{"1": "producer-attachment"}`,
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork"},
+					{},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if len(instance.NetworkInterfaces) != 2 {
+					t.Fatalf("expected two network interfaces, got %d", len(instance.NetworkInterfaces))
+				}
+				expected := "projects/project/regions/test-region/networkAttachments/producer-attachment"
+				if instance.NetworkInterfaces[1].NetworkAttachment != expected {
+					t.Errorf("Expected network attachment %q, got %q", expected, instance.NetworkInterfaces[1].NetworkAttachment)
+				}
+				if instance.NetworkInterfaces[0].NetworkAttachment != "" {
+					t.Errorf("Expected no network attachment on primary interface, got %q", instance.NetworkInterfaces[0].NetworkAttachment)
+				}
+			},
+		},
+		{
+			name: "Fail on network attachment on the primary interface",
+			annotations: map[string]string{
+				networkAttachmentsAnnotation: `{"0": "producer-attachment"}`,
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{},
+				},
+			},
+			expectedError: errors.New("invalid machine.openshift.io/gcp-network-attachments annotation: the primary network interface cannot use a network attachment"),
+		},
+		{
+			name: "Fail on network attachment on interface with subnetwork",
+			annotations: map[string]string{
+				networkAttachmentsAnnotation: `{"1": "producer-attachment"}`,
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork"},
+					{Subnetwork: "other-subnetwork"},
+				},
+			},
+			expectedError: errors.New("network interface 1 uses a network attachment and must not set network, subnetwork or publicIP"),
+		},
+		{
+			name: "Fail on reserved external address without public IP",
+			annotations: map[string]string{
+				externalAddressAnnotation: "test-address",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork"},
+				},
+			},
+			expectedError: errors.New("machine.openshift.io/gcp-external-address annotation is set but the primary network interface does not have publicIP enabled"),
+		},
+		{
+			name: "Fail on reserved external address in another region",
+			annotations: map[string]string{
+				externalAddressAnnotation: "https://www.googleapis.com/compute/v1/projects/project/regions/other-region/addresses/test-address",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork", PublicIP: true},
+				},
+			},
+			expectedError: errors.New("external address \"https://www.googleapis.com/compute/v1/projects/project/regions/other-region/addresses/test-address\" is in region \"other-region\", but the machine is in region \"test-region\""),
+		},
+		{
+			name: "Fail on reserved external address in use by another instance",
+			annotations: map[string]string{
+				externalAddressAnnotation: "test-address",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork", PublicIP: true},
+				},
+			},
+			mockAddressesGet: func(project string, region string, name string) (*compute.Address, error) {
+				return &compute.Address{Name: name, Address: "34.1.2.3", AddressType: "EXTERNAL", Status: "IN_USE", Users: []string{"other"}}, nil
+			},
+			expectedError: errors.New("external address \"test-address\" is already in use by [other]"),
+		},
+		{
+			name: "Static internal address is assigned to the primary interface",
+			annotations: map[string]string{
+				staticInternalAddressAnnotation: "true",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork"},
+				},
+			},
+			mockAddressesGet: func(project string, region string, name string) (*compute.Address, error) {
+				return &compute.Address{Name: name, Address: "10.0.0.100", AddressType: "INTERNAL", Status: "RESERVED"}, nil
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.NetworkInterfaces[0].NetworkIP != "10.0.0.100" {
+					t.Errorf("Expected NetworkIP: %q, got %q", "10.0.0.100", instance.NetworkInterfaces[0].NetworkIP)
+				}
+			},
+		},
+		{
+			name: "Static internal address is reserved before the instance is created",
+			annotations: map[string]string{
+				staticInternalAddressAnnotation: "true",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork"},
+				},
+			},
+			mockAddressesGet: func(project string, region string, name string) (*compute.Address, error) {
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			},
+			expectedError: errors.New("requeue in: 20s"),
+		},
+		{
+			name: "Fail on internal address",
+			annotations: map[string]string{
+				externalAddressAnnotation: "test-address",
+			},
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{Network: "test-network", Subnetwork: "test-subnetwork", PublicIP: true},
+				},
+			},
+			mockAddressesGet: func(project string, region string, name string) (*compute.Address, error) {
+				return &compute.Address{Name: name, Address: "10.0.0.2", AddressType: "INTERNAL", Status: "RESERVED"}, nil
+			},
+			expectedError: errors.New("address \"test-address\" has type \"INTERNAL\", only EXTERNAL addresses can be attached as public IP"),
+		},
 	}
 
 	mockTagService := tagservice.NewMockTagService()
@@ -548,10 +1071,12 @@ func TestCreate(t *testing.T) {
 			machineScope := machineScope{
 				machine: &machinev1.Machine{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "",
-						Namespace: "",
-						Labels:    labels,
+						Name:        "",
+						Namespace:   "",
+						Labels:      labels,
+						Annotations: tc.annotations,
 					},
+					Spec: machinev1.MachineSpec{Taints: tc.taints},
 				},
 				coreClient:     fakeClient,
 				providerSpec:   providerSpec,
@@ -568,6 +1093,14 @@ func TestCreate(t *testing.T) {
 				mockComputeService.MockInstancesInsert = tc.mockInstancesInsert
 			}
 
+			if tc.mockAddressesGet != nil {
+				mockComputeService.MockAddressesGet = tc.mockAddressesGet
+			}
+
+			if tc.mockResourcePolicy != nil {
+				mockComputeService.MockResourcePoliciesGet = tc.mockResourcePolicy
+			}
+
 			err := reconciler.create()
 
 			if tc.expectedCondition != nil {
@@ -605,6 +1138,23 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestNewReconcilerDependencies(t *testing.T) {
+	r := newReconciler(&machineScope{})
+	if r.clock == nil || r.httpClient == nil || r.eventRecorder == nil {
+		t.Errorf("Expected dependencies to be defaulted, got clock: %v, httpClient: %v, eventRecorder: %v", r.clock, r.httpClient, r.eventRecorder)
+	}
+	// The default event recorder must not block or panic.
+	r.eventRecorder.Event(&machinev1.Machine{}, corev1.EventTypeNormal, "Test", "test")
+
+	fakeClock := clocktesting.NewFakeClock(time.Unix(0, 0))
+	httpClient := &http.Client{}
+	eventRecorder := record.NewFakeRecorder(1)
+	r = newReconciler(&machineScope{clock: fakeClock, httpClient: httpClient, eventRecorder: eventRecorder})
+	if r.clock != fakeClock || r.httpClient != httpClient || r.eventRecorder != eventRecorder {
+		t.Error("Expected injected dependencies to be used")
+	}
+}
+
 func TestReconcileMachineWithCloudState(t *testing.T) {
 	_, mockComputeService := computeservice.NewComputeServiceMock()
 
@@ -661,6 +1211,180 @@ func TestReconcileMachineWithCloudState(t *testing.T) {
 	}
 }
 
+func TestReconcileIPForwardingCondition(t *testing.T) {
+	cases := []struct {
+		name               string
+		specCanIPForward   bool
+		instanceForwarding bool
+		existingConditions []metav1.Condition
+		expectedCondition  *metav1.Condition
+	}{
+		{
+			name: "No condition without IP forwarding",
+		},
+		{
+			name:               "IP forwarding enabled",
+			specCanIPForward:   true,
+			instanceForwarding: true,
+			expectedCondition: &metav1.Condition{
+				Type:   ipForwardingConditionType,
+				Status: metav1.ConditionTrue,
+				Reason: ipForwardingEnabledReason,
+			},
+		},
+		{
+			name:               "IP forwarding set in spec after creation",
+			specCanIPForward:   true,
+			instanceForwarding: false,
+			expectedCondition: &metav1.Condition{
+				Type:   ipForwardingConditionType,
+				Status: metav1.ConditionFalse,
+				Reason: ipForwardingMismatchReason,
+			},
+		},
+		{
+			name: "Previously reported condition is updated",
+			existingConditions: []metav1.Condition{
+				{Type: ipForwardingConditionType, Status: metav1.ConditionTrue, Reason: ipForwardingEnabledReason},
+			},
+			expectedCondition: &metav1.Condition{
+				Type:   ipForwardingConditionType,
+				Status: metav1.ConditionFalse,
+				Reason: ipForwardingDisabledReason,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newReconciler(&machineScope{
+				machine:        &machinev1.Machine{},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{CanIPForward: tc.specCanIPForward},
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.existingConditions},
+			})
+
+			r.reconcileIPForwardingCondition(&compute.Instance{CanIpForward: tc.instanceForwarding})
+
+			condition := findCondition(r.providerStatus.Conditions, ipForwardingConditionType)
+			if tc.expectedCondition == nil {
+				if condition != nil {
+					t.Errorf("Expected no condition, got %v", condition)
+				}
+				return
+			}
+			if condition == nil {
+				t.Fatalf("Expected condition %v, got none", tc.expectedCondition)
+			}
+			if condition.Status != tc.expectedCondition.Status || condition.Reason != tc.expectedCondition.Reason {
+				t.Errorf("Expected: %s/%s, got %s/%s", tc.expectedCondition.Status, tc.expectedCondition.Reason, condition.Status, condition.Reason)
+			}
+		})
+	}
+}
+
+type targetPoolTrackingComputeService struct {
+	*computeservice.GCPComputeServiceMock
+	addedInstances []string
+}
+
+func (c *targetPoolTrackingComputeService) TargetPoolsAddInstance(project string, region string, name string, instance string) (*compute.Operation, error) {
+	c.addedInstances = append(c.addedInstances, instance)
+	return nil, nil
+}
+
+func TestReconcileLoadBalancerRegistration(t *testing.T) {
+	cases := []struct {
+		name               string
+		annotations        map[string]string
+		existingConditions []metav1.Condition
+		expectedAdded      int
+		expectedCondition  *metav1.Condition
+		expectedError      error
+	}{
+		{
+			name:          "Register instance in target pools",
+			expectedAdded: 1,
+		},
+		{
+			name:        "Skip registration",
+			annotations: map[string]string{skipLoadBalancerRegistrationAnnotation: "true"},
+			expectedCondition: &metav1.Condition{
+				Type:   loadBalancerRegistrationConditionType,
+				Status: metav1.ConditionFalse,
+				Reason: loadBalancerRegistrationSkippedReason,
+			},
+		},
+		{
+			name:        "Register instance again after it was skipped",
+			annotations: map[string]string{skipLoadBalancerRegistrationAnnotation: "false"},
+			existingConditions: []metav1.Condition{
+				{Type: loadBalancerRegistrationConditionType, Status: metav1.ConditionFalse, Reason: loadBalancerRegistrationSkippedReason},
+			},
+			expectedAdded: 1,
+			expectedCondition: &metav1.Condition{
+				Type:   loadBalancerRegistrationConditionType,
+				Status: metav1.ConditionTrue,
+				Reason: loadBalancerRegistrationSucceededReason,
+			},
+		},
+		{
+			name:          "Fail on invalid annotation value",
+			annotations:   map[string]string{skipLoadBalancerRegistrationAnnotation: "maybe"},
+			expectedError: errors.New("invalid value \"maybe\" for annotation machine.openshift.io/gcp-skip-lb-registration: strconv.ParseBool: parsing \"maybe\": invalid syntax"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			computeService := &targetPoolTrackingComputeService{GCPComputeServiceMock: mockComputeService}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "testInstance",
+						Annotations: tc.annotations,
+					},
+				},
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Region:      computeservice.NoMachinesInPool,
+					TargetPools: []string{"pool1"},
+				},
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.existingConditions},
+				computeService: computeService,
+			})
+
+			err := r.reconcileLoadBalancerRegistration()
+			if tc.expectedError != nil {
+				if err == nil || err.Error() != tc.expectedError.Error() {
+					t.Errorf("Expected: %v, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reconciler was not expected to return error: %v", err)
+			}
+
+			if len(computeService.addedInstances) != tc.expectedAdded {
+				t.Errorf("Expected %d instances added to target pools, got %d", tc.expectedAdded, len(computeService.addedInstances))
+			}
+
+			condition := findCondition(r.providerStatus.Conditions, loadBalancerRegistrationConditionType)
+			if tc.expectedCondition == nil {
+				if condition != nil {
+					t.Errorf("Expected no condition, got %v", condition)
+				}
+				return
+			}
+			if condition == nil {
+				t.Fatalf("Expected condition %v, got none", tc.expectedCondition)
+			}
+			if condition.Status != tc.expectedCondition.Status || condition.Reason != tc.expectedCondition.Reason {
+				t.Errorf("Expected: %s/%s, got %s/%s", tc.expectedCondition.Status, tc.expectedCondition.Reason, condition.Status, condition.Reason)
+			}
+		})
+	}
+}
+
 func TestExists(t *testing.T) {
 	_, mockComputeService := computeservice.NewComputeServiceMock()
 	machineScope := machineScope{
@@ -1241,7 +1965,7 @@ func TestRestartPolicyToBool(t *testing.T) {
 	}
 }
 
-func TestEnsureCorrectNetworkAndSubnetName(t *testing.T) {
+func TestControlPlaneNetworkInterface(t *testing.T) {
 	testType := "testType"
 	testRegion := "testRegion"
 	testZone := "testZone"
@@ -1333,7 +2057,8 @@ func TestEnsureCorrectNetworkAndSubnetName(t *testing.T) {
 
 	for _, tc := range cases {
 		r.providerSpec.NetworkInterfaces = tc.networkInterfaces
-		actualNetworkName, actualSubnetworkName := r.ensureCorrectNetworkAndSubnetName()
+		nic := r.controlPlaneNetworkInterface()
+		actualNetworkName, actualSubnetworkName := nic.Network, nic.Subnetwork
 
 		if actualNetworkName != tc.expectedNetworkName || actualSubnetworkName != tc.expectedSubnetworkName {
 			t.Errorf("Expected NetworkName: %s, got: %s\nExpected SubnetworkName: %s, got: %s", tc.expectedNetworkName, actualNetworkName, tc.expectedSubnetworkName, actualSubnetworkName)
@@ -0,0 +1,53 @@
+package machine
+
+import (
+	"strings"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	anyReservationAffinity      = "any"
+	noReservationAffinity       = "none"
+	specificReservationAffinity = "specific"
+
+	// reservationNameKey is the label key to select specific reservations by name.
+	reservationNameKey = "compute.googleapis.com/reservation-name"
+)
+
+// reservationAffinity returns the reservation affinity requested for the machine,
+// or nil when the GCP default should be used.
+func (r *Reconciler) reservationAffinity() (*compute.ReservationAffinity, error) {
+	affinity, _ := r.getAnnotation(reservationAffinityAnnotation)
+	reservations := r.getListAnnotation(reservationsAnnotation)
+
+	switch strings.ToLower(affinity) {
+	case "":
+		if len(reservations) > 0 {
+			return nil, machinecontroller.InvalidMachineConfiguration("%s annotation requires %s annotation to be %q", reservationsAnnotation, reservationAffinityAnnotation, specificReservationAffinity)
+		}
+		return nil, nil
+	case anyReservationAffinity, noReservationAffinity:
+		if len(reservations) > 0 {
+			return nil, machinecontroller.InvalidMachineConfiguration("%s annotation can only be used with %s %q, got %q", reservationsAnnotation, reservationAffinityAnnotation, specificReservationAffinity, affinity)
+		}
+		consumeType := "ANY_RESERVATION"
+		if strings.ToLower(affinity) == noReservationAffinity {
+			consumeType = "NO_RESERVATION"
+		}
+		return &compute.ReservationAffinity{ConsumeReservationType: consumeType}, nil
+	case specificReservationAffinity:
+		if len(reservations) == 0 {
+			return nil, machinecontroller.InvalidMachineConfiguration("%s %q requires the reservations to consume in the %s annotation", reservationAffinityAnnotation, affinity, reservationsAnnotation)
+		}
+		return &compute.ReservationAffinity{
+			ConsumeReservationType: "SPECIFIC_RESERVATION",
+			Key:                    reservationNameKey,
+			Values:                 reservations,
+		}, nil
+	default:
+		return nil, machinecontroller.InvalidMachineConfiguration("invalid value %q for annotation %s, must be one of %q, %q or %q",
+			affinity, reservationAffinityAnnotation, anyReservationAffinity, noReservationAffinity, specificReservationAffinity)
+	}
+}
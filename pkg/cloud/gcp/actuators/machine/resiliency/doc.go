@@ -0,0 +1,16 @@
+// Package resiliency holds the crash-consistency tests of the machine actuator. They replay the
+// reconciles of the machine controller against a stateful fake of the compute API and crash the
+// controller between the steps of a reconcile, e.g. after an instance was inserted or before the
+// machine status was written, then restart it with nothing but the persisted machine.
+//
+// Every subsystem of the actuator must keep the guarantees these tests codify:
+//
+//   - A reconcile is idempotent: repeating it after it completed changes nothing in the cloud.
+//   - A crash at any point converges: the following reconciles reach the same machine and cloud
+//     state as an uninterrupted reconcile, without the machine failing.
+//   - No resource leaks: a cloud resource created for a machine is found again after a crash, is
+//     never created twice and is released when the machine is deleted.
+//
+// Cloud side effects that are not persisted in the machine before the next API call must therefore
+// be discoverable from the cloud, e.g. by resource name, and checked before they are repeated.
+package resiliency
@@ -0,0 +1,348 @@
+package resiliency
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute/fakegce"
+	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const (
+	machineName      = "worker-0"
+	machineNamespace = "openshift-machine-api"
+	machineFinalizer = "machine.machine.openshift.io"
+	// maxReconciles bounds the reconciles a machine may take to converge after crashes.
+	maxReconciles = 10
+
+	staticInternalAddressAnnotation = "machine.openshift.io/gcp-static-internal-ip"
+	pendingOperationsAnnotation     = "machine.openshift.io/gcp-pending-operations"
+)
+
+func init() {
+	configv1.AddToScheme(scheme.Scheme)
+	machinev1.AddToScheme(scheme.Scheme)
+}
+
+// errCrash is panicked with to stop the controller at a crash point, so that nothing after it runs.
+var errCrash = errors.New("simulated controller crash")
+
+// crashPoint is a step of a reconcile at which the controller crashes. Exactly one of its fields is set.
+type crashPoint struct {
+	// afterCall crashes once the compute API served a call of the method.
+	afterCall string
+	// beforeWrite crashes before the machine ("spec") or its status ("status") is written.
+	beforeWrite string
+}
+
+func (c crashPoint) String() string {
+	if c.afterCall != "" {
+		return "after " + c.afterCall
+	}
+	return "before the " + c.beforeWrite + " write"
+}
+
+var (
+	crashAfterInstanceInsert = crashPoint{afterCall: "InstancesInsert"}
+	crashAfterInstanceDelete = crashPoint{afterCall: "InstancesDelete"}
+	crashAfterAddressInsert  = crashPoint{afterCall: "AddressesInsert"}
+	crashAfterAddressDelete  = crashPoint{afterCall: "AddressesDelete"}
+	crashBeforeSpecWrite     = crashPoint{beforeWrite: "spec"}
+	crashBeforeStatusWrite   = crashPoint{beforeWrite: "status"}
+)
+
+// harness runs the reconciles of a machine the way the machine controller does, with a new
+// actuator per reconcile, since a crashed controller restarts without any in-memory state.
+type harness struct {
+	t       *testing.T
+	gce     *fakegce.FakeGCE
+	client  client.Client
+	crashes []crashPoint
+}
+
+func newHarness(t *testing.T, crashes ...crashPoint) *harness {
+	h := &harness{t: t, gce: fakegce.New(fakegce.Config{}), crashes: crashes}
+	h.client = controllerfake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithStatusSubresource(&machinev1.Machine{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				h.maybeCrash(crashPoint{beforeWrite: "spec"})
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				h.maybeCrash(crashPoint{beforeWrite: subResourceName})
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	return h
+}
+
+// maybeCrash crashes the controller if point is the next crash point.
+func (h *harness) maybeCrash(point crashPoint) {
+	if len(h.crashes) > 0 && h.crashes[0] == point {
+		h.crashes = h.crashes[1:]
+		h.t.Logf("Crashing %s", point)
+		panic(errCrash)
+	}
+}
+
+func (h *harness) crashInterceptor(ctx context.Context, method string, call func() error) error {
+	if err := call(); err != nil {
+		return err
+	}
+	h.maybeCrash(crashPoint{afterCall: method})
+	return nil
+}
+
+func (h *harness) newActuator() *machine.Actuator {
+	return machine.NewActuator(machine.ActuatorParams{
+		CoreClient:    h.client,
+		EventRecorder: record.NewFakeRecorder(100),
+		ComputeClientBuilder: func(serviceAccountJSON string) (computeservice.GCPComputeService, error) {
+			return computeservice.WithInterceptors(h.gce, h.crashInterceptor), nil
+		},
+		TagsClientBuilder: tagservice.NewMockTagServiceBuilder,
+		FeatureGates:      featuregates.NewFeatureGate(nil, []configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}),
+	})
+}
+
+func (h *harness) createMachine(annotations map[string]string) {
+	providerSpec, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+		ProjectID:   "project",
+		Region:      "us-east1",
+		Zone:        "us-east1-b",
+		MachineType: "n1-standard-4",
+		Disks:       []*machinev1.GCPDisk{{Boot: true, AutoDelete: true, SizeGB: 128, Type: "pd-ssd", Image: "rhcos"}},
+		NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+			{Network: "network", Subnetwork: "subnetwork"},
+		},
+	})
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	m := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        machineName,
+			Namespace:   machineNamespace,
+			Labels:      map[string]string{machinev1.MachineClusterIDLabel: computeservice.MockClusterID},
+			Annotations: annotations,
+			Finalizers:  []string{machineFinalizer},
+		},
+		Spec: machinev1.MachineSpec{ProviderSpec: machinev1.ProviderSpec{Value: providerSpec}},
+	}
+	if err := h.client.Create(context.Background(), m); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// getMachine returns the persisted machine, or nil once it is gone.
+func (h *harness) getMachine() *machinev1.Machine {
+	m := &machinev1.Machine{}
+	err := h.client.Get(context.Background(), client.ObjectKey{Namespace: machineNamespace, Name: machineName}, m)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return m
+}
+
+// reconcile runs a reconcile of the machine controller on the persisted machine and reports
+// whether the controller crashed during it.
+func (h *harness) reconcile() (err error, crashed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errCrash {
+				panic(r)
+			}
+			crashed = true
+		}
+	}()
+
+	ctx := context.Background()
+	actuator := h.newActuator()
+	m := h.getMachine()
+	if m == nil {
+		return nil, false
+	}
+
+	if !m.DeletionTimestamp.IsZero() {
+		if err := actuator.Delete(ctx, m); err != nil {
+			return err, false
+		}
+		exists, err := actuator.Exists(ctx, m)
+		if err != nil || exists {
+			return err, false
+		}
+		m.Finalizers = nil
+		return h.client.Update(ctx, m), false
+	}
+
+	exists, err := actuator.Exists(ctx, m)
+	if err != nil {
+		return err, false
+	}
+	if exists {
+		return actuator.Update(ctx, m), false
+	}
+	if m.Spec.ProviderID != nil || len(m.Status.Addresses) > 0 {
+		// The machine controller fails a machine whose instance was created but is gone.
+		h.t.Fatalf("Instance of machine %s is lost", m.Name)
+	}
+	return actuator.Create(ctx, m), false
+}
+
+// reconcileUntil reconciles until done returns true, failing the test if the machine fails or does
+// not converge.
+func (h *harness) reconcileUntil(description string, done func(*machinev1.Machine) bool) {
+	for i := 0; i < maxReconciles; i++ {
+		err, crashed := h.reconcile()
+		if crashed {
+			continue
+		}
+		var machineErr *machinecontroller.MachineError
+		if errors.As(err, &machineErr) {
+			h.t.Fatalf("Machine failed: %v", err)
+		}
+		if err == nil && done(h.getMachine()) {
+			return
+		}
+	}
+	h.t.Fatalf("Machine did not converge to %s within %d reconciles", description, maxReconciles)
+}
+
+func provisioned(m *machinev1.Machine) bool {
+	if m == nil || m.Spec.ProviderID == nil {
+		return false
+	}
+	status, err := util.ProviderStatusFromRawExtension(m.Status.ProviderStatus)
+	if err != nil || status.InstanceState == nil || *status.InstanceState != "RUNNING" {
+		return false
+	}
+	return conditionStatus(status.Conditions, string(machinev1.MachineCreated)) == metav1.ConditionTrue &&
+		m.Annotations[pendingOperationsAnnotation] == ""
+}
+
+func conditionStatus(conditions []metav1.Condition, conditionType string) metav1.ConditionStatus {
+	for _, condition := range conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+	return metav1.ConditionUnknown
+}
+
+func TestCrashConsistency(t *testing.T) {
+	cases := []struct {
+		name                  string
+		staticInternalAddress bool
+		crashes               []crashPoint
+	}{
+		{
+			name: "No crash",
+		},
+		{
+			name:    "Crash after the instance is inserted",
+			crashes: []crashPoint{crashAfterInstanceInsert},
+		},
+		{
+			name:    "Crash before the machine is written",
+			crashes: []crashPoint{crashBeforeSpecWrite},
+		},
+		{
+			name:    "Crash before the status is written",
+			crashes: []crashPoint{crashBeforeStatusWrite},
+		},
+		{
+			name:    "Crash mid-delete",
+			crashes: []crashPoint{crashAfterInstanceDelete},
+		},
+		{
+			name:    "Repeated crashes",
+			crashes: []crashPoint{crashAfterInstanceInsert, crashBeforeStatusWrite, crashBeforeStatusWrite, crashAfterInstanceDelete},
+		},
+		{
+			name:                  "Crashes with a static internal address",
+			staticInternalAddress: true,
+			crashes:               []crashPoint{crashAfterAddressInsert, crashAfterInstanceInsert, crashAfterInstanceDelete, crashAfterAddressDelete},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t, tc.crashes...)
+			var annotations map[string]string
+			if tc.staticInternalAddress {
+				annotations = map[string]string{staticInternalAddressAnnotation: "true"}
+			}
+			h.createMachine(annotations)
+
+			h.reconcileUntil("provisioned", provisioned)
+			instances := h.gce.Instances()
+			if len(instances) != 1 {
+				t.Fatalf("Expected 1 instance, got %d", len(instances))
+			}
+			if inserts := h.gce.Calls("InstancesInsert"); inserts != 1 {
+				t.Errorf("Expected the instance to be inserted once, got %d inserts", inserts)
+			}
+			if tc.staticInternalAddress {
+				address, ok := h.gce.Addresses()[machineName]
+				if !ok || h.gce.Calls("AddressesInsert") != 1 {
+					t.Fatalf("Expected the static internal address to be reserved once, got %d reservations", h.gce.Calls("AddressesInsert"))
+				}
+				if ip := instances[machineName].NetworkInterfaces[0].NetworkIP; ip != address.Address {
+					t.Errorf("Expected the instance to use the static internal address %s, got %s", address.Address, ip)
+				}
+			}
+
+			// Reconciles of a provisioned machine are idempotent.
+			mutations := h.gce.Mutations()
+			for i := 0; i < 3; i++ {
+				if err, crashed := h.reconcile(); err != nil || crashed {
+					t.Fatalf("Unexpected error reconciling a provisioned machine: %v, crashed: %t", err, crashed)
+				}
+			}
+			if got := h.gce.Mutations(); got != mutations {
+				t.Errorf("Expected reconciles of a provisioned machine not to change the cloud, got %d mutations", got-mutations)
+			}
+
+			if err := h.client.Delete(context.Background(), h.getMachine()); err != nil {
+				t.Fatal(err)
+			}
+			h.reconcileUntil("deleted", func(m *machinev1.Machine) bool { return m == nil })
+			if instances, addresses := h.gce.Instances(), h.gce.Addresses(); len(instances) != 0 || len(addresses) != 0 {
+				t.Errorf("Expected no leaked resources, got instances %v and addresses %v", keys(instances), keys(addresses))
+			}
+
+			if len(h.crashes) != 0 {
+				t.Errorf("Expected the controller to crash %v", h.crashes)
+			}
+		})
+	}
+}
+
+func keys[T any](resources map[string]T) []string {
+	var names []string
+	for name := range resources {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,241 @@
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// resizingConditionType reports the progress and outcome of an in-place machine type resize.
+	resizingConditionType  = "Resizing"
+	resizeSucceededReason  = "ResizeSucceeded"
+	resizeFailedReason     = "ResizeFailed"
+	resizeSucceededEvent   = "MachineTypeResized"
+	resizeFailedEvent      = "MachineTypeResizeFailed"
+	resizeStartedEvent     = "MachineTypeResizeStarted"
+	terminatedInstanceStat = "TERMINATED"
+)
+
+// resizePhase is a step of an in-place resize, it is also the reason of the Resizing condition
+// while the resize is in progress.
+type resizePhase string
+
+const (
+	resizeStopping           resizePhase = "Stopping"
+	resizeSettingMachineType resizePhase = "SettingMachineType"
+	resizeStarting           resizePhase = "Starting"
+	resizeRollingBack        resizePhase = "RollingBack"
+	// resizeFailed is kept once a resize was rolled back, so that it is not retried until the
+	// machine type of the providerSpec changes again.
+	resizeFailed resizePhase = "Failed"
+)
+
+// resizeState is the progress of an in-place resize, persisted in the resizeStateAnnotation so that
+// a resize interrupted by a restart of the controller resumes where it stopped.
+type resizeState struct {
+	Phase resizePhase `json:"phase"`
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	// Operation is the zonal operation of the last step, polled before the next step.
+	Operation string `json:"operation,omitempty"`
+	// Error is why the resize was rolled back.
+	Error string `json:"error,omitempty"`
+}
+
+// reconcileMachineTypeResize changes the machine type of the instance to the one of the
+// providerSpec in place, by stopping the instance, setting its machine type and starting it
+// again, when the machine opted in with the inPlaceResizeAnnotation. A step that fails rolls the
+// instance back to its previous machine type and starts it again. It returns true while a resize
+// is in progress, together with the error requeueing the machine.
+func (r *Reconciler) reconcileMachineTypeResize(instance *compute.Instance) (bool, error) {
+	state, err := r.resizeState()
+	if err != nil {
+		return false, err
+	}
+	actual := path.Base(instance.MachineType)
+	desired := r.providerSpec.MachineType
+
+	if state == nil || state.Phase == resizeFailed {
+		if actual == desired {
+			if state != nil {
+				delete(r.machine.Annotations, resizeStateAnnotation)
+			}
+			r.clearMaintenanceWindowDeferral()
+			return false, nil
+		}
+		if state != nil && state.To == desired {
+			// The resize to this machine type was rolled back, it is not retried.
+			r.clearMaintenanceWindowDeferral()
+			return false, nil
+		}
+		enabled, err := r.getBoolAnnotation(inPlaceResizeAnnotation)
+		if err != nil || !enabled {
+			r.clearMaintenanceWindowDeferral()
+			return false, err
+		}
+		if instance.Status != "RUNNING" && instance.Status != terminatedInstanceStat {
+			return false, nil
+		}
+		// The rest of the machine is still reconciled while the resize waits for a maintenance
+		// window, which is checked again on the next sync of the machine.
+		if deferred, err := r.deferUntilMaintenanceWindow(fmt.Sprintf("resizing the instance from %s to %s", actual, desired)); deferred || err != nil {
+			return false, err
+		}
+		state = &resizeState{Phase: resizeStopping, From: actual, To: desired}
+		r.log.Info("Resizing instance in place", "from", actual, "to", desired)
+		r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, resizeStartedEvent, "Resizing instance from %s to %s in place, the instance is stopped", actual, desired)
+	}
+
+	if err := r.stepResize(state, instance, actual); err != nil {
+		state.Phase = resizeRollingBack
+		state.Error = err.Error()
+		state.Operation = ""
+		r.log.Error(err, "Resize failed, rolling back", "from", state.From, "to", state.To)
+	}
+
+	switch state.Phase {
+	case "":
+		// The resize completed.
+		delete(r.machine.Annotations, resizeStateAnnotation)
+		message := fmt.Sprintf("instance was resized from %s to %s", state.From, state.To)
+		r.eventRecorder.Event(r.machine, corev1.EventTypeNormal, resizeSucceededEvent, message)
+		r.setResizingCondition(metav1.ConditionFalse, resizeSucceededReason, message)
+		return false, nil
+	case resizeFailed:
+		r.setResizeState(state)
+		message := fmt.Sprintf("instance was rolled back to %s after resizing it to %s failed: %s", state.From, state.To, state.Error)
+		r.eventRecorder.Event(r.machine, corev1.EventTypeWarning, resizeFailedEvent, message)
+		r.setResizingCondition(metav1.ConditionFalse, resizeFailedReason, message)
+		return false, nil
+	}
+	r.setResizeState(state)
+	message := fmt.Sprintf("resizing instance from %s to %s", state.From, state.To)
+	if state.Phase == resizeRollingBack {
+		message = fmt.Sprintf("rolling instance back to %s after resizing it to %s failed: %s", state.From, state.To, state.Error)
+	}
+	r.setResizingCondition(metav1.ConditionTrue, string(state.Phase), message)
+	return true, &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+}
+
+// stepResize advances the resize as far as the instance allows. The operation of the previous step
+// must be done before the instance is looked at again, the instance was read before it completed.
+// An empty phase means the resize completed. Errors of the resize steps are returned to roll back,
+// errors while rolling back are retried by the following reconciles.
+func (r *Reconciler) stepResize(state *resizeState, instance *compute.Instance, actual string) error {
+	if state.Operation != "" {
+		operation, err := r.computeService.ZoneOperationsGet(r.projectID, r.providerSpec.Zone, state.Operation)
+		if err != nil {
+			r.log.Error(err, "Failed to get resize operation", "operation", state.Operation, "phase", state.Phase)
+			return nil
+		}
+		if operation.Status != operationDoneStatus {
+			return nil
+		}
+		state.Operation = ""
+		if errs := operationErrors(operation); len(errs) > 0 {
+			return r.resizeStepFailed(state, fmt.Errorf("%s operation %s failed: %s", state.Phase, operation.Name, strings.Join(errs, "; ")))
+		}
+		return nil
+	}
+
+	for {
+		var operation *compute.Operation
+		var err error
+		switch state.Phase {
+		case resizeStopping:
+			switch instance.Status {
+			case terminatedInstanceStat:
+				state.Phase = resizeSettingMachineType
+				continue
+			case "RUNNING":
+				operation, err = r.computeService.InstancesStop(r.projectID, r.providerSpec.Zone, instance.Name)
+			}
+		case resizeSettingMachineType:
+			if actual == state.To {
+				state.Phase = resizeStarting
+				continue
+			}
+			operation, err = r.setMachineType(instance.Name, state.To)
+		case resizeStarting:
+			switch instance.Status {
+			case "RUNNING":
+				state.Phase = ""
+			case terminatedInstanceStat:
+				operation, err = r.computeService.InstancesStart(r.projectID, r.providerSpec.Zone, instance.Name)
+			}
+		case resizeRollingBack:
+			switch {
+			case actual != state.From && instance.Status == terminatedInstanceStat:
+				operation, err = r.setMachineType(instance.Name, state.From)
+			case actual != state.From && instance.Status == "RUNNING":
+				// The instance was started with the new machine type before the failure was seen.
+				operation, err = r.computeService.InstancesStop(r.projectID, r.providerSpec.Zone, instance.Name)
+			case instance.Status == terminatedInstanceStat:
+				operation, err = r.computeService.InstancesStart(r.projectID, r.providerSpec.Zone, instance.Name)
+			case instance.Status == "RUNNING":
+				state.Phase = resizeFailed
+			}
+		}
+		if err != nil {
+			return r.resizeStepFailed(state, fmt.Errorf("%s failed: %w", state.Phase, err))
+		}
+		if operation != nil && operation.Status != operationDoneStatus {
+			state.Operation = operation.Name
+		}
+		return nil
+	}
+}
+
+// resizeStepFailed returns the error of a resize step to roll the resize back. Errors while rolling
+// back are only logged, the rollback is retried.
+func (r *Reconciler) resizeStepFailed(state *resizeState, err error) error {
+	if state.Phase == resizeRollingBack {
+		r.log.Error(err, "Failed to roll back the resize, retrying", "to", state.From)
+		return nil
+	}
+	return err
+}
+
+func (r *Reconciler) setMachineType(instance, machineType string) (*compute.Operation, error) {
+	return r.computeService.InstancesSetMachineType(r.projectID, r.providerSpec.Zone, instance, &compute.InstancesSetMachineTypeRequest{
+		MachineType: fmt.Sprintf(machineTypeFmt, r.providerSpec.Zone, machineType),
+	})
+}
+
+// resizeState returns the resize in progress or failed, nil when there is none.
+func (r *Reconciler) resizeState() (*resizeState, error) {
+	state := &resizeState{}
+	if ok, err := r.getJSONAnnotation(resizeStateAnnotation, state); !ok || err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (r *Reconciler) setResizeState(state *resizeState) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		r.log.Error(err, "Failed to record resize state")
+		return
+	}
+	if r.machine.Annotations == nil {
+		r.machine.Annotations = map[string]string{}
+	}
+	r.machine.Annotations[resizeStateAnnotation] = string(data)
+}
+
+func (r *Reconciler) setResizingCondition(status metav1.ConditionStatus, reason, message string) {
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    resizingConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
@@ -0,0 +1,136 @@
+package machine
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReconcileMachineTypeResize(t *testing.T) {
+	cases := []struct {
+		name                string
+		annotations         map[string]string
+		failStart           bool
+		expectedCalls       []string
+		expectedMachineType string
+		expectedReason      string
+		expectedAnnotation  bool
+	}{
+		{
+			name:                "Machine type is not resized without the annotation",
+			expectedMachineType: "n2-standard-4",
+		},
+		{
+			name:                "Machine type is resized in place",
+			annotations:         map[string]string{inPlaceResizeAnnotation: "true"},
+			expectedCalls:       []string{"stop", "setMachineType n2-standard-8", "start"},
+			expectedMachineType: "n2-standard-8",
+			expectedReason:      resizeSucceededReason,
+		},
+		{
+			name:                "Machine type is rolled back when the instance fails to start",
+			annotations:         map[string]string{inPlaceResizeAnnotation: "true"},
+			failStart:           true,
+			expectedCalls:       []string{"stop", "setMachineType n2-standard-8", "start", "setMachineType n2-standard-4", "start"},
+			expectedMachineType: "n2-standard-4",
+			expectedReason:      resizeFailedReason,
+			expectedAnnotation:  true,
+		},
+		{
+			name: "Failed resize is not retried",
+			annotations: map[string]string{
+				inPlaceResizeAnnotation: "true",
+				resizeStateAnnotation:   `{"phase": "Failed", "from": "n2-standard-4", "to": "n2-standard-8"}`,
+			},
+			expectedMachineType: "n2-standard-4",
+			expectedAnnotation:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			instance := &compute.Instance{
+				Name:        "worker-a",
+				Status:      "RUNNING",
+				MachineType: "https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b/machineTypes/n2-standard-4",
+			}
+			var calls []string
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockInstancesStop = func(_, _, _ string) (*compute.Operation, error) {
+				calls = append(calls, "stop")
+				instance.Status = "TERMINATED"
+				return &compute.Operation{Name: "stop", Status: "DONE"}, nil
+			}
+			mockComputeService.MockSetMachineType = func(_, _, _ string, request *compute.InstancesSetMachineTypeRequest) (*compute.Operation, error) {
+				calls = append(calls, "setMachineType "+path.Base(request.MachineType))
+				instance.MachineType = request.MachineType
+				return &compute.Operation{Name: "setMachineType", Status: "PENDING"}, nil
+			}
+			mockComputeService.MockInstancesStart = func(_, _, _ string) (*compute.Operation, error) {
+				calls = append(calls, "start")
+				if tc.failStart && path.Base(instance.MachineType) == "n2-standard-8" {
+					return nil, errors.New("ZONE_RESOURCE_POOL_EXHAUSTED")
+				}
+				instance.Status = "RUNNING"
+				return &compute.Operation{Name: "start", Status: "DONE"}, nil
+			}
+			mockComputeService.MockZoneOperationsGet = func(_, _, operation string) (*compute.Operation, error) {
+				return &compute.Operation{Name: operation, Status: "DONE"}, nil
+			}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker-a",
+					Annotations: tc.annotations,
+				}},
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:        "us-east1-b",
+					MachineType: "n2-standard-8",
+				},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+				eventRecorder:  record.NewFakeRecorder(10),
+			})
+
+			// Every reconcile advances the resize by at most one step.
+			for i := 0; ; i++ {
+				if i == 10 {
+					t.Fatalf("Expected the resize to complete within %d reconciles", i)
+				}
+				resizing, err := r.reconcileMachineTypeResize(instance)
+				if !resizing {
+					if err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+					break
+				}
+				if err == nil {
+					t.Fatalf("Expected a requeue while resizing")
+				}
+			}
+
+			if strings.Join(calls, ",") != strings.Join(tc.expectedCalls, ",") {
+				t.Errorf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+			if machineType := path.Base(instance.MachineType); machineType != tc.expectedMachineType || instance.Status != "RUNNING" {
+				t.Errorf("Expected a running %s instance, got a %s %s instance", tc.expectedMachineType, instance.Status, machineType)
+			}
+			condition := findCondition(r.providerStatus.Conditions, resizingConditionType)
+			switch {
+			case tc.expectedReason == "" && condition != nil:
+				t.Errorf("Expected no condition, got %v", condition)
+			case tc.expectedReason != "" && (condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != tc.expectedReason):
+				t.Errorf("Expected a False condition with reason %s, got %v", tc.expectedReason, condition)
+			}
+			if _, ok := r.machine.Annotations[resizeStateAnnotation]; ok != tc.expectedAnnotation {
+				t.Errorf("Expected the resize state annotation to be set %v, got %v", tc.expectedAnnotation, r.machine.Annotations)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	serviceAccountNotFoundReason = "ServiceAccountNotFound"
+	serviceAccountDisabledReason = "ServiceAccountDisabled"
+
+	// gceDefaultServiceAccount refers to the Compute Engine default service account of the project.
+	gceDefaultServiceAccount = "default"
+
+	oauthScopePrefix     = "https://www.googleapis.com/auth/"
+	cloudPlatformScope   = oauthScopePrefix + "cloud-platform"
+	defaultInstanceScope = cloudPlatformScope
+)
+
+// serviceAccountScopeAliases are the scope aliases understood by gcloud, so that the same
+// values can be used in the providerSpec, see `gcloud compute instances create --scopes`.
+var serviceAccountScopeAliases = map[string]string{
+	"bigquery":              oauthScopePrefix + "bigquery",
+	"cloud-platform":        cloudPlatformScope,
+	"cloud-source-repos":    oauthScopePrefix + "source.full_control",
+	"cloud-source-repos-ro": oauthScopePrefix + "source.read_only",
+	"compute-ro":            oauthScopePrefix + "compute.readonly",
+	"compute-rw":            oauthScopePrefix + "compute",
+	"datastore":             oauthScopePrefix + "datastore",
+	"default":               cloudPlatformScope,
+	"logging-write":         oauthScopePrefix + "logging.write",
+	"monitoring":            oauthScopePrefix + "monitoring",
+	"monitoring-read":       oauthScopePrefix + "monitoring.read",
+	"monitoring-write":      oauthScopePrefix + "monitoring.write",
+	"pubsub":                oauthScopePrefix + "pubsub",
+	"service-control":       oauthScopePrefix + "servicecontrol",
+	"service-management":    oauthScopePrefix + "service.management.readonly",
+	"sql-admin":             oauthScopePrefix + "sqlservice.admin",
+	"storage-full":          oauthScopePrefix + "devstorage.full_control",
+	"storage-ro":            oauthScopePrefix + "devstorage.read_only",
+	"storage-rw":            oauthScopePrefix + "devstorage.read_write",
+	"taskqueue":             oauthScopePrefix + "taskqueue",
+	"trace":                 oauthScopePrefix + "trace.append",
+	"userinfo-email":        oauthScopePrefix + "userinfo.email",
+}
+
+// serviceAccounts returns the service accounts of the instance. Machines that do not set one
+// get the default service account of the cluster nodes, if configured. Scopes may be given
+// as gcloud aliases and default to cloud-platform.
+func (r *Reconciler) serviceAccounts() ([]*compute.ServiceAccount, error) {
+	specServiceAccounts := r.providerSpec.ServiceAccounts
+	if len(specServiceAccounts) > 1 {
+		return nil, machinecontroller.InvalidMachineConfiguration("at most one service account can be attached to an instance, got %d", len(specServiceAccounts))
+	}
+
+	email, scopes := r.options.DefaultServiceAccount, []string(nil)
+	if len(specServiceAccounts) == 1 {
+		email, scopes = specServiceAccounts[0].Email, specServiceAccounts[0].Scopes
+		if email == "" {
+			return nil, machinecontroller.InvalidMachineConfiguration("service account email must be set")
+		}
+	}
+	if email == "" {
+		return []*compute.ServiceAccount{}, nil
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{defaultInstanceScope}
+	}
+	resolved := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !strings.HasPrefix(scope, "https://") {
+			alias, ok := serviceAccountScopeAliases[scope]
+			if !ok {
+				return nil, machinecontroller.InvalidMachineConfiguration("invalid scope %q of service account %s, must be a URL or a gcloud scope alias", scope, email)
+			}
+			scope = alias
+		}
+		resolved = append(resolved, scope)
+	}
+
+	return []*compute.ServiceAccount{{Email: email, Scopes: resolved}}, nil
+}
+
+// checkServiceAccounts verifies that the service accounts of the instance exist and are enabled,
+// so that a misspelled account fails the creation instead of producing a node that cannot pull
+// images. The check is skipped when the credentials are not allowed to read the account.
+func (r *Reconciler) checkServiceAccounts(state *preflightState) error {
+	if r.iamService == nil {
+		return nil
+	}
+
+	for _, serviceAccount := range state.instance.ServiceAccounts {
+		if serviceAccount.Email == gceDefaultServiceAccount {
+			continue
+		}
+
+		account, err := r.iamService.ServiceAccountsGet(r.Context, serviceAccount.Email)
+		if err != nil {
+			var apiErr *googleapi.Error
+			switch {
+			case isNotFoundError(err):
+				return &preflightError{
+					reason: serviceAccountNotFoundReason,
+					err:    machinecontroller.InvalidMachineConfiguration("service account %s does not exist", serviceAccount.Email),
+				}
+			case errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden:
+				r.log.Error(err, "Not allowed to verify service account, skipping the check", "serviceAccount", serviceAccount.Email)
+				continue
+			}
+			return fmt.Errorf("failed to get service account %s: %w", serviceAccount.Email, err)
+		}
+		if account.Disabled {
+			return &preflightError{
+				reason: serviceAccountDisabledReason,
+				err:    machinecontroller.InvalidMachineConfiguration("service account %s is disabled", serviceAccount.Email),
+			}
+		}
+	}
+	return nil
+}
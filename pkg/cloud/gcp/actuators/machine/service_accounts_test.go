@@ -0,0 +1,87 @@
+package machine
+
+import (
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestServiceAccounts(t *testing.T) {
+	cases := []struct {
+		name                  string
+		serviceAccounts       []machinev1.GCPServiceAccount
+		defaultServiceAccount string
+		expected              []*compute.ServiceAccount
+		expectedError         string
+	}{
+		{
+			name:     "No service account",
+			expected: []*compute.ServiceAccount{},
+		},
+		{
+			name:                  "Default service account of the cluster nodes",
+			defaultServiceAccount: "infra-w@project.iam.gserviceaccount.com",
+			expected:              []*compute.ServiceAccount{{Email: "infra-w@project.iam.gserviceaccount.com", Scopes: []string{cloudPlatformScope}}},
+		},
+		{
+			name:                  "Service account of the machine takes precedence",
+			serviceAccounts:       []machinev1.GCPServiceAccount{{Email: "custom@project.iam.gserviceaccount.com", Scopes: []string{"storage-ro", "https://www.googleapis.com/auth/logging.write"}}},
+			defaultServiceAccount: "infra-w@project.iam.gserviceaccount.com",
+			expected: []*compute.ServiceAccount{{
+				Email:  "custom@project.iam.gserviceaccount.com",
+				Scopes: []string{"https://www.googleapis.com/auth/devstorage.read_only", "https://www.googleapis.com/auth/logging.write"},
+			}},
+		},
+		{
+			name:            "Scopes default to cloud-platform",
+			serviceAccounts: []machinev1.GCPServiceAccount{{Email: "custom@project.iam.gserviceaccount.com"}},
+			expected:        []*compute.ServiceAccount{{Email: "custom@project.iam.gserviceaccount.com", Scopes: []string{cloudPlatformScope}}},
+		},
+		{
+			name:            "Invalid scope",
+			serviceAccounts: []machinev1.GCPServiceAccount{{Email: "custom@project.iam.gserviceaccount.com", Scopes: []string{"storage"}}},
+			expectedError:   "invalid scope \"storage\" of service account custom@project.iam.gserviceaccount.com, must be a URL or a gcloud scope alias",
+		},
+		{
+			name:            "Missing email",
+			serviceAccounts: []machinev1.GCPServiceAccount{{Scopes: []string{"cloud-platform"}}},
+			expectedError:   "service account email must be set",
+		},
+		{
+			name: "More than one service account",
+			serviceAccounts: []machinev1.GCPServiceAccount{
+				{Email: "a@project.iam.gserviceaccount.com"},
+				{Email: "b@project.iam.gserviceaccount.com"},
+			},
+			expectedError: "at most one service account can be attached to an instance, got 2",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newReconciler(&machineScope{
+				providerSpec: &machinev1.GCPMachineProviderSpec{ServiceAccounts: tc.serviceAccounts},
+				options:      Options{DefaultServiceAccount: tc.defaultServiceAccount},
+			})
+
+			serviceAccounts, err := r.serviceAccounts()
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Fatalf("Expected error %q, got %v", tc.expectedError, err)
+				}
+				if !isInvalidMachineConfigurationError(err) {
+					t.Errorf("Expected an invalid machine configuration error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(serviceAccounts, tc.expected) {
+				t.Errorf("Expected service accounts %+v, got %+v", tc.expected, serviceAccounts)
+			}
+		})
+	}
+}
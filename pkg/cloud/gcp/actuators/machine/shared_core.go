@@ -0,0 +1,86 @@
+package machine
+
+import (
+	"fmt"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SharedCorePolicy is what the reconciler does about critical machines using a shared-core machine type.
+type SharedCorePolicy string
+
+const (
+	// SharedCorePolicyWarn reports the machines in the SharedCoreMachineType condition.
+	SharedCorePolicyWarn SharedCorePolicy = "warn"
+	// SharedCorePolicyBlock also refuses to create their instances.
+	SharedCorePolicyBlock SharedCorePolicy = "block"
+
+	// sharedCoreConditionType reports whether a control plane or infra machine uses a shared-core
+	// machine type, whose vCPUs are only available in bursts.
+	sharedCoreConditionType  = "SharedCoreMachineType"
+	sharedCoreInUseReason    = "SharedCoreMachineTypeInUse"
+	sharedCoreNotInUseReason = "DedicatedCoreMachineType"
+	sharedCoreBlockedReason  = "SharedCoreMachineTypeBlocked"
+
+	infraMachineRole = "infra"
+)
+
+// sharedCoreMachineTypes are the machine types that share physical cores with other instances.
+var sharedCoreMachineTypes = map[string]bool{
+	"e2-micro":  true,
+	"e2-small":  true,
+	"e2-medium": true,
+	"f1-micro":  true,
+	"g1-small":  true,
+}
+
+// ParseSharedCorePolicy returns the policy of the given name.
+func ParseSharedCorePolicy(name string) (SharedCorePolicy, error) {
+	switch policy := SharedCorePolicy(name); policy {
+	case SharedCorePolicyWarn, SharedCorePolicyBlock:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown shared-core policy %q, expected %s or %s", name, SharedCorePolicyWarn, SharedCorePolicyBlock)
+}
+
+// usesSharedCoreForCriticalRole returns true if the machine is a control plane or infra machine
+// with a shared-core machine type.
+func (r *Reconciler) usesSharedCoreForCriticalRole() bool {
+	role := r.machine.Labels[openshiftMachineRoleLabel]
+	return (role == masterMachineRole || role == infraMachineRole) && sharedCoreMachineTypes[r.providerSpec.MachineType]
+}
+
+// checkSharedCoreMachineType refuses to create the instance of a critical machine with a
+// shared-core machine type when the policy blocks them.
+func (r *Reconciler) checkSharedCoreMachineType(_ *preflightState) error {
+	if r.options.SharedCorePolicy != SharedCorePolicyBlock || !r.usesSharedCoreForCriticalRole() {
+		return nil
+	}
+	return &preflightError{
+		reason: sharedCoreBlockedReason,
+		err: machinecontroller.InvalidMachineConfiguration("shared-core machine type %s is not allowed for %s machines",
+			r.providerSpec.MachineType, r.machine.Labels[openshiftMachineRoleLabel]),
+	}
+}
+
+// reconcileSharedCoreCondition warns about critical machines using a shared-core machine type, which
+// may not have enough CPU for the control plane or infra workloads under sustained load. Machines
+// that never used one do not get the condition.
+func (r *Reconciler) reconcileSharedCoreCondition() {
+	condition := metav1.Condition{
+		Type:    sharedCoreConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  sharedCoreNotInUseReason,
+		Message: "machine type has dedicated cores",
+	}
+	if r.usesSharedCoreForCriticalRole() {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = sharedCoreInUseReason
+		condition.Message = fmt.Sprintf("%s machine uses shared-core machine type %s, whose vCPUs are only available in bursts, consider a larger machine type",
+			r.machine.Labels[openshiftMachineRoleLabel], r.providerSpec.MachineType)
+	} else if findCondition(r.providerStatus.Conditions, sharedCoreConditionType) == nil {
+		return
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, condition)
+}
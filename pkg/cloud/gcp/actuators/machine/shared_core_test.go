@@ -0,0 +1,97 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSharedCoreMachineType(t *testing.T) {
+	cases := []struct {
+		name               string
+		role               string
+		machineType        string
+		policy             SharedCorePolicy
+		existingConditions []metav1.Condition
+		expectedReason     string
+		expectedBlocked    bool
+	}{
+		{
+			name:        "Worker with a shared-core machine type",
+			role:        "worker",
+			machineType: "e2-medium",
+			policy:      SharedCorePolicyBlock,
+		},
+		{
+			name:        "Control plane with a dedicated machine type",
+			role:        masterMachineRole,
+			machineType: "e2-standard-4",
+			policy:      SharedCorePolicyBlock,
+		},
+		{
+			name:           "Control plane with a shared-core machine type is reported",
+			role:           masterMachineRole,
+			machineType:    "e2-medium",
+			policy:         SharedCorePolicyWarn,
+			expectedReason: sharedCoreInUseReason,
+		},
+		{
+			name:            "Infra machine with a shared-core machine type is blocked",
+			role:            infraMachineRole,
+			machineType:     "e2-small",
+			policy:          SharedCorePolicyBlock,
+			expectedReason:  sharedCoreInUseReason,
+			expectedBlocked: true,
+		},
+		{
+			name:        "Condition is cleared after resizing",
+			role:        infraMachineRole,
+			machineType: "e2-standard-4",
+			existingConditions: []metav1.Condition{
+				{Type: sharedCoreConditionType, Status: metav1.ConditionTrue, Reason: sharedCoreInUseReason},
+			},
+			expectedReason: sharedCoreNotInUseReason,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+					Name:   "test",
+					Labels: map[string]string{openshiftMachineRoleLabel: tc.role},
+				}},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{MachineType: tc.machineType},
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.existingConditions},
+				options:        Options{SharedCorePolicy: tc.policy},
+			})
+
+			err := r.checkSharedCoreMachineType(&preflightState{})
+			var machineErr *machinecontroller.MachineError
+			if blocked := errors.As(err, &machineErr); blocked != tc.expectedBlocked {
+				t.Errorf("Expected the instance to be blocked %v, got %v", tc.expectedBlocked, err)
+			}
+
+			r.reconcileSharedCoreCondition()
+			condition := findCondition(r.providerStatus.Conditions, sharedCoreConditionType)
+			switch {
+			case tc.expectedReason == "" && condition != nil:
+				t.Errorf("Expected no condition, got %v", condition)
+			case tc.expectedReason != "" && (condition == nil || condition.Reason != tc.expectedReason):
+				t.Errorf("Expected a condition with reason %s, got %v", tc.expectedReason, condition)
+			}
+		})
+	}
+}
+
+func TestParseSharedCorePolicy(t *testing.T) {
+	if policy, err := ParseSharedCorePolicy("block"); err != nil || policy != SharedCorePolicyBlock {
+		t.Errorf("Expected the block policy, got %q, %v", policy, err)
+	}
+	if _, err := ParseSharedCorePolicy("deny"); err == nil {
+		t.Errorf("Expected an error for an unknown policy")
+	}
+}
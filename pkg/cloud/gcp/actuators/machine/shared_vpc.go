@@ -0,0 +1,126 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// sharedVPCConditionType reports whether the credentials of the machine may attach the
+	// instance to the subnetworks of the shared VPC host projects of its network interfaces.
+	sharedVPCConditionType             = "SharedVPCAccess"
+	sharedVPCPermissionsGrantedReason  = "SharedVPCPermissionsGranted"
+	sharedVPCPermissionDeniedReason    = "SharedVPCPermissionDenied"
+	sharedVPCSubnetworkNotFoundReason  = "SharedVPCSubnetworkNotFound"
+	sharedVPCPermissionsGrantedMessage = "credentials may use the subnetworks of the shared VPC host projects"
+	sharedVPCNetworkUserRole           = "roles/compute.networkUser"
+	subnetworksUsePermission           = "compute.subnetworks.use"
+	subnetworksUseExternalIPPermission = "compute.subnetworks.useExternalIp"
+)
+
+// networkProject returns the project of the network of an interface: the host project of a
+// shared VPC when the interface sets one, the project of the machine otherwise.
+func (r *Reconciler) networkProject(nic *machinev1.GCPNetworkInterface) string {
+	if nic.ProjectID != "" {
+		return nic.ProjectID
+	}
+	return r.projectID
+}
+
+// networkURL returns the partial URL of the network of an interface, in its network project.
+func (r *Reconciler) networkURL(nic *machinev1.GCPNetworkInterface) string {
+	return fmt.Sprintf("projects/%s/global/networks/%s", r.networkProject(nic), nic.Network)
+}
+
+// subnetworkURL returns the partial URL of the subnetwork of an interface, in its network project
+// and the region of the machine.
+func (r *Reconciler) subnetworkURL(nic *machinev1.GCPNetworkInterface) string {
+	return fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", r.networkProject(nic), r.providerSpec.Region, nic.Subnetwork)
+}
+
+// checkSharedVPCPermissions verifies that the credentials of the machine were granted
+// roles/compute.networkUser, or the permissions it carries, on the subnetworks the instance is
+// attached to in shared VPC host projects, so that a missing grant fails the creation with the
+// sharedVPCConditionType condition instead of an opaque error from instances.insert. The check
+// is skipped when the credentials are not allowed to test the permissions.
+func (r *Reconciler) checkSharedVPCPermissions(state *preflightState) error {
+	checked := false
+	for i, nic := range r.providerSpec.NetworkInterfaces {
+		if nic.Subnetwork == "" || r.networkProject(nic) == r.projectID {
+			continue
+		}
+		if i < len(state.instance.NetworkInterfaces) && state.instance.NetworkInterfaces[i].NetworkAttachment != "" {
+			continue
+		}
+
+		required := []string{subnetworksUsePermission}
+		if nic.PublicIP {
+			required = append(required, subnetworksUseExternalIPPermission)
+		}
+		granted, err := r.computeService.SubnetworksTestIamPermissions(nic.ProjectID, r.providerSpec.Region, nic.Subnetwork, required)
+		if err != nil {
+			var apiErr *googleapi.Error
+			switch {
+			case isNotFoundError(err):
+				return r.sharedVPCError(sharedVPCSubnetworkNotFoundReason,
+					machinecontroller.InvalidMachineConfiguration("subnetwork %s does not exist in region %s of shared VPC host project %s",
+						nic.Subnetwork, r.providerSpec.Region, nic.ProjectID))
+			case errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden:
+				r.log.Error(err, "Not allowed to test the permissions on the shared VPC subnetwork, skipping the check", "subnetwork", r.subnetworkURL(nic))
+				continue
+			}
+			return fmt.Errorf("failed to test permissions on subnetwork %s: %w", r.subnetworkURL(nic), err)
+		}
+
+		if missing := missingPermissions(required, granted); len(missing) > 0 {
+			return r.sharedVPCError(sharedVPCPermissionDeniedReason,
+				machinecontroller.InvalidMachineConfiguration("credentials of project %s lack %s on subnetwork %s of shared VPC host project %s, grant them %s on the subnetwork",
+					r.projectID, strings.Join(missing, ", "), nic.Subnetwork, nic.ProjectID, sharedVPCNetworkUserRole))
+		}
+		checked = true
+	}
+
+	if checked {
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+			Type:    sharedVPCConditionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  sharedVPCPermissionsGrantedReason,
+			Message: sharedVPCPermissionsGrantedMessage,
+		})
+	}
+	return nil
+}
+
+// sharedVPCError sets the sharedVPCConditionType condition to False and returns the pre-flight
+// error failing the creation for the same reason.
+func (r *Reconciler) sharedVPCError(reason string, err error) error {
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    sharedVPCConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: err.Error(),
+	})
+	return &preflightError{reason: reason, err: err}
+}
+
+// missingPermissions returns the required permissions that were not granted.
+func missingPermissions(required, granted []string) []string {
+	grantedSet := make(map[string]bool, len(granted))
+	for _, permission := range granted {
+		grantedSet[permission] = true
+	}
+	var missing []string
+	for _, permission := range required {
+		if !grantedSet[permission] {
+			missing = append(missing, permission)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,180 @@
+package machine
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNetworkURLs(t *testing.T) {
+	r := newReconciler(&machineScope{
+		Context:      context.Background(),
+		machine:      &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+		providerSpec: &machinev1.GCPMachineProviderSpec{Region: "us-east1"},
+		projectID:    "service-project",
+	})
+
+	cases := []struct {
+		name               string
+		nic                *machinev1.GCPNetworkInterface
+		expectedNetwork    string
+		expectedSubnetwork string
+	}{
+		{
+			name:               "Network of the project of the machine",
+			nic:                &machinev1.GCPNetworkInterface{Network: "network", Subnetwork: "subnet"},
+			expectedNetwork:    "projects/service-project/global/networks/network",
+			expectedSubnetwork: "projects/service-project/regions/us-east1/subnetworks/subnet",
+		},
+		{
+			name:               "Shared VPC",
+			nic:                &machinev1.GCPNetworkInterface{ProjectID: "host-project", Network: "network", Subnetwork: "subnet"},
+			expectedNetwork:    "projects/host-project/global/networks/network",
+			expectedSubnetwork: "projects/host-project/regions/us-east1/subnetworks/subnet",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if network := r.networkURL(tc.nic); network != tc.expectedNetwork {
+				t.Errorf("Expected network %s, got %s", tc.expectedNetwork, network)
+			}
+			if subnetwork := r.subnetworkURL(tc.nic); subnetwork != tc.expectedSubnetwork {
+				t.Errorf("Expected subnetwork %s, got %s", tc.expectedSubnetwork, subnetwork)
+			}
+		})
+	}
+}
+
+func TestCheckSharedVPCPermissions(t *testing.T) {
+	sharedVPC := []*machinev1.GCPNetworkInterface{{ProjectID: "host-project", Network: "network", Subnetwork: "subnet"}}
+
+	cases := []struct {
+		name              string
+		networkInterfaces []*machinev1.GCPNetworkInterface
+		mockPermissions   func(project string, region string, subnetwork string, permissions []string) ([]string, error)
+		expectedCalls     int
+		expectedStatus    metav1.ConditionStatus
+		expectedReason    string
+		expectedError     string
+	}{
+		{
+			name:              "Network of the project of the machine is not checked",
+			networkInterfaces: []*machinev1.GCPNetworkInterface{{Network: "network", Subnetwork: "subnet"}},
+		},
+		{
+			name:              "Network user of the host project",
+			networkInterfaces: sharedVPC,
+			expectedCalls:     1,
+			expectedStatus:    metav1.ConditionTrue,
+			expectedReason:    sharedVPCPermissionsGrantedReason,
+		},
+		{
+			name:              "Not a network user of the host project",
+			networkInterfaces: sharedVPC,
+			mockPermissions: func(_ string, _ string, _ string, _ []string) ([]string, error) {
+				return nil, nil
+			},
+			expectedCalls:  1,
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: sharedVPCPermissionDeniedReason,
+			expectedError: "credentials of project service-project lack compute.subnetworks.use on subnetwork subnet of shared VPC host project host-project, " +
+				"grant them roles/compute.networkUser on the subnetwork",
+		},
+		{
+			name:              "External IP not allowed in the host project",
+			networkInterfaces: []*machinev1.GCPNetworkInterface{{ProjectID: "host-project", Network: "network", Subnetwork: "subnet", PublicIP: true}},
+			mockPermissions: func(_ string, _ string, _ string, _ []string) ([]string, error) {
+				return []string{subnetworksUsePermission}, nil
+			},
+			expectedCalls:  1,
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: sharedVPCPermissionDeniedReason,
+			expectedError: "credentials of project service-project lack compute.subnetworks.useExternalIp on subnetwork subnet of shared VPC host project host-project, " +
+				"grant them roles/compute.networkUser on the subnetwork",
+		},
+		{
+			name:              "Subnetwork missing in the host project",
+			networkInterfaces: sharedVPC,
+			mockPermissions: func(_ string, _ string, _ string, _ []string) ([]string, error) {
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			},
+			expectedCalls:  1,
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: sharedVPCSubnetworkNotFoundReason,
+			expectedError:  "subnetwork subnet does not exist in region us-east1 of shared VPC host project host-project",
+		},
+		{
+			name:              "Check is skipped without permission",
+			networkInterfaces: sharedVPC,
+			mockPermissions: func(_ string, _ string, _ string, _ []string) ([]string, error) {
+				return nil, &googleapi.Error{Code: http.StatusForbidden}
+			},
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			calls := 0
+			mockComputeService.MockSubnetworkPermissions = func(project string, region string, subnetwork string, permissions []string) ([]string, error) {
+				calls++
+				if project != "host-project" || region != "us-east1" || subnetwork != "subnet" {
+					t.Errorf("Unexpected subnetwork %s/%s/%s", project, region, subnetwork)
+				}
+				if tc.mockPermissions != nil {
+					return tc.mockPermissions(project, region, subnetwork, permissions)
+				}
+				return permissions, nil
+			}
+
+			r := newReconciler(&machineScope{
+				Context:        context.Background(),
+				machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "us-east1", NetworkInterfaces: tc.networkInterfaces},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+				projectID:      "service-project",
+			})
+
+			err := r.runPreflightChecks(&compute.Instance{})
+			if calls != tc.expectedCalls {
+				t.Errorf("Expected %d permission tests, got %d", tc.expectedCalls, calls)
+			}
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			} else {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Fatalf("Expected error %q, got %v", tc.expectedError, err)
+				}
+				if !isInvalidMachineConfigurationError(err) {
+					t.Errorf("Expected an invalid machine configuration error, got %v", err)
+				}
+				created := findCondition(r.providerStatus.Conditions, string(machinev1.MachineCreated))
+				if created == nil || created.Status != metav1.ConditionFalse || created.Reason != tc.expectedReason {
+					t.Errorf("Expected the MachineCreated condition False/%s, got %v", tc.expectedReason, created)
+				}
+			}
+
+			condition := findCondition(r.providerStatus.Conditions, sharedVPCConditionType)
+			if tc.expectedReason == "" {
+				if condition != nil {
+					t.Errorf("Expected no %s condition, got %v", sharedVPCConditionType, condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tc.expectedStatus || condition.Reason != tc.expectedReason {
+				t.Errorf("Expected the %s condition %s/%s, got %v", sharedVPCConditionType, tc.expectedStatus, tc.expectedReason, condition)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package machine
+
+import (
+	"fmt"
+	"path"
+
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const integrityPolicyRelearnedEventReason = "IntegrityPolicyRelearned"
+
+// reconcileShieldedIntegrityPolicy updates the integrity policy baseline of a running shielded
+// instance after its boot disk was recreated from a different image, when the machine opted in
+// with the shieldedIntegrityAutoRelearnAnnotation. The first image seen is only recorded, as the
+// baseline is learned from the first boot of the instance.
+func (r *Reconciler) reconcileShieldedIntegrityPolicy(instance *compute.Instance) error {
+	enabled, err := r.getBoolAnnotation(shieldedIntegrityAutoRelearnAnnotation)
+	if err != nil || !enabled {
+		return err
+	}
+	if instance.ShieldedInstanceConfig == nil || !instance.ShieldedInstanceConfig.EnableIntegrityMonitoring {
+		return nil
+	}
+
+	var bootDisk *compute.AttachedDisk
+	for _, disk := range instance.Disks {
+		if disk.Boot {
+			bootDisk = disk
+			break
+		}
+	}
+	if bootDisk == nil || bootDisk.Source == "" {
+		return nil
+	}
+
+	disk, err := r.computeService.DisksGet(r.projectID, r.providerSpec.Zone, path.Base(bootDisk.Source))
+	if err != nil {
+		return fmt.Errorf("failed to get boot disk of instance %s: %w", instance.Name, err)
+	}
+	image := disk.SourceImageId
+	if image == "" {
+		image = disk.SourceImage
+	}
+	if image == "" {
+		// The boot disk was not created from an image, e.g. from a snapshot.
+		return nil
+	}
+
+	baseline, ok := r.getAnnotation(shieldedIntegrityBaselineImageAnnotation)
+	if ok && baseline != "" && baseline != image {
+		r.log.Info("Boot image changed, relearning the integrity policy baseline", "from", baseline, "to", image)
+		policy := &compute.ShieldedInstanceIntegrityPolicy{UpdateAutoLearnPolicy: true}
+		if _, err := r.computeService.InstancesSetShieldedInstanceIntegrityPolicy(r.projectID, r.providerSpec.Zone, instance.Name, policy); err != nil {
+			return fmt.Errorf("failed to relearn the integrity policy of instance %s: %w", instance.Name, err)
+		}
+		r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, integrityPolicyRelearnedEventReason,
+			"Relearned the integrity policy baseline after the boot image changed from %s to %s", baseline, image)
+	}
+
+	if r.machine.Annotations == nil {
+		r.machine.Annotations = map[string]string{}
+	}
+	r.machine.Annotations[shieldedIntegrityBaselineImageAnnotation] = image
+	return nil
+}
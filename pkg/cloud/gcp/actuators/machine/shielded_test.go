@@ -0,0 +1,97 @@
+package machine
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReconcileShieldedIntegrityPolicy(t *testing.T) {
+	cases := []struct {
+		name                string
+		annotations         map[string]string
+		integrityMonitoring bool
+		expectRelearn       bool
+		expectedBaseline    string
+	}{
+		{
+			name:                "Auto relearn not enabled",
+			integrityMonitoring: true,
+		},
+		{
+			name:                "Integrity monitoring disabled",
+			annotations:         map[string]string{shieldedIntegrityAutoRelearnAnnotation: "true"},
+			integrityMonitoring: false,
+		},
+		{
+			name:                "First image is recorded without relearning",
+			annotations:         map[string]string{shieldedIntegrityAutoRelearnAnnotation: "true"},
+			integrityMonitoring: true,
+			expectedBaseline:    "1234567890",
+		},
+		{
+			name: "Unchanged image",
+			annotations: map[string]string{
+				shieldedIntegrityAutoRelearnAnnotation:   "true",
+				shieldedIntegrityBaselineImageAnnotation: "1234567890",
+			},
+			integrityMonitoring: true,
+			expectedBaseline:    "1234567890",
+		},
+		{
+			name: "Changed image is relearned",
+			annotations: map[string]string{
+				shieldedIntegrityAutoRelearnAnnotation:   "true",
+				shieldedIntegrityBaselineImageAnnotation: "111",
+			},
+			integrityMonitoring: true,
+			expectRelearn:       true,
+			expectedBaseline:    "1234567890",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			var relearned bool
+			mockComputeService.MockSetIntegrityPolicy = func(_ string, _ string, _ string, policy *compute.ShieldedInstanceIntegrityPolicy) (*compute.Operation, error) {
+				relearned = policy.UpdateAutoLearnPolicy
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			recorder := record.NewFakeRecorder(1)
+
+			r := newReconciler(&machineScope{
+				machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-a", Annotations: tc.annotations}},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Zone: "us-east1-b"},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+				eventRecorder:  recorder,
+			})
+
+			instance := &compute.Instance{
+				Name:                   "worker-a",
+				ShieldedInstanceConfig: &compute.ShieldedInstanceConfig{EnableIntegrityMonitoring: tc.integrityMonitoring},
+				Disks: []*compute.AttachedDisk{
+					{Boot: true, Source: "https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b/disks/worker-a"},
+				},
+			}
+			if err := r.reconcileShieldedIntegrityPolicy(instance); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if relearned != tc.expectRelearn {
+				t.Errorf("Expected relearn: %v, got %v", tc.expectRelearn, relearned)
+			}
+			if tc.expectRelearn && len(recorder.Events) != 1 {
+				t.Errorf("Expected an %s event", integrityPolicyRelearnedEventReason)
+			}
+			if baseline := r.machine.Annotations[shieldedIntegrityBaselineImageAnnotation]; baseline != tc.expectedBaseline {
+				t.Errorf("Expected baseline image %q, got %q", tc.expectedBaseline, baseline)
+			}
+		})
+	}
+}
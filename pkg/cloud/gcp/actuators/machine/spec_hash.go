@@ -0,0 +1,41 @@
+package machine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/openshift/machine-api-provider-gcp/pkg/version"
+	"google.golang.org/api/compute/v1"
+)
+
+// specHashPrefix identifies the hash algorithm of the specHashAnnotation values.
+const specHashPrefix = "sha256:"
+
+// instanceSpecHash returns the hash of the instance insert request rendered from the machine. The
+// JSON encoding of the request is stable, maps are encoded with sorted keys, so the same machine
+// rendered by the same provider version always has the same hash, and a different hash for an
+// unchanged machine means the provider renders it differently.
+func instanceSpecHash(instance *compute.Instance) (string, error) {
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return specHashPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// recordInstanceSpecHash sets the specHashAnnotation to the hash of the insert request of the
+// instance. The provider status type is defined by the machine API and cannot hold it.
+func (r *Reconciler) recordInstanceSpecHash(instance *compute.Instance) {
+	hash, err := instanceSpecHash(instance)
+	if err != nil {
+		r.log.Error(err, "Failed to hash the instance request")
+		return
+	}
+	r.log.Info("Rendered instance request", "hash", hash, "providerVersion", version.Version)
+	if r.machine.Annotations == nil {
+		r.machine.Annotations = map[string]string{}
+	}
+	r.machine.Annotations[specHashAnnotation] = hash
+}
@@ -0,0 +1,46 @@
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInstanceSpecHash(t *testing.T) {
+	render := func(machineType string) *compute.Instance {
+		labels := map[string]string{}
+		for _, key := range []string{"a", "b", "c", "d", "e"} {
+			labels[key] = "value"
+		}
+		return &compute.Instance{Name: "worker-a", MachineType: machineType, Labels: labels}
+	}
+
+	hash, err := instanceSpecHash(render("n2-standard-4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, specHashPrefix) || len(hash) != len(specHashPrefix)+64 {
+		t.Errorf("Expected a sha256 hash, got %q", hash)
+	}
+	for i := 0; i < 10; i++ {
+		if again, _ := instanceSpecHash(render("n2-standard-4")); again != hash {
+			t.Fatalf("Expected the same request to have the same hash, got %q and %q", hash, again)
+		}
+	}
+	if changed, _ := instanceSpecHash(render("n2-standard-8")); changed == hash {
+		t.Error("Expected a different request to have a different hash")
+	}
+
+	r := newReconciler(&machineScope{
+		machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-a"}},
+		providerSpec:   &machinev1.GCPMachineProviderSpec{},
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+	})
+	r.recordInstanceSpecHash(render("n2-standard-4"))
+	if r.machine.Annotations[specHashAnnotation] != hash {
+		t.Errorf("Expected annotation %s to be %q, got %q", specHashAnnotation, hash, r.machine.Annotations[specHashAnnotation])
+	}
+}
@@ -0,0 +1,227 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	pricingservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/pricing"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+)
+
+// SpotZonePolicy is how the reconciler picks the zone of the instances of preemptible (spot) machines
+// that have fallback zones.
+type SpotZonePolicy string
+
+const (
+	// SpotZonePolicyRequested creates the instances in the zone of the providerSpec.
+	SpotZonePolicyRequested SpotZonePolicy = "requested"
+	// SpotZonePolicyCheapest creates the instances in the zone with the lowest spot price of the
+	// machine type among the zone of the providerSpec and its fallback zones, according to the Cloud
+	// Billing catalog.
+	SpotZonePolicyCheapest SpotZonePolicy = "cheapest"
+	// SpotZonePolicyLeastPreempted creates the instances in the zone where the fewest spot instances
+	// of the project were preempted recently among the zone of the providerSpec and its fallback zones.
+	SpotZonePolicyLeastPreempted SpotZonePolicy = "least-preempted"
+
+	// spotZoneSelectionConditionType records the figures the zone of the instance was selected by.
+	spotZoneSelectionConditionType   = "SpotZoneSelection"
+	cheapestZoneSelectedReason       = "CheapestZoneSelected"
+	spotPricingUnavailableReason     = "SpotPricingUnavailable"
+	leastPreemptedZoneSelectedReason = "LeastPreemptedZoneSelected"
+	preemptionStatsUnavailableReason = "PreemptionStatsUnavailable"
+
+	// skuCacheTTL is how long the SKUs of the catalog are reused, the catalog lists thousands of
+	// SKUs over several pages and its prices rarely change.
+	skuCacheTTL = 24 * time.Hour
+)
+
+// ParseSpotZonePolicy returns the policy of the given name.
+func ParseSpotZonePolicy(name string) (SpotZonePolicy, error) {
+	switch policy := SpotZonePolicy(name); policy {
+	case SpotZonePolicyRequested, SpotZonePolicyCheapest, SpotZonePolicyLeastPreempted:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown spot zone policy %q, expected %s, %s or %s", name, SpotZonePolicyRequested, SpotZonePolicyCheapest, SpotZonePolicyLeastPreempted)
+}
+
+// skuCache holds the Compute Engine SKUs of the Cloud Billing catalog for all machines of the actuator.
+type skuCache struct {
+	clock clock.Clock
+
+	mu        sync.Mutex
+	skus      []pricingservice.SKU
+	fetchedAt time.Time
+}
+
+func newSKUCache(c clock.Clock) *skuCache {
+	if c == nil {
+		c = clock.RealClock{}
+	}
+	return &skuCache{clock: c}
+}
+
+// get returns the cached SKUs, listing them with the service when they are older than skuCacheTTL.
+// Failures are not cached.
+func (c *skuCache) get(ctx context.Context, service pricingservice.PricingService) ([]pricingservice.SKU, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.skus != nil && c.clock.Since(c.fetchedAt) < skuCacheTTL {
+		return c.skus, nil
+	}
+	skus, err := service.ComputeSKUs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.skus, c.fetchedAt = skus, c.clock.Now()
+	return skus, nil
+}
+
+// selectSpotZone moves a preemptible machine about to be created to the best of its requested and
+// fallback zones according to the spot zone policy, and requeues it so that the instance is built and
+// validated for the new zone from scratch. The figures of the zones are recorded in the
+// SpotZoneSelection condition, which also makes the selection happen once per machine: zones
+// exhausted later on are left to the zone fallback. The requested zone is kept when the figures can
+// not be determined.
+func (r *Reconciler) selectSpotZone() error {
+	if r.options.SpotZonePolicy == SpotZonePolicyRequested || r.options.SpotZonePolicy == "" || !r.providerSpec.Preemptible ||
+		findCondition(r.providerStatus.Conditions, spotZoneSelectionConditionType) != nil {
+		return nil
+	}
+	if name, ok := r.getAnnotation(createOperationAnnotation); ok && name != "" {
+		return nil
+	}
+	zones, err := r.fallbackZones()
+	if err != nil || len(zones) < 2 {
+		return err
+	}
+
+	var scores map[string]float64
+	var criterion, scoreFormat, reason, unavailableReason string
+	switch r.options.SpotZonePolicy {
+	case SpotZonePolicyCheapest:
+		scores, err = r.spotZonePrices(zones)
+		criterion = "the hourly spot price of " + r.providerSpec.MachineType
+		scoreFormat, reason, unavailableReason = "%s $%.4f", cheapestZoneSelectedReason, spotPricingUnavailableReason
+	case SpotZonePolicyLeastPreempted:
+		scores, err = r.zonePreemptions(zones)
+		criterion = fmt.Sprintf("the spot preemptions of the last %d days", int(preemptionStatsWindow.Hours()/24))
+		scoreFormat, reason, unavailableReason = "%s %.0f", leastPreemptedZoneSelectedReason, preemptionStatsUnavailableReason
+	default:
+		return nil
+	}
+	if err != nil {
+		r.log.Error(err, "Failed to rank the zones of the spot instance, keeping its zone", "criterion", criterion)
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+			Type:    spotZoneSelectionConditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  unavailableReason,
+			Message: fmt.Sprintf("kept zone %s: %v", r.providerSpec.Zone, err),
+		})
+		return nil
+	}
+
+	// Ties keep the order of the zones, starting with the requested zone.
+	best := zones[0]
+	for _, zone := range zones[1:] {
+		if scores[zone] < scores[best] {
+			best = zone
+		}
+	}
+	figures := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		figures = append(figures, fmt.Sprintf(scoreFormat, zone, scores[zone]))
+	}
+	message := fmt.Sprintf("selected zone %s by %s: %s", best, criterion, strings.Join(figures, ", "))
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    spotZoneSelectionConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	if best == r.providerSpec.Zone {
+		return nil
+	}
+
+	r.log.Info("Selected zone of the spot instance", "selectedZone", best, "criterion", criterion, "scores", figures)
+	r.setZone(best)
+	r.eventRecorder.Eventf(r.machine, corev1.EventTypeNormal, reason, "%s", message)
+	return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+}
+
+// spotZonePrices returns the hourly spot price in USD of the machine type in each of the zones.
+// The catalog prices compute resources per region, so zones of the same region cost the same.
+func (r *Reconciler) spotZonePrices(zones []string) (map[string]float64, error) {
+	if r.pricingService == nil || r.skuCache == nil {
+		return nil, fmt.Errorf("the Cloud Billing catalog is not available")
+	}
+
+	family, cpus, memoryMb, custom, err := r.machineTypeResources()
+	if err != nil {
+		return nil, err
+	}
+	skus, err := r.skuCache.get(r.Context, r.pricingService)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := map[string]float64{}
+	for _, zone := range zones {
+		region := zone[:strings.LastIndex(zone, "-")]
+		corePrice, ok := spotUnitPrice(skus, family, "Instance Core", region, custom)
+		if !ok {
+			return nil, fmt.Errorf("no spot price of %s cores in region %s", family, region)
+		}
+		ramPrice, ok := spotUnitPrice(skus, family, "Instance Ram", region, custom)
+		if !ok {
+			return nil, fmt.Errorf("no spot price of %s memory in region %s", family, region)
+		}
+		prices[zone] = float64(cpus)*corePrice + float64(memoryMb)/1024*ramPrice
+	}
+	return prices, nil
+}
+
+// machineTypeResources returns the family, vCPUs and memory of the machine type of the machine.
+func (r *Reconciler) machineTypeResources() (family string, cpus int64, memoryMb int64, custom bool, err error) {
+	customType, err := util.ParseCustomMachineType(r.providerSpec.MachineType)
+	if err != nil {
+		return "", 0, 0, false, err
+	}
+	if customType != nil {
+		return customType.Family, customType.CPUs, customType.MemoryMb, true, nil
+	}
+	machineType, err := r.computeService.MachineTypesGet(r.projectID, r.providerSpec.Zone, r.providerSpec.MachineType)
+	if err != nil {
+		return "", 0, 0, false, fmt.Errorf("failed to get machine type %s: %w", r.providerSpec.MachineType, err)
+	}
+	family, _, _ = strings.Cut(r.providerSpec.MachineType, "-")
+	return family, machineType.GuestCpus, machineType.MemoryMb, false, nil
+}
+
+// spotUnitPrice returns the price of a vCPU hour ("Instance Core") or GiB hour ("Instance Ram") of the
+// spot instances of the machine family in the region, e.g. of the SKU "Spot Preemptible N2 Instance Core
+// running in Americas". Custom machine types have their own SKUs, sole tenant nodes are not priced.
+func spotUnitPrice(skus []pricingservice.SKU, family, resource, region string, custom bool) (float64, bool) {
+	familyWord := " " + strings.ToUpper(family) + " "
+	for _, sku := range skus {
+		if sku.Category.ResourceFamily != "Compute" || sku.Category.UsageType != "Preemptible" ||
+			!strings.Contains(sku.Description, familyWord) || !strings.Contains(sku.Description, resource) ||
+			strings.Contains(sku.Description, "Custom") != custom ||
+			strings.Contains(sku.Description, "Sole Tenancy") || strings.Contains(sku.Description, "Extended") ||
+			!containsString(sku.ServiceRegions, region) || len(sku.PricingInfo) == 0 {
+			continue
+		}
+		rates := sku.PricingInfo[0].PricingExpression.TieredRates
+		if len(rates) == 0 {
+			continue
+		}
+		return rates[len(rates)-1].UnitPrice.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,200 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	pricingservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/pricing"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func spotSKU(description, region string, nanos int64) pricingservice.SKU {
+	return pricingservice.SKU{
+		Description:    description,
+		Category:       pricingservice.Category{ResourceFamily: "Compute", UsageType: "Preemptible"},
+		ServiceRegions: []string{region},
+		PricingInfo: []pricingservice.PricingInfo{{PricingExpression: pricingservice.PricingExpression{
+			TieredRates: []pricingservice.TieredRate{{UnitPrice: pricingservice.Money{Units: "0", Nanos: nanos}}},
+		}}},
+	}
+}
+
+func TestSelectSpotZone(t *testing.T) {
+	skus := []pricingservice.SKU{
+		spotSKU("Spot Preemptible N2 Instance Core running in Americas", "us-east1", 8000000),
+		spotSKU("Spot Preemptible N2 Instance Ram running in Americas", "us-east1", 1000000),
+		spotSKU("Spot Preemptible N2 Custom Instance Core running in Americas", "us-east1", 9000000),
+		spotSKU("Spot Preemptible N2 Custom Instance Ram running in Americas", "us-east1", 2000000),
+		spotSKU("Spot Preemptible N2D AMD Instance Core running in Americas", "us-east1", 1000000),
+		spotSKU("Spot Preemptible N2 Instance Core running in EMEA", "europe-west1", 1000000),
+	}
+
+	cases := []struct {
+		name              string
+		policy            SpotZonePolicy
+		preemptible       bool
+		machineType       string
+		annotations       map[string]string
+		existing          []metav1.Condition
+		skusErr           error
+		expectedCondition metav1.ConditionStatus
+		expectedMessage   string
+	}{
+		{
+			name:        "Requested zone policy",
+			policy:      SpotZonePolicyRequested,
+			preemptible: true,
+			annotations: map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+		},
+		{
+			name:        "Machine is not preemptible",
+			policy:      SpotZonePolicyCheapest,
+			annotations: map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+		},
+		{
+			name:        "Machine without fallback zones",
+			policy:      SpotZonePolicyCheapest,
+			preemptible: true,
+		},
+		{
+			name:        "Zone already selected",
+			policy:      SpotZonePolicyCheapest,
+			preemptible: true,
+			annotations: map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+			existing: []metav1.Condition{{
+				Type: spotZoneSelectionConditionType, Status: metav1.ConditionTrue, Reason: cheapestZoneSelectedReason, Message: "selected before",
+			}},
+			expectedCondition: metav1.ConditionTrue,
+			expectedMessage:   "selected before",
+		},
+		{
+			name:              "Zones of a region cost the same and keep the requested zone",
+			policy:            SpotZonePolicyCheapest,
+			preemptible:       true,
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+			expectedCondition: metav1.ConditionTrue,
+			// 2 cores at $0.008 and 8 GiB at $0.001.
+			expectedMessage: "selected zone us-east1-b by the hourly spot price of n2-standard-2: us-east1-b $0.0240, us-east1-c $0.0240",
+		},
+		{
+			name:              "Custom machine types are priced by the custom SKUs",
+			policy:            SpotZonePolicyCheapest,
+			preemptible:       true,
+			machineType:       "n2-custom-4-4096",
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+			expectedCondition: metav1.ConditionTrue,
+			// 4 cores at $0.009 and 4 GiB at $0.002.
+			expectedMessage: "selected zone us-east1-b by the hourly spot price of n2-custom-4-4096: us-east1-b $0.0440, us-east1-c $0.0440",
+		},
+		{
+			name:              "Machine family without spot prices",
+			policy:            SpotZonePolicyCheapest,
+			preemptible:       true,
+			machineType:       "c3-standard-4",
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+			expectedCondition: metav1.ConditionFalse,
+			expectedMessage:   "kept zone us-east1-b: no spot price of c3 cores in region us-east1",
+		},
+		{
+			name:              "Catalog not available",
+			policy:            SpotZonePolicyCheapest,
+			preemptible:       true,
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+			skusErr:           errors.New("permission denied"),
+			expectedCondition: metav1.ConditionFalse,
+			expectedMessage:   "kept zone us-east1-b: permission denied",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockMachineTypesGet = func(project string, zone string, machineType string) (*compute.MachineType, error) {
+				return &compute.MachineType{Name: machineType, GuestCpus: 2, MemoryMb: 8192}, nil
+			}
+			mockPricingService := pricingservice.NewMockPricingService()
+			mockPricingService.MockComputeSKUs = func(ctx context.Context) ([]pricingservice.SKU, error) {
+				return skus, tc.skusErr
+			}
+			machineType := tc.machineType
+			if machineType == "" {
+				machineType = "n2-standard-2"
+			}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{Name: "testInstance", Annotations: tc.annotations},
+				},
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:        "us-east1-b",
+					Region:      "us-east1",
+					MachineType: machineType,
+					Preemptible: tc.preemptible,
+				},
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.existing},
+				computeService: mockComputeService,
+				options:        Options{SpotZonePolicy: tc.policy},
+				pricingService: mockPricingService,
+				skuCache:       newSKUCache(nil),
+			})
+
+			if err := r.selectSpotZone(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if r.providerSpec.Zone != "us-east1-b" {
+				t.Errorf("Expected zone us-east1-b to be kept, got %s", r.providerSpec.Zone)
+			}
+			condition := findCondition(r.providerStatus.Conditions, spotZoneSelectionConditionType)
+			if tc.expectedCondition == "" {
+				if condition != nil {
+					t.Errorf("Expected no %s condition, got %v", spotZoneSelectionConditionType, condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tc.expectedCondition || condition.Message != tc.expectedMessage {
+				t.Errorf("Expected a %s condition with message %q, got %v", tc.expectedCondition, tc.expectedMessage, condition)
+			}
+		})
+	}
+}
+
+func TestSKUCache(t *testing.T) {
+	lookups := 0
+	mockPricingService := pricingservice.NewMockPricingService()
+	mockPricingService.MockComputeSKUs = func(ctx context.Context) ([]pricingservice.SKU, error) {
+		lookups++
+		if lookups == 1 {
+			return nil, errors.New("backend error")
+		}
+		return []pricingservice.SKU{{Description: "Spot Preemptible N2 Instance Core running in Americas"}}, nil
+	}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	cache := newSKUCache(fakeClock)
+
+	if _, err := cache.get(context.Background(), mockPricingService); err == nil {
+		t.Fatal("Expected the error of the catalog")
+	}
+	for i := 0; i < 3; i++ {
+		skus, err := cache.get(context.Background(), mockPricingService)
+		if err != nil || len(skus) != 1 || !strings.Contains(skus[0].Description, "N2") {
+			t.Fatalf("Expected the SKUs of the catalog, got %v, %v", skus, err)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("Expected the failure not to be cached and the SKUs to be, got %d lookups", lookups)
+	}
+
+	fakeClock.Step(skuCacheTTL)
+	if _, err := cache.get(context.Background(), mockPricingService); err != nil {
+		t.Fatal(err)
+	}
+	if lookups != 3 {
+		t.Errorf("Expected the SKUs to be listed again after %s, got %d lookups", skuCacheTTL, lookups)
+	}
+}
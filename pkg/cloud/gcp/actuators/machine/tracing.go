@@ -0,0 +1,54 @@
+package machine
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/compute/v1"
+)
+
+// startReconcileSpan starts the span of an action of the actuator on the machine, e.g. Create. The
+// compute API calls made by the action and the operations it sees complete are its children.
+func startReconcileSpan(ctx context.Context, action string, machine *machinev1.Machine) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracing.Tracer().Start(ctx, "machine."+action, trace.WithAttributes(
+		attribute.String("machine.namespace", machine.Namespace),
+		attribute.String("machine.name", machine.Name),
+	))
+}
+
+// traceOperation records a completed operation as a span from its insertion to its end, so that the
+// time GCP took to complete it, e.g. to boot an inserted instance, shows in the trace of the
+// reconcile that saw it complete.
+func (r *Reconciler) traceOperation(tracked trackedOperation, operation *compute.Operation, errs []string) {
+	inserted, err := time.Parse(time.RFC3339, operation.InsertTime)
+	if err != nil {
+		return
+	}
+	ended, err := time.Parse(time.RFC3339, operation.EndTime)
+	if err != nil {
+		return
+	}
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := tracing.Tracer().Start(ctx, "compute.operation."+tracked.Action, trace.WithTimestamp(inserted), trace.WithAttributes(
+		attribute.String("gcp.compute.operation.name", operation.Name),
+		attribute.Int64("gcp.compute.operation.id", int64(operation.Id)),
+		attribute.String("gcp.compute.operation.type", operation.OperationType),
+		attribute.String("gcp.compute.operation.target", operation.TargetLink),
+	))
+	if len(errs) > 0 {
+		span.SetStatus(codes.Error, strings.Join(errs, "; "))
+	}
+	span.End(trace.WithTimestamp(ended))
+}
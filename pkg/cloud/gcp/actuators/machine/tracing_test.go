@@ -0,0 +1,54 @@
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTraceOperation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}}
+	ctx, parent := startReconcileSpan(context.Background(), createEventAction, machine)
+	r := newReconciler(&machineScope{Context: ctx, machine: machine})
+
+	inserted := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	r.traceOperation(trackedOperation{Name: "operation-1", Action: insertOperationAction}, &compute.Operation{
+		Name:       "operation-1",
+		InsertTime: inserted.Format(time.RFC3339),
+		EndTime:    inserted.Add(40 * time.Second).Format(time.RFC3339),
+	}, []string{"ZONE_RESOURCE_POOL_EXHAUSTED: out of resources"})
+	// Operations without timestamps are not traced.
+	r.traceOperation(trackedOperation{Name: "operation-2", Action: insertOperationAction}, &compute.Operation{Name: "operation-2"}, nil)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected the operation and reconcile spans, got %d spans", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "compute.operation.insert" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected an insert operation span in the reconcile, got %s with parent %s", span.Name(), span.Parent().SpanID())
+	}
+	if duration := span.EndTime().Sub(span.StartTime()); duration != 40*time.Second {
+		t.Errorf("Expected the span to last as long as the operation, got %s", duration)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("Expected the failed operation to have an error status, got %v", span.Status())
+	}
+	if spans[1].Name() != "machine.Create" {
+		t.Errorf("Expected the reconcile span, got %s", spans[1].Name())
+	}
+}
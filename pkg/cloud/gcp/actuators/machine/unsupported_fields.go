@@ -0,0 +1,39 @@
+package machine
+
+import (
+	"strings"
+
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reconcileUnsupportedFieldsCondition sets a warning condition listing the providerSpec fields this
+// version of the provider does not know and ignores, so that the intent of a newer API version is
+// not silently dropped on a version-skewed cluster. Machines that never set such fields do not get
+// the condition.
+func (r *Reconciler) reconcileUnsupportedFieldsCondition() {
+	if r.machine.Spec.ProviderSpec.Value == nil {
+		return
+	}
+	unknown, err := util.UnknownProviderSpecFields(r.machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		r.log.Error(err, "Failed to detect unsupported providerSpec fields")
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:    unsupportedFieldsConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  noUnsupportedFieldsReason,
+		Message: "providerSpec only sets supported fields",
+	}
+	if len(unknown) > 0 {
+		r.log.Info("ProviderSpec sets fields this version of the provider does not support, they are ignored", "fields", unknown)
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = unsupportedFieldsSetReason
+		condition.Message = "providerSpec sets fields this version of the provider does not support and ignores: " + strings.Join(unknown, ", ")
+	} else if findCondition(r.providerStatus.Conditions, unsupportedFieldsConditionType) == nil {
+		return
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, condition)
+}
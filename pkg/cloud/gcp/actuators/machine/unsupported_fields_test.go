@@ -0,0 +1,70 @@
+package machine
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestReconcileUnsupportedFieldsCondition(t *testing.T) {
+	cases := []struct {
+		name               string
+		raw                string
+		existingConditions []metav1.Condition
+		expectedStatus     metav1.ConditionStatus
+		expectedMessage    string
+	}{
+		{
+			name: "No condition without unsupported fields",
+			raw:  `{"zone":"us-east1-b"}`,
+		},
+		{
+			name:            "Unsupported fields set",
+			raw:             `{"zone":"us-east1-b","hostMaintenancePolicy":"Migrate","disks":[{"sizeGb":64,"provisionedIops":3000}]}`,
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "providerSpec sets fields this version of the provider does not support and ignores: disks[0].provisionedIops, hostMaintenancePolicy",
+		},
+		{
+			name: "Condition is cleared once the fields are removed",
+			raw:  `{"zone":"us-east1-b"}`,
+			existingConditions: []metav1.Condition{
+				{Type: unsupportedFieldsConditionType, Status: metav1.ConditionTrue, Reason: unsupportedFieldsSetReason},
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedMessage: "providerSpec only sets supported fields",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+					Spec: machinev1.MachineSpec{
+						ProviderSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{Raw: []byte(tc.raw)}},
+					},
+				},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{},
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.existingConditions},
+			})
+
+			r.reconcileUnsupportedFieldsCondition()
+
+			condition := findCondition(r.providerStatus.Conditions, unsupportedFieldsConditionType)
+			if tc.expectedStatus == "" {
+				if condition != nil {
+					t.Errorf("Expected no condition, got %v", condition)
+				}
+				return
+			}
+			if condition == nil {
+				t.Fatal("Expected condition, got none")
+			}
+			if condition.Status != tc.expectedStatus || condition.Message != tc.expectedMessage {
+				t.Errorf("Expected %s %q, got %s %q", tc.expectedStatus, tc.expectedMessage, condition.Status, condition.Message)
+			}
+		})
+	}
+}
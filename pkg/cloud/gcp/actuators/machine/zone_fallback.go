@@ -0,0 +1,156 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/providerid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// zoneFallbackConditionType reports that the instance is created in another zone than the one
+	// the machine requested because that zone ran out of resources.
+	zoneFallbackConditionType    = "ZoneFallback"
+	zoneResourcesExhaustedReason = string(gcperrors.ZoneResourcesExhausted)
+	// fallbackZonesExhaustedReason reports that every zone ran out of resources once, the machine
+	// is then retried in its requested zone only.
+	fallbackZonesExhaustedReason = "FallbackZonesExhausted"
+)
+
+// instanceZone returns the zone of the instance of a machine: the zone a zone fallback or a spot
+// zone selection moved it to, otherwise the zone of its providerSpec.
+func instanceZone(machine *machinev1.Machine, providerSpec *machinev1.GCPMachineProviderSpec) string {
+	if zone := machine.Annotations[zoneAnnotation]; zone != "" {
+		return zone
+	}
+	return providerSpec.Zone
+}
+
+// requestedZone returns the zone the machine requested, which its providerSpec keeps when its
+// instance is moved to another zone.
+func (s *machineScope) requestedZone() string {
+	if zone := s.machine.Annotations[requestedZoneAnnotation]; zone != "" {
+		return zone
+	}
+	if s.specZone != "" {
+		return s.specZone
+	}
+	return s.providerSpec.Zone
+}
+
+// setZone moves the instance of the machine to another zone, e.g. on a zone fallback, and logs the
+// following messages with that zone. The providerSpec is persisted with the requested zone, which
+// is immutable once the machine has a provider ID, and the zone of the instance is recorded in the
+// zoneAnnotation annotation instead. A provider ID is moved to the zone in the same patch, so that
+// the instance is not looked up in the previous zone.
+func (s *machineScope) setZone(zone string) {
+	if s.specZone == "" {
+		s.specZone = s.providerSpec.Zone
+	}
+	s.providerSpec.Zone = zone
+	if zone == s.specZone {
+		delete(s.machine.Annotations, zoneAnnotation)
+	} else {
+		if s.machine.Annotations == nil {
+			s.machine.Annotations = map[string]string{}
+		}
+		s.machine.Annotations[zoneAnnotation] = zone
+	}
+	s.providerID = providerid.New(s.projectID, zone, s.instanceName).String()
+	if s.machine.Spec.ProviderID != nil {
+		providerID := s.providerID
+		s.machine.Spec.ProviderID = &providerID
+	}
+	s.log = newScopeLogger(s.Context, s.machine, s.projectID, zone)
+}
+
+// fallbackZones returns the zones to try in turn once the zone requested by the machine is out of
+// resources, starting with the requested zone. It returns nil when no fallback zones are set.
+func (r *Reconciler) fallbackZones() ([]string, error) {
+	fallbacks := r.getListAnnotation(fallbackZonesAnnotation)
+	if len(fallbacks) == 0 {
+		return nil, nil
+	}
+
+	zones := []string{r.requestedZone()}
+	for _, zone := range fallbacks {
+		if !strings.HasPrefix(zone, r.providerSpec.Region+"-") {
+			return nil, machinecontroller.InvalidMachineConfiguration("invalid %s annotation: zone %q is not in region %q", fallbackZonesAnnotation, zone, r.providerSpec.Region)
+		}
+		if !containsString(zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
+// reconcilePreviousCreateOperation moves the machine to its next fallback zone when the instance
+// inserted by the previous attempt failed to be created because the zone ran out of resources.
+func (r *Reconciler) reconcilePreviousCreateOperation() error {
+	name, ok := r.getAnnotation(createOperationAnnotation)
+	if !ok || name == "" || len(r.getListAnnotation(fallbackZonesAnnotation)) == 0 {
+		return nil
+	}
+
+	operation, err := r.computeService.ZoneOperationsGet(r.projectID, r.providerSpec.Zone, name)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get create operation %s: %w", name, err)
+	}
+	if reason, message, ok := gcperrors.ClassifyOperation(operation); ok && reason == gcperrors.ZoneResourcesExhausted {
+		return r.fallBackToNextZone(message)
+	}
+	return nil
+}
+
+// fallBackToNextZone moves the machine to the zone following its current zone in the fallback zones
+// and requeues it, so that the instance is built and validated for the new zone from scratch, see
+// setZone. After the last fallback zone the machine moves back to its requested zone once, and is
+// then retried there like a machine without fallback zones. It returns nil when the machine has no
+// fallback zones or already went through all of them.
+func (r *Reconciler) fallBackToNextZone(cause string) error {
+	zones, err := r.fallbackZones()
+	if err != nil || len(zones) == 0 {
+		return err
+	}
+	if condition := findCondition(r.providerStatus.Conditions, zoneFallbackConditionType); condition != nil && condition.Reason == fallbackZonesExhaustedReason {
+		return nil
+	}
+
+	current := r.providerSpec.Zone
+	next := zones[0]
+	for i, zone := range zones {
+		if zone == current {
+			next = zones[(i+1)%len(zones)]
+			break
+		}
+	}
+
+	delete(r.machine.Annotations, createOperationAnnotation)
+	r.log.Info("Zone is out of resources, falling back to the next zone", "nextZone", next, "cause", cause)
+	r.setZone(next)
+
+	message := fmt.Sprintf("zone %s is out of resources, creating the instance in zone %s: %s", current, next, cause)
+	r.eventRecorder.Eventf(r.machine, corev1.EventTypeWarning, zoneResourcesExhaustedReason, "%s", message)
+	// The condition is False once the zones wrapped around to the requested zone.
+	status, reason := metav1.ConditionTrue, zoneResourcesExhaustedReason
+	if next == zones[0] {
+		status, reason = metav1.ConditionFalse, fallbackZonesExhaustedReason
+		message = fmt.Sprintf("all zones are out of resources, retrying in requested zone %s only: %s", next, cause)
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    zoneFallbackConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+	return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+}
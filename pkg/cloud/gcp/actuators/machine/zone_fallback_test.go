@@ -0,0 +1,266 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestZoneFallback(t *testing.T) {
+	stockOut := &googleapi.Error{Code: 503, Message: "The zone does not have enough resources available to fulfill the request. ZONE_RESOURCE_POOL_EXHAUSTED"}
+	failedOperation := &compute.Operation{
+		Status: "DONE",
+		Error: &compute.OperationError{Errors: []*compute.OperationErrorErrors{
+			{Code: "ZONE_RESOURCE_POOL_EXHAUSTED", Message: "The zone does not have enough resources available"},
+		}},
+	}
+
+	cases := []struct {
+		name                string
+		zone                string
+		annotations         map[string]string
+		conditions          []metav1.Condition
+		insertError         error
+		operation           *compute.Operation
+		expectedError       error
+		expectedZone        string
+		expectedCondition   metav1.ConditionStatus
+		expectedReason      string
+		expectedOperationID string
+	}{
+		{
+			name:          "Stock-out without fallback zones is retried in the same zone",
+			zone:          "us-east1-b",
+			insertError:   stockOut,
+			expectedError: &machinecontroller.RequeueAfterError{},
+			expectedZone:  "us-east1-b",
+		},
+		{
+			name:              "Stock-out falls back to the next zone",
+			zone:              "us-east1-b",
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c, us-east1-d"},
+			insertError:       stockOut,
+			expectedError:     &machinecontroller.RequeueAfterError{},
+			expectedZone:      "us-east1-c",
+			expectedCondition: metav1.ConditionTrue,
+			expectedReason:    zoneResourcesExhaustedReason,
+		},
+		{
+			name:              "Stock-out in the last fallback zone wraps around",
+			zone:              "us-east1-b",
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c,us-east1-d", zoneAnnotation: "us-east1-d"},
+			insertError:       stockOut,
+			expectedError:     &machinecontroller.RequeueAfterError{},
+			expectedZone:      "us-east1-b",
+			expectedCondition: metav1.ConditionFalse,
+			expectedReason:    fallbackZonesExhaustedReason,
+		},
+		{
+			name:              "Stock-out after the zones wrapped around is retried in the requested zone",
+			zone:              "us-east1-b",
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c,us-east1-d"},
+			conditions:        []metav1.Condition{{Type: zoneFallbackConditionType, Status: metav1.ConditionFalse, Reason: fallbackZonesExhaustedReason}},
+			insertError:       stockOut,
+			expectedError:     &machinecontroller.RequeueAfterError{},
+			expectedZone:      "us-east1-b",
+			expectedCondition: metav1.ConditionFalse,
+			expectedReason:    fallbackZonesExhaustedReason,
+		},
+		{
+			name:              "Stock-out of a machine moved by an earlier version wraps around to its requested zone",
+			zone:              "us-east1-d",
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c,us-east1-d", requestedZoneAnnotation: "us-east1-b"},
+			insertError:       stockOut,
+			expectedError:     &machinecontroller.RequeueAfterError{},
+			expectedZone:      "us-east1-b",
+			expectedCondition: metav1.ConditionFalse,
+			expectedReason:    fallbackZonesExhaustedReason,
+		},
+		{
+			name:              "Previous create operation ran out of resources",
+			zone:              "us-east1-b",
+			annotations:       map[string]string{fallbackZonesAnnotation: "us-east1-c", createOperationAnnotation: "operation-1"},
+			operation:         failedOperation,
+			expectedError:     &machinecontroller.RequeueAfterError{},
+			expectedZone:      "us-east1-c",
+			expectedCondition: metav1.ConditionTrue,
+			expectedReason:    zoneResourcesExhaustedReason,
+		},
+		{
+			name:                "Previous create operation succeeded",
+			zone:                "us-east1-b",
+			annotations:         map[string]string{fallbackZonesAnnotation: "us-east1-c", createOperationAnnotation: "operation-1"},
+			operation:           &compute.Operation{Status: "DONE"},
+			expectedZone:        "us-east1-b",
+			expectedOperationID: "operation-1",
+		},
+		{
+			name:          "Fallback zone in another region",
+			zone:          "us-east1-b",
+			annotations:   map[string]string{fallbackZonesAnnotation: "us-west1-a"},
+			insertError:   stockOut,
+			expectedError: machinecontroller.InvalidMachineConfiguration("invalid %s annotation: zone %q is not in region %q", fallbackZonesAnnotation, "us-west1-a", "us-east1"),
+			expectedZone:  "us-east1-b",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			if tc.insertError != nil {
+				mockComputeService.MockInstancesInsert = func(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
+					return nil, tc.insertError
+				}
+			}
+			if tc.operation != nil {
+				mockComputeService.MockZoneOperationsGet = func(project string, zone string, operation string) (*compute.Operation, error) {
+					return tc.operation, nil
+				}
+			}
+
+			infra := &configv1.Infrastructure{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Status: configv1.InfrastructureStatus{
+					InfrastructureName: "test-748kjf",
+					PlatformStatus:     &configv1.PlatformStatus{Type: configv1.GCPPlatformType, GCP: &configv1.GCPPlatformStatus{}},
+				},
+			}
+			machine := &machinev1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Labels:      map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+					Annotations: tc.annotations,
+				},
+			}
+			providerSpec := &machinev1.GCPMachineProviderSpec{Region: "us-east1", Zone: tc.zone}
+			r := newReconciler(&machineScope{
+				machine:        machine,
+				coreClient:     controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(infra).Build(),
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "us-east1", Zone: instanceZone(machine, providerSpec)},
+				providerStatus: &machinev1.GCPMachineProviderStatus{Conditions: tc.conditions},
+				specZone:       tc.zone,
+				computeService: mockComputeService,
+				projectID:      "project",
+				featureGates:   featuregates.NewFeatureGate(nil, []configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}),
+			})
+
+			err := r.create()
+			switch expected := tc.expectedError.(type) {
+			case nil:
+				var requeueErr *machinecontroller.RequeueAfterError
+				if errors.As(err, &requeueErr) {
+					t.Errorf("Unexpected requeue: %v", err)
+				}
+			case *machinecontroller.RequeueAfterError:
+				var requeueErr *machinecontroller.RequeueAfterError
+				if !errors.As(err, &requeueErr) {
+					t.Errorf("Expected a requeue, got %v", err)
+				}
+			default:
+				if err == nil || err.Error() != expected.Error() {
+					t.Errorf("Expected error %v, got %v", expected, err)
+				}
+			}
+
+			if r.providerSpec.Zone != tc.expectedZone {
+				t.Errorf("Expected zone %s, got %s", tc.expectedZone, r.providerSpec.Zone)
+			}
+			condition := findCondition(r.providerStatus.Conditions, zoneFallbackConditionType)
+			if tc.expectedCondition == "" {
+				if condition != nil {
+					t.Errorf("Unexpected %s condition: %v", zoneFallbackConditionType, condition)
+				}
+			} else {
+				if condition == nil || condition.Status != tc.expectedCondition || condition.Reason != tc.expectedReason {
+					t.Errorf("Expected %s condition with status %s and reason %s, got %v", zoneFallbackConditionType, tc.expectedCondition, tc.expectedReason, condition)
+				}
+			}
+			expectedAnnotation := tc.expectedZone
+			if tc.expectedZone == tc.zone {
+				expectedAnnotation = ""
+			}
+			if zone := r.machine.Annotations[zoneAnnotation]; zone != expectedAnnotation {
+				t.Errorf("Expected the zone of the instance to be recorded as %q, got %q", expectedAnnotation, zone)
+			}
+			if tc.expectedOperationID != "" && r.machine.Annotations[createOperationAnnotation] != tc.expectedOperationID {
+				t.Errorf("Expected create operation %s to be kept, got %q", tc.expectedOperationID, r.machine.Annotations[createOperationAnnotation])
+			}
+		})
+	}
+}
+
+func TestZoneFallbackWithProviderID(t *testing.T) {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	mockComputeService.MockInstancesInsert = func(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
+		return nil, &googleapi.Error{Code: 503, Message: "ZONE_RESOURCE_POOL_EXHAUSTED"}
+	}
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status: configv1.InfrastructureStatus{
+			InfrastructureName: "test-748kjf",
+			PlatformStatus:     &configv1.PlatformStatus{Type: configv1.GCPPlatformType, GCP: &configv1.GCPPlatformStatus{}},
+		},
+	}
+	providerID := "gce://project/us-east1-b/test"
+	requested := &machinev1.GCPMachineProviderSpec{Region: "us-east1", Zone: "us-east1-b"}
+	value, err := util.RawExtensionFromProviderSpec(requested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Labels:      map[string]string{machinev1.MachineClusterIDLabel: "CLUSTERID"},
+			Annotations: map[string]string{fallbackZonesAnnotation: "us-east1-c"},
+		},
+		Spec: machinev1.MachineSpec{ProviderID: &providerID, ProviderSpec: machinev1.ProviderSpec{Value: value}},
+	}
+	r := newReconciler(&machineScope{
+		machine:        machine,
+		coreClient:     controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(infra).Build(),
+		providerSpec:   requested.DeepCopy(),
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		specZone:       "us-east1-b",
+		computeService: mockComputeService,
+		projectID:      "project",
+		instanceName:   "test",
+		featureGates:   featuregates.NewFeatureGate(nil, []configv1.FeatureGateName{configv1.FeatureGateGCPLabelsTags}),
+	})
+
+	var requeueErr *machinecontroller.RequeueAfterError
+	if err := r.create(); !errors.As(err, &requeueErr) {
+		t.Fatalf("Expected a requeue, got %v", err)
+	}
+	if id := r.instanceID(); id.Zone != "us-east1-c" {
+		t.Errorf("Expected the instance to be looked up in zone us-east1-c, got %s", id.Zone)
+	}
+	if *r.machine.Spec.ProviderID != "gce://project/us-east1-c/test" {
+		t.Errorf("Expected the provider ID to be moved to zone us-east1-c, got %s", *r.machine.Spec.ProviderID)
+	}
+
+	if err := r.setMachineSpec(); err != nil {
+		t.Fatal(err)
+	}
+	persisted, err := util.ProviderSpecFromRawExtension(r.machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := ValidateProviderSpecUpdate(persisted, requested, field.NewPath("providerSpec")); len(errs) > 0 {
+		t.Errorf("Expected the persisted providerSpec to be a valid update, got %v", errs.ToAggregate())
+	}
+	if persisted.Zone != "us-east1-b" || r.machine.Annotations[zoneAnnotation] != "us-east1-c" {
+		t.Errorf("Expected zone us-east1-b to be kept in the providerSpec and zone us-east1-c recorded, got %s and %q", persisted.Zone, r.machine.Annotations[zoneAnnotation])
+	}
+}
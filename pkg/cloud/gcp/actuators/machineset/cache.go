@@ -14,17 +14,27 @@ limitations under the License.
 package machineset
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"sync"
+	"time"
 
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
 	gce "google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 )
 
+// notFoundTTL is how long a machine type GCP does not know is remembered. Machine types are not
+// expected to appear, but new ones roll out to zones over time, so the lookup is retried eventually
+// instead of on every resync of the MachineSet.
+const notFoundTTL = time.Hour
+
 // machineTypeKey is used to identify MachineType.
 type machineTypeKey struct {
+	projectID   string
 	zone        string
 	machineType string
 }
@@ -33,24 +43,34 @@ type machineTypeKey struct {
 type machineTypesCache struct {
 	cacheMutex        sync.Mutex
 	machineTypesCache map[machineTypeKey]*gce.MachineType
+	// notFound holds when the machine types GCP did not know were looked up.
+	notFound map[machineTypeKey]time.Time
+	clock    clock.Clock
 }
 
 // newMachineTypesCache creates empty machineCache.
 func newMachineTypesCache() *machineTypesCache {
 	return &machineTypesCache{
 		machineTypesCache: map[machineTypeKey]*gce.MachineType{},
+		notFound:          map[machineTypeKey]time.Time{},
+		clock:             clock.RealClock{},
 	}
 }
 
-// getMachineTypeFromCache retrieves machine type from cache under lock.
+// getMachineTypeFromCache retrieves machine type from cache under lock. Machine types GCP does not
+// know are returned as nil without an error, and not looked up again for notFoundTTL.
 func (mc *machineTypesCache) getMachineTypeFromCache(gcpService computeservice.GCPComputeService, projectID string, zone string, machineType string) (*gce.MachineType, error) {
 	mc.cacheMutex.Lock()
 	defer mc.cacheMutex.Unlock()
 
+	key := machineTypeKey{projectID: projectID, zone: zone, machineType: machineType}
 	// Machine Type already fetched from GCE
-	if mt, ok := mc.machineTypesCache[machineTypeKey{zone, machineType}]; ok {
+	if mt, ok := mc.machineTypesCache[key]; ok {
 		return mt, nil
 	}
+	if lookedUp, ok := mc.notFound[key]; ok && mc.clock.Since(lookedUp) < notFoundTTL {
+		return nil, nil
+	}
 
 	mt, err := gcpService.MachineTypesGet(projectID, zone, machineType)
 	if err != nil {
@@ -59,18 +79,17 @@ func (mc *machineTypesCache) getMachineTypeFromCache(gcpService computeservice.G
 		}
 		klog.Errorf("Unable to set scale from zero annotations: unknown instance type: %s", machineType)
 		klog.Errorf("Autoscaling from zero will not work. To fix this, manually populate machine annotations for your instance type: %v", []string{cpuKey, memoryKey})
+		mc.notFound[key] = mc.clock.Now()
 		// Returning no instance type and no error to prevent further reconciliation
 		return nil, nil
 	}
 
-	mc.machineTypesCache[machineTypeKey{zone, machineType}] = mt
+	delete(mc.notFound, key)
+	mc.machineTypesCache[key] = mt
 	return mt, nil
 }
 
 func isNotFoundError(err error) bool {
-	switch t := err.(type) {
-	case *googleapi.Error:
-		return t.Code == 404
-	}
-	return false
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
 }
@@ -0,0 +1,53 @@
+package machineset
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestMachineTypesCache(t *testing.T) {
+	lookups := map[string]int{}
+	_, service := computeservice.NewComputeServiceMock()
+	service.MockMachineTypesGet = func(project string, zone string, machineType string) (*compute.MachineType, error) {
+		lookups[project+"/"+machineType]++
+		if machineType == "n9-unknown-2" {
+			return nil, &googleapi.Error{Code: http.StatusNotFound}
+		}
+		return &compute.MachineType{Name: machineType, GuestCpus: 2, MemoryMb: 7680}, nil
+	}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	cache := newMachineTypesCache()
+	cache.clock = fakeClock
+
+	for i := 0; i < 3; i++ {
+		for _, project := range []string{"project-a", "project-b"} {
+			machineType, err := cache.getMachineTypeFromCache(service, project, "us-east1-b", "n1-standard-2")
+			if err != nil || machineType == nil || machineType.GuestCpus != 2 {
+				t.Fatalf("Expected machine type n1-standard-2, got %v, %v", machineType, err)
+			}
+		}
+		if machineType, err := cache.getMachineTypeFromCache(service, "project-a", "us-east1-b", "n9-unknown-2"); err != nil || machineType != nil {
+			t.Fatalf("Expected an unknown machine type, got %v, %v", machineType, err)
+		}
+	}
+	expected := map[string]int{"project-a/n1-standard-2": 1, "project-b/n1-standard-2": 1, "project-a/n9-unknown-2": 1}
+	for key, count := range expected {
+		if lookups[key] != count {
+			t.Errorf("Expected %d lookups of %s, got %d", count, key, lookups[key])
+		}
+	}
+
+	fakeClock.Step(notFoundTTL)
+	if _, err := cache.getMachineTypeFromCache(service, "project-a", "us-east1-b", "n9-unknown-2"); err != nil {
+		t.Fatal(err)
+	}
+	if lookups["project-a/n9-unknown-2"] != 2 {
+		t.Errorf("Expected the unknown machine type to be looked up again after %s", notFoundTTL)
+	}
+}
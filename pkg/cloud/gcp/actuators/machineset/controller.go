@@ -23,8 +23,9 @@ import (
 	"github.com/go-logr/logr"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	mapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
-	mapiutil "github.com/openshift/machine-api-operator/pkg/util"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/credentials"
+	gce "google.golang.org/api/compute/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -48,6 +49,8 @@ const (
 type Reconciler struct {
 	Client client.Client
 	Log    logr.Logger
+	// Credentials builds the credentials of the compute client from the credentials secret. Optional.
+	Credentials *credentials.Builder
 
 	recorder record.EventRecorder
 	scheme   *runtime.Scheme
@@ -142,7 +145,7 @@ func (r *Reconciler) reconcile(machineSet *machinev1.MachineSet) (ctrl.Result, e
 		return ctrl.Result{}, err
 	}
 
-	machineType, err := r.cache.getMachineTypeFromCache(gceService, providerConfig.ProjectID, providerConfig.Zone, providerConfig.MachineType)
+	machineType, err := r.getMachineType(gceService, providerConfig)
 	if err != nil {
 		return ctrl.Result{}, mapierrors.InvalidMachineConfiguration("error fetching machine type %q: %v", providerConfig.MachineType, err)
 	} else if machineType == nil {
@@ -170,24 +173,46 @@ func (r *Reconciler) reconcile(machineSet *machinev1.MachineSet) (ctrl.Result, e
 		machineSet.Annotations[gpuKey] = strconv.FormatInt(0, 10)
 	}
 
-	// We guarantee that any existing labels provided via the capacity annotations are preserved.
+	// We guarantee that any existing labels and taints provided via the capacity annotations are preserved.
 	// See https://github.com/kubernetes/autoscaler/pull/5382 and https://github.com/kubernetes/autoscaler/pull/5697
-	machineSet.Annotations[labelsKey] = mapiutil.MergeCommaSeparatedKeyValuePairs(
-		fmt.Sprintf("kubernetes.io/arch=%s", util.CPUArchitecture(providerConfig.MachineType)),
-		machineSet.Annotations[labelsKey])
+	machineSet.Annotations[labelsKey] = mergeCapacityList(nodeLabelHints(machineSet, providerConfig, machineType), machineSet.Annotations[labelsKey])
+	if taints := nodeTaintHints(machineSet); len(taints) > 0 || machineSet.Annotations[taintsKey] != "" {
+		machineSet.Annotations[taintsKey] = mergeCapacityList(taints, machineSet.Annotations[taintsKey])
+	}
 	return ctrl.Result{}, nil
 }
 
+// getMachineType returns the machine type of the providerConfig. The capacity of custom machine
+// types is taken from their name, other machine types are fetched from GCP.
+func (r *Reconciler) getMachineType(gceService computeservice.GCPComputeService, providerConfig *machinev1.GCPMachineProviderSpec) (*gce.MachineType, error) {
+	custom, err := util.ParseCustomMachineType(providerConfig.MachineType)
+	if err != nil {
+		return nil, err
+	}
+	if custom != nil {
+		return &gce.MachineType{
+			Name:      providerConfig.MachineType,
+			GuestCpus: custom.CPUs,
+			MemoryMb:  custom.MemoryMb,
+		}, nil
+	}
+	return r.cache.getMachineTypeFromCache(gceService, providerConfig.ProjectID, providerConfig.Zone, providerConfig.MachineType)
+}
+
 func getproviderConfig(machineSet *machinev1.MachineSet) (*machinev1.GCPMachineProviderSpec, error) {
 	return util.ProviderSpecFromRawExtension(machineSet.Spec.Template.Spec.ProviderSpec.Value)
 }
 
 // getRealGCPService constructs a real GCPService for talking to GCP
 func (r *Reconciler) getRealGCPService(namespace string, providerConfig machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, error) {
-	serviceAccountJSON, err := util.GetCredentialsSecret(r.Client, namespace, providerConfig)
+	serviceAccountJSON, impersonateServiceAccount, err := util.GetCredentials(r.Client, namespace, providerConfig)
 	if err != nil {
 		return nil, err
 	}
+	serviceAccountJSON, err = r.Credentials.Build(serviceAccountJSON, impersonateServiceAccount)
+	if err != nil {
+		return nil, mapierrors.InvalidMachineConfiguration("error building credentials: %v", err)
+	}
 
 	computeService, err := computeservice.NewComputeService(serviceAccountJSON)
 	if err != nil {
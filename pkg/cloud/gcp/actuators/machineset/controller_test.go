@@ -185,7 +185,7 @@ var _ = Describe("Reconciler", func() {
 				cpuKey:    "2",
 				memoryKey: "7680",
 				gpuKey:    "2",
-				labelsKey: "kubernetes.io/arch=amd64",
+				labelsKey: "cluster-api/accelerator=nvidia-tesla-p100,kubernetes.io/arch=amd64",
 			},
 			expectedEvents: []string{},
 		}),
@@ -339,7 +339,7 @@ func TestReconcile(t *testing.T) {
 				cpuKey:    "2",
 				memoryKey: "7680",
 				gpuKey:    "2",
-				labelsKey: "kubernetes.io/arch=amd64",
+				labelsKey: "cluster-api/accelerator=nvidia-tesla-p100,kubernetes.io/arch=amd64",
 			},
 			expectErr: false,
 		},
@@ -369,6 +369,44 @@ func TestReconcile(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name:        "with a custom machine type",
+			machineType: "n2-custom-8-73728-ext",
+			mockMachineTypesGet: func(_ string, _ string, _ string) (*compute.MachineType, error) {
+				return nil, errors.New("custom machine types should not be fetched")
+			},
+			existingAnnotations: make(map[string]string),
+			expectedAnnotations: map[string]string{
+				cpuKey:    "8",
+				memoryKey: "73728",
+				gpuKey:    "0",
+				labelsKey: "kubernetes.io/arch=amd64",
+			},
+			expectErr: false,
+		},
+		{
+			name:        "with a shared-core custom machine type",
+			machineType: "e2-custom-small-2048",
+			mockMachineTypesGet: func(_ string, _ string, machineType string) (*compute.MachineType, error) {
+				return &compute.MachineType{Name: machineType, GuestCpus: 2, MemoryMb: 2048}, nil
+			},
+			existingAnnotations: make(map[string]string),
+			expectedAnnotations: map[string]string{
+				cpuKey:    "2",
+				memoryKey: "2048",
+				gpuKey:    "0",
+				labelsKey: "kubernetes.io/arch=amd64",
+			},
+			expectErr: false,
+		},
+		{
+			name:                "with an invalid custom machine type",
+			machineType:         "custom-3-7680",
+			mockMachineTypesGet: mockMachineTypesFunc,
+			existingAnnotations: make(map[string]string),
+			expectedAnnotations: make(map[string]string),
+			expectErr:           true,
+		},
 		{
 			name:                "with existing annotations",
 			machineType:         "n1-standard-2",
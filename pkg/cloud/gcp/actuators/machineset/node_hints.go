@@ -0,0 +1,97 @@
+/*
+Copyright The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	mapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	gce "google.golang.org/api/compute/v1"
+)
+
+const (
+	// taintsKey exposes the taints the nodes of the MachineSet register with, for the autoscaler
+	// to foresee them when scaling from zero.
+	taintsKey = "capacity.cluster-autoscaler.kubernetes.io/taints"
+
+	// gpuTypeLabel is the node label the autoscaler matches the GPU types of its resource limits with.
+	gpuTypeLabel = "cluster-api/accelerator"
+
+	// nodeLabelsAnnotation and nodeTaintsAnnotation are the annotations of the machine template the
+	// machine controller passes to the kubelet, see the machine package.
+	nodeLabelsAnnotation = "machine.openshift.io/gcp-node-labels"
+	nodeTaintsAnnotation = "machine.openshift.io/gcp-node-taints"
+)
+
+// nodeLabelHints returns the labels, in the key=value format, the nodes of the MachineSet get from
+// its providerSpec and annotations and that the autoscaler cannot see in the machine template: the
+// CPU architecture, the GPU type, whether the instances are preemptible, and the labels the kubelet
+// registers the node with.
+func nodeLabelHints(machineSet *machinev1.MachineSet, providerConfig *machinev1.GCPMachineProviderSpec, machineType *gce.MachineType) []string {
+	labels := []string{fmt.Sprintf("kubernetes.io/arch=%s", util.CPUArchitecture(providerConfig.MachineType))}
+	switch {
+	case len(providerConfig.GPUs) > 0 && providerConfig.GPUs[0].Type != "":
+		labels = append(labels, fmt.Sprintf("%s=%s", gpuTypeLabel, providerConfig.GPUs[0].Type))
+	case len(machineType.Accelerators) > 0 && machineType.Accelerators[0].GuestAcceleratorType != "":
+		labels = append(labels, fmt.Sprintf("%s=%s", gpuTypeLabel, machineType.Accelerators[0].GuestAcceleratorType))
+	}
+	if providerConfig.Preemptible {
+		labels = append(labels, mapierrors.MachineInterruptibleInstanceLabelName+"=")
+	}
+	return append(labels, splitList(machineSet.Spec.Template.Annotations[nodeLabelsAnnotation])...)
+}
+
+// nodeTaintHints returns the taints, in the key=value:Effect format, the kubelet registers the nodes
+// of the MachineSet with. The taints of the machine template are visible to the autoscaler already.
+func nodeTaintHints(machineSet *machinev1.MachineSet) []string {
+	return splitList(machineSet.Spec.Template.Annotations[nodeTaintsAnnotation])
+}
+
+// mergeCapacityList merges the entries of a capacity annotation with its existing value. Entries
+// are keyed by the key of the label or taint, existing entries win so that values set by users are
+// preserved. The result is sorted so that it does not change between reconciles.
+func mergeCapacityList(entries []string, existing string) string {
+	merged := map[string]string{}
+	for _, entry := range append(entries, splitList(existing)...) {
+		merged[capacityEntryKey(entry)] = entry
+	}
+	result := make([]string, 0, len(merged))
+	for _, entry := range merged {
+		result = append(result, entry)
+	}
+	sort.Strings(result)
+	return strings.Join(result, ",")
+}
+
+// capacityEntryKey returns the key of a key=value label or key=value:Effect taint.
+func capacityEntryKey(entry string) string {
+	if i := strings.IndexAny(entry, "=:"); i >= 0 {
+		return entry[:i]
+	}
+	return entry
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
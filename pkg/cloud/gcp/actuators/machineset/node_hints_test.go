@@ -0,0 +1,96 @@
+package machineset
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestNodeHints(t *testing.T) {
+	cases := []struct {
+		name                string
+		machineType         string
+		preemptible         bool
+		templateAnnotations map[string]string
+		existingAnnotations map[string]string
+		expectedLabels      string
+		expectedTaints      string
+	}{
+		{
+			name:           "GPU type of the machine type",
+			machineType:    "a2-highgpu-1g",
+			expectedLabels: "cluster-api/accelerator=nvidia-tesla-a100,kubernetes.io/arch=amd64",
+		},
+		{
+			name:           "Preemptible instances",
+			machineType:    "n1-standard-2",
+			preemptible:    true,
+			expectedLabels: "kubernetes.io/arch=amd64,machine.openshift.io/interruptible-instance=",
+		},
+		{
+			name:        "Node labels and taints of the kubelet",
+			machineType: "n1-standard-2",
+			templateAnnotations: map[string]string{
+				nodeLabelsAnnotation: "pool=batch, node.kubernetes.io/tier=spot",
+				nodeTaintsAnnotation: "dedicated=batch:NoSchedule,spot:PreferNoSchedule",
+			},
+			expectedLabels: "kubernetes.io/arch=amd64,node.kubernetes.io/tier=spot,pool=batch",
+			expectedTaints: "dedicated=batch:NoSchedule,spot:PreferNoSchedule",
+		},
+		{
+			name:        "Existing labels and taints are preserved",
+			machineType: "n1-standard-2",
+			templateAnnotations: map[string]string{
+				nodeLabelsAnnotation: "pool=batch",
+				nodeTaintsAnnotation: "dedicated=batch:NoSchedule",
+			},
+			existingAnnotations: map[string]string{
+				labelsKey: "pool=gpu,zone=a",
+				taintsKey: "dedicated=gpu:NoExecute",
+			},
+			expectedLabels: "kubernetes.io/arch=amd64,pool=gpu,zone=a",
+			expectedTaints: "dedicated=gpu:NoExecute",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, service := computeservice.NewComputeServiceMock()
+			service.MockMachineTypesGet = func(_ string, _ string, machineType string) (*compute.MachineType, error) {
+				machine := &compute.MachineType{Name: machineType, GuestCpus: 2, MemoryMb: 7680}
+				if machineType == "a2-highgpu-1g" {
+					machine.Accelerators = []*compute.MachineTypeAccelerators{{GuestAcceleratorCount: 1, GuestAcceleratorType: "nvidia-tesla-a100"}}
+				}
+				return machine, nil
+			}
+			r := &Reconciler{
+				recorder: record.NewFakeRecorder(1),
+				cache:    newMachineTypesCache(),
+				getGCPService: func(_ string, _ machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, error) {
+					return service, nil
+				},
+			}
+			providerSpec, err := providerSpecFromMachine(&machinev1.GCPMachineProviderSpec{MachineType: tc.machineType, Preemptible: tc.preemptible})
+			if err != nil {
+				t.Fatal(err)
+			}
+			machineSet := &machinev1.MachineSet{}
+			machineSet.Annotations = tc.existingAnnotations
+			machineSet.Spec.Template.Annotations = tc.templateAnnotations
+			machineSet.Spec.Template.Spec.ProviderSpec = providerSpec
+
+			if _, err := r.reconcile(machineSet); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if labels := machineSet.Annotations[labelsKey]; labels != tc.expectedLabels {
+				t.Errorf("Expected labels %q, got %q", tc.expectedLabels, labels)
+			}
+			if taints, ok := machineSet.Annotations[taintsKey]; taints != tc.expectedTaints || ok != (tc.expectedTaints != "") {
+				t.Errorf("Expected taints %q, got %q", tc.expectedTaints, taints)
+			}
+		})
+	}
+}
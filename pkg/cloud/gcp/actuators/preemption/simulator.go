@@ -0,0 +1,148 @@
+package preemption
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/credentials"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SimulatePreemptionLabel opts a preemptible machine into simulated preemptions.
+	SimulatePreemptionLabel = "machine.openshift.io/gcp-simulate-preemption"
+
+	simulatedPreemptionEventReason = "SimulatedPreemption"
+)
+
+// Simulator periodically preempts a random fraction of the preemptible machines labelled with
+// SimulatePreemptionLabel by simulating a maintenance event on their instances. It is meant for
+// test and staging clusters, to validate that workloads tolerate spot churn before spot machines
+// are used in production.
+type Simulator struct {
+	Client               client.Client
+	ComputeClientBuilder computeservice.BuilderFuncType
+	// Credentials builds the credentials of the compute client from the credentials secret. Optional.
+	Credentials *credentials.Builder
+	// Interval between two rounds of simulated preemptions.
+	Interval time.Duration
+	// Fraction of the labelled machines preempted in each round, at least one machine is preempted
+	// when any is labelled.
+	Fraction float64
+	// Namespace restricts the simulation to the machines of a namespace, all namespaces if empty.
+	Namespace string
+
+	recorder record.EventRecorder
+	rand     *rand.Rand
+}
+
+// SetupWithManager adds the simulator to the manager, it only runs on the leader.
+func (s *Simulator) SetupWithManager(mgr ctrl.Manager) error {
+	if s.Interval <= 0 {
+		return fmt.Errorf("preemption simulation interval must be positive, got %s", s.Interval)
+	}
+	if s.Fraction <= 0 || s.Fraction > 1 {
+		return fmt.Errorf("preemption simulation fraction must be in (0, 1], got %v", s.Fraction)
+	}
+	s.recorder = mgr.GetEventRecorderFor("preemption-simulator")
+	return mgr.Add(s)
+}
+
+// Start runs a round of simulated preemptions every interval until the context is done.
+func (s *Simulator) Start(ctx context.Context) error {
+	klog.Warningf("Simulating the preemption of %.0f%% of the machines labelled %s every %s", s.Fraction*100, SimulatePreemptionLabel, s.Interval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.simulate(ctx); err != nil {
+			klog.Errorf("Failed to simulate preemptions: %v", err)
+		}
+	}, s.Interval)
+	return nil
+}
+
+// simulate preempts the instances of a random selection of the eligible machines.
+func (s *Simulator) simulate(ctx context.Context) error {
+	machines := &machinev1.MachineList{}
+	opts := []client.ListOption{client.HasLabels{SimulatePreemptionLabel}}
+	if s.Namespace != "" {
+		opts = append(opts, client.InNamespace(s.Namespace))
+	}
+	if err := s.Client.List(ctx, machines, opts...); err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	for _, machine := range s.selectMachines(machines.Items) {
+		if err := s.preempt(&machine); err != nil {
+			klog.Errorf("%s: failed to simulate preemption: %v", machine.Name, err)
+		}
+	}
+	return nil
+}
+
+// selectMachines returns a random Fraction of the running preemptible machines opted into the simulation.
+func (s *Simulator) selectMachines(machines []machinev1.Machine) []machinev1.Machine {
+	var eligible []machinev1.Machine
+	for _, machine := range machines {
+		if machine.Labels[SimulatePreemptionLabel] != "true" || machine.DeletionTimestamp != nil || machine.Status.NodeRef == nil {
+			continue
+		}
+		providerSpec, err := util.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+		if err != nil || !providerSpec.Preemptible {
+			continue
+		}
+		eligible = append(eligible, machine)
+	}
+	if len(eligible) == 0 {
+		return nil
+	}
+
+	if s.rand == nil {
+		s.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	s.rand.Shuffle(len(eligible), func(i, j int) { eligible[i], eligible[j] = eligible[j], eligible[i] })
+	count := int(math.Ceil(s.Fraction * float64(len(eligible))))
+	return eligible[:count]
+}
+
+func (s *Simulator) preempt(machine *machinev1.Machine) error {
+	providerSpec, err := util.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return err
+	}
+	serviceAccountJSON, impersonateServiceAccount, err := util.GetCredentials(s.Client, machine.Namespace, *providerSpec)
+	if err != nil {
+		return err
+	}
+	projectID := providerSpec.ProjectID
+	if projectID == "" {
+		if projectID, err = util.GetProjectIDFromJSONKey([]byte(serviceAccountJSON)); err != nil {
+			return fmt.Errorf("error getting project from JSON key: %w", err)
+		}
+	}
+	if serviceAccountJSON, err = s.Credentials.Build(serviceAccountJSON, impersonateServiceAccount); err != nil {
+		return fmt.Errorf("error building credentials: %w", err)
+	}
+	computeService, err := s.ComputeClientBuilder(serviceAccountJSON)
+	if err != nil {
+		return fmt.Errorf("error creating compute service: %w", err)
+	}
+
+	klog.Infof("%s: simulating preemption", machine.Name)
+	if _, err := computeService.InstancesSimulateMaintenanceEvent(projectID, providerSpec.Zone, machine.Name); err != nil {
+		return err
+	}
+	if s.recorder != nil {
+		s.recorder.Eventf(machine, corev1.EventTypeWarning, simulatedPreemptionEventReason, "Simulated the preemption of instance %s", machine.Name)
+	}
+	return nil
+}
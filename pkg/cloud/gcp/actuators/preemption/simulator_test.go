@@ -0,0 +1,93 @@
+package preemption
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newMachine(t *testing.T, name string, labels map[string]string, preemptible bool, hasNode bool) *machinev1.Machine {
+	providerSpec, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+		ProjectID:   "project",
+		Zone:        "us-east1-b",
+		Preemptible: preemptible,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-machine-api", Labels: labels},
+		Spec:       machinev1.MachineSpec{ProviderSpec: machinev1.ProviderSpec{Value: providerSpec}},
+	}
+	if hasNode {
+		machine.Status.NodeRef = &corev1.ObjectReference{Name: name}
+	}
+	return machine
+}
+
+func TestSimulate(t *testing.T) {
+	optedIn := map[string]string{SimulatePreemptionLabel: "true"}
+	objects := []client.Object{
+		newMachine(t, "not-preemptible", optedIn, false, true),
+		newMachine(t, "opted-out", map[string]string{SimulatePreemptionLabel: "false"}, true, true),
+		newMachine(t, "not-a-node", optedIn, true, false),
+		newMachine(t, "not-labelled", nil, true, true),
+	}
+	eligible := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("spot-%d", i)
+		eligible[name] = true
+		objects = append(objects, newMachine(t, name, optedIn, true, true))
+	}
+
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	fakeClient := controllerfake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	var preempted []string
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	mockComputeService.MockSimulateMaintenance = func(project string, zone string, instance string) (*compute.Operation, error) {
+		if project != "project" || zone != "us-east1-b" {
+			t.Errorf("Unexpected project %q or zone %q", project, zone)
+		}
+		preempted = append(preempted, instance)
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+
+	simulator := &Simulator{
+		Client: fakeClient,
+		ComputeClientBuilder: func(_ string) (computeservice.GCPComputeService, error) {
+			return mockComputeService, nil
+		},
+		Fraction: 0.5,
+		rand:     rand.New(rand.NewSource(1)),
+	}
+	if err := simulator.simulate(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(preempted) != 2 {
+		t.Fatalf("Expected 2 machines to be preempted, got %v", preempted)
+	}
+	for _, name := range preempted {
+		if !eligible[name] {
+			t.Errorf("Machine %s is not eligible for simulated preemptions", name)
+		}
+	}
+}
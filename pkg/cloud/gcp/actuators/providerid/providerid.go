@@ -0,0 +1,70 @@
+// Package providerid builds and parses the provider IDs of GCP instances, gce://project/zone/name,
+// which link a Machine to its Node. Nodes registered by different versions of the cloud provider,
+// or Machines migrated from other tools, may carry them in other formats that name the same
+// instance, so parsing accepts those too.
+package providerid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scheme is the scheme of the provider IDs of GCP instances.
+const Scheme = "gce://"
+
+// ProviderID identifies an instance by its project, zone and name.
+type ProviderID struct {
+	Project  string
+	Zone     string
+	Instance string
+}
+
+// New returns the provider ID of an instance.
+func New(project, zone, instance string) ProviderID {
+	return ProviderID{Project: project, Zone: zone, Instance: instance}
+}
+
+// String returns the canonical format of the provider ID, the one set by the cloud provider on
+// nodes, see
+// https://github.com/kubernetes/kubernetes/blob/8765fa2e48974e005ad16e65cb5c3acf5acff17b/staging/src/k8s.io/legacy-cloud-providers/gce/gce_util.go#L204.
+func (p ProviderID) String() string {
+	return fmt.Sprintf("%s%s/%s/%s", Scheme, p.Project, p.Zone, p.Instance)
+}
+
+// Parse parses a provider ID, accepting besides the canonical format:
+//   - a scheme in another case, e.g. GCE://project/zone/name,
+//   - empty path segments, e.g. gce:///project/zone/name or a trailing slash,
+//   - no scheme, e.g. project/zone/name,
+//   - the resource path or self link of the instance, e.g.
+//     projects/project/zones/zone/instances/name or
+//     https://www.googleapis.com/compute/v1/projects/project/zones/zone/instances/name.
+func Parse(providerID string) (ProviderID, error) {
+	value := strings.TrimSpace(providerID)
+	if scheme, rest, ok := strings.Cut(value, "://"); ok {
+		switch strings.ToLower(scheme) {
+		case "gce":
+			value = rest
+		case "https", "http":
+			// A self link, the resource path follows the host and the API version.
+			if i := strings.Index(rest, "/projects/"); i >= 0 {
+				value = rest[i+1:]
+			}
+		default:
+			return ProviderID{}, fmt.Errorf("provider ID %q is not a GCP provider ID", providerID)
+		}
+	}
+
+	var parts []string
+	for _, part := range strings.Split(value, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	switch {
+	case len(parts) == 3:
+		return New(parts[0], parts[1], parts[2]), nil
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "zones" && parts[4] == "instances":
+		return New(parts[1], parts[3], parts[5]), nil
+	}
+	return ProviderID{}, fmt.Errorf("provider ID %q is not of the form %sproject/zone/name", providerID, Scheme)
+}
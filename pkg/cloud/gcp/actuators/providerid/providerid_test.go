@@ -0,0 +1,63 @@
+package providerid
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	expected := New("my-project", "us-central1-a", "worker-a-x7k2p")
+
+	cases := []struct {
+		name       string
+		providerID string
+		expected   ProviderID
+		expectErr  bool
+	}{
+		{name: "Canonical", providerID: "gce://my-project/us-central1-a/worker-a-x7k2p", expected: expected},
+		{name: "Upper case scheme", providerID: "GCE://my-project/us-central1-a/worker-a-x7k2p", expected: expected},
+		{name: "Empty segments", providerID: "gce:///my-project//us-central1-a/worker-a-x7k2p/", expected: expected},
+		{name: "No scheme", providerID: "my-project/us-central1-a/worker-a-x7k2p", expected: expected},
+		{name: "Resource path", providerID: "projects/my-project/zones/us-central1-a/instances/worker-a-x7k2p", expected: expected},
+		{
+			name:       "Self link",
+			providerID: "https://www.googleapis.com/compute/v1/projects/my-project/zones/us-central1-a/instances/worker-a-x7k2p",
+			expected:   expected,
+		},
+		{
+			name:       "Domain-scoped project",
+			providerID: "gce://example.com:my-project/us-central1-a/worker-a-x7k2p",
+			expected:   New("example.com:my-project", "us-central1-a", "worker-a-x7k2p"),
+		},
+		{name: "Other provider", providerID: "aws:///us-east-1a/i-0123456789", expectErr: true},
+		{name: "Missing zone", providerID: "gce://my-project/worker-a-x7k2p", expectErr: true},
+		{name: "Empty", providerID: "", expectErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			providerID, err := Parse(tc.providerID)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %v", providerID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if providerID != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, providerID)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	providerID := New("my-project", "us-central1-a", "worker-a-x7k2p")
+	if s := providerID.String(); s != "gce://my-project/us-central1-a/worker-a-x7k2p" {
+		t.Errorf("Expected gce://my-project/us-central1-a/worker-a-x7k2p, got %s", s)
+	}
+	if parsed, err := Parse(providerID.String()); err != nil || parsed != providerID {
+		t.Errorf("Expected %v to round-trip, got %v, %v", providerID, parsed, err)
+	}
+}
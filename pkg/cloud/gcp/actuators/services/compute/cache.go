@@ -0,0 +1,60 @@
+package computeservice
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// DefaultMaxCachedServices is the number of compute services kept by NewCachingBuilder by default,
+// enough for the credentials secrets of a cluster and a rotation of each.
+const DefaultMaxCachedServices = 8
+
+// cachingBuilder reuses the compute service built for the same credentials.
+type cachingBuilder struct {
+	builder    BuilderFuncType
+	maxEntries int
+
+	mu       sync.Mutex
+	services map[[sha256.Size]byte]GCPComputeService
+	// order lists the cached credentials from the least to the most recently built.
+	order [][sha256.Size]byte
+}
+
+// NewCachingBuilder returns a builder that reuses the compute service built for the same credentials
+// JSON, so that its access tokens are reused across reconciles. The credentials are read from the
+// credentials secret on every reconcile, so once the secret is rotated a compute service is built
+// for the new key without restarting the controller. At most maxEntries services are kept, the
+// least recently built ones are dropped first.
+func NewCachingBuilder(builder BuilderFuncType, maxEntries int) BuilderFuncType {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxCachedServices
+	}
+	c := &cachingBuilder{
+		builder:    builder,
+		maxEntries: maxEntries,
+		services:   map[[sha256.Size]byte]GCPComputeService{},
+	}
+	return c.build
+}
+
+func (c *cachingBuilder) build(serviceAccountJSON string) (GCPComputeService, error) {
+	key := sha256.Sum256([]byte(serviceAccountJSON))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if service, ok := c.services[key]; ok {
+		return service, nil
+	}
+
+	service, err := c.builder(serviceAccountJSON)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.order) >= c.maxEntries {
+		delete(c.services, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.services[key] = service
+	c.order = append(c.order, key)
+	return service, nil
+}
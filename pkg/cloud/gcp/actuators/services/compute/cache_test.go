@@ -0,0 +1,41 @@
+package computeservice
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCachingBuilder(t *testing.T) {
+	built := map[string]int{}
+	builder := NewCachingBuilder(func(serviceAccountJSON string) (GCPComputeService, error) {
+		if serviceAccountJSON == "invalid" {
+			return nil, errors.New("invalid credentials")
+		}
+		built[serviceAccountJSON]++
+		_, service := NewComputeServiceMock()
+		return service, nil
+	}, 2)
+
+	first, _ := builder("key-1")
+	again, _ := builder("key-1")
+	if first != again || built["key-1"] != 1 {
+		t.Errorf("Expected the compute service to be reused for the same credentials, built %d times", built["key-1"])
+	}
+
+	// A rotated key gets a new compute service.
+	rotated, _ := builder("key-2")
+	if rotated == first || built["key-2"] != 1 {
+		t.Errorf("Expected a new compute service for rotated credentials")
+	}
+
+	if _, err := builder("invalid"); err == nil {
+		t.Error("Expected the error of the builder")
+	}
+
+	// The least recently built service is dropped once the cache is full.
+	builder("key-3")
+	builder("key-1")
+	if built["key-1"] != 2 {
+		t.Errorf("Expected the compute service of the oldest credentials to be rebuilt, built %d times", built["key-1"])
+	}
+}
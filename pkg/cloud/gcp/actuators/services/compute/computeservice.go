@@ -3,10 +3,12 @@ package computeservice
 import (
 	"context"
 	"log"
+	"net/http"
 	"strings"
 
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 
 	"github.com/openshift/machine-api-provider-gcp/pkg/version"
 	"google.golang.org/api/compute/v1"
@@ -17,34 +19,77 @@ import (
 type GCPComputeService interface {
 	InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error)
 	InstancesInsert(project string, zone string, instance *compute.Instance) (*compute.Operation, error)
+	InstancesInsertFromTemplate(project string, zone string, sourceInstanceTemplate string, instance *compute.Instance) (*compute.Operation, error)
 	InstancesGet(project string, zone string, instance string) (*compute.Instance, error)
+	InstancesAggregatedList(project string, filter string) ([]*compute.Instance, error)
+	InstancesSetLabels(project string, zone string, instance string, request *compute.InstancesSetLabelsRequest) (*compute.Operation, error)
+	InstancesSetTags(project string, zone string, instance string, tags *compute.Tags) (*compute.Operation, error)
+	InstancesSetMetadata(project string, zone string, instance string, metadata *compute.Metadata) (*compute.Operation, error)
+	InstancesStop(project string, zone string, instance string) (*compute.Operation, error)
+	InstancesStart(project string, zone string, instance string) (*compute.Operation, error)
+	InstancesSetMachineType(project string, zone string, instance string, request *compute.InstancesSetMachineTypeRequest) (*compute.Operation, error)
+	InstancesSetDeletionProtection(project string, zone string, instance string, deletionProtection bool) (*compute.Operation, error)
+	InstancesGetSerialPortOutput(project string, zone string, instance string, start int64) (*compute.SerialPortOutput, error)
+	InstancesSetShieldedInstanceIntegrityPolicy(project string, zone string, instance string, policy *compute.ShieldedInstanceIntegrityPolicy) (*compute.Operation, error)
+	DisksGet(project string, zone string, disk string) (*compute.Disk, error)
+	DisksList(project string, zone string, filter string) ([]*compute.Disk, error)
+	DisksDelete(project string, zone string, disk string) (*compute.Operation, error)
+	InstancesSimulateMaintenanceEvent(project string, zone string, instance string) (*compute.Operation, error)
+	ImagesGet(project string, image string) (*compute.Image, error)
+	ImagesGetFromFamily(project string, family string) (*compute.Image, error)
 	ZonesGet(project string, zone string) (*compute.Zone, error)
 	ZoneOperationsGet(project string, zone string, operation string) (*compute.Operation, error)
+	ZoneOperationsList(project string, zone string, filter string) ([]*compute.Operation, error)
+	RegionOperationsGet(project string, region string, operation string) (*compute.Operation, error)
 	BasePath() string
 	TargetPoolsGet(project string, region string, name string) (*compute.TargetPool, error)
 	TargetPoolsAddInstance(project string, region string, name string, instance string) (*compute.Operation, error)
 	TargetPoolsRemoveInstance(project string, region string, name string, instance string) (*compute.Operation, error)
 	MachineTypesGet(project string, machineType string, zone string) (*compute.MachineType, error)
 	RegionGet(project string, region string) (*compute.Region, error)
+	NetworksGet(project string, network string) (*compute.Network, error)
 	GPUCompatibleMachineTypesList(project string, zone string, ctx context.Context) (map[string]int64, []string)
 	AcceleratorTypeGet(project string, zone string, acceleratorType string) (*compute.AcceleratorType, error)
+	AcceleratorTypesList(project string, zone string, ctx context.Context) ([]*compute.AcceleratorType, error)
 	InstanceGroupsListInstances(project string, zone string, instanceGroup string, request *compute.InstanceGroupsListInstancesRequest) (*compute.InstanceGroupsListInstances, error)
 	InstanceGroupsAddInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error)
 	InstanceGroupsRemoveInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error)
 	InstanceGroupInsert(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error)
 	InstanceGroupGet(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error)
+	InstanceGroupsSetNamedPorts(project string, zone string, instanceGroupName string, request *compute.InstanceGroupsSetNamedPortsRequest) (*compute.Operation, error)
 	AddInstanceGroupToBackendService(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error)
 	BackendServiceGet(project string, region string, backendServiceName string) (*compute.BackendService, error)
+	BackendServiceGetHealth(project string, region string, backendServiceName string, group string) ([]*compute.HealthStatus, error)
+	NetworkEndpointGroupGet(project string, zone string, name string) (*compute.NetworkEndpointGroup, error)
+	NetworkEndpointGroupInsert(project string, zone string, networkEndpointGroup *compute.NetworkEndpointGroup) (*compute.Operation, error)
+	NetworkEndpointGroupsListNetworkEndpoints(project string, zone string, name string) ([]*compute.NetworkEndpoint, error)
+	NetworkEndpointGroupsAttachNetworkEndpoints(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error)
+	NetworkEndpointGroupsDetachNetworkEndpoints(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error)
+	AddressesGet(project string, region string, name string) (*compute.Address, error)
+	AddressesInsert(project string, region string, address *compute.Address) (*compute.Operation, error)
+	AddressesDelete(project string, region string, name string) (*compute.Operation, error)
+	AddressesList(project string, region string, filter string) ([]*compute.Address, error)
+	ResourcePoliciesGet(project string, region string, name string) (*compute.ResourcePolicy, error)
+	SubnetworksTestIamPermissions(project string, region string, subnetwork string, permissions []string) ([]string, error)
+	SubnetworksGet(project string, region string, subnetwork string) (*compute.Subnetwork, error)
+	GlobalForwardingRulesList(project string, filter string) ([]*compute.ForwardingRule, error)
+	FirewallsGet(project string, firewall string) (*compute.Firewall, error)
+	FirewallsPatch(project string, firewall string, firewallResource *compute.Firewall) (*compute.Operation, error)
+	InstanceTemplatesInsert(project string, instanceTemplate *compute.InstanceTemplate) (*compute.Operation, error)
 }
 
 type computeService struct {
 	service *compute.Service
+	// ctx is the context of the API calls, see WithContext. Nil means context.TODO().
+	ctx context.Context
 }
 
 // BuilderFuncType is function type for building gcp client
 type BuilderFuncType func(serviceAccountJSON string) (GCPComputeService, error)
 
-// NewComputeService return a new computeService
+// NewComputeService return a new computeService. The credentials are either a service account key
+// or workload identity federation (external_account) credentials, whose token exchange and service
+// account impersonation are configured in the JSON.
 func NewComputeService(serviceAccountJSON string) (GCPComputeService, error) {
 	ctx := context.TODO()
 
@@ -53,7 +98,16 @@ func NewComputeService(serviceAccountJSON string) (GCPComputeService, error) {
 		return nil, err
 	}
 
-	service, err := compute.NewService(ctx, option.WithCredentials(creds))
+	// The requests are dumped below the authentication, so that the dumps show them as sent.
+	// Deprecation headers of the responses are reported whether they are dumped or not.
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxIdleConnsPerHost = 100
+	transport, err := htransport.NewTransport(ctx, &dumpingTransport{base: &deprecationTransport{base: base}}, option.WithCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := compute.NewService(ctx, option.WithHTTPClient(&http.Client{Transport: transport}))
 	if err != nil {
 		return nil, err
 	}
@@ -64,26 +118,199 @@ func NewComputeService(serviceAccountJSON string) (GCPComputeService, error) {
 	}, nil
 }
 
+// NewEndpointBuilder returns a builder of compute services that call the compute API at the given
+// endpoint without authentication, ignoring the credentials. It is meant for local development
+// against a fake of the compute API, e.g. the fake-gce binary.
+func NewEndpointBuilder(endpoint string) BuilderFuncType {
+	return func(serviceAccountJSON string) (GCPComputeService, error) {
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		client := &http.Client{Transport: &dumpingTransport{base: &deprecationTransport{base: base}}}
+		service, err := compute.NewService(context.TODO(), option.WithHTTPClient(client), option.WithEndpoint(endpoint))
+		if err != nil {
+			return nil, err
+		}
+		service.UserAgent = "gcpprovider.openshift.io/" + version.Version.String()
+
+		return &computeService{
+			service: service,
+		}, nil
+	}
+}
+
+// contextualService is implemented by the compute services that can make their API calls with a context.
+type contextualService interface {
+	withContext(ctx context.Context) GCPComputeService
+}
+
+// WithContext returns a GCPComputeService making the API calls of the given service with ctx, so
+// that they are cancelled once ctx is done, e.g. when the deadline of a reconcile passed, and the
+// interceptors of the service do not wait past its deadline. Services that do not support a
+// context, e.g. the mock, are returned as is.
+func WithContext(ctx context.Context, service GCPComputeService) GCPComputeService {
+	contextual, ok := service.(contextualService)
+	if ctx == nil || !ok {
+		return service
+	}
+	return contextual.withContext(ctx)
+}
+
+func (c *computeService) withContext(ctx context.Context) GCPComputeService {
+	return &computeService{service: c.service, ctx: ctx}
+}
+
+// context returns the context of the API calls.
+func (c *computeService) context() context.Context {
+	if c.ctx == nil {
+		return context.TODO()
+	}
+	return c.ctx
+}
+
 // InstancesInsert is a pass through wrapper for compute.Service.Instances.Insert(...)
 func (c *computeService) InstancesInsert(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
-	return c.service.Instances.Insert(project, zone, instance).Do()
+	return c.service.Instances.Insert(project, zone, instance).Context(c.context()).Do()
+}
+
+// InstancesInsertFromTemplate is a pass through wrapper for compute.Service.Instances.Insert(...) with a
+// source instance template, whose properties the ones of the instance override.
+func (c *computeService) InstancesInsertFromTemplate(project string, zone string, sourceInstanceTemplate string, instance *compute.Instance) (*compute.Operation, error) {
+	return c.service.Instances.Insert(project, zone, instance).SourceInstanceTemplate(sourceInstanceTemplate).Context(c.context()).Do()
+}
+
+// InstancesGetSerialPortOutput is a pass through wrapper for compute.Service.Instances.GetSerialPortOutput(...)
+// of the first serial port. A negative start returns the last -start bytes of the output.
+func (c *computeService) InstancesGetSerialPortOutput(project string, zone string, instance string, start int64) (*compute.SerialPortOutput, error) {
+	return c.service.Instances.GetSerialPortOutput(project, zone, instance).Port(1).Start(start).Context(c.context()).Do()
+}
+
+// InstancesSetShieldedInstanceIntegrityPolicy is a pass through wrapper for compute.Service.Instances.SetShieldedInstanceIntegrityPolicy(...)
+func (c *computeService) InstancesSetShieldedInstanceIntegrityPolicy(project string, zone string, instance string, policy *compute.ShieldedInstanceIntegrityPolicy) (*compute.Operation, error) {
+	return c.service.Instances.SetShieldedInstanceIntegrityPolicy(project, zone, instance, policy).Context(c.context()).Do()
+}
+
+// DisksGet is a pass through wrapper for compute.Service.Disks.Get(...)
+func (c *computeService) DisksGet(project string, zone string, disk string) (*compute.Disk, error) {
+	return c.service.Disks.Get(project, zone, disk).Context(c.context()).Do()
+}
+
+// DisksList returns the disks of the zone matching the filter, reading all pages of
+// compute.Service.Disks.List(...).
+func (c *computeService) DisksList(project string, zone string, filter string) ([]*compute.Disk, error) {
+	var disks []*compute.Disk
+	if err := c.service.Disks.List(project, zone).Filter(filter).Pages(c.context(), func(page *compute.DiskList) error {
+		disks = append(disks, page.Items...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return disks, nil
+}
+
+// DisksDelete is a pass through wrapper for compute.Service.Disks.Delete(...)
+func (c *computeService) DisksDelete(project string, zone string, disk string) (*compute.Operation, error) {
+	return c.service.Disks.Delete(project, zone, disk).Context(c.context()).Do()
+}
+
+// ImagesGet is a pass through wrapper for compute.Service.Images.Get(...)
+func (c *computeService) ImagesGet(project string, image string) (*compute.Image, error) {
+	return c.service.Images.Get(project, image).Context(c.context()).Do()
+}
+
+// ImagesGetFromFamily is a pass through wrapper for compute.Service.Images.GetFromFamily(...)
+func (c *computeService) ImagesGetFromFamily(project string, family string) (*compute.Image, error) {
+	return c.service.Images.GetFromFamily(project, family).Context(c.context()).Do()
+}
+
+// InstancesSimulateMaintenanceEvent is a pass through wrapper for compute.Service.Instances.SimulateMaintenanceEvent(...)
+// Preemptible instances are preempted by a simulated maintenance event.
+func (c *computeService) InstancesSimulateMaintenanceEvent(project string, zone string, instance string) (*compute.Operation, error) {
+	return c.service.Instances.SimulateMaintenanceEvent(project, zone, instance).Context(c.context()).Do()
 }
 
 // ZoneOperationsGet is a pass through wrapper for compute.Service.ZoneOperations.Get(...)
 func (c *computeService) ZoneOperationsGet(project string, zone string, operation string) (*compute.Operation, error) {
-	return c.service.ZoneOperations.Get(project, zone, operation).Do()
+	return c.service.ZoneOperations.Get(project, zone, operation).Context(c.context()).Do()
+}
+
+// ZoneOperationsList returns the operations of the zone matching the filter, reading all pages of
+// compute.Service.ZoneOperations.List(...).
+func (c *computeService) ZoneOperationsList(project string, zone string, filter string) ([]*compute.Operation, error) {
+	var operations []*compute.Operation
+	if err := c.service.ZoneOperations.List(project, zone).Filter(filter).Pages(c.context(), func(page *compute.OperationList) error {
+		operations = append(operations, page.Items...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// RegionOperationsGet is a pass through wrapper for compute.Service.RegionOperations.Get(...)
+func (c *computeService) RegionOperationsGet(project string, region string, operation string) (*compute.Operation, error) {
+	return c.service.RegionOperations.Get(project, region, operation).Context(c.context()).Do()
 }
 
 func (c *computeService) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
-	return c.service.Instances.Get(project, zone, instance).Do()
+	return c.service.Instances.Get(project, zone, instance).Context(c.context()).Do()
+}
+
+// InstancesAggregatedList returns the instances of all zones of the project matching the filter,
+// reading all pages of compute.Service.Instances.AggregatedList(...).
+func (c *computeService) InstancesAggregatedList(project string, filter string) ([]*compute.Instance, error) {
+	var instances []*compute.Instance
+	req := c.service.Instances.AggregatedList(project).Filter(filter)
+	if err := req.Pages(c.context(), func(page *compute.InstanceAggregatedList) error {
+		for _, scoped := range page.Items {
+			instances = append(instances, scoped.Instances...)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// InstancesSetLabels is a pass through wrapper for compute.Service.Instances.SetLabels(...)
+func (c *computeService) InstancesSetLabels(project string, zone string, instance string, request *compute.InstancesSetLabelsRequest) (*compute.Operation, error) {
+	return c.service.Instances.SetLabels(project, zone, instance, request).Context(c.context()).Do()
+}
+
+// InstancesSetTags is a pass through wrapper for compute.Service.Instances.SetTags(...)
+func (c *computeService) InstancesSetTags(project string, zone string, instance string, tags *compute.Tags) (*compute.Operation, error) {
+	return c.service.Instances.SetTags(project, zone, instance, tags).Context(c.context()).Do()
+}
+
+// InstancesSetMetadata is a pass through wrapper for compute.Service.Instances.SetMetadata(...)
+func (c *computeService) InstancesSetMetadata(project string, zone string, instance string, metadata *compute.Metadata) (*compute.Operation, error) {
+	return c.service.Instances.SetMetadata(project, zone, instance, metadata).Context(c.context()).Do()
+}
+
+// InstancesStop is a pass through wrapper for compute.Service.Instances.Stop(...)
+func (c *computeService) InstancesStop(project string, zone string, instance string) (*compute.Operation, error) {
+	return c.service.Instances.Stop(project, zone, instance).Context(c.context()).Do()
+}
+
+// InstancesStart is a pass through wrapper for compute.Service.Instances.Start(...)
+func (c *computeService) InstancesStart(project string, zone string, instance string) (*compute.Operation, error) {
+	return c.service.Instances.Start(project, zone, instance).Context(c.context()).Do()
+}
+
+// InstancesSetMachineType is a pass through wrapper for compute.Service.Instances.SetMachineType(...)
+func (c *computeService) InstancesSetMachineType(project string, zone string, instance string, request *compute.InstancesSetMachineTypeRequest) (*compute.Operation, error) {
+	return c.service.Instances.SetMachineType(project, zone, instance, request).Context(c.context()).Do()
+}
+
+// InstancesSetDeletionProtection is a pass through wrapper for compute.Service.Instances.SetDeletionProtection(...)
+func (c *computeService) InstancesSetDeletionProtection(project string, zone string, instance string, deletionProtection bool) (*compute.Operation, error) {
+	return c.service.Instances.SetDeletionProtection(project, zone, instance).DeletionProtection(deletionProtection).Context(c.context()).Do()
 }
 
 func (c *computeService) InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error) {
-	return c.service.Instances.Delete(project, zone, instance).RequestId(requestId).Do()
+	return c.service.Instances.Delete(project, zone, instance).RequestId(requestId).Context(c.context()).Do()
 }
 
 func (c *computeService) ZonesGet(project string, zone string) (*compute.Zone, error) {
-	return c.service.Zones.Get(project, zone).Do()
+	return c.service.Zones.Get(project, zone).Context(c.context()).Do()
 }
 
 func (c *computeService) BasePath() string {
@@ -91,7 +318,7 @@ func (c *computeService) BasePath() string {
 }
 
 func (c *computeService) TargetPoolsGet(project string, region string, name string) (*compute.TargetPool, error) {
-	return c.service.TargetPools.Get(project, region, name).Do()
+	return c.service.TargetPools.Get(project, region, name).Context(c.context()).Do()
 }
 
 func (c *computeService) TargetPoolsAddInstance(project string, region string, name string, instanceLink string) (*compute.Operation, error) {
@@ -102,7 +329,7 @@ func (c *computeService) TargetPoolsAddInstance(project string, region string, n
 			},
 		},
 	}
-	return c.service.TargetPools.AddInstance(project, region, name, rb).Do()
+	return c.service.TargetPools.AddInstance(project, region, name, rb).Context(c.context()).Do()
 }
 
 func (c *computeService) TargetPoolsRemoveInstance(project string, region string, name string, instanceLink string) (*compute.Operation, error) {
@@ -113,11 +340,11 @@ func (c *computeService) TargetPoolsRemoveInstance(project string, region string
 			},
 		},
 	}
-	return c.service.TargetPools.RemoveInstance(project, region, name, rb).Do()
+	return c.service.TargetPools.RemoveInstance(project, region, name, rb).Context(c.context()).Do()
 }
 
 func (c *computeService) MachineTypesGet(project string, zone string, machineType string) (*compute.MachineType, error) {
-	return c.service.MachineTypes.Get(project, zone, machineType).Do()
+	return c.service.MachineTypes.Get(project, zone, machineType).Context(c.context()).Do()
 }
 
 // GPUCompatibleMachineTypesList function lists machineTypes available in the zone and return map of A2 family and slice of N1 family machineTypes
@@ -143,11 +370,26 @@ func (c *computeService) GPUCompatibleMachineTypesList(project string, zone stri
 }
 
 func (c *computeService) AcceleratorTypeGet(project string, zone string, acceleratorType string) (*compute.AcceleratorType, error) {
-	return c.service.AcceleratorTypes.Get(project, zone, acceleratorType).Do()
+	return c.service.AcceleratorTypes.Get(project, zone, acceleratorType).Context(c.context()).Do()
+}
+
+func (c *computeService) AcceleratorTypesList(project string, zone string, ctx context.Context) ([]*compute.AcceleratorType, error) {
+	var acceleratorTypes []*compute.AcceleratorType
+	if err := c.service.AcceleratorTypes.List(project, zone).Pages(ctx, func(page *compute.AcceleratorTypeList) error {
+		acceleratorTypes = append(acceleratorTypes, page.Items...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return acceleratorTypes, nil
 }
 
 func (c *computeService) RegionGet(project string, region string) (*compute.Region, error) {
-	return c.service.Regions.Get(project, region).Do()
+	return c.service.Regions.Get(project, region).Context(c.context()).Do()
+}
+
+func (c *computeService) NetworksGet(project string, network string) (*compute.Network, error) {
+	return c.service.Networks.Get(project, network).Context(c.context()).Do()
 }
 
 func (c *computeService) InstanceGroupsAddInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
@@ -158,7 +400,7 @@ func (c *computeService) InstanceGroupsAddInstances(project string, zone string,
 			},
 		},
 	}
-	return c.service.InstanceGroups.AddInstances(project, zone, instanceGroup, request).Do()
+	return c.service.InstanceGroups.AddInstances(project, zone, instanceGroup, request).Context(c.context()).Do()
 }
 
 func (c *computeService) InstanceGroupsRemoveInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
@@ -169,25 +411,140 @@ func (c *computeService) InstanceGroupsRemoveInstances(project string, zone stri
 			},
 		},
 	}
-	return c.service.InstanceGroups.RemoveInstances(project, zone, instanceGroup, request).Do()
+	return c.service.InstanceGroups.RemoveInstances(project, zone, instanceGroup, request).Context(c.context()).Do()
 }
 
 func (c *computeService) InstanceGroupsListInstances(project string, zone string, instanceGroup string, request *compute.InstanceGroupsListInstancesRequest) (*compute.InstanceGroupsListInstances, error) {
-	return c.service.InstanceGroups.ListInstances(project, zone, instanceGroup, request).Do()
+	return c.service.InstanceGroups.ListInstances(project, zone, instanceGroup, request).Context(c.context()).Do()
 }
 
 func (c *computeService) InstanceGroupInsert(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error) {
-	return c.service.InstanceGroups.Insert(project, zone, instanceGroup).Do()
+	return c.service.InstanceGroups.Insert(project, zone, instanceGroup).Context(c.context()).Do()
 }
 
 func (c *computeService) InstanceGroupGet(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error) {
-	return c.service.InstanceGroups.Get(project, zone, instanceGroupName).Do()
+	return c.service.InstanceGroups.Get(project, zone, instanceGroupName).Context(c.context()).Do()
+}
+
+func (c *computeService) InstanceGroupsSetNamedPorts(project string, zone string, instanceGroupName string, request *compute.InstanceGroupsSetNamedPortsRequest) (*compute.Operation, error) {
+	return c.service.InstanceGroups.SetNamedPorts(project, zone, instanceGroupName, request).Context(c.context()).Do()
 }
 
 func (c *computeService) AddInstanceGroupToBackendService(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
-	return c.service.RegionBackendServices.Update(project, region, backendServiceName, backendService).Do()
+	return c.service.RegionBackendServices.Update(project, region, backendServiceName, backendService).Context(c.context()).Do()
 }
 
 func (c *computeService) BackendServiceGet(project string, region string, backendServiceName string) (*compute.BackendService, error) {
-	return c.service.RegionBackendServices.Get(project, region, backendServiceName).Do()
+	return c.service.RegionBackendServices.Get(project, region, backendServiceName).Context(c.context()).Do()
+}
+
+// BackendServiceGetHealth returns the health of the endpoints of the group, an instance group or
+// network endpoint group URL, as seen by the health check of the regional backend service.
+func (c *computeService) BackendServiceGetHealth(project string, region string, backendServiceName string, group string) ([]*compute.HealthStatus, error) {
+	health, err := c.service.RegionBackendServices.GetHealth(project, region, backendServiceName, &compute.ResourceGroupReference{Group: group}).Context(c.context()).Do()
+	if err != nil {
+		return nil, err
+	}
+	return health.HealthStatus, nil
+}
+
+func (c *computeService) NetworkEndpointGroupGet(project string, zone string, name string) (*compute.NetworkEndpointGroup, error) {
+	return c.service.NetworkEndpointGroups.Get(project, zone, name).Context(c.context()).Do()
+}
+
+func (c *computeService) NetworkEndpointGroupInsert(project string, zone string, networkEndpointGroup *compute.NetworkEndpointGroup) (*compute.Operation, error) {
+	return c.service.NetworkEndpointGroups.Insert(project, zone, networkEndpointGroup).Context(c.context()).Do()
+}
+
+func (c *computeService) NetworkEndpointGroupsListNetworkEndpoints(project string, zone string, name string) ([]*compute.NetworkEndpoint, error) {
+	var endpoints []*compute.NetworkEndpoint
+	request := &compute.NetworkEndpointGroupsListEndpointsRequest{}
+	if err := c.service.NetworkEndpointGroups.ListNetworkEndpoints(project, zone, name, request).Pages(c.context(), func(page *compute.NetworkEndpointGroupsListNetworkEndpoints) error {
+		for _, item := range page.Items {
+			endpoints = append(endpoints, item.NetworkEndpoint)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (c *computeService) NetworkEndpointGroupsAttachNetworkEndpoints(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	request := &compute.NetworkEndpointGroupsAttachEndpointsRequest{NetworkEndpoints: endpoints}
+	return c.service.NetworkEndpointGroups.AttachNetworkEndpoints(project, zone, name, request).Context(c.context()).Do()
+}
+
+func (c *computeService) NetworkEndpointGroupsDetachNetworkEndpoints(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	request := &compute.NetworkEndpointGroupsDetachEndpointsRequest{NetworkEndpoints: endpoints}
+	return c.service.NetworkEndpointGroups.DetachNetworkEndpoints(project, zone, name, request).Context(c.context()).Do()
+}
+
+func (c *computeService) AddressesGet(project string, region string, name string) (*compute.Address, error) {
+	return c.service.Addresses.Get(project, region, name).Context(c.context()).Do()
+}
+
+func (c *computeService) AddressesInsert(project string, region string, address *compute.Address) (*compute.Operation, error) {
+	return c.service.Addresses.Insert(project, region, address).Context(c.context()).Do()
+}
+
+func (c *computeService) AddressesDelete(project string, region string, name string) (*compute.Operation, error) {
+	return c.service.Addresses.Delete(project, region, name).Context(c.context()).Do()
+}
+
+// AddressesList returns the addresses of the region matching the filter, reading all pages of
+// compute.Service.Addresses.List(...).
+func (c *computeService) AddressesList(project string, region string, filter string) ([]*compute.Address, error) {
+	var addresses []*compute.Address
+	if err := c.service.Addresses.List(project, region).Filter(filter).Pages(c.context(), func(page *compute.AddressList) error {
+		addresses = append(addresses, page.Items...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+func (c *computeService) ResourcePoliciesGet(project string, region string, name string) (*compute.ResourcePolicy, error) {
+	return c.service.ResourcePolicies.Get(project, region, name).Context(c.context()).Do()
+}
+
+// SubnetworksTestIamPermissions returns the permissions the caller has on the subnetwork among
+// the given ones.
+func (c *computeService) SubnetworksTestIamPermissions(project string, region string, subnetwork string, permissions []string) ([]string, error) {
+	response, err := c.service.Subnetworks.TestIamPermissions(project, region, subnetwork, &compute.TestPermissionsRequest{
+		Permissions: permissions,
+	}).Context(c.context()).Do()
+	if err != nil {
+		return nil, err
+	}
+	return response.Permissions, nil
+}
+
+func (c *computeService) SubnetworksGet(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+	return c.service.Subnetworks.Get(project, region, subnetwork).Context(c.context()).Do()
+}
+
+func (c *computeService) GlobalForwardingRulesList(project string, filter string) ([]*compute.ForwardingRule, error) {
+	var forwardingRules []*compute.ForwardingRule
+	if err := c.service.GlobalForwardingRules.List(project).Filter(filter).Pages(c.context(), func(page *compute.ForwardingRuleList) error {
+		forwardingRules = append(forwardingRules, page.Items...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return forwardingRules, nil
+}
+
+func (c *computeService) FirewallsGet(project string, firewall string) (*compute.Firewall, error) {
+	return c.service.Firewalls.Get(project, firewall).Context(c.context()).Do()
+}
+
+func (c *computeService) FirewallsPatch(project string, firewall string, firewallResource *compute.Firewall) (*compute.Operation, error) {
+	return c.service.Firewalls.Patch(project, firewall, firewallResource).Context(c.context()).Do()
+}
+
+// InstanceTemplatesInsert is a pass through wrapper for compute.Service.InstanceTemplates.Insert(...)
+func (c *computeService) InstanceTemplatesInsert(project string, instanceTemplate *compute.InstanceTemplate) (*compute.Operation, error) {
+	return c.service.InstanceTemplates.Insert(project, instanceTemplate).Context(c.context()).Do()
 }
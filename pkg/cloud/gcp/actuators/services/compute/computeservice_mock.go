@@ -10,6 +10,8 @@ import (
 )
 
 const (
+	// MockClusterID is the cluster the resources returned by the mock are labelled as owned by.
+	MockClusterID                  = "CLUSTERID"
 	NoMachinesInPool               = "NoMachinesInPool"
 	WithMachineInPool              = "WithMachineInPool"
 	GroupDoesNotExist              = "groupDoesNotExist"
@@ -26,10 +28,53 @@ const (
 )
 
 type GCPComputeServiceMock struct {
-	MockInstancesInsert   func(project string, zone string, instance *compute.Instance) (*compute.Operation, error)
-	MockMachineTypesGet   func(project string, zone string, machineType string) (*compute.MachineType, error)
-	mockZoneOperationsGet func(project string, zone string, operation string) (*compute.Operation, error)
-	mockInstancesGet      func(project string, zone string, instance string) (*compute.Instance, error)
+	MockInstancesInsert       func(project string, zone string, instance *compute.Instance) (*compute.Operation, error)
+	MockMachineTypesGet       func(project string, zone string, machineType string) (*compute.MachineType, error)
+	mockZoneOperationsGet     func(project string, zone string, operation string) (*compute.Operation, error)
+	mockInstancesGet          func(project string, zone string, instance string) (*compute.Instance, error)
+	MockAddressesGet          func(project string, region string, name string) (*compute.Address, error)
+	MockAddressesInsert       func(project string, region string, address *compute.Address) (*compute.Operation, error)
+	MockAddressesDelete       func(project string, region string, name string) (*compute.Operation, error)
+	MockResourcePoliciesGet   func(project string, region string, name string) (*compute.ResourcePolicy, error)
+	MockAcceleratorTypesList  func(project string, zone string, ctx context.Context) ([]*compute.AcceleratorType, error)
+	MockRegionGet             func(project string, region string) (*compute.Region, error)
+	MockNetworksGet           func(project string, network string) (*compute.Network, error)
+	MockZoneOperationsGet     func(project string, zone string, operation string) (*compute.Operation, error)
+	MockRegionOperationsGet   func(project string, region string, operation string) (*compute.Operation, error)
+	MockSerialPortOutput      func(project string, zone string, instance string, start int64) (*compute.SerialPortOutput, error)
+	MockSetIntegrityPolicy    func(project string, zone string, instance string, policy *compute.ShieldedInstanceIntegrityPolicy) (*compute.Operation, error)
+	MockDisksGet              func(project string, zone string, disk string) (*compute.Disk, error)
+	MockSimulateMaintenance   func(project string, zone string, instance string) (*compute.Operation, error)
+	MockImagesGet             func(project string, image string) (*compute.Image, error)
+	MockImagesGetFromFamily   func(project string, family string) (*compute.Image, error)
+	MockInstancesList         func(project string, filter string) ([]*compute.Instance, error)
+	MockSetLabels             func(project string, zone string, instance string, request *compute.InstancesSetLabelsRequest) (*compute.Operation, error)
+	MockSetTags               func(project string, zone string, instance string, tags *compute.Tags) (*compute.Operation, error)
+	MockSetMetadata           func(project string, zone string, instance string, metadata *compute.Metadata) (*compute.Operation, error)
+	MockInstancesStop         func(project string, zone string, instance string) (*compute.Operation, error)
+	MockInstancesStart        func(project string, zone string, instance string) (*compute.Operation, error)
+	MockSetMachineType        func(project string, zone string, instance string, request *compute.InstancesSetMachineTypeRequest) (*compute.Operation, error)
+	MockSetDeletionProtection func(project string, zone string, instance string, deletionProtection bool) (*compute.Operation, error)
+	MockDisksList             func(project string, zone string, filter string) ([]*compute.Disk, error)
+	MockZoneOperationsList    func(project string, zone string, filter string) ([]*compute.Operation, error)
+	MockDisksDelete           func(project string, zone string, disk string) (*compute.Operation, error)
+	MockAddressesList         func(project string, region string, filter string) ([]*compute.Address, error)
+	MockInstanceGroupGet      func(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error)
+	MockSetNamedPorts         func(project string, zone string, instanceGroupName string, request *compute.InstanceGroupsSetNamedPortsRequest) (*compute.Operation, error)
+	MockBackendServiceGet     func(project string, region string, backendServiceName string) (*compute.BackendService, error)
+	MockBackendServiceHealth  func(project string, region string, backendServiceName string, group string) ([]*compute.HealthStatus, error)
+	MockNEGGet                func(project string, zone string, name string) (*compute.NetworkEndpointGroup, error)
+	MockNEGInsert             func(project string, zone string, networkEndpointGroup *compute.NetworkEndpointGroup) (*compute.Operation, error)
+	MockNEGListEndpoints      func(project string, zone string, name string) ([]*compute.NetworkEndpoint, error)
+	MockNEGAttachEndpoints    func(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error)
+	MockNEGDetachEndpoints    func(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error)
+	MockSubnetworkPermissions func(project string, region string, subnetwork string, permissions []string) ([]string, error)
+	MockSubnetworksGet        func(project string, region string, subnetwork string) (*compute.Subnetwork, error)
+	MockForwardingRulesList   func(project string, filter string) ([]*compute.ForwardingRule, error)
+	MockFirewallsGet          func(project string, firewall string) (*compute.Firewall, error)
+	MockFirewallsPatch        func(project string, firewall string, firewallResource *compute.Firewall) (*compute.Operation, error)
+	MockInsertFromTemplate    func(project string, zone string, sourceInstanceTemplate string, instance *compute.Instance) (*compute.Operation, error)
+	MockTemplatesInsert       func(project string, instanceTemplate *compute.InstanceTemplate) (*compute.Operation, error)
 }
 
 func (c *GCPComputeServiceMock) InstancesInsert(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
@@ -39,19 +84,105 @@ func (c *GCPComputeServiceMock) InstancesInsert(project string, zone string, ins
 	return c.MockInstancesInsert(project, zone, instance)
 }
 
+// InstancesInsertFromTemplate inserts the instance like InstancesInsert unless mocked otherwise.
+func (c *GCPComputeServiceMock) InstancesInsertFromTemplate(project string, zone string, sourceInstanceTemplate string, instance *compute.Instance) (*compute.Operation, error) {
+	if c.MockInsertFromTemplate == nil {
+		return c.InstancesInsert(project, zone, instance)
+	}
+	return c.MockInsertFromTemplate(project, zone, sourceInstanceTemplate, instance)
+}
+
 func (c *GCPComputeServiceMock) InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error) {
 	return &compute.Operation{
 		Status: "DONE",
 	}, nil
 }
 
+func (c *GCPComputeServiceMock) InstancesGetSerialPortOutput(project string, zone string, instance string, start int64) (*compute.SerialPortOutput, error) {
+	if c.MockSerialPortOutput == nil {
+		return &compute.SerialPortOutput{Contents: "serial console output"}, nil
+	}
+	return c.MockSerialPortOutput(project, zone, instance, start)
+}
+
+func (c *GCPComputeServiceMock) InstancesSetShieldedInstanceIntegrityPolicy(project string, zone string, instance string, policy *compute.ShieldedInstanceIntegrityPolicy) (*compute.Operation, error) {
+	if c.MockSetIntegrityPolicy == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockSetIntegrityPolicy(project, zone, instance, policy)
+}
+
+func (c *GCPComputeServiceMock) DisksGet(project string, zone string, disk string) (*compute.Disk, error) {
+	if c.MockDisksGet == nil {
+		return &compute.Disk{
+			Name:          disk,
+			Zone:          zone,
+			SourceImage:   "projects/rhcos-cloud/global/images/rhcos",
+			SourceImageId: "1234567890",
+		}, nil
+	}
+	return c.MockDisksGet(project, zone, disk)
+}
+
+func (c *GCPComputeServiceMock) DisksList(project string, zone string, filter string) ([]*compute.Disk, error) {
+	if c.MockDisksList == nil {
+		return nil, nil
+	}
+	return c.MockDisksList(project, zone, filter)
+}
+
+func (c *GCPComputeServiceMock) ZoneOperationsList(project string, zone string, filter string) ([]*compute.Operation, error) {
+	if c.MockZoneOperationsList == nil {
+		return nil, nil
+	}
+	return c.MockZoneOperationsList(project, zone, filter)
+}
+
+func (c *GCPComputeServiceMock) DisksDelete(project string, zone string, disk string) (*compute.Operation, error) {
+	if c.MockDisksDelete == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockDisksDelete(project, zone, disk)
+}
+
+func (c *GCPComputeServiceMock) ImagesGet(project string, image string) (*compute.Image, error) {
+	if c.MockImagesGet == nil {
+		return &compute.Image{Name: image}, nil
+	}
+	return c.MockImagesGet(project, image)
+}
+
+func (c *GCPComputeServiceMock) ImagesGetFromFamily(project string, family string) (*compute.Image, error) {
+	if c.MockImagesGetFromFamily == nil {
+		return &compute.Image{Name: family, Family: family}, nil
+	}
+	return c.MockImagesGetFromFamily(project, family)
+}
+
+func (c *GCPComputeServiceMock) InstancesSimulateMaintenanceEvent(project string, zone string, instance string) (*compute.Operation, error) {
+	if c.MockSimulateMaintenance == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockSimulateMaintenance(project, zone, instance)
+}
+
 func (c *GCPComputeServiceMock) ZoneOperationsGet(project string, zone string, operation string) (*compute.Operation, error) {
+	if c.MockZoneOperationsGet != nil {
+		return c.MockZoneOperationsGet(project, zone, operation)
+	}
 	if c.mockZoneOperationsGet == nil {
 		return nil, nil
 	}
 	return c.mockZoneOperationsGet(project, zone, operation)
 }
 
+func (c *GCPComputeServiceMock) RegionOperationsGet(project string, region string, operation string) (*compute.Operation, error) {
+	if c.MockRegionOperationsGet == nil {
+		return &compute.Operation{Name: operation, Status: "DONE"}, nil
+	}
+	return c.MockRegionOperationsGet(project, region, operation)
+}
+
 func (c *GCPComputeServiceMock) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
 	if c.mockInstancesGet == nil {
 		return &compute.Instance{
@@ -59,6 +190,9 @@ func (c *GCPComputeServiceMock) InstancesGet(project string, zone string, instan
 			Zone:         zone,
 			MachineType:  "n1-standard-1",
 			CanIpForward: true,
+			Labels: map[string]string{
+				"kubernetes-io-cluster-" + MockClusterID: "owned",
+			},
 			NetworkInterfaces: []*compute.NetworkInterface{
 				{
 					NetworkIP: "10.0.0.15",
@@ -107,7 +241,12 @@ func (c *GCPComputeServiceMock) TargetPoolsRemoveInstance(project string, region
 
 func (c *GCPComputeServiceMock) MachineTypesGet(project string, zone string, machineType string) (*compute.MachineType, error) {
 	if c.MockMachineTypesGet == nil {
-		return nil, nil
+		return &compute.MachineType{
+			Name:      machineType,
+			Zone:      zone,
+			GuestCpus: 4,
+			MemoryMb:  16384,
+		}, nil
 	}
 	return c.MockMachineTypesGet(project, zone, machineType)
 }
@@ -146,13 +285,89 @@ func MockBuilderFuncTypeNotFound(serviceAccountJSON string) (GCPComputeService,
 }
 
 func (c *GCPComputeServiceMock) RegionGet(project string, region string) (*compute.Region, error) {
-	return &compute.Region{Quotas: nil}, nil
+	if c.MockRegionGet == nil {
+		return &compute.Region{Quotas: nil}, nil
+	}
+	return c.MockRegionGet(project, region)
+}
+
+func (c *GCPComputeServiceMock) NetworksGet(project string, network string) (*compute.Network, error) {
+	if c.MockNetworksGet == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	return c.MockNetworksGet(project, network)
 }
 
 func (c *GCPComputeServiceMock) GPUCompatibleMachineTypesList(project string, zone string, ctx context.Context) (map[string]int64, []string) {
 	var compatibleMachineType = []string{"n1-test-machineType"}
 	return nil, compatibleMachineType
 }
+func (c *GCPComputeServiceMock) InstancesAggregatedList(project string, filter string) ([]*compute.Instance, error) {
+	if c.MockInstancesList == nil {
+		return nil, nil
+	}
+	return c.MockInstancesList(project, filter)
+}
+
+func (c *GCPComputeServiceMock) InstancesSetLabels(project string, zone string, instance string, request *compute.InstancesSetLabelsRequest) (*compute.Operation, error) {
+	if c.MockSetLabels == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockSetLabels(project, zone, instance, request)
+}
+
+func (c *GCPComputeServiceMock) InstancesSetTags(project string, zone string, instance string, tags *compute.Tags) (*compute.Operation, error) {
+	if c.MockSetTags == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockSetTags(project, zone, instance, tags)
+}
+
+func (c *GCPComputeServiceMock) InstancesSetMetadata(project string, zone string, instance string, metadata *compute.Metadata) (*compute.Operation, error) {
+	if c.MockSetMetadata == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockSetMetadata(project, zone, instance, metadata)
+}
+
+func (c *GCPComputeServiceMock) InstancesStop(project string, zone string, instance string) (*compute.Operation, error) {
+	if c.MockInstancesStop == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockInstancesStop(project, zone, instance)
+}
+
+func (c *GCPComputeServiceMock) InstancesStart(project string, zone string, instance string) (*compute.Operation, error) {
+	if c.MockInstancesStart == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockInstancesStart(project, zone, instance)
+}
+
+func (c *GCPComputeServiceMock) InstancesSetMachineType(project string, zone string, instance string, request *compute.InstancesSetMachineTypeRequest) (*compute.Operation, error) {
+	if c.MockSetMachineType == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockSetMachineType(project, zone, instance, request)
+}
+
+func (c *GCPComputeServiceMock) InstancesSetDeletionProtection(project string, zone string, instance string, deletionProtection bool) (*compute.Operation, error) {
+	if c.MockSetDeletionProtection == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockSetDeletionProtection(project, zone, instance, deletionProtection)
+}
+
+func (c *GCPComputeServiceMock) AcceleratorTypesList(project string, zone string, ctx context.Context) ([]*compute.AcceleratorType, error) {
+	if c.MockAcceleratorTypesList == nil {
+		return []*compute.AcceleratorType{
+			{Name: "nvidia-tesla-t4", MaximumCardsPerInstance: 4},
+			{Name: "nvidia-tesla-v100", MaximumCardsPerInstance: 8},
+		}, nil
+	}
+	return c.MockAcceleratorTypesList(project, zone, ctx)
+}
+
 func (c *GCPComputeServiceMock) AcceleratorTypeGet(project string, zone string, acceleratorType string) (*compute.AcceleratorType, error) {
 	return nil, nil
 }
@@ -217,6 +432,9 @@ func (c *GCPComputeServiceMock) InstanceGroupInsert(project string, zone string,
 }
 
 func (c *GCPComputeServiceMock) InstanceGroupGet(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error) {
+	if c.MockInstanceGroupGet != nil {
+		return c.MockInstanceGroupGet(project, zone, instanceGroupName)
+	}
 	if project == ErrFailGroupGet {
 		return nil, errors.New("instanceGroupGet request failed")
 	}
@@ -226,6 +444,13 @@ func (c *GCPComputeServiceMock) InstanceGroupGet(project string, zone string, in
 	return nil, nil
 }
 
+func (c *GCPComputeServiceMock) InstanceGroupsSetNamedPorts(project string, zone string, instanceGroupName string, request *compute.InstanceGroupsSetNamedPortsRequest) (*compute.Operation, error) {
+	if c.MockSetNamedPorts == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockSetNamedPorts(project, zone, instanceGroupName, request)
+}
+
 func (c *GCPComputeServiceMock) AddInstanceGroupToBackendService(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
 	if project == ErrPatchingBackendService {
 		return nil, errors.New("failed to add new instanceGroup to backend service")
@@ -236,6 +461,9 @@ func (c *GCPComputeServiceMock) AddInstanceGroupToBackendService(project string,
 }
 
 func (c *GCPComputeServiceMock) BackendServiceGet(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+	if c.MockBackendServiceGet != nil {
+		return c.MockBackendServiceGet(project, region, backendServiceName)
+	}
 	if project == ErrGettingBackendService || project == ErrPatchingBackendService {
 		return nil, errors.New("failed to get the regional backend service")
 	}
@@ -247,3 +475,138 @@ func (c *GCPComputeServiceMock) BackendServiceGet(project string, region string,
 		},
 	}, nil
 }
+
+func (c *GCPComputeServiceMock) BackendServiceGetHealth(project string, region string, backendServiceName string, group string) ([]*compute.HealthStatus, error) {
+	if c.MockBackendServiceHealth == nil {
+		return nil, nil
+	}
+	return c.MockBackendServiceHealth(project, region, backendServiceName, group)
+}
+
+func (c *GCPComputeServiceMock) NetworkEndpointGroupGet(project string, zone string, name string) (*compute.NetworkEndpointGroup, error) {
+	if c.MockNEGGet == nil {
+		return &compute.NetworkEndpointGroup{
+			Name:                name,
+			Zone:                zone,
+			NetworkEndpointType: "GCE_VM_IP",
+			SelfLink:            fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/zones/%s/networkEndpointGroups/%s", project, zone, name),
+		}, nil
+	}
+	return c.MockNEGGet(project, zone, name)
+}
+
+func (c *GCPComputeServiceMock) NetworkEndpointGroupInsert(project string, zone string, networkEndpointGroup *compute.NetworkEndpointGroup) (*compute.Operation, error) {
+	if c.MockNEGInsert == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockNEGInsert(project, zone, networkEndpointGroup)
+}
+
+func (c *GCPComputeServiceMock) NetworkEndpointGroupsListNetworkEndpoints(project string, zone string, name string) ([]*compute.NetworkEndpoint, error) {
+	if c.MockNEGListEndpoints == nil {
+		return nil, nil
+	}
+	return c.MockNEGListEndpoints(project, zone, name)
+}
+
+func (c *GCPComputeServiceMock) NetworkEndpointGroupsAttachNetworkEndpoints(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	if c.MockNEGAttachEndpoints == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockNEGAttachEndpoints(project, zone, name, endpoints)
+}
+
+func (c *GCPComputeServiceMock) NetworkEndpointGroupsDetachNetworkEndpoints(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	if c.MockNEGDetachEndpoints == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockNEGDetachEndpoints(project, zone, name, endpoints)
+}
+
+func (c *GCPComputeServiceMock) AddressesGet(project string, region string, name string) (*compute.Address, error) {
+	if c.MockAddressesGet == nil {
+		return &compute.Address{
+			Name:        name,
+			Address:     "35.243.147.143",
+			AddressType: "EXTERNAL",
+			Region:      region,
+			Status:      "RESERVED",
+		}, nil
+	}
+	return c.MockAddressesGet(project, region, name)
+}
+
+func (c *GCPComputeServiceMock) AddressesInsert(project string, region string, address *compute.Address) (*compute.Operation, error) {
+	if c.MockAddressesInsert == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockAddressesInsert(project, region, address)
+}
+
+func (c *GCPComputeServiceMock) AddressesDelete(project string, region string, name string) (*compute.Operation, error) {
+	if c.MockAddressesDelete == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockAddressesDelete(project, region, name)
+}
+
+func (c *GCPComputeServiceMock) AddressesList(project string, region string, filter string) ([]*compute.Address, error) {
+	if c.MockAddressesList == nil {
+		return nil, nil
+	}
+	return c.MockAddressesList(project, region, filter)
+}
+
+func (c *GCPComputeServiceMock) ResourcePoliciesGet(project string, region string, name string) (*compute.ResourcePolicy, error) {
+	if c.MockResourcePoliciesGet == nil {
+		return &compute.ResourcePolicy{
+			Name:                   name,
+			Region:                 region,
+			SnapshotSchedulePolicy: &compute.ResourcePolicySnapshotSchedulePolicy{},
+		}, nil
+	}
+	return c.MockResourcePoliciesGet(project, region, name)
+}
+
+// SubnetworksTestIamPermissions grants all permissions unless mocked.
+func (c *GCPComputeServiceMock) SubnetworksTestIamPermissions(project string, region string, subnetwork string, permissions []string) ([]string, error) {
+	if c.MockSubnetworkPermissions == nil {
+		return permissions, nil
+	}
+	return c.MockSubnetworkPermissions(project, region, subnetwork, permissions)
+}
+
+func (c *GCPComputeServiceMock) SubnetworksGet(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+	if c.MockSubnetworksGet == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	return c.MockSubnetworksGet(project, region, subnetwork)
+}
+
+func (c *GCPComputeServiceMock) GlobalForwardingRulesList(project string, filter string) ([]*compute.ForwardingRule, error) {
+	if c.MockForwardingRulesList == nil {
+		return nil, nil
+	}
+	return c.MockForwardingRulesList(project, filter)
+}
+
+func (c *GCPComputeServiceMock) FirewallsGet(project string, firewall string) (*compute.Firewall, error) {
+	if c.MockFirewallsGet == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	return c.MockFirewallsGet(project, firewall)
+}
+
+func (c *GCPComputeServiceMock) FirewallsPatch(project string, firewall string, firewallResource *compute.Firewall) (*compute.Operation, error) {
+	if c.MockFirewallsPatch == nil {
+		return nil, nil
+	}
+	return c.MockFirewallsPatch(project, firewall, firewallResource)
+}
+
+func (c *GCPComputeServiceMock) InstanceTemplatesInsert(project string, instanceTemplate *compute.InstanceTemplate) (*compute.Operation, error) {
+	if c.MockTemplatesInsert == nil {
+		return nil, nil
+	}
+	return c.MockTemplatesInsert(project, instanceTemplate)
+}
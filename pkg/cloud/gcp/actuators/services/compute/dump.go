@@ -0,0 +1,65 @@
+package computeservice
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"sync/atomic"
+
+	"k8s.io/klog/v2"
+)
+
+// maxDumpSize bounds the size of a dumped request or response, instance insertions carry the
+// whole user data.
+const maxDumpSize = 64 * 1024
+
+var requestDumping atomic.Bool
+
+// SetRequestDumping enables or disables logging the HTTP requests and responses of all compute
+// services, including the ones already built. The Authorization header is redacted, request
+// bodies are logged as is.
+func SetRequestDumping(enabled bool) {
+	requestDumping.Store(enabled)
+}
+
+// dumpingTransport logs the requests and responses passing through it while request dumping is enabled.
+type dumpingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *dumpingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !requestDumping.Load() {
+		return t.base.RoundTrip(req)
+	}
+
+	redacted := req.Clone(req.Context())
+	if redacted.Header.Get("Authorization") != "" {
+		redacted.Header.Set("Authorization", "REDACTED")
+	}
+	redacted.Body = req.Body
+	if dump, err := httputil.DumpRequestOut(redacted, true); err != nil {
+		klog.Errorf("Failed to dump compute API request %s %s: %v", req.Method, req.URL, err)
+	} else {
+		// Dumping consumed the body, the redacted request holds a copy of it.
+		req.Body = redacted.Body
+		klog.Infof("Compute API request:\n%s", truncateDump(dump))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		klog.Infof("Compute API request %s %s failed: %v", req.Method, req.URL, err)
+		return resp, err
+	}
+	if dump, err := httputil.DumpResponse(resp, true); err != nil {
+		klog.Errorf("Failed to dump compute API response of %s %s: %v", req.Method, req.URL, err)
+	} else {
+		klog.Infof("Compute API response of %s %s:\n%s", req.Method, req.URL, truncateDump(dump))
+	}
+	return resp, nil
+}
+
+func truncateDump(dump []byte) []byte {
+	if len(dump) <= maxDumpSize {
+		return dump
+	}
+	return append(dump[:maxDumpSize:maxDumpSize], "\n... (truncated)"...)
+}
@@ -0,0 +1,63 @@
+package computeservice
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestDumpingTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("echo: " + string(body)))
+	}))
+	defer server.Close()
+
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	fs.Set("logtostderr", "false")
+	defer fs.Set("logtostderr", "true")
+	var logs bytes.Buffer
+	klog.SetOutput(&logs)
+	defer klog.SetOutput(io.Discard)
+
+	client := &http.Client{Transport: &dumpingTransport{base: http.DefaultTransport}}
+	send := func() string {
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer secret-token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if body := send(); body != "echo: payload" || logs.Len() != 0 {
+		t.Errorf("Expected the request to pass through without logs, got %q and logs %q", body, logs.String())
+	}
+
+	SetRequestDumping(true)
+	defer SetRequestDumping(false)
+	if body := send(); body != "echo: payload" {
+		t.Errorf("Expected the request and response bodies to be preserved, got %q", body)
+	}
+	klog.Flush()
+	dump := logs.String()
+	if !strings.Contains(dump, "payload") || !strings.Contains(dump, "echo: payload") {
+		t.Errorf("Expected the request and response to be dumped, got %q", dump)
+	}
+	if strings.Contains(dump, "secret-token") {
+		t.Errorf("Expected the Authorization header to be redacted, got %q", dump)
+	}
+}
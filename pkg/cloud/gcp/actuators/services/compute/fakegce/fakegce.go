@@ -0,0 +1,329 @@
+// Package fakegce is a stateful fake of the compute API for the instances and addresses of a
+// project. Unlike the compute service mock it remembers what was created and deleted, so that leaks
+// and duplicate creations are observable. It is used in-process by the crash-consistency tests and
+// served over HTTP by the fake-gce binary, so that the provider can be run locally end to end.
+package fakegce
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"k8s.io/utils/clock"
+)
+
+const (
+	instanceLinkFmt    = "https://www.googleapis.com/compute/v1/projects/%s/zones/%s/instances/%s"
+	zoneLinkFmt        = "https://www.googleapis.com/compute/v1/projects/%s/zones/%s"
+	regionLinkFmt      = "https://www.googleapis.com/compute/v1/projects/%s/regions/%s"
+	addressStatusInUse = "IN_USE"
+)
+
+// Config configures the behaviour of the fake beyond its state.
+type Config struct {
+	// OperationLatency is how long operations are reported as running before they are done.
+	// Zero completes them on the first poll.
+	OperationLatency time.Duration
+	// Faults are the errors the fake injects, see FaultProfiles. Optional.
+	Faults *FaultProfile
+	// Seed seeds the random injection of faults, so that a run can be repeated.
+	Seed int64
+	// Clock is used for the operation latency. Defaults to the real clock.
+	Clock clock.Clock
+}
+
+// FakeGCE fakes the compute API calls that create and delete instances and addresses, and the
+// operations they return. Calls it does not fake are served by the compute service mock. It is
+// safe for concurrent use.
+type FakeGCE struct {
+	*computeservice.GCPComputeServiceMock
+
+	config Config
+	mu     sync.Mutex
+	random *rand.Rand
+
+	instances  map[string]*compute.Instance
+	addresses  map[string]*compute.Address
+	operations map[string]*operation
+	// deleteRequests are the operations of the instance deletions by request ID, since the
+	// compute API returns the operation of the first request when a request ID is reused.
+	deleteRequests map[string]*compute.Operation
+	// calls counts the calls per method, including the failed ones.
+	calls map[string]int
+}
+
+// operation is an operation of the fake, done once the operation latency passed.
+type operation struct {
+	done   *compute.Operation
+	doneAt time.Time
+}
+
+// New returns a fake without resources.
+func New(config Config) *FakeGCE {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	_, mock := computeservice.NewComputeServiceMock()
+	return &FakeGCE{
+		GCPComputeServiceMock: mock,
+		config:                config,
+		random:                rand.New(rand.NewSource(config.Seed)),
+		instances:             map[string]*compute.Instance{},
+		addresses:             map[string]*compute.Address{},
+		operations:            map[string]*operation{},
+		deleteRequests:        map[string]*compute.Operation{},
+		calls:                 map[string]int{},
+	}
+}
+
+// Instances returns the instances of the fake by name.
+func (f *FakeGCE) Instances() map[string]*compute.Instance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	instances := make(map[string]*compute.Instance, len(f.instances))
+	for name, instance := range f.instances {
+		instances[name] = instance
+	}
+	return instances
+}
+
+// Addresses returns the addresses of the fake by name.
+func (f *FakeGCE) Addresses() map[string]*compute.Address {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	addresses := make(map[string]*compute.Address, len(f.addresses))
+	for name, address := range f.addresses {
+		addresses[name] = address
+	}
+	return addresses
+}
+
+// Calls returns the number of calls of the given method, including the failed ones.
+func (f *FakeGCE) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// Mutations returns the number of calls that created or deleted resources.
+func (f *FakeGCE) Mutations() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls["InstancesInsert"] + f.calls["InstancesDelete"] + f.calls["AddressesInsert"] + f.calls["AddressesDelete"]
+}
+
+// call locks the fake for a call of the given method and counts it. It returns the error the fault
+// profile injects into the call, if any.
+func (f *FakeGCE) call(method string) error {
+	f.mu.Lock()
+	f.calls[method]++
+	return f.config.Faults.callError(f.random)
+}
+
+// newOperation returns an operation that completes successfully, or with the given error, once the
+// operation latency passed. It is returned as running to the caller, like the compute API does.
+func (f *FakeGCE) newOperation(kind, target, zoneOrRegionLink string, regional bool, errs *compute.OperationError) *compute.Operation {
+	name := fmt.Sprintf("operation-%d-%s-%s", len(f.operations), kind, target)
+	done := &compute.Operation{Name: name, OperationType: kind, Status: "DONE", Error: errs}
+	if regional {
+		done.Region = zoneOrRegionLink
+	} else {
+		done.Zone = zoneOrRegionLink
+	}
+	f.operations[name] = &operation{done: done, doneAt: f.config.Clock.Now().Add(f.config.OperationLatency)}
+
+	running := *done
+	running.Status = "RUNNING"
+	running.Error = nil
+	return &running
+}
+
+// getOperation returns the operation of the given name as running until the operation latency passed.
+func (f *FakeGCE) getOperation(name string, regional bool) (*compute.Operation, error) {
+	found, ok := f.operations[name]
+	if !ok || regional && found.done.Region == "" || !regional && found.done.Zone == "" {
+		return nil, notFound("operations", name)
+	}
+	if f.config.Clock.Now().Before(found.doneAt) {
+		running := *found.done
+		running.Status = "RUNNING"
+		running.Error = nil
+		return &running, nil
+	}
+	return found.done, nil
+}
+
+func notFound(kind, name string) error {
+	return &googleapi.Error{Code: http.StatusNotFound, Message: fmt.Sprintf("The resource '%s %s' was not found", kind, name)}
+}
+
+func (f *FakeGCE) InstancesInsert(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
+	err := f.call("InstancesInsert")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.instances[instance.Name]; ok {
+		return nil, &googleapi.Error{Code: http.StatusConflict, Message: fmt.Sprintf("The resource 'instances/%s' already exists", instance.Name)}
+	}
+	zoneLink := fmt.Sprintf(zoneLinkFmt, project, zone)
+	if errs := f.config.Faults.insertError(f.random); errs != nil {
+		// The instance is not created, the error is reported by the operation.
+		return f.newOperation("insert", instance.Name, zoneLink, false, errs), nil
+	}
+
+	created := *instance
+	created.Id = uint64(len(f.instances) + 1)
+	created.Status = "RUNNING"
+	created.Zone = zoneLink
+	created.SelfLink = fmt.Sprintf(instanceLinkFmt, project, zone, instance.Name)
+	created.CreationTimestamp = f.config.Clock.Now().UTC().Format(time.RFC3339)
+	for i, nic := range created.NetworkInterfaces {
+		if nic.NetworkIP == "" {
+			nic.NetworkIP = fmt.Sprintf("10.0.0.%d", 10+i)
+		}
+		for _, address := range f.addresses {
+			if address.Address == nic.NetworkIP {
+				address.Status = addressStatusInUse
+				address.Users = []string{created.SelfLink}
+			}
+		}
+	}
+	f.instances[instance.Name] = &created
+	return f.newOperation("insert", instance.Name, created.Zone, false, nil), nil
+}
+
+// InstancesInsertFromTemplate inserts the instance as if the template had no properties, the fake
+// does not know instance templates.
+func (f *FakeGCE) InstancesInsertFromTemplate(project string, zone string, sourceInstanceTemplate string, instance *compute.Instance) (*compute.Operation, error) {
+	return f.InstancesInsert(project, zone, instance)
+}
+
+func (f *FakeGCE) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
+	err := f.call("InstancesGet")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	found, ok := f.instances[instance]
+	if !ok {
+		return nil, notFound("instances", instance)
+	}
+	return found, nil
+}
+
+// InstancesAggregatedList returns all instances, the filter is ignored.
+func (f *FakeGCE) InstancesAggregatedList(project string, filter string) ([]*compute.Instance, error) {
+	err := f.call("InstancesAggregatedList")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(f.instances))
+	for name := range f.instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	instances := make([]*compute.Instance, 0, len(names))
+	for _, name := range names {
+		instances = append(instances, f.instances[name])
+	}
+	return instances, nil
+}
+
+func (f *FakeGCE) InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error) {
+	err := f.call("InstancesDelete")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if operation, ok := f.deleteRequests[requestId]; ok && requestId != "" {
+		return operation, nil
+	}
+	deleted, ok := f.instances[instance]
+	if !ok {
+		return nil, notFound("instances", instance)
+	}
+	delete(f.instances, instance)
+	for _, address := range f.addresses {
+		if len(address.Users) > 0 && address.Users[0] == deleted.SelfLink {
+			address.Status = "RESERVED"
+			address.Users = nil
+		}
+	}
+
+	operation := f.newOperation("delete", instance, deleted.Zone, false, nil)
+	f.deleteRequests[requestId] = operation
+	return operation, nil
+}
+
+func (f *FakeGCE) ZoneOperationsGet(project string, zone string, operation string) (*compute.Operation, error) {
+	err := f.call("ZoneOperationsGet")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return f.getOperation(operation, false)
+}
+
+func (f *FakeGCE) RegionOperationsGet(project string, region string, operation string) (*compute.Operation, error) {
+	err := f.call("RegionOperationsGet")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return f.getOperation(operation, true)
+}
+
+func (f *FakeGCE) AddressesGet(project string, region string, name string) (*compute.Address, error) {
+	err := f.call("AddressesGet")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	found, ok := f.addresses[name]
+	if !ok {
+		return nil, notFound("addresses", name)
+	}
+	return found, nil
+}
+
+func (f *FakeGCE) AddressesInsert(project string, region string, address *compute.Address) (*compute.Operation, error) {
+	err := f.call("AddressesInsert")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.addresses[address.Name]; ok {
+		return nil, &googleapi.Error{Code: http.StatusConflict, Message: fmt.Sprintf("The resource 'addresses/%s' already exists", address.Name)}
+	}
+
+	created := *address
+	created.Status = "RESERVED"
+	created.Address = fmt.Sprintf("10.0.1.%d", 10+len(f.addresses))
+	f.addresses[address.Name] = &created
+	return f.newOperation("insert", address.Name, fmt.Sprintf(regionLinkFmt, project, region), true, nil), nil
+}
+
+func (f *FakeGCE) AddressesDelete(project string, region string, name string) (*compute.Operation, error) {
+	err := f.call("AddressesDelete")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	found, ok := f.addresses[name]
+	if !ok {
+		return nil, notFound("addresses", name)
+	}
+	if found.Status == addressStatusInUse {
+		return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("The address resource '%s' is already being used", name)}
+	}
+	delete(f.addresses, name)
+	return f.newOperation("delete", name, fmt.Sprintf(regionLinkFmt, project, region), true, nil), nil
+}
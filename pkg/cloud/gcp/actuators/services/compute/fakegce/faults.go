@@ -0,0 +1,98 @@
+package fakegce
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// FaultProfile describes the errors the fake injects, to exercise the error handling of the
+// provider the way the compute API fails in practice.
+type FaultProfile struct {
+	// Name is the name of the profile.
+	Name string
+	// CallErrorRate is the fraction, between 0 and 1, of calls that fail with CallError.
+	CallErrorRate float64
+	// CallError is the error failed calls return.
+	CallError *googleapi.Error
+	// InsertErrorRate is the fraction, between 0 and 1, of instance inserts whose operation fails
+	// with InsertError, without creating the instance.
+	InsertErrorRate float64
+	// InsertError is the error of failed insert operations.
+	InsertError *compute.OperationErrorErrors
+}
+
+// FaultProfiles are the predefined fault profiles by name.
+var FaultProfiles = map[string]*FaultProfile{
+	"none": {Name: "none"},
+	"flaky": {
+		Name:          "flaky",
+		CallErrorRate: 0.1,
+		CallError: &googleapi.Error{
+			Code:    http.StatusServiceUnavailable,
+			Message: "The service is currently unavailable.",
+			Errors:  []googleapi.ErrorItem{{Reason: "backendError", Message: "The service is currently unavailable."}},
+		},
+	},
+	"rate-limited": {
+		Name:          "rate-limited",
+		CallErrorRate: 0.2,
+		CallError: &googleapi.Error{
+			Code:    http.StatusForbidden,
+			Message: "Rate Limit Exceeded",
+			Errors:  []googleapi.ErrorItem{{Reason: "rateLimitExceeded", Message: "Rate Limit Exceeded"}},
+		},
+	},
+	"stockout": {
+		Name:            "stockout",
+		InsertErrorRate: 0.5,
+		InsertError: &compute.OperationErrorErrors{
+			Code:    "ZONE_RESOURCE_POOL_EXHAUSTED",
+			Message: "The zone does not have enough resources available to fulfill the request.",
+		},
+	},
+	"quota": {
+		Name:            "quota",
+		InsertErrorRate: 1,
+		InsertError: &compute.OperationErrorErrors{
+			Code:    "QUOTA_EXCEEDED",
+			Message: "Quota 'CPUS' exceeded.",
+		},
+	},
+}
+
+// ParseFaultProfile returns the predefined fault profile of the given name.
+func ParseFaultProfile(name string) (*FaultProfile, error) {
+	if profile, ok := FaultProfiles[name]; ok {
+		return profile, nil
+	}
+	names := make([]string, 0, len(FaultProfiles))
+	for name := range FaultProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown fault profile %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// callError returns the error to fail a call with, if any.
+func (p *FaultProfile) callError(random *rand.Rand) error {
+	if p == nil || p.CallError == nil || random.Float64() >= p.CallErrorRate {
+		return nil
+	}
+	err := *p.CallError
+	return &err
+}
+
+// insertError returns the error of the operation of an instance insert, if any.
+func (p *FaultProfile) insertError(random *rand.Rand) *compute.OperationError {
+	if p == nil || p.InsertError == nil || random.Float64() >= p.InsertErrorRate {
+		return nil
+	}
+	err := *p.InsertError
+	return &compute.OperationError{Errors: []*compute.OperationErrorErrors{&err}}
+}
@@ -0,0 +1,302 @@
+package fakegce
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"k8s.io/klog/v2"
+)
+
+// BasePath is the path the fake serves the compute API at. Clients use the URL of the server with
+// this path as endpoint.
+const BasePath = "/compute/v1/"
+
+// errNotImplemented is returned for the calls of the compute API the server does not serve.
+var errNotImplemented = &googleapi.Error{
+	Code:    http.StatusNotImplemented,
+	Message: "not implemented by the fake GCE server",
+	Errors:  []googleapi.ErrorItem{{Reason: "notImplemented"}},
+}
+
+// Handler serves the compute API calls of the provider over HTTP, the way the compute API client
+// sends them, from the fake or, for the calls the fake does not fake, from the compute service mock.
+func (f *FakeGCE) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		response, err := f.serve(req)
+		if err != nil {
+			klog.V(2).Infof("%s %s: %v", req.Method, req.URL.Path, err)
+			writeError(w, err)
+			return
+		}
+		klog.V(4).Infof("%s %s", req.Method, req.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			klog.Errorf("Failed to write response of %s %s: %v", req.Method, req.URL.Path, err)
+		}
+	})
+}
+
+// serve routes the request, e.g. GET /compute/v1/projects/p/zones/z/instances/i, to the method of
+// the compute service it was sent by.
+func (f *FakeGCE) serve(req *http.Request) (interface{}, error) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, BasePath), "/"), "/")
+	if len(segments) < 3 || segments[0] != "projects" {
+		return nil, errNotImplemented
+	}
+	project, scope, path := segments[1], segments[2], segments[3:]
+	query := req.URL.Query()
+	route := req.Method + " " + scope + strings.Repeat("/*", len(path))
+
+	switch scope {
+	case "zones":
+		if len(path) == 0 {
+			return nil, errNotImplemented
+		}
+		return f.serveZonal(req, route, project, path, query.Get)
+	case "regions":
+		if len(path) == 0 {
+			return nil, errNotImplemented
+		}
+		return f.serveRegional(req, route, project, path, query.Get)
+	case "aggregated":
+		if route == "GET aggregated/*" && path[0] == "instances" {
+			instances, err := f.InstancesAggregatedList(project, query.Get("filter"))
+			if err != nil {
+				return nil, err
+			}
+			return &compute.InstanceAggregatedList{Items: map[string]compute.InstancesScopedList{"zones": {Instances: instances}}}, nil
+		}
+	case "global":
+		switch {
+		case route == "GET global/*/*" && path[0] == "images":
+			return f.ImagesGet(project, path[1])
+		case route == "GET global/*/*/*" && path[0] == "images" && path[1] == "family":
+			return f.ImagesGetFromFamily(project, path[2])
+		case route == "GET global/*/*" && path[0] == "firewalls":
+			return f.FirewallsGet(project, path[1])
+		case route == "PATCH global/*/*" && path[0] == "firewalls":
+			firewall := &compute.Firewall{}
+			if err := decode(req, firewall); err != nil {
+				return nil, err
+			}
+			return operationOrDone(f.FirewallsPatch(project, path[1], firewall))
+		case route == "GET global/*" && path[0] == "forwardingRules":
+			forwardingRules, err := f.GlobalForwardingRulesList(project, query.Get("filter"))
+			if err != nil {
+				return nil, err
+			}
+			return &compute.ForwardingRuleList{Items: forwardingRules}, nil
+		}
+	}
+	return nil, errNotImplemented
+}
+
+func (f *FakeGCE) serveZonal(req *http.Request, route, project string, path []string, query func(string) string) (interface{}, error) {
+	zone := path[0]
+	switch route {
+	case "GET zones/*":
+		return f.ZonesGet(project, zone)
+	case "POST zones/*/*":
+		if path[1] == "instances" {
+			instance := &compute.Instance{}
+			if err := decode(req, instance); err != nil {
+				return nil, err
+			}
+			if template := query("sourceInstanceTemplate"); template != "" {
+				return f.InstancesInsertFromTemplate(project, zone, template, instance)
+			}
+			return f.InstancesInsert(project, zone, instance)
+		}
+	case "GET zones/*/*":
+		if path[1] == "disks" {
+			disks, err := f.DisksList(project, zone, query("filter"))
+			if err != nil {
+				return nil, err
+			}
+			return &compute.DiskList{Items: disks}, nil
+		}
+	case "GET zones/*/*/*":
+		switch path[1] {
+		case "instances":
+			return f.InstancesGet(project, zone, path[2])
+		case "operations":
+			return f.ZoneOperationsGet(project, zone, path[2])
+		case "machineTypes":
+			return f.MachineTypesGet(project, zone, path[2])
+		case "disks":
+			return f.DisksGet(project, zone, path[2])
+		}
+	case "DELETE zones/*/*/*":
+		switch path[1] {
+		case "instances":
+			return f.InstancesDelete(query("requestId"), project, zone, path[2])
+		case "disks":
+			return f.DisksDelete(project, zone, path[2])
+		}
+	case "GET zones/*/*/*/*":
+		if path[1] == "instances" && path[3] == "serialPort" {
+			start, _ := strconv.ParseInt(query("start"), 10, 64)
+			return f.InstancesGetSerialPortOutput(project, zone, path[2], start)
+		}
+	case "POST zones/*/*/*/*":
+		if path[1] == "instances" {
+			return f.serveInstanceAction(req, project, zone, path[2], path[3], query)
+		}
+	}
+	return nil, errNotImplemented
+}
+
+func (f *FakeGCE) serveInstanceAction(req *http.Request, project, zone, instance, action string, query func(string) string) (interface{}, error) {
+	switch action {
+	case "stop":
+		return f.InstancesStop(project, zone, instance)
+	case "start":
+		return f.InstancesStart(project, zone, instance)
+	case "simulateMaintenanceEvent":
+		return f.InstancesSimulateMaintenanceEvent(project, zone, instance)
+	case "setDeletionProtection":
+		// The compute API defaults the protection to true.
+		return f.InstancesSetDeletionProtection(project, zone, instance, query("deletionProtection") != "false")
+	case "setLabels":
+		request := &compute.InstancesSetLabelsRequest{}
+		if err := decode(req, request); err != nil {
+			return nil, err
+		}
+		return f.InstancesSetLabels(project, zone, instance, request)
+	case "setTags":
+		tags := &compute.Tags{}
+		if err := decode(req, tags); err != nil {
+			return nil, err
+		}
+		return f.InstancesSetTags(project, zone, instance, tags)
+	case "setMetadata":
+		metadata := &compute.Metadata{}
+		if err := decode(req, metadata); err != nil {
+			return nil, err
+		}
+		return f.InstancesSetMetadata(project, zone, instance, metadata)
+	case "setMachineType":
+		request := &compute.InstancesSetMachineTypeRequest{}
+		if err := decode(req, request); err != nil {
+			return nil, err
+		}
+		return f.InstancesSetMachineType(project, zone, instance, request)
+	}
+	return nil, errNotImplemented
+}
+
+func (f *FakeGCE) serveRegional(req *http.Request, route, project string, path []string, query func(string) string) (interface{}, error) {
+	region := path[0]
+	switch route {
+	case "GET regions/*":
+		return f.RegionGet(project, region)
+	case "POST regions/*/*":
+		if path[1] == "addresses" {
+			address := &compute.Address{}
+			if err := decode(req, address); err != nil {
+				return nil, err
+			}
+			return f.AddressesInsert(project, region, address)
+		}
+	case "GET regions/*/*":
+		if path[1] == "addresses" {
+			addresses, err := f.AddressesList(project, region, query("filter"))
+			if err != nil {
+				return nil, err
+			}
+			return &compute.AddressList{Items: addresses}, nil
+		}
+	case "GET regions/*/*/*":
+		switch path[1] {
+		case "subnetworks":
+			return f.SubnetworksGet(project, region, path[2])
+		case "addresses":
+			return f.AddressesGet(project, region, path[2])
+		case "operations":
+			return f.RegionOperationsGet(project, region, path[2])
+		case "targetPools":
+			return f.TargetPoolsGet(project, region, path[2])
+		case "resourcePolicies":
+			return f.ResourcePoliciesGet(project, region, path[2])
+		}
+	case "DELETE regions/*/*/*":
+		if path[1] == "addresses" {
+			return f.AddressesDelete(project, region, path[2])
+		}
+	case "POST regions/*/*/*/*":
+		if path[1] == "targetPools" && (path[3] == "addInstance" || path[3] == "removeInstance") {
+			request := &compute.TargetPoolsAddInstanceRequest{}
+			if err := decode(req, request); err != nil {
+				return nil, err
+			}
+			if len(request.Instances) != 1 {
+				return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: "expected exactly one instance"}
+			}
+			if path[3] == "addInstance" {
+				return operationOrDone(f.TargetPoolsAddInstance(project, region, path[2], request.Instances[0].Instance))
+			}
+			return operationOrDone(f.TargetPoolsRemoveInstance(project, region, path[2], request.Instances[0].Instance))
+		}
+		if path[1] == "subnetworks" && path[3] == "testIamPermissions" {
+			request := &compute.TestPermissionsRequest{}
+			if err := decode(req, request); err != nil {
+				return nil, err
+			}
+			permissions, err := f.SubnetworksTestIamPermissions(project, region, path[2], request.Permissions)
+			if err != nil {
+				return nil, err
+			}
+			return &compute.TestPermissionsResponse{Permissions: permissions}, nil
+		}
+	}
+	return nil, errNotImplemented
+}
+
+// operationOrDone replaces the nil operations the compute service mock returns by a done one.
+func operationOrDone(operation *compute.Operation, err error) (*compute.Operation, error) {
+	if operation == nil && err == nil {
+		operation = &compute.Operation{Status: "DONE"}
+	}
+	return operation, err
+}
+
+func decode(req *http.Request, out interface{}) error {
+	if err := json.NewDecoder(req.Body).Decode(out); err != nil {
+		return &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("invalid request body: %v", err)}
+	}
+	return nil
+}
+
+// writeError writes the error in the format of the compute API, so that the client returns it as
+// a *googleapi.Error.
+func writeError(w http.ResponseWriter, err error) {
+	apiErr := &googleapi.Error{}
+	if !errors.As(err, &apiErr) {
+		apiErr = &googleapi.Error{Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+	body := map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    apiErr.Code,
+			"message": apiErr.Message,
+			"errors":  apiErr.Errors,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		klog.Errorf("Failed to write error response: %v", err)
+	}
+}
+
+// NewClientBuilder returns a builder of compute services that call the fake served at the given
+// URL, e.g. by the fake-gce binary, without authentication.
+func NewClientBuilder(url string) computeservice.BuilderFuncType {
+	return computeservice.NewEndpointBuilder(strings.TrimSuffix(url, "/") + BasePath)
+}
@@ -0,0 +1,112 @@
+package fakegce
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestServer(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	gce := New(Config{OperationLatency: time.Minute, Clock: fakeClock})
+	server := httptest.NewServer(gce.Handler())
+	defer server.Close()
+	client, err := NewClientBuilder(server.URL)("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operation, err := client.InstancesInsert("test", "us-east1-b", &compute.Instance{Name: "worker-0", Labels: map[string]string{"role": "worker"}})
+	if err != nil {
+		t.Fatalf("Unexpected error inserting instance: %v", err)
+	}
+	if operation.Status != "RUNNING" {
+		t.Errorf("Expected the insert to be running, got %s", operation.Status)
+	}
+	if operation, err = client.ZoneOperationsGet("test", "us-east1-b", operation.Name); err != nil || operation.Status != "RUNNING" {
+		t.Errorf("Expected the insert to be running until the operation latency passed, got %v, %v", operation, err)
+	}
+	fakeClock.Step(time.Minute)
+	if operation, err = client.ZoneOperationsGet("test", "us-east1-b", operation.Name); err != nil || operation.Status != "DONE" {
+		t.Errorf("Expected the insert to be done, got %v, %v", operation, err)
+	}
+
+	instance, err := client.InstancesGet("test", "us-east1-b", "worker-0")
+	if err != nil {
+		t.Fatalf("Unexpected error getting instance: %v", err)
+	}
+	if instance.Status != "RUNNING" || instance.Labels["role"] != "worker" {
+		t.Errorf("Expected the running instance with its labels, got %+v", instance)
+	}
+	if instances, err := client.InstancesAggregatedList("test", ""); err != nil || len(instances) != 1 {
+		t.Errorf("Expected the instance to be listed, got %v, %v", instances, err)
+	}
+
+	// A machine type is served by the compute service mock.
+	if machineType, err := client.MachineTypesGet("test", "us-east1-b", "n2-standard-4"); err != nil || machineType.Name != "n2-standard-4" {
+		t.Errorf("Expected the machine type, got %v, %v", machineType, err)
+	}
+
+	first, err := client.InstancesDelete("request", "test", "us-east1-b", "worker-0")
+	if err != nil {
+		t.Fatalf("Unexpected error deleting instance: %v", err)
+	}
+	if repeated, err := client.InstancesDelete("request", "test", "us-east1-b", "worker-0"); err != nil || repeated.Name != first.Name {
+		t.Errorf("Expected the operation of the first deletion for the same request ID, got %v, %v", repeated, err)
+	}
+	_, err = client.InstancesGet("test", "us-east1-b", "worker-0")
+	apiErr := &googleapi.Error{}
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Errorf("Expected the deleted instance not to be found, got %v", err)
+	}
+	if gce.Mutations() != 3 {
+		t.Errorf("Expected 3 mutations, got %d", gce.Mutations())
+	}
+
+	_, err = client.InstanceGroupGet("test", "us-east1-b", "group")
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotImplemented {
+		t.Errorf("Expected calls the server does not serve to fail, got %v", err)
+	}
+}
+
+func TestFaultProfiles(t *testing.T) {
+	for _, name := range []string{"quota", "rate-limited"} {
+		t.Run(name, func(t *testing.T) {
+			faults, err := ParseFaultProfile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gce := New(Config{Faults: faults})
+			var failedCalls, failedInserts int
+			for i := 0; i < 100; i++ {
+				operation, err := gce.InstancesInsert("test", "us-east1-b", &compute.Instance{Name: fmt.Sprintf("worker-%d", i)})
+				switch {
+				case err != nil:
+					failedCalls++
+				case operation != nil:
+					done, _ := gce.ZoneOperationsGet("test", "us-east1-b", operation.Name)
+					if done != nil && done.Error != nil {
+						failedInserts++
+					}
+				}
+			}
+			if name == "quota" && (failedInserts != 100 || len(gce.Instances()) != 0) {
+				t.Errorf("Expected every insert to fail without creating an instance, got %d failures and %d instances", failedInserts, len(gce.Instances()))
+			}
+			if name == "rate-limited" && (failedCalls == 0 || failedCalls > 50) {
+				t.Errorf("Expected some calls to be rate limited, got %d", failedCalls)
+			}
+		})
+	}
+
+	if _, err := ParseFaultProfile("unknown"); err == nil {
+		t.Errorf("Expected an error for an unknown fault profile")
+	}
+}
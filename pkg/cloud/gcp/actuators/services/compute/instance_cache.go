@@ -0,0 +1,246 @@
+package computeservice
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultInstanceCacheTTL is how long a running instance is served from the cache by default.
+	DefaultInstanceCacheTTL = 10 * time.Second
+
+	runningInstanceStatus = "RUNNING"
+)
+
+// instanceCache holds the instances recently fetched by the compute services of a builder.
+type instanceCache struct {
+	clock clock.Clock
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	entries map[string]instanceCacheEntry
+}
+
+type instanceCacheEntry struct {
+	instance  *compute.Instance
+	fetchedAt time.Time
+}
+
+func instanceCacheKey(project, zone, name string) string {
+	return path.Join(project, zone, name)
+}
+
+func (c *instanceCache) get(key string) (*compute.Instance, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.clock.Since(entry.fetchedAt) >= c.ttl {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.instance, true
+}
+
+func (c *instanceCache) set(key string, instance *compute.Instance) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Expired entries of instances that are no longer read, e.g. deleted ones, are dropped here.
+	now := c.clock.Now()
+	for k, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = instanceCacheEntry{instance: instance, fetchedAt: now}
+}
+
+// update caches the running instances of a list of the project started at listedAt, and drops the
+// cached instances of the project fetched before that are not in the list. They were deleted or do
+// not match the filter of the list, in which case the next lookup fetches them again.
+func (c *instanceCache) update(project string, listedAt time.Time, instances []*compute.Instance) {
+	listed := make(map[string]*compute.Instance, len(instances))
+	for _, instance := range instances {
+		listed[instanceCacheKey(project, path.Base(instance.Zone), instance.Name)] = instance
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if _, ok := listed[key]; !ok && strings.HasPrefix(key, project+"/") && entry.fetchedAt.Before(listedAt) {
+			delete(c.entries, key)
+		}
+	}
+	now := c.clock.Now()
+	for key, instance := range listed {
+		if instance.Status != runningInstanceStatus {
+			delete(c.entries, key)
+			continue
+		}
+		if cached, err := copyInstance(instance); err == nil {
+			c.entries[key] = instanceCacheEntry{instance: cached, fetchedAt: now}
+		}
+	}
+}
+
+func (c *instanceCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// NewInstanceCachingBuilder returns a builder whose compute services serve InstancesGet of running
+// instances from a cache shared by all of them for up to ttl, so that the repeated lookups of the
+// existence checks and reconciles of a machine do not each cost a read of the project API quota.
+// Instances in any other state are always fetched, since the reconciler waits for them to change,
+// and calls that change an instance through the services drop it from the cache. Changes made
+// outside the controller, e.g. an instance deleted in the console, are seen after at most ttl, or
+// as soon as an aggregated list of the instances of the project no longer returns the instance.
+// The builder is returned as is if ttl is not positive.
+func NewInstanceCachingBuilder(builder BuilderFuncType, ttl time.Duration, clk clock.Clock) BuilderFuncType {
+	if ttl <= 0 {
+		return builder
+	}
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	cache := &instanceCache{clock: clk, ttl: ttl, entries: map[string]instanceCacheEntry{}}
+	return func(serviceAccountJSON string) (GCPComputeService, error) {
+		service, err := builder(serviceAccountJSON)
+		if err != nil {
+			return nil, err
+		}
+		return &instanceCachingService{GCPComputeService: service, cache: cache}, nil
+	}
+}
+
+// instanceCachingService serves InstancesGet from the instance cache and passes all other calls through.
+type instanceCachingService struct {
+	GCPComputeService
+	cache *instanceCache
+}
+
+func (s *instanceCachingService) withContext(ctx context.Context) GCPComputeService {
+	return &instanceCachingService{GCPComputeService: WithContext(ctx, s.GCPComputeService), cache: s.cache}
+}
+
+func (s *instanceCachingService) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
+	key := instanceCacheKey(project, zone, instance)
+	if cached, ok := s.cache.get(key); ok {
+		instanceCacheRequestsTotal.WithLabelValues("hit").Inc()
+		return copyInstance(cached)
+	}
+	instanceCacheRequestsTotal.WithLabelValues("miss").Inc()
+
+	fetched, err := s.GCPComputeService.InstancesGet(project, zone, instance)
+	if err != nil || fetched == nil || fetched.Status != runningInstanceStatus {
+		s.cache.invalidate(key)
+		return fetched, err
+	}
+	cached, err := copyInstance(fetched)
+	if err != nil {
+		klog.Warningf("Not caching instance %s: %v", key, err)
+		return fetched, nil
+	}
+	s.cache.set(key, cached)
+	return fetched, nil
+}
+
+// InstancesAggregatedList lists the instances from the compute API and refreshes the cache with them.
+func (s *instanceCachingService) InstancesAggregatedList(project string, filter string) ([]*compute.Instance, error) {
+	listedAt := s.cache.clock.Now()
+	instances, err := s.GCPComputeService.InstancesAggregatedList(project, filter)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.update(project, listedAt, instances)
+	return instances, nil
+}
+
+func (s *instanceCachingService) InstancesInsert(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
+	if instance != nil {
+		defer s.cache.invalidate(instanceCacheKey(project, zone, instance.Name))
+	}
+	return s.GCPComputeService.InstancesInsert(project, zone, instance)
+}
+
+func (s *instanceCachingService) InstancesInsertFromTemplate(project string, zone string, sourceInstanceTemplate string, instance *compute.Instance) (*compute.Operation, error) {
+	if instance != nil {
+		defer s.cache.invalidate(instanceCacheKey(project, zone, instance.Name))
+	}
+	return s.GCPComputeService.InstancesInsertFromTemplate(project, zone, sourceInstanceTemplate, instance)
+}
+
+func (s *instanceCachingService) InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesDelete(requestId, project, zone, instance)
+}
+
+func (s *instanceCachingService) InstancesSetShieldedInstanceIntegrityPolicy(project string, zone string, instance string, policy *compute.ShieldedInstanceIntegrityPolicy) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesSetShieldedInstanceIntegrityPolicy(project, zone, instance, policy)
+}
+
+func (s *instanceCachingService) InstancesSimulateMaintenanceEvent(project string, zone string, instance string) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesSimulateMaintenanceEvent(project, zone, instance)
+}
+
+func (s *instanceCachingService) InstancesSetLabels(project string, zone string, instance string, request *compute.InstancesSetLabelsRequest) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesSetLabels(project, zone, instance, request)
+}
+
+func (s *instanceCachingService) InstancesSetTags(project string, zone string, instance string, tags *compute.Tags) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesSetTags(project, zone, instance, tags)
+}
+
+func (s *instanceCachingService) InstancesSetMetadata(project string, zone string, instance string, metadata *compute.Metadata) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesSetMetadata(project, zone, instance, metadata)
+}
+
+func (s *instanceCachingService) InstancesStop(project string, zone string, instance string) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesStop(project, zone, instance)
+}
+
+func (s *instanceCachingService) InstancesStart(project string, zone string, instance string) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesStart(project, zone, instance)
+}
+
+func (s *instanceCachingService) InstancesSetMachineType(project string, zone string, instance string, request *compute.InstancesSetMachineTypeRequest) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesSetMachineType(project, zone, instance, request)
+}
+
+func (s *instanceCachingService) InstancesSetDeletionProtection(project string, zone string, instance string, deletionProtection bool) (*compute.Operation, error) {
+	defer s.cache.invalidate(instanceCacheKey(project, zone, instance))
+	return s.GCPComputeService.InstancesSetDeletionProtection(project, zone, instance, deletionProtection)
+}
+
+// copyInstance returns a deep copy of the instance, so that callers changing the instance they got
+// do not change the cached one.
+func copyInstance(instance *compute.Instance) (*compute.Instance, error) {
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return nil, err
+	}
+	copied := &compute.Instance{}
+	if err := json.Unmarshal(data, copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
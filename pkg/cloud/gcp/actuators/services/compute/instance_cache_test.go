@@ -0,0 +1,148 @@
+package computeservice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestInstanceCachingBuilder(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	_, mock := NewComputeServiceMock()
+	gets := 0
+	status := "RUNNING"
+	var getErr error
+	mock.mockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+		gets++
+		if getErr != nil {
+			return nil, getErr
+		}
+		return &compute.Instance{Name: instance, Status: status, Labels: map[string]string{"foo": "bar"}}, nil
+	}
+	builder := NewInstanceCachingBuilder(func(string) (GCPComputeService, error) {
+		return mock, nil
+	}, 10*time.Second, clock)
+	service, _ := builder("key-1")
+	// Services built for other credentials share the cache.
+	other, _ := builder("key-2")
+
+	steps := []struct {
+		name         string
+		service      GCPComputeService
+		before       func()
+		expectedGets int
+	}{
+		{
+			name:         "First lookup is fetched",
+			service:      service,
+			expectedGets: 1,
+		},
+		{
+			name:         "Running instance is served from the cache",
+			service:      other,
+			before:       func() { clock.Step(5 * time.Second) },
+			expectedGets: 1,
+		},
+		{
+			name:         "Expired entry is fetched",
+			service:      service,
+			before:       func() { clock.Step(5 * time.Second) },
+			expectedGets: 2,
+		},
+		{
+			name:    "Changed instance is fetched",
+			service: service,
+			before: func() {
+				service.InstancesSetShieldedInstanceIntegrityPolicy("project", "zone", "instance", &compute.ShieldedInstanceIntegrityPolicy{})
+			},
+			expectedGets: 3,
+		},
+		{
+			name:         "Instances not running are not cached",
+			service:      service,
+			before:       func() { clock.Step(time.Minute); status = "STOPPING" },
+			expectedGets: 4,
+		},
+		{
+			name:         "Instances not running are fetched",
+			service:      service,
+			expectedGets: 5,
+		},
+		{
+			name:         "Errors are not cached",
+			service:      service,
+			before:       func() { status = "RUNNING"; getErr = errors.New("not found") },
+			expectedGets: 6,
+		},
+		{
+			name:         "Lookup after an error is fetched",
+			service:      service,
+			before:       func() { getErr = nil },
+			expectedGets: 7,
+		},
+		{
+			name:         "Deleted instance is fetched",
+			service:      service,
+			before:       func() { service.InstancesDelete("", "project", "zone", "instance") },
+			expectedGets: 8,
+		},
+	}
+
+	for _, step := range steps {
+		if step.before != nil {
+			step.before()
+		}
+		instance, err := step.service.InstancesGet("project", "zone", "instance")
+		if gets != step.expectedGets {
+			t.Errorf("%s: expected %d compute API lookups, got %d", step.name, step.expectedGets, gets)
+		}
+		if err == nil && (instance.Name != "instance" || instance.Labels["foo"] != "bar") {
+			t.Errorf("%s: unexpected instance %+v", step.name, instance)
+		}
+		// Callers may change the instances they get without changing the cache.
+		if instance != nil {
+			instance.Labels["foo"] = "changed"
+		}
+	}
+}
+
+func TestInstanceCachingBuilderAggregatedList(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	_, mock := NewComputeServiceMock()
+	gets := 0
+	mock.mockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+		gets++
+		return &compute.Instance{Name: instance, Status: "RUNNING"}, nil
+	}
+	var listed []*compute.Instance
+	mock.MockInstancesList = func(project string, filter string) ([]*compute.Instance, error) {
+		return listed, nil
+	}
+	service, _ := NewInstanceCachingBuilder(func(string) (GCPComputeService, error) {
+		return mock, nil
+	}, 10*time.Second, clock)("key")
+
+	// Running instances of a list are cached.
+	listed = []*compute.Instance{{Name: "a", Zone: "https://www.googleapis.com/compute/v1/projects/project/zones/zone", Status: "RUNNING"}}
+	if _, err := service.InstancesAggregatedList("project", ""); err != nil {
+		t.Fatal(err)
+	}
+	service.InstancesGet("project", "zone", "a")
+	if gets != 0 {
+		t.Errorf("Expected the listed instance to be served from the cache, got %d lookups", gets)
+	}
+
+	// Cached instances missing from a later list are dropped.
+	clock.Step(time.Second)
+	listed = nil
+	if _, err := service.InstancesAggregatedList("project", ""); err != nil {
+		t.Fatal(err)
+	}
+	service.InstancesGet("project", "zone", "a")
+	if gets != 1 {
+		t.Errorf("Expected the instance missing from the list to be fetched, got %d lookups", gets)
+	}
+}
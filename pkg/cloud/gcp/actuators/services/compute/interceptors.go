@@ -0,0 +1,511 @@
+package computeservice
+
+import (
+	"context"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// CallInterceptor is invoked around every compute API call made through a service returned by
+// WithInterceptors. ctx is the context of the call, see WithContext, and method is the name of the
+// GCPComputeService method being called. An interceptor may refuse the call by returning an error
+// without invoking call, and must not wait past the deadline of ctx.
+type CallInterceptor func(ctx context.Context, method string, call func() error) error
+
+// WithInterceptors returns a GCPComputeService that passes every API call of the given service
+// through the interceptors. The first interceptor is the outermost one, nil interceptors are skipped.
+func WithInterceptors(service GCPComputeService, interceptors ...CallInterceptor) GCPComputeService {
+	var active []CallInterceptor
+	for _, interceptor := range interceptors {
+		if interceptor != nil {
+			active = append(active, interceptor)
+		}
+	}
+	if len(active) == 0 {
+		return service
+	}
+	return &interceptedComputeService{service: service, interceptors: active}
+}
+
+// NewInterceptingBuilder returns a builder whose compute services pass every API call through the
+// interceptors, see WithInterceptors.
+func NewInterceptingBuilder(builder BuilderFuncType, interceptors ...CallInterceptor) BuilderFuncType {
+	return func(serviceAccountJSON string) (GCPComputeService, error) {
+		service, err := builder(serviceAccountJSON)
+		if err != nil {
+			return nil, err
+		}
+		return WithInterceptors(service, interceptors...), nil
+	}
+}
+
+// MethodKind tells whether a GCPComputeService method only reads resources or changes them.
+type MethodKind string
+
+const (
+	ReadMethod     MethodKind = "read"
+	MutationMethod MethodKind = "mutation"
+)
+
+// methodKinds is the kind of every method passed through the interceptors. The retry and rate
+// limit interceptors rely on it, so methods added to GCPComputeService must be listed here.
+var methodKinds = map[string]MethodKind{
+	"InstancesGet":                                ReadMethod,
+	"InstancesGetSerialPortOutput":                ReadMethod,
+	"DisksGet":                                    ReadMethod,
+	"DisksList":                                   ReadMethod,
+	"ZoneOperationsList":                          ReadMethod,
+	"ImagesGet":                                   ReadMethod,
+	"ImagesGetFromFamily":                         ReadMethod,
+	"ZonesGet":                                    ReadMethod,
+	"ZoneOperationsGet":                           ReadMethod,
+	"RegionOperationsGet":                         ReadMethod,
+	"TargetPoolsGet":                              ReadMethod,
+	"MachineTypesGet":                             ReadMethod,
+	"RegionGet":                                   ReadMethod,
+	"NetworksGet":                                 ReadMethod,
+	"GPUCompatibleMachineTypesList":               ReadMethod,
+	"AcceleratorTypeGet":                          ReadMethod,
+	"InstancesAggregatedList":                     ReadMethod,
+	"AcceleratorTypesList":                        ReadMethod,
+	"InstanceGroupsListInstances":                 ReadMethod,
+	"InstanceGroupGet":                            ReadMethod,
+	"BackendServiceGet":                           ReadMethod,
+	"BackendServiceGetHealth":                     ReadMethod,
+	"NetworkEndpointGroupGet":                     ReadMethod,
+	"NetworkEndpointGroupsListNetworkEndpoints":   ReadMethod,
+	"AddressesGet":                                ReadMethod,
+	"AddressesList":                               ReadMethod,
+	"ResourcePoliciesGet":                         ReadMethod,
+	"SubnetworksTestIamPermissions":               ReadMethod,
+	"SubnetworksGet":                              ReadMethod,
+	"GlobalForwardingRulesList":                   ReadMethod,
+	"FirewallsGet":                                ReadMethod,
+	"InstancesDelete":                             MutationMethod,
+	"InstancesInsert":                             MutationMethod,
+	"InstancesInsertFromTemplate":                 MutationMethod,
+	"InstancesSetShieldedInstanceIntegrityPolicy": MutationMethod,
+	"DisksDelete":                                 MutationMethod,
+	"InstancesSimulateMaintenanceEvent":           MutationMethod,
+	"TargetPoolsAddInstance":                      MutationMethod,
+	"TargetPoolsRemoveInstance":                   MutationMethod,
+	"InstancesSetLabels":                          MutationMethod,
+	"InstancesSetTags":                            MutationMethod,
+	"InstancesSetMetadata":                        MutationMethod,
+	"InstancesStop":                               MutationMethod,
+	"InstancesStart":                              MutationMethod,
+	"InstancesSetMachineType":                     MutationMethod,
+	"InstancesSetDeletionProtection":              MutationMethod,
+	"InstanceGroupsAddInstances":                  MutationMethod,
+	"InstanceGroupsRemoveInstances":               MutationMethod,
+	"InstanceGroupInsert":                         MutationMethod,
+	"InstanceGroupsSetNamedPorts":                 MutationMethod,
+	"AddInstanceGroupToBackendService":            MutationMethod,
+	"NetworkEndpointGroupInsert":                  MutationMethod,
+	"NetworkEndpointGroupsAttachNetworkEndpoints": MutationMethod,
+	"NetworkEndpointGroupsDetachNetworkEndpoints": MutationMethod,
+	"AddressesInsert":                             MutationMethod,
+	"AddressesDelete":                             MutationMethod,
+	"FirewallsPatch":                              MutationMethod,
+	"InstanceTemplatesInsert":                     MutationMethod,
+}
+
+// IsReadMethod returns true if the GCPComputeService method only reads resources. Unknown methods
+// are treated as mutations, which are neither retried on server errors nor counted as reads.
+func IsReadMethod(method string) bool {
+	return methodKinds[method] == ReadMethod
+}
+
+type interceptedComputeService struct {
+	service      GCPComputeService
+	interceptors []CallInterceptor
+	// ctx is the context passed to the interceptors, nil means context.TODO().
+	ctx context.Context
+}
+
+func (c *interceptedComputeService) withContext(ctx context.Context) GCPComputeService {
+	return &interceptedComputeService{service: WithContext(ctx, c.service), interceptors: c.interceptors, ctx: ctx}
+}
+
+func (c *interceptedComputeService) intercept(method string, call func() error) error {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.interceptors[i], call
+		call = func() error { return interceptor(ctx, method, next) }
+	}
+	return call()
+}
+
+func interceptCall[T any](c *interceptedComputeService, method string, call func() (T, error)) (T, error) {
+	var out T
+	err := c.intercept(method, func() error {
+		var err error
+		out, err = call()
+		return err
+	})
+	return out, err
+}
+
+func (c *interceptedComputeService) InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesDelete", func() (*compute.Operation, error) {
+		return c.service.InstancesDelete(requestId, project, zone, instance)
+	})
+}
+
+func (c *interceptedComputeService) InstancesInsert(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesInsert", func() (*compute.Operation, error) {
+		return c.service.InstancesInsert(project, zone, instance)
+	})
+}
+
+func (c *interceptedComputeService) InstancesInsertFromTemplate(project string, zone string, sourceInstanceTemplate string, instance *compute.Instance) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesInsertFromTemplate", func() (*compute.Operation, error) {
+		return c.service.InstancesInsertFromTemplate(project, zone, sourceInstanceTemplate, instance)
+	})
+}
+
+func (c *interceptedComputeService) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
+	return interceptCall(c, "InstancesGet", func() (*compute.Instance, error) {
+		return c.service.InstancesGet(project, zone, instance)
+	})
+}
+
+func (c *interceptedComputeService) InstancesGetSerialPortOutput(project string, zone string, instance string, start int64) (*compute.SerialPortOutput, error) {
+	return interceptCall(c, "InstancesGetSerialPortOutput", func() (*compute.SerialPortOutput, error) {
+		return c.service.InstancesGetSerialPortOutput(project, zone, instance, start)
+	})
+}
+
+func (c *interceptedComputeService) InstancesSetShieldedInstanceIntegrityPolicy(project string, zone string, instance string, policy *compute.ShieldedInstanceIntegrityPolicy) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesSetShieldedInstanceIntegrityPolicy", func() (*compute.Operation, error) {
+		return c.service.InstancesSetShieldedInstanceIntegrityPolicy(project, zone, instance, policy)
+	})
+}
+
+func (c *interceptedComputeService) DisksGet(project string, zone string, disk string) (*compute.Disk, error) {
+	return interceptCall(c, "DisksGet", func() (*compute.Disk, error) {
+		return c.service.DisksGet(project, zone, disk)
+	})
+}
+
+func (c *interceptedComputeService) DisksList(project string, zone string, filter string) ([]*compute.Disk, error) {
+	return interceptCall(c, "DisksList", func() ([]*compute.Disk, error) {
+		return c.service.DisksList(project, zone, filter)
+	})
+}
+
+func (c *interceptedComputeService) ZoneOperationsList(project string, zone string, filter string) ([]*compute.Operation, error) {
+	return interceptCall(c, "ZoneOperationsList", func() ([]*compute.Operation, error) {
+		return c.service.ZoneOperationsList(project, zone, filter)
+	})
+}
+
+func (c *interceptedComputeService) DisksDelete(project string, zone string, disk string) (*compute.Operation, error) {
+	return interceptCall(c, "DisksDelete", func() (*compute.Operation, error) {
+		return c.service.DisksDelete(project, zone, disk)
+	})
+}
+
+func (c *interceptedComputeService) ImagesGet(project string, image string) (*compute.Image, error) {
+	return interceptCall(c, "ImagesGet", func() (*compute.Image, error) {
+		return c.service.ImagesGet(project, image)
+	})
+}
+
+func (c *interceptedComputeService) ImagesGetFromFamily(project string, family string) (*compute.Image, error) {
+	return interceptCall(c, "ImagesGetFromFamily", func() (*compute.Image, error) {
+		return c.service.ImagesGetFromFamily(project, family)
+	})
+}
+
+func (c *interceptedComputeService) InstancesSimulateMaintenanceEvent(project string, zone string, instance string) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesSimulateMaintenanceEvent", func() (*compute.Operation, error) {
+		return c.service.InstancesSimulateMaintenanceEvent(project, zone, instance)
+	})
+}
+
+func (c *interceptedComputeService) ZonesGet(project string, zone string) (*compute.Zone, error) {
+	return interceptCall(c, "ZonesGet", func() (*compute.Zone, error) {
+		return c.service.ZonesGet(project, zone)
+	})
+}
+
+func (c *interceptedComputeService) ZoneOperationsGet(project string, zone string, operation string) (*compute.Operation, error) {
+	return interceptCall(c, "ZoneOperationsGet", func() (*compute.Operation, error) {
+		return c.service.ZoneOperationsGet(project, zone, operation)
+	})
+}
+
+func (c *interceptedComputeService) RegionOperationsGet(project string, region string, operation string) (*compute.Operation, error) {
+	return interceptCall(c, "RegionOperationsGet", func() (*compute.Operation, error) {
+		return c.service.RegionOperationsGet(project, region, operation)
+	})
+}
+
+// BasePath does not call the API and is not intercepted.
+func (c *interceptedComputeService) BasePath() string {
+	return c.service.BasePath()
+}
+
+func (c *interceptedComputeService) TargetPoolsGet(project string, region string, name string) (*compute.TargetPool, error) {
+	return interceptCall(c, "TargetPoolsGet", func() (*compute.TargetPool, error) {
+		return c.service.TargetPoolsGet(project, region, name)
+	})
+}
+
+func (c *interceptedComputeService) TargetPoolsAddInstance(project string, region string, name string, instance string) (*compute.Operation, error) {
+	return interceptCall(c, "TargetPoolsAddInstance", func() (*compute.Operation, error) {
+		return c.service.TargetPoolsAddInstance(project, region, name, instance)
+	})
+}
+
+func (c *interceptedComputeService) TargetPoolsRemoveInstance(project string, region string, name string, instance string) (*compute.Operation, error) {
+	return interceptCall(c, "TargetPoolsRemoveInstance", func() (*compute.Operation, error) {
+		return c.service.TargetPoolsRemoveInstance(project, region, name, instance)
+	})
+}
+
+func (c *interceptedComputeService) MachineTypesGet(project string, machineType string, zone string) (*compute.MachineType, error) {
+	return interceptCall(c, "MachineTypesGet", func() (*compute.MachineType, error) {
+		return c.service.MachineTypesGet(project, machineType, zone)
+	})
+}
+
+func (c *interceptedComputeService) RegionGet(project string, region string) (*compute.Region, error) {
+	return interceptCall(c, "RegionGet", func() (*compute.Region, error) {
+		return c.service.RegionGet(project, region)
+	})
+}
+
+func (c *interceptedComputeService) NetworksGet(project string, network string) (*compute.Network, error) {
+	return interceptCall(c, "NetworksGet", func() (*compute.Network, error) {
+		return c.service.NetworksGet(project, network)
+	})
+}
+
+// GPUCompatibleMachineTypesList cannot report errors, empty results are returned when an
+// interceptor refuses the call.
+func (c *interceptedComputeService) GPUCompatibleMachineTypesList(project string, zone string, ctx context.Context) (map[string]int64, []string) {
+	var (
+		a2MachineFamily map[string]int64
+		n1MachineFamily []string
+	)
+	_ = c.intercept("GPUCompatibleMachineTypesList", func() error {
+		a2MachineFamily, n1MachineFamily = c.service.GPUCompatibleMachineTypesList(project, zone, ctx)
+		return nil
+	})
+	return a2MachineFamily, n1MachineFamily
+}
+
+func (c *interceptedComputeService) AcceleratorTypeGet(project string, zone string, acceleratorType string) (*compute.AcceleratorType, error) {
+	return interceptCall(c, "AcceleratorTypeGet", func() (*compute.AcceleratorType, error) {
+		return c.service.AcceleratorTypeGet(project, zone, acceleratorType)
+	})
+}
+
+func (c *interceptedComputeService) InstancesAggregatedList(project string, filter string) ([]*compute.Instance, error) {
+	return interceptCall(c, "InstancesAggregatedList", func() ([]*compute.Instance, error) {
+		return c.service.InstancesAggregatedList(project, filter)
+	})
+}
+
+func (c *interceptedComputeService) InstancesSetLabels(project string, zone string, instance string, request *compute.InstancesSetLabelsRequest) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesSetLabels", func() (*compute.Operation, error) {
+		return c.service.InstancesSetLabels(project, zone, instance, request)
+	})
+}
+
+func (c *interceptedComputeService) InstancesSetTags(project string, zone string, instance string, tags *compute.Tags) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesSetTags", func() (*compute.Operation, error) {
+		return c.service.InstancesSetTags(project, zone, instance, tags)
+	})
+}
+
+func (c *interceptedComputeService) InstancesSetMetadata(project string, zone string, instance string, metadata *compute.Metadata) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesSetMetadata", func() (*compute.Operation, error) {
+		return c.service.InstancesSetMetadata(project, zone, instance, metadata)
+	})
+}
+
+func (c *interceptedComputeService) InstancesStop(project string, zone string, instance string) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesStop", func() (*compute.Operation, error) {
+		return c.service.InstancesStop(project, zone, instance)
+	})
+}
+
+func (c *interceptedComputeService) InstancesStart(project string, zone string, instance string) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesStart", func() (*compute.Operation, error) {
+		return c.service.InstancesStart(project, zone, instance)
+	})
+}
+
+func (c *interceptedComputeService) InstancesSetMachineType(project string, zone string, instance string, request *compute.InstancesSetMachineTypeRequest) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesSetMachineType", func() (*compute.Operation, error) {
+		return c.service.InstancesSetMachineType(project, zone, instance, request)
+	})
+}
+
+func (c *interceptedComputeService) InstancesSetDeletionProtection(project string, zone string, instance string, deletionProtection bool) (*compute.Operation, error) {
+	return interceptCall(c, "InstancesSetDeletionProtection", func() (*compute.Operation, error) {
+		return c.service.InstancesSetDeletionProtection(project, zone, instance, deletionProtection)
+	})
+}
+
+func (c *interceptedComputeService) AcceleratorTypesList(project string, zone string, ctx context.Context) ([]*compute.AcceleratorType, error) {
+	return interceptCall(c, "AcceleratorTypesList", func() ([]*compute.AcceleratorType, error) {
+		return c.service.AcceleratorTypesList(project, zone, ctx)
+	})
+}
+
+func (c *interceptedComputeService) InstanceGroupsListInstances(project string, zone string, instanceGroup string, request *compute.InstanceGroupsListInstancesRequest) (*compute.InstanceGroupsListInstances, error) {
+	return interceptCall(c, "InstanceGroupsListInstances", func() (*compute.InstanceGroupsListInstances, error) {
+		return c.service.InstanceGroupsListInstances(project, zone, instanceGroup, request)
+	})
+}
+
+func (c *interceptedComputeService) InstanceGroupsAddInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
+	return interceptCall(c, "InstanceGroupsAddInstances", func() (*compute.Operation, error) {
+		return c.service.InstanceGroupsAddInstances(project, zone, instance, instanceGroup)
+	})
+}
+
+func (c *interceptedComputeService) InstanceGroupsRemoveInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
+	return interceptCall(c, "InstanceGroupsRemoveInstances", func() (*compute.Operation, error) {
+		return c.service.InstanceGroupsRemoveInstances(project, zone, instance, instanceGroup)
+	})
+}
+
+func (c *interceptedComputeService) InstanceGroupInsert(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error) {
+	return interceptCall(c, "InstanceGroupInsert", func() (*compute.Operation, error) {
+		return c.service.InstanceGroupInsert(project, zone, instanceGroup)
+	})
+}
+
+func (c *interceptedComputeService) InstanceGroupGet(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error) {
+	return interceptCall(c, "InstanceGroupGet", func() (*compute.InstanceGroup, error) {
+		return c.service.InstanceGroupGet(project, zone, instanceGroupName)
+	})
+}
+
+func (c *interceptedComputeService) InstanceGroupsSetNamedPorts(project string, zone string, instanceGroupName string, request *compute.InstanceGroupsSetNamedPortsRequest) (*compute.Operation, error) {
+	return interceptCall(c, "InstanceGroupsSetNamedPorts", func() (*compute.Operation, error) {
+		return c.service.InstanceGroupsSetNamedPorts(project, zone, instanceGroupName, request)
+	})
+}
+
+func (c *interceptedComputeService) AddInstanceGroupToBackendService(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+	return interceptCall(c, "AddInstanceGroupToBackendService", func() (*compute.Operation, error) {
+		return c.service.AddInstanceGroupToBackendService(project, region, backendServiceName, backendService)
+	})
+}
+
+func (c *interceptedComputeService) BackendServiceGet(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+	return interceptCall(c, "BackendServiceGet", func() (*compute.BackendService, error) {
+		return c.service.BackendServiceGet(project, region, backendServiceName)
+	})
+}
+
+func (c *interceptedComputeService) BackendServiceGetHealth(project string, region string, backendServiceName string, group string) ([]*compute.HealthStatus, error) {
+	return interceptCall(c, "BackendServiceGetHealth", func() ([]*compute.HealthStatus, error) {
+		return c.service.BackendServiceGetHealth(project, region, backendServiceName, group)
+	})
+}
+
+func (c *interceptedComputeService) NetworkEndpointGroupGet(project string, zone string, name string) (*compute.NetworkEndpointGroup, error) {
+	return interceptCall(c, "NetworkEndpointGroupGet", func() (*compute.NetworkEndpointGroup, error) {
+		return c.service.NetworkEndpointGroupGet(project, zone, name)
+	})
+}
+
+func (c *interceptedComputeService) NetworkEndpointGroupInsert(project string, zone string, networkEndpointGroup *compute.NetworkEndpointGroup) (*compute.Operation, error) {
+	return interceptCall(c, "NetworkEndpointGroupInsert", func() (*compute.Operation, error) {
+		return c.service.NetworkEndpointGroupInsert(project, zone, networkEndpointGroup)
+	})
+}
+
+func (c *interceptedComputeService) NetworkEndpointGroupsListNetworkEndpoints(project string, zone string, name string) ([]*compute.NetworkEndpoint, error) {
+	return interceptCall(c, "NetworkEndpointGroupsListNetworkEndpoints", func() ([]*compute.NetworkEndpoint, error) {
+		return c.service.NetworkEndpointGroupsListNetworkEndpoints(project, zone, name)
+	})
+}
+
+func (c *interceptedComputeService) NetworkEndpointGroupsAttachNetworkEndpoints(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	return interceptCall(c, "NetworkEndpointGroupsAttachNetworkEndpoints", func() (*compute.Operation, error) {
+		return c.service.NetworkEndpointGroupsAttachNetworkEndpoints(project, zone, name, endpoints)
+	})
+}
+
+func (c *interceptedComputeService) NetworkEndpointGroupsDetachNetworkEndpoints(project string, zone string, name string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	return interceptCall(c, "NetworkEndpointGroupsDetachNetworkEndpoints", func() (*compute.Operation, error) {
+		return c.service.NetworkEndpointGroupsDetachNetworkEndpoints(project, zone, name, endpoints)
+	})
+}
+
+func (c *interceptedComputeService) AddressesGet(project string, region string, name string) (*compute.Address, error) {
+	return interceptCall(c, "AddressesGet", func() (*compute.Address, error) {
+		return c.service.AddressesGet(project, region, name)
+	})
+}
+
+func (c *interceptedComputeService) AddressesInsert(project string, region string, address *compute.Address) (*compute.Operation, error) {
+	return interceptCall(c, "AddressesInsert", func() (*compute.Operation, error) {
+		return c.service.AddressesInsert(project, region, address)
+	})
+}
+
+func (c *interceptedComputeService) AddressesDelete(project string, region string, name string) (*compute.Operation, error) {
+	return interceptCall(c, "AddressesDelete", func() (*compute.Operation, error) {
+		return c.service.AddressesDelete(project, region, name)
+	})
+}
+
+func (c *interceptedComputeService) AddressesList(project string, region string, filter string) ([]*compute.Address, error) {
+	return interceptCall(c, "AddressesList", func() ([]*compute.Address, error) {
+		return c.service.AddressesList(project, region, filter)
+	})
+}
+
+func (c *interceptedComputeService) ResourcePoliciesGet(project string, region string, name string) (*compute.ResourcePolicy, error) {
+	return interceptCall(c, "ResourcePoliciesGet", func() (*compute.ResourcePolicy, error) {
+		return c.service.ResourcePoliciesGet(project, region, name)
+	})
+}
+
+func (c *interceptedComputeService) SubnetworksTestIamPermissions(project string, region string, subnetwork string, permissions []string) ([]string, error) {
+	return interceptCall(c, "SubnetworksTestIamPermissions", func() ([]string, error) {
+		return c.service.SubnetworksTestIamPermissions(project, region, subnetwork, permissions)
+	})
+}
+
+func (c *interceptedComputeService) SubnetworksGet(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+	return interceptCall(c, "SubnetworksGet", func() (*compute.Subnetwork, error) {
+		return c.service.SubnetworksGet(project, region, subnetwork)
+	})
+}
+
+func (c *interceptedComputeService) GlobalForwardingRulesList(project string, filter string) ([]*compute.ForwardingRule, error) {
+	return interceptCall(c, "GlobalForwardingRulesList", func() ([]*compute.ForwardingRule, error) {
+		return c.service.GlobalForwardingRulesList(project, filter)
+	})
+}
+
+func (c *interceptedComputeService) FirewallsGet(project string, firewall string) (*compute.Firewall, error) {
+	return interceptCall(c, "FirewallsGet", func() (*compute.Firewall, error) {
+		return c.service.FirewallsGet(project, firewall)
+	})
+}
+
+func (c *interceptedComputeService) FirewallsPatch(project string, firewall string, firewallResource *compute.Firewall) (*compute.Operation, error) {
+	return interceptCall(c, "FirewallsPatch", func() (*compute.Operation, error) {
+		return c.service.FirewallsPatch(project, firewall, firewallResource)
+	})
+}
+
+func (c *interceptedComputeService) InstanceTemplatesInsert(project string, instanceTemplate *compute.InstanceTemplate) (*compute.Operation, error) {
+	return interceptCall(c, "InstanceTemplatesInsert", func() (*compute.Operation, error) {
+		return c.service.InstanceTemplatesInsert(project, instanceTemplate)
+	})
+}
@@ -0,0 +1,106 @@
+package computeservice
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/googleapi"
+	"k8s.io/utils/clock"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// apiCallsTotal counts the compute API calls by method and response code.
+	apiCallsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_gcp_compute_api_calls_total",
+			Help: "Number of compute API calls, by GCPComputeService method and HTTP response code (error when no response was received)",
+		}, []string{"method", "code"},
+	)
+
+	// apiCallDurationSeconds observes the latency of the compute API calls.
+	apiCallDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mapi_gcp_compute_api_call_duration_seconds",
+			Help:    "Latency of compute API calls, by GCPComputeService method and HTTP response code",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"method", "code"},
+	)
+
+	// throttledCallsTotal counts the compute API calls the client-side rate limits delayed or rejected.
+	throttledCallsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_gcp_compute_api_throttled_calls_total",
+			Help: "Number of compute API calls throttled by the client-side rate limits, by API group (read or mutate) and result (delayed or rejected)",
+		}, []string{"group", "result"},
+	)
+
+	// throttleWaitSeconds observes how long delayed compute API calls waited for their budget.
+	throttleWaitSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mapi_gcp_compute_api_throttle_wait_seconds",
+			Help:    "Time compute API calls were delayed by the client-side rate limits, by API group",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"group"},
+	)
+
+	// instanceCacheRequestsTotal counts the instance lookups served from the instance cache or the compute API.
+	instanceCacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_gcp_compute_instance_cache_requests_total",
+			Help: "Number of instance lookups by result (hit when served from the instance cache, miss when fetched from the compute API)",
+		}, []string{"result"},
+	)
+
+	// apiDeprecatedResponsesTotal counts the compute API responses carrying a deprecation signal.
+	apiDeprecatedResponsesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_gcp_api_deprecated_responses_total",
+			Help: "Number of GCP API responses with a Sunset, Deprecation or deprecation Warning header, by API",
+		}, []string{"api"},
+	)
+
+	// apiSunsetTimestampSeconds is the shutdown time announced by the Sunset header of an API.
+	apiSunsetTimestampSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mapi_gcp_api_sunset_timestamp_seconds",
+			Help: "Unix time at which a GCP API used by the provider is scheduled to be shut down, from its Sunset header, by API",
+		}, []string{"api"},
+	)
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(apiCallsTotal, apiCallDurationSeconds, throttledCallsTotal, throttleWaitSeconds, instanceCacheRequestsTotal,
+		apiDeprecatedResponsesTotal, apiSunsetTimestampSeconds)
+}
+
+// NewMetricsInterceptor returns a CallInterceptor counting the compute API calls and observing their
+// latency, by method and response code. It should be the innermost interceptor, so that every
+// attempt of a retried call is measured and calls refused by the other interceptors are not.
+func NewMetricsInterceptor(clk clock.PassiveClock) CallInterceptor {
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	return func(_ context.Context, method string, call func() error) error {
+		started := clk.Now()
+		err := call()
+		code := responseCode(err)
+		apiCallsTotal.WithLabelValues(method, code).Inc()
+		apiCallDurationSeconds.WithLabelValues(method, code).Observe(clk.Since(started).Seconds())
+		return err
+	}
+}
+
+// responseCode returns the HTTP status code of the response to a compute API call.
+func responseCode(err error) string {
+	if err == nil {
+		return "200"
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return strconv.Itoa(apiErr.Code)
+	}
+	return "error"
+}
@@ -0,0 +1,57 @@
+package computeservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/googleapi"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestMetricsInterceptor(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	intercept := NewMetricsInterceptor(clk)
+
+	cases := []struct {
+		name         string
+		method       string
+		err          error
+		expectedCode string
+	}{
+		{name: "Succeeded", method: "InstancesGet", expectedCode: "200"},
+		{name: "Quota exceeded", method: "InstancesInsert", err: &googleapi.Error{Code: 429}, expectedCode: "429"},
+		{name: "Wrapped API error", method: "InstancesDelete", err: errors.Join(errors.New("deleting"), &googleapi.Error{Code: 404}), expectedCode: "404"},
+		{name: "No response", method: "ZonesGet", err: context.DeadlineExceeded, expectedCode: "error"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := intercept(context.Background(), tc.method, func() error {
+				clk.Step(2 * time.Second)
+				return tc.err
+			})
+			if err != tc.err {
+				t.Errorf("Expected the error of the call to be returned, got %v", err)
+			}
+
+			metric := &dto.Metric{}
+			if err := apiCallsTotal.WithLabelValues(tc.method, tc.expectedCode).Write(metric); err != nil {
+				t.Fatal(err)
+			}
+			if calls := metric.GetCounter().GetValue(); calls != 1 {
+				t.Errorf("Expected 1 call with code %s, got %v", tc.expectedCode, calls)
+			}
+			metric = &dto.Metric{}
+			if err := apiCallDurationSeconds.WithLabelValues(tc.method, tc.expectedCode).(prometheus.Metric).Write(metric); err != nil {
+				t.Fatal(err)
+			}
+			if histogram := metric.GetHistogram(); histogram.GetSampleCount() != 1 || histogram.GetSampleSum() != 2 {
+				t.Errorf("Expected a latency of 2s, got %d samples summing to %v", histogram.GetSampleCount(), histogram.GetSampleSum())
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package computeservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	readAPIGroup   = "read"
+	mutateAPIGroup = "mutate"
+)
+
+// RateLimits configures the client-side rate limiting of compute API calls. The Compute Engine API
+// quota of a project is shared with other controllers, e.g. the cloud controller manager, which a
+// mass scale-up must not starve. Reads and mutations have separate budgets, since they are
+// accounted in separate quotas.
+type RateLimits struct {
+	// ReadQPS is the sustained rate of read calls, zero disables the limit of reads.
+	ReadQPS float64
+	// ReadBurst is the number of read calls allowed above ReadQPS after a quiet period.
+	ReadBurst int
+	// MutateQPS is the sustained rate of calls that change resources, zero disables the limit of mutations.
+	MutateQPS float64
+	// MutateBurst is the number of mutating calls allowed above MutateQPS after a quiet period.
+	MutateBurst int
+	// MaxWait bounds the time a call waits for its budget. Calls that would wait longer, or past the
+	// deadline of their context, fail with a RateLimitExceeded error instead, which requeues the
+	// machine. Zero means calls wait up to the deadline of their context.
+	MaxWait time.Duration
+}
+
+// DefaultRateLimits are the rate limits of the controller unless configured otherwise.
+var DefaultRateLimits = RateLimits{
+	ReadQPS:     20,
+	ReadBurst:   40,
+	MutateQPS:   5,
+	MutateBurst: 10,
+	MaxWait:     10 * time.Second,
+}
+
+// NewRateLimitInterceptor returns a CallInterceptor delaying calls that exceed the token bucket of
+// their API group, or nil when both groups are unlimited. The interceptor must be shared by all
+// compute services of the controller for the budgets to be enforced across machines.
+func NewRateLimitInterceptor(limits RateLimits, clk clock.Clock) CallInterceptor {
+	if limits.ReadQPS <= 0 && limits.MutateQPS <= 0 {
+		return nil
+	}
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	limiters := map[string]*rate.Limiter{
+		readAPIGroup:   newLimiter(limits.ReadQPS, limits.ReadBurst),
+		mutateAPIGroup: newLimiter(limits.MutateQPS, limits.MutateBurst),
+	}
+	return func(ctx context.Context, method string, call func() error) error {
+		group := mutateAPIGroup
+		if IsReadMethod(method) {
+			group = readAPIGroup
+		}
+		limiter := limiters[group]
+		if limiter == nil {
+			return call()
+		}
+
+		now := clk.Now()
+		reservation := limiter.ReserveN(now, 1)
+		wait := reservation.DelayFrom(now)
+		deadline, hasDeadline := ctx.Deadline()
+		if !reservation.OK() || (limits.MaxWait > 0 && wait > limits.MaxWait) || (hasDeadline && now.Add(wait).After(deadline)) {
+			reservation.CancelAt(now)
+			throttledCallsTotal.WithLabelValues(group, "rejected").Inc()
+			return &gcperrors.Error{
+				Reason: gcperrors.RateLimitExceeded,
+				Err:    fmt.Errorf("client-side rate limit of %s calls exceeded, %s would wait %s", group, method, wait),
+			}
+		}
+		if wait > 0 {
+			throttledCallsTotal.WithLabelValues(group, "delayed").Inc()
+			throttleWaitSeconds.WithLabelValues(group).Observe(wait.Seconds())
+			klog.V(3).Infof("Delaying %s by %s to stay within the %s rate limit", method, wait, group)
+			clk.Sleep(wait)
+		}
+		return call()
+	}
+}
+
+// newLimiter returns a token bucket with the given rate and burst, or nil when the rate is unlimited.
+func newLimiter(qps float64, burst int) *rate.Limiter {
+	if qps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(qps), burst)
+}
@@ -0,0 +1,125 @@
+package computeservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/compute/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestRateLimitInterceptor(t *testing.T) {
+	limits := RateLimits{ReadQPS: 2, ReadBurst: 2, MutateQPS: 1, MutateBurst: 1}
+
+	cases := []struct {
+		name          string
+		limits        RateLimits
+		reads         int
+		mutations     int
+		deadline      time.Duration
+		expectedSlept time.Duration
+		expectedError bool
+	}{
+		{
+			name:   "Calls within the burst are not delayed",
+			limits: limits,
+			reads:  2,
+		},
+		{
+			name:          "Reads above the burst are delayed",
+			limits:        limits,
+			reads:         4,
+			expectedSlept: time.Second,
+		},
+		{
+			name:          "Mutations have their own budget",
+			limits:        limits,
+			reads:         2,
+			mutations:     2,
+			expectedSlept: time.Second,
+		},
+		{
+			name:      "Unlimited group is not delayed",
+			limits:    RateLimits{ReadQPS: 1, ReadBurst: 1},
+			mutations: 5,
+		},
+		{
+			name:          "Calls that would wait too long are rejected",
+			limits:        RateLimits{MutateQPS: 1, MutateBurst: 1, MaxWait: 500 * time.Millisecond},
+			mutations:     2,
+			expectedError: true,
+		},
+		{
+			name:          "Calls that would wait past the deadline are rejected",
+			limits:        RateLimits{MutateQPS: 1, MutateBurst: 1},
+			mutations:     2,
+			deadline:      500 * time.Millisecond,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			clock := clocktesting.NewFakeClock(start)
+			_, mock := NewComputeServiceMock()
+			service := WithInterceptors(mock, NewRateLimitInterceptor(tc.limits, clock))
+			if tc.deadline > 0 {
+				ctx, cancel := context.WithDeadline(context.Background(), start.Add(tc.deadline))
+				defer cancel()
+				service = WithContext(ctx, service)
+			}
+
+			var err error
+			for i := 0; i < tc.reads && err == nil; i++ {
+				_, err = service.RegionGet("project", "region")
+			}
+			for i := 0; i < tc.mutations && err == nil; i++ {
+				_, err = service.InstancesInsert("project", "zone", &compute.Instance{})
+			}
+
+			if (err != nil) != tc.expectedError {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if err != nil && !gcperrors.Is(err, gcperrors.RateLimitExceeded) {
+				t.Errorf("Expected a RateLimitExceeded error, got %v", err)
+			}
+			if slept := clock.Since(start); slept != tc.expectedSlept {
+				t.Errorf("Expected to wait %s, waited %s", tc.expectedSlept, slept)
+			}
+		})
+	}
+}
+
+func TestRateLimitInterceptorMetrics(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	_, mock := NewComputeServiceMock()
+	service := WithInterceptors(mock, NewRateLimitInterceptor(RateLimits{ReadQPS: 1, ReadBurst: 1}, clock))
+
+	delayed := delayedReads(t)
+	for i := 0; i < 3; i++ {
+		if _, err := service.RegionGet("project", "region"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if got := delayedReads(t) - delayed; got != 2 {
+		t.Errorf("Expected 2 delayed reads, got %v", got)
+	}
+}
+
+func TestRateLimitInterceptorDisabled(t *testing.T) {
+	if interceptor := NewRateLimitInterceptor(RateLimits{}, nil); interceptor != nil {
+		t.Errorf("Expected rate limiting to be disabled")
+	}
+}
+
+func delayedReads(t *testing.T) float64 {
+	metric := &dto.Metric{}
+	if err := throttledCallsTotal.WithLabelValues(readAPIGroup, "delayed").Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetCounter().GetValue()
+}
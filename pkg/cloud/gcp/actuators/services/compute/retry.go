@@ -0,0 +1,118 @@
+package computeservice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/gcperrors"
+	"google.golang.org/api/googleapi"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// RetryPolicy configures the retries of compute API calls that failed with a transient error,
+// so that rate limits and backend hiccups during mass scale-ups do not fail machines.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of a call, including the first one. Values below 2
+	// disable retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, it doubles with every further retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts.
+	MaxBackoff time.Duration
+	// MaxRetryDuration bounds the time a single call may spend waiting for retries, zero means
+	// the call is only bounded by MaxAttempts.
+	MaxRetryDuration time.Duration
+	// Jitter is the fraction of the backoff, between 0 and 1, randomly added to or removed from it.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the retry policy of the controller unless configured otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:      4,
+	InitialBackoff:   500 * time.Millisecond,
+	MaxBackoff:       10 * time.Second,
+	MaxRetryDuration: 30 * time.Second,
+	Jitter:           0.2,
+}
+
+// NewRetryInterceptor returns a CallInterceptor retrying calls according to the policy, or nil
+// when the policy disables retries. Reads are retried on rate limits, server errors and
+// connection resets. Mutations are only retried on rate limits, which reject the request before
+// it is processed, since retrying e.g. an insert the server did process would fail as a conflict.
+// A Retry-After longer than the backoff is honored, or ends the retries when it does not fit in
+// the retry duration or the deadline of the caller. The retries also end when the caller is
+// cancelled while waiting.
+func NewRetryInterceptor(policy RetryPolicy, clk clock.Clock) CallInterceptor {
+	if policy.MaxAttempts < 2 {
+		return nil
+	}
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	return func(ctx context.Context, method string, call func() error) error {
+		start := clk.Now()
+		backoff := policy.InitialBackoff
+		for attempt := 1; ; attempt++ {
+			err := call()
+			if err == nil || attempt >= policy.MaxAttempts || !isRetryable(method, err) {
+				return err
+			}
+
+			wait := policy.jitter(backoff)
+			// The API may ask for a longer wait when it throttles the project, retrying sooner would
+			// only be rejected again.
+			if retryAfter, ok := gcperrors.RetryAfter(err); ok && retryAfter > wait {
+				wait = retryAfter
+			}
+			if policy.MaxRetryDuration > 0 && clk.Since(start)+wait > policy.MaxRetryDuration {
+				return err
+			}
+			// A retry after the deadline of the caller would only block it, the caller requeues instead.
+			if deadline, ok := ctx.Deadline(); ok && clk.Now().Add(wait).After(deadline) {
+				return err
+			}
+			klog.V(2).Infof("Retrying %s after %s, attempt %d of %d failed: %v", method, wait, attempt, policy.MaxAttempts, err)
+			// The caller may be cancelled while waiting, e.g. on shutdown.
+			select {
+			case <-ctx.Done():
+				return err
+			case <-clk.After(wait):
+			}
+
+			backoff *= 2
+			if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}
+}
+
+func (p RetryPolicy) jitter(backoff time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return backoff
+	}
+	return backoff + time.Duration((rand.Float64()*2-1)*p.Jitter*float64(backoff))
+}
+
+// isRetryable returns true if the call of method that failed with err may succeed when retried.
+func isRetryable(method string, err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		if apiErr.Code == http.StatusTooManyRequests {
+			return true
+		}
+		for _, item := range apiErr.Errors {
+			if item.Reason == "rateLimitExceeded" || item.Reason == "userRateLimitExceeded" {
+				return true
+			}
+		}
+		return IsReadMethod(method) && apiErr.Code >= http.StatusInternalServerError
+	}
+	return IsReadMethod(method) && (errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF))
+}
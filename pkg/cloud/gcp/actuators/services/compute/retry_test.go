@@ -0,0 +1,298 @@
+package computeservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestRetryInterceptor(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}
+
+	cases := []struct {
+		name             string
+		policy           RetryPolicy
+		method           string
+		errs             []error
+		expectedAttempts int
+		expectedError    bool
+		expectedSlept    time.Duration
+	}{
+		{
+			name:             "Success is not retried",
+			policy:           policy,
+			method:           "InstancesGet",
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Read is retried on server errors",
+			policy:           policy,
+			method:           "InstancesGet",
+			errs:             []error{&googleapi.Error{Code: 503}, &googleapi.Error{Code: 500}},
+			expectedAttempts: 3,
+			expectedSlept:    3 * time.Second,
+		},
+		{
+			name:             "Read is retried on connection resets",
+			policy:           policy,
+			method:           "RegionGet",
+			errs:             []error{fmt.Errorf("read tcp: %w", syscall.ECONNRESET)},
+			expectedAttempts: 2,
+			expectedSlept:    time.Second,
+		},
+		{
+			name:             "Retry-After longer than the backoff is honored",
+			policy:           policy,
+			method:           "InstancesInsert",
+			errs:             []error{&googleapi.Error{Code: 429, Header: http.Header{"Retry-After": []string{"5"}}}},
+			expectedAttempts: 2,
+			expectedSlept:    5 * time.Second,
+		},
+		{
+			name:             "Retry-After past the retry duration ends the retries",
+			policy:           RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxRetryDuration: 10 * time.Second},
+			method:           "InstancesInsert",
+			errs:             []error{&googleapi.Error{Code: 429, Header: http.Header{"Retry-After": []string{"30"}}}},
+			expectedAttempts: 1,
+			expectedError:    true,
+		},
+		{
+			name:             "Attempts are bounded",
+			policy:           policy,
+			method:           "InstancesGet",
+			errs:             []error{&googleapi.Error{Code: 429}, &googleapi.Error{Code: 429}, &googleapi.Error{Code: 429}},
+			expectedAttempts: 3,
+			expectedError:    true,
+			expectedSlept:    3 * time.Second,
+		},
+		{
+			name:             "Mutation is retried on rate limits",
+			policy:           policy,
+			method:           "InstancesInsert",
+			errs:             []error{&googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}},
+			expectedAttempts: 2,
+			expectedSlept:    time.Second,
+		},
+		{
+			name:             "Mutation is not retried on server errors",
+			policy:           policy,
+			method:           "InstancesInsert",
+			errs:             []error{&googleapi.Error{Code: 503}},
+			expectedAttempts: 1,
+			expectedError:    true,
+		},
+		{
+			name:             "Client errors are not retried",
+			policy:           policy,
+			method:           "InstancesGet",
+			errs:             []error{&googleapi.Error{Code: 404}},
+			expectedAttempts: 1,
+			expectedError:    true,
+		},
+		{
+			name:             "Retries are bounded by the call budget",
+			policy:           RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxRetryDuration: 2 * time.Second},
+			method:           "InstancesGet",
+			errs:             []error{&googleapi.Error{Code: 503}, &googleapi.Error{Code: 503}, &googleapi.Error{Code: 503}},
+			expectedAttempts: 2,
+			expectedError:    true,
+			expectedSlept:    time.Second,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			clock := newAutoStepClock(start)
+			_, mock := NewComputeServiceMock()
+			attempts := 0
+			call := func() error {
+				attempts++
+				if attempts <= len(tc.errs) {
+					return tc.errs[attempts-1]
+				}
+				return nil
+			}
+			mock.MockInstancesInsert = func(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
+				return nil, call()
+			}
+			mock.MockRegionGet = func(project string, region string) (*compute.Region, error) {
+				return nil, call()
+			}
+			mock.mockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+				return nil, call()
+			}
+			service := WithInterceptors(mock, NewRetryInterceptor(tc.policy, clock))
+
+			var err error
+			switch tc.method {
+			case "InstancesInsert":
+				_, err = service.InstancesInsert("project", "zone", &compute.Instance{})
+			case "RegionGet":
+				_, err = service.RegionGet("project", "region")
+			default:
+				_, err = service.InstancesGet("project", "zone", "instance")
+			}
+
+			if attempts != tc.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tc.expectedAttempts, attempts)
+			}
+			if (err != nil) != tc.expectedError {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if slept := clock.Since(start); slept != tc.expectedSlept {
+				t.Errorf("Expected to wait %s, waited %s", tc.expectedSlept, slept)
+			}
+		})
+	}
+}
+
+func TestRetryInterceptorDeadline(t *testing.T) {
+	start := time.Now()
+	clock := newAutoStepClock(start)
+	_, mock := NewComputeServiceMock()
+	attempts := 0
+	mock.MockRegionGet = func(project string, region string) (*compute.Region, error) {
+		attempts++
+		return nil, &googleapi.Error{Code: 503}
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), start.Add(2*time.Second))
+	defer cancel()
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second}
+	service := WithContext(ctx, WithInterceptors(mock, NewRetryInterceptor(policy, clock)))
+
+	if _, err := service.RegionGet("project", "region"); err == nil {
+		t.Errorf("Expected the error of the last attempt")
+	}
+	// The second retry would start 3s after the first attempt, past the deadline.
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts before the deadline, got %d", attempts)
+	}
+	if slept := clock.Since(start); slept != time.Second {
+		t.Errorf("Expected to wait 1s, waited %s", slept)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "region"}`))
+	}))
+	defer server.Close()
+	service, err := NewEndpointBuilder(server.URL + "/compute/v1/")("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.RegionGet("project", "region"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := WithContext(ctx, service).RegionGet("project", "region"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the call to be cancelled with its context, got %v", err)
+	}
+
+	_, mock := NewComputeServiceMock()
+	if WithContext(ctx, mock) != GCPComputeService(mock) {
+		t.Errorf("Expected services without context support to be returned as is")
+	}
+}
+
+func TestRetryInterceptorDisabled(t *testing.T) {
+	if interceptor := NewRetryInterceptor(RetryPolicy{MaxAttempts: 1}, nil); interceptor != nil {
+		t.Errorf("Expected retries to be disabled")
+	}
+	_, mock := NewComputeServiceMock()
+	if service := WithInterceptors(mock, NewRetryInterceptor(RetryPolicy{}, nil)); service != GCPComputeService(mock) {
+		t.Errorf("Expected the service not to be wrapped without interceptors")
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	policy := RetryPolicy{Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if wait := policy.jitter(10 * time.Second); wait < 8*time.Second || wait > 12*time.Second {
+			t.Fatalf("Expected the wait to be within 20%% of 10s, got %s", wait)
+		}
+	}
+}
+
+// autoStepClock is a fake clock whose timers fire right away, stepping the clock to them.
+type autoStepClock struct {
+	*clocktesting.FakeClock
+}
+
+func newAutoStepClock(t time.Time) autoStepClock {
+	return autoStepClock{FakeClock: clocktesting.NewFakeClock(t)}
+}
+
+func (c autoStepClock) After(d time.Duration) <-chan time.Time {
+	ch := c.FakeClock.After(d)
+	c.Step(d)
+	return ch
+}
+
+func TestRetryInterceptorCancelled(t *testing.T) {
+	_, mock := NewComputeServiceMock()
+	attempts := 0
+	mock.MockRegionGet = func(project string, region string) (*compute.Region, error) {
+		attempts++
+		return nil, &googleapi.Error{Code: 503}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}
+	service := WithContext(ctx, WithInterceptors(mock, NewRetryInterceptor(policy, clocktesting.NewFakeClock(time.Now()))))
+
+	done := make(chan error)
+	go func() {
+		_, err := service.RegionGet("project", "region")
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Expected the error of the last attempt")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the retry to stop waiting once the caller is cancelled")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestIsReadMethod(t *testing.T) {
+	service := reflect.TypeOf((*GCPComputeService)(nil)).Elem()
+	for i := 0; i < service.NumMethod(); i++ {
+		// BasePath does not call the API.
+		if name := service.Method(i).Name; name != "BasePath" {
+			if _, ok := methodKinds[name]; !ok {
+				t.Errorf("Expected method %s to be marked as a read or a mutation", name)
+			}
+		}
+	}
+
+	for method, expected := range map[string]bool{
+		"InstancesGet":                     true,
+		"SubnetworksTestIamPermissions":    true,
+		"AddInstanceGroupToBackendService": false,
+		"InstanceGroupInsert":              false,
+		"UnknownMethod":                    false,
+	} {
+		if read := IsReadMethod(method); read != expected {
+			t.Errorf("Expected IsReadMethod(%s) to be %v, got %v", method, expected, read)
+		}
+	}
+}
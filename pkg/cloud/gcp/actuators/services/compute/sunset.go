@@ -0,0 +1,88 @@
+package computeservice
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	sunsetHeader      = "Sunset"
+	deprecationHeader = "Deprecation"
+	warningHeader     = "Warning"
+
+	// deprecationWarningCode is the code of the Warning header values Google APIs use to report
+	// deprecated API versions and features.
+	deprecationWarningCode = "299"
+)
+
+// deprecationTransport looks for deprecation signals in the responses passing through it: the
+// Sunset header (RFC 8594), the Deprecation header (RFC 9745) and 299 Warning headers. They are
+// reported by metrics and logged once per API and value, so that operators learn that the
+// provider relies on an API scheduled for shutdown before it is shut down.
+type deprecationTransport struct {
+	base http.RoundTripper
+
+	// logged remembers the signals already logged, by API and value.
+	logged sync.Map
+}
+
+func (t *deprecationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+
+	api := apiOfRequest(req)
+	deprecated := false
+	if value := resp.Header.Get(sunsetHeader); value != "" {
+		deprecated = true
+		if sunset, err := http.ParseTime(value); err == nil {
+			apiSunsetTimestampSeconds.WithLabelValues(api).Set(float64(sunset.Unix()))
+		}
+		t.logOnce(api, sunsetHeader, value, "Compute API %s is scheduled to be shut down at %s", api, value)
+	}
+	if value := resp.Header.Get(deprecationHeader); value != "" {
+		deprecated = true
+		t.logOnce(api, deprecationHeader, value, "Compute API %s is deprecated (%s: %s)", api, deprecationHeader, formatDeprecation(value))
+	}
+	for _, value := range resp.Header.Values(warningHeader) {
+		if strings.HasPrefix(value, deprecationWarningCode+" ") {
+			deprecated = true
+			t.logOnce(api, warningHeader, value, "Compute API %s returned a deprecation warning: %s", api, value)
+		}
+	}
+	if deprecated {
+		apiDeprecatedResponsesTotal.WithLabelValues(api).Inc()
+	}
+	return resp, nil
+}
+
+func (t *deprecationTransport) logOnce(api, header, value, format string, args ...interface{}) {
+	if _, logged := t.logged.LoadOrStore(api+"\x00"+header+"\x00"+value, true); !logged {
+		klog.Warningf(format, args...)
+	}
+}
+
+// apiOfRequest returns the host and version of the API a request is sent to, e.g.
+// "compute.googleapis.com/compute/v1", which is bounded unlike the full path.
+func apiOfRequest(req *http.Request) string {
+	segments := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 3)
+	if len(segments) > 2 {
+		segments = segments[:2]
+	}
+	return strings.TrimSuffix(req.URL.Host+"/"+strings.Join(segments, "/"), "/")
+}
+
+// formatDeprecation returns the date of a Deprecation header value, which is either a structured
+// date, e.g. "@1688169599", or, in earlier drafts of RFC 9745, an HTTP date or "true".
+func formatDeprecation(value string) string {
+	if seconds, err := strconv.ParseInt(strings.TrimPrefix(value, "@"), 10, 64); err == nil && strings.HasPrefix(value, "@") {
+		return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+	}
+	return value
+}
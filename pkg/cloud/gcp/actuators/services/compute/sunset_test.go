@@ -0,0 +1,80 @@
+package computeservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestDeprecationTransport(t *testing.T) {
+	headers := http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, values := range headers {
+			w.Header()[key] = values
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &deprecationTransport{base: http.DefaultTransport}}
+	send := func() string {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/compute/beta/projects/test/zones/a/instances/b", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return apiOfRequest(req)
+	}
+	deprecatedResponses := func(api string) float64 {
+		metric := &dto.Metric{}
+		if err := apiDeprecatedResponsesTotal.WithLabelValues(api).Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		return metric.GetCounter().GetValue()
+	}
+
+	api := send()
+	if deprecatedResponses(api) != 0 {
+		t.Errorf("Expected responses without deprecation headers not to be counted")
+	}
+
+	headers.Set(sunsetHeader, "Sat, 31 Oct 2026 23:59:59 GMT")
+	headers.Set(deprecationHeader, "@1688169599")
+	headers.Add(warningHeader, `299 - "Compute Engine API beta is deprecated"`)
+	send()
+	if deprecatedResponses(api) != 1 {
+		t.Errorf("Expected a deprecated response to be counted once, got %v", deprecatedResponses(api))
+	}
+	sunset := &dto.Metric{}
+	if err := apiSunsetTimestampSeconds.WithLabelValues(api).Write(sunset); err != nil {
+		t.Fatal(err)
+	}
+	if sunset.GetGauge().GetValue() != 1793491199 {
+		t.Errorf("Expected the sunset time to be reported, got %v", sunset.GetGauge().GetValue())
+	}
+
+	// Other warnings are not deprecation signals.
+	headers = http.Header{}
+	headers.Set(warningHeader, `199 - "Miscellaneous warning"`)
+	send()
+	if deprecatedResponses(api) != 1 {
+		t.Errorf("Expected other warnings not to be counted, got %v", deprecatedResponses(api))
+	}
+}
+
+func TestAPIOfRequest(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/compute/v1/projects/test/zones/a/instances/b": "compute.googleapis.com/compute/v1",
+		"/compute/v1": "compute.googleapis.com/compute/v1",
+		"/":           "compute.googleapis.com",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "https://compute.googleapis.com"+path, nil)
+		if api := apiOfRequest(req); api != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, api)
+		}
+	}
+}
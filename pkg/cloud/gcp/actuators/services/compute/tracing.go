@@ -0,0 +1,22 @@
+package computeservice
+
+import (
+	"context"
+
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracingInterceptor returns a CallInterceptor recording a span for every compute API call, as a
+// child of the span of the context of the call, see WithContext. It should be the outermost
+// interceptor, so that the span covers the retries of the call and its waits for the rate limits.
+func NewTracingInterceptor() CallInterceptor {
+	return func(ctx context.Context, method string, call func() error) error {
+		_, span := tracing.Tracer().Start(ctx, "compute."+method, trace.WithSpanKind(trace.SpanKindClient))
+		err := call()
+		span.SetAttributes(attribute.String("gcp.compute.response_code", responseCode(err)))
+		tracing.End(span, err)
+		return err
+	}
+}
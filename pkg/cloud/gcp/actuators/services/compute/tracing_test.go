@@ -0,0 +1,61 @@
+package computeservice
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/api/googleapi"
+)
+
+func TestTracingInterceptor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "machine.Create")
+	intercept := NewTracingInterceptor()
+	if err := intercept(ctx, "InstancesGet", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	quotaExceeded := &googleapi.Error{Code: 429, Message: "quota exceeded"}
+	if err := intercept(ctx, "InstancesInsert", func() error { return quotaExceeded }); err != quotaExceeded {
+		t.Fatalf("Expected the error of the call, got %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	for i, expected := range []struct {
+		name   string
+		code   string
+		status codes.Code
+	}{
+		{name: "compute.InstancesGet", code: "200", status: codes.Unset},
+		{name: "compute.InstancesInsert", code: "429", status: codes.Error},
+	} {
+		span := spans[i]
+		if span.Name() != expected.name || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected a %s child span of the reconcile, got %s with parent %s", expected.name, span.Name(), span.Parent().SpanID())
+		}
+		if span.Status().Code != expected.status {
+			t.Errorf("Expected %s to have status %v, got %v", expected.name, expected.status, span.Status())
+		}
+		found := false
+		for _, attr := range span.Attributes() {
+			if attr == attribute.String("gcp.compute.response_code", expected.code) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s to have response code %s, got %v", expected.name, expected.code, span.Attributes())
+		}
+	}
+}
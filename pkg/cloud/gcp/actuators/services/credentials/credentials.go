@@ -0,0 +1,89 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	impersonatedServiceAccountType = "impersonated_service_account"
+	impersonationURLFmt            = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken"
+)
+
+// Builder builds the credentials the GCP clients are created with from the credentials of the
+// credentials secret, so that the controller can run with a low-privilege identity that only
+// impersonates the service account doing the actual work. A nil Builder uses the credentials
+// of the secret as they are.
+type Builder struct {
+	// ImpersonateServiceAccount is the email of the service account to impersonate through the
+	// iamcredentials API, unless the credentials secret names another one.
+	ImpersonateServiceAccount string
+	// Delegates is the chain of service accounts to impersonate ImpersonateServiceAccount through.
+	Delegates []string
+}
+
+// impersonatedCredentials is the impersonated_service_account credentials file understood by
+// golang.org/x/oauth2/google.CredentialsFromJSON.
+type impersonatedCredentials struct {
+	Type                           string          `json:"type"`
+	ServiceAccountImpersonationURL string          `json:"service_account_impersonation_url"`
+	Delegates                      []string        `json:"delegates,omitempty"`
+	SourceCredentials              json.RawMessage `json:"source_credentials"`
+	// ProjectID keeps the project of the source credentials, which is the project of the cluster.
+	ProjectID string `json:"project_id,omitempty"`
+}
+
+// Build returns the credentials JSON to create GCP clients with. secretServiceAccount is the
+// service account to impersonate named by the credentials secret, it takes precedence over the
+// ImpersonateServiceAccount of the Builder.
+func (b *Builder) Build(serviceAccountJSON string, secretServiceAccount string) (string, error) {
+	target := secretServiceAccount
+	var delegates []string
+	if target == "" && b != nil {
+		target, delegates = b.ImpersonateServiceAccount, b.Delegates
+	}
+	if target == "" {
+		return serviceAccountJSON, nil
+	}
+	return Impersonate(serviceAccountJSON, target, delegates)
+}
+
+// Impersonate returns credentials JSON impersonating the target service account with the
+// given source credentials, e.g. a service account key or external_account credentials.
+func Impersonate(sourceJSON string, target string, delegates []string) (string, error) {
+	if !strings.Contains(target, "@") {
+		return "", fmt.Errorf("invalid service account %q to impersonate, must be an email", target)
+	}
+	if strings.TrimSpace(sourceJSON) == "" {
+		return "", errors.New("impersonating a service account requires credentials in the credentials secret")
+	}
+
+	var source struct {
+		ProjectID string `json:"project_id"`
+	}
+	if err := json.Unmarshal([]byte(sourceJSON), &source); err != nil {
+		return "", fmt.Errorf("error un marshalling JSON key: %w", err)
+	}
+
+	delegateEmails := make([]string, 0, len(delegates))
+	for _, delegate := range delegates {
+		if !strings.HasPrefix(delegate, "projects/") {
+			delegate = "projects/-/serviceAccounts/" + delegate
+		}
+		delegateEmails = append(delegateEmails, delegate)
+	}
+
+	impersonated, err := json.Marshal(impersonatedCredentials{
+		Type:                           impersonatedServiceAccountType,
+		ServiceAccountImpersonationURL: fmt.Sprintf(impersonationURLFmt, target),
+		Delegates:                      delegateEmails,
+		SourceCredentials:              json.RawMessage(sourceJSON),
+		ProjectID:                      source.ProjectID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error building impersonated credentials: %w", err)
+	}
+	return string(impersonated), nil
+}
@@ -0,0 +1,88 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"golang.org/x/oauth2/google"
+)
+
+const serviceAccountJSON = `{"type": "service_account", "project_id": "cluster-project", "client_email": "controller@cluster-project.iam.gserviceaccount.com", "private_key": "key"}`
+
+func TestBuild(t *testing.T) {
+	cases := []struct {
+		name                 string
+		builder              *Builder
+		secretServiceAccount string
+		expectedURL          string
+		expectedDelegates    []string
+	}{
+		{
+			name: "No builder",
+		},
+		{
+			name:    "No impersonation",
+			builder: &Builder{},
+		},
+		{
+			name:                 "Service account named by the secret",
+			secretServiceAccount: "machine-api@cluster-project.iam.gserviceaccount.com",
+			expectedURL:          "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/machine-api@cluster-project.iam.gserviceaccount.com:generateAccessToken",
+		},
+		{
+			name:              "Service account of the controller",
+			builder:           &Builder{ImpersonateServiceAccount: "machine-api@cluster-project.iam.gserviceaccount.com", Delegates: []string{"delegate@cluster-project.iam.gserviceaccount.com"}},
+			expectedURL:       "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/machine-api@cluster-project.iam.gserviceaccount.com:generateAccessToken",
+			expectedDelegates: []string{"projects/-/serviceAccounts/delegate@cluster-project.iam.gserviceaccount.com"},
+		},
+		{
+			name:                 "Secret takes precedence over the controller",
+			builder:              &Builder{ImpersonateServiceAccount: "machine-api@cluster-project.iam.gserviceaccount.com", Delegates: []string{"delegate@cluster-project.iam.gserviceaccount.com"}},
+			secretServiceAccount: "other@cluster-project.iam.gserviceaccount.com",
+			expectedURL:          "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/other@cluster-project.iam.gserviceaccount.com:generateAccessToken",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			built, err := tc.builder.Build(serviceAccountJSON, tc.secretServiceAccount)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.expectedURL == "" {
+				if built != serviceAccountJSON {
+					t.Errorf("Expected the credentials to be used as they are, got %s", built)
+				}
+				return
+			}
+
+			impersonated := impersonatedCredentials{}
+			if err := json.Unmarshal([]byte(built), &impersonated); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if impersonated.Type != impersonatedServiceAccountType || impersonated.ServiceAccountImpersonationURL != tc.expectedURL {
+				t.Errorf("Expected impersonation through %s, got %s", tc.expectedURL, built)
+			}
+			if !reflect.DeepEqual(impersonated.Delegates, tc.expectedDelegates) {
+				t.Errorf("Expected delegates %v, got %v", tc.expectedDelegates, impersonated.Delegates)
+			}
+			if impersonated.ProjectID != "cluster-project" {
+				t.Errorf("Expected the project of the source credentials, got %q", impersonated.ProjectID)
+			}
+			if _, err := google.CredentialsFromJSON(context.Background(), []byte(built)); err != nil {
+				t.Errorf("Expected credentials usable by the GCP clients, got %v", err)
+			}
+		})
+	}
+}
+
+func TestImpersonateErrors(t *testing.T) {
+	if _, err := Impersonate(serviceAccountJSON, "machine-api", nil); err == nil {
+		t.Error("Expected an error for a service account that is not an email")
+	}
+	if _, err := Impersonate("", "machine-api@cluster-project.iam.gserviceaccount.com", nil); err == nil {
+		t.Error("Expected an error without source credentials")
+	}
+}
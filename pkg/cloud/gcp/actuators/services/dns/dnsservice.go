@@ -0,0 +1,150 @@
+package dnsservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const (
+	dnsBasePath = "https://dns.googleapis.com/dns/v1/"
+	dnsScope    = "https://www.googleapis.com/auth/ndev.clouddns.readwrite"
+)
+
+// ManagedZone is the subset of a Cloud DNS managed zone the provider cares about,
+// see https://cloud.google.com/dns/docs/reference/v1/managedZones.
+type ManagedZone struct {
+	Name string `json:"name"`
+	// DNSName is the DNS name of the zone, ending with a dot, e.g. example.com.
+	DNSName string `json:"dnsName"`
+}
+
+// ResourceRecordSet is a Cloud DNS record set, see
+// https://cloud.google.com/dns/docs/reference/v1/resourceRecordSets.
+type ResourceRecordSet struct {
+	// Name is the fully qualified name of the record set, ending with a dot.
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int64    `json:"ttl,omitempty"`
+	Rrdatas []string `json:"rrdatas"`
+}
+
+// DNSService is a minimal client of the Cloud DNS API, which is not part of the vendored
+// google.golang.org/api, to enable tests to mock it.
+type DNSService interface {
+	ManagedZonesGet(ctx context.Context, project, zone string) (*ManagedZone, error)
+	ResourceRecordSetsGet(ctx context.Context, project, zone, name, recordType string) (*ResourceRecordSet, error)
+	ResourceRecordSetsCreate(ctx context.Context, project, zone string, recordSet *ResourceRecordSet) (*ResourceRecordSet, error)
+	ResourceRecordSetsPatch(ctx context.Context, project, zone string, recordSet *ResourceRecordSet) (*ResourceRecordSet, error)
+	ResourceRecordSetsDelete(ctx context.Context, project, zone, name, recordType string) error
+}
+
+// dnsService implements DNSService using the Cloud DNS REST API.
+type dnsService struct {
+	client   *http.Client
+	basePath string
+}
+
+// BuilderFuncType is function type for building the Cloud DNS client.
+type BuilderFuncType func(ctx context.Context, serviceAccountJSON string) (DNSService, error)
+
+// NewDNSService returns a new dnsService.
+func NewDNSService(ctx context.Context, serviceAccountJSON string) (DNSService, error) {
+	client, _, err := htransport.NewClient(ctx, option.WithCredentialsJSON([]byte(serviceAccountJSON)), option.WithScopes(dnsScope))
+	if err != nil {
+		return nil, fmt.Errorf("could not create new dns service: %w", err)
+	}
+	return &dnsService{client: client, basePath: dnsBasePath}, nil
+}
+
+// ManagedZonesGet returns the managed zone of the project with the given name.
+func (s *dnsService) ManagedZonesGet(ctx context.Context, project, zone string) (*ManagedZone, error) {
+	managedZone := &ManagedZone{}
+	if err := s.do(ctx, http.MethodGet, zonePath(project, zone), nil, managedZone); err != nil {
+		return nil, err
+	}
+	return managedZone, nil
+}
+
+// ResourceRecordSetsGet returns the record set of the zone with the given name and type. Errors
+// returned by the API are *googleapi.Error, so that a missing record set can be told apart.
+func (s *dnsService) ResourceRecordSetsGet(ctx context.Context, project, zone, name, recordType string) (*ResourceRecordSet, error) {
+	recordSet := &ResourceRecordSet{}
+	if err := s.do(ctx, http.MethodGet, recordSetPath(project, zone, name, recordType), nil, recordSet); err != nil {
+		return nil, err
+	}
+	return recordSet, nil
+}
+
+// ResourceRecordSetsCreate creates a record set in the zone.
+func (s *dnsService) ResourceRecordSetsCreate(ctx context.Context, project, zone string, recordSet *ResourceRecordSet) (*ResourceRecordSet, error) {
+	created := &ResourceRecordSet{}
+	if err := s.do(ctx, http.MethodPost, zonePath(project, zone)+"/rrsets", recordSet, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ResourceRecordSetsPatch replaces the TTL and the data of a record set of the zone.
+func (s *dnsService) ResourceRecordSetsPatch(ctx context.Context, project, zone string, recordSet *ResourceRecordSet) (*ResourceRecordSet, error) {
+	patched := &ResourceRecordSet{}
+	if err := s.do(ctx, http.MethodPatch, recordSetPath(project, zone, recordSet.Name, recordSet.Type), recordSet, patched); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+// ResourceRecordSetsDelete deletes the record set of the zone with the given name and type.
+func (s *dnsService) ResourceRecordSetsDelete(ctx context.Context, project, zone, name, recordType string) error {
+	return s.do(ctx, http.MethodDelete, recordSetPath(project, zone, name, recordType), nil, nil)
+}
+
+// do sends a request to the API and decodes the response into out, when not nil.
+func (s *dnsService) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.basePath+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(resp)
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not decode the response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func zonePath(project, zone string) string {
+	return "projects/" + url.PathEscape(project) + "/managedZones/" + url.PathEscape(zone)
+}
+
+func recordSetPath(project, zone, name, recordType string) string {
+	return zonePath(project, zone) + "/rrsets/" + url.PathEscape(name) + "/" + url.PathEscape(recordType)
+}
@@ -0,0 +1,62 @@
+package dnsservice
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+// MockDNSService mocks DNSService interface for tests.
+type MockDNSService struct {
+	MockManagedZonesGet          func(ctx context.Context, project, zone string) (*ManagedZone, error)
+	MockResourceRecordSetsGet    func(ctx context.Context, project, zone, name, recordType string) (*ResourceRecordSet, error)
+	MockResourceRecordSetsCreate func(ctx context.Context, project, zone string, recordSet *ResourceRecordSet) (*ResourceRecordSet, error)
+	MockResourceRecordSetsPatch  func(ctx context.Context, project, zone string, recordSet *ResourceRecordSet) (*ResourceRecordSet, error)
+	MockResourceRecordSetsDelete func(ctx context.Context, project, zone, name, recordType string) error
+}
+
+// NewMockDNSService returns new mock of dnsService.
+func NewMockDNSService() *MockDNSService {
+	return &MockDNSService{}
+}
+
+// ManagedZonesGet returns a zone named after the example.com domain unless mocked otherwise.
+func (m *MockDNSService) ManagedZonesGet(ctx context.Context, project, zone string) (*ManagedZone, error) {
+	if m.MockManagedZonesGet == nil {
+		return &ManagedZone{Name: zone, DNSName: "example.com."}, nil
+	}
+	return m.MockManagedZonesGet(ctx, project, zone)
+}
+
+// ResourceRecordSetsGet returns a not found error unless mocked otherwise.
+func (m *MockDNSService) ResourceRecordSetsGet(ctx context.Context, project, zone, name, recordType string) (*ResourceRecordSet, error) {
+	if m.MockResourceRecordSetsGet == nil {
+		return nil, &googleapi.Error{Code: http.StatusNotFound}
+	}
+	return m.MockResourceRecordSetsGet(ctx, project, zone, name, recordType)
+}
+
+// ResourceRecordSetsCreate returns the record set unless mocked otherwise.
+func (m *MockDNSService) ResourceRecordSetsCreate(ctx context.Context, project, zone string, recordSet *ResourceRecordSet) (*ResourceRecordSet, error) {
+	if m.MockResourceRecordSetsCreate == nil {
+		return recordSet, nil
+	}
+	return m.MockResourceRecordSetsCreate(ctx, project, zone, recordSet)
+}
+
+// ResourceRecordSetsPatch returns the record set unless mocked otherwise.
+func (m *MockDNSService) ResourceRecordSetsPatch(ctx context.Context, project, zone string, recordSet *ResourceRecordSet) (*ResourceRecordSet, error) {
+	if m.MockResourceRecordSetsPatch == nil {
+		return recordSet, nil
+	}
+	return m.MockResourceRecordSetsPatch(ctx, project, zone, recordSet)
+}
+
+// ResourceRecordSetsDelete succeeds unless mocked otherwise.
+func (m *MockDNSService) ResourceRecordSetsDelete(ctx context.Context, project, zone, name, recordType string) error {
+	if m.MockResourceRecordSetsDelete == nil {
+		return nil
+	}
+	return m.MockResourceRecordSetsDelete(ctx, project, zone, name, recordType)
+}
@@ -0,0 +1,70 @@
+package dnsservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestResourceRecordSets(t *testing.T) {
+	var created *ResourceRecordSet
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /projects/project/managedZones/nodes":
+			w.Write([]byte(`{"name": "nodes", "dnsName": "nodes.example.com."}`))
+		case "GET /projects/project/managedZones/nodes/rrsets/worker-a.nodes.example.com./A":
+			w.Write([]byte(`{"name": "worker-a.nodes.example.com.", "type": "A", "ttl": 300, "rrdatas": ["10.0.0.2"]}`))
+		case "POST /projects/project/managedZones/nodes/rrsets":
+			created = &ResourceRecordSet{}
+			if err := json.NewDecoder(r.Body).Decode(created); err != nil {
+				t.Errorf("Failed to decode the record set: %v", err)
+			}
+			json.NewEncoder(w).Encode(created)
+		case "DELETE /projects/project/managedZones/nodes/rrsets/worker-a.nodes.example.com./A":
+			deleted = r.URL.Path
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": 404, "message": "The 'parameters.name' resource named 'worker-b.nodes.example.com.' does not exist."}}`))
+		}
+	}))
+	defer server.Close()
+
+	service := &dnsService{client: server.Client(), basePath: server.URL + "/"}
+	ctx := context.Background()
+
+	zone, err := service.ManagedZonesGet(ctx, "project", "nodes")
+	if err != nil || zone.DNSName != "nodes.example.com." {
+		t.Errorf("Unexpected zone %+v, %v", zone, err)
+	}
+
+	recordSet, err := service.ResourceRecordSetsGet(ctx, "project", "nodes", "worker-a.nodes.example.com.", "A")
+	if err != nil || len(recordSet.Rrdatas) != 1 || recordSet.Rrdatas[0] != "10.0.0.2" {
+		t.Errorf("Unexpected record set %+v, %v", recordSet, err)
+	}
+
+	_, err = service.ResourceRecordSetsGet(ctx, "project", "nodes", "worker-b.nodes.example.com.", "A")
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Errorf("Expected a not found googleapi.Error, got %v", err)
+	}
+
+	if _, err := service.ResourceRecordSetsCreate(ctx, "project", "nodes", &ResourceRecordSet{
+		Name: "worker-c.nodes.example.com.", Type: "A", TTL: 300, Rrdatas: []string{"10.0.0.4"},
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if created == nil || created.Name != "worker-c.nodes.example.com." || created.Rrdatas[0] != "10.0.0.4" {
+		t.Errorf("Unexpected created record set %+v", created)
+	}
+
+	if err := service.ResourceRecordSetsDelete(ctx, "project", "nodes", "worker-a.nodes.example.com.", "A"); err != nil || deleted == "" {
+		t.Errorf("Expected the record set to be deleted, got %v", err)
+	}
+}
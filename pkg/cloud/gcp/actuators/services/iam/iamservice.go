@@ -0,0 +1,76 @@
+package iamservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const (
+	iamBasePath = "https://iam.googleapis.com/v1/"
+	iamScope    = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// ServiceAccount is the subset of an IAM service account the provider cares about,
+// see https://cloud.google.com/iam/docs/reference/rest/v1/projects.serviceAccounts.
+type ServiceAccount struct {
+	Name      string `json:"name"`
+	ProjectID string `json:"projectId"`
+	UniqueID  string `json:"uniqueId"`
+	Email     string `json:"email"`
+	Disabled  bool   `json:"disabled"`
+}
+
+// IAMService is a minimal client of the IAM API, which is not part of the vendored
+// google.golang.org/api, to enable tests to mock it.
+type IAMService interface {
+	ServiceAccountsGet(ctx context.Context, email string) (*ServiceAccount, error)
+}
+
+// iamService implements IAMService using the IAM REST API.
+type iamService struct {
+	client   *http.Client
+	basePath string
+}
+
+// BuilderFuncType is function type for building GCP IAM client.
+type BuilderFuncType func(ctx context.Context, serviceAccountJSON string) (IAMService, error)
+
+// NewIAMService returns a new iamService.
+func NewIAMService(ctx context.Context, serviceAccountJSON string) (IAMService, error) {
+	client, _, err := htransport.NewClient(ctx, option.WithCredentialsJSON([]byte(serviceAccountJSON)), option.WithScopes(iamScope))
+	if err != nil {
+		return nil, fmt.Errorf("could not create new iam service: %w", err)
+	}
+	return &iamService{client: client, basePath: iamBasePath}, nil
+}
+
+// ServiceAccountsGet returns the service account with the given email. Errors returned by
+// the API are *googleapi.Error, so that a missing service account can be told apart.
+func (s *iamService) ServiceAccountsGet(ctx context.Context, email string) (*ServiceAccount, error) {
+	// The "-" wildcard resolves the project from the email of the service account.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.basePath+"projects/-/serviceAccounts/"+url.PathEscape(email), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(resp)
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	serviceAccount := &ServiceAccount{}
+	if err := json.NewDecoder(resp.Body).Decode(serviceAccount); err != nil {
+		return nil, fmt.Errorf("could not decode service account %s: %w", email, err)
+	}
+	return serviceAccount, nil
+}
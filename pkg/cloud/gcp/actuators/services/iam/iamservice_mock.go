@@ -0,0 +1,28 @@
+package iamservice
+
+import (
+	"context"
+)
+
+// MockIAMService mocks IAMService interface for tests.
+type MockIAMService struct {
+	MockServiceAccountsGet func(ctx context.Context, email string) (*ServiceAccount, error)
+}
+
+// NewMockIAMService returns new mock of iamService.
+func NewMockIAMService() *MockIAMService {
+	return &MockIAMService{}
+}
+
+// NewMockIAMServiceBuilder returns new mock for creating GCP IAM client.
+func NewMockIAMServiceBuilder(ctx context.Context, serviceAccountJSON string) (IAMService, error) {
+	return NewMockIAMService(), nil
+}
+
+// ServiceAccountsGet returns the requested service account, which exists unless mocked otherwise.
+func (m *MockIAMService) ServiceAccountsGet(ctx context.Context, email string) (*ServiceAccount, error) {
+	if m.MockServiceAccountsGet == nil {
+		return &ServiceAccount{Email: email}, nil
+	}
+	return m.MockServiceAccountsGet(ctx, email)
+}
@@ -0,0 +1,40 @@
+package iamservice
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestServiceAccountsGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/-/serviceAccounts/worker@project.iam.gserviceaccount.com":
+			w.Write([]byte(`{"name": "projects/project/serviceAccounts/worker@project.iam.gserviceaccount.com", "email": "worker@project.iam.gserviceaccount.com", "disabled": true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": 404, "message": "Unknown service account"}}`))
+		}
+	}))
+	defer server.Close()
+
+	service := &iamService{client: server.Client(), basePath: server.URL + "/"}
+
+	serviceAccount, err := service.ServiceAccountsGet(context.Background(), "worker@project.iam.gserviceaccount.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if serviceAccount.Email != "worker@project.iam.gserviceaccount.com" || !serviceAccount.Disabled {
+		t.Errorf("Unexpected service account %+v", serviceAccount)
+	}
+
+	_, err = service.ServiceAccountsGet(context.Background(), "typo@project.iam.gserviceaccount.com")
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Errorf("Expected a not found googleapi.Error, got %v", err)
+	}
+}
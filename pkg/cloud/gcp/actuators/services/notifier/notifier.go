@@ -0,0 +1,133 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// EventType classifies a machine failure.
+type EventType string
+
+const (
+	// CreateFailed is published when an instance could not be created for any other reason.
+	CreateFailed EventType = "CreateFailed"
+	// QuotaExceeded is published when a regional or project quota prevents the creation.
+	QuotaExceeded EventType = "QuotaExceeded"
+	// StockOut is published when the zone does not have the resources to create the instance.
+	StockOut EventType = "StockOut"
+)
+
+// Event is a structured machine failure event.
+type Event struct {
+	Type      EventType `json:"type"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Namespace string    `json:"namespace"`
+	Machine   string    `json:"machine"`
+	Zone      string    `json:"zone,omitempty"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+// Notifier publishes machine failure events to an external sink.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+const (
+	// DefaultMinInterval is the default minimum interval between two events of the same type for a machine.
+	DefaultMinInterval = 30 * time.Minute
+	webhookTimeout     = 10 * time.Second
+)
+
+type eventKey struct {
+	eventType EventType
+	namespace string
+	machine   string
+}
+
+type webhookNotifier struct {
+	url         string
+	client      *http.Client
+	clock       clock.Clock
+	minInterval time.Duration
+
+	lock sync.Mutex
+	sent map[eventKey]time.Time
+}
+
+// NewWebhookNotifier returns a Notifier that POSTs events as JSON to the given URL, e.g. a Slack relay.
+// Failing machines are retried on every reconcile, so an event of the same type for the same machine
+// is published at most once per minInterval.
+func NewWebhookNotifier(url string, client *http.Client, clk clock.Clock, minInterval time.Duration) Notifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	return &webhookNotifier{
+		url:         url,
+		client:      client,
+		clock:       clk,
+		minInterval: minInterval,
+		sent:        map[eventKey]time.Time{},
+	}
+}
+
+// Notify publishes the event unless the same event was published within the minimum interval.
+func (n *webhookNotifier) Notify(ctx context.Context, event Event) error {
+	now := n.clock.Now()
+	key := eventKey{eventType: event.Type, namespace: event.Namespace, machine: event.Machine}
+
+	n.lock.Lock()
+	if last, ok := n.sent[key]; ok && now.Sub(last) < n.minInterval {
+		n.lock.Unlock()
+		return nil
+	}
+	n.sent[key] = now
+	n.lock.Unlock()
+
+	if event.Time.IsZero() {
+		event.Time = now
+	}
+	if err := n.post(ctx, event); err != nil {
+		// Allow the event to be published again on the next failure.
+		n.lock.Lock()
+		delete(n.sent, key)
+		n.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (n *webhookNotifier) post(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", event.Type, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to publish %s event: webhook returned %s", event.Type, resp.Status)
+	}
+	return nil
+}
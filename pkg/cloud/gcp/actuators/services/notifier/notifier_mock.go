@@ -0,0 +1,20 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+)
+
+// MockNotifier records the events it is asked to publish.
+type MockNotifier struct {
+	lock   sync.Mutex
+	Events []Event
+}
+
+// Notify records the event.
+func (m *MockNotifier) Notify(ctx context.Context, event Event) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.Events = append(m.Events, event)
+	return nil
+}
@@ -0,0 +1,66 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var received []Event
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode event: %v", err)
+		}
+		received = append(received, event)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	fakeClock := clocktesting.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	n := NewWebhookNotifier(server.URL, server.Client(), fakeClock, time.Hour)
+	event := Event{Type: QuotaExceeded, Namespace: "openshift-machine-api", Machine: "worker-a", Message: "insufficient CPUS quota"}
+
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(received) != 1 || received[0].Machine != "worker-a" || !received[0].Time.Equal(fakeClock.Now()) {
+		t.Fatalf("Expected the event to be published with the current time, got %+v", received)
+	}
+
+	// The same event is not published again within the minimum interval, other events are.
+	fakeClock.Step(30 * time.Minute)
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := n.Notify(context.Background(), Event{Type: StockOut, Namespace: "openshift-machine-api", Machine: "worker-a"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(received) != 2 || received[1].Type != StockOut {
+		t.Fatalf("Expected only the stock-out event to be published, got %+v", received)
+	}
+
+	// Failed deliveries do not count against the minimum interval.
+	fakeClock.Step(time.Hour)
+	status = http.StatusInternalServerError
+	if err := n.Notify(context.Background(), event); err == nil {
+		t.Fatal("Expected an error when the webhook fails")
+	}
+	status = http.StatusOK
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(received) != 4 {
+		t.Fatalf("Expected the event to be published again after a failed delivery, got %d events", len(received))
+	}
+}
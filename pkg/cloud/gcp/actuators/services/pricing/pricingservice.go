@@ -0,0 +1,133 @@
+package pricingservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const (
+	cloudBillingBasePath = "https://cloudbilling.googleapis.com/v1/"
+	cloudBillingScope    = "https://www.googleapis.com/auth/cloud-platform"
+	// computeEngineServiceID is the ID of the Compute Engine service in the Cloud Billing catalog.
+	computeEngineServiceID = "6F81-5844-456A"
+)
+
+// SKU is the subset of a Cloud Billing catalog SKU the provider cares about,
+// see https://cloud.google.com/billing/docs/reference/rest/v1/services.skus.
+type SKU struct {
+	Description    string        `json:"description"`
+	Category       Category      `json:"category"`
+	ServiceRegions []string      `json:"serviceRegions"`
+	PricingInfo    []PricingInfo `json:"pricingInfo"`
+}
+
+// Category classifies a SKU, e.g. the vCPUs (CPU) of preemptible (Preemptible) instances.
+type Category struct {
+	ResourceFamily string `json:"resourceFamily"`
+	ResourceGroup  string `json:"resourceGroup"`
+	UsageType      string `json:"usageType"`
+}
+
+// PricingInfo is the price of a SKU from a point in time on.
+type PricingInfo struct {
+	PricingExpression PricingExpression `json:"pricingExpression"`
+}
+
+// PricingExpression is the price of one usage unit of a SKU, e.g. h for a vCPU hour or GiBy.h for a
+// GiB of memory for an hour, by usage tier.
+type PricingExpression struct {
+	UsageUnit   string       `json:"usageUnit"`
+	TieredRates []TieredRate `json:"tieredRates"`
+}
+
+// TieredRate is the price of a usage unit from a usage amount on.
+type TieredRate struct {
+	StartUsageAmount float64 `json:"startUsageAmount"`
+	UnitPrice        Money   `json:"unitPrice"`
+}
+
+// Money is an amount of a currency.
+type Money struct {
+	CurrencyCode string `json:"currencyCode"`
+	Units        string `json:"units"`
+	Nanos        int64  `json:"nanos"`
+}
+
+// Float returns the amount as a float, Units is an int64 in a string.
+func (m Money) Float() float64 {
+	var units int64
+	fmt.Sscan(m.Units, &units)
+	return float64(units) + float64(m.Nanos)/1e9
+}
+
+// PricingService is a minimal client of the Cloud Billing catalog API, which is not part of the
+// vendored google.golang.org/api, to enable tests to mock it.
+type PricingService interface {
+	// ComputeSKUs returns the SKUs of the Compute Engine service with prices in USD.
+	ComputeSKUs(ctx context.Context) ([]SKU, error)
+}
+
+// pricingService implements PricingService using the Cloud Billing REST API.
+type pricingService struct {
+	client   *http.Client
+	basePath string
+}
+
+// BuilderFuncType is function type for building the Cloud Billing catalog client.
+type BuilderFuncType func(ctx context.Context, serviceAccountJSON string) (PricingService, error)
+
+// NewPricingService returns a new pricingService.
+func NewPricingService(ctx context.Context, serviceAccountJSON string) (PricingService, error) {
+	client, _, err := htransport.NewClient(ctx, option.WithCredentialsJSON([]byte(serviceAccountJSON)), option.WithScopes(cloudBillingScope))
+	if err != nil {
+		return nil, fmt.Errorf("could not create new pricing service: %w", err)
+	}
+	return &pricingService{client: client, basePath: cloudBillingBasePath}, nil
+}
+
+type listSKUsResponse struct {
+	SKUs          []SKU  `json:"skus"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// ComputeSKUs lists all pages of the SKUs of the Compute Engine service. Errors returned by the API
+// are *googleapi.Error.
+func (s *pricingService) ComputeSKUs(ctx context.Context) ([]SKU, error) {
+	var skus []SKU
+	pageToken := ""
+	for {
+		query := url.Values{"currencyCode": {"USD"}, "pageSize": {"5000"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.basePath+"services/"+computeEngineServiceID+"/skus?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		page := &listSKUsResponse{}
+		err = googleapi.CheckResponse(resp)
+		if err == nil {
+			err = json.NewDecoder(resp.Body).Decode(page)
+		}
+		googleapi.CloseBody(resp)
+		if err != nil {
+			return nil, fmt.Errorf("could not list the SKUs of Compute Engine: %w", err)
+		}
+		skus = append(skus, page.SKUs...)
+		if page.NextPageToken == "" {
+			return skus, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
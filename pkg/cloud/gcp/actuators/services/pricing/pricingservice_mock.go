@@ -0,0 +1,23 @@
+package pricingservice
+
+import (
+	"context"
+)
+
+// MockPricingService mocks PricingService interface for tests.
+type MockPricingService struct {
+	MockComputeSKUs func(ctx context.Context) ([]SKU, error)
+}
+
+// NewMockPricingService returns new mock of pricingService.
+func NewMockPricingService() *MockPricingService {
+	return &MockPricingService{}
+}
+
+// ComputeSKUs returns the mocked SKUs, none unless mocked otherwise.
+func (m *MockPricingService) ComputeSKUs(ctx context.Context) ([]SKU, error) {
+	if m.MockComputeSKUs == nil {
+		return nil, nil
+	}
+	return m.MockComputeSKUs(ctx)
+}
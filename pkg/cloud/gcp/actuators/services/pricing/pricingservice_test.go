@@ -0,0 +1,49 @@
+package pricingservice
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestComputeSKUs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/6F81-5844-456A/skus" || r.URL.Query().Get("currencyCode") != "USD" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": 404, "message": "Unknown service"}}`))
+			return
+		}
+		switch r.URL.Query().Get("pageToken") {
+		case "":
+			w.Write([]byte(`{"skus": [{"description": "Spot Preemptible N2 Instance Core running in Americas", "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "Preemptible"}, "serviceRegions": ["us-east1"], "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 7614000}}]}}]}], "nextPageToken": "page2"}`))
+		case "page2":
+			w.Write([]byte(`{"skus": [{"description": "Spot Preemptible N2 Instance Ram running in Americas", "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "Preemptible"}, "serviceRegions": ["us-east1"]}]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	service := &pricingService{client: server.Client(), basePath: server.URL + "/"}
+	skus, err := service.ComputeSKUs(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(skus) != 2 || skus[1].Category.ResourceGroup != "RAM" {
+		t.Fatalf("Expected the SKUs of both pages, got %+v", skus)
+	}
+	if price := skus[0].PricingInfo[0].PricingExpression.TieredRates[0].UnitPrice.Float(); price != 0.007614 {
+		t.Errorf("Expected a price of 0.007614, got %v", price)
+	}
+
+	service.basePath = server.URL + "/unknown/"
+	_, err = service.ComputeSKUs(context.Background())
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Errorf("Expected a not found googleapi.Error, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package tracing sets up OpenTelemetry tracing of the controller, so that the provisioning of a
+// machine can be followed end to end in a trace collector: from the reconciles of the actuator,
+// through the compute API calls they make, to the time GCP took to complete their operations.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/machine-api-provider-gcp/pkg/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// tracerName is the instrumentation scope of the spans of the provider.
+	tracerName  = "github.com/openshift/machine-api-provider-gcp"
+	serviceName = "machine-api-provider-gcp"
+)
+
+// Options configures the export of the spans.
+type Options struct {
+	// Endpoint is the host:port of the OTLP/HTTP receiver of the collector. Tracing is disabled
+	// when it is empty.
+	Endpoint string
+	// Insecure sends the spans over plain HTTP instead of HTTPS.
+	Insecure bool
+	// SamplingRatio is the fraction of the reconciles that are traced, between 0 and 1. The compute
+	// API calls of a traced reconcile are always traced.
+	SamplingRatio float64
+}
+
+// Tracer returns the tracer of the provider. Its spans are dropped until Setup installs an exporter.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Setup installs the global tracer provider exporting the spans to the collector of the options. It
+// returns a function flushing the spans not exported yet, to be called before the controller exits.
+// Nothing is installed when the endpoint is empty.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if opts.SamplingRatio < 0 || opts.SamplingRatio > 1 {
+		return nil, fmt.Errorf("sampling ratio must be between 0 and 1, got %v", opts.SamplingRatio)
+	}
+
+	clientOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SamplingRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", version.Raw),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// End ends the span, setting its status to the error, if any.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,28 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("Expected tracing to be disabled without an endpoint, got %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected error shutting down disabled tracing: %v", err)
+	}
+
+	if _, err := Setup(context.Background(), Options{Endpoint: "localhost:4318", SamplingRatio: 2}); err == nil {
+		t.Error("Expected an error for a sampling ratio above 1")
+	}
+
+	shutdown, err = Setup(context.Background(), Options{Endpoint: "localhost:4318", Insecure: true, SamplingRatio: 0.5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected error flushing no spans: %v", err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	machineapierros "github.com/openshift/machine-api-operator/pkg/controller/machine"
@@ -30,10 +31,17 @@ import (
 
 const (
 	credentialsSecretKey = "service_account.json"
+	// impersonateServiceAccountSecretKey optionally holds the email of a service account to
+	// impersonate with the credentials of the secret.
+	impersonateServiceAccountSecretKey = "impersonate_service_account"
+
+	externalAccountCredentialsType            = "external_account"
+	impersonatedServiceAccountCredentialsType = "impersonated_service_account"
 )
 
 // This expects the https://github.com/openshift/cloud-credential-operator to make a secret
-// with a serviceAccount JSON Key content available. E.g:
+// with a serviceAccount JSON Key content available. Workload identity federation
+// (external_account) credentials are supported as well. E.g:
 //
 //	apiVersion: v1
 //	kind: Secret
@@ -44,25 +52,50 @@ const (
 //	data:
 //	 serviceAccountJSON: base64 encoded content of the file
 func GetCredentialsSecret(coreClient controllerclient.Client, namespace string, spec machinev1.GCPMachineProviderSpec) (string, error) {
-	if spec.CredentialsSecret == nil {
-		return "", nil
+	serviceAccountJSON, _, err := GetCredentials(coreClient, namespace, spec)
+	return serviceAccountJSON, err
+}
+
+// GetCredentials returns the credentials JSON of the credentials secret and the service
+// account to impersonate with them, if the secret names one. Machines in a project that the
+// ProjectCredentialsConfigMapName ConfigMap maps to a secret use that secret instead of
+// their credentials secret.
+func GetCredentials(coreClient controllerclient.Client, namespace string, spec machinev1.GCPMachineProviderSpec) (serviceAccountJSON string, impersonateServiceAccount string, err error) {
+	var secretName string
+	if spec.CredentialsSecret != nil {
+		secretName = spec.CredentialsSecret.Name
+	}
+	if spec.ProjectID != "" {
+		projectSecretName, err := credentialsSecretForProject(coreClient, namespace, spec.ProjectID)
+		if err != nil {
+			return "", "", err
+		}
+		if projectSecretName != "" {
+			secretName = projectSecretName
+		}
+	}
+	if secretName == "" {
+		return "", "", nil
 	}
 	var credentialsSecret apicorev1.Secret
 
-	if err := coreClient.Get(context.Background(), controllerclient.ObjectKey{Namespace: namespace, Name: spec.CredentialsSecret.Name}, &credentialsSecret); err != nil {
+	if err := coreClient.Get(context.Background(), controllerclient.ObjectKey{Namespace: namespace, Name: secretName}, &credentialsSecret); err != nil {
 		if apimachineryerrors.IsNotFound(err) {
-			machineapierros.InvalidMachineConfiguration("credentials secret %q in namespace %q not found: %v", spec.CredentialsSecret.Name, namespace, err.Error())
+			machineapierros.InvalidMachineConfiguration("credentials secret %q in namespace %q not found: %v", secretName, namespace, err.Error())
 		}
-		return "", fmt.Errorf("error getting credentials secret %q in namespace %q: %v", spec.CredentialsSecret.Name, namespace, err)
+		return "", "", fmt.Errorf("error getting credentials secret %q in namespace %q: %v", secretName, namespace, err)
 	}
 	data, exists := credentialsSecret.Data[credentialsSecretKey]
 	if !exists {
-		return "", machineapierros.InvalidMachineConfiguration("secret %v/%v does not have %q field set. Thus, no credentials applied when creating an instance", namespace, spec.CredentialsSecret.Name, credentialsSecretKey)
+		return "", "", machineapierros.InvalidMachineConfiguration("secret %v/%v does not have %q field set. Thus, no credentials applied when creating an instance", namespace, secretName, credentialsSecretKey)
 	}
 
-	return string(data), nil
+	return string(data), strings.TrimSpace(string(credentialsSecret.Data[impersonateServiceAccountSecretKey])), nil
 }
 
+// GetProjectIDFromJSONKey returns the project of the credentials. Service account keys name it,
+// workload identity federation credentials only carry it as their quota project or in the email
+// of the service account they impersonate.
 func GetProjectIDFromJSONKey(content []byte) (string, error) {
 	var JSONKey struct {
 		ProjectID string `json:"project_id"`
@@ -70,15 +103,61 @@ func GetProjectIDFromJSONKey(content []byte) (string, error) {
 	if err := json.Unmarshal(content, &JSONKey); err != nil {
 		return "", fmt.Errorf("error un marshalling JSON key: %v", err)
 	}
-	return JSONKey.ProjectID, nil
+	if JSONKey.ProjectID != "" {
+		return JSONKey.ProjectID, nil
+	}
+	return getProjectIDFromFederatedCredentials(content)
+}
+
+// getProjectIDFromFederatedCredentials returns the project of workload identity federation
+// credentials, or an empty string for other credentials.
+func getProjectIDFromFederatedCredentials(content []byte) (string, error) {
+	var federated struct {
+		Type                           string `json:"type"`
+		QuotaProjectID                 string `json:"quota_project_id"`
+		ServiceAccountImpersonationURL string `json:"service_account_impersonation_url"`
+	}
+	if err := json.Unmarshal(content, &federated); err != nil {
+		return "", fmt.Errorf("error un marshalling JSON key: %v", err)
+	}
+
+	switch federated.Type {
+	case externalAccountCredentialsType, impersonatedServiceAccountCredentialsType:
+		if federated.QuotaProjectID != "" {
+			return federated.QuotaProjectID, nil
+		}
+		if projectID := projectIDFromImpersonationURL(federated.ServiceAccountImpersonationURL); projectID != "" {
+			return projectID, nil
+		}
+		return "", fmt.Errorf("the project cannot be determined from %s credentials without a quota project or service account impersonation, set it in the providerSpec", federated.Type)
+	}
+	return "", nil
+}
+
+// projectIDFromImpersonationURL returns the project of the service account impersonated through
+// https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/<name>@<project>.iam.gserviceaccount.com:generateAccessToken.
+func projectIDFromImpersonationURL(url string) string {
+	_, email, found := strings.Cut(url, "/serviceAccounts/")
+	if !found {
+		return ""
+	}
+	email, _, _ = strings.Cut(email, ":")
+	_, domain, _ := strings.Cut(email, "@")
+	projectID, found := strings.CutSuffix(domain, ".iam.gserviceaccount.com")
+	if !found {
+		return ""
+	}
+	return projectID
 }
 
+// CreateOauth2Client returns an HTTP client authenticated with the given credentials, either a
+// service account key or workload identity federation credentials.
 func CreateOauth2Client(serviceAccountJSON string, scope ...string) (*http.Client, error) {
 	ctx := context.Background()
 
-	jwt, err := google.JWTConfigFromJSON([]byte(serviceAccountJSON), scope...)
+	creds, err := google.CredentialsFromJSON(ctx, []byte(serviceAccountJSON), scope...)
 	if err != nil {
 		return nil, err
 	}
-	return oauth2.NewClient(ctx, jwt.TokenSource(ctx)), nil
+	return oauth2.NewClient(ctx, creds.TokenSource), nil
 }
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"testing"
+)
+
+const externalAccountJSON = `{
+  "type": "external_account",
+  "audience": "//iam.googleapis.com/projects/123456/locations/global/workloadIdentityPools/openshift/providers/cluster",
+  "subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+  "token_url": "https://sts.googleapis.com/v1/token",
+  "service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/machine-api@cluster-project.iam.gserviceaccount.com:generateAccessToken",
+  "credential_source": {"file": "/var/run/secrets/openshift/serviceaccount/token"}
+}`
+
+func TestGetProjectIDFromJSONKey(t *testing.T) {
+	cases := []struct {
+		name          string
+		json          string
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "Service account key",
+			json:     `{"type": "service_account", "project_id": "sa-project"}`,
+			expected: "sa-project",
+		},
+		{
+			name:     "External account impersonating a service account",
+			json:     externalAccountJSON,
+			expected: "cluster-project",
+		},
+		{
+			name:     "External account with a quota project",
+			json:     `{"type": "external_account", "quota_project_id": "quota-project", "service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/machine-api@cluster-project.iam.gserviceaccount.com:generateAccessToken"}`,
+			expected: "quota-project",
+		},
+		{
+			name:          "External account without a project",
+			json:          `{"type": "external_account", "audience": "//iam.googleapis.com/projects/123456/locations/global/workloadIdentityPools/openshift/providers/cluster"}`,
+			expectedError: "the project cannot be determined from external_account credentials without a quota project or service account impersonation, set it in the providerSpec",
+		},
+		{
+			name: "No credentials",
+			json: `{}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			projectID, err := GetProjectIDFromJSONKey([]byte(tc.json))
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Fatalf("Expected error %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if projectID != tc.expected {
+				t.Errorf("Expected project %q, got %q", tc.expected, projectID)
+			}
+		})
+	}
+}
+
+func TestCreateOauth2ClientWithExternalAccount(t *testing.T) {
+	if _, err := CreateOauth2Client(externalAccountJSON, "https://www.googleapis.com/auth/cloud-platform"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
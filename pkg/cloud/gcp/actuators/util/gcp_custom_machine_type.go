@@ -0,0 +1,158 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	customMachineTypeMarker   = "custom"
+	extendedMemorySuffix      = "-ext"
+	customMemoryGranularityMb = 256
+)
+
+// CustomMachineType is a parsed custom machine type, e.g. custom-8-32768 (N1),
+// n2-custom-8-32768 or n2-custom-8-73728-ext with extended memory.
+type CustomMachineType struct {
+	// Family is the machine family, custom machine types without a family prefix are N1.
+	Family         string
+	CPUs           int64
+	MemoryMb       int64
+	ExtendedMemory bool
+}
+
+// customMachineTypeRules are the vCPU and memory rules of a machine family for custom machine types,
+// see https://cloud.google.com/compute/docs/instances/creating-instance-with-custom-machine-type.
+type customMachineTypeRules struct {
+	validCPUs      func(cpus int64) bool
+	cpusRule       string
+	minMbPerCPU    float64
+	maxMbPerCPU    float64
+	maxMemoryMb    int64
+	maxExtendedMb  int64
+	extendedMemory bool
+}
+
+func evenUpTo(max int64) func(int64) bool {
+	return func(cpus int64) bool { return cpus >= 2 && cpus <= max && cpus%2 == 0 }
+}
+
+var customMachineTypeFamilies = map[string]customMachineTypeRules{
+	"n1": {
+		validCPUs:      func(cpus int64) bool { return cpus == 1 || evenUpTo(96)(cpus) },
+		cpusRule:       "1 or an even number up to 96",
+		minMbPerCPU:    921.6,
+		maxMbPerCPU:    6656,
+		extendedMemory: true,
+		maxExtendedMb:  624 * 1024,
+	},
+	"n2": {
+		validCPUs: func(cpus int64) bool {
+			switch {
+			case cpus <= 32:
+				return evenUpTo(32)(cpus)
+			case cpus <= 80:
+				return cpus%4 == 0
+			default:
+				return cpus <= 128 && cpus%8 == 0
+			}
+		},
+		cpusRule:       "a multiple of 2 up to 32, of 4 up to 80 or of 8 up to 128",
+		minMbPerCPU:    512,
+		maxMbPerCPU:    8192,
+		extendedMemory: true,
+		maxExtendedMb:  864 * 1024,
+	},
+	"n2d": {
+		validCPUs: func(cpus int64) bool {
+			return cpus == 2 || cpus == 4 || cpus == 8 || (cpus >= 16 && cpus <= 224 && cpus%16 == 0)
+		},
+		cpusRule:       "2, 4, 8 or a multiple of 16 up to 224",
+		minMbPerCPU:    512,
+		maxMbPerCPU:    8192,
+		extendedMemory: true,
+		maxExtendedMb:  768 * 1024,
+	},
+	"e2": {
+		validCPUs:   evenUpTo(32),
+		cpusRule:    "an even number up to 32",
+		minMbPerCPU: 512,
+		maxMbPerCPU: 8192,
+		maxMemoryMb: 128 * 1024,
+	},
+}
+
+// ParseCustomMachineType parses and validates a custom machine type. It returns nil without
+// error when machineType is a predefined machine type, or a custom machine type of a family or in
+// a form whose rules are not known here, e.g. n4-custom-8-32768 or the shared-core
+// e2-custom-micro-2048. Those are left to the compute API to validate and describe.
+func ParseCustomMachineType(machineType string) (*CustomMachineType, error) {
+	name, extended := strings.CutSuffix(machineType, extendedMemorySuffix)
+	parts := strings.Split(name, "-")
+
+	var family string
+	switch {
+	case len(parts) > 0 && parts[0] == customMachineTypeMarker:
+		family, parts = "n1", parts[1:]
+	case len(parts) > 1 && parts[1] == customMachineTypeMarker:
+		family, parts = parts[0], parts[2:]
+	default:
+		return nil, nil
+	}
+
+	if _, ok := customMachineTypeFamilies[family]; !ok || len(parts) != 2 {
+		return nil, nil
+	}
+	cpus, cpusErr := strconv.ParseInt(parts[0], 10, 64)
+	memoryMb, memoryErr := strconv.ParseInt(parts[1], 10, 64)
+	if cpusErr != nil || memoryErr != nil {
+		return nil, nil
+	}
+	if cpus <= 0 {
+		return nil, fmt.Errorf("custom machine type %q has an invalid number of vCPUs %q", machineType, parts[0])
+	}
+	if memoryMb <= 0 {
+		return nil, fmt.Errorf("custom machine type %q has an invalid amount of memory %q", machineType, parts[1])
+	}
+
+	customMachineType := &CustomMachineType{Family: family, CPUs: cpus, MemoryMb: memoryMb, ExtendedMemory: extended}
+	if err := customMachineType.validate(); err != nil {
+		return nil, fmt.Errorf("custom machine type %q is invalid: %w", machineType, err)
+	}
+	return customMachineType, nil
+}
+
+func (c *CustomMachineType) validate() error {
+	rules := customMachineTypeFamilies[c.Family]
+	if !rules.validCPUs(c.CPUs) {
+		return fmt.Errorf("%s custom machine types must have %s vCPUs, got %d", c.Family, rules.cpusRule, c.CPUs)
+	}
+	if c.MemoryMb%customMemoryGranularityMb != 0 {
+		return fmt.Errorf("memory must be a multiple of %d MB, got %d", customMemoryGranularityMb, c.MemoryMb)
+	}
+	if c.ExtendedMemory && !rules.extendedMemory {
+		return fmt.Errorf("%s custom machine types do not support extended memory", c.Family)
+	}
+
+	if minMb := rules.minMbPerCPU * float64(c.CPUs); float64(c.MemoryMb) < minMb {
+		return fmt.Errorf("%s custom machine types need at least %.1f MB of memory per vCPU, got %d MB for %d vCPUs", c.Family, rules.minMbPerCPU, c.MemoryMb, c.CPUs)
+	}
+	if c.ExtendedMemory {
+		if c.MemoryMb > rules.maxExtendedMb {
+			return fmt.Errorf("%s custom machine types support at most %d MB of extended memory, got %d MB", c.Family, rules.maxExtendedMb, c.MemoryMb)
+		}
+		return nil
+	}
+	if maxMb := rules.maxMbPerCPU * float64(c.CPUs); float64(c.MemoryMb) > maxMb {
+		hint := ""
+		if rules.extendedMemory {
+			hint = ", use the " + extendedMemorySuffix + " suffix for extended memory"
+		}
+		return fmt.Errorf("%s custom machine types support at most %.0f MB of memory per vCPU, got %d MB for %d vCPUs%s", c.Family, rules.maxMbPerCPU, c.MemoryMb, c.CPUs, hint)
+	}
+	if rules.maxMemoryMb > 0 && c.MemoryMb > rules.maxMemoryMb {
+		return fmt.Errorf("%s custom machine types support at most %d MB of memory, got %d MB", c.Family, rules.maxMemoryMb, c.MemoryMb)
+	}
+	return nil
+}
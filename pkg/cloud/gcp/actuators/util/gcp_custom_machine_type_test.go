@@ -0,0 +1,116 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCustomMachineType(t *testing.T) {
+	tests := []struct {
+		name        string
+		machineType string
+		want        *CustomMachineType
+		wantErr     string
+	}{
+		{
+			name:        "predefined machine type",
+			machineType: "n2-standard-8",
+		},
+		{
+			name:        "N1 custom machine type",
+			machineType: "custom-8-32768",
+			want:        &CustomMachineType{Family: "n1", CPUs: 8, MemoryMb: 32768},
+		},
+		{
+			name:        "N1 custom machine type with a single vCPU",
+			machineType: "custom-1-3840",
+			want:        &CustomMachineType{Family: "n1", CPUs: 1, MemoryMb: 3840},
+		},
+		{
+			name:        "N2 custom machine type with extended memory",
+			machineType: "n2-custom-8-73728-ext",
+			want:        &CustomMachineType{Family: "n2", CPUs: 8, MemoryMb: 73728, ExtendedMemory: true},
+		},
+		{
+			name:        "E2 custom machine type",
+			machineType: "e2-custom-4-8192",
+			want:        &CustomMachineType{Family: "e2", CPUs: 4, MemoryMb: 8192},
+		},
+		{
+			name:        "N1 odd number of vCPUs",
+			machineType: "custom-3-7680",
+			wantErr:     `custom machine type "custom-3-7680" is invalid: n1 custom machine types must have 1 or an even number up to 96 vCPUs, got 3`,
+		},
+		{
+			name:        "N2 vCPUs not a multiple of 4 above 32",
+			machineType: "n2-custom-34-34816",
+			wantErr:     `custom machine type "n2-custom-34-34816" is invalid: n2 custom machine types must have a multiple of 2 up to 32, of 4 up to 80 or of 8 up to 128 vCPUs, got 34`,
+		},
+		{
+			name:        "memory not a multiple of 256 MB",
+			machineType: "n2-custom-2-4000",
+			wantErr:     `custom machine type "n2-custom-2-4000" is invalid: memory must be a multiple of 256 MB, got 4000`,
+		},
+		{
+			name:        "too little memory per vCPU",
+			machineType: "custom-4-3072",
+			wantErr:     `custom machine type "custom-4-3072" is invalid: n1 custom machine types need at least 921.6 MB of memory per vCPU, got 3072 MB for 4 vCPUs`,
+		},
+		{
+			name:        "too much memory per vCPU without extended memory",
+			machineType: "n2-custom-2-32768",
+			wantErr:     `custom machine type "n2-custom-2-32768" is invalid: n2 custom machine types support at most 8192 MB of memory per vCPU, got 32768 MB for 2 vCPUs, use the -ext suffix for extended memory`,
+		},
+		{
+			name:        "E2 does not support extended memory",
+			machineType: "e2-custom-2-32768-ext",
+			wantErr:     `custom machine type "e2-custom-2-32768-ext" is invalid: e2 custom machine types do not support extended memory`,
+		},
+		{
+			name:        "no vCPUs",
+			machineType: "n2-custom-0-4096",
+			wantErr:     `custom machine type "n2-custom-0-4096" has an invalid number of vCPUs "0"`,
+		},
+		{
+			name:        "N4 custom machine type is left to the compute API",
+			machineType: "n4-custom-8-32768",
+		},
+		{
+			name:        "C3 custom machine type is left to the compute API",
+			machineType: "c3-custom-4-16384",
+		},
+		{
+			name:        "E2 shared-core micro custom machine type is left to the compute API",
+			machineType: "e2-custom-micro-1024",
+		},
+		{
+			name:        "E2 shared-core small custom machine type is left to the compute API",
+			machineType: "e2-custom-small-2048",
+		},
+		{
+			name:        "E2 shared-core medium custom machine type is left to the compute API",
+			machineType: "e2-custom-medium-4096",
+		},
+		{
+			name:        "custom machine type in an unknown form is left to the compute API",
+			machineType: "n2-custom-8-32768-ext-beta",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCustomMachineType(tt.machineType)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ParseCustomMachineType() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCustomMachineType() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCustomMachineType() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
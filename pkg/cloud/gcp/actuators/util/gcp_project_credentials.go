@@ -0,0 +1,52 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apicorev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ProjectCredentialsConfigMapName is the ConfigMap, in the namespace of the machines, that maps
+	// the projects machines are created in to the credentials secrets used for them, e.g. for shared
+	// VPC service projects or node pools in other projects than the cluster's.
+	ProjectCredentialsConfigMapName = "gcp-project-credentials"
+	// projectCredentialsKeyPrefix, followed by a project ID, holds the name of the credentials secret of that project.
+	projectCredentialsKeyPrefix = "project."
+	// projectPrefixCredentialsKeyPrefix, followed by a project ID prefix, holds the name of the credentials
+	// secret of the projects starting with that prefix. The longest matching prefix wins.
+	projectPrefixCredentialsKeyPrefix = "projectPrefix."
+)
+
+// credentialsSecretForProject returns the name of the credentials secret the ProjectCredentialsConfigMapName
+// ConfigMap selects for the project, or an empty string if it does not select one.
+func credentialsSecretForProject(coreClient controllerclient.Client, namespace, projectID string) (string, error) {
+	configMap := &apicorev1.ConfigMap{}
+	key := controllerclient.ObjectKey{Namespace: namespace, Name: ProjectCredentialsConfigMapName}
+	if err := coreClient.Get(context.Background(), key, configMap); err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting configmap %q in namespace %q: %v", ProjectCredentialsConfigMapName, namespace, err)
+	}
+
+	if name := configMap.Data[projectCredentialsKeyPrefix+projectID]; name != "" {
+		return name, nil
+	}
+
+	var secretName, longestPrefix string
+	for key, name := range configMap.Data {
+		prefix, ok := strings.CutPrefix(key, projectPrefixCredentialsKeyPrefix)
+		if !ok || name == "" || !strings.HasPrefix(projectID, prefix) {
+			continue
+		}
+		if len(prefix) > len(longestPrefix) || secretName == "" {
+			secretName, longestPrefix = name, prefix
+		}
+	}
+	return secretName, nil
+}
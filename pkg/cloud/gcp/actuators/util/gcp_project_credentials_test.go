@@ -0,0 +1,93 @@
+package util
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetCredentialsForProject(t *testing.T) {
+	secret := func(name string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Data:       map[string][]byte{credentialsSecretKey: []byte(name)},
+		}
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ProjectCredentialsConfigMapName, Namespace: "test"},
+		Data: map[string]string{
+			"project.service-project":  "service-project-credentials",
+			"projectPrefix.pool-":      "pool-credentials",
+			"projectPrefix.pool-east-": "pool-east-credentials",
+		},
+	}
+
+	cases := []struct {
+		name      string
+		projectID string
+		objects   []client.Object
+		expected  string
+	}{
+		{
+			name:     "No project",
+			objects:  []client.Object{configMap},
+			expected: "default-credentials",
+		},
+		{
+			name:      "Project without a ConfigMap",
+			projectID: "service-project",
+			expected:  "default-credentials",
+		},
+		{
+			name:      "Project mapped to a secret",
+			projectID: "service-project",
+			objects:   []client.Object{configMap},
+			expected:  "service-project-credentials",
+		},
+		{
+			name:      "Project matching a prefix",
+			projectID: "pool-west-1",
+			objects:   []client.Object{configMap},
+			expected:  "pool-credentials",
+		},
+		{
+			name:      "Project matching the longest prefix",
+			projectID: "pool-east-1",
+			objects:   []client.Object{configMap},
+			expected:  "pool-east-credentials",
+		},
+		{
+			name:      "Project not mapped",
+			projectID: "other-project",
+			objects:   []client.Object{configMap},
+			expected:  "default-credentials",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := append([]client.Object{
+				secret("default-credentials"),
+				secret("service-project-credentials"),
+				secret("pool-credentials"),
+				secret("pool-east-credentials"),
+			}, tc.objects...)
+			coreClient := controllerfake.NewClientBuilder().WithObjects(objects...).Build()
+
+			credentials, _, err := GetCredentials(coreClient, "test", machinev1.GCPMachineProviderSpec{
+				ProjectID:         tc.projectID,
+				CredentialsSecret: &corev1.LocalObjectReference{Name: "default-credentials"},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if credentials != tc.expected {
+				t.Errorf("Expected credentials of secret %q, got %q", tc.expected, credentials)
+			}
+		})
+	}
+}
@@ -42,6 +42,9 @@ const (
 	// ocpDefaultLabelFmt is the format string for the default label
 	// added to the OpenShift created GCP resources.
 	ocpDefaultLabelFmt = "kubernetes-io-cluster-%s"
+
+	// ClusterOwnedLabelValue is the value of the cluster label on GCP resources owned by the cluster.
+	ClusterOwnedLabelValue = "owned"
 )
 
 // GetInfrastructure returns the Infrastructure object infrastructure/cluster or empty
@@ -71,10 +74,20 @@ func getInfraResourceLabels(platformStatus *configv1.PlatformStatus) (labels map
 // getOCPLabels returns the OCP specific labels to be added to the resources.
 func getOCPLabels(clusterID string) map[string]string {
 	return map[string]string{
-		fmt.Sprintf(ocpDefaultLabelFmt, clusterID): "owned",
+		ClusterOwnedLabelKey(clusterID): ClusterOwnedLabelValue,
 	}
 }
 
+// ClusterOwnedLabelKey returns the key of the label marking GCP resources as owned by the given cluster.
+func ClusterOwnedLabelKey(clusterID string) string {
+	return fmt.Sprintf(ocpDefaultLabelFmt, clusterID)
+}
+
+// IsClusterOwned returns whether the labels of a GCP resource mark it as owned by the given cluster.
+func IsClusterOwned(labels map[string]string, clusterID string) bool {
+	return clusterID != "" && labels[ClusterOwnedLabelKey(clusterID)] == ClusterOwnedLabelValue
+}
+
 // mergeLabels is for merging OCP specific labels, labels defined in Infrastructure.Status and
 // GCPMachineProviderSpec with OCP, GCPMachineProviderSpec, Infrastructure labels precedence order.
 func mergeLabels(ocpLabels, providerSpecLabels, infraLabels map[string]string) map[string]string {
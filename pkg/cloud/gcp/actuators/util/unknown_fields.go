@@ -0,0 +1,131 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// UnknownProviderSpecFields returns the paths, e.g. "disks[1].provisionedIops", of the fields set in
+// the raw providerSpec that GCPMachineProviderSpec does not know, and that are therefore ignored.
+// They are typically set by a newer version of the API than the one the provider is built with.
+func UnknownProviderSpecFields(rawExtension *runtime.RawExtension) ([]string, error) {
+	if rawExtension == nil || len(rawExtension.Raw) == 0 {
+		return nil, nil
+	}
+	raw, err := yaml.YAMLToJSON(rawExtension.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("error converting providerSpec to JSON: %v", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("error unmarshalling providerSpec: %v", err)
+	}
+	var unknown []string
+	collectUnknownFields("", value, reflect.TypeOf(machinev1.GCPMachineProviderSpec{}), &unknown)
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// collectUnknownFields appends the paths of the fields of value, decoded from JSON, that t has no field for.
+func collectUnknownFields(path string, value interface{}, t reflect.Type, unknown *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types decoding themselves, e.g. quantities and times, are not walked.
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := map[string]reflect.Type{}
+		jsonFields(t, fields)
+		for name, fieldValue := range object {
+			fieldType, known := lookupJSONField(fields, name)
+			if !known {
+				*unknown = append(*unknown, joinFieldPath(path, name))
+				continue
+			}
+			collectUnknownFields(joinFieldPath(path, name), fieldValue, fieldType, unknown)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			collectUnknownFields(fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), unknown)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, item := range object {
+			collectUnknownFields(fmt.Sprintf("%s[%s]", path, key), item, t.Elem(), unknown)
+		}
+	}
+}
+
+// jsonFields adds the JSON names of the fields of the struct type t, including those of its
+// inlined embedded structs, to fields.
+func jsonFields(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				jsonFields(embedded, fields)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+}
+
+// lookupJSONField returns the type of the field with the JSON name, which encoding/json matches
+// case-insensitively when there is no exact match.
+func lookupJSONField(fields map[string]reflect.Type, name string) (reflect.Type, bool) {
+	if fieldType, ok := fields[name]; ok {
+		return fieldType, true
+	}
+	for fieldName, fieldType := range fields {
+		if strings.EqualFold(fieldName, name) {
+			return fieldType, true
+		}
+	}
+	return nil, false
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
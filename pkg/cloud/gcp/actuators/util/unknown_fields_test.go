@@ -0,0 +1,47 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestUnknownProviderSpecFields(t *testing.T) {
+	cases := []struct {
+		name     string
+		raw      string
+		expected []string
+	}{
+		{
+			name: "Known fields",
+			raw:  `{"apiVersion":"machine.openshift.io/v1beta1","kind":"GCPMachineProviderSpec","metadata":{"creationTimestamp":null},"zone":"us-east1-b","disks":[{"sizeGb":128,"encryptionKey":{"kmsKey":{"name":"key"}}}],"labels":{"a":"b"},"shieldedInstanceConfig":{"secureBoot":"Enabled"}}`,
+		},
+		{
+			name: "Field names are matched like encoding/json does",
+			raw:  `{"Zone":"us-east1-b"}`,
+		},
+		{
+			name:     "Unknown fields at every level",
+			raw:      `{"zone":"us-east1-b","hostMaintenancePolicy":"Migrate","disks":[{"sizeGb":128},{"sizeGb":64,"provisionedIops":3000}],"networkInterfaces":[{"network":"n","stackType":"IPV4_IPV6"}]}`,
+			expected: []string{"disks[1].provisionedIops", "hostMaintenancePolicy", "networkInterfaces[0].stackType"},
+		},
+		{
+			name:     "YAML",
+			raw:      "zone: us-east1-b\nnewField: true\n",
+			expected: []string{"newField"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			unknown, err := UnknownProviderSpecFields(&runtime.RawExtension{Raw: []byte(tc.raw)})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(unknown, tc.expected) {
+				t.Errorf("Expected unknown fields %v, got %v", tc.expected, unknown)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/machine"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// MachineDefaultingPath and MachineSetDefaultingPath are the paths of the mutating webhooks on
+	// the webhook server, to be set in the MutatingWebhookConfiguration.
+	MachineDefaultingPath    = "/mutate-machine-openshift-io-v1beta1-machine"
+	MachineSetDefaultingPath = "/mutate-machine-openshift-io-v1beta1-machineset"
+)
+
+// machineDefaulter defaults the providerSpec of Machines, and the prefix of their generated names.
+type machineDefaulter struct {
+	decoder  *admission.Decoder
+	defaults machine.ProviderSpecDefaults
+}
+
+// Handle implements admission.Handler.
+func (d *machineDefaulter) Handle(_ context.Context, req admission.Request) admission.Response {
+	m := &machinev1.Machine{}
+	if resp, ok := decodeForDefaulting(d.decoder, req, m); !ok {
+		return resp
+	}
+	if req.Operation == admissionv1.Create && m.Name == "" {
+		m.GenerateName = machine.DefaultGenerateName(m.GenerateName)
+	}
+	if resp, ok := defaultProviderSpec(req, d.defaults, &m.ObjectMeta, &m.Spec.ProviderSpec, &m.Annotations); !ok {
+		return resp
+	}
+	return patchResponse(req, m)
+}
+
+// machineSetDefaulter defaults the providerSpec of the template of MachineSets. The defaults that
+// are annotations go to the template, to be set on the machines of the MachineSet.
+type machineSetDefaulter struct {
+	decoder  *admission.Decoder
+	defaults machine.ProviderSpecDefaults
+}
+
+// Handle implements admission.Handler.
+func (d *machineSetDefaulter) Handle(_ context.Context, req admission.Request) admission.Response {
+	machineSet := &machinev1.MachineSet{}
+	if resp, ok := decodeForDefaulting(d.decoder, req, machineSet); !ok {
+		return resp
+	}
+	template := &machineSet.Spec.Template
+	if resp, ok := defaultProviderSpec(req, d.defaults, &machineSet.ObjectMeta, &template.Spec.ProviderSpec, &template.ObjectMeta.Annotations); !ok {
+		return resp
+	}
+	return patchResponse(req, machineSet)
+}
+
+// decodeForDefaulting decodes the object of a creation or an update, and returns false with the
+// response to give when the object must not be defaulted.
+func decodeForDefaulting(decoder *admission.Decoder, req admission.Request, object metav1.Object) (admission.Response, bool) {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed(""), false
+	}
+	if err := decoder.DecodeRaw(req.Object, object.(runtime.Object)); err != nil {
+		return admission.Errored(http.StatusBadRequest, err), false
+	}
+	if object.GetDeletionTimestamp() != nil {
+		return admission.Allowed("the object is being deleted"), false
+	}
+	return admission.Response{}, true
+}
+
+// defaultProviderSpec applies the defaults to the providerSpec of the object, and to the
+// annotations of its machines. A new object gets the configured version of the defaults, unless it
+// names one, which is recorded on it; an updated object gets the version recorded at its creation,
+// so that objects created before the defaults are left alone.
+func defaultProviderSpec(req admission.Request, defaults machine.ProviderSpecDefaults, meta *metav1.ObjectMeta, providerSpec *machinev1.ProviderSpec, machineAnnotations *map[string]string) (admission.Response, bool) {
+	version, err := machine.DefaultsVersion(meta.Annotations)
+	if err != nil {
+		return admission.Denied(err.Error()), false
+	}
+	if _, recorded := meta.Annotations[machine.DefaultsVersionAnnotation]; !recorded && req.Operation == admissionv1.Create && defaults.Version > 0 {
+		version = defaults.Version
+		metav1.SetMetaDataAnnotation(meta, machine.DefaultsVersionAnnotation, strconv.Itoa(version))
+	}
+	if version == 0 || providerSpec.Value == nil {
+		return admission.Response{}, true
+	}
+
+	raw, annotations, err := defaults.Default(version, providerSpec.Value.Raw, *machineAnnotations)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err), false
+	}
+	providerSpec.Value = &runtime.RawExtension{Raw: raw}
+	if len(annotations) > 0 {
+		*machineAnnotations = annotations
+	}
+	return admission.Response{}, true
+}
+
+// patchResponse allows the request with the patch from its object to the defaulted one.
+func patchResponse(req admission.Request, object runtime.Object) admission.Response {
+	defaulted, err := json.Marshal(object)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, defaulted)
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/machine"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestDefaulters(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder := admission.NewDecoder(scheme)
+	defaults := machine.ProviderSpecDefaults{Version: machine.LatestDefaultsVersion, CredentialsSecret: machine.DefaultCredentialsSecret}
+	providerSpec := machinev1.ProviderSpec{Value: &runtime.RawExtension{Raw: []byte(`{"zone":"us-central1-a"}`)}}
+
+	newMachine := func(annotations map[string]string) runtime.Object {
+		return &machinev1.Machine{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "machine.openshift.io/v1beta1", Kind: "Machine"},
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "cluster.example-worker-", Namespace: "openshift-machine-api", Annotations: annotations},
+			Spec:       machinev1.MachineSpec{ProviderSpec: providerSpec},
+		}
+	}
+	newMachineSet := func(annotations map[string]string) runtime.Object {
+		machineSet := &machinev1.MachineSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "machine.openshift.io/v1beta1", Kind: "MachineSet"},
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "openshift-machine-api", Annotations: annotations},
+		}
+		machineSet.Spec.Template.Spec.ProviderSpec = providerSpec
+		return machineSet
+	}
+	machineProviderSpecPath := "/spec/providerSpec/value"
+	machineSetProviderSpecPath := "/spec/template/spec/providerSpec/value"
+
+	defaulters := []struct {
+		kind             string
+		handler          admission.Handler
+		object           func(annotations map[string]string) runtime.Object
+		providerSpecPath string
+	}{
+		{kind: "Machine", handler: &machineDefaulter{decoder: decoder, defaults: defaults}, object: newMachine, providerSpecPath: machineProviderSpecPath},
+		{kind: "MachineSet", handler: &machineSetDefaulter{decoder: decoder, defaults: defaults}, object: newMachineSet, providerSpecPath: machineSetProviderSpecPath},
+	}
+	cases := []struct {
+		name              string
+		operation         admissionv1.Operation
+		annotations       map[string]string
+		expectDefaults    bool
+		expectVersionSet  bool
+		expectGenerateSet bool
+	}{
+		{name: "Create", operation: admissionv1.Create, expectDefaults: true, expectVersionSet: true, expectGenerateSet: true},
+		{name: "Create pinned to no defaults", operation: admissionv1.Create, annotations: map[string]string{machine.DefaultsVersionAnnotation: "0"}, expectGenerateSet: true},
+		{name: "Update of an object created before the defaults", operation: admissionv1.Update},
+		{name: "Update of a defaulted object", operation: admissionv1.Update, annotations: map[string]string{machine.DefaultsVersionAnnotation: "1"}, expectDefaults: true},
+	}
+
+	for _, defaulter := range defaulters {
+		for _, tc := range cases {
+			t.Run(defaulter.kind+"/"+tc.name, func(t *testing.T) {
+				req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: tc.operation}}
+				req.Object = rawObject(t, defaulter.object(tc.annotations))
+				resp := defaulter.handler.Handle(context.Background(), req)
+				if !resp.Allowed {
+					t.Fatalf("Expected the request to be allowed, got %v", resp.Result)
+				}
+
+				paths := map[string]bool{}
+				for _, patch := range resp.Patches {
+					paths[patch.Path] = true
+				}
+				if paths[defaulter.providerSpecPath+"/region"] != tc.expectDefaults {
+					t.Errorf("Expected the region to be defaulted: %v, got patches %v", tc.expectDefaults, resp.Patches)
+				}
+				if paths["/metadata/annotations"] != tc.expectVersionSet {
+					t.Errorf("Expected the defaults version to be recorded: %v, got patches %v", tc.expectVersionSet, resp.Patches)
+				}
+				if defaulter.kind == "Machine" && paths["/metadata/generateName"] != tc.expectGenerateSet {
+					t.Errorf("Expected the generated name to be defaulted: %v, got patches %v", tc.expectGenerateSet, resp.Patches)
+				}
+			})
+		}
+	}
+}
+
+func TestDefaultersKeepDeletingObjects(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	now := metav1.Now()
+	m := &machinev1.Machine{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "machine.openshift.io/v1beta1", Kind: "Machine"},
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", DeletionTimestamp: &now, Annotations: map[string]string{machine.DefaultsVersionAnnotation: "1"}},
+		Spec:       machinev1.MachineSpec{ProviderSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{Raw: []byte(`{"zone":"us-central1-a"}`)}}},
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaulter := &machineDefaulter{decoder: admission.NewDecoder(scheme), defaults: machine.ProviderSpecDefaults{Version: 1}}
+	resp := defaulter.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Update,
+		Object:    runtime.RawExtension{Raw: data},
+	}})
+	if !resp.Allowed || len(resp.Patches) > 0 {
+		t.Errorf("Expected a deleting machine to be allowed unchanged, got %v", resp)
+	}
+}
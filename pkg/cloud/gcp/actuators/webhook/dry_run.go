@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// DryRunner runs the creation of the instance of a machine without creating anything, see
+// machine.Actuator.DryRun.
+type DryRunner interface {
+	DryRun(ctx context.Context, machine *machinev1.Machine) error
+}
+
+// dryRun completes the response of the validation of a dry-run request, e.g. of
+// `oc apply --dry-run=server`, with a dry run of the creation of the instance of the machine: the
+// request is denied with the error the creation would fail with, or allowed with a warning telling
+// the dry run succeeded. Other requests, objects being deleted and requests already denied are left
+// alone.
+func dryRun(ctx context.Context, dryRunner DryRunner, req admission.Request, resp admission.Response, machine *machinev1.Machine) admission.Response {
+	if dryRunner == nil || req.DryRun == nil || !*req.DryRun || !resp.Allowed || machine.DeletionTimestamp != nil {
+		return resp
+	}
+	if err := dryRunner.DryRun(ctx, machine); err != nil {
+		return admission.Denied(fmt.Sprintf("dry run of the instance creation failed: %v", err))
+	}
+	return resp.WithWarnings(fmt.Sprintf("dry run of the instance creation of machine %s succeeded", machine.Name))
+}
+
+// dryRunMachine returns a machine of the MachineSet, named after it, to dry run its creation.
+func dryRunMachine(machineSet *machinev1.MachineSet, namespace string) *machinev1.Machine {
+	return &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        machineSet.Name + "-dry-run",
+			Namespace:   namespace,
+			Labels:      machineSet.Spec.Template.Labels,
+			Annotations: machineSet.Spec.Template.Annotations,
+		},
+		Spec: machineSet.Spec.Template.Spec,
+	}
+}
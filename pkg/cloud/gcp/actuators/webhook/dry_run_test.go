@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+type fakeDryRunner struct {
+	machines []*machinev1.Machine
+	err      error
+}
+
+func (f *fakeDryRunner) DryRun(_ context.Context, machine *machinev1.Machine) error {
+	f.machines = append(f.machines, machine)
+	return f.err
+}
+
+func TestValidatorsDryRun(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder := admission.NewDecoder(scheme)
+
+	providerSpec := func(zone string) machinev1.ProviderSpec {
+		value, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+			Region:      "us-central1",
+			Zone:        zone,
+			MachineType: "n1-standard-4",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return machinev1.ProviderSpec{Value: value}
+	}
+	machineSet := func(zone string) runtime.Object {
+		machineSet := &machinev1.MachineSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "machine.openshift.io/v1beta1", Kind: "MachineSet"},
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "openshift-machine-api"},
+		}
+		machineSet.Spec.Template.Labels = map[string]string{machinev1.MachineClusterIDLabel: "cluster"}
+		machineSet.Spec.Template.Annotations = map[string]string{"machine.openshift.io/gcp-static-internal-ip": "true"}
+		machineSet.Spec.Template.Spec.ProviderSpec = providerSpec(zone)
+		return machineSet
+	}
+	machine := func(providerID string) runtime.Object {
+		machine := &machinev1.Machine{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "machine.openshift.io/v1beta1", Kind: "Machine"},
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-a"},
+			Spec:       machinev1.MachineSpec{ProviderSpec: providerSpec("us-central1-a")},
+		}
+		if providerID != "" {
+			machine.Spec.ProviderID = &providerID
+		}
+		return machine
+	}
+	dryRunRequest := func(object runtime.Object) admission.Request {
+		dryRun := true
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "openshift-machine-api",
+			DryRun:    &dryRun,
+			Object:    rawObject(t, object),
+		}}
+	}
+
+	cases := []struct {
+		name            string
+		kind            string
+		req             admission.Request
+		err             error
+		expectedAllowed bool
+		expectedMachine string
+		expectedMessage string
+	}{
+		{
+			name: "Not a dry run",
+			kind: "MachineSet",
+			req: admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Object:    rawObject(t, machineSet("us-central1-a")),
+			}},
+			expectedAllowed: true,
+		},
+		{
+			name:            "MachineSet dry run succeeds",
+			kind:            "MachineSet",
+			req:             dryRunRequest(machineSet("us-central1-a")),
+			expectedAllowed: true,
+			expectedMachine: "worker-dry-run",
+		},
+		{
+			name:            "MachineSet dry run fails",
+			kind:            "MachineSet",
+			req:             dryRunRequest(machineSet("us-central1-a")),
+			err:             errors.New("machine type n1-standard-4 is not available in zone us-central1-a"),
+			expectedMachine: "worker-dry-run",
+			expectedMessage: "dry run of the instance creation failed: machine type n1-standard-4 is not available in zone us-central1-a",
+		},
+		{
+			name: "Invalid providerSpec is not dry run",
+			kind: "MachineSet",
+			req:  dryRunRequest(machineSet("europe-west1-b")),
+		},
+		{
+			name:            "Machine dry run succeeds",
+			kind:            "Machine",
+			req:             dryRunRequest(machine("")),
+			expectedAllowed: true,
+			expectedMachine: "worker-a",
+		},
+		{
+			name:            "Machine with an instance is not dry run",
+			kind:            "Machine",
+			req:             dryRunRequest(machine("gce://project/us-central1-a/worker-a")),
+			expectedAllowed: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dryRunner := &fakeDryRunner{err: tc.err}
+			var handler admission.Handler = &machineSetValidator{decoder: decoder, dryRunner: dryRunner}
+			if tc.kind == "Machine" {
+				handler = &machineValidator{decoder: decoder, dryRunner: dryRunner}
+			}
+
+			resp := handler.Handle(context.Background(), tc.req)
+			if resp.Allowed != tc.expectedAllowed {
+				t.Errorf("Expected allowed to be %v, got %v: %v", tc.expectedAllowed, resp.Allowed, resp.Result)
+			}
+			if tc.expectedMessage != "" && (resp.Result == nil || resp.Result.Message != tc.expectedMessage) {
+				t.Errorf("Expected message %q, got %v", tc.expectedMessage, resp.Result)
+			}
+
+			if tc.expectedMachine == "" {
+				if len(dryRunner.machines) != 0 {
+					t.Errorf("Expected no dry run, got %d", len(dryRunner.machines))
+				}
+				return
+			}
+			if len(dryRunner.machines) != 1 {
+				t.Fatalf("Expected one dry run, got %d", len(dryRunner.machines))
+			}
+			dryRunMachine := dryRunner.machines[0]
+			if dryRunMachine.Name != tc.expectedMachine || dryRunMachine.Namespace != "openshift-machine-api" {
+				t.Errorf("Expected machine openshift-machine-api/%s, got %s/%s", tc.expectedMachine, dryRunMachine.Namespace, dryRunMachine.Name)
+			}
+			if tc.kind == "MachineSet" && (dryRunMachine.Labels[machinev1.MachineClusterIDLabel] != "cluster" || len(dryRunMachine.Annotations) != 1) {
+				t.Errorf("Expected the labels and annotations of the machine template, got %v and %v", dryRunMachine.Labels, dryRunMachine.Annotations)
+			}
+			if tc.expectedAllowed && (len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "succeeded")) {
+				t.Errorf("Expected a warning telling the dry run succeeded, got %v", resp.Warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Package webhook validates the GCP providerSpec of Machines and MachineSets on admission, so that
+// a misconfigured machine is rejected when it is applied instead of failing at instance creation,
+// possibly long after, on every machine a MachineSet scales up. It also defaults the providerSpec,
+// so that a minimal MachineSet is usable.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// MachinePath and MachineSetPath are the paths of the validating webhooks on the webhook server,
+	// to be set in the ValidatingWebhookConfiguration.
+	MachinePath    = "/validate-machine-openshift-io-v1beta1-machine"
+	MachineSetPath = "/validate-machine-openshift-io-v1beta1-machineset"
+
+	// userDataSecretKey is the key of the user data in the userDataSecret, as read by the reconciler.
+	userDataSecretKey = "userData"
+)
+
+// SetupWithManager registers the validating and defaulting webhooks of Machines and MachineSets on
+// the webhook server of the manager. The validating webhooks dry run the creation of the instance
+// on dry-run requests when dryRunner is set.
+func SetupWithManager(mgr ctrl.Manager, defaults machine.ProviderSpecDefaults, dryRunner DryRunner) error {
+	decoder := admission.NewDecoder(mgr.GetScheme())
+	// The user data secrets are read uncached, machines are not admitted often enough to justify
+	// watching all the secrets.
+	reader := mgr.GetAPIReader()
+	server := mgr.GetWebhookServer()
+	server.Register(MachinePath, &admission.Webhook{Handler: &machineValidator{decoder: decoder, reader: reader, dryRunner: dryRunner}})
+	server.Register(MachineSetPath, &admission.Webhook{Handler: &machineSetValidator{decoder: decoder, reader: reader, dryRunner: dryRunner}})
+	server.Register(MachineDefaultingPath, &admission.Webhook{Handler: &machineDefaulter{decoder: decoder, defaults: defaults}})
+	server.Register(MachineSetDefaultingPath, &admission.Webhook{Handler: &machineSetDefaulter{decoder: decoder, defaults: defaults}})
+	return nil
+}
+
+// machineValidator validates the providerSpec of Machines.
+type machineValidator struct {
+	decoder   *admission.Decoder
+	reader    client.Reader
+	dryRunner DryRunner
+}
+
+// Handle implements admission.Handler.
+func (v *machineValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+	newMachine, oldMachine := &machinev1.Machine{}, &machinev1.Machine{}
+	if err := v.decoder.DecodeRaw(req.Object, newMachine); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if req.Operation == admissionv1.Update {
+		if err := v.decoder.DecodeRaw(req.OldObject, oldMachine); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	fldPath := field.NewPath("spec", "providerSpec", "value")
+	resp := validate(ctx, v.reader, req, newMachine.DeletionTimestamp != nil, newMachine.Spec.ProviderSpec, oldMachine.Spec.ProviderSpec, fldPath)
+	if resp.Allowed && req.Operation == admissionv1.Update && newMachine.DeletionTimestamp == nil && hasInstance(oldMachine) {
+		resp = validateInPlaceUpdate(newMachine.Spec.ProviderSpec, oldMachine.Spec.ProviderSpec, fldPath)
+	}
+	if hasInstance(newMachine) {
+		return resp
+	}
+	if newMachine.Namespace == "" {
+		newMachine.Namespace = req.Namespace
+	}
+	return dryRun(ctx, v.dryRunner, req, resp, newMachine)
+}
+
+// hasInstance reports whether an instance was created for the machine.
+func hasInstance(m *machinev1.Machine) bool {
+	return m.Spec.ProviderID != nil && *m.Spec.ProviderID != ""
+}
+
+// validateInPlaceUpdate denies the changes of the providerSpec of a machine with an instance that
+// the reconciler cannot apply to the instance, instead of leaving the machine to drift from it.
+// The providerSpec of MachineSets can change freely, it only applies to their new machines.
+func validateInPlaceUpdate(newSpec, oldSpec machinev1.ProviderSpec, fldPath *field.Path) admission.Response {
+	if rawEqual(newSpec.Value, oldSpec.Value) {
+		return admission.Allowed("the providerSpec is unchanged")
+	}
+	newProviderSpec, err := util.ProviderSpecFromRawExtension(newSpec.Value)
+	if err != nil {
+		return admission.Denied(field.ErrorList{field.Invalid(fldPath, "", err.Error())}.ToAggregate().Error())
+	}
+	oldProviderSpec, err := util.ProviderSpecFromRawExtension(oldSpec.Value)
+	if err != nil {
+		return admission.Allowed("the previous providerSpec is invalid")
+	}
+	if errs := machine.ValidateProviderSpecUpdate(newProviderSpec, oldProviderSpec, fldPath); len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+// machineSetValidator validates the providerSpec of the template of MachineSets.
+type machineSetValidator struct {
+	decoder   *admission.Decoder
+	reader    client.Reader
+	dryRunner DryRunner
+}
+
+// Handle implements admission.Handler.
+func (v *machineSetValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+	newMachineSet, oldMachineSet := &machinev1.MachineSet{}, &machinev1.MachineSet{}
+	if err := v.decoder.DecodeRaw(req.Object, newMachineSet); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if req.Operation == admissionv1.Update {
+		if err := v.decoder.DecodeRaw(req.OldObject, oldMachineSet); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	resp := validate(ctx, v.reader, req, newMachineSet.DeletionTimestamp != nil, newMachineSet.Spec.Template.Spec.ProviderSpec, oldMachineSet.Spec.Template.Spec.ProviderSpec, field.NewPath("spec", "template", "spec", "providerSpec", "value"))
+	if newMachineSet.DeletionTimestamp != nil {
+		return resp
+	}
+	return dryRun(ctx, v.dryRunner, req, resp, dryRunMachine(newMachineSet, req.Namespace))
+}
+
+// validate denies the creation of an object with an invalid providerSpec, and updates changing the
+// providerSpec to an invalid one. Updates leaving the providerSpec alone are allowed, so that the
+// objects created before the webhook, or by an older version of it, can still be scaled, relabelled
+// and deleted.
+func validate(ctx context.Context, reader client.Reader, req admission.Request, deleting bool, newSpec, oldSpec machinev1.ProviderSpec, fldPath *field.Path) admission.Response {
+	if deleting {
+		return admission.Allowed("the object is being deleted")
+	}
+	if req.Operation == admissionv1.Update && rawEqual(newSpec.Value, oldSpec.Value) {
+		return admission.Allowed("the providerSpec is unchanged")
+	}
+	if newSpec.Value == nil {
+		return admission.Denied(field.ErrorList{field.Required(fldPath, "providerSpec must be set")}.ToAggregate().Error())
+	}
+
+	providerSpec, err := util.ProviderSpecFromRawExtension(newSpec.Value)
+	if err != nil {
+		return admission.Denied(field.ErrorList{field.Invalid(fldPath, "", err.Error())}.ToAggregate().Error())
+	}
+	errs := machine.ValidateProviderSpec(providerSpec, fldPath)
+	errs = append(errs, validateUserData(ctx, reader, req.Namespace, providerSpec, fldPath.Child("userDataSecret"))...)
+	if len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+// validateUserData checks the size of the user data of the userDataSecret with the metadata. A
+// secret that cannot be read is not checked, it may be created after the object, and the reconciler
+// checks the metadata again before creating the instance.
+func validateUserData(ctx context.Context, reader client.Reader, namespace string, providerSpec *machinev1.GCPMachineProviderSpec, fldPath *field.Path) field.ErrorList {
+	if reader == nil || providerSpec.UserDataSecret == nil || providerSpec.UserDataSecret.Name == "" {
+		return nil
+	}
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: providerSpec.UserDataSecret.Name}, secret); err != nil {
+		klog.V(2).InfoS("Not checking the size of the user data", "secret", klog.KRef(namespace, providerSpec.UserDataSecret.Name), "err", err)
+		return nil
+	}
+	return machine.ValidateUserData(secret.Data[userDataSecretKey], providerSpec.Metadata, fldPath)
+}
+
+// rawEqual reports whether the providerSpecs are the same. Both the old and the new object are
+// serialized by the API server, so an unchanged providerSpec has the same bytes.
+func rawEqual(a, b *runtime.RawExtension) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return bytes.Equal(a.Raw, b.Raw)
+}
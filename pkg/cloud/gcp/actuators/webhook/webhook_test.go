@@ -0,0 +1,241 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidators(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder := admission.NewDecoder(scheme)
+
+	providerSpec := func(zone string) machinev1.ProviderSpec {
+		value, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+			Region:      "us-central1",
+			Zone:        zone,
+			MachineType: "n1-standard-4",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return machinev1.ProviderSpec{Value: value}
+	}
+	newMachine := func(zone string, deleting bool) runtime.Object {
+		machine := &machinev1.Machine{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "machine.openshift.io/v1beta1", Kind: "Machine"},
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "openshift-machine-api"},
+			Spec:       machinev1.MachineSpec{ProviderSpec: providerSpec(zone)},
+		}
+		if deleting {
+			now := metav1.Now()
+			machine.DeletionTimestamp = &now
+		}
+		return machine
+	}
+	newMachineSet := func(zone string, deleting bool) runtime.Object {
+		machineSet := &machinev1.MachineSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "machine.openshift.io/v1beta1", Kind: "MachineSet"},
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "openshift-machine-api"},
+		}
+		machineSet.Spec.Template.Spec.ProviderSpec = providerSpec(zone)
+		if deleting {
+			now := metav1.Now()
+			machineSet.DeletionTimestamp = &now
+		}
+		return machineSet
+	}
+
+	validators := []struct {
+		kind    string
+		handler admission.Handler
+		object  func(zone string, deleting bool) runtime.Object
+	}{
+		{kind: "Machine", handler: &machineValidator{decoder: decoder}, object: newMachine},
+		{kind: "MachineSet", handler: &machineSetValidator{decoder: decoder}, object: newMachineSet},
+	}
+	cases := []struct {
+		name      string
+		operation admissionv1.Operation
+		oldZone   string
+		newZone   string
+		deleting  bool
+		allowed   bool
+	}{
+		{name: "Create valid", operation: admissionv1.Create, newZone: "us-central1-a", allowed: true},
+		{name: "Create with zone of another region", operation: admissionv1.Create, newZone: "europe-west1-b"},
+		{name: "Update to an invalid zone", operation: admissionv1.Update, oldZone: "us-central1-a", newZone: "europe-west1-b"},
+		{name: "Update keeping an invalid zone", operation: admissionv1.Update, oldZone: "europe-west1-b", newZone: "europe-west1-b", allowed: true},
+		{name: "Update while deleting", operation: admissionv1.Update, oldZone: "us-central1-a", newZone: "europe-west1-b", deleting: true, allowed: true},
+		{name: "Delete", operation: admissionv1.Delete, allowed: true},
+	}
+
+	for _, validator := range validators {
+		for _, tc := range cases {
+			t.Run(validator.kind+"/"+tc.name, func(t *testing.T) {
+				req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: tc.operation}}
+				if tc.newZone != "" {
+					req.Object = rawObject(t, validator.object(tc.newZone, tc.deleting))
+				}
+				if tc.oldZone != "" {
+					req.OldObject = rawObject(t, validator.object(tc.oldZone, false))
+				}
+				resp := validator.handler.Handle(context.Background(), req)
+				if resp.Allowed != tc.allowed {
+					t.Errorf("Expected allowed to be %v, got %v: %v", tc.allowed, resp.Allowed, resp.Result)
+				}
+			})
+		}
+	}
+}
+
+func rawObject(t *testing.T, object runtime.Object) runtime.RawExtension {
+	data, err := json.Marshal(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: data}
+}
+
+func TestMachineValidatorImmutableFields(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	validator := &machineValidator{decoder: admission.NewDecoder(scheme)}
+
+	newMachine := func(providerID, zone, subnetwork string, labels map[string]string) runtime.Object {
+		value, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+			Region:            "us-central1",
+			Zone:              zone,
+			MachineType:       "n1-standard-4",
+			NetworkInterfaces: []*machinev1.GCPNetworkInterface{{Network: "net", Subnetwork: subnetwork}},
+			Labels:            labels,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		machine := &machinev1.Machine{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "machine.openshift.io/v1beta1", Kind: "Machine"},
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "openshift-machine-api"},
+			Spec:       machinev1.MachineSpec{ProviderSpec: machinev1.ProviderSpec{Value: value}},
+		}
+		if providerID != "" {
+			machine.Spec.ProviderID = &providerID
+		}
+		return machine
+	}
+	providerID := "gce://project/us-central1-a/worker"
+
+	cases := []struct {
+		name      string
+		oldObject runtime.Object
+		newObject runtime.Object
+		allowed   bool
+	}{
+		{
+			name:      "Labels of a machine with an instance",
+			oldObject: newMachine(providerID, "us-central1-a", "workers", nil),
+			newObject: newMachine(providerID, "us-central1-a", "workers", map[string]string{"team": "infra"}),
+			allowed:   true,
+		},
+		{
+			name:      "Zone of a machine with an instance",
+			oldObject: newMachine(providerID, "us-central1-a", "workers", nil),
+			newObject: newMachine(providerID, "us-central1-b", "workers", nil),
+		},
+		{
+			name:      "Subnetwork of a machine with an instance",
+			oldObject: newMachine(providerID, "us-central1-a", "workers", nil),
+			newObject: newMachine(providerID, "us-central1-a", "infra", nil),
+		},
+		{
+			name:      "Zone of a machine without an instance",
+			oldObject: newMachine("", "us-central1-a", "workers", nil),
+			newObject: newMachine("", "us-central1-b", "workers", nil),
+			allowed:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := validator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Update,
+				Object:    rawObject(t, tc.newObject),
+				OldObject: rawObject(t, tc.oldObject),
+			}})
+			if resp.Allowed != tc.allowed {
+				t.Errorf("Expected allowed to be %v, got %v: %v", tc.allowed, resp.Allowed, resp.Result)
+			}
+		})
+	}
+}
+
+func TestValidatorsUserData(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-user-data", Namespace: "openshift-machine-api"},
+			Data:       map[string][]byte{userDataSecretKey: []byte(`{"ignition":{"version":"3.2.0"}}`)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "large-user-data", Namespace: "openshift-machine-api"},
+			Data:       map[string][]byte{userDataSecretKey: make([]byte, 300*1024)},
+		},
+	).Build()
+	validator := &machineValidator{decoder: admission.NewDecoder(scheme), reader: reader}
+
+	cases := []struct {
+		secret  string
+		allowed bool
+	}{
+		{secret: "worker-user-data", allowed: true},
+		{secret: "large-user-data"},
+		{secret: "missing-user-data", allowed: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.secret, func(t *testing.T) {
+			value, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+				Region:         "us-central1",
+				Zone:           "us-central1-a",
+				MachineType:    "n1-standard-4",
+				UserDataSecret: &corev1.LocalObjectReference{Name: tc.secret},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			machine := &machinev1.Machine{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "machine.openshift.io/v1beta1", Kind: "Machine"},
+				ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "openshift-machine-api"},
+				Spec:       machinev1.MachineSpec{ProviderSpec: machinev1.ProviderSpec{Value: value}},
+			}
+			resp := validator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Namespace: "openshift-machine-api",
+				Object:    rawObject(t, machine),
+			}})
+			if resp.Allowed != tc.allowed {
+				t.Errorf("Expected allowed to be %v, got %v: %v", tc.allowed, resp.Allowed, resp.Result)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package termination
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TerminationAction is what the handler does once the instance is terminating.
+type TerminationAction string
+
+const (
+	// TerminationActionMarkNode adds the Terminating condition to the node, leaving it to a
+	// MachineHealthCheck to delete the machine.
+	TerminationActionMarkNode TerminationAction = "mark-node"
+	// TerminationActionDeleteMachine also deletes the machine of the node, so that the MachineSet
+	// requests a replacement immediately instead of waiting for a MachineHealthCheck.
+	TerminationActionDeleteMachine TerminationAction = "delete-machine"
+)
+
+// ParseTerminationAction returns the action of the given name.
+func ParseTerminationAction(name string) (TerminationAction, error) {
+	switch action := TerminationAction(name); action {
+	case TerminationActionMarkNode, TerminationActionDeleteMachine:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown termination action %q, expected %s or %s", name, TerminationActionMarkNode, TerminationActionDeleteMachine)
+}
+
+// handleTermination marks the node for deletion, taints it when a taint effect is set and, with
+// TerminationActionDeleteMachine, deletes its machine. It is retried as a whole, every step is
+// idempotent.
+func (h *handler) handleTermination(ctx context.Context, action TerminationAction, taintEffect corev1.TaintEffect) error {
+	if err := h.markNodeForDeletion(ctx); err != nil {
+		return err
+	}
+	if taintEffect != "" {
+		if err := h.taintNode(ctx, taintEffect); err != nil {
+			return err
+		}
+	}
+	if action != TerminationActionDeleteMachine {
+		return nil
+	}
+	return h.deleteMachine(ctx)
+}
+
+// deleteMachine deletes the machine of the node. Nodes without a machine are only marked, and a
+// machine that is already gone is not an error.
+func (h *handler) deleteMachine(ctx context.Context) error {
+	node := &corev1.Node{}
+	if err := h.client.Get(ctx, client.ObjectKey{Name: h.nodeName}, node); err != nil {
+		return fmt.Errorf("error fetching node: %v", err)
+	}
+	namespace, name, ok := strings.Cut(node.Annotations[machineAnnotation], "/")
+	if !ok {
+		h.log.Info("Node has no machine, only marking it for deletion", "annotation", machineAnnotation)
+		return nil
+	}
+	if h.namespace != "" && namespace != h.namespace {
+		h.log.Info("Machine of the node is not in the namespace of the handler, only marking the node for deletion", "machine", namespace+"/"+name)
+		return nil
+	}
+
+	machine := partialObject("Machine")
+	machine.Namespace, machine.Name = namespace, name
+	if err := h.client.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting machine %s/%s: %v", namespace, name, err)
+	}
+	machinesDeletedTotal.Inc()
+	h.log.V(1).Info("Deleted machine of the terminating instance", "machine", namespace+"/"+name)
+	return nil
+}
@@ -0,0 +1,104 @@
+package termination
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTerminationAction(t *testing.T) {
+	cases := []struct {
+		name            string
+		action          TerminationAction
+		namespace       string
+		nodeAnnotations map[string]string
+		noMachine       bool
+		expectedDeleted bool
+	}{
+		{
+			name:            "Mark node keeps the machine",
+			action:          TerminationActionMarkNode,
+			nodeAnnotations: map[string]string{machineAnnotation: "openshift-machine-api/spot-a"},
+		},
+		{
+			name:            "Delete machine",
+			action:          TerminationActionDeleteMachine,
+			nodeAnnotations: map[string]string{machineAnnotation: "openshift-machine-api/spot-a"},
+			expectedDeleted: true,
+		},
+		{
+			name:            "Machine already deleted",
+			action:          TerminationActionDeleteMachine,
+			nodeAnnotations: map[string]string{machineAnnotation: "openshift-machine-api/spot-a"},
+			noMachine:       true,
+			expectedDeleted: true,
+		},
+		{
+			name:   "Node without machine is only marked",
+			action: TerminationActionDeleteMachine,
+		},
+		{
+			name:            "Machine outside of the namespace of the handler is kept",
+			action:          TerminationActionDeleteMachine,
+			namespace:       "other",
+			nodeAnnotations: map[string]string{machineAnnotation: "openshift-machine-api/spot-a"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: tc.nodeAnnotations}}
+			objects := []client.Object{node}
+			if !tc.noMachine {
+				objects = append(objects, &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "spot-a", Namespace: "openshift-machine-api"}})
+			}
+			c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(objects...).WithStatusSubresource(node).Build()
+			h := &handler{
+				client:                c,
+				nodeName:              "node",
+				namespace:             tc.namespace,
+				log:                   klogr.New(),
+				markRetryWindow:       100 * time.Millisecond,
+				markInitialBackoff:    time.Millisecond,
+				markMaxBackoff:        5 * time.Millisecond,
+				circuitBreakerTrigger: 5,
+			}
+			h.Reconfigure(WithTerminationAction(tc.action))
+
+			if err := h.markNodeWithRetry(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			updated := &corev1.Node{}
+			if err := c.Get(context.Background(), client.ObjectKey{Name: "node"}, updated); err != nil {
+				t.Fatal(err)
+			}
+			if !nodeHasTerminationCondition(updated) {
+				t.Error("Expected the node to be marked")
+			}
+			err := c.Get(context.Background(), client.ObjectKey{Namespace: "openshift-machine-api", Name: "spot-a"}, &machinev1.Machine{})
+			if deleted := apierrors.IsNotFound(err); deleted != tc.expectedDeleted {
+				t.Errorf("Expected machine deleted %v, got %v", tc.expectedDeleted, err)
+			}
+		})
+	}
+}
+
+func TestParseTerminationAction(t *testing.T) {
+	for _, name := range []string{"mark-node", "delete-machine"} {
+		if action, err := ParseTerminationAction(name); err != nil || string(action) != name {
+			t.Errorf("Expected action %s, got %s, %v", name, action, err)
+		}
+	}
+	if _, err := ParseTerminationAction("annotate"); err == nil {
+		t.Error("Expected an error for an unknown action")
+	}
+}
@@ -0,0 +1,189 @@
+package termination
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const defaultPollInterval = 5 * time.Second
+
+// Config is the configuration file of the termination handler, e.g.
+//
+//	pollInterval: 5s
+//	operationPollInterval: 30s
+//	metricsAddress: ":8080"
+//	journalPath: /var/lib/termination-handler/journal
+//	drain:
+//	  markRetryWindow: 30s
+//	  action: mark-node
+//	  taintEffect: NoSchedule
+//	features:
+//	  circuitBreaker: false
+//	  maintenanceEvents: true
+//	  waitForChange: true
+//
+// Everything but the namespace and the metrics address is applied again when the handler
+// receives SIGHUP.
+type Config struct {
+	// PollInterval is the interval at which the termination notice endpoint is checked.
+	PollInterval metav1.Duration `json:"pollInterval,omitempty"`
+	// OperationPollInterval is the interval at which the compute operations of the instance, e.g.
+	// a preemption or a simulated maintenance event, are checked as a second source of termination
+	// notices. It is disabled when zero, enabling it on reload takes a restart of the handler.
+	OperationPollInterval metav1.Duration `json:"operationPollInterval,omitempty"`
+	// Namespace is the namespace that the machine for the node lives in. All namespaces
+	// are searched when it is empty.
+	Namespace string `json:"namespace,omitempty"`
+	// MetricsAddress is the address the metrics endpoint and the /healthz and /readyz probes
+	// bind to. They are disabled when empty or "0".
+	MetricsAddress string `json:"metricsAddress,omitempty"`
+	// JournalPath is the file a pending node marking is recorded in, so that it is applied
+	// after a restart of the handler.
+	JournalPath string `json:"journalPath,omitempty"`
+	// Drain configures how the node is marked for deletion, which makes it drained.
+	Drain DrainConfig `json:"drain,omitempty"`
+	// Features toggles optional behaviour of the handler.
+	Features FeaturesConfig `json:"features,omitempty"`
+}
+
+// DrainConfig configures how the node is marked for deletion once the instance is terminating.
+type DrainConfig struct {
+	// MarkRetryWindow is how long to keep retrying to mark the node.
+	MarkRetryWindow metav1.Duration `json:"markRetryWindow,omitempty"`
+	// InitialBackoff is the delay before the first retry to mark the node.
+	InitialBackoff metav1.Duration `json:"initialBackoff,omitempty"`
+	// MaxBackoff caps the delay between retries to mark the node.
+	MaxBackoff metav1.Duration `json:"maxBackoff,omitempty"`
+	// CircuitBreakerTrigger is the number of consecutive failures after which the handler
+	// waits for the API server to become healthy before retrying.
+	CircuitBreakerTrigger int `json:"circuitBreakerTrigger,omitempty"`
+	// Action is what the handler does once the instance is terminating: mark-node only marks
+	// the node, delete-machine also deletes its machine. Defaults to mark-node.
+	Action TerminationAction `json:"action,omitempty"`
+	// TaintEffect is the effect, NoSchedule or NoExecute, of the
+	// cloud.google.com/impending-termination taint applied to the node next to the Terminating
+	// condition. The node is not tainted when empty.
+	TaintEffect corev1.TaintEffect `json:"taintEffect,omitempty"`
+}
+
+// FeaturesConfig toggles optional behaviour of the handler. Every feature is enabled by default.
+type FeaturesConfig struct {
+	// Journal records pending node markings in the JournalPath.
+	Journal *bool `json:"journal,omitempty"`
+	// CircuitBreaker stops hammering an unavailable API server while marking the node.
+	CircuitBreaker *bool `json:"circuitBreaker,omitempty"`
+	// MaintenanceEvents reports upcoming maintenance events of the instance in the
+	// MaintenanceScheduled condition of the node.
+	MaintenanceEvents *bool `json:"maintenanceEvents,omitempty"`
+	// WaitForChange long-polls the termination notice endpoint instead of checking it every
+	// PollInterval, falling back to polling when the long-poll fails.
+	WaitForChange *bool `json:"waitForChange,omitempty"`
+}
+
+// DefaultConfig returns the configuration used when no configuration file is given.
+func DefaultConfig() *Config {
+	c := &Config{}
+	c.setDefaults()
+	return c
+}
+
+// LoadConfig reads and validates the configuration file at the given path. Fields that are
+// not set in the file keep their default.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("could not decode config file %s: %w", path, err)
+	}
+	c.setDefaults()
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *Config) setDefaults() {
+	if c.PollInterval.Duration == 0 {
+		c.PollInterval.Duration = defaultPollInterval
+	}
+	if c.Drain.MarkRetryWindow.Duration == 0 {
+		c.Drain.MarkRetryWindow.Duration = defaultMarkRetryWindow
+	}
+	if c.Drain.InitialBackoff.Duration == 0 {
+		c.Drain.InitialBackoff.Duration = defaultMarkInitialBackoff
+	}
+	if c.Drain.MaxBackoff.Duration == 0 {
+		c.Drain.MaxBackoff.Duration = defaultMarkMaxBackoff
+	}
+	if c.Drain.CircuitBreakerTrigger == 0 {
+		c.Drain.CircuitBreakerTrigger = defaultCircuitBreakerTrigger
+	}
+	if c.Drain.Action == "" {
+		c.Drain.Action = TerminationActionMarkNode
+	}
+}
+
+// Validate checks that the configuration can be applied.
+func (c *Config) Validate() error {
+	if c.PollInterval.Duration < 0 {
+		return fmt.Errorf("pollInterval must be positive, got %s", c.PollInterval.Duration)
+	}
+	if c.OperationPollInterval.Duration < 0 {
+		return fmt.Errorf("operationPollInterval must be positive, got %s", c.OperationPollInterval.Duration)
+	}
+	if c.Drain.MarkRetryWindow.Duration < 0 {
+		return fmt.Errorf("drain.markRetryWindow must be positive, got %s", c.Drain.MarkRetryWindow.Duration)
+	}
+	if c.Drain.InitialBackoff.Duration < 0 || c.Drain.MaxBackoff.Duration < c.Drain.InitialBackoff.Duration {
+		return fmt.Errorf("drain.initialBackoff must be positive and not exceed drain.maxBackoff, got %s and %s", c.Drain.InitialBackoff.Duration, c.Drain.MaxBackoff.Duration)
+	}
+	if c.Drain.CircuitBreakerTrigger < 0 {
+		return fmt.Errorf("drain.circuitBreakerTrigger must be positive, got %d", c.Drain.CircuitBreakerTrigger)
+	}
+	if _, err := ParseTerminationAction(string(c.Drain.Action)); err != nil {
+		return fmt.Errorf("drain.action: %w", err)
+	}
+	if _, err := ParseTaintEffect(string(c.Drain.TaintEffect)); err != nil {
+		return fmt.Errorf("drain.taintEffect: %w", err)
+	}
+	return nil
+}
+
+// Options returns the handler options for the configuration.
+func (c *Config) Options() []Option {
+	journalPath := c.JournalPath
+	if !enabled(c.Features.Journal) {
+		journalPath = ""
+	}
+	trigger := c.Drain.CircuitBreakerTrigger
+	if !enabled(c.Features.CircuitBreaker) {
+		trigger = math.MaxInt
+	}
+	return []Option{
+		WithPollInterval(c.PollInterval.Duration),
+		WithOperationPollInterval(c.OperationPollInterval.Duration),
+		WithMarkRetryWindow(c.Drain.MarkRetryWindow.Duration),
+		WithMarkBackoff(c.Drain.InitialBackoff.Duration, c.Drain.MaxBackoff.Duration),
+		WithCircuitBreakerTrigger(trigger),
+		WithTerminationAction(c.Drain.Action),
+		WithTaintEffect(c.Drain.TaintEffect),
+		WithJournalPath(journalPath),
+		WithMaintenanceEvents(enabled(c.Features.MaintenanceEvents)),
+		WithWaitForChange(enabled(c.Features.WaitForChange)),
+	}
+}
+
+// enabled returns the value of a feature toggle, which defaults to true.
+func enabled(feature *bool) bool {
+	return feature == nil || *feature
+}
@@ -0,0 +1,140 @@
+package termination
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cases := []struct {
+		name          string
+		content       string
+		expectedError string
+		validate      func(t *testing.T, c *Config)
+	}{
+		{
+			name:    "defaults",
+			content: "",
+			validate: func(t *testing.T, c *Config) {
+				if c.PollInterval.Duration != defaultPollInterval || c.Drain.MarkRetryWindow.Duration != defaultMarkRetryWindow ||
+					c.Drain.CircuitBreakerTrigger != defaultCircuitBreakerTrigger {
+					t.Errorf("Expected default configuration, got %+v", c)
+				}
+			},
+		},
+		{
+			name: "overrides",
+			content: `
+pollInterval: 1s
+namespace: openshift-machine-api
+metricsAddress: ":8080"
+journalPath: /var/lib/journal
+drain:
+  markRetryWindow: 2m
+  circuitBreakerTrigger: 3
+features:
+  journal: false
+`,
+			validate: func(t *testing.T, c *Config) {
+				if c.PollInterval.Duration != time.Second || c.Namespace != "openshift-machine-api" || c.MetricsAddress != ":8080" ||
+					c.Drain.MarkRetryWindow.Duration != 2*time.Minute || c.Drain.CircuitBreakerTrigger != 3 {
+					t.Errorf("Unexpected configuration %+v", c)
+				}
+				if c.Drain.MaxBackoff.Duration != defaultMarkMaxBackoff {
+					t.Errorf("Expected default max backoff, got %s", c.Drain.MaxBackoff.Duration)
+				}
+				if enabled(c.Features.Journal) || !enabled(c.Features.CircuitBreaker) {
+					t.Errorf("Unexpected features %+v", c.Features)
+				}
+			},
+		},
+		{
+			name:          "unknown field",
+			content:       "pollIntervalSeconds: 5",
+			expectedError: `unknown field "pollIntervalSeconds"`,
+		},
+		{
+			name: "invalid backoff",
+			content: `
+drain:
+  initialBackoff: 1m
+  maxBackoff: 1s
+`,
+			expectedError: "drain.initialBackoff must be positive and not exceed drain.maxBackoff",
+		},
+		{
+			name: "invalid action",
+			content: `
+drain:
+  action: annotate
+`,
+			expectedError: `drain.action: unknown termination action "annotate"`,
+		},
+		{
+			name: "invalid taint effect",
+			content: `
+drain:
+  taintEffect: PreferNoSchedule
+`,
+			expectedError: `drain.taintEffect: unsupported taint effect "PreferNoSchedule"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := LoadConfig(path)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tc.validate(t, c)
+		})
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	h := &handler{}
+	h.Reconfigure(DefaultConfig().Options()...)
+	if h.pollInterval != defaultPollInterval || h.markRetryWindow != defaultMarkRetryWindow ||
+		h.markInitialBackoff != defaultMarkInitialBackoff || h.markMaxBackoff != defaultMarkMaxBackoff ||
+		h.circuitBreakerTrigger != defaultCircuitBreakerTrigger || !h.maintenanceEvents || !h.waitForChange {
+		t.Errorf("Expected default settings, got poll interval %s, retry window %s, backoff %s-%s, trigger %d",
+			h.pollInterval, h.markRetryWindow, h.markInitialBackoff, h.markMaxBackoff, h.circuitBreakerTrigger)
+	}
+
+	disabled := false
+	c := DefaultConfig()
+	c.PollInterval.Duration = time.Minute
+	c.JournalPath = "/var/lib/journal"
+	c.Features = FeaturesConfig{Journal: &disabled, CircuitBreaker: &disabled, MaintenanceEvents: &disabled, WaitForChange: &disabled}
+	h.Reconfigure(c.Options()...)
+	if h.pollInterval != time.Minute {
+		t.Errorf("Expected poll interval to be reconfigured, got %s", h.pollInterval)
+	}
+	if h.journal.path != "" {
+		t.Errorf("Expected journal to be disabled, got path %q", h.journal.path)
+	}
+	if h.circuitBreakerTrigger != math.MaxInt {
+		t.Errorf("Expected circuit breaker to be disabled, got trigger %d", h.circuitBreakerTrigger)
+	}
+	if h.maintenanceEvents {
+		t.Error("Expected maintenance events to be disabled")
+	}
+	if h.waitForChange {
+		t.Error("Expected waiting for changes to be disabled")
+	}
+}
@@ -0,0 +1,89 @@
+package termination
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// preemptionNoticeReason is the reason of the events recorded once the instance is terminating.
+	preemptionNoticeReason = "PreemptionNotice"
+	// eventComponent is the source component of the events of the handler.
+	eventComponent = "machine-api-termination-handler"
+	// eventTimeout bounds recording the events, which must not hold up marking the node.
+	eventTimeout = 10 * time.Second
+)
+
+// recordPreemptionEvents records a PreemptionNotice event on the node and on its machine, so that
+// spot interruptions can be audited from the events of the cluster. The message names the source
+// that reported the termination and the detection latency. Failures are logged and do not keep the
+// node from being marked.
+//
+// The events are created directly rather than through an event broadcaster, which sends them in the
+// background and could drop them when the handler exits right after marking the node.
+func (h *handler) recordPreemptionEvents(source string, latency time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+
+	node := &corev1.Node{}
+	if err := h.client.Get(ctx, client.ObjectKey{Name: h.nodeName}, node); err != nil {
+		h.log.Error(err, "Could not read node to record the preemption notice")
+		return
+	}
+	message := fmt.Sprintf("Instance is terminating, reported by the %s source and detected within %s", source, latency.Round(time.Millisecond))
+
+	nodeRef := corev1.ObjectReference{APIVersion: "v1", Kind: "Node", Name: node.Name, UID: node.UID}
+	if err := h.createEvent(ctx, metav1.NamespaceDefault, nodeRef, message); err != nil {
+		h.log.Error(err, "Could not record the preemption notice on the node")
+	}
+
+	namespace, name, ok := strings.Cut(node.Annotations[machineAnnotation], "/")
+	if !ok {
+		return
+	}
+	machine := partialObject("Machine")
+	if err := h.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, machine); err != nil {
+		h.log.Error(err, "Could not read machine to record the preemption notice", "machine", namespace+"/"+name)
+		return
+	}
+	machineRef := corev1.ObjectReference{
+		APIVersion: machinev1.GroupVersion.String(),
+		Kind:       "Machine",
+		Namespace:  namespace,
+		Name:       name,
+		UID:        machine.UID,
+	}
+	if err := h.createEvent(ctx, namespace, machineRef, message); err != nil {
+		h.log.Error(err, "Could not record the preemption notice on the machine", "machine", namespace+"/"+name)
+	}
+}
+
+// createEvent creates a PreemptionNotice warning event for the object in the namespace.
+func (h *handler) createEvent(ctx context.Context, namespace string, involved corev1.ObjectReference, message string) error {
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			// Named like the events of client-go, which are unique per object and time.
+			Name:      fmt.Sprintf("%v.%x", involved.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject:      involved,
+		Reason:              preemptionNoticeReason,
+		Message:             message,
+		Type:                corev1.EventTypeWarning,
+		Source:              corev1.EventSource{Component: eventComponent, Host: h.nodeName},
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		ReportingController: eventComponent,
+		ReportingInstance:   h.nodeName,
+	}
+	return h.client.Create(ctx, event)
+}
@@ -0,0 +1,103 @@
+package termination
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRecordPreemptionEvents(t *testing.T) {
+	cases := []struct {
+		name            string
+		nodeAnnotations map[string]string
+		expectedKinds   []string
+	}{
+		{
+			name:            "Node and machine",
+			nodeAnnotations: map[string]string{machineAnnotation: "openshift-machine-api/spot-a"},
+			expectedKinds:   []string{"Machine", "Node"},
+		},
+		{
+			name:          "Node without machine",
+			expectedKinds: []string{"Node"},
+		},
+		{
+			name:            "Machine not found",
+			nodeAnnotations: map[string]string{machineAnnotation: "openshift-machine-api/spot-b"},
+			expectedKinds:   []string{"Node"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", UID: "node-uid", Annotations: tc.nodeAnnotations}}
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "spot-a", Namespace: "openshift-machine-api", UID: "machine-uid"}}
+			c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(node, machine).Build()
+			h := &handler{client: c, nodeName: "node", log: klogr.New()}
+
+			h.recordPreemptionEvents(metadataSource, 1500*time.Millisecond)
+
+			events := &corev1.EventList{}
+			if err := c.List(context.Background(), events); err != nil {
+				t.Fatal(err)
+			}
+			kinds := []string{}
+			for _, event := range events.Items {
+				kinds = append(kinds, event.InvolvedObject.Kind)
+				if event.Reason != preemptionNoticeReason || event.Type != corev1.EventTypeWarning {
+					t.Errorf("Expected a %s warning, got %s %s", preemptionNoticeReason, event.Type, event.Reason)
+				}
+				if !strings.Contains(event.Message, "metadata source") || !strings.Contains(event.Message, "1.5s") {
+					t.Errorf("Expected the source and latency in the message, got %q", event.Message)
+				}
+				switch event.InvolvedObject.Kind {
+				case "Node":
+					if event.Namespace != metav1.NamespaceDefault || event.InvolvedObject.UID != "node-uid" {
+						t.Errorf("Unexpected node event %s/%s for %v", event.Namespace, event.Name, event.InvolvedObject)
+					}
+				case "Machine":
+					if event.Namespace != "openshift-machine-api" || event.InvolvedObject.UID != "machine-uid" {
+						t.Errorf("Unexpected machine event %s/%s for %v", event.Namespace, event.Name, event.InvolvedObject)
+					}
+				}
+			}
+			sort.Strings(kinds)
+			if strings.Join(kinds, ",") != strings.Join(tc.expectedKinds, ",") {
+				t.Errorf("Expected events for %v, got %v", tc.expectedKinds, kinds)
+			}
+		})
+	}
+}
+
+func TestPollTerminationSourcesLatency(t *testing.T) {
+	metadata := newMetadataServer("FALSE")
+	server := httptest.NewServer(metadata)
+	defer server.Close()
+	pollURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &handler{pollURL: pollURL, pollInterval: 100 * time.Millisecond, log: klogr.New()}
+	time.AfterFunc(250*time.Millisecond, func() { metadata.set("TRUE") })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	source, latency, err := h.pollTerminationSources(ctx, time.Now())
+	if err != nil || source != metadataSource {
+		t.Fatalf("Expected the %s source, got %q, %v", metadataSource, source, err)
+	}
+	// The termination is seen on the first poll after it, at most a poll interval late.
+	if latency <= 0 || latency > time.Second {
+		t.Errorf("Expected the latency since the last poll not reporting the termination, got %s", latency)
+	}
+}
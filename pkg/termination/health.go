@@ -0,0 +1,80 @@
+package termination
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthGracePeriod is added to the poll interval before a handler that has not polled the
+// termination endpoint is considered unhealthy, to absorb slow responses of the metadata server.
+const healthGracePeriod = 30 * time.Second
+
+// health tracks the progress of the handler for its health and readiness probes.
+type health struct {
+	mu sync.Mutex
+	// lastPoll is when the termination endpoint was last checked successfully.
+	lastPoll time.Time
+	// marking is set once the instance is terminating, polling stops while the node is marked.
+	marking bool
+}
+
+// recordPoll records a successful check of the termination endpoint.
+func (h *handler) recordPoll() {
+	now := time.Now()
+	h.health.mu.Lock()
+	h.health.lastPoll = now
+	h.health.mu.Unlock()
+	pollsTotal.Inc()
+	lastPollTimestamp.Set(float64(now.Unix()))
+}
+
+// recordMarking records that the handler stopped polling to mark the node.
+func (h *handler) recordMarking() {
+	h.health.mu.Lock()
+	h.health.marking = true
+	h.health.mu.Unlock()
+}
+
+// Healthy returns an error when the handler stopped polling the termination endpoint for more than
+// three poll intervals, e.g. because the metadata server hangs. A handler that has not polled yet
+// or is marking the node is healthy.
+func (h *handler) Healthy() error {
+	h.mu.RLock()
+	interval := h.pollInterval
+	h.mu.RUnlock()
+
+	h.health.mu.Lock()
+	defer h.health.mu.Unlock()
+	if h.health.marking || h.health.lastPoll.IsZero() {
+		return nil
+	}
+	if since := time.Since(h.health.lastPoll); since > 3*interval+healthGracePeriod {
+		return fmt.Errorf("termination endpoint last polled %s ago", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Ready returns an error until the termination endpoint was polled successfully, or the handler
+// started to mark the node.
+func (h *handler) Ready() error {
+	h.health.mu.Lock()
+	defer h.health.mu.Unlock()
+	if h.health.marking || !h.health.lastPoll.IsZero() {
+		return nil
+	}
+	return fmt.Errorf("termination endpoint not polled yet")
+}
+
+// ProbeHandler returns an HTTP handler answering 200 when the check passes and 503 with the error
+// otherwise, for the liveness and readiness probes of the termination handler.
+func ProbeHandler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+}
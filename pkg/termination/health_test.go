@@ -0,0 +1,53 @@
+package termination
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	h := &handler{pollInterval: time.Second}
+	if err := h.Healthy(); err != nil {
+		t.Errorf("Expected a handler that has not polled yet to be healthy, got %v", err)
+	}
+	if err := h.Ready(); err == nil {
+		t.Error("Expected a handler that has not polled yet not to be ready")
+	}
+
+	h.recordPoll()
+	if err := h.Healthy(); err != nil {
+		t.Errorf("Expected a polling handler to be healthy, got %v", err)
+	}
+	if err := h.Ready(); err != nil {
+		t.Errorf("Expected a polling handler to be ready, got %v", err)
+	}
+
+	h.health.lastPoll = time.Now().Add(-time.Hour)
+	if err := h.Healthy(); err == nil {
+		t.Error("Expected a handler that stopped polling to be unhealthy")
+	}
+
+	h.recordMarking()
+	if err := h.Healthy(); err != nil {
+		t.Errorf("Expected a handler marking the node to be healthy, got %v", err)
+	}
+}
+
+func TestProbeHandler(t *testing.T) {
+	for _, tc := range []struct {
+		err          error
+		expectedCode int
+	}{
+		{expectedCode: http.StatusOK},
+		{err: errors.New("termination endpoint not polled yet"), expectedCode: http.StatusServiceUnavailable},
+	} {
+		recorder := httptest.NewRecorder()
+		ProbeHandler(func() error { return tc.err }).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if recorder.Code != tc.expectedCode {
+			t.Errorf("Expected status %d for error %v, got %d", tc.expectedCode, tc.err, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package termination
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// journalEntry records that a node was found to be terminating but could not be marked yet.
+type journalEntry struct {
+	NodeName string    `json:"nodeName"`
+	Reason   string    `json:"reason"`
+	Time     time.Time `json:"time"`
+}
+
+// journal persists pending node markings on local disk, so that a restarted termination
+// handler applies them as soon as the API server is reachable again, even if the
+// termination signal is no longer observable. A journal without a path is a no-op.
+type journal struct {
+	path string
+}
+
+// record stores a pending marking for the given node.
+func (j journal) record(nodeName, reason string, now time.Time) error {
+	if j.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(journalEntry{NodeName: nodeName, Reason: reason, Time: now})
+	if err != nil {
+		return fmt.Errorf("could not encode journal entry: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial journal behind.
+	tmp := filepath.Join(filepath.Dir(j.path), "."+filepath.Base(j.path)+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("could not write journal: %w", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("could not write journal: %w", err)
+	}
+	return nil
+}
+
+// pending returns the pending marking for the given node, if any. A marking recorded longer than
+// the retry window ago is dropped: the handler that recorded it gave up on it, and the node may
+// have been recreated on the same host since.
+func (j journal) pending(nodeName string, now time.Time, retryWindow time.Duration) (*journalEntry, error) {
+	if j.path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read journal: %w", err)
+	}
+
+	entry := &journalEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("could not decode journal %q: %w", j.path, err)
+	}
+	if entry.NodeName != nodeName {
+		// Left behind by a handler of another node, e.g. on a reused host path.
+		return nil, nil
+	}
+	if now.Sub(entry.Time) > retryWindow {
+		return nil, j.clear()
+	}
+	return entry, nil
+}
+
+// clear removes the pending marking once it has been applied.
+func (j journal) clear() error {
+	if j.path == "" {
+		return nil
+	}
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not clear journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package termination
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestJournal(t *testing.T) {
+	j := journal{path: filepath.Join(t.TempDir(), "journal")}
+	now := time.Unix(100, 0).UTC()
+	window := 30 * time.Second
+
+	if entry, err := j.pending("node", now, window); err != nil || entry != nil {
+		t.Fatalf("Expected no pending entry, got %v, %v", entry, err)
+	}
+
+	if err := j.record("node", terminationRequestedReason, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entry, err := j.pending("node", now.Add(window), window)
+	if err != nil || entry == nil || !entry.Time.Equal(now) || entry.Reason != terminationRequestedReason {
+		t.Errorf("Expected pending entry at %v, got %v, %v", now, entry, err)
+	}
+	if entry, err := j.pending("other-node", now, window); err != nil || entry != nil {
+		t.Errorf("Expected no pending entry for another node, got %v, %v", entry, err)
+	}
+
+	if err := j.clear(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry, err := j.pending("node", now, window); err != nil || entry != nil {
+		t.Errorf("Expected no pending entry after clear, got %v, %v", entry, err)
+	}
+
+	// An entry older than the retry window is not replayed and is dropped.
+	if err := j.record("node", terminationRequestedReason, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry, err := j.pending("node", now.Add(window+time.Second), window); err != nil || entry != nil {
+		t.Errorf("Expected a stale entry not to be replayed, got %v, %v", entry, err)
+	}
+	if _, err := os.Stat(j.path); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale entry to be removed, got %v", err)
+	}
+
+	// A journal without a path is a no-op.
+	if err := (journal{}).record("node", terminationRequestedReason, now); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestMarkNodeWithRetry(t *testing.T) {
+	cases := []struct {
+		name            string
+		failures        int
+		apiHealthy      bool
+		expectError     bool
+		expectedProbes  bool
+		expectedJournal bool
+	}{
+		{
+			name: "Mark node on first attempt",
+		},
+		{
+			name:     "Mark node after transient failures",
+			failures: 2,
+		},
+		{
+			name:           "Mark node once the API server is healthy again",
+			failures:       5,
+			apiHealthy:     true,
+			expectedProbes: true,
+		},
+		{
+			name:            "Give up after the retry window",
+			failures:        1000,
+			expectError:     true,
+			expectedJournal: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+			failures := tc.failures
+			c := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(node).
+				WithStatusSubresource(node).
+				WithInterceptorFuncs(interceptor.Funcs{
+					SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+						if failures > 0 {
+							failures--
+							return errors.New("connection refused")
+						}
+						return c.Status().Update(ctx, obj, opts...)
+					},
+				}).Build()
+
+			probed := false
+			h := &handler{
+				client:   c,
+				nodeName: "node",
+				log:      klogr.New(),
+				journal:  journal{path: filepath.Join(t.TempDir(), "journal")},
+				apiHealthy: func(context.Context) error {
+					probed = true
+					if !tc.apiHealthy {
+						return errors.New("unhealthy")
+					}
+					return nil
+				},
+				markRetryWindow:       100 * time.Millisecond,
+				markInitialBackoff:    time.Millisecond,
+				markMaxBackoff:        5 * time.Millisecond,
+				circuitBreakerTrigger: 5,
+			}
+			recordedAt := time.Now()
+			if err := h.journal.record("node", terminationRequestedReason, recordedAt); err != nil {
+				t.Fatal(err)
+			}
+
+			err := h.markNodeWithRetry(context.Background())
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got %v", tc.expectError, err)
+			}
+			if probed != tc.expectedProbes && !tc.expectError {
+				t.Errorf("Expected API server probes: %v, got %v", tc.expectedProbes, probed)
+			}
+
+			entry, _ := h.journal.pending("node", recordedAt, h.markRetryWindow)
+			if (entry != nil) != tc.expectedJournal {
+				t.Errorf("Expected pending journal entry: %v, got %v", tc.expectedJournal, entry)
+			}
+
+			if !tc.expectError {
+				updated := &corev1.Node{}
+				if err := c.Get(context.Background(), client.ObjectKey{Name: "node"}, updated); err != nil {
+					t.Fatal(err)
+				}
+				if !nodeHasTerminationCondition(updated) {
+					t.Error("Expected node to have the terminating condition")
+				}
+			}
+		})
+	}
+}
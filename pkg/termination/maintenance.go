@@ -0,0 +1,124 @@
+package termination
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	gcpMaintenanceEventEndpointURL                             = "http://169.254.169.254/computeMetadata/v1/instance/maintenance-event"
+	maintenanceScheduledConditionType corev1.NodeConditionType = "MaintenanceScheduled"
+	noMaintenanceScheduledReason                               = "NoMaintenanceScheduled"
+
+	// noMaintenanceEvent is reported by the metadata server while no maintenance is upcoming.
+	noMaintenanceEvent = "NONE"
+)
+
+// checkMaintenanceEndpoint returns the upcoming maintenance event of the instance, e.g.
+// MIGRATE_ON_HOST_MAINTENANCE or TERMINATE_ON_HOST_MAINTENANCE, or NONE.
+func (h *handler) checkMaintenanceEndpoint(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.maintenanceURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create request %q: %w", h.maintenanceURL.String(), err)
+	}
+	req.Header.Add("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not get URL %q: %w", h.maintenanceURL.String(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not get URL %q: %s", h.maintenanceURL.String(), resp.Status)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	return strings.TrimSpace(string(bodyBytes)), nil
+}
+
+// reconcileMaintenanceEvent reports the upcoming maintenance event of the instance in the
+// MaintenanceScheduled condition of the node, so that workloads sensitive to live migrations or to
+// the termination of the instance can be moved away beforehand. The node is only updated when the
+// event differs from the last one reported, which is returned. Failures are logged and retried on
+// the next poll.
+func (h *handler) reconcileMaintenanceEvent(ctx context.Context, reported string) string {
+	event, err := h.checkMaintenanceEndpoint(ctx)
+	if err != nil {
+		h.log.Error(err, "Could not check the maintenance event of the instance")
+		return reported
+	}
+	if event == reported {
+		return reported
+	}
+
+	if err := h.setMaintenanceCondition(ctx, event); err != nil {
+		h.log.Error(err, "Could not report the maintenance event on the node", "event", event)
+		return reported
+	}
+	if event != noMaintenanceEvent {
+		maintenanceEventsTotal.Inc()
+		h.log.V(1).Info("Maintenance scheduled for the instance", "event", event)
+	}
+	return event
+}
+
+// setMaintenanceCondition sets the MaintenanceScheduled condition of the node for the event.
+func (h *handler) setMaintenanceCondition(ctx context.Context, event string) error {
+	node := &corev1.Node{}
+	if err := h.client.Get(ctx, client.ObjectKey{Name: h.nodeName}, node); err != nil {
+		return fmt.Errorf("error fetching node: %v", err)
+	}
+
+	now := metav1.Now()
+	condition := corev1.NodeCondition{
+		Type:               maintenanceScheduledConditionType,
+		Status:             corev1.ConditionFalse,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+		Reason:             noMaintenanceScheduledReason,
+		Message:            "No maintenance is scheduled for this instance",
+	}
+	if event != noMaintenanceEvent {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = maintenanceEventReason(event)
+		condition.Message = fmt.Sprintf("The cloud provider scheduled a %s maintenance event for this instance", event)
+	}
+
+	conditions := []corev1.NodeCondition{}
+	for _, existing := range node.Status.Conditions {
+		if existing.Type != maintenanceScheduledConditionType {
+			conditions = append(conditions, existing)
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+	}
+	node.Status.Conditions = append(conditions, condition)
+	if err := h.client.Status().Update(ctx, node); err != nil {
+		return fmt.Errorf("error updating node status: %v", err)
+	}
+	return nil
+}
+
+// maintenanceEventReason returns the condition reason of a maintenance event, e.g.
+// MigrateOnHostMaintenance for MIGRATE_ON_HOST_MAINTENANCE.
+func maintenanceEventReason(event string) string {
+	reason := ""
+	for _, word := range strings.Split(strings.ToLower(event), "_") {
+		if word != "" {
+			reason += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return reason
+}
@@ -0,0 +1,100 @@
+package termination
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileMaintenanceEvent(t *testing.T) {
+	event := noMaintenanceEvent
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			t.Errorf("Expected the Metadata-Flavor header")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(event))
+	}))
+	defer server.Close()
+	maintenanceURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+		}},
+	}
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(node).WithStatusSubresource(node).Build()
+	h := &handler{client: c, nodeName: "node", log: klogr.New(), maintenanceURL: maintenanceURL}
+
+	steps := []struct {
+		event            string
+		status           int
+		expectedReported string
+		expectedStatus   corev1.ConditionStatus
+		expectedReason   string
+	}{
+		{
+			event:            noMaintenanceEvent,
+			status:           http.StatusOK,
+			expectedReported: noMaintenanceEvent,
+			expectedStatus:   corev1.ConditionFalse,
+			expectedReason:   noMaintenanceScheduledReason,
+		},
+		{
+			event:            "MIGRATE_ON_HOST_MAINTENANCE",
+			status:           http.StatusOK,
+			expectedReported: "MIGRATE_ON_HOST_MAINTENANCE",
+			expectedStatus:   corev1.ConditionTrue,
+			expectedReason:   "MigrateOnHostMaintenance",
+		},
+		{
+			// Failures keep the condition and are retried on the next poll.
+			event:            noMaintenanceEvent,
+			status:           http.StatusServiceUnavailable,
+			expectedReported: "MIGRATE_ON_HOST_MAINTENANCE",
+			expectedStatus:   corev1.ConditionTrue,
+			expectedReason:   "MigrateOnHostMaintenance",
+		},
+		{
+			event:            noMaintenanceEvent,
+			status:           http.StatusOK,
+			expectedReported: noMaintenanceEvent,
+			expectedStatus:   corev1.ConditionFalse,
+			expectedReason:   noMaintenanceScheduledReason,
+		},
+	}
+
+	reported := ""
+	for i, step := range steps {
+		event, status = step.event, step.status
+		reported = h.reconcileMaintenanceEvent(context.Background(), reported)
+		if reported != step.expectedReported {
+			t.Errorf("Step %d: expected reported event %s, got %s", i, step.expectedReported, reported)
+		}
+
+		updated := &corev1.Node{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "node"}, updated); err != nil {
+			t.Fatal(err)
+		}
+		if len(updated.Status.Conditions) != 2 || updated.Status.Conditions[0].Type != corev1.NodeReady {
+			t.Fatalf("Step %d: expected the other conditions to be kept, got %v", i, updated.Status.Conditions)
+		}
+		condition := updated.Status.Conditions[1]
+		if condition.Type != maintenanceScheduledConditionType || condition.Status != step.expectedStatus || condition.Reason != step.expectedReason {
+			t.Errorf("Step %d: expected a %s %s condition with reason %s, got %v", i, step.expectedStatus, maintenanceScheduledConditionType, step.expectedReason, condition)
+		}
+	}
+}
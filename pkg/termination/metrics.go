@@ -0,0 +1,82 @@
+package termination
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	pollsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mapi_gcp_termination_handler_polls_total",
+		Help: "Number of successful checks of the termination notice endpoint",
+	})
+	lastPollTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mapi_gcp_termination_handler_last_poll_timestamp_seconds",
+		Help: "Unix time of the last successful check of the termination notice endpoint",
+	})
+	markDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "mapi_gcp_termination_handler_node_mark_duration_seconds",
+		Help:    "Time from the start of marking the node until it succeeded, including retries",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+	})
+	terminationNoticesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mapi_gcp_termination_handler_termination_notices_total",
+		Help: "Number of termination notices seen for the instance",
+	})
+	pollErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mapi_gcp_termination_handler_poll_errors_total",
+		Help: "Number of failed checks of the termination notice endpoint",
+	})
+	watchErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mapi_gcp_termination_handler_watch_errors_total",
+		Help: "Number of failed long-polls of the termination notice endpoint, which is then polled instead",
+	})
+	operationPollErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mapi_gcp_termination_handler_operation_poll_errors_total",
+		Help: "Number of failed checks of the operations of the instance",
+	})
+	maintenanceEventsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mapi_gcp_termination_handler_maintenance_events_total",
+		Help: "Number of upcoming maintenance events seen for the instance",
+	})
+	markFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mapi_gcp_termination_handler_node_mark_failures_total",
+		Help: "Number of failed attempts to mark the node for deletion",
+	})
+	machinesDeletedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mapi_gcp_termination_handler_machines_deleted_total",
+		Help: "Number of machines deleted because their instance was terminating",
+	})
+	markingsSkippedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mapi_gcp_termination_handler_node_markings_skipped_total",
+		Help: "Number of termination notices for which the node was not marked because marking is disabled for it",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		pollsTotal,
+		lastPollTimestamp,
+		markDurationSeconds,
+		terminationNoticesTotal,
+		pollErrorsTotal,
+		watchErrorsTotal,
+		operationPollErrorsTotal,
+		maintenanceEventsTotal,
+		markFailuresTotal,
+		machinesDeletedTotal,
+		markingsSkippedTotal,
+	)
+}
+
+// MetricsHandler returns the HTTP handler serving the metrics of the termination handler.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,87 @@
+package termination
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+const (
+	// metadataSource and operationsSource name where a termination notice was seen first.
+	metadataSource   = "metadata"
+	operationsSource = "operations"
+)
+
+// terminatingOperationTypes are the zone operations GCP records when it terminates, or is about to
+// terminate, an instance.
+var terminatingOperationTypes = map[string]bool{
+	"compute.instances.preempted":                  true,
+	"compute.instances.simulateMaintenanceEvent":   true,
+	"compute.instances.terminateOnHostMaintenance": true,
+}
+
+// OperationSource reports the operations GCP recorded for the instance, as a second source of
+// termination notices next to the preempted endpoint of the metadata server.
+type OperationSource interface {
+	// TerminatingOperation returns the type of the first operation inserted since the given time
+	// that terminates the instance, or an empty string if there is none.
+	TerminatingOperation(ctx context.Context, since time.Time) (string, error)
+}
+
+// computeOperationSource lists the zone operations of the instance with the compute API, using the
+// service account of the instance.
+type computeOperationSource struct {
+	service    *compute.Service
+	project    string
+	zone       string
+	instanceID string
+}
+
+// NewComputeOperationSource returns an OperationSource for the instance the handler runs on, as
+// identified by the metadata server. The service account of the instance needs the
+// compute.zoneOperations.list permission.
+func NewComputeOperationSource(ctx context.Context, opts ...option.ClientOption) (OperationSource, error) {
+	project, err := metadata.ProjectID()
+	if err != nil {
+		return nil, fmt.Errorf("could not get project from metadata: %w", err)
+	}
+	zone, err := metadata.Zone()
+	if err != nil {
+		return nil, fmt.Errorf("could not get zone from metadata: %w", err)
+	}
+	instanceID, err := metadata.InstanceID()
+	if err != nil {
+		return nil, fmt.Errorf("could not get instance ID from metadata: %w", err)
+	}
+	opts = append([]option.ClientOption{option.WithScopes(compute.ComputeReadonlyScope)}, opts...)
+	service, err := compute.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create compute service: %w", err)
+	}
+	return &computeOperationSource{service: service, project: project, zone: zone, instanceID: instanceID}, nil
+}
+
+func (s *computeOperationSource) TerminatingOperation(ctx context.Context, since time.Time) (string, error) {
+	found := ""
+	err := s.service.ZoneOperations.List(s.project, s.zone).Filter("targetId = "+s.instanceID).Pages(ctx, func(page *compute.OperationList) error {
+		for _, operation := range page.Items {
+			if found != "" || !terminatingOperationTypes[operation.OperationType] {
+				continue
+			}
+			// Operations of a previous boot of the instance are not relevant.
+			inserted, err := time.Parse(time.RFC3339, operation.InsertTime)
+			if err == nil && !inserted.Before(since) {
+				found = operation.OperationType
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not list operations of instance %s: %w", s.instanceID, err)
+	}
+	return found, nil
+}
@@ -0,0 +1,122 @@
+package termination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"k8s.io/klog/v2/klogr"
+)
+
+func TestComputeOperationSource(t *testing.T) {
+	started := time.Now().Truncate(time.Second)
+	operations := []*compute.Operation{
+		{OperationType: "compute.instances.preempted", InsertTime: started.Add(-time.Hour).Format(time.RFC3339)},
+		{OperationType: "compute.instances.setLabels", InsertTime: started.Add(time.Second).Format(time.RFC3339)},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/project/zones/us-east1-b/operations" || r.URL.Query().Get("filter") != "targetId = 1234" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		json.NewEncoder(w).Encode(&compute.OperationList{Items: operations})
+	}))
+	defer server.Close()
+	service, err := compute.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &computeOperationSource{service: service, project: "project", zone: "us-east1-b", instanceID: "1234"}
+
+	operationType, err := source.TerminatingOperation(context.Background(), started)
+	if err != nil || operationType != "" {
+		t.Fatalf("Expected the preemption of a previous boot and other operations to be ignored, got %q, %v", operationType, err)
+	}
+
+	operations = append(operations, &compute.Operation{OperationType: "compute.instances.simulateMaintenanceEvent", InsertTime: started.Add(time.Minute).Format(time.RFC3339)})
+	operationType, err = source.TerminatingOperation(context.Background(), started)
+	if err != nil || operationType != "compute.instances.simulateMaintenanceEvent" {
+		t.Fatalf("Expected the simulated maintenance event, got %q, %v", operationType, err)
+	}
+}
+
+type fakeOperationSource struct {
+	operationType string
+	err           error
+	calls         int
+}
+
+func (f *fakeOperationSource) TerminatingOperation(_ context.Context, _ time.Time) (string, error) {
+	f.calls++
+	return f.operationType, f.err
+}
+
+func TestPollTerminationSources(t *testing.T) {
+	cases := []struct {
+		name           string
+		preempted      bool
+		operations     *fakeOperationSource
+		expectedSource string
+	}{
+		{
+			name:           "Metadata server reports the preemption",
+			preempted:      true,
+			operations:     &fakeOperationSource{},
+			expectedSource: metadataSource,
+		},
+		{
+			name:           "Operation reported before the metadata server",
+			operations:     &fakeOperationSource{operationType: "compute.instances.preempted"},
+			expectedSource: operationsSource,
+		},
+		{
+			name:       "Failures to check the operations are ignored",
+			operations: &fakeOperationSource{err: errors.New("permission denied")},
+		},
+		{
+			name: "Operations are not checked without a source",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.preempted {
+					w.Write([]byte("TRUE"))
+					return
+				}
+				w.Write([]byte("FALSE"))
+			}))
+			defer server.Close()
+			pollURL, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := &handler{log: klogr.New(), pollURL: pollURL, pollInterval: 5 * time.Millisecond}
+			opts := []Option{WithOperationPollInterval(time.Millisecond)}
+			if tc.operations != nil {
+				opts = append(opts, WithOperationSource(tc.operations))
+			}
+			h.Reconfigure(opts...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			source, _, err := h.pollTerminationSources(ctx, time.Now())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if source != tc.expectedSource {
+				t.Errorf("Expected source %q, got %q", tc.expectedSource, source)
+			}
+			if tc.operations != nil && !tc.preempted && tc.operations.calls == 0 {
+				t.Error("Expected the operations to be checked")
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package termination
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	defaultMarkRetryWindow       = 30 * time.Second
+	defaultMarkInitialBackoff    = time.Second
+	defaultMarkMaxBackoff        = 30 * time.Second
+	defaultCircuitBreakerTrigger = 5
+)
+
+// Option configures optional behaviour of the Handler.
+type Option func(*handler)
+
+// WithMarkRetryWindow sets how long the handler keeps trying to mark the node once the
+// instance is terminating, e.g. while the API server is unavailable.
+func WithMarkRetryWindow(window time.Duration) Option {
+	return func(h *handler) {
+		h.markRetryWindow = window
+	}
+}
+
+// WithJournalPath sets the file in which a pending node marking is recorded, so that it
+// survives restarts of the handler. The journal is disabled when the path is empty.
+func WithJournalPath(path string) Option {
+	return func(h *handler) {
+		h.journal = journal{path: path}
+	}
+}
+
+// WithPollInterval sets the interval at which the termination notice endpoint is checked.
+func WithPollInterval(interval time.Duration) Option {
+	return func(h *handler) {
+		h.pollInterval = interval
+	}
+}
+
+// WithMarkBackoff sets the initial and maximum delay between retries to mark the node.
+func WithMarkBackoff(initial, max time.Duration) Option {
+	return func(h *handler) {
+		h.markInitialBackoff = initial
+		h.markMaxBackoff = max
+	}
+}
+
+// WithCircuitBreakerTrigger sets the number of consecutive failures to mark the node after
+// which the handler waits for the API server to become healthy before retrying.
+func WithCircuitBreakerTrigger(failures int) Option {
+	return func(h *handler) {
+		h.circuitBreakerTrigger = failures
+	}
+}
+
+// WithOperationSource sets the source of the operations of the instance checked for terminations
+// next to the termination notice endpoint.
+func WithOperationSource(source OperationSource) Option {
+	return func(h *handler) {
+		h.operations = source
+	}
+}
+
+// WithOperationPollInterval sets the interval at which the operations of the instance are checked,
+// zero disables the checks.
+func WithOperationPollInterval(interval time.Duration) Option {
+	return func(h *handler) {
+		h.operationPollInterval = interval
+	}
+}
+
+// WithMaintenanceEvents enables reporting the upcoming maintenance events of the instance in the
+// MaintenanceScheduled condition of the node.
+func WithMaintenanceEvents(enabled bool) Option {
+	return func(h *handler) {
+		h.maintenanceEvents = enabled
+	}
+}
+
+// WithWaitForChange enables long-polling the termination endpoint, so that a preemption is seen as
+// soon as the metadata server reports it. The endpoint is polled every poll interval otherwise.
+func WithWaitForChange(enabled bool) Option {
+	return func(h *handler) {
+		h.waitForChange = enabled
+	}
+}
+
+// WithTerminationAction sets what the handler does once the instance is terminating.
+func WithTerminationAction(action TerminationAction) Option {
+	return func(h *handler) {
+		h.terminationAction = action
+	}
+}
+
+// WithTaintEffect sets the effect of the impending termination taint applied to the node once the
+// instance is terminating. The node is not tainted when the effect is empty.
+func WithTaintEffect(effect corev1.TaintEffect) Option {
+	return func(h *handler) {
+		h.taintEffect = effect
+	}
+}
@@ -0,0 +1,86 @@
+package termination
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// MarkingDisabledAnnotation opts nodes out of being marked with the Terminating condition, e.g.
+	// spot pools whose preemptions are handled by another operator. It is read from the node, its
+	// machine and the MachineSet of the machine, in that order, and the first value found wins, so
+	// that setting it on a MachineSet covers all its nodes and a machine or node can override it.
+	MarkingDisabledAnnotation = "machine.openshift.io/gcp-disable-termination-marking"
+
+	// machineAnnotation is set on nodes by the machine controller to the namespace/name of their machine.
+	machineAnnotation = "machine.openshift.io/machine"
+)
+
+// markingDisabled returns whether the node opted out of being marked and which object opted it
+// out. Machines and MachineSets that cannot be read are logged and ignored, so that the node is
+// marked unless it explicitly opted out.
+func (h *handler) markingDisabled(ctx context.Context) (bool, string) {
+	node := &corev1.Node{}
+	if err := h.client.Get(ctx, client.ObjectKey{Name: h.nodeName}, node); err != nil {
+		h.log.Error(err, "Could not read node to check whether marking is disabled")
+		return false, ""
+	}
+	if disabled, ok := markingDisabledAnnotation(node.Annotations); ok {
+		return disabled, "node " + node.Name
+	}
+
+	namespace, name, ok := strings.Cut(node.Annotations[machineAnnotation], "/")
+	if !ok {
+		return false, ""
+	}
+	machine := partialObject("Machine")
+	if err := h.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, machine); err != nil {
+		h.log.Error(err, "Could not read machine to check whether marking is disabled", "machine", node.Annotations[machineAnnotation])
+		return false, ""
+	}
+	if disabled, ok := markingDisabledAnnotation(machine.Annotations); ok {
+		return disabled, "machine " + namespace + "/" + name
+	}
+
+	owner := metav1.GetControllerOf(machine)
+	if owner == nil || owner.Kind != "MachineSet" {
+		return false, ""
+	}
+	machineSet := partialObject("MachineSet")
+	if err := h.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: owner.Name}, machineSet); err != nil {
+		h.log.Error(err, "Could not read MachineSet to check whether marking is disabled", "machineSet", namespace+"/"+owner.Name)
+		return false, ""
+	}
+	if disabled, ok := markingDisabledAnnotation(machineSet.Annotations); ok {
+		return disabled, "MachineSet " + namespace + "/" + owner.Name
+	}
+	return false, ""
+}
+
+// partialObject returns the metadata of a Machine API object of the given kind, which is all that
+// is needed to read its annotations.
+func partialObject(kind string) *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(machinev1.GroupVersion.WithKind(kind))
+	return obj
+}
+
+// markingDisabledAnnotation returns the value of the MarkingDisabledAnnotation and whether it is
+// set. Values that are not booleans are ignored.
+func markingDisabledAnnotation(annotations map[string]string) (bool, bool) {
+	value, ok := annotations[MarkingDisabledAnnotation]
+	if !ok {
+		return false, false
+	}
+	disabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false
+	}
+	return disabled, true
+}
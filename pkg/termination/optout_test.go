@@ -0,0 +1,144 @@
+package termination
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubernetesscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2/klogr"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMarkingDisabled(t *testing.T) {
+	cases := []struct {
+		name               string
+		node               map[string]string
+		machine            map[string]string
+		machineSet         map[string]string
+		noMachine          bool
+		expectedDisabled   bool
+		expectedDisabledBy string
+	}{
+		{
+			name: "Marking is enabled by default",
+		},
+		{
+			name:               "MachineSet disables marking",
+			machineSet:         map[string]string{MarkingDisabledAnnotation: "true"},
+			expectedDisabled:   true,
+			expectedDisabledBy: "MachineSet openshift-machine-api/spot",
+		},
+		{
+			name:               "Node annotation propagated from the MachineSet disables marking",
+			node:               map[string]string{MarkingDisabledAnnotation: "true"},
+			expectedDisabled:   true,
+			expectedDisabledBy: "node node",
+		},
+		{
+			name:               "Machine overrides the MachineSet",
+			machine:            map[string]string{MarkingDisabledAnnotation: "false"},
+			machineSet:         map[string]string{MarkingDisabledAnnotation: "true"},
+			expectedDisabledBy: "machine openshift-machine-api/spot-a",
+		},
+		{
+			name:               "Invalid values are ignored",
+			machine:            map[string]string{MarkingDisabledAnnotation: "yes please"},
+			machineSet:         map[string]string{MarkingDisabledAnnotation: "true"},
+			expectedDisabled:   true,
+			expectedDisabledBy: "MachineSet openshift-machine-api/spot",
+		},
+		{
+			name:      "Missing machine keeps marking enabled",
+			noMachine: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: map[string]string{
+				machineAnnotation: "openshift-machine-api/spot-a",
+			}}}
+			for k, v := range tc.node {
+				node.Annotations[k] = v
+			}
+			objects := []client.Object{node, &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "spot", Namespace: "openshift-machine-api", Annotations: tc.machineSet},
+			}}
+			if !tc.noMachine {
+				objects = append(objects, &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+					Name:        "spot-a",
+					Namespace:   "openshift-machine-api",
+					Annotations: tc.machine,
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: machinev1.GroupVersion.String(),
+						Kind:       "MachineSet",
+						Name:       "spot",
+						Controller: pointer.Bool(true),
+					}},
+				}})
+			}
+			h := &handler{
+				client:   fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(objects...).Build(),
+				nodeName: "node",
+				log:      klogr.New(),
+			}
+
+			disabled, disabledBy := h.markingDisabled(context.Background())
+			if disabled != tc.expectedDisabled || disabledBy != tc.expectedDisabledBy {
+				t.Errorf("Expected disabled %v by %q, got %v by %q", tc.expectedDisabled, tc.expectedDisabledBy, disabled, disabledBy)
+			}
+		})
+	}
+}
+
+func TestMarkNodeWithRetrySkipsDisabledNodes(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: map[string]string{MarkingDisabledAnnotation: "true"}}}
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(node).WithStatusSubresource(node).Build()
+	h := &handler{
+		client:                c,
+		nodeName:              "node",
+		log:                   klogr.New(),
+		journal:               journal{path: filepath.Join(t.TempDir(), "journal")},
+		markRetryWindow:       100 * time.Millisecond,
+		markInitialBackoff:    time.Millisecond,
+		markMaxBackoff:        5 * time.Millisecond,
+		circuitBreakerTrigger: 5,
+	}
+	if err := h.journal.record("node", terminationRequestedReason, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.markNodeWithRetry(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	updated := &corev1.Node{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: "node"}, updated); err != nil {
+		t.Fatal(err)
+	}
+	if nodeHasTerminationCondition(updated) {
+		t.Error("Expected the node not to be marked")
+	}
+	if entry, _ := h.journal.pending("node", time.Now(), h.markRetryWindow); entry != nil {
+		t.Errorf("Expected the journal to be cleared, got %v", entry)
+	}
+}
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := kubernetesscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
@@ -0,0 +1,52 @@
+package termination
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// impendingTerminationTaintKey is the key of the taint applied to the node of a terminating instance.
+const impendingTerminationTaintKey = "cloud.google.com/impending-termination"
+
+// ParseTaintEffect returns the effect of the taint applied to the node of a terminating instance.
+// The empty effect disables the taint.
+func ParseTaintEffect(name string) (corev1.TaintEffect, error) {
+	switch effect := corev1.TaintEffect(name); effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectNoExecute:
+		return effect, nil
+	}
+	return "", fmt.Errorf("unsupported taint effect %q, expected %s or %s", name, corev1.TaintEffectNoSchedule, corev1.TaintEffectNoExecute)
+}
+
+// taintNode applies the impending termination taint with the given effect to the node, so that the
+// scheduler, and with NoExecute the taint manager, react even when nothing watches the Terminating
+// condition. A taint with another effect is replaced.
+func (h *handler) taintNode(ctx context.Context, effect corev1.TaintEffect) error {
+	node := &corev1.Node{}
+	if err := h.client.Get(ctx, client.ObjectKey{Name: h.nodeName}, node); err != nil {
+		return fmt.Errorf("error fetching node: %v", err)
+	}
+
+	now := metav1.Now()
+	taint := corev1.Taint{Key: impendingTerminationTaintKey, Effect: effect, TimeAdded: &now}
+	taints := []corev1.Taint{taint}
+	for _, existing := range node.Spec.Taints {
+		if existing.Key != impendingTerminationTaintKey {
+			taints = append(taints, existing)
+			continue
+		}
+		if existing.Effect == effect {
+			return nil
+		}
+	}
+	node.Spec.Taints = taints
+	if err := h.client.Update(ctx, node); err != nil {
+		return fmt.Errorf("error tainting node: %v", err)
+	}
+	h.log.V(1).Info("Tainted node of the terminating instance", "taint", impendingTerminationTaintKey, "effect", effect)
+	return nil
+}
@@ -0,0 +1,104 @@
+package termination
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTaintNode(t *testing.T) {
+	otherTaint := corev1.Taint{Key: "dedicated", Value: "spot", Effect: corev1.TaintEffectNoSchedule}
+	cases := []struct {
+		name           string
+		effect         corev1.TaintEffect
+		taints         []corev1.Taint
+		expectedTaints []corev1.Taint
+	}{
+		{
+			name:           "Taint disabled",
+			taints:         []corev1.Taint{otherTaint},
+			expectedTaints: []corev1.Taint{otherTaint},
+		},
+		{
+			name:   "NoSchedule taint added",
+			effect: corev1.TaintEffectNoSchedule,
+			taints: []corev1.Taint{otherTaint},
+			expectedTaints: []corev1.Taint{
+				{Key: impendingTerminationTaintKey, Effect: corev1.TaintEffectNoSchedule},
+				otherTaint,
+			},
+		},
+		{
+			name:   "Taint with another effect replaced",
+			effect: corev1.TaintEffectNoExecute,
+			taints: []corev1.Taint{otherTaint, {Key: impendingTerminationTaintKey, Effect: corev1.TaintEffectNoSchedule}},
+			expectedTaints: []corev1.Taint{
+				{Key: impendingTerminationTaintKey, Effect: corev1.TaintEffectNoExecute},
+				otherTaint,
+			},
+		},
+		{
+			name:           "Taint already applied",
+			effect:         corev1.TaintEffectNoExecute,
+			taints:         []corev1.Taint{otherTaint, {Key: impendingTerminationTaintKey, Effect: corev1.TaintEffectNoExecute}},
+			expectedTaints: []corev1.Taint{otherTaint, {Key: impendingTerminationTaintKey, Effect: corev1.TaintEffectNoExecute}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node"},
+				Spec:       corev1.NodeSpec{Taints: tc.taints},
+			}
+			c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(node).WithStatusSubresource(node).Build()
+			h := &handler{
+				client:                c,
+				nodeName:              "node",
+				log:                   klogr.New(),
+				markRetryWindow:       100 * time.Millisecond,
+				markInitialBackoff:    time.Millisecond,
+				markMaxBackoff:        5 * time.Millisecond,
+				circuitBreakerTrigger: 5,
+			}
+			h.Reconfigure(WithTaintEffect(tc.effect))
+
+			if err := h.markNodeWithRetry(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			updated := &corev1.Node{}
+			if err := c.Get(context.Background(), client.ObjectKey{Name: "node"}, updated); err != nil {
+				t.Fatal(err)
+			}
+			if !nodeHasTerminationCondition(updated) {
+				t.Error("Expected the node to be marked")
+			}
+			if len(updated.Spec.Taints) != len(tc.expectedTaints) {
+				t.Fatalf("Expected taints %v, got %v", tc.expectedTaints, updated.Spec.Taints)
+			}
+			for i, expected := range tc.expectedTaints {
+				if taint := updated.Spec.Taints[i]; taint.Key != expected.Key || taint.Value != expected.Value || taint.Effect != expected.Effect {
+					t.Errorf("Expected taint %v, got %v", expected, taint)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTaintEffect(t *testing.T) {
+	for _, name := range []string{"", "NoSchedule", "NoExecute"} {
+		if effect, err := ParseTaintEffect(name); err != nil || string(effect) != name {
+			t.Errorf("Expected effect %q, got %q, %v", name, effect, err)
+		}
+	}
+	if _, err := ParseTaintEffect("PreferNoSchedule"); err == nil {
+		t.Error("Expected an error for an unsupported effect")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"sync"
@@ -13,6 +14,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -28,42 +30,112 @@ const (
 // notice endpoint and mark node for deletion if the instance termination notice is fulfilled.
 type Handler interface {
 	Run(stop <-chan struct{}) error
+	// Reconfigure applies the options to the running handler, e.g. after the
+	// configuration file was reloaded.
+	Reconfigure(opts ...Option)
+	// Healthy returns an error when the handler stopped polling the termination endpoint.
+	Healthy() error
+	// Ready returns an error until the handler polled the termination endpoint.
+	Ready() error
 }
 
 // NewHandler constructs a new Handler
-func NewHandler(logger logr.Logger, cfg *rest.Config, pollInterval time.Duration, namespace, nodeName string) (Handler, error) {
+func NewHandler(logger logr.Logger, cfg *rest.Config, pollInterval time.Duration, namespace, nodeName string, opts ...Option) (Handler, error) {
 	c, err := client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	if err != nil {
 		return nil, fmt.Errorf("error creating client: %v", err)
 	}
 
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating discovery client: %v", err)
+	}
+
 	pollURL, err := url.Parse(gcpTerminationEndpointURL)
 	if err != nil {
 		// This should never happen
 		panic(err)
 	}
+	maintenanceURL, err := url.Parse(gcpMaintenanceEventEndpointURL)
+	if err != nil {
+		// This should never happen
+		panic(err)
+	}
 
 	logger = logger.WithValues("node", nodeName, "namespace", namespace)
 
-	return &handler{
-		client:       c,
-		pollURL:      pollURL,
-		pollInterval: pollInterval,
-		nodeName:     nodeName,
-		namespace:    namespace,
-		log:          logger,
-	}, nil
+	h := &handler{
+		client:         c,
+		pollURL:        pollURL,
+		maintenanceURL: maintenanceURL,
+		pollInterval:   pollInterval,
+		nodeName:       nodeName,
+		namespace:      namespace,
+		log:            logger,
+		apiHealthy: func(ctx context.Context) error {
+			return discoveryClient.RESTClient().Get().AbsPath("/readyz").Do(ctx).Error()
+		},
+		markRetryWindow:       defaultMarkRetryWindow,
+		markInitialBackoff:    defaultMarkInitialBackoff,
+		markMaxBackoff:        defaultMarkMaxBackoff,
+		circuitBreakerTrigger: defaultCircuitBreakerTrigger,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
 }
 
 // handler implements the logic to check the termination endpoint and delete the
 // machine associated with the node
 type handler struct {
-	client       client.Client
-	pollURL      *url.URL
-	pollInterval time.Duration
-	nodeName     string
-	namespace    string
-	log          logr.Logger
+	client         client.Client
+	pollURL        *url.URL
+	maintenanceURL *url.URL
+	pollInterval   time.Duration
+	nodeName       string
+	namespace      string
+	log            logr.Logger
+	health         health
+
+	// mu guards the settings below, which can be changed by Reconfigure while running.
+	mu sync.RWMutex
+
+	// apiHealthy probes the API server while the circuit breaker is open.
+	apiHealthy func(context.Context) error
+	// journal records pending node markings across restarts.
+	journal journal
+
+	markRetryWindow       time.Duration
+	markInitialBackoff    time.Duration
+	markMaxBackoff        time.Duration
+	circuitBreakerTrigger int
+	// terminationAction is what to do once the instance is terminating, besides marking the node.
+	terminationAction TerminationAction
+	// taintEffect is the effect of the impending termination taint applied to the node, none when empty.
+	taintEffect corev1.TaintEffect
+
+	// operations is checked every operationPollInterval for operations terminating the instance,
+	// which may be seen before the metadata server reports the preemption. Disabled when nil or
+	// when the interval is zero.
+	operations            OperationSource
+	operationPollInterval time.Duration
+	// maintenanceEvents reports the upcoming maintenance events of the instance in the
+	// MaintenanceScheduled condition of the node while polling.
+	maintenanceEvents bool
+	// waitForChange long-polls the termination endpoint with wait_for_change instead of checking
+	// it every poll interval.
+	waitForChange bool
+}
+
+// Reconfigure applies the options to the running handler. Settings in use by an ongoing
+// attempt to mark the node take effect on the next attempt.
+func (h *handler) Reconfigure(opts ...Option) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, opt := range opts {
+		opt(h)
+	}
 }
 
 // Run starts the handler and runs the termination logic
@@ -93,29 +165,140 @@ func (h *handler) Run(stop <-chan struct{}) error {
 
 func (h *handler) run(ctx context.Context) error {
 	logger := h.log.WithValues("node", h.nodeName)
+
+	// A previous run may have seen the termination notice without being able to mark the node.
+	h.mu.RLock()
+	journal, retryWindow := h.journal, h.markRetryWindow
+	h.mu.RUnlock()
+	entry, err := journal.pending(h.nodeName, time.Now(), retryWindow)
+	if err != nil {
+		logger.Error(err, "Could not read journal, ignoring it")
+	}
+	if entry != nil {
+		logger.V(1).Info("Found pending node marking in journal, marking Node for deletion", "since", entry.Time)
+		return h.markNodeWithRetry(ctx)
+	}
+
 	logger.V(1).Info("Monitoring node termination")
 
-	if err := wait.PollUntilContextCancel(ctx, h.pollInterval, true, func(_ context.Context) (bool, error) {
-		terminated, err := h.checkTerminationEndpoint()
-		if !terminated {
-			logger.V(2).Info("Instance not marked for termination")
-		}
-		return terminated, err
-	}); err != nil && err != context.Canceled {
+	source, latency, err := h.pollTerminationSources(ctx, time.Now())
+	if err != nil {
 		return fmt.Errorf("error polling termination endpoint: %w", err)
 	}
 
 	// We might arrive here due to the context being cancelled before we have gotten
 	// a clean signal from the termination endpoint, we check once more.
-	if terminated, err := h.checkTerminationEndpoint(); err != nil {
-		return err
-	} else if !terminated {
-		return nil
+	if source == "" {
+		if terminated, err := h.checkTerminationEndpoint(); err != nil {
+			return err
+		} else if !terminated {
+			return nil
+		}
+		source = metadataSource
+	}
+
+	// Both sources lead to a single marking of the node, whichever reports the termination first.
+	logger.V(1).Info("Instance marked for termination, marking Node for deletion", "source", source, "latency", latency)
+	terminationNoticesTotal.Inc()
+	h.recordPreemptionEvents(source, latency)
+
+	h.mu.RLock()
+	journal = h.journal
+	h.mu.RUnlock()
+	if err := journal.record(h.nodeName, terminationRequestedReason, time.Now()); err != nil {
+		logger.Error(err, "Could not record pending node marking in journal")
 	}
 
-	// Will only get here if the termination endpoint returned FALSE
-	logger.V(1).Info("Instance marked for termination, marking Node for deletion")
+	return h.markNodeWithRetry(ctx)
+}
 
+// pollTerminationSources checks the termination endpoint, and the operations of the instance
+// inserted since the handler started, until the instance is marked for termination or the context
+// is cancelled. It returns the source that reported the termination first, or an empty string when
+// the context was cancelled, with the detection latency: how long ago the source was last seen not
+// reporting the termination. Upcoming maintenance events are reported on the node along the way.
+// The poll intervals are read before every wait so that reconfigured intervals apply without a
+// restart.
+func (h *handler) pollTerminationSources(ctx context.Context, started time.Time) (string, time.Duration, error) {
+	var lastOperationsCheck time.Time
+	reportedMaintenanceEvent := ""
+	etag := ""
+	// metadataClear and operationsClear are when the sources were last seen not reporting the
+	// termination. A pending long-poll keeps the metadata source clear until it returns.
+	metadataClear, operationsClear := time.Now(), time.Now()
+	for {
+		h.mu.RLock()
+		interval, operations, operationInterval := h.pollInterval, h.operations, h.operationPollInterval
+		maintenanceEvents, waitForChange := h.maintenanceEvents, h.waitForChange
+		h.mu.RUnlock()
+
+		// The long-poll waits for up to a poll interval itself, so the other sources are still
+		// checked at that interval. When it fails, the endpoint is polled instead until the
+		// next iteration tries again.
+		watched, terminated := false, false
+		if waitForChange {
+			var err error
+			terminated, etag, err = h.watchTerminationEndpoint(ctx, etag, interval)
+			if ctx.Err() != nil {
+				return "", time.Since(metadataClear), nil
+			}
+			if err != nil {
+				watchErrorsTotal.Inc()
+				h.log.V(2).Info("Could not wait for a change of the termination endpoint, polling it instead", "error", err.Error())
+			} else {
+				watched = true
+				metadataClear = time.Now()
+			}
+		}
+		if !watched {
+			var err error
+			terminated, err = h.checkTerminationEndpoint()
+			if err != nil {
+				pollErrorsTotal.Inc()
+				return "", 0, err
+			}
+		}
+		h.recordPoll()
+		if terminated {
+			return metadataSource, time.Since(metadataClear), nil
+		}
+		metadataClear = time.Now()
+		h.log.V(2).Info("Instance not marked for termination")
+
+		if maintenanceEvents {
+			reportedMaintenanceEvent = h.reconcileMaintenanceEvent(ctx, reportedMaintenanceEvent)
+		}
+		if operations != nil && operationInterval > 0 && time.Since(lastOperationsCheck) >= operationInterval {
+			lastOperationsCheck = time.Now()
+			// The operations are a best effort second source, the metadata server remains authoritative.
+			operationType, err := operations.TerminatingOperation(ctx, started)
+			if err != nil {
+				operationPollErrorsTotal.Inc()
+				h.log.Error(err, "Could not check the operations of the instance")
+			} else if operationType != "" {
+				h.log.V(1).Info("Found operation terminating the instance", "operationType", operationType)
+				return operationsSource, time.Since(operationsClear), nil
+			} else {
+				operationsClear = lastOperationsCheck
+			}
+		}
+
+		if watched {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return "", time.Since(metadataClear), nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// markNodeWithRetry marks the node for deletion, unless it opted out with the
+// MarkingDisabledAnnotation, retrying with exponential backoff for up to the mark retry
+// window. After repeated failures the circuit breaker opens: instead of hammering an
+// unavailable API server, the handler probes its health and retries as soon as it is back.
+func (h *handler) markNodeWithRetry(ctx context.Context) error {
 	// Because we might have arrived here due to the context being cancelled, we need
 	// to check if it has been cancelled and if so create a new background context for the polling call.
 	var tmpctx context.Context
@@ -128,24 +311,67 @@ func (h *handler) run(ctx context.Context) error {
 		tmpctx = ctx
 	}
 
-	// Try every second to mark the node for termination up to a 30 second timeout.
-	// This should help to prevent intermittent errors and ensure we don't end up in crash loop backoff.
-	markCtx, cancel := context.WithTimeout(tmpctx, 30*time.Second)
-	defer cancel()
-	if err := wait.PollUntilContextCancel(markCtx, time.Second, true, func(ictx context.Context) (bool, error) {
-		if err := h.markNodeForDeletion(ictx); err != nil {
-			h.log.Error(err, "Instance not marked for termination")
-			return false, nil
+	h.recordMarking()
+	started := time.Now()
+
+	h.mu.RLock()
+	retryWindow, initialBackoff, maxBackoff := h.markRetryWindow, h.markInitialBackoff, h.markMaxBackoff
+	trigger, journal, action, taintEffect := h.circuitBreakerTrigger, h.journal, h.terminationAction, h.taintEffect
+	h.mu.RUnlock()
+
+	if disabled, source := h.markingDisabled(tmpctx); disabled {
+		h.log.V(1).Info("Marking is disabled, leaving the node to be handled by another component", "disabledBy", source, "annotation", MarkingDisabledAnnotation)
+		markingsSkippedTotal.Inc()
+		if err := journal.clear(); err != nil {
+			h.log.Error(err, "Could not clear journal")
 		}
-		return true, nil
-	}); err != nil {
-		return fmt.Errorf("error marking node: %v", err)
+		return nil
 	}
 
-	return nil
+	markCtx, cancel := context.WithTimeout(tmpctx, retryWindow)
+	defer cancel()
+
+	backoff := wait.Backoff{
+		Duration: initialBackoff,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    math.MaxInt32,
+		Cap:      maxBackoff,
+	}
+	failures := 0
+	for {
+		if failures >= trigger {
+			h.log.V(1).Info("Circuit breaker open, waiting for the API server to become healthy", "failures", failures)
+			if err := wait.PollUntilContextCancel(markCtx, initialBackoff, true, func(ictx context.Context) (bool, error) {
+				return h.apiHealthy(ictx) == nil, nil
+			}); err != nil {
+				return fmt.Errorf("error marking node: API server did not become healthy within %s", retryWindow)
+			}
+			// Half-open: a single attempt decides whether the breaker closes again.
+			failures = trigger - 1
+		}
+
+		err := h.handleTermination(markCtx, action, taintEffect)
+		if err == nil {
+			markDurationSeconds.Observe(time.Since(started).Seconds())
+			if err := journal.clear(); err != nil {
+				h.log.Error(err, "Could not clear journal")
+			}
+			return nil
+		}
+		failures++
+		markFailuresTotal.Inc()
+		h.log.Error(err, "Instance not marked for termination", "failures", failures)
+
+		select {
+		case <-markCtx.Done():
+			return fmt.Errorf("error marking node: %v", err)
+		case <-time.After(backoff.Step()):
+		}
+	}
 }
 
-func (h handler) checkTerminationEndpoint() (bool, error) {
+func (h *handler) checkTerminationEndpoint() (bool, error) {
 	req, err := http.NewRequest("GET", h.pollURL.String(), nil)
 	if err != nil {
 		return false, fmt.Errorf("could not create request %q: %w", h.pollURL.String(), err)
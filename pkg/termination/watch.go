@@ -0,0 +1,52 @@
+package termination
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// watchTerminationEndpoint waits for the termination endpoint to change from the value with the
+// given ETag, for up to timeout, with the wait_for_change hanging GET of the metadata server. A
+// preemption is then seen as soon as the metadata server reports it instead of up to a poll interval
+// late. It returns whether the instance is terminating and the ETag of the value to wait on next.
+// The first call, with an empty ETag, returns the current value immediately.
+func (h *handler) watchTerminationEndpoint(ctx context.Context, etag string, timeout time.Duration) (bool, string, error) {
+	watchURL := *h.pollURL
+	query := watchURL.Query()
+	query.Set("wait_for_change", "true")
+	query.Set("timeout_sec", strconv.Itoa(max(1, int(timeout.Seconds()))))
+	if etag != "" {
+		query.Set("last_etag", etag)
+	}
+	watchURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, watchURL.String(), nil)
+	if err != nil {
+		return false, "", fmt.Errorf("could not create request %q: %w", watchURL.String(), err)
+	}
+	req.Header.Add("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, "", fmt.Errorf("could not get URL %q: %w", watchURL.String(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("could not get URL %q: %s", watchURL.String(), resp.Status)
+	}
+	// Without an ETag the next request could not wait for a change, and would return immediately.
+	nextETag := resp.Header.Get("ETag")
+	if nextETag == "" {
+		return false, "", fmt.Errorf("no ETag in the response of %q", watchURL.String())
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	return string(bodyBytes) == "TRUE", nextETag, nil
+}
@@ -0,0 +1,130 @@
+package termination
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/klog/v2/klogr"
+)
+
+// metadataServer emulates the wait_for_change hanging GET of the metadata server for a single value.
+type metadataServer struct {
+	mu      sync.Mutex
+	value   string
+	version int
+	changed chan struct{}
+	noETag  bool
+}
+
+func newMetadataServer(value string) *metadataServer {
+	return &metadataServer{value: value, changed: make(chan struct{})}
+}
+
+func (s *metadataServer) set(value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value = value
+	s.version++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *metadataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	etag, changed := strconv.Itoa(s.version), s.changed
+	s.mu.Unlock()
+
+	if r.URL.Query().Get("wait_for_change") == "true" && r.URL.Query().Get("last_etag") == etag {
+		timeout, _ := strconv.Atoi(r.URL.Query().Get("timeout_sec"))
+		select {
+		case <-changed:
+		case <-time.After(time.Duration(timeout) * time.Second):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.noETag {
+		w.Header().Set("ETag", strconv.Itoa(s.version))
+	}
+	w.Write([]byte(s.value))
+}
+
+func TestWaitForChange(t *testing.T) {
+	metadata := newMetadataServer("FALSE")
+	server := httptest.NewServer(metadata)
+	defer server.Close()
+	pollURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With a poll interval of a minute, only the long-poll sees the preemption in time.
+	h := &handler{pollURL: pollURL, pollInterval: time.Minute, log: klogr.New(), waitForChange: true}
+	time.AfterFunc(200*time.Millisecond, func() { metadata.set("TRUE") })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	source, _, err := h.pollTerminationSources(ctx, start)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if source != metadataSource {
+		t.Fatalf("Expected the termination to be reported by the %s source, got %q", metadataSource, source)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the termination to be seen right away, took %s", elapsed)
+	}
+}
+
+func TestWaitForChangeFallback(t *testing.T) {
+	metadata := newMetadataServer("FALSE")
+	metadata.noETag = true
+	server := httptest.NewServer(metadata)
+	defer server.Close()
+	pollURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &handler{pollURL: pollURL, pollInterval: 50 * time.Millisecond, log: klogr.New(), waitForChange: true}
+	time.AfterFunc(200*time.Millisecond, func() { metadata.set("TRUE") })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	source, _, err := h.pollTerminationSources(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if source != metadataSource {
+		t.Fatalf("Expected the termination to be reported by the %s source after falling back to polling, got %q", metadataSource, source)
+	}
+}
+
+func TestWaitForChangeCancelled(t *testing.T) {
+	metadata := newMetadataServer("FALSE")
+	server := httptest.NewServer(metadata)
+	defer server.Close()
+	pollURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &handler{pollURL: pollURL, pollInterval: time.Minute, log: klogr.New(), waitForChange: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	source, _, err := h.pollTerminationSources(ctx, time.Now())
+	if err != nil || source != "" {
+		t.Errorf("Expected the long-poll to stop when cancelled, got %q, %v", source, err)
+	}
+}